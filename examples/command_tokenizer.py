@@ -0,0 +1,66 @@
+#!/usr/bin/env python3
+"""Reference implementation of the external command tokenizer protocol.
+
+TokEntropyDrift runs the configured command once per batch. The command reads
+{"texts": ["...", ...]} from stdin and writes a JSON array to stdout with one
+element per text, in the same order:
+
+    [
+      {
+        "tokens": [
+          {"text": "Hello", "id": 42, "start_pos": 0, "end_pos": 5}
+        ],
+        "metadata": {"model": "example"}
+      }
+    ]
+
+start_pos and end_pos are byte offsets into the UTF-8 encoding of the text.
+"metadata" is optional. To fail a single text, write {"error": "message"} in its
+place; a non-zero exit status fails the whole batch, with stderr in the error.
+
+This example splits words and punctuation and numbers tokens in order of first
+appearance. Configure it with:
+
+    tokenizers:
+      enabled: ["example-command"]
+      configs:
+        example-command:
+          type: "custom"
+          command: "python3 examples/command_tokenizer.py"
+          parameters:
+            timeout: "30s"
+"""
+
+import json
+import re
+import sys
+
+TOKEN_PATTERN = re.compile(r"\w+|[^\w\s]")
+
+
+def tokenize(text, vocab):
+    tokens = []
+    for match in TOKEN_PATTERN.finditer(text):
+        piece = match.group()
+        # Offsets are in bytes, so measure the UTF-8 encoding of the prefix
+        start = len(text[:match.start()].encode("utf-8"))
+        end = start + len(piece.encode("utf-8"))
+        token_id = vocab.setdefault(piece, len(vocab))
+        tokens.append({"text": piece, "id": token_id, "start_pos": start, "end_pos": end})
+    return tokens
+
+
+def main():
+    request = json.load(sys.stdin)
+    vocab = {}
+    results = []
+    for text in request["texts"]:
+        try:
+            results.append({"tokens": tokenize(text, vocab), "metadata": {"model": "example"}})
+        except Exception as e:
+            results.append({"error": str(e)})
+    json.dump(results, sys.stdout)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,60 @@
+#!/usr/bin/env python3
+"""Reference implementation of the exec plugin protocol.
+
+TokEntropyDrift runs the command in the plugin's manifest once per analysis, in
+the manifest's directory. The command reads the analysis context from stdin:
+
+    {
+      "document": "Hello world",
+      "tokenization": {"tokens": [{"text": "Hello", "id": 42, ...}], ...},
+      "tokenizer_name": "gpt2",
+      "config": {"long_token_length": 8}
+    }
+
+"config" holds the plugin's configuration, overridden by any analysis options.
+The command writes a JSON array of metric results to stdout:
+
+    [
+      {"name": "mean_token_length", "value": 2.5, "unit": "characters"}
+    ]
+
+"unit" and "metadata" are optional. A non-zero exit status fails the analysis,
+with stderr in the error.
+
+Install it by copying this file and token_stats.plugin.json into the plugin
+directory, and configure it with:
+
+    plugins:
+      auto_load: true
+      configs:
+        token_stats:
+          long_token_length: 8
+          timeout: "10s"
+"""
+
+import json
+import sys
+
+
+def main():
+    request = json.load(sys.stdin)
+    tokens = (request.get("tokenization") or {}).get("tokens") or []
+    long_length = request.get("config", {}).get("long_token_length", 8)
+
+    lengths = [len(token["text"]) for token in tokens]
+    if not lengths:
+        json.dump([], sys.stdout)
+        return
+
+    long_tokens = sum(1 for length in lengths if length >= long_length)
+    json.dump([
+        {"name": "mean_token_length", "value": sum(lengths) / len(lengths), "unit": "characters"},
+        {"name": "long_token_ratio", "value": long_tokens / len(lengths), "unit": "ratio",
+         "metadata": {"long_token_length": long_length}},
+        {"name": "distinct_token_ratio", "value": len({token["text"] for token in tokens}) / len(tokens),
+         "unit": "ratio"},
+    ], sys.stdout)
+
+
+if __name__ == "__main__":
+    main()
@@ -102,7 +102,7 @@ func main() {
 	heatmapTypes := []string{"token_count", "entropy", "compression", "reuse"}
 
 	for _, heatmapType := range heatmapTypes {
-		heatmapData := vizEngine.prepareHeatmapData(analysisResults, heatmapType)
+		heatmapData := vizEngine.PrepareHeatmapData(analysisResults, heatmapType)
 		if heatmapData != nil {
 			result, err := vizEngine.GenerateHeatmap(*heatmapData, heatmapType)
 			if err != nil {
@@ -147,11 +147,18 @@ func main() {
 	// Step 9: Generate drift analysis (if multiple tokenizers)
 	fmt.Println("9. Generating drift analysis...")
 	if len(tokenizerNames) > 1 {
-		// Prepare drift data
-		driftData := prepareDriftData(analysisResults, tokenizerNames)
-		if driftData != nil {
-			driftViz, err := vizEngine.GenerateDriftVisualization(*driftData)
+		tokA, errA := tokenizers.GetGlobal(tokenizerNames[0])
+		tokB, errB := tokenizers.GetGlobal(tokenizerNames[1])
+		if errA == nil && errB == nil {
+			docs := make([]string, 0, 5)
+			for _, doc := range documents[:5] {
+				docs = append(docs, doc.Content)
+			}
+
+			corpusDrift, err := metrics.NewDriftCalculator(0.5).CalculateCorpusDrift(context.Background(), docs, tokA, tokB)
 			if err != nil {
+				log.Printf("Warning: Failed to calculate corpus drift: %v", err)
+			} else if driftViz, err := vizEngine.GenerateDriftVisualization(visualization.NewDriftData(corpusDrift)); err != nil {
 				log.Printf("Warning: Failed to generate drift visualization: %v", err)
 			} else {
 				fmt.Printf("   Generated drift visualization: %s\n", driftViz.Filepath)
@@ -162,17 +169,20 @@ func main() {
 	// Step 10: Generate rolling entropy plot
 	fmt.Println("10. Generating rolling entropy plot...")
 	if len(analysisResults) > 0 {
-		// Use the first result for rolling entropy
-		result := analysisResults[0]
-		if entropyMetric, exists := result.Metrics["entropy_rolling_entropy_mean"]; exists {
-			rollingData := visualization.RollingEntropyData{
-				DocumentID:    "sample_doc",
-				TokenizerName: result.TokenizerName,
-				WindowSize:    50,
-				EntropyValues: []float64{entropyMetric.Value}, // Simplified for example
+		// Overlay the rolling entropy of every tokenization of the first document
+		var tokenizations []*tokenizers.TokenizationResult
+		for _, result := range analysisResults {
+			if result.Document == documents[0].Content && result.Tokenization != nil {
+				tokenizations = append(tokenizations, result.Tokenization)
 			}
+		}
 
-			rollingViz, err := vizEngine.GenerateRollingEntropyPlot(rollingData)
+		rollingData, err := visualization.NewMultiRollingEntropyData(metrics.NewEntropyCalculator(50, true), "sample_doc", tokenizations)
+		if err != nil {
+			log.Printf("Warning: Failed to calculate rolling entropy: %v", err)
+		} else if len(rollingData.Series) > 0 {
+			rollingData.Smoothing = 5
+			rollingViz, err := vizEngine.GenerateMultiRollingEntropyPlot(rollingData)
 			if err != nil {
 				log.Printf("Warning: Failed to generate rolling entropy plot: %v", err)
 			} else {
@@ -195,67 +205,3 @@ func truncateString(s string, maxLen int) string {
 	}
 	return s[:maxLen] + "..."
 }
-
-// Helper function to prepare drift data
-func prepareDriftData(analysisResults []*metrics.AnalysisResult, tokenizerNames []string) *visualization.DriftData {
-	if len(tokenizerNames) < 2 {
-		return nil
-	}
-
-	// Group results by document
-	docResults := make(map[string]map[string]*metrics.AnalysisResult)
-	for _, result := range analysisResults {
-		if _, exists := docResults[result.Document]; !exists {
-			docResults[result.Document] = make(map[string]*metrics.AnalysisResult)
-		}
-		docResults[result.Document][result.TokenizerName] = result
-	}
-
-	// Prepare drift metrics
-	var documents []string
-	var tokenCountDeltas []float64
-	var entropyDeltas []float64
-	var alignmentScores []float64
-
-	for doc, results := range docResults {
-		if len(results) >= 2 {
-			documents = append(documents, truncateString(doc, 30))
-
-			// Calculate deltas between first two tokenizers
-			tokenizer1 := tokenizerNames[0]
-			tokenizer2 := tokenizerNames[1]
-
-			if result1, exists1 := results[tokenizer1]; exists1 {
-				if result2, exists2 := results[tokenizer2]; exists2 {
-					// Token count delta
-					tokenDelta := float64(result2.TokenCount - result1.TokenCount)
-					tokenCountDeltas = append(tokenCountDeltas, tokenDelta)
-
-					// Entropy delta
-					entropy1 := result1.Metrics["entropy_global_entropy"].Value
-					entropy2 := result2.Metrics["entropy_global_entropy"].Value
-					entropyDeltas = append(entropyDeltas, entropy2-entropy1)
-
-					// Simple alignment score (placeholder)
-					alignmentScores = append(alignmentScores, 0.85)
-				}
-			}
-		}
-	}
-
-	if len(documents) == 0 {
-		return nil
-	}
-
-	return &visualization.DriftData{
-		ComparisonID: fmt.Sprintf("%s_vs_%s", tokenizerNames[0], tokenizerNames[1]),
-		Tokenizer1:   tokenizerNames[0],
-		Tokenizer2:   tokenizerNames[1],
-		Documents:    documents,
-		DriftMetrics: map[string][]float64{
-			"token_count_delta": tokenCountDeltas,
-			"entropy_delta":     entropyDeltas,
-			"alignment_score":   alignmentScores,
-		},
-	}
-}
@@ -2,43 +2,71 @@ package advanced
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/RevBooyah/tokentropydrift/internal/cache"
-	"github.com/RevBooyah/tokentropydrift/internal/config"
-	"github.com/RevBooyah/tokentropydrift/internal/metrics"
-	"github.com/RevBooyah/tokentropydrift/internal/parallel"
-	"github.com/RevBooyah/tokentropydrift/internal/plugins"
-	"github.com/RevBooyah/tokentropydrift/internal/streaming"
-	"github.com/RevBooyah/tokentropydrift/internal/tokenizers"
+	"github.com/RevBooyah/TokEntropyDrift/internal/cache"
+	"github.com/RevBooyah/TokEntropyDrift/internal/config"
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+	"github.com/RevBooyah/TokEntropyDrift/internal/parallel"
+	"github.com/RevBooyah/TokEntropyDrift/internal/plugins"
+	"github.com/RevBooyah/TokEntropyDrift/internal/streaming"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
 )
 
-// AdvancedManager manages all advanced features
+// ErrStrategyDisabled is returned when an analysis asks for a strategy whose
+// processing is disabled in the configuration
+var ErrStrategyDisabled = errors.New("strategy disabled")
+
+// AdvancedManager manages all advanced features. It is safe for concurrent use.
 type AdvancedManager struct {
-	config     *config.Config
-	cache      *cache.Cache
-	processor  *parallel.Processor
-	streamer   *streaming.StreamAnalyzer
-	pluginReg  *plugins.Registry
-	engine     *metrics.Engine
-	tokenizers map[string]tokenizers.Tokenizer
+	config    *config.Config
+	cache     *cache.Cache
+	pipeline  *parallel.Pipeline
+	streamer  *streaming.StreamAnalyzer
+	pluginReg *plugins.Registry
+	engine    *metrics.Engine
+
+	tokenizersMu sync.RWMutex
+	tokenizers   map[string]tokenizers.Tokenizer
+
+	ownsPlugins      bool                 // the plugin registry was created by the manager, which closes it
+	pluginLoadErrors []*plugins.LoadError // plugin files that failed to load
+}
+
+// Option configures an AdvancedManager
+type Option func(*AdvancedManager)
+
+// WithPluginRegistry uses plugins already loaded into registry, whose document hook
+// its owner has added to the engine, instead of loading the plugin directory. The
+// manager leaves the registry open when it closes.
+func WithPluginRegistry(registry *plugins.Registry) Option {
+	return func(m *AdvancedManager) {
+		m.pluginReg = registry
+	}
 }
 
 // NewAdvancedManager creates a new advanced features manager
-func NewAdvancedManager(cfg *config.Config, engine *metrics.Engine) (*AdvancedManager, error) {
+func NewAdvancedManager(cfg *config.Config, engine *metrics.Engine, options ...Option) (*AdvancedManager, error) {
 	manager := &AdvancedManager{
 		config:     cfg,
 		engine:     engine,
 		tokenizers: make(map[string]tokenizers.Tokenizer),
 	}
+	for _, option := range options {
+		option(manager)
+	}
 
 	// Initialize cache if enabled
 	if cfg.Cache.Enabled {
 		cacheConfig := cache.CacheConfig{
 			MaxSize:         cfg.Cache.MaxSize,
+			MaxBytes:        int64(cfg.Cache.MaxSizeMB) << 20,
+			Shards:          cfg.Cache.Shards,
 			TTL:             parseDuration(cfg.Cache.TTL),
 			CleanupInterval: parseDuration(cfg.Cache.CleanupInterval),
 			EnableStats:     cfg.Cache.EnableStats,
@@ -46,15 +74,19 @@ func NewAdvancedManager(cfg *config.Config, engine *metrics.Engine) (*AdvancedMa
 		manager.cache = cache.NewCache(cacheConfig)
 	}
 
-	// Initialize parallel processor if enabled
+	// Initialize the parallel pipeline if enabled. It both tokenizes and analyzes, so
+	// the parallel path yields the same results as the standard one.
 	if cfg.Parallel.Enabled {
-		processorConfig := parallel.ProcessorConfig{
-			MaxWorkers:    cfg.Parallel.MaxWorkers,
-			BatchSize:     cfg.Parallel.BatchSize,
-			Timeout:       parseDuration(cfg.Parallel.Timeout),
-			EnableMetrics: cfg.Parallel.EnableMetrics,
+		pipelineConfig := parallel.PipelineConfig{
+			TokenizeWorkers:  cfg.Parallel.MaxWorkers,
+			AnalyzeWorkers:   cfg.Parallel.MaxWorkers,
+			Timeout:          parseDuration(cfg.Parallel.Timeout),
+			AbortAfterErrors: cfg.Parallel.AbortAfterErrors,
 		}
-		manager.processor = parallel.NewProcessor(processorConfig)
+		if cfg.Parallel.ProgressInterval != "" {
+			pipelineConfig.ProgressInterval = parseDuration(cfg.Parallel.ProgressInterval)
+		}
+		manager.pipeline = parallel.NewPipeline(pipelineConfig, engine)
 	}
 
 	// Initialize streaming analyzer if enabled
@@ -66,32 +98,44 @@ func NewAdvancedManager(cfg *config.Config, engine *metrics.Engine) (*AdvancedMa
 			EnableProgress:   cfg.Streaming.EnableProgress,
 			ProgressInterval: cfg.Streaming.ProgressInterval,
 			Timeout:          parseDuration(cfg.Streaming.Timeout),
+
+			RetainChunkResults: cfg.Streaming.RetainChunkResults,
+			ChunkMode:          streaming.ChunkMode(cfg.Streaming.ChunkMode),
+			Delimiter:          cfg.Streaming.Delimiter,
+			WeightBy:           cfg.Streaming.WeightBy,
+			Drift: streaming.DriftConfig{
+				Enabled:        cfg.Streaming.Drift.Enabled,
+				BaselineChunks: cfg.Streaming.Drift.BaselineChunks,
+				Decay:          cfg.Streaming.Drift.Decay,
+				ZThreshold:     cfg.Streaming.Drift.ZThreshold,
+			},
 		}
 		manager.streamer = streaming.NewStreamAnalyzer(streamConfig, engine)
 	}
 
-	// Initialize plugin registry if enabled
-	if cfg.Plugins.Enabled {
+	// Initialize plugin registry if enabled and none was given
+	if cfg.Plugins.Enabled && manager.pluginReg == nil {
 		manager.pluginReg = plugins.NewRegistry()
+		manager.ownsPlugins = true
+		manager.pluginReg.SetMaxConcurrency(cfg.Plugins.MaxConcurrency)
 		if err := manager.loadPlugins(); err != nil {
 			return nil, fmt.Errorf("failed to load plugins: %w", err)
 		}
+		// Plugin metrics join each document's results as plugin_{name}_{metric}
+		engine.AddDocumentHook(manager.pluginReg.DocumentHook())
 	}
 
 	return manager, nil
 }
 
-// RegisterTokenizer registers a tokenizer with caching if enabled
+// RegisterTokenizer registers a tokenizer, caching its tokenizations in the manager's
+// cache if enabled. All tokenizers share the one cache, so cache.max_size bounds the
+// entries of all of them together.
 func (m *AdvancedManager) RegisterTokenizer(name string, tokenizer tokenizers.Tokenizer) error {
-	if m.config.Cache.Enabled && m.cache != nil {
-		cacheConfig := cache.CacheConfig{
-			MaxSize:         m.config.Cache.MaxSize,
-			TTL:             parseDuration(m.config.Cache.TTL),
-			CleanupInterval: parseDuration(m.config.Cache.CleanupInterval),
-			EnableStats:     m.config.Cache.EnableStats,
-		}
-		cachedTokenizer := tokenizers.NewCachedTokenizer(tokenizer, cache.NewCache(cacheConfig))
-		m.tokenizers[name] = cachedTokenizer
+	m.tokenizersMu.Lock()
+	defer m.tokenizersMu.Unlock()
+	if m.cache != nil {
+		m.tokenizers[name] = tokenizers.NewCachedTokenizerWithCache(tokenizer, m.cache)
 	} else {
 		m.tokenizers[name] = tokenizer
 	}
@@ -100,6 +144,8 @@ func (m *AdvancedManager) RegisterTokenizer(name string, tokenizer tokenizers.To
 
 // GetTokenizer retrieves a registered tokenizer
 func (m *AdvancedManager) GetTokenizer(name string) (tokenizers.Tokenizer, error) {
+	m.tokenizersMu.RLock()
+	defer m.tokenizersMu.RUnlock()
 	tokenizer, exists := m.tokenizers[name]
 	if !exists {
 		return nil, fmt.Errorf("tokenizer %s not found", name)
@@ -107,7 +153,21 @@ func (m *AdvancedManager) GetTokenizer(name string) (tokenizers.Tokenizer, error
 	return tokenizer, nil
 }
 
-// AnalyzeWithAdvanced performs analysis using all advanced features
+// Progress reports how far an analysis has come. Parallel runs count documents out of
+// Total; streaming runs count chunks, with the lines read so far and a Total of -1
+// as the number of chunks is not known ahead. Standard runs report no progress.
+type Progress struct {
+	Strategy  string
+	Processed int // documents, or chunks when streaming
+	Failed    int
+	Total     int
+	Lines     int // streaming only
+	Elapsed   time.Duration
+}
+
+// AnalyzeWithAdvanced performs analysis using all advanced features. The progress
+// callback receives (processed, failed, total, elapsed) from parallel runs and
+// (chunk, -1, lines, elapsed) from streaming runs.
 func (m *AdvancedManager) AnalyzeWithAdvanced(
 	ctx context.Context,
 	texts []string,
@@ -115,6 +175,31 @@ func (m *AdvancedManager) AnalyzeWithAdvanced(
 	progressCallback func(int, int, int, time.Duration),
 ) (*AdvancedAnalysisResult, error) {
 
+	var progress func(Progress)
+	if progressCallback != nil {
+		progress = func(p Progress) {
+			if p.Strategy == StrategyStreaming {
+				progressCallback(p.Processed, p.Total, p.Lines, p.Elapsed)
+			} else {
+				progressCallback(p.Processed, p.Failed, p.Total, p.Elapsed)
+			}
+		}
+	}
+	return m.AnalyzeWithStrategy(ctx, texts, tokenizerName, "", progress)
+}
+
+// AnalyzeWithStrategy performs analysis like AnalyzeWithAdvanced, with the given
+// strategy instead of the one the number of texts calls for when strategy is not
+// empty. Forcing a strategy whose processing is disabled is ErrStrategyDisabled.
+// progress may be nil.
+func (m *AdvancedManager) AnalyzeWithStrategy(
+	ctx context.Context,
+	texts []string,
+	tokenizerName string,
+	strategy string,
+	progress func(Progress),
+) (*AdvancedAnalysisResult, error) {
+
 	tokenizer, err := m.GetTokenizer(tokenizerName)
 	if err != nil {
 		return nil, err
@@ -124,23 +209,40 @@ func (m *AdvancedManager) AnalyzeWithAdvanced(
 		StartTime: time.Now(),
 		Config:    m.config,
 	}
-
-	// Use parallel processing for large datasets
-	if m.config.Parallel.Enabled && len(texts) > m.config.Parallel.BatchSize {
-		result.ParallelStats = m.processParallel(ctx, texts, tokenizer)
+	if strategy == "" {
+		result.Strategy, result.StrategyReason = m.chooseStrategy(len(texts))
 	} else {
-		// Use streaming for very large datasets
-		if m.config.Streaming.Enabled && len(texts) > m.config.Streaming.ChunkSize*10 {
-			result.StreamingStats = m.processStreaming(ctx, texts, tokenizer, progressCallback)
-		} else {
-			// Use standard processing
-			result.StandardResults = m.processStandard(ctx, texts, tokenizer)
+		if err := m.checkStrategy(strategy); err != nil {
+			return nil, err
 		}
+		result.Strategy, result.StrategyReason = strategy, "requested by the caller"
+	}
+	sampler := startMemorySampler()
+
+	switch result.Strategy {
+	case StrategyParallel:
+		batch, stats := m.processParallel(ctx, texts, tokenizer, progress)
+		result.Results, result.Errors, result.Skipped = batch.Results, batch.Errors, batch.Skipped
+		result.ParallelStats = stats
+	case StrategyStreaming:
+		result.StreamingStats = m.processStreaming(ctx, texts, tokenizer, progress)
+	default:
+		batch := m.processStandard(ctx, texts, tokenizer)
+		result.Results, result.Errors, result.Skipped = batch.Results, batch.Errors, batch.Skipped
 	}
 
 	// Execute plugins if enabled
 	if m.config.Plugins.Enabled && m.pluginReg != nil {
-		result.PluginResults = m.executePlugins(ctx, texts, tokenizer)
+		result.PluginResults = m.executePlugins(result.Results, result.Errors)
+	}
+
+	result.Memory = sampler.finish()
+	if result.StreamingStats != nil {
+		m.recordStreamingMemory(result)
+	}
+	if cached, ok := tokenizer.(*tokenizers.CachedTokenizer); ok {
+		stats := cached.GetCacheStats()
+		result.CacheStats = &stats
 	}
 
 	result.EndTime = time.Now()
@@ -149,27 +251,81 @@ func (m *AdvancedManager) AnalyzeWithAdvanced(
 	return result, nil
 }
 
-// processParallel processes texts using parallel processing
+// chooseStrategy picks how texts are analyzed and says why. Parallel processing takes
+// batches larger than parallel.batch_size, streaming takes more than ten chunks'
+// worth of documents, and anything else is analyzed in the standard way.
+func (m *AdvancedManager) chooseStrategy(documents int) (string, string) {
+	if m.pipeline != nil && documents > m.config.Parallel.BatchSize {
+		return StrategyParallel, fmt.Sprintf("%d documents exceed parallel.batch_size %d", documents, m.config.Parallel.BatchSize)
+	}
+	if m.streamer != nil && documents > m.config.Streaming.ChunkSize*10 {
+		return StrategyStreaming, fmt.Sprintf("%d documents exceed 10 streaming chunks of %d", documents, m.config.Streaming.ChunkSize)
+	}
+	switch {
+	case m.pipeline == nil && m.streamer == nil:
+		return StrategyStandard, "parallel and streaming processing are disabled"
+	case m.pipeline == nil:
+		return StrategyStandard, fmt.Sprintf("%d documents fit in 10 streaming chunks of %d", documents, m.config.Streaming.ChunkSize)
+	default:
+		return StrategyStandard, fmt.Sprintf("%d documents fit in parallel.batch_size %d", documents, m.config.Parallel.BatchSize)
+	}
+}
+
+// checkStrategy reports whether strategy can be forced: it must be known, and its
+// processing enabled
+func (m *AdvancedManager) checkStrategy(strategy string) error {
+	switch strategy {
+	case StrategyStandard:
+		return nil
+	case StrategyParallel:
+		if m.pipeline == nil {
+			return fmt.Errorf("%w: %s needs parallel.enabled", ErrStrategyDisabled, strategy)
+		}
+		return nil
+	case StrategyStreaming:
+		if m.streamer == nil {
+			return fmt.Errorf("%w: %s needs streaming.enabled", ErrStrategyDisabled, strategy)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown strategy %q", strategy)
+	}
+}
+
+// processParallel tokenizes and analyzes texts in the parallel pipeline. Results are in
+// document order and each failure carries the index of the document that failed, as
+// with processStandard; documents never started count as skipped. progress may be
+// nil.
 func (m *AdvancedManager) processParallel(
 	ctx context.Context,
 	texts []string,
 	tokenizer tokenizers.Tokenizer,
-) *parallel.ProcessingStats {
-
-	results, _, stats := m.processor.ProcessTokenizations(ctx, texts, tokenizer)
-
-	// Convert results to analysis results
-	analysisResults := make([]*metrics.AnalysisResult, len(results))
-	for i, result := range results {
-		analysisResults[i] = &metrics.AnalysisResult{
-			Document:      result.Document,
-			TokenizerName: result.Tokenizer,
-			TokenCount:    len(result.Tokens),
-			Tokenization:  result,
+	progress func(Progress),
+) (*metrics.BatchResult, *parallel.PipelineStats) {
+
+	var progressCallback parallel.ProgressCallback
+	if progress != nil {
+		progressCallback = func(processed, failed, total int, elapsed time.Duration) {
+			progress(Progress{Strategy: StrategyParallel, Processed: processed, Failed: failed, Total: total, Elapsed: elapsed})
 		}
 	}
+	items, stats := m.pipeline.Run(ctx, texts, tokenizer, progressCallback)
 
-	return &stats
+	batch := &metrics.BatchResult{
+		Results: make([]*metrics.AnalysisResult, 0, len(items)),
+	}
+	for _, item := range items {
+		switch {
+		case item.Err == nil:
+			batch.Results = append(batch.Results, item.Result)
+		case item.Stage == "":
+			batch.Skipped++
+		default:
+			batch.Errors = append(batch.Errors, &metrics.DocumentError{Index: item.Index, Err: item.Err})
+		}
+	}
+
+	return batch, &stats
 }
 
 // processStreaming processes texts using streaming analysis
@@ -177,13 +333,32 @@ func (m *AdvancedManager) processStreaming(
 	ctx context.Context,
 	texts []string,
 	tokenizer tokenizers.Tokenizer,
-	progressCallback func(int, int, int, time.Duration),
+	progress func(Progress),
 ) *streaming.StreamResult {
 
+	var progressCallback streaming.ProgressCallback
+	if progress != nil {
+		progressCallback = func(chunk, total, lines int, elapsed time.Duration) {
+			progress(Progress{Strategy: StrategyStreaming, Processed: chunk, Total: total, Lines: lines, Elapsed: elapsed})
+		}
+	}
+
 	// Convert texts to a reader for streaming
 	reader := createTextReader(texts)
 
-	streamResult, err := m.streamer.AnalyzeStream(ctx, reader, tokenizer, progressCallback)
+	var sink streaming.ChunkSink
+	if path := m.config.Streaming.ChunkResultsPath; path != "" {
+		fileSink, err := streaming.NewFileSink(path)
+		if err != nil {
+			return &streaming.StreamResult{
+				Errors: []string{err.Error()},
+			}
+		}
+		defer fileSink.Close()
+		sink = fileSink
+	}
+
+	streamResult, err := m.streamer.AnalyzeStreamTo(ctx, reader, tokenizer, sink, progressCallback)
 	if err != nil {
 		// Return empty result on error
 		return &streaming.StreamResult{
@@ -194,77 +369,117 @@ func (m *AdvancedManager) processStreaming(
 	return streamResult
 }
 
-// processStandard processes texts using standard analysis
+// recordStreamingMemory adds the streaming figures to the result's memory usage, with a
+// warning for each way the run outgrew streaming.max_memory_mb
+func (m *AdvancedManager) recordStreamingMemory(result *AdvancedAnalysisResult) {
+	stream := result.StreamingStats
+	result.Memory.BytesRead = stream.BytesRead
+	result.Memory.ChunksRetained = len(stream.ChunkResults)
+	result.Memory.RetainedBytes = stream.RetainedBytes
+
+	limitMB := m.config.Streaming.MaxMemoryMB
+	if limitMB <= 0 {
+		return
+	}
+	if stream.Truncated {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(
+			"retained chunk results reached streaming.max_memory_mb %d; only the first %d chunks were kept", limitMB, len(stream.ChunkResults)))
+	}
+	if peak := result.Memory.PeakHeapAllocBytes; peak > uint64(limitMB)<<20 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(
+			"peak heap of %d MB exceeded streaming.max_memory_mb %d", peak>>20, limitMB))
+	}
+}
+
+// processStandard processes texts using standard analysis. Documents that fail are
+// reported in the batch errors rather than replaced with empty results.
 func (m *AdvancedManager) processStandard(
 	ctx context.Context,
 	texts []string,
 	tokenizer tokenizers.Tokenizer,
-) []*metrics.AnalysisResult {
+) *metrics.BatchResult {
 
-	results := make([]*metrics.AnalysisResult, len(texts))
-	for i, text := range texts {
-		result, err := m.engine.AnalyzeDocument(ctx, text, tokenizer)
-		if err != nil {
-			// Create empty result on error
-			results[i] = &metrics.AnalysisResult{
-				Document:      text,
-				TokenizerName: tokenizer.Name(),
-				TokenCount:    0,
-				Metrics:       make(map[string]metrics.MetricResult),
-			}
-		} else {
-			results[i] = result
-		}
-	}
-
-	return results
+	// A cancelled context still leaves the partial batch, with Skipped counting the rest
+	batch, _ := m.engine.AnalyzeBatch(ctx, texts, tokenizer)
+	return batch
 }
 
-// executePlugins executes all registered plugins
+// executePlugins collects the plugin results of each analyzed document, matched to the
+// document's index by skipping the documents that failed. Standard and parallel
+// results both carry the metrics of the plugin hook. Streaming keeps no per-document
+// results.
 func (m *AdvancedManager) executePlugins(
-	ctx context.Context,
-	texts []string,
-	tokenizer tokenizers.Tokenizer,
-) map[string][]plugins.MetricResult {
+	results []*metrics.AnalysisResult,
+	failed []*metrics.DocumentError,
+) []DocumentPluginResults {
 
-	// Create analysis context for plugins
-	analysisContext := &plugins.AnalysisContext{
-		Document:      strings.Join(texts, "\n"),
-		TokenizerName: tokenizer.Name(),
-		Config:        make(map[string]interface{}),
-		Context:       ctx,
+	failedIndexes := make(map[int]bool, len(failed))
+	for _, failure := range failed {
+		failedIndexes[failure.Index] = true
 	}
 
-	// Execute plugins
-	pluginResults, err := m.pluginReg.ExecuteMetrics(analysisContext)
-	if err != nil {
-		return map[string][]plugins.MetricResult{
-			"error": {
-				{
-					Name:  "plugin_execution_error",
-					Value: 0,
-					Unit:  "error",
-				},
-			},
+	documents := make([]DocumentPluginResults, 0, len(results))
+	index := 0
+	for _, result := range results {
+		for failedIndexes[index] {
+			index++
 		}
+
+		document := DocumentPluginResults{Index: index}
+		document.Results, document.Errors = plugins.DocumentResults(result)
+		documents = append(documents, document)
+		index++
 	}
 
-	return pluginResults
+	return documents
 }
 
-// loadPlugins loads and registers plugins
+// loadPlugins loads and registers the plugins in the plugin directory when auto-loading
+// is enabled. Files that fail to load are kept for GetPluginLoadErrors rather than
+// failing the manager.
 func (m *AdvancedManager) loadPlugins() error {
-	// TODO: Implement plugin loading from files
-	// For now, return nil to indicate no plugins loaded
+	if !m.config.Plugins.AutoLoad {
+		return nil
+	}
+
+	_, m.pluginLoadErrors = m.pluginReg.LoadDirectory(m.config.Plugins.PluginDirectory, m.config.Plugins.Configs)
 	return nil
 }
 
-// GetCacheStats returns cache statistics if caching is enabled
+// GetCacheStats returns the statistics of the cache all tokenizers share, with the
+// hits and misses of every registered tokenizer, if caching is enabled
 func (m *AdvancedManager) GetCacheStats() *cache.CacheStats {
-	if m.cache != nil {
-		return m.cache.GetStats()
+	if m.cache == nil {
+		return nil
 	}
-	return nil
+	stats := m.cache.GetStats()
+	stats.Hits, stats.Misses = 0, 0
+	m.tokenizersMu.RLock()
+	defer m.tokenizersMu.RUnlock()
+	for _, tokenizer := range m.tokenizers {
+		if cached, ok := tokenizer.(*tokenizers.CachedTokenizer); ok {
+			own := cached.GetCacheStats()
+			stats.Hits += own.Hits
+			stats.Misses += own.Misses
+		}
+	}
+	return &stats
+}
+
+// GetTokenizerCacheStats returns the cache statistics of one registered tokenizer: its
+// own hits and misses, and the size of the shared cache. It returns nil if caching is
+// disabled.
+func (m *AdvancedManager) GetTokenizerCacheStats(name string) (*cache.CacheStats, error) {
+	tokenizer, err := m.GetTokenizer(name)
+	if err != nil {
+		return nil, err
+	}
+	cached, ok := tokenizer.(*tokenizers.CachedTokenizer)
+	if !ok {
+		return nil, nil
+	}
+	stats := cached.GetCacheStats()
+	return &stats, nil
 }
 
 // GetPluginInfo returns information about loaded plugins
@@ -275,28 +490,56 @@ func (m *AdvancedManager) GetPluginInfo() []plugins.PluginInfo {
 	return []plugins.PluginInfo{}
 }
 
+// GetPluginLoadErrors returns the plugin files that failed to load, one error per file
+func (m *AdvancedManager) GetPluginLoadErrors() []*plugins.LoadError {
+	return m.pluginLoadErrors
+}
+
 // Close cleans up all resources
 func (m *AdvancedManager) Close() error {
 	if m.cache != nil {
 		m.cache.Close()
 	}
-	if m.pluginReg != nil {
+	if m.pluginReg != nil && m.ownsPlugins {
 		m.pluginReg.Close()
 	}
 	return nil
 }
 
-// AdvancedAnalysisResult represents the result of advanced analysis
+// Strategies AnalyzeWithAdvanced chooses between
+const (
+	StrategyStandard  = "standard"
+	StrategyParallel  = "parallel"
+	StrategyStreaming = "streaming"
+)
+
+// AdvancedAnalysisResult represents the result of advanced analysis. Results, Errors
+// and Skipped hold the per-document analysis of the standard and parallel strategies
+// alike; streaming reports its aggregate in StreamingStats instead.
 type AdvancedAnalysisResult struct {
-	StartTime       time.Time                         `json:"start_time"`
-	EndTime         time.Time                         `json:"end_time"`
-	Duration        time.Duration                     `json:"duration"`
-	Config          *config.Config                    `json:"config"`
-	StandardResults []*metrics.AnalysisResult         `json:"standard_results,omitempty"`
-	ParallelStats   *parallel.ProcessingStats         `json:"parallel_stats,omitempty"`
-	StreamingStats  *streaming.StreamResult           `json:"streaming_stats,omitempty"`
-	PluginResults   map[string][]plugins.MetricResult `json:"plugin_results,omitempty"`
-	CacheStats      *cache.CacheStats                 `json:"cache_stats,omitempty"`
+	StartTime      time.Time                 `json:"start_time"`
+	EndTime        time.Time                 `json:"end_time"`
+	Duration       time.Duration             `json:"duration"`
+	Config         *config.Config            `json:"config"`
+	Strategy       string                    `json:"strategy"`
+	StrategyReason string                    `json:"strategy_reason"`
+	Results        []*metrics.AnalysisResult `json:"results,omitempty"`
+	Errors         []*metrics.DocumentError  `json:"errors,omitempty"`
+	Skipped        int                       `json:"skipped,omitempty"`
+	ParallelStats  *parallel.PipelineStats   `json:"parallel_stats,omitempty"`
+	StreamingStats *streaming.StreamResult   `json:"streaming_stats,omitempty"`
+	PluginResults  []DocumentPluginResults   `json:"plugin_results,omitempty"`
+	CacheStats     *cache.CacheStats         `json:"cache_stats,omitempty"`
+	Memory         *MemoryUsage              `json:"memory"`
+	Warnings       []string                  `json:"warnings,omitempty"` // e.g. streaming.max_memory_mb exceeded
+}
+
+// DocumentPluginResults holds the plugin results of one document, by plugin name, and
+// the plugins that failed on it
+type DocumentPluginResults struct {
+	Index   int                               `json:"index"`
+	Results map[string][]plugins.MetricResult `json:"results"`
+	Errors  map[string]string                 `json:"errors,omitempty"`
 }
 
 // Helper functions
@@ -2,12 +2,15 @@ package advanced
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
-	"github.com/RevBooyah/tokentropydrift/internal/config"
-	"github.com/RevBooyah/tokentropydrift/internal/metrics"
-	"github.com/RevBooyah/tokentropydrift/internal/tokenizers"
+	"github.com/RevBooyah/TokEntropyDrift/internal/config"
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+	"github.com/RevBooyah/TokEntropyDrift/internal/plugins/examples"
+	"github.com/RevBooyah/TokEntropyDrift/internal/streaming"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
 )
 
 func TestAdvancedManagerCreation(t *testing.T) {
@@ -40,7 +43,7 @@ func TestAdvancedManagerCreation(t *testing.T) {
 			Enabled:         true,
 			AutoLoad:        true,
 			PluginDirectory: "plugins",
-			Configs:         make(map[string]interface{}),
+			Configs:         make(map[string]map[string]interface{}),
 		},
 	}
 
@@ -68,8 +71,8 @@ func TestAdvancedManagerCreation(t *testing.T) {
 		t.Error("Cache should be initialized when enabled")
 	}
 
-	if manager.processor == nil {
-		t.Error("Processor should be initialized when enabled")
+	if manager.pipeline == nil {
+		t.Error("Pipeline should be initialized when enabled")
 	}
 
 	if manager.streamer == nil {
@@ -111,7 +114,7 @@ func TestAdvancedManagerWithMockTokenizer(t *testing.T) {
 			Enabled:         true,
 			AutoLoad:        true,
 			PluginDirectory: "plugins",
-			Configs:         make(map[string]interface{}),
+			Configs:         make(map[string]map[string]interface{}),
 		},
 	}
 
@@ -131,7 +134,7 @@ func TestAdvancedManagerWithMockTokenizer(t *testing.T) {
 	defer manager.Close()
 
 	// Create and register mock tokenizer
-	mockTokenizer := tokenizers.NewMockTokenizer()
+	mockTokenizer := tokenizers.NewMockTokenizer("mock")
 	mockTokenizer.Initialize(tokenizers.TokenizerConfig{
 		Name: "mock",
 		Type: "custom",
@@ -232,8 +235,8 @@ func TestAdvancedManagerWithoutFeatures(t *testing.T) {
 		t.Error("Cache should be nil when disabled")
 	}
 
-	if manager.processor != nil {
-		t.Error("Processor should be nil when disabled")
+	if manager.pipeline != nil {
+		t.Error("Pipeline should be nil when disabled")
 	}
 
 	if manager.streamer != nil {
@@ -244,3 +247,267 @@ func TestAdvancedManagerWithoutFeatures(t *testing.T) {
 		t.Error("Plugin registry should be nil when disabled")
 	}
 }
+
+func TestAdvancedManagerSharedCache(t *testing.T) {
+	cfg := &config.Config{
+		Cache: config.CacheConfig{
+			Enabled:         true,
+			MaxSize:         100,
+			TTL:             "1h",
+			CleanupInterval: "10m",
+			EnableStats:     true,
+		},
+	}
+	manager, err := NewAdvancedManager(cfg, metrics.NewEngine(metrics.EngineConfig{EntropyWindowSize: 10}))
+	if err != nil {
+		t.Fatalf("Failed to create AdvancedManager: %v", err)
+	}
+	defer manager.Close()
+
+	if err := manager.RegisterTokenizer("chars", tokenizers.NewCharTokenizer("chars")); err != nil {
+		t.Fatalf("Failed to register tokenizer: %v", err)
+	}
+	if err := manager.RegisterTokenizer("words", tokenizers.NewWhitespaceTokenizer("words")); err != nil {
+		t.Fatalf("Failed to register tokenizer: %v", err)
+	}
+
+	ctx := context.Background()
+	tokenize := func(name, text string) {
+		tokenizer, err := manager.GetTokenizer(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tokenizer.Tokenize(ctx, text); err != nil {
+			t.Fatalf("Tokenize returned error: %v", err)
+		}
+	}
+	// chars hits twice; words misses on the same text, then hits once
+	for i := 0; i < 3; i++ {
+		tokenize("chars", "hello world")
+	}
+	tokenize("words", "hello world")
+	tokenize("words", "hello world")
+
+	tests := []struct {
+		name         string
+		hits, misses int64
+	}{
+		{"chars", 2, 1},
+		{"words", 1, 1},
+	}
+	for _, tt := range tests {
+		stats, err := manager.GetTokenizerCacheStats(tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Hits != tt.hits || stats.Misses != tt.misses {
+			t.Errorf("%s stats = %+v, want %d hits and %d misses", tt.name, stats, tt.hits, tt.misses)
+		}
+	}
+
+	// Both tokenizers' entries live in the one cache
+	total := manager.GetCacheStats()
+	if total.Hits != 3 || total.Misses != 2 || total.Size != 2 {
+		t.Errorf("cache stats = %+v, want 3 hits, 2 misses and 2 entries", total)
+	}
+}
+
+func TestAdvancedManagerPluginsUseTokenizations(t *testing.T) {
+	cfg := &config.Config{
+		Plugins: config.PluginsConfig{
+			Enabled: true,
+		},
+	}
+	engine := metrics.NewEngine(metrics.EngineConfig{EntropyWindowSize: 50})
+
+	manager, err := NewAdvancedManager(cfg, engine)
+	if err != nil {
+		t.Fatalf("Failed to create AdvancedManager: %v", err)
+	}
+	defer manager.Close()
+
+	if err := manager.pluginReg.Register(examples.NewTokenLengthAnalyzer()); err != nil {
+		t.Fatalf("Failed to register plugin: %v", err)
+	}
+
+	mockTokenizer := tokenizers.NewMockTokenizer("mock")
+	mockTokenizer.Initialize(tokenizers.TokenizerConfig{Name: "mock", Type: "custom"})
+	if err := manager.RegisterTokenizer("mock", mockTokenizer); err != nil {
+		t.Fatalf("Failed to register tokenizer: %v", err)
+	}
+
+	texts := []string{
+		"The quick brown fox jumps over the lazy dog.",
+		"Hello, world! This is a test.",
+	}
+	result, err := manager.AnalyzeWithAdvanced(context.Background(), texts, "mock", nil)
+	if err != nil {
+		t.Fatalf("Analysis failed: %v", err)
+	}
+
+	if len(result.PluginResults) != len(texts) {
+		t.Fatalf("PluginResults has %d documents, want %d", len(result.PluginResults), len(texts))
+	}
+	for i, document := range result.PluginResults {
+		if document.Index != i {
+			t.Errorf("PluginResults[%d].Index = %d", i, document.Index)
+		}
+		if len(document.Results["token_length_analyzer"]) == 0 {
+			t.Errorf("document %d has no token length metrics: %+v", i, document)
+		}
+		if len(document.Errors) != 0 {
+			t.Errorf("document %d has plugin errors: %v", i, document.Errors)
+		}
+	}
+
+	found := false
+	for name := range result.Results[0].Metrics {
+		if metrics.IsPluginMetric(name) {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("standard results should include the plugin metrics")
+	}
+}
+
+func TestAdvancedManagerStrategies(t *testing.T) {
+	texts := []string{
+		"The quick brown fox jumps over the lazy dog.",
+		"Hello, world! This is a test.",
+		"Entropy drifts between tokenizers.",
+		"One more document for the batch.",
+	}
+
+	tests := []struct {
+		name     string
+		parallel config.ParallelConfig
+		strategy string
+	}{
+		{"parallel", config.ParallelConfig{Enabled: true, MaxWorkers: 2, BatchSize: 2, Timeout: "1m"}, StrategyParallel},
+		{"standard below batch size", config.ParallelConfig{Enabled: true, MaxWorkers: 2, BatchSize: 10, Timeout: "1m"}, StrategyStandard},
+		{"standard when disabled", config.ParallelConfig{}, StrategyStandard},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, err := NewAdvancedManager(&config.Config{Parallel: tt.parallel}, metrics.NewEngine(metrics.EngineConfig{EntropyWindowSize: 10}))
+			if err != nil {
+				t.Fatalf("Failed to create AdvancedManager: %v", err)
+			}
+			defer manager.Close()
+
+			mockTokenizer := tokenizers.NewMockTokenizer("mock")
+			mockTokenizer.Initialize(tokenizers.TokenizerConfig{Name: "mock", Type: "custom"})
+			if err := manager.RegisterTokenizer("mock", mockTokenizer); err != nil {
+				t.Fatalf("Failed to register tokenizer: %v", err)
+			}
+
+			result, err := manager.AnalyzeWithAdvanced(context.Background(), texts, "mock", nil)
+			if err != nil {
+				t.Fatalf("Analysis failed: %v", err)
+			}
+
+			if result.Strategy != tt.strategy || result.StrategyReason == "" {
+				t.Errorf("strategy = %q (%q), want %q with a reason", result.Strategy, result.StrategyReason, tt.strategy)
+			}
+			if result.Memory == nil || result.Memory.PeakHeapAllocBytes < result.Memory.HeapAllocBeforeBytes {
+				t.Errorf("Memory = %+v, want a peak no lower than the starting heap", result.Memory)
+			}
+			if (result.ParallelStats != nil) != (tt.strategy == StrategyParallel) {
+				t.Errorf("ParallelStats = %+v for the %s strategy", result.ParallelStats, result.Strategy)
+			}
+			if len(result.Results) != len(texts) || len(result.Errors) != 0 {
+				t.Fatalf("got %d results and %d errors, want %d results", len(result.Results), len(result.Errors), len(texts))
+			}
+			for i, document := range result.Results {
+				if document.Document != texts[i] {
+					t.Errorf("Results[%d] is for %q, want document order", i, document.Document)
+				}
+				if _, ok := document.Metrics["entropy_global_entropy"]; !ok {
+					t.Errorf("Results[%d] has no entropy metric: %v", i, document.Metrics)
+				}
+			}
+		})
+	}
+}
+
+func TestRecordStreamingMemory(t *testing.T) {
+	chunks := []*metrics.AnalysisResult{{}, {}}
+
+	tests := []struct {
+		name      string
+		limitMB   int
+		truncated bool
+		peak      uint64
+		warnings  int
+	}{
+		{"within the limit", 4, false, 1 << 20, 0},
+		{"chunk results truncated", 4, true, 1 << 20, 1},
+		{"peak heap over the limit", 4, false, 5 << 20, 1},
+		{"both", 4, true, 5 << 20, 2},
+		{"no limit", 0, true, 5 << 20, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := &AdvancedManager{config: &config.Config{Streaming: config.StreamingConfig{MaxMemoryMB: tt.limitMB}}}
+			result := &AdvancedAnalysisResult{
+				Memory: &MemoryUsage{PeakHeapAllocBytes: tt.peak},
+				StreamingStats: &streaming.StreamResult{
+					BytesRead:     1234,
+					ChunkResults:  chunks,
+					Truncated:     tt.truncated,
+					RetainedBytes: 99,
+				},
+			}
+			manager.recordStreamingMemory(result)
+
+			if len(result.Warnings) != tt.warnings {
+				t.Errorf("warnings = %q, want %d", result.Warnings, tt.warnings)
+			}
+			memory := result.Memory
+			if memory.BytesRead != 1234 || memory.ChunksRetained != len(chunks) || memory.RetainedBytes != 99 {
+				t.Errorf("Memory = %+v, want the streaming figures", memory)
+			}
+		})
+	}
+}
+
+func TestAnalyzeWithStrategy(t *testing.T) {
+	cfg := &config.Config{
+		Parallel: config.ParallelConfig{Enabled: true, MaxWorkers: 2, BatchSize: 100, Timeout: "1m", ProgressInterval: "1ms"},
+	}
+	manager, err := NewAdvancedManager(cfg, metrics.NewEngine(metrics.EngineConfig{EntropyWindowSize: 10}))
+	if err != nil {
+		t.Fatalf("Failed to create AdvancedManager: %v", err)
+	}
+	defer manager.Close()
+
+	mockTokenizer := tokenizers.NewMockTokenizer("mock")
+	mockTokenizer.Initialize(tokenizers.TokenizerConfig{Name: "mock", Type: "custom"})
+	if err := manager.RegisterTokenizer("mock", mockTokenizer); err != nil {
+		t.Fatalf("Failed to register tokenizer: %v", err)
+	}
+	texts := []string{"one small document", "and another one"}
+
+	// Two documents fit in the batch size, but parallel was asked for
+	var last Progress
+	result, err := manager.AnalyzeWithStrategy(context.Background(), texts, "mock", StrategyParallel, func(p Progress) {
+		last = p
+	})
+	if err != nil {
+		t.Fatalf("Analysis failed: %v", err)
+	}
+	if result.Strategy != StrategyParallel || len(result.Results) != len(texts) {
+		t.Errorf("strategy %q with %d results, want parallel with %d", result.Strategy, len(result.Results), len(texts))
+	}
+	if last.Strategy != StrategyParallel || last.Processed != len(texts) || last.Total != len(texts) {
+		t.Errorf("last progress = %+v, want every document processed", last)
+	}
+
+	if _, err := manager.AnalyzeWithStrategy(context.Background(), texts, "mock", StrategyStreaming, nil); !errors.Is(err, ErrStrategyDisabled) {
+		t.Errorf("forcing disabled streaming returned %v, want ErrStrategyDisabled", err)
+	}
+	if _, err := manager.AnalyzeWithStrategy(context.Background(), texts, "mock", "quantum", nil); err == nil {
+		t.Error("expected an error for an unknown strategy")
+	}
+}
@@ -0,0 +1,86 @@
+package advanced
+
+import (
+	"runtime"
+	"time"
+)
+
+// memorySampleInterval is how often the heap is sampled for its peak during a run
+const memorySampleInterval = 50 * time.Millisecond
+
+// MemoryUsage is what one analysis cost in memory. The heap figures cover the whole
+// process, so concurrent work elsewhere shows up in them; the peak is sampled, so a
+// spike shorter than the sampling interval can be missed.
+type MemoryUsage struct {
+	HeapAllocBeforeBytes uint64 `json:"heap_alloc_before_bytes"`
+	HeapAllocAfterBytes  uint64 `json:"heap_alloc_after_bytes"`
+	PeakHeapAllocBytes   uint64 `json:"peak_heap_alloc_bytes"`
+	TotalAllocBytes      uint64 `json:"total_alloc_bytes"` // allocated during the run, including what was since freed
+	NumGC                uint32 `json:"num_gc"`            // garbage collections during the run
+
+	// Streaming runs only
+	BytesRead      int64 `json:"bytes_read,omitempty"`
+	ChunksRetained int   `json:"chunks_retained,omitempty"`
+	RetainedBytes  int64 `json:"retained_bytes,omitempty"` // estimated size of the retained chunk results
+}
+
+// memorySampler tracks the peak heap of a run from a ticker goroutine. Only that
+// goroutine touches peak until finish has stopped it.
+type memorySampler struct {
+	before runtime.MemStats
+	peak   uint64
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// startMemorySampler records the heap before a run and starts sampling its peak
+func startMemorySampler() *memorySampler {
+	s := &memorySampler{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	runtime.ReadMemStats(&s.before)
+	s.peak = s.before.HeapAlloc
+
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(memorySampleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				var stats runtime.MemStats
+				runtime.ReadMemStats(&stats)
+				s.record(stats.HeapAlloc)
+			}
+		}
+	}()
+	return s
+}
+
+// record raises the peak to heapAlloc if it is higher
+func (s *memorySampler) record(heapAlloc uint64) {
+	if heapAlloc > s.peak {
+		s.peak = heapAlloc
+	}
+}
+
+// finish stops sampling and returns the run's memory usage
+func (s *memorySampler) finish() *MemoryUsage {
+	close(s.stop)
+	<-s.done
+
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+	s.record(after.HeapAlloc)
+
+	return &MemoryUsage{
+		HeapAllocBeforeBytes: s.before.HeapAlloc,
+		HeapAllocAfterBytes:  after.HeapAlloc,
+		PeakHeapAllocBytes:   s.peak,
+		TotalAllocBytes:      after.TotalAlloc - s.before.TotalAlloc,
+		NumGC:                after.NumGC - s.before.NumGC,
+	}
+}
@@ -0,0 +1,183 @@
+// Package benchmarks measures tokenizer throughput and latency over a corpus
+package benchmarks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/stats"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// CacheMode selects how a caching tokenizer's cache is treated during a benchmark
+type CacheMode string
+
+const (
+	// CacheDefault leaves the cache, if any, alone
+	CacheDefault CacheMode = ""
+	// CacheCold clears the cache before each measured pass, so only repeats within a
+	// pass are hits
+	CacheCold CacheMode = "cold"
+	// CacheWarm fills the cache during warmup, so every measured call is a hit
+	CacheWarm CacheMode = "warm"
+)
+
+// Options configures a tokenizer benchmark
+type Options struct {
+	Warmup   int           // untimed passes over the corpus before measuring
+	Duration time.Duration // passes repeat until this much time is measured; at least one is made
+	Cache    CacheMode     // requires a tokenizer with a cache, such as a CachedTokenizer
+}
+
+// DefaultOptions returns the options the CLI benchmarks with
+func DefaultOptions() Options {
+	return Options{
+		Warmup:   1,
+		Duration: 10 * time.Second,
+	}
+}
+
+// Result holds the measurements of one tokenizer over a corpus
+type Result struct {
+	Tokenizer string    `json:"tokenizer"`
+	Cache     CacheMode `json:"cache,omitempty"`
+	Passes    int       `json:"passes"`
+	Documents int       `json:"documents"` // documents tokenized while measuring
+	Tokens    int64     `json:"tokens"`
+
+	Duration           time.Duration `json:"duration"` // time spent tokenizing, excluding warmup and cache clears
+	DocumentsPerSecond float64       `json:"documents_per_second"`
+	TokensPerSecond    float64       `json:"tokens_per_second"`
+	LatencyP50         time.Duration `json:"latency_p50"` // per document
+	LatencyP95         time.Duration `json:"latency_p95"`
+	PeakRSSBytes       uint64        `json:"peak_rss_bytes"` // of the whole process; 0 where unavailable
+}
+
+// maxLatencySamples bounds the latencies kept for percentiles, so a long benchmark of a
+// fast tokenizer does not inflate the memory it measures
+const maxLatencySamples = 100000
+
+// cacheClearer is implemented by tokenizers with a cache that can be emptied
+type cacheClearer interface {
+	ClearCache()
+}
+
+// RunTokenizerBenchmark tokenizes the corpus with tokenizer, first for the warmup
+// passes and then repeatedly until the options' duration has been measured
+func RunTokenizerBenchmark(ctx context.Context, tokenizer tokenizers.Tokenizer, corpus []string, opts Options) (*Result, error) {
+	if len(corpus) == 0 {
+		return nil, errors.New("benchmark corpus is empty")
+	}
+	clearer, cached := tokenizer.(cacheClearer)
+	if opts.Cache != CacheDefault && !cached {
+		return nil, fmt.Errorf("tokenizer %s has no cache to benchmark %s", tokenizer.Name(), opts.Cache)
+	}
+
+	warmup := opts.Warmup
+	switch opts.Cache {
+	case CacheDefault:
+	case CacheCold:
+		clearer.ClearCache()
+	case CacheWarm:
+		// The cache is only warm after a pass over the corpus
+		clearer.ClearCache()
+		warmup = max(warmup, 1)
+	default:
+		return nil, fmt.Errorf("unknown cache mode %q", opts.Cache)
+	}
+
+	for i := 0; i < warmup; i++ {
+		if _, _, err := runPass(ctx, tokenizer, corpus, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	resetPeakRSS()
+	result := &Result{Tokenizer: tokenizer.Name(), Cache: opts.Cache}
+	latencies := &latencySample{random: rand.New(rand.NewSource(1))}
+	for result.Passes == 0 || result.Duration < opts.Duration {
+		if opts.Cache == CacheCold {
+			clearer.ClearCache()
+		}
+		tokens, elapsed, err := runPass(ctx, tokenizer, corpus, latencies)
+		if err != nil {
+			return nil, err
+		}
+		result.Passes++
+		result.Documents += len(corpus)
+		result.Tokens += tokens
+		result.Duration += elapsed
+	}
+	result.PeakRSSBytes = peakRSS()
+
+	if seconds := result.Duration.Seconds(); seconds > 0 {
+		result.DocumentsPerSecond = float64(result.Documents) / seconds
+		result.TokensPerSecond = float64(result.Tokens) / seconds
+	}
+	percentiles := stats.Percentiles(latencies.values, 50, 95)
+	result.LatencyP50 = time.Duration(percentiles[0])
+	result.LatencyP95 = time.Duration(percentiles[1])
+	return result, nil
+}
+
+// CompareCache benchmarks a caching tokenizer cold and then warm, to show how much its
+// cache saves on the corpus
+func CompareCache(ctx context.Context, tokenizer *tokenizers.CachedTokenizer, corpus []string, opts Options) ([]*Result, error) {
+	var results []*Result
+	for _, mode := range []CacheMode{CacheCold, CacheWarm} {
+		opts.Cache = mode
+		result, err := RunTokenizerBenchmark(ctx, tokenizer, corpus, opts)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// runPass tokenizes each document of the corpus once, returning the token count and
+// the time spent tokenizing. Each document's latency is added to latencies when it is
+// not nil.
+func runPass(ctx context.Context, tokenizer tokenizers.Tokenizer, corpus []string, latencies *latencySample) (int64, time.Duration, error) {
+	var tokens int64
+	var elapsed time.Duration
+	for i, text := range corpus {
+		if err := ctx.Err(); err != nil {
+			return 0, 0, err
+		}
+		start := time.Now()
+		result, err := tokenizer.Tokenize(ctx, text)
+		latency := time.Since(start)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to tokenize document %d with %s: %w", i, tokenizer.Name(), err)
+		}
+		tokens += int64(len(result.Tokens))
+		elapsed += latency
+		if latencies != nil {
+			latencies.add(latency)
+		}
+	}
+	return tokens, elapsed, nil
+}
+
+// latencySample is a uniform sample of at most maxLatencySamples latencies
+type latencySample struct {
+	values []time.Duration
+	seen   int
+	random *rand.Rand
+}
+
+// add offers a latency to the sample, replacing a random kept one once it is full
+func (l *latencySample) add(latency time.Duration) {
+	l.seen++
+	if len(l.values) < maxLatencySamples {
+		l.values = append(l.values, latency)
+		return
+	}
+	if i := l.random.Intn(l.seen); i < maxLatencySamples {
+		l.values[i] = latency
+	}
+}
@@ -0,0 +1,152 @@
+package benchmarks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/cache"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+var testCorpus = []string{"the quick brown fox", "jumps over", "the lazy dog"}
+
+// countingTokenizer counts the documents that reach the underlying tokenizer
+type countingTokenizer struct {
+	*tokenizers.MockTokenizer
+	calls int
+}
+
+func (c *countingTokenizer) Tokenize(ctx context.Context, text string) (*tokenizers.TokenizationResult, error) {
+	c.calls++
+	return c.MockTokenizer.Tokenize(ctx, text)
+}
+
+func newCountingTokenizer(t *testing.T) *countingTokenizer {
+	mock := tokenizers.NewMockTokenizer("mock")
+	if err := mock.Initialize(tokenizers.TokenizerConfig{Name: "mock", Type: "custom"}); err != nil {
+		t.Fatalf("failed to initialize mock tokenizer: %v", err)
+	}
+	return &countingTokenizer{MockTokenizer: mock}
+}
+
+func TestRunTokenizerBenchmark(t *testing.T) {
+	tokenizer := newCountingTokenizer(t)
+	result, err := RunTokenizerBenchmark(context.Background(), tokenizer, testCorpus, Options{Warmup: 2})
+	if err != nil {
+		t.Fatalf("RunTokenizerBenchmark returned error: %v", err)
+	}
+
+	// Without a duration a single pass is measured, after the warmup passes
+	if result.Passes != 1 || result.Documents != 3 || result.Tokens != 9 {
+		t.Errorf("result = %+v, want 1 pass over 3 documents and 9 tokens", result)
+	}
+	if tokenizer.calls != 9 {
+		t.Errorf("tokenizer called %d times, want 9 with 2 warmup passes", tokenizer.calls)
+	}
+	if result.DocumentsPerSecond <= 0 || math.Abs(result.TokensPerSecond/result.DocumentsPerSecond-3) > 1e-9 {
+		t.Errorf("throughput = %v docs/s, %v tokens/s", result.DocumentsPerSecond, result.TokensPerSecond)
+	}
+	if result.LatencyP50 <= 0 || result.LatencyP95 < result.LatencyP50 {
+		t.Errorf("latency p50 = %v, p95 = %v", result.LatencyP50, result.LatencyP95)
+	}
+}
+
+func TestRunTokenizerBenchmarkDuration(t *testing.T) {
+	result, err := RunTokenizerBenchmark(context.Background(), newCountingTokenizer(t), testCorpus, Options{Duration: time.Millisecond})
+	if err != nil {
+		t.Fatalf("RunTokenizerBenchmark returned error: %v", err)
+	}
+	if result.Duration < time.Millisecond || result.Documents != result.Passes*3 {
+		t.Errorf("measured %v over %d passes and %d documents, want at least 1ms of whole passes", result.Duration, result.Passes, result.Documents)
+	}
+}
+
+func TestRunTokenizerBenchmarkErrors(t *testing.T) {
+	ctx := context.Background()
+	if _, err := RunTokenizerBenchmark(ctx, newCountingTokenizer(t), nil, Options{}); err == nil {
+		t.Error("expected an error for an empty corpus")
+	}
+	if _, err := RunTokenizerBenchmark(ctx, newCountingTokenizer(t), testCorpus, Options{Cache: CacheWarm}); err == nil {
+		t.Error("expected an error for a cache mode on a tokenizer without a cache")
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := RunTokenizerBenchmark(canceled, newCountingTokenizer(t), testCorpus, Options{}); err == nil {
+		t.Error("expected an error for a canceled context")
+	}
+}
+
+func TestCompareCache(t *testing.T) {
+	underlying := newCountingTokenizer(t)
+	cached := tokenizers.NewCachedTokenizer(underlying, cache.CacheConfig{MaxSize: 100})
+	defer cached.Close()
+
+	results, err := CompareCache(context.Background(), cached, testCorpus, Options{})
+	if err != nil {
+		t.Fatalf("CompareCache returned error: %v", err)
+	}
+	if len(results) != 2 || results[0].Cache != CacheCold || results[1].Cache != CacheWarm {
+		t.Fatalf("results = %+v, want a cold and a warm result", results)
+	}
+	// Cold tokenizes every document; warm tokenizes them once while warming up and
+	// then only hits the cache
+	if underlying.calls != 6 {
+		t.Errorf("underlying tokenizer called %d times, want 6", underlying.calls)
+	}
+	if results[1].Tokens != results[0].Tokens {
+		t.Errorf("warm counted %d tokens, cold %d", results[1].Tokens, results[0].Tokens)
+	}
+}
+
+func TestLatencySample(t *testing.T) {
+	sample := &latencySample{random: rand.New(rand.NewSource(1))}
+	for i := 0; i < maxLatencySamples*2; i++ {
+		sample.add(time.Duration(i))
+	}
+	if len(sample.values) != maxLatencySamples || sample.seen != maxLatencySamples*2 {
+		t.Fatalf("kept %d of %d latencies, want %d", len(sample.values), sample.seen, maxLatencySamples)
+	}
+	replaced := 0
+	for _, value := range sample.values {
+		if value >= maxLatencySamples {
+			replaced++
+		}
+	}
+	// About half of the kept latencies should come from the second half
+	if replaced < maxLatencySamples/3 || replaced > maxLatencySamples*2/3 {
+		t.Errorf("%d kept latencies from the second half, want about half", replaced)
+	}
+}
+
+func TestReport(t *testing.T) {
+	results := []*Result{
+		{Tokenizer: "gpt2", DocumentsPerSecond: 2000, TokensPerSecond: 50000, LatencyP50: 400 * time.Microsecond, LatencyP95: 1500 * time.Microsecond, PeakRSSBytes: 64 << 20},
+		{Tokenizer: "cached_gpt2", Cache: CacheWarm, DocumentsPerSecond: 4000, TokensPerSecond: 100000, LatencyP50: 2 * time.Second},
+	}
+
+	table := Table(results)
+	for _, want := range []string{
+		"| gpt2 | - | 2000.0 | 50000.0 | 400µs | 1.5ms | 64.0 MiB | 0.50x |",
+		"| cached_gpt2 | warm | 4000.0 | 100000.0 | 2s | 0ns | - | 1.00x |",
+	} {
+		if !strings.Contains(table, want) {
+			t.Errorf("table is missing row %q:\n%s", want, table)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, results); err != nil {
+		t.Fatalf("WriteJSON returned error: %v", err)
+	}
+	var decoded []*Result
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded) != 2 || decoded[1].Cache != CacheWarm {
+		t.Errorf("WriteJSON wrote %s (%v)", buf.String(), err)
+	}
+}
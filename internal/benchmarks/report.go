@@ -0,0 +1,69 @@
+package benchmarks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// WriteJSON writes results to w as an indented JSON array
+func WriteJSON(w io.Writer, results []*Result) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(results); err != nil {
+		return fmt.Errorf("failed to write benchmark results: %w", err)
+	}
+	return nil
+}
+
+// Table renders results as a Markdown comparison table, one row per tokenizer and
+// cache mode. The relative column compares throughput with the fastest row.
+func Table(results []*Result) string {
+	var fastest float64
+	for _, result := range results {
+		fastest = max(fastest, result.DocumentsPerSecond)
+	}
+
+	var b strings.Builder
+	b.WriteString("| Tokenizer | Cache | Docs/s | Tokens/s | p50 | p95 | Peak RSS | Relative |\n")
+	b.WriteString("|---|---|---:|---:|---:|---:|---:|---:|\n")
+	for _, result := range results {
+		cache := string(result.Cache)
+		if cache == "" {
+			cache = "-"
+		}
+		relative := "-"
+		if fastest > 0 {
+			relative = fmt.Sprintf("%.2fx", result.DocumentsPerSecond/fastest)
+		}
+		fmt.Fprintf(&b, "| %s | %s | %.1f | %.1f | %s | %s | %s | %s |\n",
+			result.Tokenizer, cache, result.DocumentsPerSecond, result.TokensPerSecond,
+			formatLatency(result.LatencyP50), formatLatency(result.LatencyP95),
+			formatBytes(result.PeakRSSBytes), relative)
+	}
+	return b.String()
+}
+
+// formatLatency formats a latency with three significant digits in a unit that suits it
+func formatLatency(latency time.Duration) string {
+	switch {
+	case latency >= time.Second:
+		return fmt.Sprintf("%.3gs", latency.Seconds())
+	case latency >= time.Millisecond:
+		return fmt.Sprintf("%.3gms", float64(latency)/float64(time.Millisecond))
+	case latency >= time.Microsecond:
+		return fmt.Sprintf("%.3gµs", float64(latency)/float64(time.Microsecond))
+	default:
+		return fmt.Sprintf("%dns", latency.Nanoseconds())
+	}
+}
+
+// formatBytes formats a byte count in MiB, or "-" when unknown
+func formatBytes(bytes uint64) string {
+	if bytes == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f MiB", float64(bytes)/(1<<20))
+}
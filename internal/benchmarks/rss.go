@@ -0,0 +1,39 @@
+package benchmarks
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// resetPeakRSS resets the kernel's record of the process's peak resident set size, so
+// the next reading covers only what follows. Where that is unsupported the peak covers
+// the whole process lifetime.
+func resetPeakRSS() {
+	_ = os.WriteFile("/proc/self/clear_refs", []byte("5"), 0)
+}
+
+// peakRSS returns the process's peak resident set size in bytes from /proc, or 0
+// where it is unavailable
+func peakRSS() uint64 {
+	file, err := os.Open("/proc/self/status")
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// VmHWM:     12345 kB
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 3 && fields[0] == "VmHWM:" && fields[2] == "kB" {
+			kilobytes, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0
+			}
+			return kilobytes * 1024
+		}
+	}
+	return 0
+}
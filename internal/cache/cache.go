@@ -0,0 +1,301 @@
+// Package cache provides the in-memory cache tokenizations and analysis results are
+// kept in, with TTL expiry, entry and byte limits and least-recently-used eviction
+package cache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+// DefaultShards is the number of shards of a cache configured without one
+const DefaultShards = 16
+
+// entryOverhead approximates the bytes an entry takes besides its key and value: the
+// entry, its list element and its map slot
+const entryOverhead = 128
+
+// CacheConfig holds the configuration of a cache. The limits are split evenly between
+// the shards, each evicting its own least recently used entries, so the cache as a
+// whole evicts in approximately LRU order.
+type CacheConfig struct {
+	MaxSize         int           `json:"max_size"`         // entries kept; 0 or less keeps any number
+	MaxBytes        int64         `json:"max_bytes"`        // approximate bytes kept; 0 or less keeps any amount
+	Shards          int           `json:"shards"`           // independently locked parts; 0 or less uses DefaultShards
+	TTL             time.Duration `json:"ttl"`              // how long an entry lives; 0 keeps entries until evicted
+	CleanupInterval time.Duration `json:"cleanup_interval"` // how often expired entries are dropped; 0 drops them on access only
+	EnableStats     bool          `json:"enable_stats"`     // count hits, misses and evictions
+}
+
+// CacheStats holds cache statistics. Hits, misses and evictions are counted only when
+// the cache is configured with EnableStats.
+type CacheStats struct {
+	Hits      int64        `json:"hits"`
+	Misses    int64        `json:"misses"`
+	Evictions int64        `json:"evictions"`
+	Size      int          `json:"size"`
+	MaxSize   int          `json:"max_size"`
+	Bytes     int64        `json:"bytes"`
+	MaxBytes  int64        `json:"max_bytes"`
+	Shards    []ShardStats `json:"shards,omitempty"`
+}
+
+// ShardStats holds the statistics of one shard of a cache
+type ShardStats struct {
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+	Size      int   `json:"size"`
+	Bytes     int64 `json:"bytes"`
+}
+
+// Sizer is implemented by values that know their approximate size in bytes, by which
+// the cache bounds its memory. Other values count as their key and a fixed overhead.
+type Sizer interface {
+	CacheSize() int64
+}
+
+// CacheEntry is a cached value
+type CacheEntry struct {
+	Key       string
+	Value     interface{}
+	Size      int64     // approximate bytes, the key and overhead included
+	ExpiresAt time.Time // zero if the entry never expires
+}
+
+// expired reports whether the entry has expired at now
+func (e *CacheEntry) expired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && now.After(e.ExpiresAt)
+}
+
+// Cache is an in-memory cache safe for concurrent use. Keys are spread over shards
+// with locks of their own, so concurrent callers rarely wait for each other.
+type Cache struct {
+	config CacheConfig
+	seed   maphash.Seed
+	shards []*shard
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// shard is a part of a cache, evicting its least recently used entries when over its
+// share of the limits
+type shard struct {
+	mu       sync.Mutex
+	data     map[string]*list.Element // of *CacheEntry
+	order    *list.List               // most recently used first
+	maxSize  int
+	maxBytes int64
+	stats    ShardStats
+}
+
+// NewCache creates a cache, which starts dropping expired entries in the background if
+// it has both a TTL and a cleanup interval. Close stops it.
+func NewCache(config CacheConfig) *Cache {
+	count := config.Shards
+	if count <= 0 {
+		count = DefaultShards
+	}
+	// Every shard holds at least one entry, so a small cache has fewer shards
+	if config.MaxSize > 0 && config.MaxSize < count {
+		count = config.MaxSize
+	}
+
+	c := &Cache{
+		config: config,
+		seed:   maphash.MakeSeed(),
+		shards: make([]*shard, count),
+		stop:   make(chan struct{}),
+	}
+	for i := range c.shards {
+		c.shards[i] = &shard{
+			data:     make(map[string]*list.Element),
+			order:    list.New(),
+			maxSize:  int(share(int64(config.MaxSize), count, i)),
+			maxBytes: share(config.MaxBytes, count, i),
+		}
+	}
+	if config.TTL > 0 && config.CleanupInterval > 0 {
+		go c.cleanup()
+	}
+	return c
+}
+
+// share returns shard i's part of limit split between count shards, the first shards
+// taking the remainder so the parts add up to it; a limit of 0 or less stays 0
+func share(limit int64, count, i int) int64 {
+	if limit <= 0 {
+		return 0
+	}
+	part := limit / int64(count)
+	if int64(i) < limit%int64(count) {
+		part++
+	}
+	return part
+}
+
+// GenerateKey returns a cache key for parts, which are hashed so that keys stay short
+// however long the text they are derived from
+func GenerateKey(parts ...string) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		hash.Write([]byte(part))
+		// Separate the parts, so that ("ab", "c") and ("a", "bc") differ
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// shardFor returns the shard holding key
+func (c *Cache) shardFor(key string) *shard {
+	if len(c.shards) == 1 {
+		return c.shards[0]
+	}
+	return c.shards[maphash.String(c.seed, key)%uint64(len(c.shards))]
+}
+
+// Get retrieves a value from the cache
+func (c *Cache) Get(key string) (interface{}, bool) {
+	s := c.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, ok := s.data[key]
+	if ok && element.Value.(*CacheEntry).expired(time.Now()) {
+		s.remove(element)
+		ok = false
+	}
+	if !ok {
+		if c.config.EnableStats {
+			s.stats.Misses++
+		}
+		return nil, false
+	}
+
+	s.order.MoveToFront(element)
+	if c.config.EnableStats {
+		s.stats.Hits++
+	}
+	return element.Value.(*CacheEntry).Value, true
+}
+
+// Set stores a value in the cache, evicting the least recently used entries of its
+// shard while the shard is over its share of the limits. A value larger than the
+// shard's share of MaxBytes is not cached.
+func (c *Cache) Set(key string, value interface{}) {
+	entry := &CacheEntry{Key: key, Value: value, Size: int64(len(key)) + entryOverhead}
+	if sized, ok := value.(Sizer); ok {
+		entry.Size += sized.CacheSize()
+	}
+	if c.config.TTL > 0 {
+		entry.ExpiresAt = time.Now().Add(c.config.TTL)
+	}
+
+	s := c.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.data[key]; ok {
+		s.remove(element)
+	}
+	if s.maxBytes > 0 && entry.Size > s.maxBytes {
+		return
+	}
+	s.data[key] = s.order.PushFront(entry)
+	s.stats.Size++
+	s.stats.Bytes += entry.Size
+
+	for (s.maxSize > 0 && s.stats.Size > s.maxSize) || (s.maxBytes > 0 && s.stats.Bytes > s.maxBytes) {
+		s.remove(s.order.Back())
+		if c.config.EnableStats {
+			s.stats.Evictions++
+		}
+	}
+}
+
+// Delete removes a value from the cache
+func (c *Cache) Delete(key string) {
+	s := c.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if element, ok := s.data[key]; ok {
+		s.remove(element)
+	}
+}
+
+// Clear removes every value from the cache
+func (c *Cache) Clear() {
+	for _, s := range c.shards {
+		s.mu.Lock()
+		s.data = make(map[string]*list.Element)
+		s.order.Init()
+		s.stats.Size, s.stats.Bytes = 0, 0
+		s.mu.Unlock()
+	}
+}
+
+// GetStats returns the statistics of the whole cache and of each shard
+func (c *Cache) GetStats() CacheStats {
+	stats := CacheStats{
+		MaxSize:  c.config.MaxSize,
+		MaxBytes: c.config.MaxBytes,
+		Shards:   make([]ShardStats, len(c.shards)),
+	}
+	for i, s := range c.shards {
+		s.mu.Lock()
+		shardStats := s.stats
+		s.mu.Unlock()
+
+		stats.Shards[i] = shardStats
+		stats.Hits += shardStats.Hits
+		stats.Misses += shardStats.Misses
+		stats.Evictions += shardStats.Evictions
+		stats.Size += shardStats.Size
+		stats.Bytes += shardStats.Bytes
+	}
+	return stats
+}
+
+// Close stops the cache and cleans up resources. The cache stays usable, but expired
+// entries are then dropped only when accessed.
+func (c *Cache) Close() {
+	c.closeOnce.Do(func() { close(c.stop) })
+}
+
+// remove removes element from the shard; s.mu must be held
+func (s *shard) remove(element *list.Element) {
+	entry := element.Value.(*CacheEntry)
+	s.order.Remove(element)
+	delete(s.data, entry.Key)
+	s.stats.Size--
+	s.stats.Bytes -= entry.Size
+}
+
+// cleanup drops expired entries every cleanup interval until the cache is closed
+func (c *Cache) cleanup() {
+	ticker := time.NewTicker(c.config.CleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case now := <-ticker.C:
+			// One shard at a time, so lookups in the others go on meanwhile
+			for _, s := range c.shards {
+				s.mu.Lock()
+				for element := s.order.Back(); element != nil; {
+					previous := element.Prev()
+					if element.Value.(*CacheEntry).expired(now) {
+						s.remove(element)
+					}
+					element = previous
+				}
+				s.mu.Unlock()
+			}
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package cache
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// sized is a value of a given size in bytes
+type sized int64
+
+func (s sized) CacheSize() int64 { return int64(s) }
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewCache(CacheConfig{MaxSize: 2, Shards: 1, EnableStats: true})
+	defer c.Close()
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	// Using a makes b the least recently used
+	if value, ok := c.Get("a"); !ok || value != 1 {
+		t.Fatalf("Get(a) = %v, %v, want 1, true", value, ok)
+	}
+	c.Set("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b should have been evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("%s should still be cached", key)
+		}
+	}
+
+	stats := c.GetStats()
+	stats.Shards = nil
+	want := CacheStats{Hits: 3, Misses: 1, Evictions: 1, Size: 2, MaxSize: 2, Bytes: 2 * (1 + entryOverhead)}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("GetStats() = %+v, want %+v", stats, want)
+	}
+}
+
+func TestCacheEvictsBySize(t *testing.T) {
+	entry := int64(1 + entryOverhead)
+	c := NewCache(CacheConfig{MaxBytes: 1000 + 2*entry, Shards: 1, EnableStats: true})
+	defer c.Close()
+
+	c.Set("a", sized(500))
+	c.Set("b", sized(500))
+	if stats := c.GetStats(); stats.Size != 2 || stats.Bytes != 1000+2*entry {
+		t.Fatalf("GetStats() = %+v, want 2 entries of %d bytes", stats, 1000+2*entry)
+	}
+	// c takes the room of a, the least recently used
+	c.Set("c", sized(400))
+	if _, ok := c.Get("a"); ok {
+		t.Error("a should have been evicted")
+	}
+	if stats := c.GetStats(); stats.Size != 2 || stats.Bytes != 900+2*entry || stats.Evictions != 1 {
+		t.Errorf("GetStats() = %+v, want 2 entries of %d bytes and 1 eviction", stats, 900+2*entry)
+	}
+
+	// A value larger than the whole budget is not cached, and evicts nothing
+	c.Set("d", sized(5000))
+	if _, ok := c.Get("d"); ok {
+		t.Error("d is over the byte limit and should not be cached")
+	}
+	if size := c.GetStats().Size; size != 2 {
+		t.Errorf("cache has %d entries, want 2", size)
+	}
+
+	// Replacing a value accounts for its new size
+	c.Set("b", sized(100))
+	if bytes := c.GetStats().Bytes; bytes != 500+2*entry {
+		t.Errorf("cache holds %d bytes, want %d", bytes, 500+2*entry)
+	}
+}
+
+func TestCacheShards(t *testing.T) {
+	c := NewCache(CacheConfig{EnableStats: true})
+	defer c.Close()
+	for i := 0; i < 100; i++ {
+		c.Set(fmt.Sprint(i), i)
+		c.Get(fmt.Sprint(i))
+	}
+
+	stats := c.GetStats()
+	if len(stats.Shards) != DefaultShards {
+		t.Fatalf("got %d shards, want %d", len(stats.Shards), DefaultShards)
+	}
+	var total ShardStats
+	used := 0
+	for _, shard := range stats.Shards {
+		total.Hits += shard.Hits
+		total.Size += shard.Size
+		total.Bytes += shard.Bytes
+		if shard.Size > 0 {
+			used++
+		}
+	}
+	if total.Hits != stats.Hits || total.Size != stats.Size || total.Bytes != stats.Bytes {
+		t.Errorf("shard totals %+v do not add up to %+v", total, stats)
+	}
+	if stats.Hits != 100 || stats.Size != 100 {
+		t.Errorf("got %d hits and %d entries, want 100 of each", stats.Hits, stats.Size)
+	}
+	if used < DefaultShards/2 {
+		t.Errorf("only %d of %d shards hold entries", used, DefaultShards)
+	}
+
+	// A cache holding fewer entries than the default has as many shards
+	if shards := len(NewCache(CacheConfig{MaxSize: 3}).GetStats().Shards); shards != 3 {
+		t.Errorf("cache of 3 entries has %d shards, want 3", shards)
+	}
+}
+
+func TestCacheExpiresEntries(t *testing.T) {
+	c := NewCache(CacheConfig{TTL: 10 * time.Millisecond, CleanupInterval: 5 * time.Millisecond})
+	defer c.Close()
+
+	c.Set("a", 1)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("a should be cached before its TTL")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if size := c.GetStats().Size; size != 0 {
+		t.Errorf("cleanup left %d entries, want 0", size)
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Error("a should have expired")
+	}
+}
+
+func TestCacheClearAndDelete(t *testing.T) {
+	c := NewCache(CacheConfig{})
+	defer c.Close()
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Delete("a")
+	if _, ok := c.Get("a"); ok {
+		t.Error("a should have been deleted")
+	}
+	c.Clear()
+	if size := c.GetStats().Size; size != 0 {
+		t.Errorf("Clear left %d entries, want 0", size)
+	}
+	// Closing twice is harmless
+	c.Close()
+}
+
+func TestGenerateKey(t *testing.T) {
+	if GenerateKey("ab", "c") == GenerateKey("a", "bc") {
+		t.Error("keys of differently split parts should differ")
+	}
+	if GenerateKey("a", "b") != GenerateKey("a", "b") {
+		t.Error("keys of the same parts should be equal")
+	}
+}
+
+// BenchmarkCacheContention compares one lock with the default shards under concurrent
+// lookups, most of them hits, as parallel workers tokenizing a corpus make. Lock
+// contention only arises with several cores, so run it with -cpu above one; on a
+// single core the two configurations measure the same.
+func BenchmarkCacheContention(b *testing.B) {
+	keys := make([]string, 4096)
+	for i := range keys {
+		keys[i] = GenerateKey(fmt.Sprint(i))
+	}
+	value := strings.Repeat("x", 64)
+
+	for _, shards := range []int{1, DefaultShards} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			c := NewCache(CacheConfig{MaxSize: 2 * len(keys), Shards: shards, EnableStats: true})
+			defer c.Close()
+			for _, key := range keys {
+				c.Set(key, value)
+			}
+			var next atomic.Int64
+			b.SetParallelism(4)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := int(next.Add(7919))
+				for pb.Next() {
+					key := keys[i%len(keys)]
+					if _, ok := c.Get(key); !ok || i%10 == 0 {
+						c.Set(key, value)
+					}
+					i++
+				}
+			})
+		})
+	}
+}
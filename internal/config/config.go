@@ -1,9 +1,19 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/loader"
+	"github.com/RevBooyah/TokEntropyDrift/internal/logger"
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+	"github.com/spf13/viper"
 )
 
 // Config represents the main application configuration
@@ -19,42 +29,208 @@ type Config struct {
 	Visualization VisualizationConfig `mapstructure:"visualization"`
 	Server        ServerConfig        `mapstructure:"server"`
 	Logging       LoggingConfig       `mapstructure:"logging"`
+	Diff          DiffConfig          `mapstructure:"diff"`
+
+	// Analysis parameters replaced for a tokenizer, by tokenizer name
+	AnalysisOverrides map[string]AnalysisOverride `mapstructure:"analysis_overrides"`
 }
 
 // InputConfig holds input file configuration
 type InputConfig struct {
 	SourcePaths []string `mapstructure:"source_paths"`
 	FileType    string   `mapstructure:"file_type"`
+	SplitMode   string   `mapstructure:"split_mode"` // line, paragraph, file or delimiter; text files only
+	Delimiter   string   `mapstructure:"delimiter"`  // document separator line in delimiter mode
+	Include     []string `mapstructure:"include"`    // globs selecting the files of directories and archives
+	Exclude     []string `mapstructure:"exclude"`    // globs skipping files of directories and archives
+
+	// JSONL field extraction; paths look like messages.0.content or messages.*.content
+	JSONLTextFields     []string `mapstructure:"jsonl_text_field"`      // path, or paths joined in order; empty uses text or content
+	JSONLTextSeparator  string   `mapstructure:"jsonl_text_separator"`  // joins the values of several text fields
+	JSONLStrict         bool     `mapstructure:"jsonl_strict"`          // error on a line without the text field instead of using the raw line
+	JSONLMetadataFields []string `mapstructure:"jsonl_metadata_fields"` // paths kept as metadata; empty keeps every top-level field
+
+	// CSV and TSV parsing
+	CSVDelimiter       string `mapstructure:"csv_delimiter"`         // single character, or "\t"; empty uses a comma, or a tab for TSV
+	CSVContentColumn   string `mapstructure:"csv_content_column"`    // header name or 0-based index; empty uses text, content or the first column
+	CSVHasHeader       bool   `mapstructure:"csv_has_header"`        // first row names the columns
+	CSVLazyQuotes      bool   `mapstructure:"csv_lazy_quotes"`       // tolerate stray quotes
+	CSVSkipRows        int    `mapstructure:"csv_skip_rows"`         // data rows skipped before reading
+	CSVMaxRows         int    `mapstructure:"csv_max_rows"`          // data rows read at most; 0 reads all
+	CSVSkipInvalidRows bool   `mapstructure:"csv_skip_invalid_rows"` // skip malformed rows instead of failing
+
+	// Document filtering and sampling, applied to every file type; 0 disables a limit
+	Dedup         bool  `mapstructure:"dedup"`           // drop documents repeating an earlier document's text
+	MinChars      int   `mapstructure:"min_chars"`       // shortest document kept, in characters
+	MaxChars      int   `mapstructure:"max_chars"`       // longest document kept, in characters
+	MaxDocuments  int   `mapstructure:"max_documents"`   // keep only the first documents
+	SampleN       int   `mapstructure:"sample_n"`        // keep a random sample of documents
+	SampleSeed    int64 `mapstructure:"sample_seed"`     // seed of the random sample
+	MaxTotalBytes int64 `mapstructure:"max_total_bytes"` // stop once documents would exceed this many bytes
+
+	Remote RemoteInputConfig `mapstructure:"remote"`
+}
+
+// RemoteInputConfig holds configuration for source paths that are http:// or https://
+// URLs
+type RemoteInputConfig struct {
+	Enabled      bool   `mapstructure:"enabled"`       // false refuses URLs
+	AllowPrivate bool   `mapstructure:"allow_private"` // allow loopback, link-local and private network addresses
+	Timeout      string `mapstructure:"timeout"`       // longest a download may take
+	MaxBytes     int64  `mapstructure:"max_bytes"`     // largest body downloaded
+	CacheDir     string `mapstructure:"cache_dir"`     // downloads kept for revalidation; empty uses remote under the output directory
 }
 
 // TokenizerConfig holds tokenizer configuration
 type TokenizerConfig struct {
-	Enabled []string                `mapstructure:"enabled"`
-	Configs map[string]TokenizerDef `mapstructure:"configs"`
+	Enabled  []string                `mapstructure:"enabled"`
+	VenvPath string                  `mapstructure:"venv_path"` // virtualenv for the Python tokenizers
+	Configs  map[string]TokenizerDef `mapstructure:"configs"`
+}
+
+// EngineConfig returns the metrics engine configuration of the analysis settings and
+// the per-tokenizer overrides
+func (c *Config) EngineConfig() metrics.EngineConfig {
+	// The analysis overrides mirror metrics.ParameterOverrides field for field
+	overrides := make(map[string]metrics.ParameterOverrides, len(c.AnalysisOverrides))
+	for name, override := range c.AnalysisOverrides {
+		overrides[name] = metrics.ParameterOverrides(override)
+	}
+	return metrics.EngineConfig{
+		EntropyWindowSize: c.Analysis.EntropyWindowSize,
+		NormalizeEntropy:  c.Analysis.NormalizeEntropy,
+		CompressionRatio:  c.Analysis.CompressionRatio,
+		DriftDetection:    c.Analysis.DriftDetection,
+		Perturbations:     c.Analysis.Perturbations,
+		NumericPattern:    c.Analysis.NumericPattern,
+
+		NoReusePatterns:  !c.Analysis.ReusePatterns,
+		NoRollingEntropy: !c.Analysis.RollingEntropy,
+		Profile:          c.Analysis.Profile,
+
+		EntropyCI:           c.Analysis.EntropyCI,
+		EntropyCIIterations: c.Analysis.EntropyCIIterations,
+		EntropyCIConfidence: c.Analysis.EntropyCIConfidence,
+		EntropyCISeed:       c.Analysis.EntropyCISeed,
+
+		MaxEditDistanceTokens: c.Analysis.MaxEditDistanceTokens,
+		MaxAlignmentTokens:    c.Analysis.MaxAlignmentTokens,
+		MATTRWindow:           c.Analysis.MATTRWindow,
+		StripSpaceMarkers:     c.Analysis.StripSpaceMarkers,
+		SpecialTokenIDs:       c.Analysis.SpecialTokenIDs,
+
+		Metrics:            c.Analysis.Metrics,
+		TokenizerOverrides: overrides,
+	}
+}
+
+// EnabledConfigs returns the adapter configuration for each enabled tokenizer, in
+// the order they are enabled
+func (t TokenizerConfig) EnabledConfigs() []tokenizers.TokenizerConfig {
+	configs := make([]tokenizers.TokenizerConfig, 0, len(t.Enabled))
+	for _, name := range t.Enabled {
+		configs = append(configs, t.ConfigFor(name))
+	}
+	return configs
+}
+
+// ConfigFor returns the adapter configuration for the named tokenizer. A tokenizer
+// without an entry under configs gets only the top-level settings.
+func (t TokenizerConfig) ConfigFor(name string) tokenizers.TokenizerConfig {
+	def := t.Configs[name]
+	return tokenizers.TokenizerConfig{
+		Name:        name,
+		Type:        def.Type,
+		Command:     def.Command,
+		LibraryPath: def.LibraryPath,
+		Parameters:  t.ParametersFor(name),
+	}
+}
+
+// IsKnown reports whether name is a built-in tokenizer or has an entry under configs
+func (t TokenizerConfig) IsKnown(name string) bool {
+	_, configured := t.Configs[name]
+	return configured || tokenizers.ValidateTokenizerName(name)
+}
+
+// ParametersFor returns the parameters configured for the named tokenizer, with
+// venv_path defaulted from the top-level setting
+func (t TokenizerConfig) ParametersFor(name string) map[string]string {
+	parameters := make(map[string]string)
+	for key, value := range t.Configs[name].Parameters {
+		parameters[key] = value
+	}
+	if _, ok := parameters["venv_path"]; !ok && t.VenvPath != "" {
+		parameters["venv_path"] = t.VenvPath
+	}
+	return parameters
 }
 
 // TokenizerDef represents a tokenizer definition
 type TokenizerDef struct {
 	Type        string            `mapstructure:"type"`
+	Command     string            `mapstructure:"command"` // external tokenizer program
 	LibraryPath string            `mapstructure:"library_path"`
 	Parameters  map[string]string `mapstructure:"parameters"`
 }
 
 // AnalysisConfig holds analysis parameters
 type AnalysisConfig struct {
-	EntropyWindowSize int  `mapstructure:"entropy_window_size"`
-	NormalizeEntropy  bool `mapstructure:"normalize_entropy"`
-	CompressionRatio  bool `mapstructure:"compression_ratio"`
-	DriftDetection    bool `mapstructure:"drift_detection"`
+	EntropyWindowSize int    `mapstructure:"entropy_window_size"`
+	NormalizeEntropy  bool   `mapstructure:"normalize_entropy"`
+	CompressionRatio  bool   `mapstructure:"compression_ratio"`
+	DriftDetection    bool   `mapstructure:"drift_detection"`
+	Perturbations     bool   `mapstructure:"perturbations"`
+	NumericPattern    string `mapstructure:"numeric_pattern"`
+
+	EntropyCI           bool    `mapstructure:"entropy_ci"`
+	EntropyCIIterations int     `mapstructure:"entropy_ci_iterations"`
+	EntropyCIConfidence float64 `mapstructure:"entropy_ci_confidence"`
+	EntropyCISeed       int64   `mapstructure:"entropy_ci_seed"`
+
+	MaxEditDistanceTokens int `mapstructure:"max_edit_distance_tokens"`
+	MaxAlignmentTokens    int `mapstructure:"max_alignment_tokens"`
+
+	MATTRWindow       int              `mapstructure:"mattr_window"`
+	StripSpaceMarkers []string         `mapstructure:"strip_space_markers"`
+	SpecialTokenIDs   map[string][]int `mapstructure:"special_token_ids"`
+
+	Metrics []string `mapstructure:"metrics"` // metric names or families to keep; empty keeps all
+
+	Profile        string `mapstructure:"profile"`         // quick, standard or exhaustive; explicit settings override it
+	ReusePatterns  bool   `mapstructure:"reuse_patterns"`  // reuse frequency and pattern payloads
+	RollingEntropy bool   `mapstructure:"rolling_entropy"` // rolling entropy statistics
+}
+
+// AnalysisOverride replaces analysis settings for one tokenizer; unset settings keep
+// the analysis section's. It mirrors metrics.ParameterOverrides field for field.
+type AnalysisOverride struct {
+	EntropyWindowSize *int     `mapstructure:"entropy_window_size"`
+	NormalizeEntropy  *bool    `mapstructure:"normalize_entropy"`
+	Metrics           []string `mapstructure:"metrics"`
 }
 
 // CacheConfig holds caching configuration
 type CacheConfig struct {
 	Enabled         bool   `mapstructure:"enabled"`
-	MaxSize         int    `mapstructure:"max_size"`
+	MaxSize         int    `mapstructure:"max_size"`    // tokenizations kept
+	MaxSizeMB       int    `mapstructure:"max_size_mb"` // approximate memory they may take; 0 bounds only the count
+	Shards          int    `mapstructure:"shards"`      // independently locked parts of each cache; 0 uses the default
 	TTL             string `mapstructure:"ttl"`
 	CleanupInterval string `mapstructure:"cleanup_interval"`
 	EnableStats     bool   `mapstructure:"enable_stats"`
+
+	// Analysis caches whole analysis results, when the cache is enabled
+	Analysis AnalysisCacheConfig `mapstructure:"analysis"`
+}
+
+// AnalysisCacheConfig holds the budget of the analysis result cache, kept apart from
+// the tokenization cache as each result holds a document's tokens and every metric
+type AnalysisCacheConfig struct {
+	Enabled   bool   `mapstructure:"enabled"`
+	MaxSize   int    `mapstructure:"max_size"`    // results kept
+	MaxSizeMB int    `mapstructure:"max_size_mb"` // approximate memory they may take; 0 bounds only the count
+	TTL       string `mapstructure:"ttl"`
 }
 
 // ParallelConfig holds parallel processing configuration
@@ -64,6 +240,9 @@ type ParallelConfig struct {
 	BatchSize     int    `mapstructure:"batch_size"`
 	Timeout       string `mapstructure:"timeout"`
 	EnableMetrics bool   `mapstructure:"enable_metrics"`
+
+	ProgressInterval string `mapstructure:"progress_interval"`  // how often to report progress
+	AbortAfterErrors int    `mapstructure:"abort_after_errors"` // stop after this many failures; 0 never stops
 }
 
 // StreamingConfig holds streaming analysis configuration
@@ -75,6 +254,23 @@ type StreamingConfig struct {
 	EnableProgress   bool   `mapstructure:"enable_progress"`
 	ProgressInterval int    `mapstructure:"progress_interval"`
 	Timeout          string `mapstructure:"timeout"`
+
+	RetainChunkResults bool   `mapstructure:"retain_chunk_results"` // keep chunk results in memory, up to max_memory_mb
+	ChunkResultsPath   string `mapstructure:"chunk_results_path"`   // write chunk results here; .csv, .parquet or JSON lines
+
+	ChunkMode string `mapstructure:"chunk_mode"` // concatenate, per_line or delimiter
+	Delimiter string `mapstructure:"delimiter"`  // document separator line in delimiter mode; empty means a blank line
+	WeightBy  string `mapstructure:"weight_by"`  // chunk weight for weighted means: tokens or lines
+
+	Drift StreamingDriftConfig `mapstructure:"drift"`
+}
+
+// StreamingDriftConfig holds configuration for drift detection while streaming
+type StreamingDriftConfig struct {
+	Enabled        bool    `mapstructure:"enabled"`
+	BaselineChunks int     `mapstructure:"baseline_chunks"` // first chunks forming a fixed baseline
+	Decay          float64 `mapstructure:"decay"`           // weight of each chunk in a rolling baseline; 0 keeps the fixed one
+	ZThreshold     float64 `mapstructure:"z_threshold"`     // flag chunks whose drift z-score exceeds this
 }
 
 // PluginsConfig holds plugin system configuration
@@ -82,6 +278,7 @@ type PluginsConfig struct {
 	Enabled         bool                              `mapstructure:"enabled"`
 	AutoLoad        bool                              `mapstructure:"auto_load"`
 	PluginDirectory string                            `mapstructure:"plugin_directory"`
+	MaxConcurrency  int                               `mapstructure:"max_concurrency"` // plugins run at once per document; below 2 runs them in turn
 	Configs         map[string]map[string]interface{} `mapstructure:"configs"`
 }
 
@@ -91,6 +288,24 @@ type OutputConfig struct {
 	Format       string `mapstructure:"format"`
 	IncludeLogs  bool   `mapstructure:"include_logs"`
 	TimestampDir bool   `mapstructure:"timestamp_dir"`
+
+	Tables TablesConfig `mapstructure:"tables"`
+}
+
+// TablesConfig holds the defaults of the Markdown and LaTeX summary tables written to
+// the reports directory
+type TablesConfig struct {
+	Metrics []string `mapstructure:"metrics"` // columns in order; empty uses every metric
+	Digits  int      `mapstructure:"digits"`  // significant digits; 0 uses 3
+	Std     bool     `mapstructure:"std"`     // standard deviation over documents in parentheses
+	Bold    bool     `mapstructure:"bold"`    // bold the best value of each ranked metric
+}
+
+// DiffConfig holds the thresholds beyond which a diff of two saved runs fails
+type DiffConfig struct {
+	MetricThresholds    map[string]float64 `mapstructure:"metric_thresholds"`     // largest relative change of a metric's mean per tokenizer, by metric; "*" for the rest
+	MaxChangedDocuments int                `mapstructure:"max_changed_documents"` // documents per tokenizer whose token count changed; negative allows any
+	MaxVocabularyChange int                `mapstructure:"max_vocabulary_change"` // tokens per tokenizer appearing or disappearing; negative allows any
 }
 
 // VisualizationConfig holds visualization settings
@@ -99,12 +314,50 @@ type VisualizationConfig struct {
 	ImageSize   string `mapstructure:"image_size"`
 	FileType    string `mapstructure:"file_type"`
 	Interactive bool   `mapstructure:"interactive"`
+	Timezone    string `mapstructure:"timezone"` // IANA name for report times; empty uses local time
+
+	OfflineAssets bool `mapstructure:"offline_assets"` // load Plotly.js from a copy next to the outputs
+	SelfContained bool `mapstructure:"self_contained"` // write the report as a single file
+
+	DisableDataExport bool `mapstructure:"disable_data_export"` // skip the .data.json and .csv files beside visualizations
+
+	HeatmapNormalize string `mapstructure:"heatmap_normalize"` // none, row, column or zscore
+	HeatmapLogScale  bool   `mapstructure:"heatmap_log_scale"` // plot log10(1+x) of heatmap values
+
+	Palette PaletteConfig `mapstructure:"palette"` // overrides of the theme's colors
+}
+
+// PaletteConfig overrides colors of the theme's palette. Colors are #rrggbb; empty
+// fields keep the theme's colors.
+type PaletteConfig struct {
+	Background  string `mapstructure:"background"`
+	Surface     string `mapstructure:"surface"`
+	Foreground  string `mapstructure:"foreground"`
+	Grid        string `mapstructure:"grid"`
+	Header      string `mapstructure:"header"`
+	HeaderText  string `mapstructure:"header_text"`
+	Nav         string `mapstructure:"nav"`
+	NavActive   string `mapstructure:"nav_active"`
+	TableHeader string `mapstructure:"table_header"`
+
+	Series        []string `mapstructure:"series"`
+	Accent        string   `mapstructure:"accent"`
+	Muted         string   `mapstructure:"muted"`
+	TokenSegments []string `mapstructure:"token_segments"`
+
+	// ColorScales maps token_count, entropy, compression, reuse, metric or comparison
+	// to a Plotly color scale name
+	ColorScales map[string]string `mapstructure:"color_scales"`
 }
 
 // ServerConfig holds web server configuration
 type ServerConfig struct {
 	Port int    `mapstructure:"port"`
 	Host string `mapstructure:"host"`
+
+	// AllowedOrigins are the origins, such as "https://dashboard.example.com", whose
+	// pages may open the progress WebSocket besides the dashboard's own; "*" allows any
+	AllowedOrigins []string `mapstructure:"allowed_origins"`
 }
 
 // LoggingConfig holds logging configuration
@@ -112,14 +365,40 @@ type LoggingConfig struct {
 	Level  string `mapstructure:"level"`
 	Format string `mapstructure:"format"`
 	File   string `mapstructure:"file"`
+
+	// Rotation of the log file by size
+	MaxSizeMB  int  `mapstructure:"max_size_mb"`  // rotate at this size; 0 never rotates
+	MaxBackups int  `mapstructure:"max_backups"`  // rotated files kept; 0 keeps all
+	MaxAgeDays int  `mapstructure:"max_age_days"` // days rotated files are kept; 0 keeps them regardless of age
+	Compress   bool `mapstructure:"compress"`     // gzip rotated files
+
+	// Levels overrides Level for the metrics, tokenizers, visualization and server
+	// modules; Sampling thins out the debug events logged once per metric
+	Levels   map[string]string `mapstructure:"levels"`
+	Sampling LogSamplingConfig `mapstructure:"sampling"`
 }
 
-// LoadConfig loads configuration from file and environment
-func LoadConfig(configPath string) (*Config, error) {
-	// Set default values
-	config := &Config{
+// LogSamplingConfig limits the metric events logged per analysis
+type LogSamplingConfig struct {
+	Every int `mapstructure:"every"` // log every Nth event; 0 or 1 logs each
+	First int `mapstructure:"first"` // log none after the first K events; 0 has no limit
+}
+
+// DefaultConfig returns the configuration used where no file or environment variable
+// sets a value
+func DefaultConfig() *Config {
+	return &Config{
 		Input: InputConfig{
-			FileType: "txt",
+			FileType:  "txt",
+			SplitMode: "line",
+
+			JSONLTextSeparator: "\n",
+			CSVHasHeader:       true,
+
+			Remote: RemoteInputConfig{
+				Timeout:  "30s",
+				MaxBytes: 100 << 20,
+			},
 		},
 		Tokenizers: TokenizerConfig{
 			Enabled: []string{"mock", "gpt2"},
@@ -129,13 +408,23 @@ func LoadConfig(configPath string) (*Config, error) {
 			NormalizeEntropy:  true,
 			CompressionRatio:  true,
 			DriftDetection:    true,
+			ReusePatterns:     true,
+			RollingEntropy:    true,
 		},
 		Cache: CacheConfig{
 			Enabled:         true,
 			MaxSize:         10000,
+			MaxSizeMB:       256,
+			Shards:          16,
 			TTL:             "1h",
 			CleanupInterval: "10m",
 			EnableStats:     true,
+			Analysis: AnalysisCacheConfig{
+				Enabled:   true,
+				MaxSize:   1000,
+				MaxSizeMB: 256,
+				TTL:       "1h",
+			},
 		},
 		Parallel: ParallelConfig{
 			Enabled:       true,
@@ -143,6 +432,8 @@ func LoadConfig(configPath string) (*Config, error) {
 			BatchSize:     100,
 			Timeout:       "30m",
 			EnableMetrics: true,
+
+			ProgressInterval: "1s",
 		},
 		Streaming: StreamingConfig{
 			Enabled:          true,
@@ -152,11 +443,18 @@ func LoadConfig(configPath string) (*Config, error) {
 			EnableProgress:   true,
 			ProgressInterval: 10,
 			Timeout:          "1h",
+			ChunkMode:        "concatenate",
+			WeightBy:         "tokens",
+			Drift: StreamingDriftConfig{
+				BaselineChunks: 10,
+				ZThreshold:     3,
+			},
 		},
 		Plugins: PluginsConfig{
 			Enabled:         true,
 			AutoLoad:        true,
 			PluginDirectory: "plugins",
+			MaxConcurrency:  1,
 			Configs:         make(map[string]map[string]interface{}),
 		},
 		Output: OutputConfig{
@@ -164,12 +462,20 @@ func LoadConfig(configPath string) (*Config, error) {
 			Format:       "csv",
 			IncludeLogs:  true,
 			TimestampDir: true,
+			Tables: TablesConfig{
+				Metrics: []string{"token_count", "compression_compression_ratio", "compression_entropy_efficiency", "entropy_global_entropy", "fertility_mean_tokens_per_word"},
+				Digits:  3,
+				Std:     true,
+				Bold:    true,
+			},
 		},
 		Visualization: VisualizationConfig{
 			Theme:       "light",
 			ImageSize:   "medium",
 			FileType:    "svg",
 			Interactive: true,
+
+			HeatmapNormalize: "none",
 		},
 		Server: ServerConfig{
 			Port: 8080,
@@ -178,7 +484,118 @@ func LoadConfig(configPath string) (*Config, error) {
 		Logging: LoggingConfig{
 			Level:  "info",
 			Format: "json",
+
+			MaxSizeMB:  100,
+			MaxBackups: 5,
+			MaxAgeDays: 30,
+			Compress:   true,
+
+			Sampling: LogSamplingConfig{Every: 1},
 		},
+		Diff: DiffConfig{
+			MetricThresholds:    map[string]float64{"*": 0.01},
+			MaxChangedDocuments: -1,
+			MaxVocabularyChange: -1,
+		},
+	}
+}
+
+// LoadOption configures LoadConfig
+type LoadOption func(*loadSettings)
+
+// loadSettings are the settings of a LoadConfig call
+type loadSettings struct {
+	strict  bool
+	profile string
+}
+
+// WithStrict makes keys in the config file that match no setting an error instead of
+// being ignored
+func WithStrict(strict bool) LoadOption {
+	return func(s *loadSettings) {
+		s.strict = strict
+	}
+}
+
+// WithProfile selects an analysis profile in place of analysis.profile, as a command
+// line flag would. Explicit analysis settings still override it.
+func WithProfile(profile string) LoadOption {
+	return func(s *loadSettings) {
+		s.profile = profile
+	}
+}
+
+// ConfigSearchPaths returns the directories searched for a ted.config file, in order,
+// when LoadConfig is given no path
+func ConfigSearchPaths() []string {
+	paths := []string{"."}
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".config", "ted"))
+	}
+	return append(paths, "/etc/ted")
+}
+
+// LoadConfig loads configuration from file and environment. The file is configPath, or
+// else the first ted.config.yaml (or .yml, .toml or .json) in ConfigSearchPaths;
+// without one the defaults apply. Environment variables prefixed TED_ override the
+// file, as TED_SERVER_PORT sets server.port and TED_TOKENIZERS_ENABLED=gpt2,bert sets a
+// list; settings keyed by name, like tokenizers.configs, can only be set in the file.
+func LoadConfig(configPath string, options ...LoadOption) (*Config, error) {
+	var settings loadSettings
+	for _, option := range options {
+		option(&settings)
+	}
+
+	v := viper.NewWithOptions(viper.KeyDelimiter(keyDelimiter))
+	if configPath != "" {
+		v.SetConfigFile(configPath)
+	} else {
+		v.SetConfigName("ted.config")
+		for _, dir := range ConfigSearchPaths() {
+			v.AddConfigPath(dir)
+		}
+	}
+	if err := v.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if configPath != "" || !errors.As(err, &notFound) {
+			return nil, fmt.Errorf("error reading config file %s: %w", v.ConfigFileUsed(), err)
+		}
+	}
+
+	v.SetEnvPrefix("TED")
+	v.SetEnvKeyReplacer(strings.NewReplacer(keyDelimiter, "_"))
+	for _, key := range settingKeys(reflect.TypeOf(Config{}), "") {
+		if err := v.BindEnv(key); err != nil {
+			return nil, fmt.Errorf("error binding environment variable for %s: %w", key, err)
+		}
+	}
+
+	// Decoding onto the defaults keeps every value the file and environment leave unset;
+	// a profile replaces the analysis defaults, so explicit settings override it
+	config := DefaultConfig()
+	profile := settings.profile
+	if profile == "" {
+		profile = v.GetString("analysis" + keyDelimiter + "profile")
+	}
+	if profile != "" {
+		analysis, err := ProfileAnalysis(profile)
+		if err != nil {
+			return nil, err
+		}
+		config.Analysis = analysis
+	}
+	unmarshal := v.Unmarshal
+	if settings.strict {
+		unmarshal = v.UnmarshalExact
+	}
+	if err := unmarshal(config); err != nil {
+		if file := v.ConfigFileUsed(); file != "" {
+			return nil, fmt.Errorf("invalid configuration in %s: %w", file, err)
+		}
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	if settings.profile != "" {
+		config.Analysis.Profile = settings.profile
 	}
 
 	// Create output directory if it doesn't exist
@@ -198,22 +615,301 @@ func LoadConfig(configPath string) (*Config, error) {
 	return config, nil
 }
 
-// ValidateConfig validates the configuration
+// ChangedSettings returns the keys of the settings that differ between two
+// configurations, such as server.port, in the order they are declared. Maps are
+// compared whole, so any change under tokenizers.configs is reported as that key; an
+// empty list or map equals a missing one.
+func ChangedSettings(old, new *Config) []string {
+	return changedSettings(reflect.ValueOf(*old), reflect.ValueOf(*new), "")
+}
+
+// changedSettings compares two values of a config struct type
+func changedSettings(old, new reflect.Value, prefix string) []string {
+	var keys []string
+	for i := 0; i < old.NumField(); i++ {
+		field := old.Type().Field(i)
+		name := field.Tag.Get("mapstructure")
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+		key := prefix + name
+		a, b := old.Field(i), new.Field(i)
+		switch field.Type.Kind() {
+		case reflect.Struct:
+			keys = append(keys, changedSettings(a, b, key+".")...)
+		case reflect.Slice, reflect.Map:
+			if a.Len() == 0 && b.Len() == 0 {
+				continue
+			}
+			fallthrough
+		default:
+			if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+// keyDelimiter separates the levels of viper's setting keys. It is not a dot, since
+// names keying maps such as tokenizers.configs may contain dots, as in gpt-3.5-turbo.
+const keyDelimiter = "::"
+
+// settingKeys returns the keys of the settings of a config struct type, such as
+// server::port. Maps are left out, since their keys are not known in advance.
+func settingKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("mapstructure")
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+		key := prefix + name
+		switch field.Type.Kind() {
+		case reflect.Struct:
+			keys = append(keys, settingKeys(field.Type, key+keyDelimiter)...)
+		case reflect.Map:
+		default:
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Problem is a setting that fails validation. Key is the setting's path, such as
+// tokenizers.configs.t5.parameters.model_path. A warning does not fail validation.
+type Problem struct {
+	Key     string `json:"key"`
+	Message string `json:"message"`
+	Warning bool   `json:"warning,omitempty"`
+}
+
+// String formats the problem as key: message
+func (p Problem) String() string {
+	if p.Warning {
+		return fmt.Sprintf("%s: %s (warning)", p.Key, p.Message)
+	}
+	return fmt.Sprintf("%s: %s", p.Key, p.Message)
+}
+
+// ValidationError lists every problem that failed validation
+type ValidationError struct {
+	Problems []Problem
+}
+
+// Error joins the problems, one per line
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		lines[i] = problem.String()
+	}
+	if len(lines) == 1 {
+		return "invalid configuration: " + lines[0]
+	}
+	return "invalid configuration:\n  " + strings.Join(lines, "\n  ")
+}
+
+// ValidateConfig validates the configuration, returning a *ValidationError listing
+// every problem with the enabled tokenizers, or the first invalid setting
 func (c *Config) ValidateConfig() error {
+	var failures []Problem
+	for _, problem := range c.Validate(false) {
+		if !problem.Warning {
+			failures = append(failures, problem)
+		}
+	}
+	if len(failures) > 0 {
+		return &ValidationError{Problems: failures}
+	}
+	return nil
+}
+
+// Validate returns every problem with the enabled tokenizers: names that are neither
+// built in nor configured, configured tokenizers without a type, and parameters their
+// adapters require. Analysis overrides for tokenizers that are not enabled are
+// warnings. With deep, each tokenizer is also initialized to probe its Python
+// packages, model files and API key. An invalid setting elsewhere is reported after
+// them, under the key "config".
+func (c *Config) Validate(deep bool) []Problem {
+	var problems []Problem
+	if len(c.Tokenizers.Enabled) == 0 {
+		problems = append(problems, Problem{Key: "tokenizers.enabled", Message: "no tokenizers enabled"})
+	}
+	for _, name := range c.Tokenizers.Enabled {
+		key := "tokenizers.configs." + name
+		def, configured := c.Tokenizers.Configs[name]
+		builtin := tokenizers.ValidateTokenizerName(name)
+		if !configured && !builtin {
+			problems = append(problems, Problem{Key: "tokenizers.enabled", Message: fmt.Sprintf(
+				"tokenizer %q is not built in and has no entry under tokenizers.configs (built-in tokenizers: %s)",
+				name, strings.Join(tokenizers.GetAvailableTokenizers(), ", "))})
+			continue
+		}
+		if !builtin && def.Type == "" {
+			problems = append(problems, Problem{Key: key + ".type", Message: "required for a tokenizer that is not built in (bpe, wordpiece, spiece or custom)"})
+			continue
+		}
+		for _, problem := range tokenizers.CheckConfig(c.Tokenizers.ConfigFor(name), deep) {
+			problemKey := key
+			if problem.Parameter != "" {
+				problemKey += ".parameters." + problem.Parameter
+			}
+			problems = append(problems, Problem{Key: problemKey, Message: problem.Message, Warning: problem.Warning})
+		}
+	}
+
+	enabled := make(map[string]bool, len(c.Tokenizers.Enabled))
+	for _, name := range c.Tokenizers.Enabled {
+		enabled[name] = true
+	}
+	for name := range c.AnalysisOverrides {
+		if !enabled[name] {
+			problems = append(problems, Problem{Key: "analysis_overrides." + name, Message: "tokenizer is not enabled, so its overrides are unused", Warning: true})
+		}
+	}
+
+	if err := c.validateSettings(); err != nil {
+		problems = append(problems, Problem{Key: "config", Message: err.Error()})
+	}
+	return problems
+}
+
+// validateSettings checks every setting other than the tokenizers, returning the first
+// that is invalid
+func (c *Config) validateSettings() error {
 	// Validate input configuration
 	if len(c.Input.SourcePaths) == 0 && c.Input.FileType == "" {
 		return fmt.Errorf("input configuration is incomplete")
 	}
 
-	// Validate tokenizer configuration
-	if len(c.Tokenizers.Enabled) == 0 {
-		return fmt.Errorf("no tokenizers enabled")
+	// Validate cache configuration
+	if c.Cache.MaxSizeMB < 0 || c.Cache.Shards < 0 || c.Cache.Analysis.MaxSizeMB < 0 {
+		return fmt.Errorf("cache max_size_mb and shards must not be negative")
+	}
+	if c.Cache.Analysis.MaxSize < 0 {
+		return fmt.Errorf("cache analysis max_size must not be negative: %d", c.Cache.Analysis.MaxSize)
+	}
+	if c.Cache.Analysis.TTL != "" {
+		if ttl, err := time.ParseDuration(c.Cache.Analysis.TTL); err != nil || ttl <= 0 {
+			return fmt.Errorf("invalid cache analysis ttl: %s", c.Cache.Analysis.TTL)
+		}
+	}
+
+	// Validate parallel processing configuration
+	if c.Parallel.ProgressInterval != "" {
+		if interval, err := time.ParseDuration(c.Parallel.ProgressInterval); err != nil || interval <= 0 {
+			return fmt.Errorf("invalid parallel progress_interval: %s", c.Parallel.ProgressInterval)
+		}
+	}
+	if c.Parallel.AbortAfterErrors < 0 {
+		return fmt.Errorf("parallel abort_after_errors must not be negative: %d", c.Parallel.AbortAfterErrors)
+	}
+
+	// Validate streaming configuration
+	switch c.Streaming.ChunkMode {
+	case "", "concatenate", "per_line", "delimiter":
+	default:
+		return fmt.Errorf("invalid streaming chunk_mode: %s (use concatenate, per_line or delimiter)", c.Streaming.ChunkMode)
+	}
+	switch c.Streaming.WeightBy {
+	case "", "tokens", "lines":
+	default:
+		return fmt.Errorf("invalid streaming weight_by: %s (use tokens or lines)", c.Streaming.WeightBy)
+	}
+	if c.Streaming.Drift.Decay < 0 || c.Streaming.Drift.Decay > 1 {
+		return fmt.Errorf("streaming drift decay must be between 0 and 1: %v", c.Streaming.Drift.Decay)
+	}
+
+	// Validate output configuration
+	switch c.Output.Format {
+	case "", "csv", "csv_wide", "jsonl", "parquet":
+	default:
+		return fmt.Errorf("invalid output format: %s (use csv, csv_wide, jsonl or parquet)", c.Output.Format)
+	}
+	if c.Output.Tables.Digits < 0 || c.Output.Tables.Digits > 17 {
+		return fmt.Errorf("output tables digits must be between 0 and 17: %d", c.Output.Tables.Digits)
+	}
+	for metric, threshold := range c.Diff.MetricThresholds {
+		if threshold < 0 {
+			return fmt.Errorf("diff metric threshold for %s must not be negative: %g", metric, threshold)
+		}
+	}
+
+	// Validate input configuration
+	switch c.Input.SplitMode {
+	case "", "line", "paragraph", "file", "delimiter":
+	default:
+		return fmt.Errorf("invalid input split_mode: %s (use line, paragraph, file or delimiter)", c.Input.SplitMode)
+	}
+	for _, path := range append(append([]string(nil), c.Input.JSONLTextFields...), c.Input.JSONLMetadataFields...) {
+		if err := loader.ValidateFieldPath(path); err != nil {
+			return fmt.Errorf("invalid input JSONL field: %w", err)
+		}
+	}
+	for _, pattern := range append(append([]string(nil), c.Input.Include...), c.Input.Exclude...) {
+		if err := loader.ValidateGlob(pattern); err != nil {
+			return fmt.Errorf("invalid input include or exclude pattern: %w", err)
+		}
+	}
+	if _, err := loader.ParseCSVDelimiter(c.Input.CSVDelimiter); err != nil {
+		return fmt.Errorf("invalid input csv_delimiter: %w", err)
+	}
+	if c.Input.CSVSkipRows < 0 || c.Input.CSVMaxRows < 0 {
+		return fmt.Errorf("input csv_skip_rows and csv_max_rows must not be negative: %d, %d", c.Input.CSVSkipRows, c.Input.CSVMaxRows)
+	}
+	if c.Input.MinChars < 0 || c.Input.MaxChars < 0 || c.Input.MaxDocuments < 0 || c.Input.SampleN < 0 || c.Input.MaxTotalBytes < 0 {
+		return fmt.Errorf("input min_chars, max_chars, max_documents, sample_n and max_total_bytes must not be negative")
+	}
+	if c.Input.MaxChars > 0 && c.Input.MinChars > c.Input.MaxChars {
+		return fmt.Errorf("input min_chars %d exceeds max_chars %d", c.Input.MinChars, c.Input.MaxChars)
+	}
+	if c.Input.Remote.Timeout != "" {
+		if timeout, err := time.ParseDuration(c.Input.Remote.Timeout); err != nil || timeout <= 0 {
+			return fmt.Errorf("invalid input remote timeout: %s", c.Input.Remote.Timeout)
+		}
+	}
+	if c.Input.Remote.MaxBytes < 0 {
+		return fmt.Errorf("input remote max_bytes must not be negative: %d", c.Input.Remote.MaxBytes)
+	}
+
+	// Validate plugin configuration
+	if c.Plugins.MaxConcurrency < 0 {
+		return fmt.Errorf("plugins max_concurrency must not be negative: %d", c.Plugins.MaxConcurrency)
+	}
+
+	// Validate visualization configuration
+	switch c.Visualization.FileType {
+	case "", "html", "svg", "png":
+	default:
+		return fmt.Errorf("invalid visualization file_type: %s (use html, svg or png)", c.Visualization.FileType)
+	}
+	if _, err := time.LoadLocation(c.Visualization.Timezone); err != nil {
+		return fmt.Errorf("invalid visualization timezone: %s", c.Visualization.Timezone)
+	}
+	switch c.Visualization.HeatmapNormalize {
+	case "", "none", "row", "column", "zscore":
+	default:
+		return fmt.Errorf("invalid visualization heatmap_normalize: %s (use none, row, column or zscore)", c.Visualization.HeatmapNormalize)
+	}
+	if err := c.Visualization.Palette.validate(); err != nil {
+		return fmt.Errorf("invalid visualization palette: %w", err)
 	}
 
 	// Validate analysis configuration
+	if c.Analysis.Profile != "" {
+		if _, err := ProfileAnalysis(c.Analysis.Profile); err != nil {
+			return err
+		}
+	}
 	if c.Analysis.EntropyWindowSize <= 0 {
 		return fmt.Errorf("entropy window size must be positive")
 	}
+	for name, override := range c.AnalysisOverrides {
+		if override.EntropyWindowSize != nil && *override.EntropyWindowSize <= 0 {
+			return fmt.Errorf("entropy window size for %s must be positive", name)
+		}
+	}
 
 	// Validate output configuration
 	if c.Output.Directory == "" {
@@ -225,9 +921,81 @@ func (c *Config) ValidateConfig() error {
 		return fmt.Errorf("invalid server port: %d", c.Server.Port)
 	}
 
+	// Validate logging configuration
+	if c.Logging.MaxSizeMB < 0 || c.Logging.MaxBackups < 0 || c.Logging.MaxAgeDays < 0 {
+		return fmt.Errorf("logging max_size_mb, max_backups and max_age_days must not be negative")
+	}
+	for module, level := range c.Logging.Levels {
+		switch module {
+		case logger.ModuleMetrics, logger.ModuleTokenizers, logger.ModuleVisualization, logger.ModuleServer:
+		default:
+			return fmt.Errorf("invalid logging module: %s", module)
+		}
+		switch level {
+		case "trace", "debug", "info", "warn", "warning", "error", "fatal", "panic":
+		default:
+			return fmt.Errorf("invalid logging level of module %s: %s", module, level)
+		}
+	}
+	if c.Logging.Sampling.Every < 0 || c.Logging.Sampling.First < 0 {
+		return fmt.Errorf("logging sampling every and first must not be negative")
+	}
+
+	return nil
+}
+
+// validate checks that every color is #rrggbb and every color scale is for a known
+// heatmap type
+func (p PaletteConfig) validate() error {
+	colors := map[string]string{
+		"background":   p.Background,
+		"surface":      p.Surface,
+		"foreground":   p.Foreground,
+		"grid":         p.Grid,
+		"header":       p.Header,
+		"header_text":  p.HeaderText,
+		"nav":          p.Nav,
+		"nav_active":   p.NavActive,
+		"table_header": p.TableHeader,
+		"accent":       p.Accent,
+		"muted":        p.Muted,
+	}
+	for name, color := range colors {
+		if color != "" && !isHexColor(color) {
+			return fmt.Errorf("%s must be a #rrggbb color: %s", name, color)
+		}
+	}
+	for name, list := range map[string][]string{"series": p.Series, "token_segments": p.TokenSegments} {
+		for i, color := range list {
+			if !isHexColor(color) {
+				return fmt.Errorf("%s[%d] must be a #rrggbb color: %q", name, i, color)
+			}
+		}
+	}
+
+	for kind := range p.ColorScales {
+		switch kind {
+		case "token_count", "entropy", "compression", "reuse", "metric", "comparison":
+		default:
+			return fmt.Errorf("unknown color_scales heatmap type: %s (use token_count, entropy, compression, reuse, metric or comparison)", kind)
+		}
+	}
 	return nil
 }
 
+// isHexColor reports whether s is a #rrggbb color
+func isHexColor(s string) bool {
+	if len(s) != 7 || s[0] != '#' {
+		return false
+	}
+	for _, c := range s[1:] {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
 // GetOutputPath returns the full path for a given output file
 func (c *Config) GetOutputPath(filename string) string {
 	return filepath.Join(c.Output.Directory, filename)
@@ -0,0 +1,333 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes a config file in a temporary directory, with the output directory
+// set inside it so loading creates nothing elsewhere
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("TED_OUTPUT_DIRECTORY", filepath.Join(dir, "output"))
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigFileAndEnvironment(t *testing.T) {
+	path := writeConfig(t, "ted.config.yaml", `
+server:
+  port: 9000
+logging:
+  level: debug
+tokenizers:
+  enabled: ["gpt2"]
+  configs:
+    my-bpe:
+      type: bpe
+input:
+  csv_has_header: false
+`)
+	t.Setenv("TED_SERVER_PORT", "9100")
+	t.Setenv("TED_ANALYSIS_ENTROPY_WINDOW_SIZE", "250")
+	t.Setenv("TED_INPUT_INCLUDE", "*.txt,*.jsonl")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+
+	defaults := DefaultConfig()
+	tests := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"environment over file", cfg.Server.Port, 9100},
+		{"file over default", cfg.Logging.Level, "debug"},
+		{"environment over default", cfg.Analysis.EntropyWindowSize, 250},
+		{"environment list", cfg.Input.Include, []string{"*.txt", "*.jsonl"}},
+		{"file list", cfg.Tokenizers.Enabled, []string{"gpt2"}},
+		{"file false over true default", cfg.Input.CSVHasHeader, false},
+		{"file map", cfg.Tokenizers.Configs["my-bpe"].Type, "bpe"},
+		{"default", cfg.Server.Host, defaults.Server.Host},
+		{"nested default", cfg.Input.Remote.Timeout, defaults.Input.Remote.Timeout},
+		{"remote input off by default", cfg.Input.Remote.Enabled, false},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigFormats(t *testing.T) {
+	path := writeConfig(t, "ted.config.toml", "[server]\nport = 7000\n")
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if cfg.Server.Port != 7000 {
+		t.Errorf("port = %d, want 7000 from TOML", cfg.Server.Port)
+	}
+}
+
+func TestLoadConfigSearchesStandardLocations(t *testing.T) {
+	path := writeConfig(t, "ted.config.yml", "server:\n  port: 7100\n")
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(filepath.Dir(path)); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	cfg, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if cfg.Server.Port != 7100 {
+		t.Errorf("port = %d, want 7100 from the file in the working directory", cfg.Server.Port)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		options []LoadOption
+		want    []string
+	}{
+		{"malformed YAML", "bad.yaml", "server:\n  port: 8080\n  host: [unclosed\n", nil, []string{"bad.yaml", "line"}},
+		{"wrong type", "type.yaml", "server:\n  port: eighty\n", nil, []string{"type.yaml", "port"}},
+		{"unknown key in strict mode", "strict.yaml", "server:\n  prot: 8080\n", []LoadOption{WithStrict(true)}, []string{"strict.yaml", "prot"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfig(t, tt.file, tt.content), tt.options...)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+
+	// Unknown keys are ignored outside strict mode
+	if _, err := LoadConfig(writeConfig(t, "lenient.yaml", "server:\n  prot: 8080\n")); err != nil {
+		t.Errorf("LoadConfig returned error for an unknown key without strict mode: %v", err)
+	}
+
+	// A named file must exist
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("expected an error for a missing config file")
+	}
+}
+
+func TestValidateCollectsTokenizerProblems(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Tokenizers.Enabled = []string{"gpt2", "nonexistent", "untyped", "bpe-local", "t5-base", "my-spiece"}
+	cfg.Tokenizers.Configs = map[string]TokenizerDef{
+		"untyped":   {},
+		"my-spiece": {Type: "spiece"},
+	}
+
+	var got []string
+	for _, problem := range cfg.Validate(false) {
+		if problem.Warning {
+			got = append(got, problem.Key+" (warning)")
+		} else {
+			got = append(got, problem.Key)
+		}
+	}
+	want := []string{
+		"tokenizers.enabled",
+		"tokenizers.configs.untyped.type",
+		"tokenizers.configs.bpe-local.parameters.vocab_path",
+		"tokenizers.configs.bpe-local.parameters.merges_path",
+		"tokenizers.configs.t5-base.parameters.model_path (warning)",
+		"tokenizers.configs.my-spiece.parameters.model_path",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate() keys = %v, want %v", got, want)
+	}
+
+	// ValidateConfig fails with every problem but the warning
+	err := cfg.ValidateConfig()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("ValidateConfig() = %v, want a *ValidationError", err)
+	}
+	if len(validationErr.Problems) != len(want)-1 {
+		t.Errorf("ValidateConfig() problems = %v, want %d", validationErr.Problems, len(want)-1)
+	}
+	if !strings.Contains(err.Error(), "nonexistent") {
+		t.Errorf("error %q does not name the unknown tokenizer", err)
+	}
+
+	// An invalid setting elsewhere is reported too
+	cfg.Tokenizers.Enabled = []string{"gpt2"}
+	cfg.Server.Port = 0
+	if problems := cfg.Validate(false); len(problems) != 1 || problems[0].Key != "config" {
+		t.Errorf("Validate() = %v, want only the invalid port", problems)
+	}
+}
+
+func TestLoadConfigAnalysisOverrides(t *testing.T) {
+	path := writeConfig(t, "ted.config.yaml", `
+tokenizers:
+  enabled: ["gpt2", "char", "gpt-3.5-turbo"]
+analysis:
+  metrics: ["token_count", "entropy", "compression"]
+analysis_overrides:
+  gpt2:
+    entropy_window_size: 500
+  char:
+    entropy_window_size: 2000
+    normalize_entropy: false
+    metrics: ["entropy"]
+  bert-base:
+    normalize_entropy: true
+  gpt-3.5-turbo:
+    entropy_window_size: 800
+`)
+	// Names keying maps may contain dots, even in strict mode
+	cfg, err := LoadConfig(path, WithStrict(true))
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+
+	gpt2, char := cfg.AnalysisOverrides["gpt2"], cfg.AnalysisOverrides["char"]
+	if gpt2.EntropyWindowSize == nil || *gpt2.EntropyWindowSize != 500 || gpt2.NormalizeEntropy != nil {
+		t.Errorf("gpt2 overrides = %+v, want only a 500-token window", gpt2)
+	}
+	if char.NormalizeEntropy == nil || *char.NormalizeEntropy || !reflect.DeepEqual(char.Metrics, []string{"entropy"}) {
+		t.Errorf("char overrides = %+v, want normalization off and the entropy metrics", char)
+	}
+	if turbo := cfg.AnalysisOverrides["gpt-3.5-turbo"]; turbo.EntropyWindowSize == nil || *turbo.EntropyWindowSize != 800 {
+		t.Errorf("gpt-3.5-turbo overrides = %+v, want an 800-token window", turbo)
+	}
+	if !reflect.DeepEqual(cfg.Analysis.Metrics, []string{"token_count", "entropy", "compression"}) {
+		t.Errorf("analysis metrics = %v", cfg.Analysis.Metrics)
+	}
+
+	// Overrides of a tokenizer that is not enabled are only a warning
+	problems := cfg.Validate(false)
+	if len(problems) != 1 || problems[0].Key != "analysis_overrides.bert-base" || !problems[0].Warning {
+		t.Errorf("Validate() = %v, want a warning for bert-base", problems)
+	}
+
+	zero := 0
+	cfg.AnalysisOverrides["gpt2"] = AnalysisOverride{EntropyWindowSize: &zero}
+	if err := cfg.ValidateConfig(); err == nil || !strings.Contains(err.Error(), "gpt2") {
+		t.Errorf("expected an error naming gpt2 for a zero window, got %v", err)
+	}
+}
+
+func TestLoadConfigDiffThresholds(t *testing.T) {
+	path := writeConfig(t, "ted.config.yaml", `
+diff:
+  metric_thresholds:
+    token_count: 0
+    compression_compression_ratio: 0.05
+  max_changed_documents: 0
+`)
+	cfg, err := LoadConfig(path, WithStrict(true))
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+
+	// The default for every other metric is kept
+	want := map[string]float64{"*": 0.01, "token_count": 0, "compression_compression_ratio": 0.05}
+	if !reflect.DeepEqual(cfg.Diff.MetricThresholds, want) {
+		t.Errorf("metric thresholds = %v, want %v", cfg.Diff.MetricThresholds, want)
+	}
+	if cfg.Diff.MaxChangedDocuments != 0 || cfg.Diff.MaxVocabularyChange != -1 {
+		t.Errorf("diff = %+v, want no changed documents and any vocabulary change allowed", cfg.Diff)
+	}
+
+	cfg.Diff.MetricThresholds["token_count"] = -1
+	if err := cfg.ValidateConfig(); err == nil || !strings.Contains(err.Error(), "token_count") {
+		t.Errorf("expected an error naming token_count for a negative threshold, got %v", err)
+	}
+}
+
+func TestLoadConfigLogLevels(t *testing.T) {
+	path := writeConfig(t, "ted.config.yaml", `
+logging:
+  level: info
+  levels:
+    metrics: warn
+    tokenizers: debug
+  sampling:
+    every: 10
+    first: 1000
+`)
+	cfg, err := LoadConfig(path, WithStrict(true))
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	want := map[string]string{"metrics": "warn", "tokenizers": "debug"}
+	if !reflect.DeepEqual(cfg.Logging.Levels, want) || cfg.Logging.Sampling != (LogSamplingConfig{Every: 10, First: 1000}) {
+		t.Errorf("logging = %+v, want the module levels and sampling", cfg.Logging)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*LoggingConfig)
+		want   string
+	}{
+		{"unknown module", func(l *LoggingConfig) { l.Levels["metric"] = "warn" }, "module: metric"},
+		{"unknown level", func(l *LoggingConfig) { l.Levels["server"] = "loud" }, "server: loud"},
+		{"negative sampling", func(l *LoggingConfig) { l.Sampling.Every = -1 }, "sampling"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Logging.Levels = map[string]string{}
+			tt.modify(&cfg.Logging)
+			if err := cfg.ValidateConfig(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ValidateConfig() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangedSettings(t *testing.T) {
+	old := DefaultConfig()
+	if changed := ChangedSettings(old, DefaultConfig()); len(changed) != 0 {
+		t.Errorf("ChangedSettings() of equal configurations = %v, want none", changed)
+	}
+
+	new := DefaultConfig()
+	new.Server.Port = old.Server.Port + 1
+	new.Analysis.EntropyWindowSize = old.Analysis.EntropyWindowSize + 1
+	new.Tokenizers.Enabled = append([]string{}, old.Tokenizers.Enabled...)
+	new.Tokenizers.Configs = map[string]TokenizerDef{"gpt2": {Parameters: map[string]string{"vocab_path": "vocab.json"}}}
+	new.Visualization.Theme = "dark-custom"
+
+	want := []string{"tokenizers.configs", "analysis.entropy_window_size", "visualization.theme", "server.port"}
+	if changed := ChangedSettings(old, new); !reflect.DeepEqual(changed, want) {
+		t.Errorf("ChangedSettings() = %v, want %v", changed, want)
+	}
+
+	// An empty map equals a missing one
+	old.AnalysisOverrides, new = nil, DefaultConfig()
+	new.AnalysisOverrides = map[string]AnalysisOverride{}
+	if changed := ChangedSettings(old, new); len(changed) != 0 {
+		t.Errorf("ChangedSettings() with an empty map = %v, want none", changed)
+	}
+}
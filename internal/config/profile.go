@@ -0,0 +1,40 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Analysis profiles: presets of the analysis settings, chosen with analysis.profile
+const (
+	ProfileQuick      = "quick"      // token count and global entropy only
+	ProfileStandard   = "standard"   // the default settings
+	ProfileExhaustive = "exhaustive" // every metric, with bootstrap intervals and perturbations
+)
+
+// ProfileNames returns the names of the analysis profiles
+func ProfileNames() []string {
+	return []string{ProfileQuick, ProfileStandard, ProfileExhaustive}
+}
+
+// ProfileAnalysis returns the analysis settings of the named profile: the defaults,
+// with the profile's changes applied and its name recorded
+func ProfileAnalysis(name string) (AnalysisConfig, error) {
+	analysis := DefaultConfig().Analysis
+	analysis.Profile = name
+	switch name {
+	case ProfileQuick:
+		analysis.Metrics = []string{"token_count", "entropy_global_entropy"}
+		analysis.ReusePatterns = false
+		analysis.RollingEntropy = false
+	case ProfileStandard:
+	case ProfileExhaustive:
+		analysis.EntropyCI = true
+		analysis.ReusePatterns = true
+		analysis.RollingEntropy = true
+		analysis.Perturbations = true
+	default:
+		return AnalysisConfig{}, fmt.Errorf("unknown analysis profile: %s (use %s)", name, strings.Join(ProfileNames(), ", "))
+	}
+	return analysis, nil
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigProfiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		env     string
+		options []LoadOption
+		want    func(AnalysisConfig) AnalysisConfig
+	}{
+		{"no profile", "analysis:\n  entropy_window_size: 100\n", "", nil, func(a AnalysisConfig) AnalysisConfig { return a }},
+		{"quick", "analysis:\n  profile: quick\n", "", nil, func(a AnalysisConfig) AnalysisConfig {
+			a.Profile = ProfileQuick
+			a.Metrics = []string{"token_count", "entropy_global_entropy"}
+			a.ReusePatterns, a.RollingEntropy = false, false
+			return a
+		}},
+		{"explicit settings override the profile", "analysis:\n  profile: quick\n  rolling_entropy: true\n  entropy_window_size: 40\n", "", nil,
+			func(a AnalysisConfig) AnalysisConfig {
+				a.Profile = ProfileQuick
+				a.Metrics = []string{"token_count", "entropy_global_entropy"}
+				a.ReusePatterns = false
+				a.EntropyWindowSize = 40
+				return a
+			}},
+		{"exhaustive from the environment", "", "exhaustive", nil, func(a AnalysisConfig) AnalysisConfig {
+			a.Profile = ProfileExhaustive
+			a.EntropyCI, a.Perturbations = true, true
+			return a
+		}},
+		{"option over file", "analysis:\n  profile: quick\n", "", []LoadOption{WithProfile(ProfileStandard)}, func(a AnalysisConfig) AnalysisConfig {
+			a.Profile = ProfileStandard
+			return a
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, "ted.config.yaml", tt.content)
+			if tt.env != "" {
+				t.Setenv("TED_ANALYSIS_PROFILE", tt.env)
+			}
+			cfg, err := LoadConfig(path, tt.options...)
+			if err != nil {
+				t.Fatalf("LoadConfig returned error: %v", err)
+			}
+			if want := tt.want(DefaultConfig().Analysis); !reflect.DeepEqual(cfg.Analysis, want) {
+				t.Errorf("analysis = %+v, want %+v", cfg.Analysis, want)
+			}
+		})
+	}
+
+	if _, err := LoadConfig(writeConfig(t, "ted.config.yaml", "analysis:\n  profile: thorough\n")); err == nil || !strings.Contains(err.Error(), "thorough") {
+		t.Errorf("expected an error naming the unknown profile, got %v", err)
+	}
+}
+
+func TestEngineConfigExpandsProfile(t *testing.T) {
+	cfg := DefaultConfig()
+	analysis, err := ProfileAnalysis(ProfileQuick)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Analysis = analysis
+	window := 500
+	cfg.AnalysisOverrides = map[string]AnalysisOverride{"gpt2": {EntropyWindowSize: &window}}
+
+	engine := cfg.EngineConfig()
+	if !engine.NoReusePatterns || !engine.NoRollingEntropy || engine.Profile != ProfileQuick {
+		t.Errorf("engine config = %+v, want quick's settings", engine)
+	}
+	if !reflect.DeepEqual(engine.Metrics, analysis.Metrics) {
+		t.Errorf("engine metrics = %v, want %v", engine.Metrics, analysis.Metrics)
+	}
+	if got := engine.TokenizerOverrides["gpt2"].EntropyWindowSize; got == nil || *got != 500 {
+		t.Errorf("gpt2 window override = %v, want 500", got)
+	}
+	if defaults := DefaultConfig().EngineConfig(); defaults.NoReusePatterns || defaults.NoRollingEntropy || defaults.Profile != "" {
+		t.Errorf("default engine config = %+v, want patterns and rolling entropy on", defaults)
+	}
+}
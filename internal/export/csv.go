@@ -0,0 +1,110 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+)
+
+// longCSVWriter writes one row per document, tokenizer and metric
+type longCSVWriter struct {
+	writer *csv.Writer
+	header bool
+}
+
+func newLongCSVWriter(w io.Writer) *longCSVWriter {
+	return &longCSVWriter{writer: csv.NewWriter(w)}
+}
+
+// Write writes a result's metrics in sorted order, after the header row on the first call
+func (l *longCSVWriter) Write(result *metrics.AnalysisResult) error {
+	if !l.header {
+		l.header = true
+		if err := l.writer.Write([]string{"document", "tokenizer", "metric", "value"}); err != nil {
+			return fmt.Errorf("failed to write export row: %w", err)
+		}
+	}
+
+	document := documentLabel(result)
+	for _, name := range metricNames(result) {
+		record := []string{document, result.TokenizerName, name, formatValue(result.Metrics[name].Value)}
+		if err := l.writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write export row: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close flushes the rows written, writing the header alone when there were none
+func (l *longCSVWriter) Close() error {
+	if !l.header {
+		l.header = true
+		l.writer.Write([]string{"document", "tokenizer", "metric", "value"})
+	}
+	l.writer.Flush()
+	if err := l.writer.Error(); err != nil {
+		return fmt.Errorf("failed to flush export: %w", err)
+	}
+	return nil
+}
+
+// wideCSVWriter writes one row per document and tokenizer, with a column per metric.
+// The columns are only known once every result is in, so rows are held until Close.
+type wideCSVWriter struct {
+	writer  *csv.Writer
+	results []*metrics.AnalysisResult
+	columns map[string]bool
+}
+
+func newWideCSVWriter(w io.Writer) *wideCSVWriter {
+	return &wideCSVWriter{writer: csv.NewWriter(w), columns: make(map[string]bool)}
+}
+
+// Write holds a result until Close
+func (c *wideCSVWriter) Write(result *metrics.AnalysisResult) error {
+	c.results = append(c.results, result)
+	for name := range result.Metrics {
+		c.columns[name] = true
+	}
+	return nil
+}
+
+// Close writes the rows, with the metrics of every result as columns in sorted order.
+// A metric a result lacks is left empty.
+func (c *wideCSVWriter) Close() error {
+	columns := make([]string, 0, len(c.columns))
+	for name := range c.columns {
+		columns = append(columns, name)
+	}
+	sort.Strings(columns)
+
+	c.writer.Write(append([]string{"document", "tokenizer"}, columns...))
+	for _, result := range c.results {
+		record := make([]string, 2, 2+len(columns))
+		record[0], record[1] = documentLabel(result), result.TokenizerName
+		for _, name := range columns {
+			value := ""
+			if metric, ok := result.Metrics[name]; ok {
+				value = formatValue(metric.Value)
+			}
+			record = append(record, value)
+		}
+		c.writer.Write(record)
+	}
+	c.results = nil
+
+	c.writer.Flush()
+	if err := c.writer.Error(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	return nil
+}
+
+// formatValue formats a value for CSV without losing precision
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,203 @@
+// Package export writes analysis results to files for use outside TokEntropyDrift:
+// long and wide CSV, JSON lines and Parquet.
+package export
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+)
+
+// Export formats
+const (
+	FormatCSV     = "csv"      // long format: one row per document, tokenizer and metric
+	FormatCSVWide = "csv_wide" // one row per document and tokenizer, metrics as columns
+	FormatJSONL   = "jsonl"    // one full analysis result per line
+	FormatParquet = "parquet"  // long format, for large corpora
+)
+
+// Formats returns the supported export formats
+func Formats() []string {
+	return []string{FormatCSV, FormatCSVWide, FormatJSONL, FormatParquet}
+}
+
+// isFormat reports whether format is a supported export format
+func isFormat(format string) bool {
+	for _, supported := range Formats() {
+		if format == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// Writer writes analysis results one at a time, so a corpus can be exported as it is
+// analyzed
+type Writer interface {
+	// Write adds one analysis result
+	Write(result *metrics.AnalysisResult) error
+
+	// Close flushes anything buffered and releases the writer's resources
+	Close() error
+}
+
+// NewWriter creates a writer of format to w. Closing the writer flushes it but does
+// not close w.
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newLongCSVWriter(w), nil
+	case FormatCSVWide:
+		return newWideCSVWriter(w), nil
+	case FormatJSONL:
+		return newJSONLWriter(w), nil
+	case FormatParquet:
+		return newParquetWriter(w), nil
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s (use %s)", format, strings.Join(Formats(), ", "))
+	}
+}
+
+// Create creates a writer of format to a new file at path, replacing any file already
+// there. An empty format is chosen from the path's extension.
+func Create(path, format string) (Writer, error) {
+	if format == "" {
+		format = FormatForPath(path)
+	}
+	if !isFormat(format) {
+		return nil, fmt.Errorf("unsupported export format: %s (use %s)", format, strings.Join(Formats(), ", "))
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export file: %w", err)
+	}
+	writer, _ := NewWriter(file, format)
+	return &fileWriter{Writer: writer, file: file}, nil
+}
+
+// FormatForPath returns the export format of a file extension: .csv, .jsonl or
+// .parquet; anything else is JSON lines
+func FormatForPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV
+	case ".parquet":
+		return FormatParquet
+	default:
+		return FormatJSONL
+	}
+}
+
+// Extension returns the file extension of an export format
+func Extension(format string) string {
+	switch format {
+	case FormatCSV, FormatCSVWide:
+		return ".csv"
+	case FormatParquet:
+		return ".parquet"
+	default:
+		return ".jsonl"
+	}
+}
+
+// ContentType returns the MIME type of an export format
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV, FormatCSVWide:
+		return "text/csv"
+	case FormatParquet:
+		return "application/vnd.apache.parquet"
+	default:
+		return "application/x-ndjson"
+	}
+}
+
+// WriteResults writes analysis results to w in format
+func WriteResults(w io.Writer, format string, results []*metrics.AnalysisResult) error {
+	writer, err := NewWriter(w, format)
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		if err := writer.Write(result); err != nil {
+			writer.Close()
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// WriteComparisons writes the results of tokenizer comparisons to w in format, each
+// followed by its pairwise drift as in FlattenComparison
+func WriteComparisons(w io.Writer, format string, comparisons []*metrics.ComparisonResult) error {
+	var results []*metrics.AnalysisResult
+	for _, comparison := range comparisons {
+		results = append(results, FlattenComparison(comparison)...)
+	}
+	return WriteResults(w, format, results)
+}
+
+// FlattenComparison returns a comparison's analysis results followed by one result per
+// tokenizer pair, named like gpt2_vs_bert-base, whose metrics are the pair's drift
+func FlattenComparison(comparison *metrics.ComparisonResult) []*metrics.AnalysisResult {
+	results := append([]*metrics.AnalysisResult(nil), comparison.Results...)
+
+	var document, documentID string
+	if len(comparison.Results) > 0 {
+		document, documentID = comparison.Results[0].Document, comparison.Results[0].DocumentID
+	}
+	for _, pair := range comparison.Pairs {
+		pairMetrics := make(map[string]metrics.MetricResult, len(pair.Drift))
+		for name, value := range pair.Drift {
+			pairMetrics[name] = metrics.MetricResult{MetricName: name, TokenizerName: pair.Name(), Value: value}
+		}
+		results = append(results, &metrics.AnalysisResult{
+			Document:      document,
+			DocumentID:    documentID,
+			TokenizerName: pair.Name(),
+			Metrics:       pairMetrics,
+			Metadata:      pair.Metadata,
+		})
+	}
+	return results
+}
+
+// documentLabel names a result's document in tabular formats: its ID, or its text
+// when it has none
+func documentLabel(result *metrics.AnalysisResult) string {
+	if result.DocumentID != "" {
+		return result.DocumentID
+	}
+	return result.Document
+}
+
+// metricNames returns the names of a result's metrics in sorted order
+func metricNames(result *metrics.AnalysisResult) []string {
+	names := make([]string, 0, len(result.Metrics))
+	for name := range result.Metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fileWriter is a writer to a file it closes
+type fileWriter struct {
+	Writer
+	file *os.File
+}
+
+// Close flushes the writer and closes its file
+func (f *fileWriter) Close() error {
+	err := f.Writer.Close()
+	if closeErr := f.file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close export file: %w", closeErr)
+	}
+	return err
+}
@@ -0,0 +1,264 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+)
+
+// testResults returns analysis results for two documents and two tokenizers, where
+// bert-base lacks the compression metric on the second document
+func testResults() []*metrics.AnalysisResult {
+	result := func(doc, tokenizer string, values map[string]float64) *metrics.AnalysisResult {
+		resultMetrics := make(map[string]metrics.MetricResult, len(values))
+		for name, value := range values {
+			resultMetrics[name] = metrics.MetricResult{MetricName: name, TokenizerName: tokenizer, Value: value}
+		}
+		return &metrics.AnalysisResult{DocumentID: doc, Document: "text of " + doc, TokenizerName: tokenizer, Metrics: resultMetrics}
+	}
+	return []*metrics.AnalysisResult{
+		result("doc.txt:1", "gpt2", map[string]float64{"token_count": 12, "entropy_global_entropy": 3.1415926535, "compression_ratio": 0.1 + 0.2}),
+		result("doc.txt:1", "bert-base", map[string]float64{"token_count": 14, "entropy_global_entropy": 2.718281828, "compression_ratio": 1e-9}),
+		result("doc.txt:2", "gpt2", map[string]float64{"token_count": 3, "entropy_global_entropy": 1.5, "compression_ratio": 2.5}),
+		result("doc.txt:2", "bert-base", map[string]float64{"token_count": 4, "entropy_global_entropy": 1.25}),
+	}
+}
+
+// value is a metric value keyed by document, tokenizer and metric
+type value struct {
+	document, tokenizer, metric string
+}
+
+// expectedValues returns the metric values of results
+func expectedValues(results []*metrics.AnalysisResult) map[value]float64 {
+	values := make(map[value]float64)
+	for _, result := range results {
+		for name, metric := range result.Metrics {
+			values[value{documentLabel(result), result.TokenizerName, name}] = metric.Value
+		}
+	}
+	return values
+}
+
+// compareValues fails the test unless got has the same keys as want and values within
+// float tolerance
+func compareValues(t *testing.T, got, want map[value]float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("got %d values, want %d", len(got), len(want))
+	}
+	for key, expected := range want {
+		actual, ok := got[key]
+		if !ok {
+			t.Errorf("missing value for %+v", key)
+			continue
+		}
+		if math.Abs(actual-expected) > 1e-12*math.Max(1, math.Abs(expected)) {
+			t.Errorf("%+v = %v, want %v", key, actual, expected)
+		}
+	}
+}
+
+// parseFloat parses a CSV value, failing the test if it is not a number
+func parseFloat(t *testing.T, s string) float64 {
+	t.Helper()
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		t.Fatalf("invalid value %q: %v", s, err)
+	}
+	return f
+}
+
+func TestLongCSVRoundTrip(t *testing.T) {
+	results := testResults()
+	var buf bytes.Buffer
+	if err := WriteResults(&buf, FormatCSV, results); err != nil {
+		t.Fatalf("WriteResults returned error: %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read CSV back: %v", err)
+	}
+	if !reflect.DeepEqual(records[0], []string{"document", "tokenizer", "metric", "value"}) {
+		t.Errorf("header = %v", records[0])
+	}
+	// Metrics are written in sorted order
+	if records[1][2] != "compression_ratio" || records[2][2] != "entropy_global_entropy" {
+		t.Errorf("first rows = %v, want metrics in sorted order", records[1:3])
+	}
+
+	got := make(map[value]float64)
+	for _, record := range records[1:] {
+		got[value{record[0], record[1], record[2]}] = parseFloat(t, record[3])
+	}
+	compareValues(t, got, expectedValues(results))
+}
+
+func TestWideCSVRoundTrip(t *testing.T) {
+	results := testResults()
+	var buf bytes.Buffer
+	if err := WriteResults(&buf, FormatCSVWide, results); err != nil {
+		t.Fatalf("WriteResults returned error: %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read CSV back: %v", err)
+	}
+	header := []string{"document", "tokenizer", "compression_ratio", "entropy_global_entropy", "token_count"}
+	if !reflect.DeepEqual(records[0], header) {
+		t.Errorf("header = %v, want %v", records[0], header)
+	}
+	if len(records) != len(results)+1 {
+		t.Fatalf("got %d rows, want %d", len(records)-1, len(results))
+	}
+
+	got := make(map[value]float64)
+	for _, record := range records[1:] {
+		for i, metric := range header[2:] {
+			if record[i+2] == "" {
+				continue
+			}
+			got[value{record[0], record[1], metric}] = parseFloat(t, record[i+2])
+		}
+	}
+	compareValues(t, got, expectedValues(results))
+
+	// The missing metric is an empty cell
+	if last := records[len(records)-1]; last[2] != "" {
+		t.Errorf("bert-base compression_ratio on doc.txt:2 = %q, want empty", last[2])
+	}
+}
+
+func TestJSONLRoundTrip(t *testing.T) {
+	results := testResults()
+	var buf bytes.Buffer
+	if err := WriteResults(&buf, FormatJSONL, results); err != nil {
+		t.Fatalf("WriteResults returned error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(results) {
+		t.Fatalf("got %d lines, want %d", len(lines), len(results))
+	}
+	var decoded []*metrics.AnalysisResult
+	for _, line := range lines {
+		var result metrics.AnalysisResult
+		if err := json.Unmarshal([]byte(line), &result); err != nil {
+			t.Fatalf("invalid line %q: %v", line, err)
+		}
+		decoded = append(decoded, &result)
+	}
+	if decoded[0].Document != "text of doc.txt:1" {
+		t.Errorf("document = %q, want the full text", decoded[0].Document)
+	}
+	compareValues(t, expectedValues(decoded), expectedValues(results))
+
+	read, err := ReadJSONL(&buf)
+	if err != nil {
+		t.Fatalf("ReadJSONL returned error: %v", err)
+	}
+	compareValues(t, expectedValues(read), expectedValues(results))
+
+	if _, err := ReadJSONL(strings.NewReader(lines[0] + "\n{broken")); err == nil {
+		t.Error("expected an error for a truncated line")
+	}
+}
+
+func TestParquetRoundTrip(t *testing.T) {
+	results := testResults()
+	path := filepath.Join(t.TempDir(), "results.parquet")
+	writer, err := Create(path, "")
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	for _, result := range results {
+		if err := writer.Write(result); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := ReadParquet(file, info.Size())
+	if err != nil {
+		t.Fatalf("ReadParquet returned error: %v", err)
+	}
+
+	got := make(map[value]float64)
+	for _, row := range rows {
+		got[value{row.Document, row.Tokenizer, row.Metric}] = row.Value
+	}
+	compareValues(t, got, expectedValues(results))
+}
+
+func TestWriteComparisons(t *testing.T) {
+	comparison := &metrics.ComparisonResult{
+		Tokenizers: []string{"gpt2", "bert-base"},
+		Pairs:      []metrics.TokenizerPair{{A: "gpt2", B: "bert-base", Drift: map[string]float64{"drift_jaccard_distance": 0.4}}},
+		Results:    testResults()[:2],
+	}
+
+	var buf bytes.Buffer
+	if err := WriteComparisons(&buf, FormatCSV, []*metrics.ComparisonResult{comparison}); err != nil {
+		t.Fatalf("WriteComparisons returned error: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read CSV back: %v", err)
+	}
+	last := records[len(records)-1]
+	if !reflect.DeepEqual(last, []string{"doc.txt:1", "gpt2_vs_bert-base", "drift_jaccard_distance", "0.4"}) {
+		t.Errorf("pair row = %v", last)
+	}
+}
+
+func TestFormats(t *testing.T) {
+	if _, err := NewWriter(&bytes.Buffer{}, "xlsx"); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+	if _, err := Create(filepath.Join(t.TempDir(), "out.csv"), "xlsx"); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"out.csv", FormatCSV},
+		{"OUT.PARQUET", FormatParquet},
+		{"out.jsonl", FormatJSONL},
+		{"out", FormatJSONL},
+	}
+	for _, tt := range tests {
+		if got := FormatForPath(tt.path); got != tt.want {
+			t.Errorf("FormatForPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	// An empty export still has its header
+	var buf bytes.Buffer
+	if err := WriteResults(&buf, FormatCSV, nil); err != nil || buf.String() != "document,tokenizer,metric,value\n" {
+		t.Errorf("empty export = %q, %v", buf.String(), err)
+	}
+}
@@ -0,0 +1,56 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+)
+
+// jsonlWriter writes each full analysis result as one line of JSON
+type jsonlWriter struct {
+	writer *bufio.Writer
+}
+
+func newJSONLWriter(w io.Writer) *jsonlWriter {
+	return &jsonlWriter{writer: bufio.NewWriter(w)}
+}
+
+// Write writes result as a line of JSON
+func (j *jsonlWriter) Write(result *metrics.AnalysisResult) error {
+	line, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode analysis result: %w", err)
+	}
+	line = append(line, '\n')
+
+	if _, err := j.writer.Write(line); err != nil {
+		return fmt.Errorf("failed to write analysis result: %w", err)
+	}
+	return nil
+}
+
+// Close flushes buffered lines
+func (j *jsonlWriter) Close() error {
+	if err := j.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush export: %w", err)
+	}
+	return nil
+}
+
+// ReadJSONL reads back the analysis results of a JSONL export, one per line
+func ReadJSONL(r io.Reader) ([]*metrics.AnalysisResult, error) {
+	var results []*metrics.AnalysisResult
+	decoder := json.NewDecoder(r)
+	for {
+		var result metrics.AnalysisResult
+		if err := decoder.Decode(&result); err == io.EOF {
+			return results, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to decode analysis result %d: %w", len(results)+1, err)
+		}
+		results = append(results, &result)
+	}
+}
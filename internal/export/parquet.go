@@ -0,0 +1,58 @@
+package export
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+	"github.com/parquet-go/parquet-go"
+)
+
+// Row is a row of a Parquet export, in the long format of the CSV export
+type Row struct {
+	Document  string  `parquet:"document,dict"`
+	Tokenizer string  `parquet:"tokenizer,dict"`
+	Metric    string  `parquet:"metric,dict"`
+	Value     float64 `parquet:"value"`
+}
+
+// parquetWriter writes rows to a zstd-compressed Parquet file. The underlying writer
+// buffers a row group at a time, so large corpora need not fit in memory.
+type parquetWriter struct {
+	writer *parquet.GenericWriter[Row]
+	rows   []Row
+}
+
+func newParquetWriter(w io.Writer) *parquetWriter {
+	return &parquetWriter{writer: parquet.NewGenericWriter[Row](w, parquet.Compression(&parquet.Zstd))}
+}
+
+// Write writes a result's metrics in sorted order
+func (p *parquetWriter) Write(result *metrics.AnalysisResult) error {
+	document := documentLabel(result)
+	p.rows = p.rows[:0]
+	for _, name := range metricNames(result) {
+		p.rows = append(p.rows, Row{Document: document, Tokenizer: result.TokenizerName, Metric: name, Value: result.Metrics[name].Value})
+	}
+	if _, err := p.writer.Write(p.rows); err != nil {
+		return fmt.Errorf("failed to write export rows: %w", err)
+	}
+	return nil
+}
+
+// Close writes the last row group and the file footer
+func (p *parquetWriter) Close() error {
+	if err := p.writer.Close(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	return nil
+}
+
+// ReadParquet reads the rows of a Parquet export
+func ReadParquet(r io.ReaderAt, size int64) ([]Row, error) {
+	rows, err := parquet.Read[Row](r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Parquet export: %w", err)
+	}
+	return rows, nil
+}
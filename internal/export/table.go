@@ -0,0 +1,263 @@
+package export
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+	"github.com/RevBooyah/TokEntropyDrift/internal/stats"
+)
+
+// TableOptions formats a summary table
+type TableOptions struct {
+	Digits int  // significant digits of each value; 0 uses 3
+	Std    bool // show the standard deviation over documents in parentheses
+	Bold   bool // bold the best mean of each column whose metric has a direction
+}
+
+// SummaryTable holds the mean and standard deviation over documents of each metric,
+// per tokenizer, for a paper-ready tokenizer × metric table
+type SummaryTable struct {
+	Tokenizers []string    // rows, in the order first seen
+	Metrics    []string    // columns
+	Mean       [][]float64 // by tokenizer, then metric; NaN where no document has the metric
+	Std        [][]float64
+	Documents  [][]int // documents averaged over
+}
+
+// Summarize averages each metric over the documents of each tokenizer. The columns are
+// metricNames in order, or every metric of the results in sorted order when empty.
+func Summarize(results []*metrics.AnalysisResult, metricNames []string) *SummaryTable {
+	if len(metricNames) == 0 {
+		seen := make(map[string]bool)
+		for _, result := range results {
+			for name := range result.Metrics {
+				if !seen[name] {
+					seen[name] = true
+					metricNames = append(metricNames, name)
+				}
+			}
+		}
+		sort.Strings(metricNames)
+	}
+
+	var tokenizerNames []string
+	values := make(map[string][][]float64)
+	for _, result := range results {
+		row, ok := values[result.TokenizerName]
+		if !ok {
+			tokenizerNames = append(tokenizerNames, result.TokenizerName)
+			row = make([][]float64, len(metricNames))
+		}
+		for j, name := range metricNames {
+			if metric, ok := result.Metrics[name]; ok {
+				row[j] = append(row[j], metric.Value)
+			}
+		}
+		values[result.TokenizerName] = row
+	}
+
+	table := &SummaryTable{Tokenizers: tokenizerNames, Metrics: metricNames}
+	for _, name := range tokenizerNames {
+		mean := make([]float64, len(metricNames))
+		std := make([]float64, len(metricNames))
+		documents := make([]int, len(metricNames))
+		for j, column := range values[name] {
+			mean[j], std[j], documents[j] = math.NaN(), math.NaN(), len(column)
+			if len(column) > 0 {
+				mean[j], std[j] = stats.Mean(column), stats.Std(column)
+			}
+		}
+		table.Mean = append(table.Mean, mean)
+		table.Std = append(table.Std, std)
+		table.Documents = append(table.Documents, documents)
+	}
+	return table
+}
+
+// Markdown renders the table as GitHub-flavored Markdown. Metric headers carry an arrow
+// pointing to their better direction.
+func (t *SummaryTable) Markdown(options TableOptions) string {
+	var b strings.Builder
+	b.WriteString("| Tokenizer |")
+	for _, name := range t.Metrics {
+		b.WriteString(" " + escapeMarkdown(name) + markdownArrow(metrics.MetricDirection(name)) + " |")
+	}
+	b.WriteString("\n|---|")
+	for range t.Metrics {
+		b.WriteString("---:|")
+	}
+	b.WriteString("\n")
+
+	best := t.best()
+	for i, tokenizer := range t.Tokenizers {
+		b.WriteString("| " + escapeMarkdown(tokenizer) + " |")
+		for j := range t.Metrics {
+			cell := t.cell(i, j, options, "-")
+			if options.Bold && best[j][i] {
+				cell = "**" + cell + "**"
+			}
+			b.WriteString(" " + cell + " |")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// LaTeX renders the table as a tabular environment with booktabs rules
+func (t *SummaryTable) LaTeX(options TableOptions) string {
+	var b strings.Builder
+	b.WriteString("\\begin{tabular}{l" + strings.Repeat("r", len(t.Metrics)) + "}\n\\toprule\nTokenizer")
+	for _, name := range t.Metrics {
+		b.WriteString(" & " + escapeLaTeX(name) + latexArrow(metrics.MetricDirection(name)))
+	}
+	b.WriteString(" \\\\\n\\midrule\n")
+
+	best := t.best()
+	for i, tokenizer := range t.Tokenizers {
+		b.WriteString(escapeLaTeX(tokenizer))
+		for j := range t.Metrics {
+			cell := t.cell(i, j, options, "--")
+			if options.Bold && best[j][i] {
+				cell = "\\textbf{" + cell + "}"
+			}
+			b.WriteString(" & " + cell)
+		}
+		b.WriteString(" \\\\\n")
+	}
+	b.WriteString("\\bottomrule\n\\end{tabular}\n")
+	return b.String()
+}
+
+// WriteTables writes the table to dir as name.md and name.tex, returning their paths
+func WriteTables(dir, name string, table *SummaryTable, options TableOptions) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create report directory: %w", err)
+	}
+
+	var paths []string
+	for _, file := range []struct {
+		extension string
+		content   string
+	}{
+		{".md", table.Markdown(options)},
+		{".tex", table.LaTeX(options)},
+	} {
+		path := filepath.Join(dir, name+file.extension)
+		if err := os.WriteFile(path, []byte(file.content), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write table: %w", err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// cell formats the mean of a tokenizer and metric, followed by the standard deviation
+// when requested
+func (t *SummaryTable) cell(i, j int, options TableOptions, missing string) string {
+	if math.IsNaN(t.Mean[i][j]) {
+		return missing
+	}
+	digits := options.Digits
+	if digits <= 0 {
+		digits = 3
+	}
+	cell := formatSignificant(t.Mean[i][j], digits)
+	if options.Std {
+		cell += " (" + formatSignificant(t.Std[i][j], digits) + ")"
+	}
+	return cell
+}
+
+// best marks, for each metric with a direction, the tokenizers with the best mean; ties
+// are all marked
+func (t *SummaryTable) best() [][]bool {
+	best := make([][]bool, len(t.Metrics))
+	for j, name := range t.Metrics {
+		best[j] = make([]bool, len(t.Tokenizers))
+		direction := metrics.MetricDirection(name)
+		if direction == metrics.Neutral {
+			continue
+		}
+
+		bestValue := math.NaN()
+		for i := range t.Tokenizers {
+			value := t.Mean[i][j]
+			if math.IsNaN(value) {
+				continue
+			}
+			if math.IsNaN(bestValue) || (direction == metrics.HigherIsBetter && value > bestValue) || (direction == metrics.LowerIsBetter && value < bestValue) {
+				bestValue = value
+			}
+		}
+		for i := range t.Tokenizers {
+			best[j][i] = t.Mean[i][j] == bestValue
+		}
+	}
+	return best
+}
+
+// formatSignificant formats a value rounded to digits significant digits, in fixed
+// notation so columns line up
+func formatSignificant(value float64, digits int) string {
+	if value == 0 || math.IsInf(value, 0) {
+		return strconv.FormatFloat(value, 'f', digits-1, 64)
+	}
+	// Round first, so 9.996 to three digits becomes 10.0 rather than 10.00
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(value, 'g', digits, 64), 64)
+	decimals := digits - 1 - int(math.Floor(math.Log10(math.Abs(rounded))))
+	return strconv.FormatFloat(rounded, 'f', max(decimals, 0), 64)
+}
+
+// markdownArrow marks a column header with the better direction of its metric
+func markdownArrow(direction metrics.Direction) string {
+	switch direction {
+	case metrics.HigherIsBetter:
+		return " ↑"
+	case metrics.LowerIsBetter:
+		return " ↓"
+	default:
+		return ""
+	}
+}
+
+// latexArrow marks a column header with the better direction of its metric
+func latexArrow(direction metrics.Direction) string {
+	switch direction {
+	case metrics.HigherIsBetter:
+		return " $\\uparrow$"
+	case metrics.LowerIsBetter:
+		return " $\\downarrow$"
+	default:
+		return ""
+	}
+}
+
+// escapeMarkdown escapes the characters that would break a Markdown table cell
+func escapeMarkdown(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+// latexEscaper escapes LaTeX's special characters
+var latexEscaper = strings.NewReplacer(
+	"\\", "\\textbackslash{}",
+	"&", "\\&",
+	"%", "\\%",
+	"$", "\\$",
+	"#", "\\#",
+	"_", "\\_",
+	"{", "\\{",
+	"}", "\\}",
+	"~", "\\textasciitilde{}",
+	"^", "\\textasciicircum{}",
+)
+
+// escapeLaTeX escapes a name for LaTeX text
+func escapeLaTeX(s string) string {
+	return latexEscaper.Replace(s)
+}
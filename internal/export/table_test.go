@@ -0,0 +1,103 @@
+package export
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	table := Summarize(testResults(), []string{"token_count", "compression_ratio"})
+
+	if strings.Join(table.Tokenizers, ",") != "gpt2,bert-base" {
+		t.Errorf("tokenizers = %v, want first-seen order", table.Tokenizers)
+	}
+	// gpt2 has 12 and 3 tokens; bert-base has compression_ratio on one document only
+	if table.Mean[0][0] != 7.5 || table.Std[0][0] != 4.5 {
+		t.Errorf("gpt2 token_count = %v (%v), want 7.5 (4.5)", table.Mean[0][0], table.Std[0][0])
+	}
+	if table.Documents[1][1] != 1 || table.Mean[1][1] != 1e-9 {
+		t.Errorf("bert-base compression_ratio = %v over %d documents, want 1e-9 over 1", table.Mean[1][1], table.Documents[1][1])
+	}
+
+	// Without a selection every metric is a column, in sorted order
+	if all := Summarize(testResults(), nil); strings.Join(all.Metrics, ",") != "compression_ratio,entropy_global_entropy,token_count" {
+		t.Errorf("metrics = %v", all.Metrics)
+	}
+
+	// A metric no document has is missing
+	if missing := Summarize(testResults(), []string{"fertility_whole_word_ratio"}); !math.IsNaN(missing.Mean[0][0]) {
+		t.Errorf("missing metric mean = %v, want NaN", missing.Mean[0][0])
+	}
+}
+
+func TestSummaryTableMarkdown(t *testing.T) {
+	table := Summarize(testResults(), []string{"token_count", "entropy_global_entropy", "fertility_whole_word_ratio"})
+
+	got := table.Markdown(TableOptions{Digits: 2, Std: true, Bold: true})
+	want := "| Tokenizer | token_count ↓ | entropy_global_entropy | fertility_whole_word_ratio ↑ |\n" +
+		"|---|---:|---:|---:|\n" +
+		"| gpt2 | **7.5 (4.5)** | 2.3 (0.82) | - |\n" +
+		"| bert-base | 9.0 (5.0) | 2.0 (0.73) | - |\n"
+	if got != want {
+		t.Errorf("Markdown() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSummaryTableLaTeX(t *testing.T) {
+	results := testResults()
+	for _, result := range results {
+		result.TokenizerName = strings.ReplaceAll(result.TokenizerName, "-", "_")
+	}
+	table := Summarize(results, []string{"compression_ratio", "token_count"})
+
+	got := table.LaTeX(TableOptions{Bold: true})
+	want := "\\begin{tabular}{lrr}\n" +
+		"\\toprule\n" +
+		"Tokenizer & compression\\_ratio $\\downarrow$ & token\\_count $\\downarrow$ \\\\\n" +
+		"\\midrule\n" +
+		"gpt2 & 1.40 & \\textbf{7.50} \\\\\n" +
+		"bert\\_base & \\textbf{0.00000000100} & 9.00 \\\\\n" +
+		"\\bottomrule\n" +
+		"\\end{tabular}\n"
+	if got != want {
+		t.Errorf("LaTeX() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFormatSignificant(t *testing.T) {
+	tests := []struct {
+		value  float64
+		digits int
+		want   string
+	}{
+		{3.14159, 3, "3.14"},
+		{12345, 3, "12300"},
+		{0.00123456, 2, "0.0012"},
+		{9.996, 3, "10.0"},
+		{-0.5, 3, "-0.500"},
+		{0, 3, "0.00"},
+	}
+	for _, tt := range tests {
+		if got := formatSignificant(tt.value, tt.digits); got != tt.want {
+			t.Errorf("formatSignificant(%v, %d) = %q, want %q", tt.value, tt.digits, got, tt.want)
+		}
+	}
+}
+
+func TestWriteTables(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "reports")
+	paths, err := WriteTables(dir, "summary", Summarize(testResults(), nil), TableOptions{})
+	if err != nil {
+		t.Fatalf("WriteTables returned error: %v", err)
+	}
+	if len(paths) != 2 || filepath.Ext(paths[0]) != ".md" || filepath.Ext(paths[1]) != ".tex" {
+		t.Fatalf("paths = %v, want a .md and a .tex file", paths)
+	}
+	content, err := os.ReadFile(paths[1])
+	if err != nil || !strings.HasPrefix(string(content), "\\begin{tabular}") {
+		t.Errorf("LaTeX file = %q, %v", content, err)
+	}
+}
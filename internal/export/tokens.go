@@ -0,0 +1,164 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/loader"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// TokenDumpOptions selects what a token dump includes
+type TokenDumpOptions struct {
+	IDsOnly bool // omit token text, keeping IDs and offsets
+	Summary bool // end the dump with a record of each document's token counts
+}
+
+// TokenRecord is one line of a token dump: the tokens of a document under one tokenizer
+type TokenRecord struct {
+	Document   string        `json:"document"`
+	Tokenizer  string        `json:"tokenizer"`
+	TokenCount int           `json:"token_count"`
+	Tokens     []DumpedToken `json:"tokens"`
+}
+
+// DumpedToken is a token of a TokenRecord. Start and End are byte offsets into the
+// document; Text is empty when the dump omits it.
+type DumpedToken struct {
+	Text  string `json:"text,omitempty"`
+	ID    int    `json:"id"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// TokenSummary is the trailing record of a dump with a summary
+type TokenSummary struct {
+	Documents   int                   `json:"documents"`
+	Tokenizers  []string              `json:"tokenizers"`
+	TokenCounts []DocumentTokenCounts `json:"token_counts"`
+}
+
+// DocumentTokenCounts holds the token count of a document under each tokenizer
+type DocumentTokenCounts struct {
+	Document string         `json:"document"`
+	Counts   map[string]int `json:"counts"`
+}
+
+// TokenDump writes tokenizations as JSON lines, one per document per tokenizer. Each
+// line is written as soon as it is encoded, so only the summary counts are kept.
+type TokenDump struct {
+	writer  io.Writer
+	options TokenDumpOptions
+
+	tokenizers []string
+	counts     []DocumentTokenCounts
+	documents  map[string]int // index into counts
+}
+
+// NewTokenDump creates a token dump writing to w
+func NewTokenDump(w io.Writer, options TokenDumpOptions) *TokenDump {
+	return &TokenDump{
+		writer:    w,
+		options:   options,
+		documents: make(map[string]int),
+	}
+}
+
+// Write writes the tokenization of the document with the given ID as one line
+func (d *TokenDump) Write(documentID string, result *tokenizers.TokenizationResult) error {
+	record := TokenRecord{
+		Document:   documentID,
+		Tokenizer:  result.Tokenizer,
+		TokenCount: len(result.Tokens),
+		Tokens:     make([]DumpedToken, len(result.Tokens)),
+	}
+	for i, token := range result.Tokens {
+		record.Tokens[i] = DumpedToken{ID: token.ID, Start: token.StartPos, End: token.EndPos}
+		if !d.options.IDsOnly {
+			record.Tokens[i].Text = token.Text
+		}
+	}
+	if err := d.writeLine(record); err != nil {
+		return fmt.Errorf("failed to write tokens: %w", err)
+	}
+
+	if d.options.Summary {
+		d.count(documentID, result.Tokenizer, len(result.Tokens))
+	}
+	return nil
+}
+
+// Close writes the summary record when the dump has one. It does not close the
+// underlying writer.
+func (d *TokenDump) Close() error {
+	if !d.options.Summary {
+		return nil
+	}
+	summary := struct {
+		Summary TokenSummary `json:"summary"`
+	}{TokenSummary{
+		Documents:   len(d.counts),
+		Tokenizers:  d.tokenizers,
+		TokenCounts: d.counts,
+	}}
+	if err := d.writeLine(summary); err != nil {
+		return fmt.Errorf("failed to write token summary: %w", err)
+	}
+	return nil
+}
+
+// TokenizeTo tokenizes each document with each tokenizer and writes the tokenizations
+// to the dump as it goes, stopping at the first error or when ctx is done
+func TokenizeTo(ctx context.Context, dump *TokenDump, documents []loader.Document, tokenizerList []tokenizers.Tokenizer) error {
+	for _, document := range documents {
+		for _, tokenizer := range tokenizerList {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			result, err := tokenizer.Tokenize(ctx, document.Content)
+			if err != nil {
+				return fmt.Errorf("failed to tokenize document %s with %s: %w", document.ID, tokenizer.Name(), err)
+			}
+			if err := dump.Write(document.ID, result); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeLine writes value as a line of JSON with a single write
+func (d *TokenDump) writeLine(value interface{}) error {
+	line, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = d.writer.Write(append(line, '\n'))
+	return err
+}
+
+// count records a token count for the summary, keeping documents and tokenizers in
+// the order first seen
+func (d *TokenDump) count(documentID, tokenizer string, tokens int) {
+	index, ok := d.documents[documentID]
+	if !ok {
+		index = len(d.counts)
+		d.documents[documentID] = index
+		d.counts = append(d.counts, DocumentTokenCounts{Document: documentID, Counts: make(map[string]int)})
+	}
+	if _, seen := d.counts[index].Counts[tokenizer]; !seen {
+		known := false
+		for _, name := range d.tokenizers {
+			if name == tokenizer {
+				known = true
+				break
+			}
+		}
+		if !known {
+			d.tokenizers = append(d.tokenizers, tokenizer)
+		}
+	}
+	d.counts[index].Counts[tokenizer] = tokens
+}
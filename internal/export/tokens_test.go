@@ -0,0 +1,106 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/loader"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// testTokenizers returns two initialized mock tokenizers
+func testTokenizers(t *testing.T) []tokenizers.Tokenizer {
+	var list []tokenizers.Tokenizer
+	for _, name := range []string{"a", "b"} {
+		tokenizer := tokenizers.NewMockTokenizer(name)
+		if err := tokenizer.Initialize(tokenizers.TokenizerConfig{Name: name, Type: "custom"}); err != nil {
+			t.Fatalf("failed to initialize mock tokenizer: %v", err)
+		}
+		list = append(list, tokenizer)
+	}
+	return list
+}
+
+func TestTokenizeTo(t *testing.T) {
+	documents := []loader.Document{
+		{ID: "d1", Content: "hello world"},
+		{ID: "d2", Content: "one two three"},
+	}
+
+	tests := []struct {
+		name     string
+		options  TokenDumpOptions
+		lines    int
+		wantText bool
+	}{
+		{"tokens", TokenDumpOptions{}, 4, true},
+		{"ids only", TokenDumpOptions{IDsOnly: true}, 4, false},
+		{"summary", TokenDumpOptions{Summary: true}, 5, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			dump := NewTokenDump(&buf, tt.options)
+			if err := TokenizeTo(context.Background(), dump, documents, testTokenizers(t)); err != nil {
+				t.Fatalf("TokenizeTo returned error: %v", err)
+			}
+			if err := dump.Close(); err != nil {
+				t.Fatalf("Close returned error: %v", err)
+			}
+
+			lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+			if len(lines) != tt.lines {
+				t.Fatalf("got %d lines, want %d:\n%s", len(lines), tt.lines, buf.String())
+			}
+
+			var record TokenRecord
+			if err := json.Unmarshal([]byte(lines[1]), &record); err != nil {
+				t.Fatalf("invalid record %q: %v", lines[1], err)
+			}
+			if record.Document != "d1" || record.Tokenizer != "b" || record.TokenCount != 2 {
+				t.Errorf("record = %+v, want d1 with tokenizer b and 2 tokens", record)
+			}
+			second := record.Tokens[1]
+			if second.Start != 6 || second.End != 11 {
+				t.Errorf("offsets = %d-%d, want 6-11", second.Start, second.End)
+			}
+			if hasText := second.Text == "world"; hasText != tt.wantText {
+				t.Errorf("text = %q, want text %v", second.Text, tt.wantText)
+			}
+			if !tt.wantText && strings.Contains(lines[1], `"text"`) {
+				t.Errorf("record %q has a text field", lines[1])
+			}
+
+			if !tt.options.Summary {
+				return
+			}
+			var summary struct {
+				Summary TokenSummary `json:"summary"`
+			}
+			if err := json.Unmarshal([]byte(lines[4]), &summary); err != nil {
+				t.Fatalf("invalid summary %q: %v", lines[4], err)
+			}
+			got := summary.Summary
+			if got.Documents != 2 || strings.Join(got.Tokenizers, ",") != "a,b" {
+				t.Errorf("summary = %+v, want 2 documents and tokenizers a,b", got)
+			}
+			if got.TokenCounts[1].Document != "d2" || got.TokenCounts[1].Counts["a"] != 3 {
+				t.Errorf("token counts = %+v, want d2 with 3 tokens", got.TokenCounts)
+			}
+		})
+	}
+}
+
+func TestTokenizeToStopsWhenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var buf bytes.Buffer
+	err := TokenizeTo(ctx, NewTokenDump(&buf, TokenDumpOptions{}), []loader.Document{{ID: "d1", Content: "text"}}, testTokenizers(t))
+	if err == nil || buf.Len() != 0 {
+		t.Errorf("TokenizeTo = %v with %d bytes written, want an error and no output", err, buf.Len())
+	}
+}
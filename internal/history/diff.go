@@ -0,0 +1,240 @@
+// Package history compares saved analysis runs of the same corpus, to track how
+// tokenization drifts across tokenizer library upgrades
+package history
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/loader"
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+	"github.com/RevBooyah/TokEntropyDrift/internal/stats"
+)
+
+// AnyMetric is the Thresholds.Metrics key applying to metrics without their own entry
+const AnyMetric = "*"
+
+// Thresholds bound the changes between two runs before their diff fails
+type Thresholds struct {
+	Metrics          map[string]float64 // largest allowed relative change of a metric's mean, by metric or AnyMetric
+	ChangedDocuments int                // largest allowed number of documents per tokenizer whose token count changed; negative allows any
+	VocabularyChange int                // largest allowed number of tokens per tokenizer appearing or disappearing; negative allows any
+}
+
+// Diff holds the changes between a baseline run and a current run
+type Diff struct {
+	Tokenizers     []TokenizerDiff `json:"tokenizers"`
+	OnlyInBaseline []string        `json:"only_in_baseline,omitempty"` // tokenizers the current run lacks
+	OnlyInCurrent  []string        `json:"only_in_current,omitempty"`
+	Violations     []string        `json:"violations,omitempty"` // changes beyond the thresholds
+}
+
+// TokenizerDiff holds the changes of one tokenizer between the runs. Metrics and
+// token counts are compared over the documents both runs have.
+type TokenizerDiff struct {
+	Tokenizer          string           `json:"tokenizer"`
+	Documents          int              `json:"documents"`           // documents in both runs
+	UnmatchedDocuments int              `json:"unmatched_documents"` // documents in only one run
+	Metrics            []MetricDelta    `json:"metrics"`
+	ChangedDocuments   []DocumentChange `json:"changed_documents,omitempty"` // token count changed
+
+	// The aggregate vocabulary is compared only when both runs kept their tokens
+	VocabularyCompared bool     `json:"vocabulary_compared"`
+	AddedTokens        []string `json:"added_tokens,omitempty"`
+	RemovedTokens      []string `json:"removed_tokens,omitempty"`
+}
+
+// MetricDelta is the change of a metric's mean over documents between the runs
+type MetricDelta struct {
+	Metric   string  `json:"metric"`
+	Baseline float64 `json:"baseline"`
+	Current  float64 `json:"current"`
+	Delta    float64 `json:"delta"`
+	// Relative is Delta as a fraction of the baseline, or nil when the baseline is 0
+	Relative *float64 `json:"relative,omitempty"`
+}
+
+// DocumentChange is a document whose token count changed
+type DocumentChange struct {
+	Document string `json:"document"`
+	Baseline int    `json:"baseline"`
+	Current  int    `json:"current"`
+}
+
+// Failed reports whether any change exceeded its threshold
+func (d *Diff) Failed() bool {
+	return len(d.Violations) > 0
+}
+
+// Compare diffs two runs' analysis results, tokenizer by tokenizer, and records the
+// changes that exceed the thresholds as violations. Documents are matched by ID, or by
+// content when they have none.
+func Compare(baseline, current []*metrics.AnalysisResult, thresholds Thresholds) *Diff {
+	baselineRuns, baselineOrder := groupByTokenizer(baseline)
+	currentRuns, currentOrder := groupByTokenizer(current)
+
+	diff := &Diff{}
+	for _, tokenizer := range baselineOrder {
+		currentRun, ok := currentRuns[tokenizer]
+		if !ok {
+			diff.OnlyInBaseline = append(diff.OnlyInBaseline, tokenizer)
+			continue
+		}
+		tokenizerDiff := compareTokenizer(tokenizer, baselineRuns[tokenizer], currentRun)
+		diff.Tokenizers = append(diff.Tokenizers, tokenizerDiff)
+		diff.Violations = append(diff.Violations, tokenizerDiff.violations(thresholds)...)
+	}
+	for _, tokenizer := range currentOrder {
+		if _, ok := baselineRuns[tokenizer]; !ok {
+			diff.OnlyInCurrent = append(diff.OnlyInCurrent, tokenizer)
+		}
+	}
+	return diff
+}
+
+// run holds a tokenizer's results of one run, keyed by document
+type run struct {
+	results map[string]*metrics.AnalysisResult
+	order   []string
+}
+
+// groupByTokenizer splits results by tokenizer, keeping tokenizers and documents in the
+// order first seen
+func groupByTokenizer(results []*metrics.AnalysisResult) (map[string]*run, []string) {
+	runs := make(map[string]*run)
+	var order []string
+	for _, result := range results {
+		r, ok := runs[result.TokenizerName]
+		if !ok {
+			r = &run{results: make(map[string]*metrics.AnalysisResult)}
+			runs[result.TokenizerName] = r
+			order = append(order, result.TokenizerName)
+		}
+
+		// A repeated document is told apart by its occurrence, so duplicates still pair up
+		key := documentKey(result)
+		for n := 2; r.results[key] != nil; n++ {
+			key = fmt.Sprintf("%s#%d", documentKey(result), n)
+		}
+		r.results[key] = result
+		r.order = append(r.order, key)
+	}
+	return runs, order
+}
+
+// documentKey names a result's document by its ID, or by the ID of its content
+func documentKey(result *metrics.AnalysisResult) string {
+	if result.DocumentID != "" {
+		return result.DocumentID
+	}
+	return loader.ContentID(result.Document)
+}
+
+// compareTokenizer diffs one tokenizer's results over the documents both runs have
+func compareTokenizer(tokenizer string, baseline, current *run) TokenizerDiff {
+	diff := TokenizerDiff{Tokenizer: tokenizer, VocabularyCompared: true}
+	baselineValues := make(map[string][]float64)
+	currentValues := make(map[string][]float64)
+	baselineVocabulary := make(map[string]bool)
+	currentVocabulary := make(map[string]bool)
+
+	for _, key := range baseline.order {
+		before := baseline.results[key]
+		after, ok := current.results[key]
+		if !ok {
+			diff.UnmatchedDocuments++
+			continue
+		}
+		diff.Documents++
+
+		if before.TokenCount != after.TokenCount {
+			diff.ChangedDocuments = append(diff.ChangedDocuments, DocumentChange{Document: key, Baseline: before.TokenCount, Current: after.TokenCount})
+		}
+		// Only metrics both runs computed are comparable
+		for name, metric := range before.Metrics {
+			if other, ok := after.Metrics[name]; ok {
+				baselineValues[name] = append(baselineValues[name], metric.Value)
+				currentValues[name] = append(currentValues[name], other.Value)
+			}
+		}
+		if before.Tokenization == nil || after.Tokenization == nil {
+			diff.VocabularyCompared = false
+			continue
+		}
+		for _, token := range before.Tokenization.Tokens {
+			baselineVocabulary[token.Text] = true
+		}
+		for _, token := range after.Tokenization.Tokens {
+			currentVocabulary[token.Text] = true
+		}
+	}
+	for _, key := range current.order {
+		if _, ok := baseline.results[key]; !ok {
+			diff.UnmatchedDocuments++
+		}
+	}
+
+	names := make([]string, 0, len(baselineValues))
+	for name := range baselineValues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		diff.Metrics = append(diff.Metrics, newMetricDelta(name, stats.Mean(baselineValues[name]), stats.Mean(currentValues[name])))
+	}
+
+	diff.VocabularyCompared = diff.VocabularyCompared && diff.Documents > 0
+	if diff.VocabularyCompared {
+		diff.AddedTokens = missingFrom(baselineVocabulary, currentVocabulary)
+		diff.RemovedTokens = missingFrom(currentVocabulary, baselineVocabulary)
+	}
+	return diff
+}
+
+// newMetricDelta returns the change of a metric's mean from baseline to current
+func newMetricDelta(name string, baseline, current float64) MetricDelta {
+	delta := MetricDelta{Metric: name, Baseline: baseline, Current: current, Delta: current - baseline}
+	if baseline != 0 {
+		relative := delta.Delta / math.Abs(baseline)
+		delta.Relative = &relative
+	}
+	return delta
+}
+
+// missingFrom returns the sorted tokens of vocabulary that reference lacks
+func missingFrom(reference, vocabulary map[string]bool) []string {
+	var missing []string
+	for token := range vocabulary {
+		if !reference[token] {
+			missing = append(missing, token)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// violations describes the changes of the tokenizer that exceed the thresholds
+func (d *TokenizerDiff) violations(thresholds Thresholds) []string {
+	var violations []string
+	for _, delta := range d.Metrics {
+		limit, ok := thresholds.Metrics[delta.Metric]
+		if !ok {
+			limit, ok = thresholds.Metrics[AnyMetric]
+		}
+		if !ok || delta.Delta == 0 {
+			continue
+		}
+		// A change from a baseline of 0 has no relative size and exceeds any limit
+		if delta.Relative == nil || math.Abs(*delta.Relative) > limit {
+			violations = append(violations, fmt.Sprintf("%s: %s changed by %s (limit %s)", d.Tokenizer, delta.Metric, formatRelative(delta), formatPercent(limit)))
+		}
+	}
+	if thresholds.ChangedDocuments >= 0 && len(d.ChangedDocuments) > thresholds.ChangedDocuments {
+		violations = append(violations, fmt.Sprintf("%s: token count changed in %d documents (limit %d)", d.Tokenizer, len(d.ChangedDocuments), thresholds.ChangedDocuments))
+	}
+	if changed := len(d.AddedTokens) + len(d.RemovedTokens); thresholds.VocabularyChange >= 0 && changed > thresholds.VocabularyChange {
+		violations = append(violations, fmt.Sprintf("%s: %d tokens appeared and %d disappeared (limit %d)", d.Tokenizer, len(d.AddedTokens), len(d.RemovedTokens), thresholds.VocabularyChange))
+	}
+	return violations
+}
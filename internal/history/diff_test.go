@@ -0,0 +1,174 @@
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// result returns an analysis result with the given tokens, whose count is also its
+// token_count metric
+func result(doc, tokenizer string, tokens ...string) *metrics.AnalysisResult {
+	tokenization := &tokenizers.TokenizationResult{Tokenizer: tokenizer}
+	for i, text := range tokens {
+		tokenization.Tokens = append(tokenization.Tokens, tokenizers.Token{Text: text, ID: i})
+	}
+	return &metrics.AnalysisResult{
+		DocumentID:    doc,
+		TokenizerName: tokenizer,
+		TokenCount:    len(tokens),
+		Metrics: map[string]metrics.MetricResult{
+			"token_count":  {Value: float64(len(tokens))},
+			"empty_tokens": {Value: 0},
+		},
+		Tokenization: tokenization,
+	}
+}
+
+func testRuns() (baseline, current []*metrics.AnalysisResult) {
+	baseline = []*metrics.AnalysisResult{
+		result("d1", "gpt2", "Hello", " world"),
+		result("d2", "gpt2", "to", "ken", "izer"),
+		result("d1", "bert", "hello", "world"),
+		result("d3", "bert", "gone"),
+	}
+	current = []*metrics.AnalysisResult{
+		result("d1", "gpt2", "Hello", " world"),
+		result("d2", "gpt2", "token", "izer"),
+		result("d1", "bert", "hello", "world"),
+		result("d1", "t5", "▁hello", "▁world"),
+	}
+	return baseline, current
+}
+
+func TestCompare(t *testing.T) {
+	baseline, current := testRuns()
+	diff := Compare(baseline, current, Thresholds{ChangedDocuments: -1, VocabularyChange: -1})
+
+	if len(diff.Tokenizers) != 2 || strings.Join(diff.OnlyInCurrent, ",") != "t5" || len(diff.OnlyInBaseline) != 0 {
+		t.Fatalf("diff = %+v, want gpt2 and bert compared and t5 only in the current run", diff)
+	}
+
+	gpt2 := diff.Tokenizers[0]
+	if gpt2.Tokenizer != "gpt2" || gpt2.Documents != 2 || gpt2.UnmatchedDocuments != 0 {
+		t.Errorf("gpt2 = %+v, want 2 documents compared", gpt2)
+	}
+	// The mean token count went from 2.5 to 2
+	var tokenCount MetricDelta
+	for _, delta := range gpt2.Metrics {
+		if delta.Metric == "token_count" {
+			tokenCount = delta
+		}
+	}
+	if tokenCount.Baseline != 2.5 || tokenCount.Current != 2 || tokenCount.Relative == nil || *tokenCount.Relative != -0.2 {
+		t.Errorf("token_count delta = %+v, want 2.5 -> 2 (-20%%)", tokenCount)
+	}
+	if len(gpt2.ChangedDocuments) != 1 || gpt2.ChangedDocuments[0] != (DocumentChange{Document: "d2", Baseline: 3, Current: 2}) {
+		t.Errorf("changed documents = %+v, want d2 from 3 to 2", gpt2.ChangedDocuments)
+	}
+	if strings.Join(gpt2.AddedTokens, ",") != "token" || strings.Join(gpt2.RemovedTokens, ",") != "ken,to" {
+		t.Errorf("added %v and removed %v, want token added and ken, to removed", gpt2.AddedTokens, gpt2.RemovedTokens)
+	}
+
+	bert := diff.Tokenizers[1]
+	if bert.Documents != 1 || bert.UnmatchedDocuments != 1 || len(bert.ChangedDocuments) != 0 || len(bert.RemovedTokens) != 0 {
+		t.Errorf("bert = %+v, want d1 unchanged and d3 unmatched", bert)
+	}
+	if diff.Failed() {
+		t.Errorf("violations = %v, want none without thresholds", diff.Violations)
+	}
+}
+
+func TestCompareThresholds(t *testing.T) {
+	tests := []struct {
+		name       string
+		thresholds Thresholds
+		want       []string
+	}{
+		{
+			name:       "within limits",
+			thresholds: Thresholds{Metrics: map[string]float64{AnyMetric: 0.25}, ChangedDocuments: 1, VocabularyChange: 3},
+		},
+		{
+			name:       "metric over its own limit",
+			thresholds: Thresholds{Metrics: map[string]float64{AnyMetric: 0.25, "token_count": 0.1}, ChangedDocuments: -1, VocabularyChange: -1},
+			want:       []string{"gpt2: token_count changed by -20% (limit 10%)"},
+		},
+		{
+			name:       "any changed document",
+			thresholds: Thresholds{ChangedDocuments: 0, VocabularyChange: -1},
+			want:       []string{"gpt2: token count changed in 1 documents (limit 0)"},
+		},
+		{
+			name:       "vocabulary",
+			thresholds: Thresholds{ChangedDocuments: -1, VocabularyChange: 2},
+			want:       []string{"gpt2: 1 tokens appeared and 2 disappeared (limit 2)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			baseline, current := testRuns()
+			diff := Compare(baseline, current, tt.thresholds)
+			if strings.Join(diff.Violations, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("violations = %q, want %q", diff.Violations, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareFromZero(t *testing.T) {
+	baseline := []*metrics.AnalysisResult{result("d1", "gpt2", "a")}
+	current := []*metrics.AnalysisResult{result("d1", "gpt2", "a")}
+	current[0].Metrics["empty_tokens"] = metrics.MetricResult{Value: 1}
+
+	diff := Compare(baseline, current, Thresholds{Metrics: map[string]float64{AnyMetric: 10}, ChangedDocuments: -1, VocabularyChange: -1})
+	if diff.Tokenizers[0].Metrics[0].Relative != nil {
+		t.Errorf("relative change from 0 = %v, want none", *diff.Tokenizers[0].Metrics[0].Relative)
+	}
+	if len(diff.Violations) != 1 || !strings.Contains(diff.Violations[0], "+1 from 0") {
+		t.Errorf("violations = %q, want the change from 0 to exceed any limit", diff.Violations)
+	}
+}
+
+func TestCompareWithoutTokens(t *testing.T) {
+	baseline, current := testRuns()
+	for _, result := range current {
+		result.Tokenization = nil
+	}
+	diff := Compare(baseline, current, Thresholds{ChangedDocuments: -1, VocabularyChange: 0})
+	if diff.Tokenizers[0].VocabularyCompared || diff.Failed() {
+		t.Errorf("diff = %+v, want the vocabulary left uncompared", diff)
+	}
+}
+
+func TestDiffReport(t *testing.T) {
+	baseline, current := testRuns()
+	diff := Compare(baseline, current, Thresholds{ChangedDocuments: 0, VocabularyChange: -1})
+
+	summary := diff.Summary()
+	for _, want := range []string{
+		"gpt2: 2 documents compared\n",
+		"  token_count: 2.5 -> 2 (-20%)\n",
+		"  1 tokens appeared: \"token\"\n",
+		"bert: 1 documents compared, 1 in only one run\n  no metric changed\n",
+		"only in the current run: t5\n",
+		"1 changes exceed the thresholds:\n  gpt2: token count changed in 1 documents (limit 0)\n",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary is missing %q:\n%s", want, summary)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := diff.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON returned error: %v", err)
+	}
+	var decoded Diff
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded.Tokenizers) != 2 || len(decoded.Violations) != 1 {
+		t.Errorf("WriteJSON wrote %s (%v)", buf.String(), err)
+	}
+}
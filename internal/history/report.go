@@ -0,0 +1,112 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// summaryTokens is how many appearing or disappearing tokens the summary lists per
+// tokenizer; the JSON diff has them all
+const summaryTokens = 10
+
+// WriteJSON writes the diff to w as indented JSON
+func (d *Diff) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(d); err != nil {
+		return fmt.Errorf("failed to write diff: %w", err)
+	}
+	return nil
+}
+
+// Summary describes the diff for people: the metrics that changed, the documents whose
+// token count changed and the tokens that appeared or disappeared, per tokenizer,
+// followed by the violations
+func (d *Diff) Summary() string {
+	var b strings.Builder
+	for _, tokenizer := range d.Tokenizers {
+		fmt.Fprintf(&b, "%s: %d documents compared", tokenizer.Tokenizer, tokenizer.Documents)
+		if tokenizer.UnmatchedDocuments > 0 {
+			fmt.Fprintf(&b, ", %d in only one run", tokenizer.UnmatchedDocuments)
+		}
+		b.WriteString("\n")
+
+		changed := 0
+		for _, delta := range tokenizer.Metrics {
+			if delta.Delta != 0 {
+				fmt.Fprintf(&b, "  %s: %s -> %s (%s)\n", delta.Metric, formatValue(delta.Baseline), formatValue(delta.Current), formatRelative(delta))
+				changed++
+			}
+		}
+		if changed == 0 {
+			b.WriteString("  no metric changed\n")
+		}
+
+		if len(tokenizer.ChangedDocuments) > 0 {
+			fmt.Fprintf(&b, "  token count changed in %d documents\n", len(tokenizer.ChangedDocuments))
+		}
+		if !tokenizer.VocabularyCompared {
+			b.WriteString("  vocabulary not compared: the results have no tokens\n")
+		} else {
+			if len(tokenizer.AddedTokens) > 0 {
+				fmt.Fprintf(&b, "  %d tokens appeared: %s\n", len(tokenizer.AddedTokens), quoteTokens(tokenizer.AddedTokens))
+			}
+			if len(tokenizer.RemovedTokens) > 0 {
+				fmt.Fprintf(&b, "  %d tokens disappeared: %s\n", len(tokenizer.RemovedTokens), quoteTokens(tokenizer.RemovedTokens))
+			}
+		}
+	}
+	if len(d.OnlyInBaseline) > 0 {
+		fmt.Fprintf(&b, "only in the baseline: %s\n", strings.Join(d.OnlyInBaseline, ", "))
+	}
+	if len(d.OnlyInCurrent) > 0 {
+		fmt.Fprintf(&b, "only in the current run: %s\n", strings.Join(d.OnlyInCurrent, ", "))
+	}
+
+	if d.Failed() {
+		fmt.Fprintf(&b, "\n%d changes exceed the thresholds:\n", len(d.Violations))
+		for _, violation := range d.Violations {
+			b.WriteString("  " + violation + "\n")
+		}
+	}
+	return b.String()
+}
+
+// quoteTokens quotes the first summaryTokens tokens, so whitespace tokens are visible
+func quoteTokens(tokens []string) string {
+	quoted := make([]string, 0, summaryTokens)
+	for _, token := range tokens[:min(len(tokens), summaryTokens)] {
+		quoted = append(quoted, strconv.Quote(token))
+	}
+	if len(tokens) > summaryTokens {
+		quoted = append(quoted, "...")
+	}
+	return strings.Join(quoted, " ")
+}
+
+// formatValue formats a metric value compactly
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', 6, 64)
+}
+
+// formatRelative formats the relative change of a metric, or its absolute change when
+// the baseline is 0
+func formatRelative(delta MetricDelta) string {
+	if delta.Relative == nil {
+		return fmt.Sprintf("%+g from 0", delta.Delta)
+	}
+	sign := ""
+	if *delta.Relative >= 0 {
+		sign = "+"
+	}
+	return sign + formatPercent(*delta.Relative)
+}
+
+// formatPercent formats a fraction as a percentage
+func formatPercent(fraction float64) string {
+	return strconv.FormatFloat(math.Round(fraction*10000)/100, 'f', -1, 64) + "%"
+}
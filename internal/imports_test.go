@@ -0,0 +1,110 @@
+package internal_test
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	// Every internal package, so that one that no longer compiles fails this test
+	// even when nothing else imports it
+	_ "github.com/RevBooyah/TokEntropyDrift/internal/advanced"
+	_ "github.com/RevBooyah/TokEntropyDrift/internal/benchmarks"
+	_ "github.com/RevBooyah/TokEntropyDrift/internal/cache"
+	_ "github.com/RevBooyah/TokEntropyDrift/internal/config"
+	_ "github.com/RevBooyah/TokEntropyDrift/internal/export"
+	_ "github.com/RevBooyah/TokEntropyDrift/internal/history"
+	_ "github.com/RevBooyah/TokEntropyDrift/internal/loader"
+	_ "github.com/RevBooyah/TokEntropyDrift/internal/logger"
+	_ "github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+	_ "github.com/RevBooyah/TokEntropyDrift/internal/parallel"
+	_ "github.com/RevBooyah/TokEntropyDrift/internal/plugins"
+	_ "github.com/RevBooyah/TokEntropyDrift/internal/plugins/examples"
+	_ "github.com/RevBooyah/TokEntropyDrift/internal/server"
+	_ "github.com/RevBooyah/TokEntropyDrift/internal/stats"
+	_ "github.com/RevBooyah/TokEntropyDrift/internal/streaming"
+	_ "github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+	_ "github.com/RevBooyah/TokEntropyDrift/internal/version"
+	_ "github.com/RevBooyah/TokEntropyDrift/internal/visualization"
+)
+
+// modulePath reads the module path from go.mod
+func modulePath(t *testing.T) string {
+	t.Helper()
+	data, err := os.ReadFile("../go.mod")
+	if err != nil {
+		t.Fatalf("failed to read go.mod: %v", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if path, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+			return strings.TrimSpace(path)
+		}
+	}
+	t.Fatal("go.mod has no module line")
+	return ""
+}
+
+// TestImportPaths checks that every internal file imports the module by its exact
+// path. A path differing only in case builds on a case-insensitive filesystem and
+// nowhere else.
+func TestImportPaths(t *testing.T) {
+	module := modulePath(t)
+	fset := token.NewFileSet()
+
+	err := filepath.WalkDir(".", func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !strings.HasSuffix(path, ".go") {
+			return err
+		}
+		file, err := parser.ParseFile(fset, path, nil, parser.ImportsOnly)
+		if err != nil {
+			return err
+		}
+		for _, spec := range file.Imports {
+			imported, _ := strconv.Unquote(spec.Path.Value)
+			if len(imported) >= len(module) && strings.EqualFold(imported[:len(module)], module) && imported[:len(module)] != module {
+				t.Errorf("%s imports %q, want the module path %q", path, imported, module)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestEveryPackageImported checks that the imports above name every internal package,
+// so a new package is compile-checked too
+func TestEveryPackageImported(t *testing.T) {
+	module := modulePath(t)
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "imports_test.go", nil, parser.ImportsOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported := make(map[string]bool)
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		imported[path] = true
+	}
+
+	packages := make(map[string]bool)
+	err = filepath.WalkDir(".", func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return err
+		}
+		packages[module+"/internal/"+filepath.ToSlash(filepath.Dir(path))] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for pkg := range packages {
+		if !imported[pkg] {
+			t.Errorf("imports_test.go does not import %s", pkg)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package loader
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Archive kinds loaded member by member
+const (
+	archiveZip   = "zip"
+	archiveTarGz = "tar.gz"
+)
+
+// WithInclude keeps only the files of a directory or archive matching one of the glob
+// patterns. A pattern matches a file's slash-separated path within the directory or
+// archive, or its base name, so *.txt matches at any depth and docs/*.txt only in docs.
+func WithInclude(patterns ...string) Option {
+	return func(l *Loader) {
+		l.include = patterns
+	}
+}
+
+// WithExclude skips the files of a directory or archive matching any of the glob
+// patterns, matched like WithInclude's
+func WithExclude(patterns ...string) Option {
+	return func(l *Loader) {
+		l.exclude = patterns
+	}
+}
+
+// ValidateGlob returns an error if pattern is not a valid include or exclude pattern
+func ValidateGlob(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+	}
+	return nil
+}
+
+// selected reports whether the file at name, a slash-separated path within a
+// directory or archive, passes the include and exclude patterns
+func (l *Loader) selected(name string) bool {
+	matches := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+			if ok, _ := path.Match(pattern, path.Base(name)); ok {
+				return true
+			}
+		}
+		return false
+	}
+	if len(l.include) > 0 && !matches(l.include) {
+		return false
+	}
+	return !matches(l.exclude)
+}
+
+// archiveKind returns the kind of archive a path or URL names by its extension, or ""
+// when it is not an archive
+func archiveKind(filePath string) string {
+	name := filePath
+	if IsRemote(filePath) {
+		if u, err := url.Parse(filePath); err == nil {
+			name = u.Path
+		}
+	}
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return archiveZip
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return archiveTarGz
+	default:
+		return ""
+	}
+}
+
+// memberName returns the cleaned, slash-separated name of an archive member, and false
+// when the name is absolute or climbs out of the archive. Members are only read, never
+// written out, but such names are skipped rather than trusted in paths and metadata.
+func memberName(name string) (string, bool) {
+	name = path.Clean(strings.ReplaceAll(name, `\`, "/"))
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") || path.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", false
+	}
+	return name, true
+}
+
+// loadMember loads the documents of an archive member, its type detected from its
+// extension. The documents' paths name the member after the archive, as in
+// corpus.zip!/docs/a.txt, and their metadata records it as archive_member.
+func (l *Loader) loadMember(r io.Reader, containerPath, name string) ([]Document, error) {
+	documents, err := l.parse(r, containerPath+"!/"+name, GetFileType(name))
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s in %s: %w", name, containerPath, err)
+	}
+	for i := range documents {
+		if documents[i].Metadata == nil {
+			documents[i].Metadata = make(map[string]string)
+		}
+		documents[i].Metadata["archive_member"] = name
+	}
+	return documents, nil
+}
+
+// loadArchive loads the documents of every selected member of an archive. Tar members
+// are streamed from the gzip stream one at a time; zip members are read through the
+// archive's central directory, so a zip must be a file.
+func (l *Loader) loadArchive(file io.Reader, filePath, kind string) ([]Document, error) {
+	var documents []Document
+	switch kind {
+	case archiveTarGz:
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", filePath, err)
+		}
+		defer gz.Close()
+
+		reader := tar.NewReader(gz)
+		for {
+			header, err := reader.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("error reading %s: %w", filePath, err)
+			}
+			name, ok := memberName(header.Name)
+			if header.Typeflag != tar.TypeReg || !ok || !l.selected(name) {
+				continue
+			}
+			memberDocuments, err := l.loadMember(reader, filePath, name)
+			if err != nil {
+				return nil, err
+			}
+			documents = append(documents, memberDocuments...)
+		}
+	case archiveZip:
+		archive, ok := file.(interface {
+			io.ReaderAt
+			Stat() (os.FileInfo, error)
+		})
+		if !ok {
+			return nil, fmt.Errorf("zip archives must be files: %s", filePath)
+		}
+		info, err := archive.Stat()
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", filePath, err)
+		}
+		reader, err := zip.NewReader(archive, info.Size())
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", filePath, err)
+		}
+		for _, member := range reader.File {
+			name, ok := memberName(member.Name)
+			if !member.Mode().IsRegular() || !ok || !l.selected(name) {
+				continue
+			}
+			body, err := member.Open()
+			if err != nil {
+				return nil, fmt.Errorf("error reading %s in %s: %w", name, filePath, err)
+			}
+			memberDocuments, err := l.loadMember(body, filePath, name)
+			body.Close()
+			if err != nil {
+				return nil, err
+			}
+			documents = append(documents, memberDocuments...)
+		}
+	}
+	return documents, nil
+}
+
+// loadDirectory loads the documents of every selected file under a directory, in
+// lexical order, each file's type detected from its extension; archives in it are
+// loaded member by member
+func (l *Loader) loadDirectory(dir string) ([]Document, error) {
+	var documents []Document
+	err := filepath.WalkDir(dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if !l.selected(name) {
+			return nil
+		}
+
+		file, err := os.Open(filePath)
+		if err != nil {
+			return fmt.Errorf("error opening file %s: %w", filePath, err)
+		}
+		defer file.Close()
+
+		var fileDocuments []Document
+		if kind := archiveKind(filePath); kind != "" {
+			fileDocuments, err = l.loadArchive(file, filePath, kind)
+		} else {
+			fileDocuments, err = l.parse(file, filePath, GetFileType(filePath))
+		}
+		if err != nil {
+			return err
+		}
+		documents = append(documents, fileDocuments...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
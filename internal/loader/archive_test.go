@@ -0,0 +1,187 @@
+package loader
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// archiveMembers are the files written to test archives, including names that climb
+// out of the archive
+var archiveMembers = []struct{ name, body string }{
+	{"docs/a.txt", "alpha\nbeta\n"},
+	{"docs/b.jsonl", `{"text": "from jsonl"}` + "\n"},
+	{"data/c.csv", "text,id\nfrom csv,1\n"},
+	{"notes/skip.md", "excluded\n"},
+	{"../escape.txt", "outside\n"},
+	{"/absolute.txt", "absolute\n"},
+}
+
+// writeZip writes archiveMembers to a zip archive
+func writeZip(t *testing.T, archivePath string) {
+	t.Helper()
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	w := zip.NewWriter(file)
+	if _, err := w.Create("docs/"); err != nil {
+		t.Fatal(err)
+	}
+	for _, member := range archiveMembers {
+		f, err := w.Create(member.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(member.body))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeTarGz writes archiveMembers to a gzipped tar archive
+func writeTarGz(t *testing.T, archivePath string) {
+	t.Helper()
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz := gzip.NewWriter(file)
+	w := tar.NewWriter(gz)
+	if err := w.WriteHeader(&tar.Header{Name: "docs/", Typeflag: tar.TypeDir, Mode: 0755}); err != nil {
+		t.Fatal(err)
+	}
+	for _, member := range archiveMembers {
+		header := &tar.Header{Name: member.name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(member.body))}
+		if err := w.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(member.body))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// members returns the content and archive member of each document, sorted
+func members(documents []Document) []string {
+	got := make([]string, len(documents))
+	for i, doc := range documents {
+		got[i] = doc.Metadata["archive_member"] + ": " + doc.Content
+	}
+	sort.Strings(got)
+	return got
+}
+
+func TestLoadArchive(t *testing.T) {
+	dir := t.TempDir()
+	archives := map[string]func(*testing.T, string){
+		"corpus.zip":    writeZip,
+		"corpus.tar.gz": writeTarGz,
+		"corpus.tgz":    writeTarGz,
+	}
+
+	for name, write := range archives {
+		t.Run(name, func(t *testing.T) {
+			archivePath := filepath.Join(dir, name)
+			write(t, archivePath)
+
+			documents, err := NewLoader("txt", WithExclude("*.md")).LoadDocuments(archivePath)
+			if err != nil {
+				t.Fatalf("LoadDocuments returned error: %v", err)
+			}
+			want := []string{
+				"data/c.csv: from csv",
+				"docs/a.txt: alpha",
+				"docs/a.txt: beta",
+				"docs/b.jsonl: from jsonl",
+			}
+			if got := members(documents); !reflect.DeepEqual(got, want) {
+				t.Errorf("documents = %q, want %q", got, want)
+			}
+			for _, doc := range documents {
+				if doc.Metadata["archive_member"] == "docs/a.txt" && doc.FilePath != archivePath+"!/docs/a.txt" {
+					t.Errorf("file path = %s, want the member after the archive", doc.FilePath)
+				}
+			}
+
+			documents, err = NewLoader("txt", WithInclude("docs/*")).LoadDocuments(archivePath)
+			if err != nil {
+				t.Fatalf("LoadDocuments returned error: %v", err)
+			}
+			if got := members(documents); len(got) != 3 {
+				t.Errorf("included documents = %q, want those in docs", got)
+			}
+		})
+	}
+}
+
+func TestLoadDirectory(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.txt":          "one\ntwo\n",
+		"nested/b.jsonl": `{"text": "three"}` + "\n",
+		"nested/c.log":   "skipped\n",
+	}
+	for name, body := range files {
+		filePath := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeZip(t, filepath.Join(dir, "nested", "corpus.zip"))
+
+	documents, err := NewLoader("txt", WithInclude("*.txt", "*.jsonl", "*.zip"), WithExclude("corpus.zip")).LoadDocuments(dir)
+	if err != nil {
+		t.Fatalf("LoadDocuments returned error: %v", err)
+	}
+	got := make([]string, len(documents))
+	for i, doc := range documents {
+		got[i] = doc.Content
+	}
+	if want := []string{"one", "two", "three"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("documents = %q, want %q", got, want)
+	}
+	if documents[2].FilePath != filepath.Join(dir, "nested", "b.jsonl") {
+		t.Errorf("file path = %s, want the file's own path", documents[2].FilePath)
+	}
+}
+
+func TestMemberName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"docs/a.txt", "docs/a.txt", true},
+		{"./docs//a.txt", "docs/a.txt", true},
+		{`docs\a.txt`, "docs/a.txt", true},
+		{"docs/../a.txt", "a.txt", true},
+		{"../a.txt", "", false},
+		{"docs/../../a.txt", "", false},
+		{"/etc/passwd", "", false},
+		{`..\a.txt`, "", false},
+		{".", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := memberName(tt.name)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("memberName(%q) = %q, %v; want %q, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package loader
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// csvOptions configures how CSV and TSV files are read
+type csvOptions struct {
+	delimiter     rune
+	contentColumn string
+	hasHeader     bool
+	lazyQuotes    bool
+	skipRows      int
+	maxRows       int
+	skipInvalid   bool
+}
+
+// RowError is a CSV row that could not be read, with the line it starts on
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e RowError) Unwrap() error {
+	return e.Err
+}
+
+// WithCSVDelimiter sets the field delimiter of CSV files. TSV files use a tab unless
+// another delimiter is set.
+func WithCSVDelimiter(delimiter rune) Option {
+	return func(l *Loader) {
+		l.csv.delimiter = delimiter
+	}
+}
+
+// WithContentColumn sets the CSV column holding a document's text, by header name or
+// by zero-based index. By default it is the text or content column, or the first.
+func WithContentColumn(column string) Option {
+	return func(l *Loader) {
+		l.csv.contentColumn = column
+	}
+}
+
+// WithCSVHeader sets whether the first CSV row is a header. Without one, metadata
+// columns are named column_<index>.
+func WithCSVHeader(hasHeader bool) Option {
+	return func(l *Loader) {
+		l.csv.hasHeader = hasHeader
+	}
+}
+
+// WithLazyQuotes tolerates quotes inside unquoted CSV fields and stray quotes inside
+// quoted ones
+func WithLazyQuotes(lazy bool) Option {
+	return func(l *Loader) {
+		l.csv.lazyQuotes = lazy
+	}
+}
+
+// WithCSVRows skips the first skip CSV data rows and then reads at most max rows; a
+// max of zero or less reads every remaining row
+func WithCSVRows(skip, max int) Option {
+	return func(l *Loader) {
+		l.csv.skipRows = skip
+		l.csv.maxRows = max
+	}
+}
+
+// WithSkipInvalidRows skips CSV rows that cannot be parsed instead of failing the load.
+// SkippedRows reports them after loading.
+func WithSkipInvalidRows(skip bool) Option {
+	return func(l *Loader) {
+		l.csv.skipInvalid = skip
+	}
+}
+
+// SkippedRows returns the invalid CSV rows skipped by the last load
+func (l *Loader) SkippedRows() []RowError {
+	return l.skippedRows
+}
+
+// ParseCSVDelimiter parses a configured delimiter: a single character, or "\t" or
+// "tab" for a tab. An empty delimiter returns zero, leaving the default.
+func ParseCSVDelimiter(delimiter string) (rune, error) {
+	switch delimiter {
+	case "":
+		return 0, nil
+	case `\t`, "tab":
+		return '\t', nil
+	}
+	runes := []rune(delimiter)
+	if len(runes) != 1 || runes[0] == '"' || runes[0] == '\r' || runes[0] == '\n' {
+		return 0, fmt.Errorf("CSV delimiter must be a single character other than a quote or newline: %q", delimiter)
+	}
+	return runes[0], nil
+}
+
+// loadCSVFile loads documents from a CSV or TSV file, one per row
+func (l *Loader) loadCSVFile(file io.Reader, filePath, fileType string) ([]Document, error) {
+	var documents []Document
+
+	reader := csv.NewReader(file)
+	reader.LazyQuotes = l.csv.lazyQuotes
+	switch {
+	case l.csv.delimiter != 0:
+		reader.Comma = l.csv.delimiter
+	case fileType == "tsv":
+		reader.Comma = '\t'
+	}
+
+	var header []string
+	if l.csv.hasHeader {
+		var err error
+		header, err = reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("error reading CSV header: %w", err)
+		}
+	}
+
+	contentColIndex, err := csvContentColumn(header, l.csv.contentColumn)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		var parseErr *csv.ParseError
+		switch {
+		case errors.As(err, &parseErr):
+			// A malformed row is skipped when asked; reading goes on after it
+			rowErr := RowError{Line: parseErr.StartLine, Err: err}
+			if l.csv.skipInvalid {
+				l.skippedRows = append(l.skippedRows, rowErr)
+				continue
+			}
+			return nil, fmt.Errorf("error reading CSV row at %w", rowErr)
+		case err != nil:
+			return nil, fmt.Errorf("error reading CSV file: %w", err)
+		}
+
+		if len(record) == 0 {
+			continue
+		}
+		startLine, _ := reader.FieldPos(0)
+		if contentColIndex >= len(record) {
+			rowErr := RowError{Line: startLine, Err: fmt.Errorf("row has %d columns, no content column %d", len(record), contentColIndex)}
+			if l.csv.skipInvalid {
+				l.skippedRows = append(l.skippedRows, rowErr)
+				continue
+			}
+			return nil, fmt.Errorf("error reading CSV row at %w", rowErr)
+		}
+
+		rows++
+		if rows <= l.csv.skipRows {
+			continue
+		}
+		if l.csv.maxRows > 0 && rows > l.csv.skipRows+l.csv.maxRows {
+			break
+		}
+
+		// Extract content
+		content := record[contentColIndex]
+
+		// Extract metadata from other columns
+		metadata := make(map[string]string)
+		for i, value := range record {
+			switch {
+			case i == contentColIndex:
+			case header == nil:
+				metadata[fmt.Sprintf("column_%d", i)] = value
+			case i < len(header):
+				metadata[header[i]] = value
+			}
+		}
+		metadata["file_type"] = fileType
+		metadata["file_name"] = filepath.Base(filePath)
+
+		// A quoted field can span lines, so the record's lines come from the reader
+		endLine, _ := reader.FieldPos(len(record) - 1)
+		endLine += strings.Count(record[len(record)-1], "\n")
+
+		doc := Document{
+			Content:   content,
+			StartLine: startLine,
+			EndLine:   endLine,
+			FilePath:  filePath,
+			Metadata:  metadata,
+		}
+		documents = append(documents, doc)
+	}
+
+	return documents, nil
+}
+
+// csvContentColumn returns the index of the content column: the configured column by
+// header name, or by index when no header column has that name. Without a configured
+// column it is the text or content column, or the first.
+func csvContentColumn(header []string, column string) (int, error) {
+	if column != "" {
+		for i, name := range header {
+			if name == column {
+				return i, nil
+			}
+		}
+		index, err := strconv.Atoi(column)
+		if err != nil || index < 0 {
+			return 0, fmt.Errorf("CSV content column %q not found", column)
+		}
+		if header != nil && index >= len(header) {
+			return 0, fmt.Errorf("CSV content column %d is beyond the %d header columns", index, len(header))
+		}
+		return index, nil
+	}
+
+	for i, name := range header {
+		if name == "text" || name == "content" {
+			return i, nil
+		}
+	}
+	// Use first column as content if no text/content column found
+	return 0, nil
+}
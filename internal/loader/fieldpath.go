@@ -0,0 +1,147 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// fieldPath is a path to values in a JSON document: keys and array indexes separated
+// by dots, such as messages.0.content. Indexes may also be written in brackets, as in
+// messages[0].content, and count from the end when negative. A * step matches every
+// element of an array or value of an object, so messages.*.content matches the content
+// of every message. A leading $ is accepted and ignored.
+type fieldPath struct {
+	raw   string
+	steps []string
+}
+
+// ValidateFieldPath returns an error if path is not a valid JSONL field path
+func ValidateFieldPath(path string) error {
+	_, err := parseFieldPath(path)
+	return err
+}
+
+// parseFieldPath parses a field path
+func parseFieldPath(path string) (fieldPath, error) {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	trimmed = strings.TrimPrefix(trimmed, ".")
+	if trimmed == "" {
+		return fieldPath{}, fmt.Errorf("empty field path %q", path)
+	}
+
+	// Brackets are another way of writing a dotted step
+	var b strings.Builder
+	for i := 0; i < len(trimmed); i++ {
+		switch trimmed[i] {
+		case '[':
+			end := strings.IndexByte(trimmed[i:], ']')
+			if end < 0 {
+				return fieldPath{}, fmt.Errorf("unclosed bracket in field path %q", path)
+			}
+			b.WriteString("." + trimmed[i+1:i+end])
+			i += end
+		case ']':
+			return fieldPath{}, fmt.Errorf("unexpected ] in field path %q", path)
+		default:
+			b.WriteByte(trimmed[i])
+		}
+	}
+
+	steps := strings.Split(strings.TrimPrefix(b.String(), "."), ".")
+	for _, step := range steps {
+		if step == "" {
+			return fieldPath{}, fmt.Errorf("empty step in field path %q", path)
+		}
+	}
+	return fieldPath{raw: path, steps: steps}, nil
+}
+
+// parseFieldPaths parses each of paths
+func parseFieldPaths(paths []string) ([]fieldPath, error) {
+	parsed := make([]fieldPath, 0, len(paths))
+	for _, path := range paths {
+		p, err := parseFieldPath(path)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, p)
+	}
+	return parsed, nil
+}
+
+// resolve returns the non-null values the path matches in value, in document order;
+// object values matched by * are taken in key order
+func (p fieldPath) resolve(value interface{}) []interface{} {
+	values := []interface{}{value}
+	for _, step := range p.steps {
+		var next []interface{}
+		for _, v := range values {
+			switch node := v.(type) {
+			case map[string]interface{}:
+				if step != "*" {
+					if child, ok := node[step]; ok {
+						next = append(next, child)
+					}
+					continue
+				}
+				keys := make([]string, 0, len(node))
+				for key := range node {
+					keys = append(keys, key)
+				}
+				sort.Strings(keys)
+				for _, key := range keys {
+					next = append(next, node[key])
+				}
+			case []interface{}:
+				if step == "*" {
+					next = append(next, node...)
+					continue
+				}
+				index, err := strconv.Atoi(step)
+				if err != nil {
+					continue
+				}
+				if index < 0 {
+					index += len(node)
+				}
+				if index >= 0 && index < len(node) {
+					next = append(next, node[index])
+				}
+			}
+		}
+		values = next
+	}
+
+	found := values[:0]
+	for _, v := range values {
+		if v != nil {
+			found = append(found, v)
+		}
+	}
+	return found
+}
+
+// top returns the top-level key the path starts from
+func (p fieldPath) top() string {
+	return p.steps[0]
+}
+
+// jsonString returns a JSON value as text: strings as they are, objects and arrays as
+// JSON and other values formatted with %v
+func jsonString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(encoded)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,52 @@
+package loader
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestFieldPathResolve(t *testing.T) {
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(`{"a": {"b": [10, {"c": "x"}, null]}, "d": {"y": 2, "x": 1}, "0": "zero"}`), &record); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want []interface{}
+	}{
+		{"a.b.0", []interface{}{10.0}},
+		{"a.b[1].c", []interface{}{"x"}},
+		{"$.a.b.-2.c", []interface{}{"x"}},
+		{"a.b.*", []interface{}{10.0, map[string]interface{}{"c": "x"}}},
+		{"d.*", []interface{}{1.0, 2.0}},
+		{"0", []interface{}{"zero"}},
+		{"a.b.5", nil},
+		{"a.missing.c", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			path, err := parseFieldPath(tt.path)
+			if err != nil {
+				t.Fatalf("parseFieldPath returned error: %v", err)
+			}
+			got := path.resolve(record)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolve = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFieldPathErrors(t *testing.T) {
+	for _, path := range []string{"", "$", "a..b", "a[0", "a]0", "a.[]"} {
+		if err := ValidateFieldPath(path); err == nil {
+			t.Errorf("ValidateFieldPath(%q) accepted an invalid path", path)
+		}
+	}
+}
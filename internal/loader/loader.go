@@ -2,7 +2,8 @@ package loader
 
 import (
 	"bufio"
-	"encoding/csv"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,48 +12,206 @@ import (
 	"strings"
 )
 
-// Document represents a single document with metadata
+// Document represents a single document with metadata. ID is a hash of the content, so
+// documents with the same text share it wherever they were read from. StartLine and
+// EndLine are the first and last lines of the file the document was read from,
+// numbered from 1.
 type Document struct {
-	Content    string            `json:"content"`
-	LineNumber int               `json:"line_number"`
-	FilePath   string            `json:"file_path"`
-	Metadata   map[string]string `json:"metadata,omitempty"`
+	ID        string            `json:"id"`
+	Content   string            `json:"content"`
+	StartLine int               `json:"start_line"`
+	EndLine   int               `json:"end_line"`
+	FilePath  string            `json:"file_path"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
 }
 
 // Loader handles loading documents from various file formats
 type Loader struct {
-	fileType string
+	fileType  string
+	splitMode SplitMode
+	delimiter string
+
+	// JSONL field extraction
+	textFields     []string
+	textSeparator  string
+	strictFields   bool
+	metadataFields []string
+
+	csv         csvOptions
+	skippedRows []RowError
+
+	dedup          bool
+	sampling       samplingOptions
+	samplingReport SamplingReport
+
+	remote remoteOptions
+
+	// Globs selecting the files of directories and archives
+	include []string
+	exclude []string
+}
+
+// Option configures a Loader
+type Option func(*Loader)
+
+// WithSplitMode sets how text files are split into documents
+func WithSplitMode(mode SplitMode) Option {
+	return func(l *Loader) {
+		l.splitMode = mode
+	}
+}
+
+// WithDelimiter sets the line separating documents in SplitDelimiter mode
+func WithDelimiter(delimiter string) Option {
+	return func(l *Loader) {
+		l.delimiter = delimiter
+	}
+}
+
+// WithTextFields sets the paths of the JSONL fields holding a document's text, such as
+// messages.0.content or messages.*.content. The values of every path are joined in
+// order. Without paths, the text is the top-level text or content field.
+func WithTextFields(paths ...string) Option {
+	return func(l *Loader) {
+		l.textFields = paths
+	}
+}
+
+// WithTextSeparator sets the separator joining the values of several JSONL text
+// fields, a newline by default
+func WithTextSeparator(separator string) Option {
+	return func(l *Loader) {
+		l.textSeparator = separator
+	}
+}
+
+// WithStrictFields makes a JSONL line without any text field an error instead of
+// being analyzed as the raw JSON line
+func WithStrictFields(strict bool) Option {
+	return func(l *Loader) {
+		l.strictFields = strict
+	}
+}
+
+// WithMetadataFields sets the paths of the JSONL fields kept as document metadata,
+// keyed by path. Without paths, every top-level field other than the text is kept.
+func WithMetadataFields(paths ...string) Option {
+	return func(l *Loader) {
+		l.metadataFields = paths
+	}
 }
 
-// NewLoader creates a new loader for the specified file type
-func NewLoader(fileType string) *Loader {
-	return &Loader{
-		fileType: strings.ToLower(fileType),
+// WithDedup drops documents whose content exactly repeats an earlier document's
+func WithDedup(dedup bool) Option {
+	return func(l *Loader) {
+		l.dedup = dedup
 	}
 }
 
-// LoadDocuments loads all documents from the given file path
+// NewLoader creates a new loader for the specified file type. Text files are split
+// into one document per line unless an option says otherwise.
+func NewLoader(fileType string, options ...Option) *Loader {
+	l := &Loader{
+		fileType:      strings.ToLower(fileType),
+		splitMode:     SplitLine,
+		textSeparator: "\n",
+		csv:           csvOptions{hasHeader: true},
+	}
+	for _, option := range options {
+		option(l)
+	}
+	if l.splitMode == "" {
+		l.splitMode = SplitLine
+	}
+	return l
+}
+
+// StdinPath is the input path that reads standard input
+const StdinPath = "-"
+
+// FileTypeAuto detects the file type of each path: from a download's content type or
+// else the extension of its path
+const FileTypeAuto = "auto"
+
+// LoadDocuments loads the documents of the given file path, or of standard input for
+// StdinPath, keeping those that pass the loader's deduplication, length filters and
+// sampling
 func (l *Loader) LoadDocuments(filePath string) ([]Document, error) {
-	file, err := os.Open(filePath)
+	l.skippedRows = nil
+	documents, err := l.loadAll(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("error opening file %s: %w", filePath, err)
+		return nil, err
 	}
-	defer file.Close()
+	for i := range documents {
+		documents[i].ID = ContentID(documents[i].Content)
+	}
+	return l.sample(documents), nil
+}
+
+// ContentID returns the ID of a document with the given content: the first 16 hex
+// digits of its SHA-256 hash
+func ContentID(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:8])
+}
+
+// loadAll loads every document of the given file path or URL. Directories and archives
+// are loaded file by file.
+func (l *Loader) loadAll(filePath string) ([]Document, error) {
+	if filePath != StdinPath && !IsRemote(filePath) {
+		if info, err := os.Stat(filePath); err == nil && info.IsDir() {
+			return l.loadDirectory(filePath)
+		}
+	}
+
+	fileType := l.fileType
+	var file io.Reader
+	switch {
+	case filePath == StdinPath:
+		file = os.Stdin
+	case IsRemote(filePath):
+		body, contentType, err := l.fetchRemote(filePath)
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		file = body
+		if fileType == FileTypeAuto {
+			fileType = remoteFileType(filePath, contentType)
+		}
+	default:
+		f, err := os.Open(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("error opening file %s: %w", filePath, err)
+		}
+		defer f.Close()
+		file = f
+	}
+	if kind := archiveKind(filePath); kind != "" {
+		return l.loadArchive(file, filePath, kind)
+	}
+	if fileType == FileTypeAuto {
+		fileType = GetFileType(filePath)
+	}
+	return l.parse(file, filePath, fileType)
+}
 
-	switch l.fileType {
+// parse loads the documents of a file of the given type
+func (l *Loader) parse(file io.Reader, filePath, fileType string) ([]Document, error) {
+	switch fileType {
 	case "txt", "text":
 		return l.loadTextFile(file, filePath)
 	case "jsonl", "json":
 		return l.loadJSONLFile(file, filePath)
-	case "csv":
-		return l.loadCSVFile(file, filePath)
+	case "csv", "tsv":
+		return l.loadCSVFile(file, filePath, fileType)
 	default:
-		return nil, fmt.Errorf("unsupported file type: %s", l.fileType)
+		return nil, fmt.Errorf("unsupported file type: %s", fileType)
 	}
 }
 
-// loadTextFile loads documents from a plain text file
-func (l *Loader) loadTextFile(file *os.File, filePath string) ([]Document, error) {
+// loadTextFile loads documents from a plain text file, split by the loader's split mode
+func (l *Loader) loadTextFile(file io.Reader, filePath string) ([]Document, error) {
 	var documents []Document
 
 	// Read the entire file content
@@ -61,32 +220,41 @@ func (l *Loader) loadTextFile(file *os.File, filePath string) ([]Document, error
 		return nil, fmt.Errorf("error reading text file: %w", err)
 	}
 
-	// Convert to string and trim any trailing whitespace
-	text := strings.TrimSpace(string(content))
-
-	// Skip empty files
-	if text == "" {
-		return documents, nil
+	spans, err := splitText(string(content), l.splitMode, l.delimiter)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create a single document for the entire file
-	doc := Document{
-		Content:    text,
-		LineNumber: 1, // Single document, so line number is 1
-		FilePath:   filePath,
-		Metadata: map[string]string{
-			"file_type":   "text",
-			"file_name":   filepath.Base(filePath),
-			"total_lines": fmt.Sprintf("%d", strings.Count(text, "\n")+1),
-		},
+	for _, span := range spans {
+		doc := Document{
+			Content:   span.text,
+			StartLine: span.start,
+			EndLine:   span.end,
+			FilePath:  filePath,
+			Metadata: map[string]string{
+				"file_type":   "text",
+				"file_name":   filepath.Base(filePath),
+				"split_mode":  string(l.splitMode),
+				"total_lines": fmt.Sprintf("%d", strings.Count(span.text, "\n")+1),
+			},
+		}
+		documents = append(documents, doc)
 	}
-	documents = append(documents, doc)
 
 	return documents, nil
 }
 
 // loadJSONLFile loads documents from a JSONL (JSON Lines) file
-func (l *Loader) loadJSONLFile(file *os.File, filePath string) ([]Document, error) {
+func (l *Loader) loadJSONLFile(file io.Reader, filePath string) ([]Document, error) {
+	textPaths, err := parseFieldPaths(l.textFields)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSONL text field: %w", err)
+	}
+	metadataPaths, err := parseFieldPaths(l.metadataFields)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSONL metadata field: %w", err)
+	}
+
 	var documents []Document
 	scanner := bufio.NewScanner(file)
 	lineNumber := 0
@@ -106,24 +274,37 @@ func (l *Loader) loadJSONLFile(file *os.File, filePath string) ([]Document, erro
 			return nil, fmt.Errorf("error parsing JSON at line %d: %w", lineNumber, err)
 		}
 
-		// Extract content field (default to "text" or "content")
-		content, ok := jsonData["text"].(string)
-		if !ok {
-			content, ok = jsonData["content"].(string)
-		}
+		content, ok := l.jsonlText(jsonData, textPaths)
 		if !ok {
-			// If no text/content field, use the entire JSON as string
+			if l.strictFields {
+				return nil, fmt.Errorf("no text field %s at line %d", l.describeTextFields(), lineNumber)
+			}
+			// If no text field, use the entire JSON as string
 			content = line
 		}
 
 		// Extract metadata
 		metadata := make(map[string]string)
-		for k, v := range jsonData {
-			if k != "text" && k != "content" {
-				if str, ok := v.(string); ok {
-					metadata[k] = str
-				} else {
-					metadata[k] = fmt.Sprintf("%v", v)
+		if len(metadataPaths) > 0 {
+			for _, path := range metadataPaths {
+				values := path.resolve(jsonData)
+				if len(values) == 0 {
+					continue
+				}
+				texts := make([]string, len(values))
+				for i, value := range values {
+					texts[i] = jsonString(value)
+				}
+				metadata[path.raw] = strings.Join(texts, ",")
+			}
+		} else {
+			textKeys := map[string]bool{"text": true, "content": true}
+			for _, path := range textPaths {
+				textKeys[path.top()] = true
+			}
+			for k, v := range jsonData {
+				if !textKeys[k] {
+					metadata[k] = jsonString(v)
 				}
 			}
 		}
@@ -131,10 +312,11 @@ func (l *Loader) loadJSONLFile(file *os.File, filePath string) ([]Document, erro
 		metadata["file_name"] = filepath.Base(filePath)
 
 		doc := Document{
-			Content:    content,
-			LineNumber: lineNumber,
-			FilePath:   filePath,
-			Metadata:   metadata,
+			Content:   content,
+			StartLine: lineNumber,
+			EndLine:   lineNumber,
+			FilePath:  filePath,
+			Metadata:  metadata,
 		}
 		documents = append(documents, doc)
 	}
@@ -146,70 +328,33 @@ func (l *Loader) loadJSONLFile(file *os.File, filePath string) ([]Document, erro
 	return documents, nil
 }
 
-// loadCSVFile loads documents from a CSV file
-func (l *Loader) loadCSVFile(file *os.File, filePath string) ([]Document, error) {
-	var documents []Document
-	reader := csv.NewReader(file)
-	lineNumber := 0
-
-	// Read header
-	header, err := reader.Read()
-	if err != nil {
-		return nil, fmt.Errorf("error reading CSV header: %w", err)
-	}
-	lineNumber++
-
-	// Find content column (default to "text" or "content")
-	contentColIndex := -1
-	for i, col := range header {
-		if col == "text" || col == "content" {
-			contentColIndex = i
-			break
+// jsonlText returns the text of a JSONL record: the values of the text paths joined by
+// the separator, or the top-level text or content string when there are no paths. It
+// reports false when the record has no text.
+func (l *Loader) jsonlText(record map[string]interface{}, textPaths []fieldPath) (string, bool) {
+	if len(textPaths) == 0 {
+		content, ok := record["text"].(string)
+		if !ok {
+			content, ok = record["content"].(string)
 		}
+		return content, ok
 	}
-	if contentColIndex == -1 {
-		// Use first column as content if no text/content column found
-		contentColIndex = 0
-	}
-
-	// Read data rows
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return nil, fmt.Errorf("error reading CSV row %d: %w", lineNumber+1, err)
-		}
-		lineNumber++
-
-		if len(record) == 0 {
-			continue
-		}
 
-		// Extract content
-		content := record[contentColIndex]
-
-		// Extract metadata from other columns
-		metadata := make(map[string]string)
-		for i, value := range record {
-			if i != contentColIndex && i < len(header) {
-				metadata[header[i]] = value
-			}
-		}
-		metadata["file_type"] = "csv"
-		metadata["file_name"] = filepath.Base(filePath)
-
-		doc := Document{
-			Content:    content,
-			LineNumber: lineNumber,
-			FilePath:   filePath,
-			Metadata:   metadata,
+	var texts []string
+	for _, path := range textPaths {
+		for _, value := range path.resolve(record) {
+			texts = append(texts, jsonString(value))
 		}
-		documents = append(documents, doc)
 	}
+	return strings.Join(texts, l.textSeparator), len(texts) > 0
+}
 
-	return documents, nil
+// describeTextFields names the JSONL text fields for error messages
+func (l *Loader) describeTextFields() string {
+	if len(l.textFields) == 0 {
+		return "text or content"
+	}
+	return strings.Join(l.textFields, ", ")
 }
 
 // GetFileType returns the detected file type based on extension
@@ -222,13 +367,19 @@ func GetFileType(filePath string) string {
 		return "jsonl"
 	case ".csv":
 		return "csv"
+	case ".tsv":
+		return "tsv"
 	default:
 		return "txt" // Default to text
 	}
 }
 
-// ValidateFile checks if the file exists and is readable
+// ValidateFile checks if the file exists and is readable. Standard input always is,
+// and URLs are checked when they are loaded.
 func ValidateFile(filePath string) error {
+	if filePath == StdinPath || IsRemote(filePath) {
+		return nil
+	}
 	file, err := os.Open(filePath)
 	if err != nil {
 		return fmt.Errorf("file does not exist or is not readable: %w", err)
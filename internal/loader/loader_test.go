@@ -0,0 +1,303 @@
+package loader
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// span is the part of a loaded document the tests compare
+type span struct {
+	content    string
+	start, end int
+}
+
+func spans(documents []Document) []span {
+	got := make([]span, len(documents))
+	for i, doc := range documents {
+		got[i] = span{doc.Content, doc.StartLine, doc.EndLine}
+	}
+	return got
+}
+
+func TestLoadTextSplitModes(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		mode      SplitMode
+		delimiter string
+		want      []span
+	}{
+		{"default is line", "lines.txt", "", "", []span{{"first line", 1, 1}, {"second line", 3, 3}, {"third", 4, 4}}},
+		{"line", "paragraphs.txt", SplitLine, "", []span{
+			{"Paragraph one starts here", 1, 1}, {"and continues.", 2, 2}, {"Paragraph two.", 5, 5},
+			{"Paragraph three", 7, 7}, {"ends here.", 8, 8},
+		}},
+		{"paragraph", "paragraphs.txt", SplitParagraph, "", []span{
+			{"Paragraph one starts here\nand continues.", 1, 2},
+			{"Paragraph two.", 5, 5},
+			{"Paragraph three\nends here.", 7, 8},
+		}},
+		{"file", "paragraphs.txt", SplitFile, "", []span{
+			{"Paragraph one starts here\nand continues.\n\n\nParagraph two.\n   \nParagraph three\nends here.", 1, 8},
+		}},
+		{"delimiter", "delimited.txt", SplitDelimiter, "---", []span{
+			{"doc one", 1, 1}, {"doc two\nmore of two", 3, 4}, {"doc three", 8, 8},
+		}},
+		{"empty delimiter splits on blank lines", "paragraphs.txt", SplitDelimiter, "", []span{
+			{"Paragraph one starts here\nand continues.", 1, 2},
+			{"Paragraph two.", 5, 5},
+			{"Paragraph three\nends here.", 7, 8},
+		}},
+		{"crlf line", "crlf.txt", SplitLine, "", []span{{"alpha", 1, 1}, {"beta", 2, 2}, {"gamma", 4, 4}}},
+		{"crlf paragraph", "crlf.txt", SplitParagraph, "", []span{{"alpha\nbeta", 1, 2}, {"gamma", 4, 4}}},
+		{"crlf file", "crlf.txt", SplitFile, "", []span{{"alpha\nbeta\n\ngamma", 1, 4}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLoader("txt", WithSplitMode(tt.mode), WithDelimiter(tt.delimiter))
+			documents, err := l.LoadDocuments(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatalf("LoadDocuments returned error: %v", err)
+			}
+
+			got := spans(documents)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d documents %q, want %d", len(got), got, len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("document %d = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadTextUnsupportedSplitMode(t *testing.T) {
+	l := NewLoader("txt", WithSplitMode("sentence"))
+	if _, err := l.LoadDocuments(filepath.Join("testdata", "lines.txt")); err == nil {
+		t.Error("expected an error for an unknown split mode")
+	}
+}
+
+func TestLoadRecordsIgnoreSplitMode(t *testing.T) {
+	tests := []struct {
+		fileType string
+		file     string
+		want     []span
+	}{
+		{"csv", "records.csv", []span{{"first record", 2, 2}, {"second record\n\nspans lines", 3, 5}}},
+		{"jsonl", "records.jsonl", []span{{"first record", 1, 1}, {"second record", 3, 3}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileType, func(t *testing.T) {
+			l := NewLoader(tt.fileType, WithSplitMode(SplitParagraph))
+			documents, err := l.LoadDocuments(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatalf("LoadDocuments returned error: %v", err)
+			}
+
+			got := spans(documents)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d documents %q, want %d", len(got), got, len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("record %d = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+			if id := documents[1].Metadata["id"]; id != "2" {
+				t.Errorf("record metadata id = %q, want 2", id)
+			}
+		})
+	}
+}
+
+func TestLoadJSONLTextFields(t *testing.T) {
+	chat := filepath.Join("testdata", "chat.jsonl")
+
+	tests := []struct {
+		name    string
+		options []Option
+		want    []span
+	}{
+		{"first message", []Option{WithTextFields("messages.0.content")}, []span{
+			{"How do tokenizers differ?", 1, 1}, {"Hello", 3, 3}, {`{"id": 3, "meta": {"source": "forum"}, "messages": []}`, 4, 4},
+		}},
+		{"every message joined", []Option{WithTextFields("$.messages[*].content"), WithTextSeparator(" | ")}, []span{
+			{"How do tokenizers differ? | They split text differently.", 1, 1}, {"Hello", 3, 3}, {`{"id": 3, "meta": {"source": "forum"}, "messages": []}`, 4, 4},
+		}},
+		{"several fields", []Option{WithTextFields("meta.source", "messages.-1.content")}, []span{
+			{"forum\nThey split text differently.", 1, 1}, {"email\nHello", 3, 3}, {"forum", 4, 4},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			documents, err := NewLoader("jsonl", tt.options...).LoadDocuments(chat)
+			if err != nil {
+				t.Fatalf("LoadDocuments returned error: %v", err)
+			}
+			got := spans(documents)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d documents %q, want %d", len(got), got, len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("document %d = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadJSONLStrictFields(t *testing.T) {
+	l := NewLoader("jsonl", WithTextFields("messages.0.content"), WithStrictFields(true))
+	_, err := l.LoadDocuments(filepath.Join("testdata", "chat.jsonl"))
+	if err == nil || !strings.Contains(err.Error(), "line 4") || !strings.Contains(err.Error(), "messages.0.content") {
+		t.Errorf("strict error = %v, want the missing path and line 4", err)
+	}
+
+	if _, err := NewLoader("jsonl", WithTextFields("messages[0")).LoadDocuments(filepath.Join("testdata", "chat.jsonl")); err == nil {
+		t.Error("expected an error for an invalid text field path")
+	}
+}
+
+func TestLoadJSONLMetadataFields(t *testing.T) {
+	chat := filepath.Join("testdata", "chat.jsonl")
+
+	documents, err := NewLoader("jsonl", WithTextFields("messages.0.content"), WithMetadataFields("meta.source", "messages.*.role", "missing")).LoadDocuments(chat)
+	if err != nil {
+		t.Fatalf("LoadDocuments returned error: %v", err)
+	}
+	metadata := documents[0].Metadata
+	if metadata["meta.source"] != "forum" || metadata["messages.*.role"] != "user,assistant" {
+		t.Errorf("selected metadata = %v", metadata)
+	}
+	if _, ok := metadata["missing"]; ok {
+		t.Error("a missing metadata path was recorded")
+	}
+	if _, ok := metadata["id"]; ok {
+		t.Error("unselected field id was copied into metadata")
+	}
+
+	// Without selected fields every top-level field but the text is kept
+	documents, err = NewLoader("jsonl", WithTextFields("messages.0.content")).LoadDocuments(chat)
+	if err != nil {
+		t.Fatalf("LoadDocuments returned error: %v", err)
+	}
+	metadata = documents[0].Metadata
+	if metadata["id"] != "1" || metadata["meta"] != `{"lang":"en","source":"forum"}` {
+		t.Errorf("metadata = %v", metadata)
+	}
+	if _, ok := metadata["messages"]; ok {
+		t.Error("the text field was copied into metadata")
+	}
+}
+
+func TestLoadCSVOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileType string
+		file     string
+		options  []Option
+		want     []span
+	}{
+		{"tsv", "tsv", "records.tsv", nil, []span{{"hello world", 2, 2}, {"quoted\ttab", 3, 3}}},
+		{"tab delimiter", "csv", "records.tsv", []Option{WithCSVDelimiter('\t'), WithContentColumn("source")}, []span{{"web", 2, 2}, {"book", 3, 3}}},
+		{"quoted multi-line field", "csv", "records.csv", []Option{WithContentColumn("1")}, []span{{"first record", 2, 2}, {"second record\n\nspans lines", 3, 5}}},
+		{"header-less", "csv", "noheader.csv", []Option{WithCSVHeader(false)}, []span{{"alpha", 1, 1}, {"beta", 2, 2}, {"gamma", 3, 3}}},
+		{"header-less by index", "csv", "noheader.csv", []Option{WithCSVHeader(false), WithContentColumn("1")}, []span{{"1", 1, 1}, {"2", 2, 2}, {"3", 3, 3}}},
+		{"skip and limit", "csv", "noheader.csv", []Option{WithCSVHeader(false), WithCSVRows(1, 1)}, []span{{"beta", 2, 2}}},
+		{"lazy quotes", "csv", "malformed.csv", []Option{WithLazyQuotes(true), WithSkipInvalidRows(true)}, []span{
+			{"good one", 2, 2}, {`bad "quote`, 3, 3}, {"last one", 5, 5},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			documents, err := NewLoader(tt.fileType, tt.options...).LoadDocuments(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatalf("LoadDocuments returned error: %v", err)
+			}
+			got := spans(documents)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d documents %q, want %d", len(got), got, len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("document %d = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	documents, err := NewLoader("csv", WithCSVHeader(false)).LoadDocuments(filepath.Join("testdata", "noheader.csv"))
+	if err != nil {
+		t.Fatalf("LoadDocuments returned error: %v", err)
+	}
+	if column := documents[0].Metadata["column_1"]; column != "1" {
+		t.Errorf("header-less metadata column_1 = %q, want 1", column)
+	}
+
+	if _, err := NewLoader("csv", WithContentColumn("body")).LoadDocuments(filepath.Join("testdata", "records.csv")); err == nil {
+		t.Error("expected an error for a missing content column")
+	}
+}
+
+func TestLoadCSVInvalidRows(t *testing.T) {
+	malformed := filepath.Join("testdata", "malformed.csv")
+
+	_, err := NewLoader("csv").LoadDocuments(malformed)
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("error = %v, want the malformed row's line 3", err)
+	}
+
+	l := NewLoader("csv", WithSkipInvalidRows(true))
+	documents, err := l.LoadDocuments(malformed)
+	if err != nil {
+		t.Fatalf("LoadDocuments returned error: %v", err)
+	}
+	if got, want := spans(documents), []span{{"good one", 2, 2}, {"last one", 5, 5}}; len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("documents = %q, want %q", got, want)
+	}
+
+	skipped := l.SkippedRows()
+	if len(skipped) != 2 || skipped[0].Line != 3 || skipped[1].Line != 4 {
+		t.Fatalf("skipped rows = %v, want lines 3 and 4", skipped)
+	}
+	if !errors.Is(skipped[1], csv.ErrFieldCount) {
+		t.Errorf("skipped row error = %v, want a field count error", skipped[1].Err)
+	}
+}
+
+func TestParseCSVDelimiter(t *testing.T) {
+	tests := []struct {
+		delimiter string
+		want      rune
+		wantErr   bool
+	}{
+		{"", 0, false},
+		{";", ';', false},
+		{`\t`, '\t', false},
+		{"tab", '\t', false},
+		{"\t", '\t', false},
+		{"ab", 0, true},
+		{`"`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%q", tt.delimiter), func(t *testing.T) {
+			got, err := ParseCSVDelimiter(tt.delimiter)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseCSVDelimiter = %q, %v; want %q, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package loader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// Limits of remote downloads when no option sets them
+const (
+	DefaultRemoteTimeout  = 30 * time.Second
+	DefaultRemoteMaxBytes = 100 << 20
+)
+
+// maxRemoteRedirects is the most redirects a download follows
+const maxRemoteRedirects = 5
+
+// remoteOptions configure loading documents from http:// and https:// URLs
+type remoteOptions struct {
+	enabled      bool
+	allowPrivate bool
+	timeout      time.Duration
+	maxBytes     int64
+	cacheDir     string
+}
+
+// WithRemote allows source paths to be http:// or https:// URLs. Remote input is off
+// unless enabled.
+func WithRemote(enabled bool) Option {
+	return func(l *Loader) {
+		l.remote.enabled = enabled
+	}
+}
+
+// WithRemotePrivateAddresses allows downloads from loopback, link-local and private
+// network addresses, which are refused by default so that a URL cannot reach services
+// only the loading machine can see
+func WithRemotePrivateAddresses(allowed bool) Option {
+	return func(l *Loader) {
+		l.remote.allowPrivate = allowed
+	}
+}
+
+// WithRemoteTimeout sets how long a download may take, DefaultRemoteTimeout when zero
+func WithRemoteTimeout(timeout time.Duration) Option {
+	return func(l *Loader) {
+		l.remote.timeout = timeout
+	}
+}
+
+// WithRemoteMaxBytes sets the largest body a download may have, DefaultRemoteMaxBytes
+// when zero
+func WithRemoteMaxBytes(n int64) Option {
+	return func(l *Loader) {
+		l.remote.maxBytes = n
+	}
+}
+
+// WithRemoteCacheDir keeps downloads in dir. A cached download is revalidated with its
+// ETag and Last-Modified headers and read from dir when the server has not changed it.
+// Without a cache directory every load downloads again.
+func WithRemoteCacheDir(dir string) Option {
+	return func(l *Loader) {
+		l.remote.cacheDir = dir
+	}
+}
+
+// IsRemote reports whether path is an http:// or https:// URL
+func IsRemote(path string) bool {
+	u, err := url.Parse(path)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// remoteEntry describes a cached download; it is stored as JSON beside the body
+type remoteEntry struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	ContentType  string `json:"content_type,omitempty"`
+}
+
+// removeOnClose is a downloaded body that is deleted once read
+type removeOnClose struct {
+	*os.File
+}
+
+// Close closes and removes the file
+func (f removeOnClose) Close() error {
+	err := f.File.Close()
+	os.Remove(f.File.Name())
+	return err
+}
+
+// fetchRemote downloads rawURL, or revalidates its cached copy, and returns the body
+// and its content type
+func (l *Loader) fetchRemote(rawURL string) (io.ReadCloser, string, error) {
+	if !l.remote.enabled {
+		return nil, "", fmt.Errorf("remote input is disabled, cannot load %s", rawURL)
+	}
+	timeout := l.remote.timeout
+	if timeout <= 0 {
+		timeout = DefaultRemoteTimeout
+	}
+	maxBytes := l.remote.maxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultRemoteMaxBytes
+	}
+
+	request, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid URL %s: %w", rawURL, err)
+	}
+
+	var bodyPath, entryPath string
+	var cached *remoteEntry
+	if dir := l.remote.cacheDir; dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, "", fmt.Errorf("error creating remote cache directory: %w", err)
+		}
+		sum := sha256.Sum256([]byte(rawURL))
+		name := hex.EncodeToString(sum[:8])
+		bodyPath = filepath.Join(dir, name+".body")
+		entryPath = filepath.Join(dir, name+".json")
+		cached = readRemoteEntry(entryPath, bodyPath, rawURL)
+		if cached != nil {
+			if cached.ETag != "" {
+				request.Header.Set("If-None-Match", cached.ETag)
+			}
+			if cached.LastModified != "" {
+				request.Header.Set("If-Modified-Since", cached.LastModified)
+			}
+		}
+	}
+
+	response, err := l.remoteClient(timeout).Do(request)
+	if err != nil {
+		return nil, "", fmt.Errorf("error fetching %s: %w", rawURL, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotModified && cached != nil {
+		body, err := os.Open(bodyPath)
+		if err != nil {
+			return nil, "", fmt.Errorf("error opening cached copy of %s: %w", rawURL, err)
+		}
+		return body, cached.ContentType, nil
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("error fetching %s: HTTP %s", rawURL, response.Status)
+	}
+	if response.ContentLength > maxBytes {
+		return nil, "", fmt.Errorf("error fetching %s: %d bytes exceeds the %d byte limit", rawURL, response.ContentLength, maxBytes)
+	}
+
+	download, err := os.CreateTemp(l.remote.cacheDir, "download-*")
+	if err != nil {
+		return nil, "", fmt.Errorf("error creating download file: %w", err)
+	}
+	body := removeOnClose{download}
+	n, err := io.Copy(download, io.LimitReader(response.Body, maxBytes+1))
+	if err != nil {
+		body.Close()
+		return nil, "", fmt.Errorf("error downloading %s: %w", rawURL, err)
+	}
+	if n > maxBytes {
+		body.Close()
+		return nil, "", fmt.Errorf("error fetching %s: body exceeds the %d byte limit", rawURL, maxBytes)
+	}
+
+	contentType := response.Header.Get("Content-Type")
+	if bodyPath == "" {
+		if _, err := download.Seek(0, io.SeekStart); err != nil {
+			body.Close()
+			return nil, "", fmt.Errorf("error reading download of %s: %w", rawURL, err)
+		}
+		return body, contentType, nil
+	}
+
+	// Move the download into the cache before recording it, so an entry always has
+	// its body
+	download.Close()
+	if err := os.Rename(download.Name(), bodyPath); err != nil {
+		os.Remove(download.Name())
+		return nil, "", fmt.Errorf("error caching %s: %w", rawURL, err)
+	}
+	entry, err := json.Marshal(remoteEntry{
+		URL:          rawURL,
+		ETag:         response.Header.Get("ETag"),
+		LastModified: response.Header.Get("Last-Modified"),
+		ContentType:  contentType,
+	})
+	if err == nil {
+		err = os.WriteFile(entryPath, entry, 0644)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("error caching %s: %w", rawURL, err)
+	}
+
+	cachedBody, err := os.Open(bodyPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("error opening cached copy of %s: %w", rawURL, err)
+	}
+	return cachedBody, contentType, nil
+}
+
+// remoteClient returns the client downloads are made with. It follows at most
+// maxRemoteRedirects redirects and, unless private addresses are allowed, checks every
+// address it connects to after resolution, so neither a redirect nor a host name that
+// resolves to a private address gets around the check.
+func (l *Loader) remoteClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !l.remote.allowPrivate {
+		dialer := &net.Dialer{Timeout: timeout, Control: refusePrivateAddress}
+		transport.DialContext = dialer.DialContext
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(request *http.Request, via []*http.Request) error {
+			if len(via) >= maxRemoteRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRemoteRedirects)
+			}
+			return nil
+		},
+	}
+}
+
+// errPrivateAddress is returned for connections to addresses outside the public internet
+var errPrivateAddress = errors.New("refusing to connect to a private address")
+
+// refusePrivateAddress is a net.Dialer Control function that refuses loopback,
+// link-local, private and unspecified addresses
+func refusePrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	ip = ip.Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("%w %s", errPrivateAddress, ip)
+	}
+	return nil
+}
+
+// readRemoteEntry returns the cache entry of rawURL, or nil when it has none or its
+// body is missing
+func readRemoteEntry(entryPath, bodyPath, rawURL string) *remoteEntry {
+	data, err := os.ReadFile(entryPath)
+	if err != nil {
+		return nil
+	}
+	var entry remoteEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != rawURL {
+		return nil
+	}
+	if _, err := os.Stat(bodyPath); err != nil {
+		return nil
+	}
+	return &entry
+}
+
+// remoteFileType returns the file type of a download from its content type, or from
+// the extension of the URL's path when the content type does not name one
+func remoteFileType(rawURL, contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv":
+		return "csv"
+	case "text/tab-separated-values":
+		return "tsv"
+	case "application/jsonl", "application/x-jsonlines", "application/x-ndjson", "application/json":
+		return "jsonl"
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "txt"
+	}
+	return GetFileType(u.Path)
+}
@@ -0,0 +1,164 @@
+package loader
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// remoteServer serves fixed bodies by path, honoring If-None-Match, and counts the
+// bodies it sends
+func remoteServer(t *testing.T, sent *int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/corpus":
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Write([]byte("text,id\nfrom csv,1\n"))
+		case "/chat.jsonl":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(`{"text": "from jsonl"}` + "\n"))
+		case "/cached.txt":
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			*sent++
+			w.Write([]byte("cached line\n"))
+		case "/large.txt":
+			w.Write([]byte(strings.Repeat("x", 100)))
+		case "/moved.txt":
+			http.Redirect(w, r, "/cached.txt", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestLoadRemote(t *testing.T) {
+	var sent int
+	server := remoteServer(t, &sent)
+
+	tests := []struct {
+		name    string
+		path    string
+		options []Option
+		want    string
+		wantErr string
+	}{
+		{"content type", "/corpus", nil, "from csv", ""},
+		{"URL extension", "/chat.jsonl", nil, "from jsonl", ""},
+		{"size cap", "/large.txt", []Option{WithRemoteMaxBytes(10)}, "", "10 byte limit"},
+		{"HTTP status", "/missing.txt", nil, "", "404 Not Found"},
+		{"redirect", "/moved.txt", nil, "cached line", ""},
+		{"redirect loop", "/loop", nil, "", "stopped after 5 redirects"},
+		// The test server listens on loopback
+		{"private address", "/corpus", []Option{WithRemotePrivateAddresses(false)}, "", "refusing to connect to a private address 127.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := append([]Option{WithRemote(true), WithRemotePrivateAddresses(true)}, tt.options...)
+			documents, err := NewLoader(FileTypeAuto, options...).LoadDocuments(server.URL + tt.path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), server.URL+tt.path) {
+					t.Errorf("error = %v, want the URL and %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadDocuments returned error: %v", err)
+			}
+			if len(documents) != 1 || documents[0].Content != tt.want || documents[0].FilePath != server.URL+tt.path {
+				t.Errorf("documents = %+v, want %q from the URL", documents, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewLoader("txt").LoadDocuments(server.URL + "/cached.txt"); err == nil || !strings.Contains(err.Error(), "disabled") {
+		t.Errorf("error = %v, want remote input disabled by default", err)
+	}
+
+	// Local paths detect their type from the extension
+	documents, err := NewLoader(FileTypeAuto).LoadDocuments(filepath.Join("testdata", "records.csv"))
+	if err != nil || len(documents) != 2 || documents[0].Content != "first record" {
+		t.Errorf("auto-detected CSV loaded %+v, %v", documents, err)
+	}
+}
+
+func TestLoadRemoteCache(t *testing.T) {
+	var sent int
+	server := remoteServer(t, &sent)
+	cacheDir := t.TempDir()
+
+	for i := 0; i < 3; i++ {
+		documents, err := NewLoader("txt", WithRemote(true), WithRemotePrivateAddresses(true), WithRemoteCacheDir(cacheDir)).LoadDocuments(server.URL + "/cached.txt")
+		if err != nil {
+			t.Fatalf("load %d returned error: %v", i, err)
+		}
+		if len(documents) != 1 || documents[0].Content != "cached line" {
+			t.Errorf("load %d documents = %+v", i, documents)
+		}
+	}
+	if sent != 1 {
+		t.Errorf("server sent the body %d times, want once", sent)
+	}
+
+	// Without a cache directory nothing is kept and every load downloads
+	if _, err := NewLoader("txt", WithRemote(true), WithRemotePrivateAddresses(true)).LoadDocuments(server.URL + "/cached.txt"); err != nil {
+		t.Fatalf("LoadDocuments returned error: %v", err)
+	}
+	if sent != 2 {
+		t.Errorf("server sent the body %d times, want twice", sent)
+	}
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil || len(entries) != 2 {
+		t.Errorf("cache directory holds %d entries, want a body and its record", len(entries))
+	}
+}
+
+func TestIsRemote(t *testing.T) {
+	for path, want := range map[string]bool{
+		"https://example.com/corpus.txt": true,
+		"http://example.com/a.csv?x=1":   true,
+		"testdata/lines.txt":             false,
+		"/abs/path.txt":                  false,
+		"ftp://example.com/a.txt":        false,
+		StdinPath:                        false,
+	} {
+		if got := IsRemote(path); got != want {
+			t.Errorf("IsRemote(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestRefusePrivateAddress(t *testing.T) {
+	for address, private := range map[string]bool{
+		"127.0.0.1:80":               true,
+		"[::1]:443":                  true,
+		"10.1.2.3:80":                true,
+		"172.16.0.1:80":              true,
+		"192.168.1.1:80":             true,
+		"169.254.169.254:80":         true,
+		"[fe80::1%eth0]:80":          true,
+		"[fd00::1]:80":               true,
+		"0.0.0.0:80":                 true,
+		"[::ffff:127.0.0.1]:80":      true,
+		"93.184.216.34:443":          false,
+		"[2606:4700::6810:84e5]:443": false,
+	} {
+		err := refusePrivateAddress("tcp", address, nil)
+		if got := errors.Is(err, errPrivateAddress); got != private || (!private && err != nil) {
+			t.Errorf("refusePrivateAddress(%s) = %v, want refused %v", address, err, private)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package loader
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"unicode/utf8"
+)
+
+// samplingOptions limit which loaded documents are kept; zero values disable a limit
+type samplingOptions struct {
+	minChars, maxChars int
+	maxDocuments       int
+	sampleN            int
+	seed               int64
+	maxTotalBytes      int64
+}
+
+// SamplingReport records how the documents of the last load were filtered and
+// sampled, so analyses can state how their input was chosen. Skipped counts the
+// documents each filter dropped, keyed by duplicate, min_chars, max_chars,
+// max_documents, sample_n and max_total_bytes.
+type SamplingReport struct {
+	Loaded  int            `json:"loaded"`
+	Kept    int            `json:"kept"`
+	Skipped map[string]int `json:"skipped,omitempty"`
+	Sampled bool           `json:"sampled"`
+	Seed    int64          `json:"seed"`
+}
+
+// WithLengthRange keeps only documents of at least minChars and at most maxChars
+// characters; zero leaves that end open
+func WithLengthRange(minChars, maxChars int) Option {
+	return func(l *Loader) {
+		l.sampling.minChars = minChars
+		l.sampling.maxChars = maxChars
+	}
+}
+
+// WithMaxDocuments keeps only the first n documents
+func WithMaxDocuments(n int) Option {
+	return func(l *Loader) {
+		l.sampling.maxDocuments = n
+	}
+}
+
+// WithSample keeps a random sample of n documents, in file order. The same seed
+// always picks the same documents from the same file.
+func WithSample(n int, seed int64) Option {
+	return func(l *Loader) {
+		l.sampling.sampleN = n
+		l.sampling.seed = seed
+	}
+}
+
+// WithMaxTotalBytes keeps documents in order until their content would exceed n bytes
+func WithMaxTotalBytes(n int64) Option {
+	return func(l *Loader) {
+		l.sampling.maxTotalBytes = n
+	}
+}
+
+// Sampling returns how the documents of the last load were filtered and sampled
+func (l *Loader) Sampling() SamplingReport {
+	return l.samplingReport
+}
+
+// sample applies deduplication, the length filters, the document limit, random
+// sampling and the byte budget to documents, in that order, and records what each
+// dropped. Sampled documents carry the seed in their metadata.
+func (l *Loader) sample(documents []Document) []Document {
+	opts := l.sampling
+	report := SamplingReport{Loaded: len(documents), Seed: opts.seed, Skipped: map[string]int{}}
+
+	seen := make(map[string]bool)
+	kept := documents[:0]
+	for _, doc := range documents {
+		chars := utf8.RuneCountInString(doc.Content)
+		switch {
+		case l.dedup && seen[doc.ID]:
+			report.Skipped["duplicate"]++
+		case opts.minChars > 0 && chars < opts.minChars:
+			report.Skipped["min_chars"]++
+		case opts.maxChars > 0 && chars > opts.maxChars:
+			report.Skipped["max_chars"]++
+		default:
+			seen[doc.ID] = true
+			kept = append(kept, doc)
+		}
+	}
+
+	if opts.maxDocuments > 0 && len(kept) > opts.maxDocuments {
+		report.Skipped["max_documents"] = len(kept) - opts.maxDocuments
+		kept = kept[:opts.maxDocuments]
+	}
+
+	if opts.sampleN > 0 {
+		report.Sampled = true
+		if len(kept) > opts.sampleN {
+			indexes := rand.New(rand.NewSource(opts.seed)).Perm(len(kept))[:opts.sampleN]
+			sort.Ints(indexes)
+			sampled := make([]Document, len(indexes))
+			for i, index := range indexes {
+				sampled[i] = kept[index]
+			}
+			report.Skipped["sample_n"] = len(kept) - opts.sampleN
+			kept = sampled
+		}
+		for i := range kept {
+			if kept[i].Metadata == nil {
+				kept[i].Metadata = make(map[string]string)
+			}
+			kept[i].Metadata["sample_seed"] = fmt.Sprint(opts.seed)
+		}
+	}
+
+	if opts.maxTotalBytes > 0 {
+		var total int64
+		for i, doc := range kept {
+			total += int64(len(doc.Content))
+			if total > opts.maxTotalBytes {
+				report.Skipped["max_total_bytes"] = len(kept) - i
+				kept = kept[:i]
+				break
+			}
+		}
+	}
+
+	if len(report.Skipped) == 0 {
+		report.Skipped = nil
+	}
+	report.Kept = len(kept)
+	l.samplingReport = report
+	return kept
+}
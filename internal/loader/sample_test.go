@@ -0,0 +1,165 @@
+package loader
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// lengths returns the content lengths of documents
+func lengths(documents []Document) []int {
+	got := make([]int, len(documents))
+	for i, doc := range documents {
+		got[i] = len(doc.Content)
+	}
+	return got
+}
+
+func TestSampling(t *testing.T) {
+	sizes := filepath.Join("testdata", "sizes.txt") // lines of 1 to 10 characters
+
+	tests := []struct {
+		name        string
+		options     []Option
+		want        []int
+		wantSkipped map[string]int
+	}{
+		{"no limits", nil, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, nil},
+		{"length range", []Option{WithLengthRange(3, 5)}, []int{3, 4, 5}, map[string]int{"min_chars": 2, "max_chars": 5}},
+		{"open ended range", []Option{WithLengthRange(9, 0)}, []int{9, 10}, map[string]int{"min_chars": 8}},
+		{"max documents", []Option{WithMaxDocuments(4)}, []int{1, 2, 3, 4}, map[string]int{"max_documents": 6}},
+		{"byte budget", []Option{WithMaxTotalBytes(10)}, []int{1, 2, 3, 4}, map[string]int{"max_total_bytes": 6}},
+		{"filters apply in order", []Option{WithLengthRange(2, 0), WithMaxDocuments(5), WithMaxTotalBytes(9)},
+			[]int{2, 3, 4}, map[string]int{"min_chars": 1, "max_documents": 4, "max_total_bytes": 2}},
+		{"sample larger than input", []Option{WithSample(20, 1)}, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLoader("txt", tt.options...)
+			documents, err := l.LoadDocuments(sizes)
+			if err != nil {
+				t.Fatalf("LoadDocuments returned error: %v", err)
+			}
+			if got := lengths(documents); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("document lengths = %v, want %v", got, tt.want)
+			}
+			report := l.Sampling()
+			if report.Loaded != 10 || report.Kept != len(tt.want) {
+				t.Errorf("loaded %d and kept %d, want 10 and %d", report.Loaded, report.Kept, len(tt.want))
+			}
+			if !reflect.DeepEqual(report.Skipped, tt.wantSkipped) {
+				t.Errorf("skipped = %v, want %v", report.Skipped, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestSampleIsSeeded(t *testing.T) {
+	sizes := filepath.Join("testdata", "sizes.txt")
+	load := func(seed int64) ([]int, *Loader) {
+		l := NewLoader("txt", WithSample(4, seed))
+		documents, err := l.LoadDocuments(sizes)
+		if err != nil {
+			t.Fatalf("LoadDocuments returned error: %v", err)
+		}
+		for _, doc := range documents {
+			if doc.Metadata["sample_seed"] != fmt.Sprint(seed) {
+				t.Errorf("sample_seed metadata = %q, want %d", doc.Metadata["sample_seed"], seed)
+			}
+		}
+		return lengths(documents), l
+	}
+
+	first, l := load(7)
+	if len(first) != 4 || !sort.IntsAreSorted(first) {
+		t.Errorf("sample = %v, want 4 documents in file order", first)
+	}
+	if again, _ := load(7); !reflect.DeepEqual(first, again) {
+		t.Errorf("same seed sampled %v and then %v", first, again)
+	}
+	if report := l.Sampling(); !report.Sampled || report.Seed != 7 || report.Skipped["sample_n"] != 6 {
+		t.Errorf("report = %+v, want a seed 7 sample skipping 6 documents", report)
+	}
+
+	differs := false
+	for seed := int64(8); seed < 20 && !differs; seed++ {
+		other, _ := load(seed)
+		differs = !reflect.DeepEqual(first, other)
+	}
+	if !differs {
+		t.Error("every seed sampled the same documents")
+	}
+}
+
+func TestSamplingAppliesToEveryFileType(t *testing.T) {
+	tests := []struct {
+		fileType, file string
+		want           []int
+	}{
+		{"jsonl", "records.jsonl", []int{12}},
+		{"csv", "records.csv", []int{12}},
+		{"tsv", "records.tsv", []int{11}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileType, func(t *testing.T) {
+			l := NewLoader(tt.fileType, WithLengthRange(11, 12))
+			documents, err := l.LoadDocuments(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatalf("LoadDocuments returned error: %v", err)
+			}
+			if got := lengths(documents); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("document lengths = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocumentIDs(t *testing.T) {
+	text, err := NewLoader("txt").LoadDocuments(filepath.Join("testdata", "records.jsonl"))
+	if err != nil {
+		t.Fatalf("LoadDocuments returned error: %v", err)
+	}
+	jsonl, err := NewLoader("jsonl").LoadDocuments(filepath.Join("testdata", "records.jsonl"))
+	if err != nil {
+		t.Fatalf("LoadDocuments returned error: %v", err)
+	}
+
+	if jsonl[0].ID != ContentID("first record") || len(jsonl[0].ID) != 16 {
+		t.Errorf("ID = %q, want the content ID of its text", jsonl[0].ID)
+	}
+	if jsonl[0].ID == jsonl[1].ID {
+		t.Error("documents with different text share an ID")
+	}
+	if text[0].ID == jsonl[0].ID {
+		t.Error("a raw JSON line and its text field share an ID")
+	}
+}
+
+func TestDedup(t *testing.T) {
+	duplicates := filepath.Join("testdata", "duplicates.txt") // alpha, beta, alpha, gamma, beta, alpha
+
+	documents, err := NewLoader("txt").LoadDocuments(duplicates)
+	if err != nil {
+		t.Fatalf("LoadDocuments returned error: %v", err)
+	}
+	if len(documents) != 6 || documents[0].ID != documents[2].ID {
+		t.Errorf("without dedup loaded %d documents, want all 6 with repeated IDs", len(documents))
+	}
+
+	l := NewLoader("txt", WithDedup(true), WithMaxDocuments(2))
+	documents, err = l.LoadDocuments(duplicates)
+	if err != nil {
+		t.Fatalf("LoadDocuments returned error: %v", err)
+	}
+	if got := spans(documents); !reflect.DeepEqual(got, []span{{"alpha", 1, 1}, {"beta", 2, 2}}) {
+		t.Errorf("documents = %q, want the first alpha and beta", got)
+	}
+	want := map[string]int{"duplicate": 3, "max_documents": 1}
+	if report := l.Sampling(); !reflect.DeepEqual(report.Skipped, want) {
+		t.Errorf("skipped = %v, want %v", report.Skipped, want)
+	}
+}
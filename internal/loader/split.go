@@ -0,0 +1,87 @@
+package loader
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SplitMode selects how a text file is split into documents
+type SplitMode string
+
+const (
+	// SplitLine makes each non-blank line a document
+	SplitLine SplitMode = "line"
+	// SplitParagraph makes each run of non-blank lines a document, splitting on blank lines
+	SplitParagraph SplitMode = "paragraph"
+	// SplitFile makes the whole file a single document
+	SplitFile SplitMode = "file"
+	// SplitDelimiter makes the text between delimiter lines a document; an empty
+	// delimiter splits on blank lines like SplitParagraph
+	SplitDelimiter SplitMode = "delimiter"
+)
+
+// textSpan is the text of a document and the lines it spans, numbered from 1
+type textSpan struct {
+	text       string
+	start, end int
+}
+
+// splitText splits text into documents by mode. Line endings may be LF or CRLF. Blank
+// lines at either end of a document are left out of it, and documents with nothing
+// but whitespace are skipped.
+func splitText(text string, mode SplitMode, delimiter string) ([]textSpan, error) {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+
+	blank := func(line string) bool { return strings.TrimSpace(line) == "" }
+	var separator func(line string) bool
+	switch mode {
+	case SplitLine:
+		var spans []textSpan
+		for i, line := range lines {
+			if !blank(line) {
+				spans = append(spans, textSpan{text: strings.TrimSpace(line), start: i + 1, end: i + 1})
+			}
+		}
+		return spans, nil
+	case SplitParagraph:
+		separator = blank
+	case SplitFile:
+		separator = func(string) bool { return false }
+	case SplitDelimiter:
+		separator = blank
+		if delimiter = strings.TrimSpace(delimiter); delimiter != "" {
+			separator = func(line string) bool { return strings.TrimSpace(line) == delimiter }
+		}
+	default:
+		return nil, fmt.Errorf("unsupported split mode: %s (use line, paragraph, file or delimiter)", mode)
+	}
+
+	var spans []textSpan
+	first, last := -1, -1 // first and last non-blank lines of the current document
+	flush := func() {
+		if first >= 0 {
+			spans = append(spans, textSpan{
+				text:  strings.TrimSpace(strings.Join(lines[first:last+1], "\n")),
+				start: first + 1,
+				end:   last + 1,
+			})
+		}
+		first, last = -1, -1
+	}
+	for i, line := range lines {
+		switch {
+		case separator(line):
+			flush()
+		case !blank(line):
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	flush()
+	return spans, nil
+}
@@ -1,19 +1,120 @@
 package logger
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"os"
+	"sync"
 
 	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
+// RequestIDField is the field naming the HTTP request an event belongs to
+const RequestIDField = "request_id"
+
 // Logger wraps logrus.Logger with additional functionality
 type Logger struct {
 	*logrus.Logger
+
+	// fields are added to every event, and events below level are dropped. Loggers
+	// made by With and ForModule share the logrus.Logger and the shared state of the
+	// logger they were made from.
+	fields  logrus.Fields
+	level   logrus.Level
+	shared  *shared
+	sampler *sampler
+}
+
+// shared is the state of the loggers made from one New call
+type shared struct {
+	file      io.WriteCloser // the log file written besides stdout, if any
+	closeOnce sync.Once
+
+	level    logrus.Level            // level of loggers without a module
+	levels   map[string]logrus.Level // levels of modules that override it
+	sampling Sampling
 }
 
-// New creates a new logger with the specified configuration
-func New(level, format, file string) (*Logger, error) {
+// discard is the logger of contexts without one
+var discard = &Logger{
+	Logger: &logrus.Logger{Out: io.Discard, Formatter: new(logrus.TextFormatter), Hooks: make(logrus.LevelHooks), Level: logrus.PanicLevel},
+	level:  logrus.PanicLevel,
+	shared: &shared{level: logrus.PanicLevel},
+}
+
+// Discard returns a logger that discards every event
+func Discard() *Logger {
+	return discard
+}
+
+// contextKey is the context key of the logger
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying l, for code serving a request to log with
+// its fields
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or one that discards every event
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(contextKey{}).(*Logger); ok {
+		return l
+	}
+	return discard
+}
+
+// Rotation limits the size of a log file. The file is renamed with a timestamp and a
+// new one started once it reaches MaxSizeMB; old files beyond MaxBackups or older than
+// MaxAgeDays are deleted.
+type Rotation struct {
+	MaxSizeMB  int  // rotate at this size; 0 never rotates
+	MaxBackups int  // rotated files kept; 0 keeps all
+	MaxAgeDays int  // days rotated files are kept; 0 keeps them regardless of age
+	Compress   bool // gzip rotated files
+}
+
+// Option configures New
+type Option func(*settings)
+
+// settings are the settings of a New call
+type settings struct {
+	rotation Rotation
+	levels   map[string]string
+	sampling Sampling
+}
+
+// WithRotation rotates the log file by size
+func WithRotation(rotation Rotation) Option {
+	return func(s *settings) {
+		s.rotation = rotation
+	}
+}
+
+// WithModuleLevels overrides the level of the loggers ForModule returns, by module
+func WithModuleLevels(levels map[string]string) Option {
+	return func(s *settings) {
+		s.levels = levels
+	}
+}
+
+// WithSampling thins out the events logged once per metric
+func WithSampling(sampling Sampling) Option {
+	return func(s *settings) {
+		s.sampling = sampling
+	}
+}
+
+// New creates a new logger with the specified configuration. Logs go to stdout and,
+// when file is set, are appended to it as well.
+func New(level, format, file string, options ...Option) (*Logger, error) {
+	var settings settings
+	for _, option := range options {
+		option(&settings)
+	}
+
 	logger := logrus.New()
 	
 	// Set log level
@@ -21,7 +122,19 @@ func New(level, format, file string) (*Logger, error) {
 	if err != nil {
 		return nil, err
 	}
-	logger.SetLevel(logLevel)
+	// Module levels may be more verbose than the level, so the logrus logger lets
+	// through everything any module logs and each Logger filters by its own level
+	levels := make(map[string]logrus.Level, len(settings.levels))
+	verbose := logLevel
+	for module, name := range settings.levels {
+		moduleLevel, err := logrus.ParseLevel(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid level of module %s: %w", module, err)
+		}
+		levels[module] = moduleLevel
+		verbose = max(verbose, moduleLevel)
+	}
+	logger.SetLevel(verbose)
 	
 	// Set log format
 	switch format {
@@ -40,35 +153,125 @@ func New(level, format, file string) (*Logger, error) {
 		})
 	}
 	
+	state := &shared{level: logLevel, levels: levels, sampling: settings.sampling}
+
 	// Set output
-	if file != "" {
-		file, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if file == "" {
+		logger.SetOutput(os.Stdout)
+	} else {
+		output, err := openFile(file, settings.rotation)
 		if err != nil {
 			return nil, err
 		}
-		logger.SetOutput(io.MultiWriter(os.Stdout, file))
-	} else {
-		logger.SetOutput(os.Stdout)
+		logger.SetOutput(io.MultiWriter(os.Stdout, output))
+		state.file = output
 	}
-	
-	return &Logger{Logger: logger}, nil
+	return &Logger{Logger: logger, level: logLevel, shared: state, sampler: newSampler(settings.sampling)}, nil
+}
+
+// openFile opens the log file for appending, through a rotating writer when rotation
+// has a size limit
+func openFile(path string, rotation Rotation) (io.WriteCloser, error) {
+	if rotation.MaxSizeMB <= 0 {
+		return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	}
+
+	writer := &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    rotation.MaxSizeMB,
+		MaxBackups: rotation.MaxBackups,
+		MaxAge:     rotation.MaxAgeDays,
+		Compress:   rotation.Compress,
+		LocalTime:  true,
+	}
+	// lumberjack opens the file on the first write; an empty write reports a file
+	// that cannot be opened now rather than losing the first log line
+	if _, err := writer.Write(nil); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// Close closes the log file, after which only stdout is written. It is safe to call
+// more than once, and on any logger made from the same New call.
+func (l *Logger) Close() error {
+	var err error
+	l.shared.closeOnce.Do(func() {
+		if l.shared.file == nil {
+			return
+		}
+		l.Logger.SetOutput(os.Stdout)
+		err = l.shared.file.Close()
+	})
+	return err
+}
+
+// With returns a logger that adds fields to every event, writing to the same output
+func (l *Logger) With(fields logrus.Fields) *Logger {
+	merged := make(logrus.Fields, len(l.fields)+len(fields))
+	for k, v := range l.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &Logger{Logger: l.Logger, fields: merged, level: l.level, shared: l.shared, sampler: l.sampler}
+}
+
+// WithRequestID returns a logger that tags every event with the request ID
+func (l *Logger) WithRequestID(id string) *Logger {
+	return l.With(logrus.Fields{RequestIDField: id})
+}
+
+// IsLevelEnabled reports whether the logger logs events at level
+func (l *Logger) IsLevelEnabled(level logrus.Level) bool {
+	return l.level >= level
+}
+
+// entry returns an entry with the logger's fields and level
+func (l *Logger) entry() *Entry {
+	return &Entry{Entry: l.Logger.WithFields(l.fields), level: l.level}
 }
 
 // WithField adds a field to the logger
-func (l *Logger) WithField(key string, value interface{}) *logrus.Entry {
-	return l.Logger.WithField(key, value)
+func (l *Logger) WithField(key string, value interface{}) *Entry {
+	return l.entry().WithField(key, value)
 }
 
 // WithFields adds multiple fields to the logger
-func (l *Logger) WithFields(fields logrus.Fields) *logrus.Entry {
-	return l.Logger.WithFields(fields)
+func (l *Logger) WithFields(fields logrus.Fields) *Entry {
+	return l.entry().WithFields(fields)
 }
 
 // WithError adds an error field to the logger
-func (l *Logger) WithError(err error) *logrus.Entry {
-	return l.Logger.WithError(err)
+func (l *Logger) WithError(err error) *Entry {
+	return l.entry().WithError(err)
 }
 
+// Debug logs a message at debug level with the logger's fields
+func (l *Logger) Debug(args ...interface{}) { l.entry().Debug(args...) }
+
+// Info logs a message at info level with the logger's fields
+func (l *Logger) Info(args ...interface{}) { l.entry().Info(args...) }
+
+// Warn logs a message at warning level with the logger's fields
+func (l *Logger) Warn(args ...interface{}) { l.entry().Warn(args...) }
+
+// Error logs a message at error level with the logger's fields
+func (l *Logger) Error(args ...interface{}) { l.entry().Error(args...) }
+
+// Debugf logs a formatted message at debug level with the logger's fields
+func (l *Logger) Debugf(format string, args ...interface{}) { l.entry().Debugf(format, args...) }
+
+// Infof logs a formatted message at info level with the logger's fields
+func (l *Logger) Infof(format string, args ...interface{}) { l.entry().Infof(format, args...) }
+
+// Warnf logs a formatted message at warning level with the logger's fields
+func (l *Logger) Warnf(format string, args ...interface{}) { l.entry().Warnf(format, args...) }
+
+// Errorf logs a formatted message at error level with the logger's fields
+func (l *Logger) Errorf(format string, args ...interface{}) { l.entry().Errorf(format, args...) }
+
 // LogAnalysisStart logs the start of an analysis run
 func (l *Logger) LogAnalysisStart(inputFile string, tokenizers []string) {
 	l.WithFields(logrus.Fields{
@@ -86,13 +289,17 @@ func (l *Logger) LogAnalysisComplete(stats map[string]interface{}) {
 	}).Info("Tokenization analysis completed")
 }
 
-// LogTokenizerStart logs the start of tokenizer processing
+// LogTokenizerStart logs the start of tokenizer processing. inputFile may be empty
+// when the text did not come from a file.
 func (l *Logger) LogTokenizerStart(tokenizerName string, inputFile string) {
-	l.WithFields(logrus.Fields{
+	fields := logrus.Fields{
 		"event":          "tokenizer_start",
 		"tokenizer_name": tokenizerName,
-		"input_file":     inputFile,
-	}).Info("Starting tokenizer processing")
+	}
+	if inputFile != "" {
+		fields["input_file"] = inputFile
+	}
+	l.WithFields(fields).Info("Starting tokenizer processing")
 }
 
 // LogTokenizerComplete logs the completion of tokenizer processing
@@ -107,6 +314,11 @@ func (l *Logger) LogTokenizerComplete(tokenizerName string, tokenCount int, dura
 
 // LogMetricCalculation logs metric calculation events
 func (l *Logger) LogMetricCalculation(metricName string, tokenizerName string, value float64) {
+	// Metrics are many per document, so skip building their fields when unused, and
+	// log only those the sampling picks
+	if !l.IsLevelEnabled(logrus.DebugLevel) || !l.sampler.allow() {
+		return
+	}
 	l.WithFields(logrus.Fields{
 		"event":          "metric_calculation",
 		"metric_name":    metricName,
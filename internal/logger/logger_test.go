@@ -0,0 +1,212 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewWritesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ted.log")
+	if err := os.WriteFile(path, []byte("earlier line\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	log, err := New("info", "text", path)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	log.Info("first message")
+	if err := log.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	// Closing again does nothing, and later lines only go to stdout
+	if err := log.Close(); err != nil {
+		t.Errorf("second Close returned error: %v", err)
+	}
+	log.Info("after close")
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(content), "earlier line\n") || !strings.Contains(string(content), "first message") {
+		t.Errorf("log file = %q, want the message appended", content)
+	}
+	if strings.Contains(string(content), "after close") {
+		t.Errorf("log file = %q, want nothing written after Close", content)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	if _, err := New("loud", "json", ""); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+	missing := filepath.Join(t.TempDir(), "missing", "ted.log")
+	if _, err := New("info", "json", missing); err == nil {
+		t.Error("expected an error for a file in a missing directory")
+	}
+}
+
+func TestOpenFileRotates(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ted.log")
+	file, err := openFile(path, Rotation{MaxSizeMB: 1, MaxBackups: 1})
+	if err != nil {
+		t.Fatalf("openFile returned error: %v", err)
+	}
+
+	line := []byte(strings.Repeat("x", 1023) + "\n")
+	for i := 0; i < 3*1024; i++ {
+		if _, err := file.Write(line); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+	}
+	if err := file.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	// Each rotation renames the full file. Old backups are removed in the background,
+	// so only check one was made.
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) < 2 {
+		t.Errorf("log directory has %d files, want the log and a backup", len(entries))
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() > 1<<20 {
+		t.Errorf("log file is %d bytes, want at most 1 MB", info.Size())
+	}
+}
+
+func TestOpenFileWithoutRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ted.log")
+	file, err := openFile(path, Rotation{MaxBackups: 3})
+	if err != nil {
+		t.Fatalf("openFile returned error: %v", err)
+	}
+	defer file.Close()
+	if _, ok := file.(*os.File); !ok {
+		t.Errorf("openFile returned %T, want a plain file without a size limit", file)
+	}
+}
+
+func TestWithRequestID(t *testing.T) {
+	log, err := New("debug", "json", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+
+	ctx := NewContext(context.Background(), log.WithRequestID("abc123"))
+	FromContext(ctx).LogMetricCalculation("token_count", "gpt2", 3)
+	FromContext(ctx).Info("plain message")
+	log.Info("untagged message")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("logged %d lines, want 3:\n%s", len(lines), buf.String())
+	}
+	for i, want := range []string{"abc123", "abc123", ""} {
+		var event map[string]interface{}
+		if err := json.Unmarshal([]byte(lines[i]), &event); err != nil {
+			t.Fatalf("line %d is not JSON: %v", i, err)
+		}
+		got, _ := event[RequestIDField].(string)
+		if got != want {
+			t.Errorf("line %d has request ID %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestFromContextWithoutLogger(t *testing.T) {
+	log := FromContext(context.Background())
+	if log != Discard() {
+		t.Fatal("FromContext without a logger should return the discarding logger")
+	}
+	// Logging to it must be safe
+	log.LogTokenizerStart("gpt2", "")
+	log.WithRequestID("abc").LogError("analysis", os.ErrNotExist, nil)
+}
+
+func TestForModule(t *testing.T) {
+	log, err := New("info", "json", "", WithModuleLevels(map[string]string{
+		ModuleMetrics:    "warn",
+		ModuleTokenizers: "debug",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+
+	log.Debug("root debug")
+	log.ForModule(ModuleMetrics).Info("metrics info")
+	log.ForModule(ModuleMetrics).WithField("k", "v").Warn("metrics warning")
+	log.ForModule(ModuleTokenizers).WithFields(nil).Debug("tokenizers debug")
+	log.ForModule(ModuleServer).Info("server info")
+
+	output := buf.String()
+	for _, want := range []string{"metrics warning", "tokenizers debug", "server info"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("log is missing %q:\n%s", want, output)
+		}
+	}
+	for _, unwanted := range []string{"root debug", "metrics info"} {
+		if strings.Contains(output, unwanted) {
+			t.Errorf("log has %q, which is below its level:\n%s", unwanted, output)
+		}
+	}
+	if !strings.Contains(output, `"module":"tokenizers"`) {
+		t.Errorf("log is missing the module field:\n%s", output)
+	}
+
+	if _, err := New("info", "json", "", WithModuleLevels(map[string]string{ModuleServer: "loud"})); err == nil {
+		t.Error("expected an error for an unknown module level")
+	}
+}
+
+func TestSampling(t *testing.T) {
+	tests := []struct {
+		name     string
+		sampling Sampling
+		want     int
+	}{
+		{"all", Sampling{}, 25},
+		{"every 10th", Sampling{Every: 10}, 3},
+		{"first 5", Sampling{First: 5}, 5},
+		{"every 2nd of the first 5", Sampling{Every: 2, First: 5}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, err := New("debug", "json", "", WithSampling(tt.sampling))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			log.SetOutput(&buf)
+
+			// Each analysis samples afresh
+			for analysis := 0; analysis < 2; analysis++ {
+				buf.Reset()
+				metrics := log.ForAnalysis().ForModule(ModuleMetrics)
+				for i := 0; i < 25; i++ {
+					metrics.LogMetricCalculation("token_count", "gpt2", float64(i))
+				}
+				if got := strings.Count(buf.String(), "\n"); got != tt.want {
+					t.Errorf("analysis %d logged %d metric events, want %d", analysis, got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package logger
+
+import (
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ModuleField is the field naming the module an event comes from
+const ModuleField = "module"
+
+// Modules whose level can be set apart from the rest
+const (
+	ModuleMetrics       = "metrics"
+	ModuleTokenizers    = "tokenizers"
+	ModuleVisualization = "visualization"
+	ModuleServer        = "server"
+)
+
+// ForModule returns a logger that tags events with the module and logs at the
+// module's level, or at the level New was given when the module has none
+func (l *Logger) ForModule(module string) *Logger {
+	derived := l.With(logrus.Fields{ModuleField: module})
+	derived.level = l.shared.level
+	if level, ok := l.shared.levels[module]; ok {
+		derived.level = level
+	}
+	return derived
+}
+
+// ForAnalysis returns a logger whose sampling starts over, so that each analysis, such
+// as a dashboard request, logs its own first events
+func (l *Logger) ForAnalysis() *Logger {
+	derived := l.With(nil)
+	derived.sampler = newSampler(l.shared.sampling)
+	return derived
+}
+
+// Entry is a logrus entry that drops events below the level of the logger it came from
+type Entry struct {
+	*logrus.Entry
+	level logrus.Level
+}
+
+// WithField adds a field to the entry
+func (e *Entry) WithField(key string, value interface{}) *Entry {
+	return &Entry{Entry: e.Entry.WithField(key, value), level: e.level}
+}
+
+// WithFields adds multiple fields to the entry
+func (e *Entry) WithFields(fields logrus.Fields) *Entry {
+	return &Entry{Entry: e.Entry.WithFields(fields), level: e.level}
+}
+
+// WithError adds an error field to the entry
+func (e *Entry) WithError(err error) *Entry {
+	return &Entry{Entry: e.Entry.WithError(err), level: e.level}
+}
+
+// Debug logs a message at debug level
+func (e *Entry) Debug(args ...interface{}) {
+	if e.level >= logrus.DebugLevel {
+		e.Entry.Debug(args...)
+	}
+}
+
+// Info logs a message at info level
+func (e *Entry) Info(args ...interface{}) {
+	if e.level >= logrus.InfoLevel {
+		e.Entry.Info(args...)
+	}
+}
+
+// Warn logs a message at warning level
+func (e *Entry) Warn(args ...interface{}) {
+	if e.level >= logrus.WarnLevel {
+		e.Entry.Warn(args...)
+	}
+}
+
+// Error logs a message at error level
+func (e *Entry) Error(args ...interface{}) {
+	if e.level >= logrus.ErrorLevel {
+		e.Entry.Error(args...)
+	}
+}
+
+// Debugf logs a formatted message at debug level
+func (e *Entry) Debugf(format string, args ...interface{}) {
+	if e.level >= logrus.DebugLevel {
+		e.Entry.Debugf(format, args...)
+	}
+}
+
+// Infof logs a formatted message at info level
+func (e *Entry) Infof(format string, args ...interface{}) {
+	if e.level >= logrus.InfoLevel {
+		e.Entry.Infof(format, args...)
+	}
+}
+
+// Warnf logs a formatted message at warning level
+func (e *Entry) Warnf(format string, args ...interface{}) {
+	if e.level >= logrus.WarnLevel {
+		e.Entry.Warnf(format, args...)
+	}
+}
+
+// Errorf logs a formatted message at error level
+func (e *Entry) Errorf(format string, args ...interface{}) {
+	if e.level >= logrus.ErrorLevel {
+		e.Entry.Errorf(format, args...)
+	}
+}
+
+// Sampling thins out the events logged once per metric, which number in the hundreds
+// of thousands on a large corpus. Both limits apply: with Every 10 and First 100, the
+// 1st, 11th, ... 91st events of an analysis are logged.
+type Sampling struct {
+	Every int // log every Nth event; 0 or 1 logs each
+	First int // log none after the first K events of an analysis; 0 has no limit
+}
+
+// sampler counts the sampled events of an analysis
+type sampler struct {
+	sampling Sampling
+	count    atomic.Int64
+}
+
+// newSampler returns a sampler, or nil when sampling logs every event
+func newSampler(sampling Sampling) *sampler {
+	if sampling.Every <= 1 && sampling.First <= 0 {
+		return nil
+	}
+	return &sampler{sampling: sampling}
+}
+
+// allow counts an event and reports whether it is logged. A nil sampler allows all.
+func (s *sampler) allow() bool {
+	if s == nil {
+		return true
+	}
+	n := s.count.Add(1)
+	if s.sampling.First > 0 && n > int64(s.sampling.First) {
+		return false
+	}
+	return s.sampling.Every <= 1 || (n-1)%int64(s.sampling.Every) == 0
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/cache"
+	"github.com/RevBooyah/TokEntropyDrift/internal/logger"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// CacheHitKey is the result metadata key set to true on results AnalyzeDocument served
+// from the analysis cache, so that timings taken over them are not mistaken for
+// analysis timings
+const CacheHitKey = "cache_hit"
+
+// SetAnalysisCache makes AnalyzeDocument reuse the result of an earlier analysis of
+// the same text by the same tokenizer configuration. Results are keyed by the
+// engine's configuration as well, so engines with different settings can share a
+// cache; the hooks are not part of the key, so add them first, and clear the cache
+// when their behaviour changes. A nil cache turns caching off.
+func (e *Engine) SetAnalysisCache(analyses *cache.Cache) {
+	e.analysesMu.Lock()
+	defer e.analysesMu.Unlock()
+	e.analyses = analyses
+	if e.configKey == "" {
+		settings, _ := json.Marshal(e.config)
+		sum := sha256.Sum256(settings)
+		e.configKey = hex.EncodeToString(sum[:8])
+	}
+}
+
+// analysisCache returns the analysis cache and the engine's configuration key, or nil
+func (e *Engine) analysisCache() (*cache.Cache, string) {
+	e.analysesMu.RLock()
+	defer e.analysesMu.RUnlock()
+	return e.analyses, e.configKey
+}
+
+// analysisKey is the cache key of document analyzed by tokenizer
+func analysisKey(document string, tokenizer tokenizers.Tokenizer, configKey string) string {
+	sum := sha256.Sum256([]byte(document))
+	return cache.GenerateKey(hex.EncodeToString(sum[:]), tokenizer.Name()+"@"+tokenizer.ConfigFingerprint(), configKey)
+}
+
+// cachedAnalysis returns a copy of the cached result for key, marked as a cache hit
+func cachedAnalysis(ctx context.Context, analyses *cache.Cache, key string) (*AnalysisResult, bool) {
+	cached, found := analyses.Get(key)
+	if !found {
+		return nil, false
+	}
+	stored, ok := cached.(*AnalysisResult)
+	if !ok {
+		return nil, false
+	}
+
+	result := stored.Clone()
+	if result.Metadata == nil {
+		result.Metadata = make(map[string]interface{})
+	}
+	result.Metadata[CacheHitKey] = true
+	logger.FromContext(ctx).ForModule(logger.ModuleMetrics).WithField("tokenizer_name", result.TokenizerName).Debug("Analysis served from cache")
+	return result, true
+}
@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/cache"
+)
+
+func TestAnalysisCache(t *testing.T) {
+	analyses := cache.NewCache(cache.CacheConfig{MaxSize: 10})
+	defer analyses.Close()
+	engine := NewEngine(EngineConfig{EntropyWindowSize: 10})
+	engine.SetAnalysisCache(analyses)
+	tokenizer := newFailingTokenizer(t, "")
+	document := "the cat sat on the mat"
+
+	first, err := engine.AnalyzeDocument(context.Background(), document, tokenizer)
+	if err != nil {
+		t.Fatalf("AnalyzeDocument returned error: %v", err)
+	}
+	if _, ok := first.Metadata[CacheHitKey]; ok {
+		t.Error("an analyzed result should not be marked as a cache hit")
+	}
+	calls := tokenizer.calls
+
+	second, err := engine.AnalyzeDocument(context.Background(), document, tokenizer)
+	if err != nil {
+		t.Fatalf("AnalyzeDocument returned error: %v", err)
+	}
+	if tokenizer.calls != calls {
+		t.Error("a cached analysis tokenized the document again")
+	}
+	if hit, _ := second.Metadata[CacheHitKey].(bool); !hit {
+		t.Errorf("metadata %s = %v, want true", CacheHitKey, second.Metadata[CacheHitKey])
+	}
+	if second.TokenCount != first.TokenCount || len(second.Metrics) != len(first.Metrics) {
+		t.Errorf("got %d tokens and %d metrics, want %d and %d", second.TokenCount, len(second.Metrics), first.TokenCount, len(first.Metrics))
+	}
+
+	tests := []struct {
+		name     string
+		engine   *Engine
+		document string
+	}{
+		{"other document", engine, "the dog sat on the log"},
+		{"other settings", NewEngine(EngineConfig{EntropyWindowSize: 20}), document},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.engine.SetAnalysisCache(analyses)
+			result, err := tt.engine.AnalyzeDocument(context.Background(), tt.document, tokenizer)
+			if err != nil {
+				t.Fatalf("AnalyzeDocument returned error: %v", err)
+			}
+			if _, ok := result.Metadata[CacheHitKey]; ok {
+				t.Error("result should not have been served from the cache")
+			}
+		})
+	}
+}
+
+func TestAnalysisCacheKeepsItsOwnCopy(t *testing.T) {
+	engine := NewEngine(EngineConfig{EntropyWindowSize: 10})
+	engine.SetAnalysisCache(cache.NewCache(cache.CacheConfig{MaxSize: 10}))
+	tokenizer := newFailingTokenizer(t, "")
+	document := "the cat sat on the mat"
+
+	// Change the analyzed result and a cached one, down to their tokens
+	for i := 0; i < 2; i++ {
+		result, err := engine.AnalyzeDocument(context.Background(), document, tokenizer)
+		if err != nil {
+			t.Fatalf("AnalyzeDocument returned error: %v", err)
+		}
+		result.TokenCount = -1
+		result.Metadata["changed"] = true
+		for name := range result.Metrics {
+			delete(result.Metrics, name)
+		}
+		result.Tokenization.Tokens[0].Text = "changed"
+		result.Tokenization.Tokens = nil
+	}
+
+	result, err := engine.AnalyzeDocument(context.Background(), document, tokenizer)
+	if err != nil {
+		t.Fatalf("AnalyzeDocument returned error: %v", err)
+	}
+	if result.TokenCount <= 0 || len(result.Metrics) == 0 {
+		t.Errorf("cached result has %d tokens and %d metrics", result.TokenCount, len(result.Metrics))
+	}
+	if _, ok := result.Metadata["changed"]; ok {
+		t.Error("cached metadata was changed")
+	}
+	if tokens := result.Tokenization.Tokens; len(tokens) == 0 || tokens[0].Text == "changed" {
+		t.Errorf("cached tokens were changed: %v", tokens)
+	}
+}
+
+func TestAnalysisResultClone(t *testing.T) {
+	if (*AnalysisResult)(nil).Clone() != nil {
+		t.Error("the clone of nil should be nil")
+	}
+
+	result, err := NewEngine(EngineConfig{EntropyWindowSize: 10}).AnalyzeDocument(context.Background(), "a b a", newFailingTokenizer(t, ""))
+	if err != nil {
+		t.Fatalf("AnalyzeDocument returned error: %v", err)
+	}
+	clone := result.Clone()
+	if clone == result || clone.Tokenization == result.Tokenization {
+		t.Fatal("the clone shares pointers with the result")
+	}
+	for name, metric := range result.Metrics {
+		if clone.Metrics[name].Value != metric.Value {
+			t.Errorf("%s = %v, want %v", name, clone.Metrics[name].Value, metric.Value)
+		}
+	}
+	if _, ok := clone.Metadata["analysis_parameters"].(AnalysisParameters); !ok {
+		t.Errorf("clone lost the type of its metadata: %T", clone.Metadata["analysis_parameters"])
+	}
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// DocumentError records why one document in a batch could not be analyzed
+type DocumentError struct {
+	Index int
+	Err   error
+}
+
+// Error implements the error interface
+func (e *DocumentError) Error() string {
+	return fmt.Sprintf("document %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the underlying analysis error
+func (e *DocumentError) Unwrap() error {
+	return e.Err
+}
+
+// MarshalJSON encodes the error message alongside the document index
+func (e *DocumentError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Index int    `json:"index"`
+		Error string `json:"error"`
+	}{
+		Index: e.Index,
+		Error: e.Err.Error(),
+	})
+}
+
+// BatchResult holds the outcome of analyzing a batch of documents. A failing document
+// does not stop the batch; it is recorded in Errors and the remaining documents are
+// still analyzed.
+type BatchResult struct {
+	Results []*AnalysisResult `json:"results"`          // successful analyses, in document order
+	Errors  []*DocumentError  `json:"errors,omitempty"` // per-document failures
+	Skipped int               `json:"skipped"`          // documents not attempted because the context ended
+}
+
+// Err combines the per-document errors, or returns nil when every attempted document succeeded
+func (b *BatchResult) Err() error {
+	errs := make([]error, len(b.Errors))
+	for i, err := range b.Errors {
+		errs[i] = err
+	}
+	return errors.Join(errs...)
+}
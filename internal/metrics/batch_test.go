@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+var errTokenize = errors.New("tokenizer crashed")
+
+// failingTokenizer fails on one specific document and cancels a context after a
+// given number of successful calls
+type failingTokenizer struct {
+	*tokenizers.MockTokenizer
+	failOn      string
+	cancelAfter int
+	cancel      context.CancelFunc
+	calls       int
+}
+
+func (f *failingTokenizer) Tokenize(ctx context.Context, text string) (*tokenizers.TokenizationResult, error) {
+	f.calls++
+	if f.cancel != nil && f.calls == f.cancelAfter {
+		f.cancel()
+	}
+	if text == f.failOn {
+		return nil, errTokenize
+	}
+	return f.MockTokenizer.Tokenize(ctx, text)
+}
+
+func newFailingTokenizer(t *testing.T, failOn string) *failingTokenizer {
+	t.Helper()
+	mock := tokenizers.NewMockTokenizer("mock")
+	if err := mock.Initialize(tokenizers.TokenizerConfig{Name: "mock", Type: "custom"}); err != nil {
+		t.Fatalf("failed to initialize mock tokenizer: %v", err)
+	}
+	return &failingTokenizer{MockTokenizer: mock, failOn: failOn}
+}
+
+func TestAnalyzeBatchContinuesPastErrors(t *testing.T) {
+	engine := NewEngine(EngineConfig{EntropyWindowSize: 10})
+	tokenizer := newFailingTokenizer(t, "bad")
+
+	batch, err := engine.AnalyzeBatch(context.Background(), []string{"a b", "bad", "c d"}, tokenizer)
+	if err != nil {
+		t.Fatalf("AnalyzeBatch returned error: %v", err)
+	}
+
+	if len(batch.Results) != 2 {
+		t.Fatalf("got %d results, want 2", len(batch.Results))
+	}
+	if batch.Results[0].Document != "a b" || batch.Results[1].Document != "c d" {
+		t.Errorf("results out of order: %q, %q", batch.Results[0].Document, batch.Results[1].Document)
+	}
+	if len(batch.Errors) != 1 || batch.Errors[0].Index != 1 {
+		t.Fatalf("expected one error for document 1, got %v", batch.Errors)
+	}
+	if !errors.Is(batch.Err(), errTokenize) {
+		t.Errorf("Err() = %v, want it to wrap %v", batch.Err(), errTokenize)
+	}
+	if batch.Skipped != 0 {
+		t.Errorf("Skipped = %d, want 0", batch.Skipped)
+	}
+
+	encoded, err := json.Marshal(batch.Errors[0])
+	if err != nil {
+		t.Fatalf("failed to marshal document error: %v", err)
+	}
+	var decoded struct {
+		Index int    `json:"index"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("failed to unmarshal document error: %v", err)
+	}
+	if decoded.Index != 1 || decoded.Error != batch.Errors[0].Err.Error() {
+		t.Errorf("unexpected JSON for document error: %s", encoded)
+	}
+}
+
+func TestAnalyzeBatchStopsOnCancellation(t *testing.T) {
+	engine := NewEngine(EngineConfig{EntropyWindowSize: 10})
+	tokenizer := newFailingTokenizer(t, "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tokenizer.cancel = cancel
+	tokenizer.cancelAfter = 2
+
+	batch, err := engine.AnalyzeBatch(ctx, []string{"a", "b", "c", "d", "e"}, tokenizer)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("AnalyzeBatch error = %v, want context.Canceled", err)
+	}
+	if batch == nil {
+		t.Fatal("expected partial results alongside the cancellation error")
+	}
+	if len(batch.Results) != 2 {
+		t.Errorf("got %d results, want 2", len(batch.Results))
+	}
+	if batch.Skipped != 3 {
+		t.Errorf("Skipped = %d, want 3", batch.Skipped)
+	}
+	if batch.Err() != nil {
+		t.Errorf("Err() = %v, want nil when no document failed", batch.Err())
+	}
+}
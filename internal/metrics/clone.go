@@ -0,0 +1,70 @@
+package metrics
+
+import "reflect"
+
+// Clone returns a deep copy of the result, so that changing either copy, down to the
+// metadata of its metrics and tokens, leaves the other as it was
+func (r *AnalysisResult) Clone() *AnalysisResult {
+	if r == nil {
+		return nil
+	}
+	return deepCopy(reflect.ValueOf(r)).Interface().(*AnalysisResult)
+}
+
+// deepCopy copies v, following pointers, interfaces, maps and slices. Unexported
+// struct fields, such as those of time.Time, are copied as they are. v must not
+// contain cycles, which results never do.
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.New(v.Elem().Type())
+		copied.Elem().Set(deepCopy(v.Elem()))
+		return copied
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(deepCopy(v.Elem()))
+		return copied
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return copied
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return copied
+	case reflect.Array:
+		copied := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return copied
+	case reflect.Struct:
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if field := copied.Field(i); field.CanSet() {
+				field.Set(deepCopy(v.Field(i)))
+			}
+		}
+		return copied
+	default:
+		return v
+	}
+}
@@ -0,0 +1,89 @@
+package metrics
+
+// TokenizerPair holds the drift statistics between two tokenizers. A is treated as
+// the reference for asymmetric metrics such as KL divergence and boundary precision.
+type TokenizerPair struct {
+	A        string                 `json:"a"`
+	B        string                 `json:"b"`
+	Drift    map[string]float64     `json:"drift"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+
+	// Vocabulary compares the tokenizers' full vocabularies; set by
+	// Engine.AddVocabularyComparisons
+	Vocabulary *VocabularyComparison `json:"vocabulary,omitempty"`
+}
+
+// Name returns the legacy "A_vs_B" key for the pair
+func (p TokenizerPair) Name() string {
+	return p.A + "_vs_" + p.B
+}
+
+// ComparisonResult represents the outcome of comparing several tokenizers on a document
+type ComparisonResult struct {
+	Tokenizers []string          `json:"tokenizers"`
+	Pairs      []TokenizerPair   `json:"pairs"`
+	Results    []*AnalysisResult `json:"results"`
+}
+
+// Pair returns the comparison between two tokenizers in either order, or nil if absent
+func (c *ComparisonResult) Pair(a, b string) *TokenizerPair {
+	for i := range c.Pairs {
+		pair := &c.Pairs[i]
+		if (pair.A == a && pair.B == b) || (pair.A == b && pair.B == a) {
+			return pair
+		}
+	}
+	return nil
+}
+
+// PairwiseMatrix builds a square matrix of a drift metric indexed by Tokenizers.
+// Each pair is computed once, so the value is mirrored across the diagonal; the
+// diagonal itself is left at zero.
+func (c *ComparisonResult) PairwiseMatrix(metric string) [][]float64 {
+	index := make(map[string]int, len(c.Tokenizers))
+	for i, name := range c.Tokenizers {
+		index[name] = i
+	}
+
+	matrix := make([][]float64, len(c.Tokenizers))
+	for i := range matrix {
+		matrix[i] = make([]float64, len(c.Tokenizers))
+	}
+
+	for _, pair := range c.Pairs {
+		i, okA := index[pair.A]
+		j, okB := index[pair.B]
+		if !okA || !okB {
+			continue
+		}
+
+		if value, exists := pair.Drift[metric]; exists {
+			matrix[i][j] = value
+			matrix[j][i] = value
+		}
+	}
+
+	return matrix
+}
+
+// ToMap converts the result into the untyped layout previously returned by
+// CompareTokenizers, keyed by "A_vs_B" pair names
+func (c *ComparisonResult) ToMap() map[string]interface{} {
+	comparison := make(map[string]interface{})
+	notes := make(map[string]interface{})
+
+	for _, pair := range c.Pairs {
+		comparison[pair.Name()] = pair.Drift
+		if len(pair.Metadata) > 0 {
+			notes[pair.Name()] = pair.Metadata
+		}
+	}
+
+	if len(notes) > 0 {
+		comparison["metadata"] = notes
+	}
+
+	comparison["individual_results"] = c.Results
+
+	return comparison
+}
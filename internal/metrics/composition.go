@@ -0,0 +1,136 @@
+package metrics
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// spaceMarkers are the leading characters tokenizers use in token text to encode
+// whitespace: a literal space, GPT-2's byte-level space, newline and tab, and the
+// SentencePiece word boundary
+var spaceMarkers = []string{" ", "Ġ", "Ċ", "ĉ", "▁"}
+
+// CompositionCalculator classifies tokens by their content to show how tokenizers
+// handle whitespace and punctuation
+type CompositionCalculator struct{}
+
+// NewCompositionCalculator creates a new composition calculator
+func NewCompositionCalculator() *CompositionCalculator {
+	return &CompositionCalculator{}
+}
+
+// CalculateCompositionStats reports the share of word-like, punctuation,
+// whitespace-bearing and mixed tokens. When the tokens carry offsets it also counts
+// tokens whose span in the document covers both whitespace and other characters.
+func (c *CompositionCalculator) CalculateCompositionStats(document string, tokens []tokenizers.Token) (map[string]float64, error) {
+	metrics := make(map[string]float64)
+
+	if len(tokens) == 0 {
+		return metrics, nil
+	}
+
+	counts := make(map[string]int)
+	for _, token := range tokens {
+		counts[classifyToken(token.Text)]++
+	}
+
+	total := float64(len(tokens))
+	metrics["word_ratio"] = float64(counts["word"]) / total
+	metrics["punctuation_ratio"] = float64(counts["punctuation"]) / total
+	metrics["whitespace_bearing_ratio"] = float64(counts["whitespace"]) / total
+	metrics["mixed_ratio"] = float64(counts["mixed"]) / total
+
+	if hasTokenOffsets(tokens) {
+		spanning := 0
+		for _, token := range tokens {
+			if token.StartPos < 0 || token.EndPos > len(document) || token.StartPos >= token.EndPos {
+				continue
+			}
+			if spansWhitespaceBoundary(document[token.StartPos:token.EndPos]) {
+				spanning++
+			}
+		}
+		metrics["boundary_spanning_count"] = float64(spanning)
+		metrics["boundary_spanning_ratio"] = float64(spanning) / total
+	}
+
+	return metrics, nil
+}
+
+// classifyToken returns "whitespace" for tokens with leading or trailing whitespace
+// (including space markers), otherwise "word", "punctuation" or "mixed" depending on
+// whether the remaining runes are all letters and digits, all punctuation and
+// symbols, or a combination
+func classifyToken(text string) string {
+	if text == "" {
+		return "mixed"
+	}
+
+	for _, marker := range spaceMarkers {
+		if strings.HasPrefix(text, marker) {
+			return "whitespace"
+		}
+	}
+
+	runes := []rune(text)
+	if unicode.IsSpace(runes[0]) || unicode.IsSpace(runes[len(runes)-1]) {
+		return "whitespace"
+	}
+
+	// WordPiece continuation pieces are still word-like
+	core := strings.TrimPrefix(text, "##")
+	if core == "" {
+		core = text
+	}
+
+	wordLike, punctuation := 0, 0
+	for _, r := range core {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+			wordLike++
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			punctuation++
+		}
+	}
+
+	total := len([]rune(core))
+	switch {
+	case wordLike == total:
+		return "word"
+	case punctuation == total:
+		return "punctuation"
+	default:
+		return "mixed"
+	}
+}
+
+// spansWhitespaceBoundary reports whether text contains both whitespace and
+// non-whitespace runes
+func spansWhitespaceBoundary(text string) bool {
+	hasSpace, hasOther := false, false
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			hasSpace = true
+		} else {
+			hasOther = true
+		}
+	}
+	return hasSpace && hasOther
+}
+
+// stripSpaceMarker removes one leading space or space marker from token text so that
+// " the", "Ġthe" and "▁the" compare equal to "the". Tokens consisting only of the
+// marker are left unchanged.
+func stripSpaceMarker(text string) string {
+	for _, marker := range spaceMarkers {
+		if rest := strings.TrimPrefix(text, marker); rest != text {
+			if rest == "" {
+				return text
+			}
+			return rest
+		}
+	}
+	return text
+}
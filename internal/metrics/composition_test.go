@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestClassifyToken(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "hello", want: "word"},
+		{text: "42", want: "word"},
+		{text: "##ing", want: "word"},
+		{text: "дом", want: "word"},
+		{text: ",", want: "punctuation"},
+		{text: "...", want: "punctuation"},
+		{text: "$", want: "punctuation"},
+		{text: " the", want: "whitespace"},
+		{text: "Ġthe", want: "whitespace"},
+		{text: "▁the", want: "whitespace"},
+		{text: "the ", want: "whitespace"},
+		{text: "\n", want: "whitespace"},
+		{text: "don't", want: "mixed"},
+		{text: "a.b", want: "mixed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := classifyToken(tt.text); got != tt.want {
+				t.Errorf("classifyToken(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompositionStats(t *testing.T) {
+	calc := NewCompositionCalculator()
+
+	// GPT-2 style: the space before "cat" is part of its token
+	document := "Hi, cat"
+	tokens := tokenizationFromSpans(document, [2]int{0, 2}, [2]int{2, 3}, [2]int{3, 7}).Tokens
+
+	stats, err := calc.CalculateCompositionStats(document, tokens)
+	if err != nil {
+		t.Fatalf("CalculateCompositionStats returned error: %v", err)
+	}
+
+	for key, want := range map[string]float64{
+		"word_ratio":               1.0 / 3.0,
+		"punctuation_ratio":        1.0 / 3.0,
+		"whitespace_bearing_ratio": 1.0 / 3.0,
+		"mixed_ratio":              0,
+		"boundary_spanning_count":  1,
+		"boundary_spanning_ratio":  1.0 / 3.0,
+	} {
+		if math.Abs(stats[key]-want) > floatTolerance {
+			t.Errorf("%s = %v, want %v", key, stats[key], want)
+		}
+	}
+
+	// Without offsets only the proportions are reported
+	stats, err = calc.CalculateCompositionStats(document, tokenizationFromTexts("Hi", ",", " cat").Tokens)
+	if err != nil {
+		t.Fatalf("CalculateCompositionStats returned error: %v", err)
+	}
+	if _, ok := stats["boundary_spanning_count"]; ok {
+		t.Errorf("expected boundary_spanning_count to be skipped without offsets")
+	}
+}
+
+func TestStripSpaceMarkersInDrift(t *testing.T) {
+	gpt2 := tokenizationFromTexts("Ġthe", "Ġcat", "Ġsat")
+	gpt2.Tokenizer = "gpt2"
+	plain := tokenizationFromTexts("the", "cat", "sat")
+	plain.Tokenizer = "bert"
+
+	calc := NewDriftCalculator(0.5)
+	metrics, err := calc.CalculateCrossTokenizerDrift(gpt2, plain)
+	if err != nil {
+		t.Fatalf("CalculateCrossTokenizerDrift returned error: %v", err)
+	}
+	if metrics["jaccard_distance"] != 1 {
+		t.Errorf("jaccard_distance = %v without normalization, want 1", metrics["jaccard_distance"])
+	}
+
+	calc.SetStripSpaceMarkers("gpt2")
+	metrics, err = calc.CalculateCrossTokenizerDrift(gpt2, plain)
+	if err != nil {
+		t.Fatalf("CalculateCrossTokenizerDrift returned error: %v", err)
+	}
+	if metrics["jaccard_distance"] != 0 {
+		t.Errorf("jaccard_distance = %v with normalization, want 0", metrics["jaccard_distance"])
+	}
+
+	// The original tokenization is left untouched
+	if gpt2.Tokens[0].Text != "Ġthe" {
+		t.Errorf("token text modified to %q", gpt2.Tokens[0].Text)
+	}
+}
+
+func TestStripSpaceMarker(t *testing.T) {
+	for text, want := range map[string]string{
+		"Ġthe": "the",
+		"▁the": "the",
+		" the": "the",
+		"the":  "the",
+		"Ġ":    "Ġ",
+		"ĠĠx":  "Ġx",
+	} {
+		if got := stripSpaceMarker(text); got != want {
+			t.Errorf("stripSpaceMarker(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
@@ -1,14 +1,20 @@
 package metrics
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
 	"math"
 
+	"github.com/RevBooyah/TokEntropyDrift/internal/stats"
 	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
 )
 
 // CompressionCalculator handles various compression metrics
 type CompressionCalculator struct {
 	includeMetadata bool
+	byteCounts      bool
 }
 
 // NewCompressionCalculator creates a new compression calculator
@@ -18,6 +24,12 @@ func NewCompressionCalculator(includeMetadata bool) *CompressionCalculator {
 	}
 }
 
+// SetByteCounts selects whether character counts use bytes instead of runes.
+// Storage sizes such as the original text size are always measured in bytes.
+func (c *CompressionCalculator) SetByteCounts(byteCounts bool) {
+	c.byteCounts = byteCounts
+}
+
 // CalculateCompressionRatio calculates the basic compression ratio
 func (c *CompressionCalculator) CalculateCompressionRatio(originalText string, tokens []tokenizers.Token) (float64, error) {
 	if len(originalText) == 0 {
@@ -72,12 +84,25 @@ func (c *CompressionCalculator) CalculateByteLevelCompression(originalText strin
 	// Token density (tokens per byte)
 	metrics["token_density"] = float64(len(tokens)) / float64(originalSize)
 
-	// Character density (characters per token)
-	totalChars := 0
+	// Character density (characters per token), in both bytes and runes
+	totalBytes := 0
+	totalRunes := 0
 	for _, token := range tokens {
-		totalChars += len(token.Text)
+		totalBytes += textLength(token.Text, true)
+		totalRunes += textLength(token.Text, false)
+	}
+	metrics["char_density_bytes"] = float64(totalBytes) / float64(len(tokens))
+	metrics["char_density_runes"] = float64(totalRunes) / float64(len(tokens))
+	if c.byteCounts {
+		metrics["char_density"] = metrics["char_density_bytes"]
+	} else {
+		metrics["char_density"] = metrics["char_density_runes"]
+	}
+
+	// Tokens per character of the original text
+	if originalRunes := textLength(originalText, false); originalRunes > 0 {
+		metrics["token_density_runes"] = float64(len(tokens)) / float64(originalRunes)
 	}
-	metrics["char_density"] = float64(totalChars) / float64(len(tokens))
 
 	return metrics, nil
 }
@@ -94,10 +119,12 @@ func (c *CompressionCalculator) CalculateTokenLevelCompression(tokens []tokenize
 	tokenLengths := make([]int, len(tokens))
 	totalLength := 0
 
+	totalBytes := 0
 	for i, token := range tokens {
-		length := len(token.Text)
+		length := textLength(token.Text, c.byteCounts)
 		tokenLengths[i] = length
 		totalLength += length
+		totalBytes += len(token.Text)
 	}
 
 	// Average token length
@@ -113,12 +140,15 @@ func (c *CompressionCalculator) CalculateTokenLevelCompression(tokens []tokenize
 	metrics["token_length_std"] = math.Sqrt(metrics["token_length_variance"])
 
 	// Token length distribution
-	metrics["min_token_length"] = float64(calculateMinInt(tokenLengths))
-	metrics["max_token_length"] = float64(calculateMaxInt(tokenLengths))
+	metrics["min_token_length"] = float64(stats.Min(tokenLengths))
+	metrics["max_token_length"] = float64(stats.Max(tokenLengths))
 
 	// Token efficiency (characters per token)
 	metrics["token_efficiency"] = float64(totalLength) / float64(len(tokens))
 
+	// Byte length regardless of the configured counting
+	metrics["avg_token_length_bytes"] = float64(totalBytes) / float64(len(tokens))
+
 	return metrics, nil
 }
 
@@ -165,6 +195,68 @@ func (c *CompressionCalculator) CalculateRedundancyFactor(tokens []tokenizers.To
 	return metrics, nil
 }
 
+// CalculateGzipBaseline measures how well a general-purpose compressor handles the text,
+// giving a baseline for the intrinsic compressibility of the document
+func (c *CompressionCalculator) CalculateGzipBaseline(originalText string) (map[string]float64, error) {
+	metrics := make(map[string]float64)
+
+	if len(originalText) == 0 {
+		return metrics, nil
+	}
+
+	var buf bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gzip writer: %w", err)
+	}
+
+	if _, err := writer.Write([]byte(originalText)); err != nil {
+		return nil, fmt.Errorf("failed to gzip text: %w", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish gzip stream: %w", err)
+	}
+
+	metrics["gzip_size"] = float64(buf.Len())
+	metrics["gzip_ratio"] = float64(buf.Len()) / float64(len(originalText))
+
+	return metrics, nil
+}
+
+// CalculateEncodedTokenSize estimates the size of the token stream under realistic
+// encodings: varint-encoded token IDs, and the lower bound achievable by an optimal
+// entropy coder (token count × global entropy in bits / 8)
+func (c *CompressionCalculator) CalculateEncodedTokenSize(originalText string, tokens []tokenizers.Token, entropy float64) (map[string]float64, error) {
+	metrics := make(map[string]float64)
+
+	if len(tokens) == 0 {
+		return metrics, nil
+	}
+
+	varintSize := 0
+	buf := make([]byte, binary.MaxVarintLen64)
+	for _, token := range tokens {
+		if token.ID >= 0 {
+			varintSize += binary.PutUvarint(buf, uint64(token.ID))
+		} else {
+			varintSize += binary.PutVarint(buf, int64(token.ID))
+		}
+	}
+
+	entropyBoundSize := float64(len(tokens)) * entropy / 8
+
+	metrics["varint_size"] = float64(varintSize)
+	metrics["entropy_bound_size"] = entropyBoundSize
+
+	if originalSize := len(originalText); originalSize > 0 {
+		metrics["varint_ratio"] = float64(varintSize) / float64(originalSize)
+		metrics["entropy_bound_ratio"] = entropyBoundSize / float64(originalSize)
+	}
+
+	return metrics, nil
+}
+
 // CalculateCompressionStats calculates comprehensive compression statistics
 func (c *CompressionCalculator) CalculateCompressionStats(originalText string, tokens []tokenizers.Token, entropy float64) (map[string]float64, error) {
 	stats := make(map[string]float64)
@@ -188,39 +280,29 @@ func (c *CompressionCalculator) CalculateCompressionStats(originalText string, t
 		}
 	}
 
-	// Redundancy factor
-	if redundancyStats, err := c.CalculateRedundancyFactor(tokens, entropy); err == nil {
-		for k, v := range redundancyStats {
-			stats["redundancy_"+k] = v
+	// Gzip baseline, compared against the fixed-width token representation
+	if gzipStats, err := c.CalculateGzipBaseline(originalText); err == nil {
+		for k, v := range gzipStats {
+			stats[k] = v
+		}
+		if gzipSize := gzipStats["gzip_size"]; gzipSize > 0 {
+			stats["tokenization_vs_gzip"] = float64(len(tokens)*4) / gzipSize
 		}
 	}
 
-	return stats, nil
-}
-
-// Helper functions for integer statistics
-func calculateMinInt(values []int) int {
-	if len(values) == 0 {
-		return 0
-	}
-	min := values[0]
-	for _, v := range values {
-		if v < min {
-			min = v
+	// Encoding-aware token size estimates
+	if encodingStats, err := c.CalculateEncodedTokenSize(originalText, tokens, entropy); err == nil {
+		for k, v := range encodingStats {
+			stats["encoding_"+k] = v
 		}
 	}
-	return min
-}
 
-func calculateMaxInt(values []int) int {
-	if len(values) == 0 {
-		return 0
-	}
-	max := values[0]
-	for _, v := range values {
-		if v > max {
-			max = v
+	// Redundancy factor
+	if redundancyStats, err := c.CalculateRedundancyFactor(tokens, entropy); err == nil {
+		for k, v := range redundancyStats {
+			stats["redundancy_"+k] = v
 		}
 	}
-	return max
+
+	return stats, nil
 }
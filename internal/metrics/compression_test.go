@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+func TestGzipBaseline(t *testing.T) {
+	calc := NewCompressionCalculator(false)
+
+	metrics, err := calc.CalculateGzipBaseline(strings.Repeat("the quick brown fox ", 50))
+	if err != nil {
+		t.Fatalf("CalculateGzipBaseline returned error: %v", err)
+	}
+	if ratio := metrics["gzip_ratio"]; ratio <= 0 || ratio >= 0.2 {
+		t.Errorf("gzip_ratio for repetitive text = %v, want within (0, 0.2)", ratio)
+	}
+
+	empty, err := calc.CalculateGzipBaseline("")
+	if err != nil {
+		t.Fatalf("CalculateGzipBaseline returned error: %v", err)
+	}
+	if len(empty) != 0 {
+		t.Errorf("expected no metrics for empty text, got %v", empty)
+	}
+}
+
+func TestEncodedTokenSize(t *testing.T) {
+	calc := NewCompressionCalculator(false)
+
+	// IDs 0..127 take one varint byte, 128..16383 take two
+	tokens := []tokenizers.Token{
+		{Text: "a", ID: 5},
+		{Text: "b", ID: 127},
+		{Text: "c", ID: 128},
+		{Text: "d", ID: 16383},
+	}
+
+	metrics, err := calc.CalculateEncodedTokenSize("abcdefgh", tokens, 2.0)
+	if err != nil {
+		t.Fatalf("CalculateEncodedTokenSize returned error: %v", err)
+	}
+
+	want := map[string]float64{
+		"varint_size":         6,
+		"varint_ratio":        0.75,
+		"entropy_bound_size":  1,
+		"entropy_bound_ratio": 0.125,
+	}
+	for key, value := range want {
+		if math.Abs(metrics[key]-value) > floatTolerance {
+			t.Errorf("%s = %v, want %v", key, metrics[key], value)
+		}
+	}
+}
+
+func TestCharacterCountsOnMixedScripts(t *testing.T) {
+	// "日本語" is 3 runes / 9 bytes, "мир" 3 runes / 6 bytes, "🙂" 1 rune / 4 bytes
+	texts := []string{"日本語", "мир", "🙂", "ok"}
+	tokens := tokenizationFromTexts(texts...).Tokens
+	document := strings.Join(texts, "")
+
+	tests := []struct {
+		name        string
+		byteCounts  bool
+		wantDensity float64
+	}{
+		{name: "runes", byteCounts: false, wantDensity: 9.0 / 4.0},
+		{name: "bytes", byteCounts: true, wantDensity: 21.0 / 4.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calc := NewCompressionCalculator(false)
+			calc.SetByteCounts(tt.byteCounts)
+
+			metrics, err := calc.CalculateByteLevelCompression(document, tokens)
+			if err != nil {
+				t.Fatalf("CalculateByteLevelCompression returned error: %v", err)
+			}
+
+			if got := metrics["char_density"]; math.Abs(got-tt.wantDensity) > floatTolerance {
+				t.Errorf("char_density = %v, want %v", got, tt.wantDensity)
+			}
+			if got := metrics["char_density_runes"]; math.Abs(got-9.0/4.0) > floatTolerance {
+				t.Errorf("char_density_runes = %v, want %v", got, 9.0/4.0)
+			}
+			if got := metrics["char_density_bytes"]; math.Abs(got-21.0/4.0) > floatTolerance {
+				t.Errorf("char_density_bytes = %v, want %v", got, 21.0/4.0)
+			}
+			if got := metrics["token_density_runes"]; math.Abs(got-4.0/9.0) > floatTolerance {
+				t.Errorf("token_density_runes = %v, want %v", got, 4.0/9.0)
+			}
+
+			levelStats, err := calc.CalculateTokenLevelCompression(tokens)
+			if err != nil {
+				t.Fatalf("CalculateTokenLevelCompression returned error: %v", err)
+			}
+			if got := levelStats["avg_token_length"]; math.Abs(got-tt.wantDensity) > floatTolerance {
+				t.Errorf("avg_token_length = %v, want %v", got, tt.wantDensity)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/loader"
+	"github.com/RevBooyah/TokEntropyDrift/internal/stats"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// MetricDistribution summarizes how a metric is distributed across documents
+type MetricDistribution struct {
+	Count int     `json:"count"`
+	Mean  float64 `json:"mean"`
+	Std   float64 `json:"std"`
+	Min   float64 `json:"min"`
+	P25   float64 `json:"p25"`
+	P50   float64 `json:"p50"`
+	P75   float64 `json:"p75"`
+	P90   float64 `json:"p90"`
+	P95   float64 `json:"p95"`
+	Max   float64 `json:"max"`
+}
+
+// CorpusAnalysisResult holds per-document results together with corpus-level
+// distributions. DocumentCount, TotalTokens and the distributions cover the documents
+// analyzed successfully.
+type CorpusAnalysisResult struct {
+	TokenizerName string                        `json:"tokenizer_name"`
+	DocumentCount int                           `json:"document_count"`
+	TotalTokens   int                           `json:"total_tokens"`
+	Documents     []*AnalysisResult             `json:"documents"`
+	Distributions map[string]MetricDistribution `json:"distributions"`
+	FailedCount   int                           `json:"failed_count"`     // documents whose analysis failed
+	Errors        []*DocumentError              `json:"errors,omitempty"` // per-document failures
+	Skipped       int                           `json:"skipped"`          // documents not attempted because the context ended
+}
+
+// Err combines the per-document errors, or returns nil when every attempted document succeeded
+func (c *CorpusAnalysisResult) Err() error {
+	errs := make([]error, len(c.Errors))
+	for i, err := range c.Errors {
+		errs[i] = err
+	}
+	return errors.Join(errs...)
+}
+
+// AnalyzeDocuments analyzes each loaded document separately and aggregates the
+// per-document metrics into corpus-level distributions. Documents that fail are
+// recorded in the result's Errors and left out of the aggregates. If the context
+// ends, the documents analyzed so far are aggregated and returned together with the
+// context's error, and the rest are counted as Skipped.
+func (e *Engine) AnalyzeDocuments(ctx context.Context, docs []loader.Document, tokenizer tokenizers.Tokenizer) (*CorpusAnalysisResult, error) {
+	corpus := &CorpusAnalysisResult{
+		TokenizerName: tokenizer.Name(),
+		Documents:     make([]*AnalysisResult, 0, len(docs)),
+		Distributions: make(map[string]MetricDistribution),
+	}
+
+	var ctxErr error
+	for i, doc := range docs {
+		select {
+		case <-ctx.Done():
+			ctxErr = ctx.Err()
+			corpus.Skipped = len(docs) - i
+		default:
+		}
+		if ctxErr != nil {
+			break
+		}
+
+		result, err := e.AnalyzeDocument(ctx, doc.Content, tokenizer)
+		if err != nil {
+			corpus.Errors = append(corpus.Errors, &DocumentError{Index: i, Err: fmt.Errorf("%s: %w", documentLabel(doc, i), err)})
+			continue
+		}
+
+		result.DocumentID = documentLabel(doc, i)
+		corpus.Documents = append(corpus.Documents, result)
+		corpus.TotalTokens += result.TokenCount
+	}
+
+	corpus.DocumentCount = len(corpus.Documents)
+	corpus.FailedCount = len(corpus.Errors)
+
+	// Collect every metric value across documents
+	values := make(map[string][]float64)
+	for _, result := range corpus.Documents {
+		for name, metric := range result.Metrics {
+			values[name] = append(values[name], metric.Value)
+		}
+	}
+
+	for name, metricValues := range values {
+		corpus.Distributions[name] = calculateDistribution(metricValues)
+	}
+
+	return corpus, ctxErr
+}
+
+// documentLabel builds a short, stable label identifying a loaded document by its
+// line, or its range of lines when it spans several
+func documentLabel(doc loader.Document, index int) string {
+	lines := fmt.Sprint(doc.StartLine)
+	switch {
+	case doc.StartLine == 0:
+		lines = fmt.Sprint(index + 1)
+	case doc.EndLine > doc.StartLine:
+		lines = fmt.Sprintf("%d-%d", doc.StartLine, doc.EndLine)
+	}
+
+	if doc.FilePath == "" {
+		return fmt.Sprintf("line %s", lines)
+	}
+
+	return fmt.Sprintf("%s:%s", filepath.Base(doc.FilePath), lines)
+}
+
+// calculateDistribution computes summary statistics and percentiles for a set of values
+func calculateDistribution(values []float64) MetricDistribution {
+	if len(values) == 0 {
+		return MetricDistribution{}
+	}
+
+	percentiles := stats.Percentiles(values, 25, 50, 75, 90, 95)
+
+	return MetricDistribution{
+		Count: len(values),
+		Mean:  stats.Mean(values),
+		Std:   stats.Std(values),
+		Min:   stats.Min(values),
+		P25:   percentiles[0],
+		P50:   percentiles[1],
+		P75:   percentiles[2],
+		P90:   percentiles[3],
+		P95:   percentiles[4],
+		Max:   stats.Max(values),
+	}
+}
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/stats"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// Series names reported by CalculateCorpusDrift
+const (
+	SeriesTokenCountDelta = "token_count_delta"
+	SeriesEntropyDelta    = "entropy_delta"
+	SeriesJaccard         = "jaccard_distance"
+	SeriesAlignment       = "alignment_score"
+)
+
+// CorpusDriftResult holds per-document drift series between two tokenizers and their
+// corpus-level summaries. Deltas are computed as tokenizer B minus tokenizer A.
+type CorpusDriftResult struct {
+	TokenizerA string               `json:"tokenizer_a"`
+	TokenizerB string               `json:"tokenizer_b"`
+	Documents  []string             `json:"documents"`
+	Series     map[string][]float64 `json:"series"`
+	Means      map[string]float64   `json:"means"`
+	Stds       map[string]float64   `json:"stds"`
+}
+
+// CalculateCorpusDrift tokenizes every document with both tokenizers and collects the
+// drift between them as per-document series
+func (d *DriftCalculator) CalculateCorpusDrift(ctx context.Context, docs []string, tokA, tokB tokenizers.Tokenizer) (*CorpusDriftResult, error) {
+	if tokA == nil || tokB == nil {
+		return nil, fmt.Errorf("both tokenizers must be provided")
+	}
+
+	result := &CorpusDriftResult{
+		TokenizerA: tokA.Name(),
+		TokenizerB: tokB.Name(),
+		Documents:  make([]string, 0, len(docs)),
+		Series:     make(map[string][]float64),
+		Means:      make(map[string]float64),
+		Stds:       make(map[string]float64),
+	}
+
+	seriesNames := []string{SeriesTokenCountDelta, SeriesEntropyDelta, SeriesJaccard, SeriesAlignment}
+	for _, name := range seriesNames {
+		result.Series[name] = make([]float64, 0, len(docs))
+	}
+
+	// Entropy deltas are compared in bits, independent of engine normalization
+	entropyCalc := NewEntropyCalculator(0, false)
+
+	for i, doc := range docs {
+		tokenizationA, err := tokA.Tokenize(ctx, doc)
+		if err != nil {
+			return nil, fmt.Errorf("error tokenizing document %d with %s: %w", i+1, tokA.Name(), err)
+		}
+
+		tokenizationB, err := tokB.Tokenize(ctx, doc)
+		if err != nil {
+			return nil, fmt.Errorf("error tokenizing document %d with %s: %w", i+1, tokB.Name(), err)
+		}
+
+		entropyA, _ := entropyCalc.CalculateGlobalEntropy(tokenizationA.Tokens)
+		entropyB, _ := entropyCalc.CalculateGlobalEntropy(tokenizationB.Tokens)
+
+		// Text comparisons use space-marker-normalized tokens when configured
+		tokensA := d.comparisonTokens(tokA.Name(), tokenizationA.Tokens)
+		tokensB := d.comparisonTokens(tokB.Name(), tokenizationB.Tokens)
+
+		jaccard, err := d.CalculateJaccardDistance(tokensA, tokensB)
+		if err != nil {
+			return nil, fmt.Errorf("error calculating jaccard distance for document %d: %w", i+1, err)
+		}
+
+		texts1 := make([]string, len(tokensA))
+		for j, token := range tokensA {
+			texts1[j] = token.Text
+		}
+		texts2 := make([]string, len(tokensB))
+		for j, token := range tokensB {
+			texts2[j] = token.Text
+		}
+
+		alignment, _ := d.calculateAlignmentScore(texts1, texts2)
+
+		result.Documents = append(result.Documents, fmt.Sprintf("doc %d", i+1))
+		result.Series[SeriesTokenCountDelta] = append(result.Series[SeriesTokenCountDelta], float64(len(tokenizationB.Tokens)-len(tokenizationA.Tokens)))
+		result.Series[SeriesEntropyDelta] = append(result.Series[SeriesEntropyDelta], entropyB-entropyA)
+		result.Series[SeriesJaccard] = append(result.Series[SeriesJaccard], jaccard)
+		result.Series[SeriesAlignment] = append(result.Series[SeriesAlignment], alignment)
+	}
+
+	for _, name := range seriesNames {
+		result.Means[name] = stats.Mean(result.Series[name])
+		result.Stds[name] = stats.Std(result.Series[name])
+	}
+
+	return result, nil
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+func TestCalculateCorpusDrift(t *testing.T) {
+	tokA := tokenizers.NewMockTokenizer("a")
+	tokB := tokenizers.NewMockTokenizer("b")
+	for _, tok := range []*tokenizers.MockTokenizer{tokA, tokB} {
+		if err := tok.Initialize(tokenizers.TokenizerConfig{Name: tok.Name(), Type: "custom"}); err != nil {
+			t.Fatalf("failed to initialize mock tokenizer: %v", err)
+		}
+	}
+
+	docs := []string{"one two", "three four five", ""}
+
+	result, err := NewDriftCalculator(0.5).CalculateCorpusDrift(context.Background(), docs, tokA, tokB)
+	if err != nil {
+		t.Fatalf("CalculateCorpusDrift returned error: %v", err)
+	}
+
+	if len(result.Documents) != len(docs) {
+		t.Fatalf("got %d documents, want %d", len(result.Documents), len(docs))
+	}
+
+	for _, name := range []string{SeriesTokenCountDelta, SeriesEntropyDelta, SeriesJaccard, SeriesAlignment} {
+		series := result.Series[name]
+		if len(series) != len(docs) {
+			t.Errorf("series %s has %d values, want %d", name, len(series), len(docs))
+		}
+		if _, ok := result.Means[name]; !ok {
+			t.Errorf("missing mean for %s", name)
+		}
+	}
+
+	// Identical tokenizers produce no drift on non-empty documents
+	for i := 0; i < 2; i++ {
+		if v := result.Series[SeriesJaccard][i]; v != 0 {
+			t.Errorf("jaccard distance for document %d = %v, want 0", i, v)
+		}
+		if v := result.Series[SeriesAlignment][i]; math.Abs(v-1) > floatTolerance {
+			t.Errorf("alignment score for document %d = %v, want 1", i, v)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/loader"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+func TestAnalyzeDocuments(t *testing.T) {
+	tokenizer := tokenizers.NewMockTokenizer("mock")
+	if err := tokenizer.Initialize(tokenizers.TokenizerConfig{Name: "mock", Type: "custom"}); err != nil {
+		t.Fatalf("failed to initialize mock tokenizer: %v", err)
+	}
+
+	docs := []loader.Document{
+		{Content: "one", StartLine: 1, EndLine: 1, FilePath: "data/corpus.txt"},
+		{Content: "one two", StartLine: 2, EndLine: 2, FilePath: "data/corpus.txt"},
+		{Content: "one two three", StartLine: 3, EndLine: 3, FilePath: "data/corpus.txt"},
+		{Content: "one two three four", StartLine: 4, EndLine: 6, FilePath: "data/corpus.txt"},
+	}
+
+	engine := NewEngine(EngineConfig{EntropyWindowSize: 10})
+	corpus, err := engine.AnalyzeDocuments(context.Background(), docs, tokenizer)
+	if err != nil {
+		t.Fatalf("AnalyzeDocuments returned error: %v", err)
+	}
+
+	if corpus.DocumentCount != len(docs) || len(corpus.Documents) != len(docs) {
+		t.Fatalf("got %d documents, want %d", len(corpus.Documents), len(docs))
+	}
+	if corpus.TotalTokens != 10 {
+		t.Errorf("TotalTokens = %d, want 10", corpus.TotalTokens)
+	}
+
+	for i, want := range []string{"corpus.txt:1", "corpus.txt:2", "corpus.txt:3", "corpus.txt:4-6"} {
+		if got := corpus.Documents[i].DocumentID; got != want {
+			t.Errorf("document %d label = %q, want %q", i, got, want)
+		}
+	}
+
+	dist, ok := corpus.Distributions["token_count"]
+	if !ok {
+		t.Fatal("expected token_count distribution")
+	}
+
+	want := MetricDistribution{Count: 4, Mean: 2.5, Min: 1, P25: 1.75, P50: 2.5, P75: 3.25, P90: 3.7, P95: 3.85, Max: 4}
+	checks := map[string][2]float64{
+		"mean": {dist.Mean, want.Mean},
+		"min":  {dist.Min, want.Min},
+		"p25":  {dist.P25, want.P25},
+		"p50":  {dist.P50, want.P50},
+		"p75":  {dist.P75, want.P75},
+		"p90":  {dist.P90, want.P90},
+		"p95":  {dist.P95, want.P95},
+		"max":  {dist.Max, want.Max},
+		"std":  {dist.Std, math.Sqrt(1.25)},
+	}
+	if dist.Count != want.Count {
+		t.Errorf("count = %d, want %d", dist.Count, want.Count)
+	}
+	for name, pair := range checks {
+		if math.Abs(pair[0]-pair[1]) > floatTolerance {
+			t.Errorf("%s = %v, want %v", name, pair[0], pair[1])
+		}
+	}
+
+	if _, ok := corpus.Distributions["entropy_global_entropy"]; !ok {
+		t.Error("expected entropy_global_entropy distribution")
+	}
+}
+
+func TestAnalyzeDocumentsContinuesPastErrors(t *testing.T) {
+	tokenizer := newFailingTokenizer(t, "bad")
+	docs := []loader.Document{
+		{Content: "one", StartLine: 1, EndLine: 1, FilePath: "data/corpus.txt"},
+		{Content: "bad", StartLine: 2, EndLine: 2, FilePath: "data/corpus.txt"},
+		{Content: "one two three", StartLine: 3, EndLine: 3, FilePath: "data/corpus.txt"},
+	}
+
+	engine := NewEngine(EngineConfig{EntropyWindowSize: 10})
+	corpus, err := engine.AnalyzeDocuments(context.Background(), docs, tokenizer)
+	if err != nil {
+		t.Fatalf("AnalyzeDocuments returned error: %v", err)
+	}
+
+	if corpus.DocumentCount != 2 || len(corpus.Documents) != 2 {
+		t.Fatalf("got %d documents, want 2", len(corpus.Documents))
+	}
+	if corpus.TotalTokens != 4 {
+		t.Errorf("TotalTokens = %d, want 4", corpus.TotalTokens)
+	}
+	if corpus.FailedCount != 1 || len(corpus.Errors) != 1 || corpus.Errors[0].Index != 1 {
+		t.Fatalf("expected one failure for document 1, got %d: %v", corpus.FailedCount, corpus.Errors)
+	}
+	if !errors.Is(corpus.Err(), errTokenize) {
+		t.Errorf("Err() = %v, want it to wrap %v", corpus.Err(), errTokenize)
+	}
+	if dist := corpus.Distributions["token_count"]; dist.Count != 2 || dist.Max != 3 {
+		t.Errorf("token_count distribution = %+v, want the two successful documents", dist)
+	}
+}
+
+func TestAnalyzeDocumentsCancelled(t *testing.T) {
+	tokenizer := tokenizers.NewMockTokenizer("mock")
+	if err := tokenizer.Initialize(tokenizers.TokenizerConfig{Name: "mock", Type: "custom"}); err != nil {
+		t.Fatalf("failed to initialize mock tokenizer: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	engine := NewEngine(EngineConfig{EntropyWindowSize: 10})
+	corpus, err := engine.AnalyzeDocuments(ctx, []loader.Document{{Content: "one"}, {Content: "two"}}, tokenizer)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want %v", err, context.Canceled)
+	}
+	if corpus == nil || corpus.Skipped != 2 || corpus.DocumentCount != 0 {
+		t.Fatalf("expected both documents skipped, got %+v", corpus)
+	}
+}
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// CoverageCalculator measures how much of a tokenizer's vocabulary a document uses
+type CoverageCalculator struct {
+	vocabSize int
+}
+
+// NewCoverageCalculator creates a new coverage calculator for the given vocabulary size
+func NewCoverageCalculator(vocabSize int) *CoverageCalculator {
+	return &CoverageCalculator{
+		vocabSize: vocabSize,
+	}
+}
+
+// CalculateCoverageStats calculates vocabulary coverage statistics from token IDs.
+// Ratios relative to the vocabulary are only reported when the size is known.
+func (c *CoverageCalculator) CalculateCoverageStats(tokens []tokenizers.Token) (map[string]float64, error) {
+	stats := make(map[string]float64)
+
+	if len(tokens) == 0 {
+		return stats, nil
+	}
+
+	uniqueIDs := make(map[int]bool)
+	maxID := tokens[0].ID
+	for _, token := range tokens {
+		uniqueIDs[token.ID] = true
+		if token.ID > maxID {
+			maxID = token.ID
+		}
+	}
+
+	stats["unique_token_ids"] = float64(len(uniqueIDs))
+
+	if c.vocabSize > 0 {
+		stats["vocab_coverage"] = float64(len(uniqueIDs)) / float64(c.vocabSize)
+		stats["max_token_id_ratio"] = float64(maxID) / float64(c.vocabSize)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// vocabCountingTokenizer wraps the mock tokenizer to count and optionally fail vocab lookups
+type vocabCountingTokenizer struct {
+	*tokenizers.MockTokenizer
+	calls int
+	err   error
+}
+
+func (v *vocabCountingTokenizer) GetVocabSize() (int, error) {
+	v.calls++
+	if v.err != nil {
+		return 0, v.err
+	}
+	return v.MockTokenizer.GetVocabSize()
+}
+
+func newVocabCountingTokenizer(t *testing.T, err error) *vocabCountingTokenizer {
+	t.Helper()
+	mock := tokenizers.NewMockTokenizer("mock")
+	if initErr := mock.Initialize(tokenizers.TokenizerConfig{Name: "mock", Type: "custom"}); initErr != nil {
+		t.Fatalf("failed to initialize mock tokenizer: %v", initErr)
+	}
+	return &vocabCountingTokenizer{MockTokenizer: mock, err: err}
+}
+
+func TestCoverageStats(t *testing.T) {
+	tokens := []tokenizers.Token{{ID: 3}, {ID: 7}, {ID: 3}, {ID: 49}}
+
+	stats, err := NewCoverageCalculator(100).CalculateCoverageStats(tokens)
+	if err != nil {
+		t.Fatalf("CalculateCoverageStats returned error: %v", err)
+	}
+
+	want := map[string]float64{
+		"unique_token_ids":   3,
+		"vocab_coverage":     0.03,
+		"max_token_id_ratio": 0.49,
+	}
+	for key, value := range want {
+		if got := stats[key]; got != value {
+			t.Errorf("%s = %v, want %v", key, got, value)
+		}
+	}
+}
+
+func TestAnalyzeDocumentCachesVocabSize(t *testing.T) {
+	tokenizer := newVocabCountingTokenizer(t, nil)
+	engine := NewEngine(EngineConfig{EntropyWindowSize: 10})
+
+	for _, doc := range []string{"a b c", "d e f"} {
+		result, err := engine.AnalyzeDocument(context.Background(), doc, tokenizer)
+		if err != nil {
+			t.Fatalf("AnalyzeDocument returned error: %v", err)
+		}
+		if _, ok := result.Metrics["vocab_coverage"]; !ok {
+			t.Error("expected vocab_coverage metric")
+		}
+	}
+
+	if tokenizer.calls != 1 {
+		t.Errorf("GetVocabSize called %d times, want 1", tokenizer.calls)
+	}
+}
+
+func TestAnalyzeDocumentRecordsVocabSizeError(t *testing.T) {
+	tokenizer := newVocabCountingTokenizer(t, errors.New("python not available"))
+	engine := NewEngine(EngineConfig{EntropyWindowSize: 10})
+
+	result, err := engine.AnalyzeDocument(context.Background(), "a b c", tokenizer)
+	if err != nil {
+		t.Fatalf("AnalyzeDocument should not fail on vocab size errors: %v", err)
+	}
+
+	if _, ok := result.Metadata["vocab_size_error"]; !ok {
+		t.Error("expected vocab_size_error in result metadata")
+	}
+	if _, ok := result.Metrics["vocab_coverage"]; ok {
+		t.Error("vocab_coverage should be omitted when the vocab size is unknown")
+	}
+	if _, ok := result.Metrics["unique_token_ids"]; !ok {
+		t.Error("unique_token_ids should still be reported")
+	}
+}
+
+func TestVocabSizeCacheFollowsConfig(t *testing.T) {
+	tokenizer := newVocabCountingTokenizer(t, nil)
+	engine := NewEngine(EngineConfig{EntropyWindowSize: 10})
+
+	var coverage []float64
+	for _, size := range []string{"100", "200", "200"} {
+		config := tokenizers.TokenizerConfig{Name: "mock", Type: "custom", Parameters: map[string]string{"vocab_size": size}}
+		if err := tokenizer.Initialize(config); err != nil {
+			t.Fatalf("failed to reinitialize mock tokenizer: %v", err)
+		}
+		result, err := engine.AnalyzeDocument(context.Background(), "a b c", tokenizer)
+		if err != nil {
+			t.Fatalf("AnalyzeDocument returned error: %v", err)
+		}
+		coverage = append(coverage, result.Metrics["vocab_coverage"].Value)
+	}
+
+	// The same name with a new configuration is looked up again
+	if tokenizer.calls != 2 {
+		t.Errorf("GetVocabSize called %d times, want once per configuration", tokenizer.calls)
+	}
+	if coverage[1] != coverage[0]/2 || coverage[2] != coverage[1] {
+		t.Errorf("vocab_coverage = %v, want it halved with the doubled vocabulary", coverage)
+	}
+}
+
+func TestVocabSizeErrorsAreNotCached(t *testing.T) {
+	tokenizer := newVocabCountingTokenizer(t, errors.New("python not available"))
+	engine := NewEngine(EngineConfig{EntropyWindowSize: 10})
+
+	if _, err := engine.AnalyzeDocument(context.Background(), "a b c", tokenizer); err != nil {
+		t.Fatalf("AnalyzeDocument returned error: %v", err)
+	}
+
+	// Once the backend is back, the next document gets a vocabulary size
+	tokenizer.err = nil
+	result, err := engine.AnalyzeDocument(context.Background(), "a b c", tokenizer)
+	if err != nil {
+		t.Fatalf("AnalyzeDocument returned error: %v", err)
+	}
+	if _, ok := result.Metadata["vocab_size_error"]; ok {
+		t.Errorf("vocab_size_error = %v after the backend recovered", result.Metadata["vocab_size_error"])
+	}
+	if _, ok := result.Metrics["vocab_coverage"]; !ok {
+		t.Error("expected vocab_coverage once the vocab size is known")
+	}
+	if tokenizer.calls != 2 {
+		t.Errorf("GetVocabSize called %d times, want 2", tokenizer.calls)
+	}
+}
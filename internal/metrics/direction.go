@@ -0,0 +1,48 @@
+package metrics
+
+// Direction tells whether a larger or a smaller value of a metric is better
+type Direction int
+
+const (
+	// Neutral metrics describe the tokenization without ranking it
+	Neutral Direction = iota
+	// HigherIsBetter metrics favor the tokenizer with the largest value
+	HigherIsBetter
+	// LowerIsBetter metrics favor the tokenizer with the smallest value
+	LowerIsBetter
+)
+
+// metricDirections ranks the metrics whose better value is clear: fewer tokens for
+// the same text, more text per token and fewer words split into pieces
+var metricDirections = map[string]Direction{
+	"token_count": LowerIsBetter,
+
+	"compression_ratio":                   LowerIsBetter,
+	"compression_compression_ratio":       LowerIsBetter,
+	"compression_compression_efficiency":  HigherIsBetter,
+	"compression_space_savings_percent":   HigherIsBetter,
+	"compression_token_density":           LowerIsBetter,
+	"compression_token_density_runes":     LowerIsBetter,
+	"compression_char_density":            HigherIsBetter,
+	"compression_char_density_bytes":      HigherIsBetter,
+	"compression_char_density_runes":      HigherIsBetter,
+	"compression_entropy_efficiency":      HigherIsBetter,
+	"compression_entropy_redundancy":      LowerIsBetter,
+	"compression_compression_potential":   LowerIsBetter,
+	"compression_varint_ratio":            LowerIsBetter,
+	"compression_entropy_bound_ratio":     LowerIsBetter,
+	"compression_tokenization_vs_gzip":    LowerIsBetter,
+	"fertility_mean_tokens_per_word":      LowerIsBetter,
+	"fertility_median_tokens_per_word":    LowerIsBetter,
+	"fertility_max_tokens_per_word":       LowerIsBetter,
+	"fertility_whole_word_ratio":          HigherIsBetter,
+	"fertility_split_3plus_ratio":         LowerIsBetter,
+	"numeric_avg_tokens_per_number":       LowerIsBetter,
+	"composition_boundary_spanning_ratio": LowerIsBetter,
+}
+
+// MetricDirection returns whether a larger or a smaller value of a metric is better;
+// metrics without a clear better value, and unknown ones, are Neutral
+func MetricDirection(name string) Direction {
+	return metricDirections[name]
+}
@@ -0,0 +1,22 @@
+package metrics
+
+import "testing"
+
+func TestMetricDirection(t *testing.T) {
+	tests := []struct {
+		name string
+		want Direction
+	}{
+		{"compression_compression_ratio", LowerIsBetter},
+		{"compression_entropy_efficiency", HigherIsBetter},
+		{"fertility_whole_word_ratio", HigherIsBetter},
+		{"token_count", LowerIsBetter},
+		{"entropy_global_entropy", Neutral},
+		{"plugin_custom_metric", Neutral},
+	}
+	for _, tt := range tests {
+		if got := MetricDirection(tt.name); got != tt.want {
+			t.Errorf("MetricDirection(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
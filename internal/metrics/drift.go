@@ -1,24 +1,53 @@
 package metrics
 
 import (
+	"errors"
 	"fmt"
 	"math"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
 )
 
+// ErrMissingOffsets is returned when a tokenization lacks character offsets
+var ErrMissingOffsets = errors.New("tokenization lacks character offsets")
+
+// ErrSequenceTooLong is returned by metrics whose cost grows quadratically with
+// sequence length when an input exceeds the configured cutoff
+var ErrSequenceTooLong = errors.New("token sequence exceeds length cutoff")
+
+// DefaultMaxEditDistanceTokens is the longest token sequence CalculateEditDistance
+// processes unless overridden with SetMaxEditDistanceTokens
+const DefaultMaxEditDistanceTokens = 50000
+
+// DefaultMaxAlignmentTokens is the longest token sequence aligned exactly; longer
+// sequences use a sampled approximation unless overridden with SetMaxAlignmentTokens
+const DefaultMaxAlignmentTokens = 20000
+
 // DriftCalculator handles drift detection and cross-tokenizer comparison
 type DriftCalculator struct {
-	alignmentThreshold float64
+	alignmentThreshold    float64
+	maxEditDistanceTokens int
+	maxAlignmentTokens    int
+	stripSpaceMarkers     map[string]bool
 }
 
 // NewDriftCalculator creates a new drift calculator
 func NewDriftCalculator(alignmentThreshold float64) *DriftCalculator {
 	return &DriftCalculator{
-		alignmentThreshold: alignmentThreshold,
+		alignmentThreshold:    alignmentThreshold,
+		maxEditDistanceTokens: DefaultMaxEditDistanceTokens,
+		maxAlignmentTokens:    DefaultMaxAlignmentTokens,
 	}
 }
 
+// SetMaxEditDistanceTokens sets the sequence length beyond which edit distance is
+// skipped. Zero or a negative value removes the cutoff.
+func (d *DriftCalculator) SetMaxEditDistanceTokens(maxTokens int) {
+	d.maxEditDistanceTokens = maxTokens
+}
+
 // CalculateJaccardDistance calculates the Jaccard distance between two token sets
 func (d *DriftCalculator) CalculateJaccardDistance(tokens1, tokens2 []tokenizers.Token) (float64, error) {
 	if len(tokens1) == 0 && len(tokens2) == 0 {
@@ -77,9 +106,12 @@ func (d *DriftCalculator) CalculateTokenAlignment(tokens1, tokens2 []tokenizers.
 		texts2[i] = token.Text
 	}
 
-	// Calculate alignment metrics
-	alignmentScore := d.calculateAlignmentScore(texts1, texts2)
-	metrics["alignment_score"] = alignmentScore
+	// Calculate alignment metrics, noting when long inputs were only sampled
+	alignmentScore, approximate := d.calculateAlignmentScore(texts1, texts2)
+	metrics["score"] = alignmentScore
+	if approximate {
+		metrics["approximate"] = 1
+	}
 
 	// Position-based drift
 	positionDrift := d.calculatePositionDrift(texts1, texts2)
@@ -96,44 +128,25 @@ func (d *DriftCalculator) CalculateTokenAlignment(tokens1, tokens2 []tokenizers.
 	return metrics, nil
 }
 
-// calculateAlignmentScore calculates how well tokens align between sequences
-func (d *DriftCalculator) calculateAlignmentScore(texts1, texts2 []string) float64 {
+// calculateAlignmentScore calculates how well tokens align between sequences, reporting
+// whether the score was approximated because a sequence exceeded the alignment cutoff
+func (d *DriftCalculator) calculateAlignmentScore(texts1, texts2 []string) (float64, bool) {
 	if len(texts1) == 0 || len(texts2) == 0 {
-		return 0.0
+		return 0.0, false
 	}
 
-	// Use dynamic programming to find longest common subsequence
-	lcs := d.longestCommonSubsequence(texts1, texts2)
+	// Longest common subsequence, exact or sampled depending on input length
+	approximate := d.maxAlignmentTokens > 0 && (len(texts1) > d.maxAlignmentTokens || len(texts2) > d.maxAlignmentTokens)
+	var lcs int
+	if approximate {
+		lcs = approximateLCS(texts1, texts2)
+	} else {
+		lcs = lcsLength(texts1, texts2)
+	}
 
 	// Calculate alignment score based on LCS length
 	maxLength := math.Max(float64(len(texts1)), float64(len(texts2)))
-	return float64(lcs) / maxLength
-}
-
-// longestCommonSubsequence finds the longest common subsequence
-func (d *DriftCalculator) longestCommonSubsequence(texts1, texts2 []string) int {
-	if len(texts1) == 0 || len(texts2) == 0 {
-		return 0
-	}
-
-	// Create DP table
-	dp := make([][]int, len(texts1)+1)
-	for i := range dp {
-		dp[i] = make([]int, len(texts2)+1)
-	}
-
-	// Fill DP table
-	for i := 1; i <= len(texts1); i++ {
-		for j := 1; j <= len(texts2); j++ {
-			if texts1[i-1] == texts2[j-1] {
-				dp[i][j] = dp[i-1][j-1] + 1
-			} else {
-				dp[i][j] = int(math.Max(float64(dp[i-1][j]), float64(dp[i][j-1])))
-			}
-		}
-	}
-
-	return dp[len(texts1)][len(texts2)]
+	return float64(lcs) / maxLength, approximate
 }
 
 // calculatePositionDrift calculates drift based on token positions
@@ -226,12 +239,131 @@ func (d *DriftCalculator) calculateContentSimilarity(texts1, texts2 []string) fl
 	return dotProduct / (math.Sqrt(magnitude1) * math.Sqrt(magnitude2))
 }
 
+// CalculateEditDistance calculates the token-level Levenshtein distance between two
+// sequences, i.e. the minimum number of token insertions, deletions and substitutions
+// turning one into the other, together with the distance divided by the longer
+// sequence length. Only two DP rows over the shorter sequence are kept, so memory
+// is O(min(n, m)); time is O(n × m), and sequences longer than the configured cutoff
+// return ErrSequenceTooLong.
+func (d *DriftCalculator) CalculateEditDistance(tokens1, tokens2 []tokenizers.Token) (map[string]float64, error) {
+	if d.exceedsEditDistanceCutoff(len(tokens1), len(tokens2)) {
+		return nil, fmt.Errorf("%w: %d and %d tokens, limit %d", ErrSequenceTooLong, len(tokens1), len(tokens2), d.maxEditDistanceTokens)
+	}
+
+	metrics := make(map[string]float64)
+
+	longer, shorter := tokens1, tokens2
+	if len(shorter) > len(longer) {
+		longer, shorter = shorter, longer
+	}
+
+	if len(longer) == 0 {
+		metrics["edit_distance"] = 0
+		metrics["normalized_edit_distance"] = 0
+		return metrics, nil
+	}
+
+	// prev[j] holds the distance between the processed prefix of longer and shorter[:j]
+	prev := make([]int, len(shorter)+1)
+	curr := make([]int, len(shorter)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(longer); i++ {
+		curr[0] = i
+		for j := 1; j <= len(shorter); j++ {
+			cost := 1
+			if longer[i-1].Text == shorter[j-1].Text {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	distance := prev[len(shorter)]
+	metrics["edit_distance"] = float64(distance)
+	metrics["normalized_edit_distance"] = float64(distance) / float64(len(longer))
+
+	return metrics, nil
+}
+
+// SetMaxAlignmentTokens sets the sequence length beyond which the alignment score is
+// approximated from sampled segments. Zero or a negative value always aligns exactly.
+func (d *DriftCalculator) SetMaxAlignmentTokens(maxTokens int) {
+	d.maxAlignmentTokens = maxTokens
+}
+
+// SetStripSpaceMarkers enables space marker normalization for the named tokenizers:
+// before their tokens are compared by text, one leading space or marker such as
+// GPT-2's "Ġ" is removed, so Jaccard and overlap metrics reflect the pieces rather
+// than the way the tokenizer encodes preceding whitespace. The name "*" matches every
+// tokenizer. Calling it with no names disables the normalization.
+func (d *DriftCalculator) SetStripSpaceMarkers(tokenizerNames ...string) {
+	d.stripSpaceMarkers = make(map[string]bool, len(tokenizerNames))
+	for _, name := range tokenizerNames {
+		d.stripSpaceMarkers[name] = true
+	}
+}
+
+// comparisonTokens returns the tokens used for text comparisons, with space markers
+// stripped when normalization is enabled for the tokenizer
+func (d *DriftCalculator) comparisonTokens(tokenizerName string, tokens []tokenizers.Token) []tokenizers.Token {
+	if !d.stripsSpaceMarkers(tokenizerName) {
+		return tokens
+	}
+
+	normalized := make([]tokenizers.Token, len(tokens))
+	for i, token := range tokens {
+		normalized[i] = token
+		normalized[i].Text = stripSpaceMarker(token.Text)
+	}
+	return normalized
+}
+
+// comparisonResult applies comparisonTokens to a tokenization result without
+// modifying the original, which may be shared with a cache
+func (d *DriftCalculator) comparisonResult(result *tokenizers.TokenizationResult) *tokenizers.TokenizationResult {
+	if !d.stripsSpaceMarkers(result.Tokenizer) {
+		return result
+	}
+
+	normalized := *result
+	normalized.Tokens = d.comparisonTokens(result.Tokenizer, result.Tokens)
+	return &normalized
+}
+
+// stripsSpaceMarkers reports whether space marker normalization applies to the tokenizer
+func (d *DriftCalculator) stripsSpaceMarkers(tokenizerName string) bool {
+	return d.stripSpaceMarkers[tokenizerName] || d.stripSpaceMarkers["*"]
+}
+
+// exceedsEditDistanceCutoff reports whether either sequence length is over the edit distance cutoff
+func (d *DriftCalculator) exceedsEditDistanceCutoff(length1, length2 int) bool {
+	return d.maxEditDistanceTokens > 0 && (length1 > d.maxEditDistanceTokens || length2 > d.maxEditDistanceTokens)
+}
+
+// minInt returns the smallest of its arguments
+func minInt(first int, rest ...int) int {
+	min := first
+	for _, v := range rest {
+		if v < min {
+			min = v
+		}
+	}
+	return min
+}
+
 // CalculateCrossTokenizerDrift calculates drift between two tokenization results
 func (d *DriftCalculator) CalculateCrossTokenizerDrift(result1, result2 *tokenizers.TokenizationResult) (map[string]float64, error) {
 	if result1 == nil || result2 == nil {
 		return nil, fmt.Errorf("both tokenization results must be provided")
 	}
 
+	// Compare token texts after any configured space marker normalization
+	result1, result2 = d.comparisonResult(result1), d.comparisonResult(result2)
+
 	metrics := make(map[string]float64)
 
 	// Jaccard distance
@@ -246,6 +378,13 @@ func (d *DriftCalculator) CalculateCrossTokenizerDrift(result1, result2 *tokeniz
 		}
 	}
 
+	// Edit distance is skipped when either sequence exceeds the length cutoff
+	if editMetrics, err := d.CalculateEditDistance(result1.Tokens, result2.Tokens); err == nil {
+		for k, v := range editMetrics {
+			metrics[k] = v
+		}
+	}
+
 	// Token count drift
 	tokenCount1 := float64(len(result1.Tokens))
 	tokenCount2 := float64(len(result2.Tokens))
@@ -266,9 +405,224 @@ func (d *DriftCalculator) CalculateCrossTokenizerDrift(result1, result2 *tokeniz
 	vocabOverlap := d.calculateVocabularyOverlap(result1.Tokens, result2.Tokens)
 	metrics["vocab_overlap"] = vocabOverlap
 
+	// Boundary agreement is skipped when either tokenization lacks offsets
+	if boundaryMetrics, err := d.CalculateBoundaryAgreement(result1, result2); err == nil {
+		for k, v := range boundaryMetrics {
+			metrics["boundary_"+k] = v
+		}
+	}
+
+	// Distribution divergence
+	if klDivergence, err := d.CalculateKLDivergence(result1, result2); err == nil {
+		metrics["kl_divergence"] = klDivergence
+	}
+	if jsDivergence, err := d.CalculateJSDivergence(result1, result2); err == nil {
+		metrics["js_divergence"] = jsDivergence
+	}
+
 	return metrics, nil
 }
 
+// CalculateKLDivergence calculates the Kullback-Leibler divergence D(P1 || P2) in bits
+// between the unigram token distributions of two tokenization results. Both
+// distributions use add-one smoothing over the union vocabulary so the result is finite.
+func (d *DriftCalculator) CalculateKLDivergence(result1, result2 *tokenizers.TokenizationResult) (float64, error) {
+	if result1 == nil || result2 == nil {
+		return 0.0, fmt.Errorf("both tokenization results must be provided")
+	}
+
+	freq1 := d.calculateTokenFrequencies(result1.Tokens)
+	freq2 := d.calculateTokenFrequencies(result2.Tokens)
+
+	vocab := make(map[string]bool)
+	for token := range freq1 {
+		vocab[token] = true
+	}
+	for token := range freq2 {
+		vocab[token] = true
+	}
+
+	if len(vocab) == 0 {
+		return 0.0, nil
+	}
+
+	// Smoothed totals: every vocabulary entry receives one extra count
+	total1 := float64(len(result1.Tokens) + len(vocab))
+	total2 := float64(len(result2.Tokens) + len(vocab))
+
+	divergence := 0.0
+	for token := range vocab {
+		p := float64(freq1[token]+1) / total1
+		q := float64(freq2[token]+1) / total2
+		divergence += p * math.Log2(p/q)
+	}
+
+	return divergence, nil
+}
+
+// CalculateJSDivergence calculates the Jensen-Shannon divergence in bits between the
+// unigram token distributions of two tokenization results. The value is symmetric and
+// bounded by [0, 1], so no smoothing is required. A result without tokens shares
+// nothing with one that has them, giving the maximum of 1; two empty results give 0.
+func (d *DriftCalculator) CalculateJSDivergence(result1, result2 *tokenizers.TokenizationResult) (float64, error) {
+	if result1 == nil || result2 == nil {
+		return 0.0, fmt.Errorf("both tokenization results must be provided")
+	}
+
+	if len(result1.Tokens) == 0 && len(result2.Tokens) == 0 {
+		return 0.0, nil
+	}
+	if len(result1.Tokens) == 0 || len(result2.Tokens) == 0 {
+		return 1.0, nil
+	}
+
+	freq1 := d.calculateTokenFrequencies(result1.Tokens)
+	freq2 := d.calculateTokenFrequencies(result2.Tokens)
+	total1 := float64(len(result1.Tokens))
+	total2 := float64(len(result2.Tokens))
+
+	vocab := make(map[string]bool)
+	for token := range freq1 {
+		vocab[token] = true
+	}
+	for token := range freq2 {
+		vocab[token] = true
+	}
+
+	divergence := 0.0
+	for token := range vocab {
+		p := float64(freq1[token]) / total1
+		q := float64(freq2[token]) / total2
+		m := (p + q) / 2
+
+		if p > 0 {
+			divergence += 0.5 * p * math.Log2(p/m)
+		}
+		if q > 0 {
+			divergence += 0.5 * q * math.Log2(q/m)
+		}
+	}
+
+	return divergence, nil
+}
+
+// CalculateBoundaryAgreement compares where two tokenizations place token boundaries
+// in the text. Boundaries of result2 are scored against those of result1 as the
+// reference, yielding precision, recall and F1 together with the number of boundaries
+// unique to each tokenization. Offsets that fall on whitespace are moved forward to the
+// next non-space character so that tokenizers attaching spaces to different sides of a
+// word still agree.
+func (d *DriftCalculator) CalculateBoundaryAgreement(result1, result2 *tokenizers.TokenizationResult) (map[string]float64, error) {
+	if result1 == nil || result2 == nil {
+		return nil, fmt.Errorf("both tokenization results must be provided")
+	}
+
+	if !hasTokenOffsets(result1.Tokens) || !hasTokenOffsets(result2.Tokens) {
+		return nil, ErrMissingOffsets
+	}
+
+	boundaries1 := d.collectBoundaries(result1)
+	boundaries2 := d.collectBoundaries(result2)
+
+	shared := 0
+	for offset := range boundaries2 {
+		if boundaries1[offset] {
+			shared++
+		}
+	}
+
+	metrics := make(map[string]float64)
+	metrics["unique_1"] = float64(len(boundaries1) - shared)
+	metrics["unique_2"] = float64(len(boundaries2) - shared)
+
+	// Two single-token tokenizations trivially agree
+	if len(boundaries1) == 0 && len(boundaries2) == 0 {
+		metrics["precision"] = 1.0
+		metrics["recall"] = 1.0
+		metrics["f1"] = 1.0
+		return metrics, nil
+	}
+
+	precision := 0.0
+	if len(boundaries2) > 0 {
+		precision = float64(shared) / float64(len(boundaries2))
+	}
+
+	recall := 0.0
+	if len(boundaries1) > 0 {
+		recall = float64(shared) / float64(len(boundaries1))
+	}
+
+	f1 := 0.0
+	if precision+recall > 0 {
+		f1 = 2 * precision * recall / (precision + recall)
+	}
+
+	metrics["precision"] = precision
+	metrics["recall"] = recall
+	metrics["f1"] = f1
+
+	return metrics, nil
+}
+
+// collectBoundaries returns the set of interior boundary offsets of a tokenization.
+// The start and end of the text are not counted as boundaries.
+func (d *DriftCalculator) collectBoundaries(result *tokenizers.TokenizationResult) map[int]bool {
+	document := result.Document
+
+	textStart, textEnd := 0, 0
+	if document != "" {
+		textStart = skipWhitespace(document, 0)
+		textEnd = len(document)
+	} else {
+		for _, token := range result.Tokens {
+			if token.EndPos > textEnd {
+				textEnd = token.EndPos
+			}
+		}
+	}
+
+	boundaries := make(map[int]bool)
+	for _, token := range result.Tokens {
+		for _, offset := range []int{token.StartPos, token.EndPos} {
+			if document != "" {
+				offset = skipWhitespace(document, offset)
+			}
+			if offset > textStart && offset < textEnd {
+				boundaries[offset] = true
+			}
+		}
+	}
+
+	return boundaries
+}
+
+// skipWhitespace advances a byte offset past any whitespace in the text
+func skipWhitespace(text string, offset int) int {
+	if offset < 0 {
+		return 0
+	}
+
+	for offset < len(text) {
+		r, size := utf8.DecodeRuneInString(text[offset:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		offset += size
+	}
+
+	return offset
+}
+
+// calculateTokenFrequencies counts occurrences of each token text
+func (d *DriftCalculator) calculateTokenFrequencies(tokens []tokenizers.Token) map[string]int {
+	freq := make(map[string]int)
+	for _, token := range tokens {
+		freq[token.Text]++
+	}
+	return freq
+}
+
 // calculateAverageTokenLength calculates the average length of tokens
 func (d *DriftCalculator) calculateAverageTokenLength(tokens []tokenizers.Token) float64 {
 	if len(tokens) == 0 {
@@ -0,0 +1,374 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+const floatTolerance = 1e-9
+
+func tokenizationFromTexts(texts ...string) *tokenizers.TokenizationResult {
+	tokens := make([]tokenizers.Token, len(texts))
+	for i, text := range texts {
+		tokens[i] = tokenizers.Token{Text: text, ID: i}
+	}
+	return &tokenizers.TokenizationResult{Tokens: tokens}
+}
+
+func TestDivergenceMetrics(t *testing.T) {
+	tests := []struct {
+		name    string
+		tokens1 []string
+		tokens2 []string
+		wantKL  float64
+		wantJS  float64
+	}{
+		{
+			name:    "identical",
+			tokens1: []string{"a", "b", "b", "c"},
+			tokens2: []string{"a", "b", "b", "c"},
+			wantKL:  0.0,
+			wantJS:  0.0,
+		},
+		{
+			// Smoothed P = (3/4, 1/4), Q = (1/4, 3/4)
+			name:    "disjoint",
+			tokens1: []string{"a", "a"},
+			tokens2: []string{"b", "b"},
+			wantKL:  0.5 * math.Log2(3),
+			wantJS:  1.0,
+		},
+		{
+			// Smoothed P = (2/5, 2/5, 1/5), Q = (1/5, 2/5, 2/5)
+			name:    "partial overlap",
+			tokens1: []string{"a", "b"},
+			tokens2: []string{"b", "c"},
+			wantKL:  0.2,
+			wantJS:  0.5,
+		},
+		{
+			// Smoothed P = Q = (1)
+			name:    "one side empty",
+			tokens1: nil,
+			tokens2: []string{"a", "a"},
+			wantKL:  0.0,
+			wantJS:  1.0,
+		},
+		{
+			name:   "both empty",
+			wantKL: 0.0,
+			wantJS: 0.0,
+		},
+	}
+
+	calc := NewDriftCalculator(0.5)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result1 := tokenizationFromTexts(tt.tokens1...)
+			result2 := tokenizationFromTexts(tt.tokens2...)
+
+			kl, err := calc.CalculateKLDivergence(result1, result2)
+			if err != nil {
+				t.Fatalf("CalculateKLDivergence returned error: %v", err)
+			}
+			if math.Abs(kl-tt.wantKL) > floatTolerance {
+				t.Errorf("KL divergence = %v, want %v", kl, tt.wantKL)
+			}
+
+			js, err := calc.CalculateJSDivergence(result1, result2)
+			if err != nil {
+				t.Fatalf("CalculateJSDivergence returned error: %v", err)
+			}
+			if math.Abs(js-tt.wantJS) > floatTolerance {
+				t.Errorf("JS divergence = %v, want %v", js, tt.wantJS)
+			}
+		})
+	}
+}
+
+func TestCrossTokenizerDriftIncludesDivergence(t *testing.T) {
+	calc := NewDriftCalculator(0.5)
+	metrics, err := calc.CalculateCrossTokenizerDrift(tokenizationFromTexts("a", "b"), tokenizationFromTexts("b", "c"))
+	if err != nil {
+		t.Fatalf("CalculateCrossTokenizerDrift returned error: %v", err)
+	}
+
+	for _, key := range []string{"kl_divergence", "js_divergence"} {
+		if _, ok := metrics[key]; !ok {
+			t.Errorf("expected %s in cross-tokenizer drift metrics", key)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		name       string
+		a, b       []string
+		distance   float64
+		normalized float64
+	}{
+		{name: "both empty", distance: 0, normalized: 0},
+		{name: "one empty", a: []string{"a", "b"}, distance: 2, normalized: 1},
+		{name: "identical", a: []string{"a", "b", "c"}, b: []string{"a", "b", "c"}, distance: 0, normalized: 0},
+		{name: "substitution", a: []string{"a", "b", "c"}, b: []string{"a", "x", "c"}, distance: 1, normalized: 1.0 / 3.0},
+		{name: "order matters", a: []string{"a", "b"}, b: []string{"b", "a"}, distance: 2, normalized: 1},
+		{
+			// kitten -> sitting at token level: two substitutions and one insertion
+			name:       "classic",
+			a:          []string{"k", "i", "t", "t", "e", "n"},
+			b:          []string{"s", "i", "t", "t", "i", "n", "g"},
+			distance:   3,
+			normalized: 3.0 / 7.0,
+		},
+	}
+
+	calc := NewDriftCalculator(0.5)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The distance is symmetric regardless of which sequence is shorter
+			for _, order := range [][2][]string{{tt.a, tt.b}, {tt.b, tt.a}} {
+				metrics, err := calc.CalculateEditDistance(tokenizationFromTexts(order[0]...).Tokens, tokenizationFromTexts(order[1]...).Tokens)
+				if err != nil {
+					t.Fatalf("CalculateEditDistance returned error: %v", err)
+				}
+				if got := metrics["edit_distance"]; got != tt.distance {
+					t.Errorf("edit_distance = %v, want %v", got, tt.distance)
+				}
+				if got := metrics["normalized_edit_distance"]; math.Abs(got-tt.normalized) > floatTolerance {
+					t.Errorf("normalized_edit_distance = %v, want %v", got, tt.normalized)
+				}
+			}
+		})
+	}
+}
+
+func TestEditDistanceCutoff(t *testing.T) {
+	calc := NewDriftCalculator(0.5)
+	calc.SetMaxEditDistanceTokens(2)
+
+	long := tokenizationFromTexts("a", "b", "c")
+	short := tokenizationFromTexts("a", "b")
+
+	if _, err := calc.CalculateEditDistance(long.Tokens, short.Tokens); !errors.Is(err, ErrSequenceTooLong) {
+		t.Errorf("expected ErrSequenceTooLong, got %v", err)
+	}
+
+	metrics, err := calc.CalculateCrossTokenizerDrift(long, short)
+	if err != nil {
+		t.Fatalf("CalculateCrossTokenizerDrift returned error: %v", err)
+	}
+	if _, ok := metrics["edit_distance"]; ok {
+		t.Errorf("expected edit_distance to be skipped beyond the cutoff")
+	}
+
+	calc.SetMaxEditDistanceTokens(0)
+	metrics, err = calc.CalculateCrossTokenizerDrift(long, short)
+	if err != nil {
+		t.Fatalf("CalculateCrossTokenizerDrift returned error: %v", err)
+	}
+	if got := metrics["edit_distance"]; got != 1 {
+		t.Errorf("edit_distance = %v without a cutoff, want 1", got)
+	}
+}
+
+// tokenizationFromSpans builds a tokenization of document with tokens at the given byte spans
+func tokenizationFromSpans(document string, spans ...[2]int) *tokenizers.TokenizationResult {
+	tokens := make([]tokenizers.Token, len(spans))
+	for i, span := range spans {
+		tokens[i] = tokenizers.Token{
+			Text:     document[span[0]:span[1]],
+			ID:       i,
+			StartPos: span[0],
+			EndPos:   span[1],
+		}
+	}
+	return &tokenizers.TokenizationResult{Document: document, Tokens: tokens}
+}
+
+func TestBoundaryAgreement(t *testing.T) {
+	const document = "unhappy cats"
+
+	tests := []struct {
+		name          string
+		result1       *tokenizers.TokenizationResult
+		result2       *tokenizers.TokenizationResult
+		wantPrecision float64
+		wantRecall    float64
+		wantF1        float64
+		wantUnique1   float64
+		wantUnique2   float64
+	}{
+		{
+			// Whitespace attached to either side of a word is the same boundary
+			name:          "identical boundaries modulo whitespace",
+			result1:       tokenizationFromSpans(document, [2]int{0, 7}, [2]int{8, 12}),
+			result2:       tokenizationFromSpans(document, [2]int{0, 7}, [2]int{7, 12}),
+			wantPrecision: 1.0,
+			wantRecall:    1.0,
+			wantF1:        1.0,
+		},
+		{
+			// Reference boundaries {8}, candidate boundaries {2, 8, 11}
+			name:          "oversegmented candidate",
+			result1:       tokenizationFromSpans(document, [2]int{0, 7}, [2]int{8, 12}),
+			result2:       tokenizationFromSpans(document, [2]int{0, 2}, [2]int{2, 7}, [2]int{7, 11}, [2]int{11, 12}),
+			wantPrecision: 1.0 / 3.0,
+			wantRecall:    1.0,
+			wantF1:        0.5,
+			wantUnique2:   2,
+		},
+		{
+			// Reference boundaries {2, 8}, candidate boundaries {8, 11}
+			name:          "partial agreement",
+			result1:       tokenizationFromSpans(document, [2]int{0, 2}, [2]int{2, 7}, [2]int{8, 12}),
+			result2:       tokenizationFromSpans(document, [2]int{0, 7}, [2]int{8, 11}, [2]int{11, 12}),
+			wantPrecision: 0.5,
+			wantRecall:    0.5,
+			wantF1:        0.5,
+			wantUnique1:   1,
+			wantUnique2:   1,
+		},
+	}
+
+	calc := NewDriftCalculator(0.5)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics, err := calc.CalculateBoundaryAgreement(tt.result1, tt.result2)
+			if err != nil {
+				t.Fatalf("CalculateBoundaryAgreement returned error: %v", err)
+			}
+
+			want := map[string]float64{
+				"precision": tt.wantPrecision,
+				"recall":    tt.wantRecall,
+				"f1":        tt.wantF1,
+				"unique_1":  tt.wantUnique1,
+				"unique_2":  tt.wantUnique2,
+			}
+			for key, value := range want {
+				if math.Abs(metrics[key]-value) > floatTolerance {
+					t.Errorf("%s = %v, want %v", key, metrics[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestBoundaryAgreementSkippedWithoutOffsets(t *testing.T) {
+	calc := NewDriftCalculator(0.5)
+	withOffsets := tokenizationFromSpans("a b", [2]int{0, 1}, [2]int{2, 3})
+	withoutOffsets := tokenizationFromTexts("a", "b")
+
+	if _, err := calc.CalculateBoundaryAgreement(withOffsets, withoutOffsets); !errors.Is(err, ErrMissingOffsets) {
+		t.Errorf("expected ErrMissingOffsets, got %v", err)
+	}
+
+	stats, err := calc.CalculateDriftStats(withOffsets, withoutOffsets)
+	if err != nil {
+		t.Fatalf("CalculateDriftStats returned error: %v", err)
+	}
+	for _, key := range []string{"drift_boundary_precision", "drift_boundary_recall", "drift_boundary_f1"} {
+		if _, ok := stats[key]; ok {
+			t.Errorf("expected %s to be skipped when offsets are missing", key)
+		}
+	}
+}
+
+func TestComparisonResultPairwiseMatrix(t *testing.T) {
+	comparison := &ComparisonResult{
+		Tokenizers: []string{"a", "b", "c"},
+		Pairs: []TokenizerPair{
+			{A: "a", B: "b", Drift: map[string]float64{"drift_jaccard_distance": 0.25}},
+			{A: "a", B: "c", Drift: map[string]float64{"drift_jaccard_distance": 0.5}},
+			{A: "b", B: "c", Drift: map[string]float64{"drift_jaccard_distance": 0.75}},
+		},
+	}
+
+	want := [][]float64{
+		{0, 0.25, 0.5},
+		{0.25, 0, 0.75},
+		{0.5, 0.75, 0},
+	}
+
+	got := comparison.PairwiseMatrix("drift_jaccard_distance")
+	for i := range want {
+		for j := range want[i] {
+			if got[i][j] != want[i][j] {
+				t.Errorf("matrix[%d][%d] = %v, want %v", i, j, got[i][j], want[i][j])
+			}
+		}
+	}
+
+	if pair := comparison.Pair("c", "b"); pair == nil || pair.Drift["drift_jaccard_distance"] != 0.75 {
+		t.Errorf("Pair(c, b) = %+v, want the b/c pair", pair)
+	}
+
+	legacy := comparison.ToMap()
+	if _, ok := legacy["a_vs_b"]; !ok {
+		t.Error("expected a_vs_b key in ToMap output")
+	}
+}
+
+func TestCompareTokenizersNotesSkippedEditDistance(t *testing.T) {
+	tokenizerA := tokenizers.NewMockTokenizer("a")
+	tokenizerB := tokenizers.NewMockTokenizer("b")
+	for _, tokenizer := range []*tokenizers.MockTokenizer{tokenizerA, tokenizerB} {
+		if err := tokenizer.Initialize(tokenizers.TokenizerConfig{Name: tokenizer.Name(), Type: "custom"}); err != nil {
+			t.Fatalf("failed to initialize mock tokenizer: %v", err)
+		}
+	}
+
+	engine := NewEngine(EngineConfig{EntropyWindowSize: 10, MaxEditDistanceTokens: 2})
+	comparison, err := engine.CompareTokenizers(context.Background(), "one two three", []tokenizers.Tokenizer{tokenizerA, tokenizerB})
+	if err != nil {
+		t.Fatalf("CompareTokenizers returned error: %v", err)
+	}
+
+	pair := comparison.Pair("a", "b")
+	if pair == nil {
+		t.Fatalf("expected a pair for a and b")
+	}
+	if _, ok := pair.Drift["drift_edit_distance"]; ok {
+		t.Errorf("expected drift_edit_distance to be skipped beyond the cutoff")
+	}
+	if _, ok := pair.Metadata["edit_distance"]; !ok {
+		t.Errorf("expected a metadata note for the skipped edit distance, got %v", pair.Metadata)
+	}
+}
+
+func TestDriftMetricNames(t *testing.T) {
+	tokenizerA := tokenizers.NewMockTokenizer("a")
+	if err := tokenizerA.Initialize(tokenizers.TokenizerConfig{Name: "a", Type: "custom"}); err != nil {
+		t.Fatalf("failed to initialize mock tokenizer: %v", err)
+	}
+	tokenizerB := tokenizers.NewCharTokenizer("b")
+
+	engine := NewEngine(EngineConfig{EntropyWindowSize: 10})
+	comparison, err := engine.CompareTokenizers(context.Background(), "one two three", []tokenizers.Tokenizer{tokenizerA, tokenizerB})
+	if err != nil {
+		t.Fatalf("CompareTokenizers returned error: %v", err)
+	}
+
+	names := make(map[string]bool)
+	for _, name := range engine.GetDriftMetricNames() {
+		names[name] = true
+	}
+	for name := range comparison.Pairs[0].Drift {
+		if strings.HasPrefix(name, "drift_") && !names[name] {
+			t.Errorf("pair drift metric %s is missing from GetDriftMetricNames", name)
+		}
+	}
+	if _, ok := comparison.Pairs[0].Drift["drift_alignment_score"]; !ok {
+		t.Errorf("pair drift metrics %v lack drift_alignment_score", comparison.Pairs[0].Drift)
+	}
+	for _, name := range engine.GetMetricNames() {
+		if strings.HasPrefix(name, "drift_") {
+			t.Errorf("GetMetricNames lists %s, which documents never have", name)
+		}
+	}
+}
@@ -4,7 +4,13 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/RevBooyah/TokEntropyDrift/internal/cache"
+	"github.com/RevBooyah/TokEntropyDrift/internal/logger"
 	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
 )
 
@@ -19,6 +25,7 @@ type MetricResult struct {
 // AnalysisResult represents the complete analysis results for a document
 type AnalysisResult struct {
 	Document      string                         `json:"document"`
+	DocumentID    string                         `json:"document_id,omitempty"`
 	TokenizerName string                         `json:"tokenizer_name"`
 	TokenCount    int                            `json:"token_count"`
 	Metrics       map[string]MetricResult        `json:"metrics"`
@@ -26,35 +33,201 @@ type AnalysisResult struct {
 	Metadata      map[string]interface{}         `json:"metadata,omitempty"`
 }
 
+// metricBytes approximates the bytes a metric result takes in a cached analysis
+const metricBytes = 256
+
+// CacheSize returns the approximate size of the result in bytes, by which caches
+// bound their memory: that of its tokenization, which it mostly consists of, and of
+// its metrics
+func (r *AnalysisResult) CacheSize() int64 {
+	size := int64(len(r.Document)+len(r.DocumentID)) + int64(len(r.Metrics))*metricBytes
+	if r.Tokenization != nil {
+		size += r.Tokenization.CacheSize()
+	}
+	return size
+}
+
 // Engine handles metric calculations for tokenization analysis
 type Engine struct {
 	config EngineConfig
+
+	// Vocabulary sizes are looked up once per tokenizer name and configuration,
+	// since adapters may need to spawn an external process to answer
+	vocabMu    sync.Mutex
+	vocabSizes map[string]int
+
+	// numeric is compiled once from config.NumericPattern; nil when the pattern is
+	// invalid, which ValidateConfig reports
+	numeric *NumericCalculator
+
+	// Hooks adding metrics to each document, and the names of the metrics they added
+	hookMu          sync.RWMutex
+	hooks           []DocumentHook
+	hookMetricNames map[string]bool
+
+	// Results of earlier analyses, keyed by text, tokenizer and configKey; nil when
+	// not caching
+	analysesMu sync.RWMutex
+	analyses   *cache.Cache
+	configKey  string // fingerprint of config
 }
 
 // EngineConfig holds configuration for the metric engine
 type EngineConfig struct {
-	EntropyWindowSize int  `json:"entropy_window_size"`
-	NormalizeEntropy  bool `json:"normalize_entropy"`
-	CompressionRatio  bool `json:"compression_ratio"`
-	DriftDetection    bool `json:"drift_detection"`
+	EntropyWindowSize int    `json:"entropy_window_size"`
+	EntropyStride     int    `json:"entropy_stride"`
+	ByteCharCounts    bool   `json:"byte_char_counts"` // count characters as bytes instead of runes
+	NormalizeEntropy  bool   `json:"normalize_entropy"`
+	CompressionRatio  bool   `json:"compression_ratio"`
+	DriftDetection    bool   `json:"drift_detection"`
+	Perturbations     bool   `json:"perturbations"`   // tokenizes ~5x per document
+	NumericPattern    string `json:"numeric_pattern"` // regex for numeric spans; empty uses the default
+
+	NoReusePatterns  bool   `json:"no_reuse_patterns"`  // skip the reuse frequency and pattern payloads
+	NoRollingEntropy bool   `json:"no_rolling_entropy"` // skip the rolling entropy statistics
+	Profile          string `json:"profile,omitempty"`  // analysis profile the settings came from, recorded in results
+
+	// Bootstrap confidence intervals for global entropy; costs iterations × tokens per document
+	EntropyCI           bool    `json:"entropy_ci"`
+	EntropyCIIterations int     `json:"entropy_ci_iterations"` // 0 uses DefaultBootstrapIterations
+	EntropyCIConfidence float64 `json:"entropy_ci_confidence"` // 0 uses DefaultBootstrapConfidence
+	EntropyCISeed       int64   `json:"entropy_ci_seed"`       // 0 seeds from the clock
+
+	// Token sequences longer than this skip edit distance drift; 0 uses
+	// DefaultMaxEditDistanceTokens and a negative value removes the cutoff
+	MaxEditDistanceTokens int `json:"max_edit_distance_tokens"`
+
+	// Token sequences longer than this get a sampled alignment score; 0 uses
+	// DefaultMaxAlignmentTokens and a negative value always aligns exactly
+	MaxAlignmentTokens int `json:"max_alignment_tokens"`
+
+	// Window for the moving-average type-token ratio (reuse_mattr_w{N}); 0 uses DefaultMATTRWindow
+	MATTRWindow int `json:"mattr_window"`
+
+	// Tokenizers whose leading space markers (e.g. GPT-2's "Ġ") are stripped before
+	// drift compares token texts; "*" applies to all
+	StripSpaceMarkers []string `json:"strip_space_markers,omitempty"`
+
+	// Special and unknown token IDs per tokenizer name, counted by id_special_count
+	SpecialTokenIDs map[string][]int `json:"special_token_ids,omitempty"`
+
+	// Metrics to keep, by name (entropy_global) or family (entropy keeps every
+	// entropy_ metric); empty keeps all. Metrics added by hooks are always kept.
+	Metrics []string `json:"metrics,omitempty"`
+
+	// Parameters replaced for a tokenizer, by tokenizer name
+	TokenizerOverrides map[string]ParameterOverrides `json:"tokenizer_overrides,omitempty"`
+}
+
+// ParameterOverrides replaces analysis parameters for one tokenizer; nil and empty
+// fields keep the engine's
+type ParameterOverrides struct {
+	EntropyWindowSize *int     `json:"entropy_window_size,omitempty"`
+	NormalizeEntropy  *bool    `json:"normalize_entropy,omitempty"`
+	Metrics           []string `json:"metrics,omitempty"`
+}
+
+// AnalysisParameters are the parameters a result was calculated with, recorded in its
+// metadata under analysis_parameters
+type AnalysisParameters struct {
+	EntropyWindowSize int      `json:"entropy_window_size"`
+	NormalizeEntropy  bool     `json:"normalize_entropy"`
+	Metrics           []string `json:"metrics,omitempty"` // empty when every metric was kept
+	Overridden        bool     `json:"overridden"`        // whether the tokenizer has overrides
+	Profile           string   `json:"profile,omitempty"` // analysis profile, if one was chosen
+}
+
+// configFor returns the engine's configuration with the tokenizer's overrides applied,
+// and whether it has any
+func (e *Engine) configFor(tokenizerName string) (EngineConfig, bool) {
+	config := e.config
+	override, ok := e.config.TokenizerOverrides[tokenizerName]
+	if !ok {
+		return config, false
+	}
+	if override.EntropyWindowSize != nil {
+		config.EntropyWindowSize = *override.EntropyWindowSize
+	}
+	if override.NormalizeEntropy != nil {
+		config.NormalizeEntropy = *override.NormalizeEntropy
+	}
+	if len(override.Metrics) > 0 {
+		config.Metrics = override.Metrics
+	}
+	return config, true
+}
+
+// selectMetrics removes the metrics that no name or family in selection matches; an
+// empty selection keeps all
+func selectMetrics(metrics map[string]MetricResult, selection []string) {
+	if len(selection) == 0 {
+		return
+	}
+	for name := range metrics {
+		selected := false
+		for _, want := range selection {
+			if name == want || strings.HasPrefix(name, want+"_") {
+				selected = true
+				break
+			}
+		}
+		if !selected {
+			delete(metrics, name)
+		}
+	}
 }
 
 // NewEngine creates a new metric engine with the given configuration
 func NewEngine(config EngineConfig) *Engine {
+	numeric, _ := NewNumericCalculator(config.NumericPattern)
 	return &Engine{
-		config: config,
+		config:     config,
+		vocabSizes: make(map[string]int),
+		numeric:    numeric,
 	}
 }
 
-// AnalyzeDocument performs complete analysis on a single document
+// AnalyzeDocument performs complete analysis on a single document. With an analysis
+// cache set, a result analyzed before is served from it, marked under CacheHitKey; the
+// cache keeps its own copy, so the result can be changed freely either way.
 func (e *Engine) AnalyzeDocument(ctx context.Context, document string, tokenizer tokenizers.Tokenizer) (*AnalysisResult, error) {
-	// Tokenize the document
+	analyses, configKey := e.analysisCache()
+	var key string
+	if analyses != nil {
+		key = analysisKey(document, tokenizer, configKey)
+		if result, found := cachedAnalysis(ctx, analyses, key); found {
+			return result, nil
+		}
+	}
+
+	// Tokenize the document, logging to the logger of ctx
+	log := logger.FromContext(ctx).ForModule(logger.ModuleMetrics)
+	log.LogTokenizerStart(tokenizer.Name(), "")
+	start := time.Now()
 	tokenization, err := tokenizer.Tokenize(ctx, document)
 	if err != nil {
 		return nil, fmt.Errorf("error tokenizing document: %w", err)
 	}
+	log.LogTokenizerComplete(tokenizer.Name(), len(tokenization.Tokens), float64(time.Since(start).Microseconds())/1000)
+
+	result, err := e.AnalyzeTokenization(ctx, document, tokenization, tokenizer)
+	if err == nil && analyses != nil {
+		analyses.Set(key, result.Clone())
+	}
+	return result, err
+}
 
-	// Calculate metrics
+// AnalyzeTokenization calculates the metrics of a document that tokenizer has already
+// tokenized, so tokenizing and analysis can run as separate stages. The tokenizer is
+// still used for its name, vocabulary size and pricing, and to re-tokenize variants
+// when perturbations are enabled.
+func (e *Engine) AnalyzeTokenization(ctx context.Context, document string, tokenization *tokenizers.TokenizationResult, tokenizer tokenizers.Tokenizer) (*AnalysisResult, error) {
+	if tokenization == nil {
+		return nil, fmt.Errorf("no tokenization to analyze")
+	}
+
+	// Calculate metrics with the tokenizer's parameters
+	config, overridden := e.configFor(tokenizer.Name())
 	metrics := make(map[string]MetricResult)
 
 	// Token count
@@ -66,7 +239,7 @@ func (e *Engine) AnalyzeDocument(ctx context.Context, document string, tokenizer
 	}
 
 	// Enhanced entropy calculations
-	entropyCalc := NewEntropyCalculator(e.config.EntropyWindowSize, e.config.NormalizeEntropy)
+	entropyCalc := newEntropyCalculator(config)
 	if entropyStats, err := entropyCalc.CalculateEntropyStats(tokenization.Tokens); err == nil {
 		for metricName, value := range entropyStats {
 			metrics["entropy_"+metricName] = MetricResult{
@@ -77,9 +250,24 @@ func (e *Engine) AnalyzeDocument(ctx context.Context, document string, tokenizer
 		}
 	}
 
+	if config.EntropyCI {
+		if ciStats, err := entropyCalc.CalculateEntropyWithCI(tokenization.Tokens, config.EntropyCIIterations, config.EntropyCIConfidence); err == nil {
+			for metricName, value := range ciStats {
+				metrics["entropy_"+metricName] = MetricResult{
+					MetricName:    "entropy_" + metricName,
+					TokenizerName: tokenizer.Name(),
+					Value:         value,
+				}
+			}
+		}
+	}
+
 	// Enhanced compression calculations
+	// Encoding bounds and redundancy need the unnormalized entropy in bits
+	rawEntropy, _ := NewEntropyCalculator(config.EntropyWindowSize, false).CalculateGlobalEntropy(tokenization.Tokens)
 	compressionCalc := NewCompressionCalculator(true)
-	if compressionStats, err := compressionCalc.CalculateCompressionStats(document, tokenization.Tokens, 0.0); err == nil {
+	compressionCalc.SetByteCounts(config.ByteCharCounts)
+	if compressionStats, err := compressionCalc.CalculateCompressionStats(document, tokenization.Tokens, rawEntropy); err == nil {
 		for metricName, value := range compressionStats {
 			metrics["compression_"+metricName] = MetricResult{
 				MetricName:    "compression_" + metricName,
@@ -89,43 +277,251 @@ func (e *Engine) AnalyzeDocument(ctx context.Context, document string, tokenizer
 		}
 	}
 
-	// Enhanced reuse calculations
-	reuseCalc := NewReuseCalculator(true)
+	// Enhanced reuse calculations; the structured frequency and pattern payloads
+	// ride along on the reuse ratio metric
+	reuseCalc := NewReuseCalculator(!config.NoReusePatterns)
+	if config.MATTRWindow > 0 {
+		reuseCalc.SetMATTRWindow(config.MATTRWindow)
+	}
 	if reuseStats, err := reuseCalc.CalculateReuseStats(tokenization.Tokens); err == nil {
-		// Convert interface{} values to float64 for metrics
-		for metricName, value := range reuseStats {
-			if floatValue, ok := value.(float64); ok {
-				metrics["reuse_"+metricName] = MetricResult{
-					MetricName:    "reuse_" + metricName,
+		for metricName, value := range reuseStats.Metrics() {
+			metrics["reuse_"+metricName] = MetricResult{
+				MetricName:    "reuse_" + metricName,
+				TokenizerName: tokenizer.Name(),
+				Value:         value,
+			}
+		}
+
+		payload := make(map[string]interface{})
+		if reuseStats.Frequency != nil {
+			payload["frequency"] = reuseStats.Frequency
+			payload["most_frequent_tokens"] = reuseStats.Frequency.MostFrequentTokens
+		}
+		if reuseStats.Patterns != nil {
+			payload["patterns"] = reuseStats.Patterns
+		}
+		// Flag MATTR values that fell back to the plain TTR of a short document
+		if diversity := reuseStats.Diversity; diversity != nil && diversity.PlainTTR {
+			name := "reuse_" + diversity.MetricName()
+			mattr := metrics[name]
+			mattr.Metadata = map[string]interface{}{
+				"plain_ttr": true,
+				"window":    diversity.MATTRWindow,
+			}
+			metrics[name] = mattr
+		}
+
+		if len(payload) > 0 {
+			ratio := metrics["reuse_reuse_ratio"]
+			ratio.Metadata = payload
+			metrics["reuse_reuse_ratio"] = ratio
+		}
+	}
+
+	// Word fertility calculations
+	fertilityCalc := NewFertilityCalculator()
+	if fertilityStats, err := fertilityCalc.CalculateFertilityStats(document, tokenization.Tokens); err == nil {
+		alignment := "offsets"
+		if !hasTokenOffsets(tokenization.Tokens) {
+			alignment = "text_match"
+		}
+		for metricName, value := range fertilityStats {
+			metrics["fertility_"+metricName] = MetricResult{
+				MetricName:    "fertility_" + metricName,
+				TokenizerName: tokenizer.Name(),
+				Value:         value,
+				Metadata: map[string]interface{}{
+					"alignment": alignment,
+				},
+			}
+		}
+	}
+
+	// Numeric literal splitting
+	if e.numeric != nil {
+		if numericStats, err := e.numeric.CalculateNumericStats(document, tokenization.Tokens); err == nil {
+			for metricName, value := range numericStats {
+				metrics["numeric_"+metricName] = MetricResult{
+					MetricName:    "numeric_" + metricName,
+					TokenizerName: tokenizer.Name(),
+					Value:         value,
+				}
+			}
+		}
+	}
+
+	// Word, punctuation and whitespace composition of the tokens
+	compositionCalc := NewCompositionCalculator()
+	if compositionStats, err := compositionCalc.CalculateCompositionStats(document, tokenization.Tokens); err == nil {
+		for metricName, value := range compositionStats {
+			metrics["composition_"+metricName] = MetricResult{
+				MetricName:    "composition_" + metricName,
+				TokenizerName: tokenizer.Name(),
+				Value:         value,
+			}
+		}
+	}
+
+	// Perturbation sensitivity, opt-in since it re-tokenizes each variant
+	if config.Perturbations {
+		if perturbationStats, err := e.analyzePerturbations(ctx, document, tokenization.Tokens, tokenizer); err == nil {
+			for metricName, value := range perturbationStats {
+				metrics["perturbation_"+metricName] = MetricResult{
+					MetricName:    "perturbation_" + metricName,
 					TokenizerName: tokenizer.Name(),
-					Value:         floatValue,
+					Value:         value,
 				}
 			}
 		}
 	}
 
-	return &AnalysisResult{
+	// Vocabulary coverage; a failing vocab size lookup is recorded but not fatal
+	metadata := make(map[string]interface{})
+	vocabSize, vocabErr := e.vocabSize(tokenizer)
+	if vocabErr != nil {
+		metadata["vocab_size_error"] = vocabErr.Error()
+	}
+	coverageCalc := NewCoverageCalculator(vocabSize)
+	if coverageStats, err := coverageCalc.CalculateCoverageStats(tokenization.Tokens); err == nil {
+		for metricName, value := range coverageStats {
+			metrics[metricName] = MetricResult{
+				MetricName:    metricName,
+				TokenizerName: tokenizer.Name(),
+				Value:         value,
+			}
+		}
+	}
+
+	// Token ID distribution, skipped by the calculator when the adapter reports no IDs
+	idCalc := NewIDStatsCalculator(vocabSize, config.SpecialTokenIDs[tokenizer.Name()])
+	if idStats, err := idCalc.CalculateIDStats(tokenization.Tokens); err == nil {
+		for metricName, value := range idStats {
+			metrics["id_"+metricName] = MetricResult{
+				MetricName:    "id_" + metricName,
+				TokenizerName: tokenizer.Name(),
+				Value:         value,
+			}
+		}
+	}
+
+	// Cost and context window usage, only for tokenizers that report pricing
+	if priced, ok := tokenizer.(tokenizers.PricedTokenizer); ok {
+		if pricing, err := priced.Pricing(); err != nil {
+			metadata["pricing_error"] = err.Error()
+		} else {
+			if pricing.PricePer1KTokens > 0 {
+				metrics["cost_estimate_usd"] = MetricResult{
+					MetricName:    "cost_estimate_usd",
+					TokenizerName: tokenizer.Name(),
+					Value:         float64(tokenCount) / 1000.0 * pricing.PricePer1KTokens,
+					Metadata: map[string]interface{}{
+						"price_per_1k_tokens": pricing.PricePer1KTokens,
+					},
+				}
+			}
+			if pricing.ContextWindow > 0 {
+				metrics["context_window_utilization"] = MetricResult{
+					MetricName:    "context_window_utilization",
+					TokenizerName: tokenizer.Name(),
+					Value:         float64(tokenCount) / float64(pricing.ContextWindow),
+					Metadata: map[string]interface{}{
+						"context_window":  pricing.ContextWindow,
+						"exceeds_context": tokenCount > pricing.ContextWindow,
+					},
+				}
+			}
+		}
+	}
+
+	selectMetrics(metrics, config.Metrics)
+	metadata["analysis_parameters"] = AnalysisParameters{
+		EntropyWindowSize: config.EntropyWindowSize,
+		NormalizeEntropy:  config.NormalizeEntropy,
+		Metrics:           config.Metrics,
+		Overridden:        overridden,
+		Profile:           config.Profile,
+	}
+
+	result := &AnalysisResult{
 		Document:      document,
 		TokenizerName: tokenizer.Name(),
 		TokenCount:    tokenCount,
 		Metrics:       metrics,
 		Tokenization:  tokenization,
-	}, nil
+		Metadata:      metadata,
+	}
+
+	// Metrics from hooks such as plugins
+	e.runHooks(ctx, result)
+	logMetrics(logger.FromContext(ctx).ForModule(logger.ModuleMetrics), result)
+
+	return result, nil
+}
+
+// logMetrics logs the metrics of a result in name order
+func logMetrics(log *logger.Logger, result *AnalysisResult) {
+	names := make([]string, 0, len(result.Metrics))
+	for name := range result.Metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		log.LogMetricCalculation(name, result.TokenizerName, result.Metrics[name].Value)
+	}
+}
+
+// vocabSize returns the tokenizer's vocabulary size, querying it only once per name
+// and configuration fingerprint. Failures are not cached, so a backend that becomes
+// available is asked again on the next document.
+func (e *Engine) vocabSize(tokenizer tokenizers.Tokenizer) (int, error) {
+	key := tokenizer.Name() + "@" + tokenizer.ConfigFingerprint()
+
+	e.vocabMu.Lock()
+	defer e.vocabMu.Unlock()
+
+	if size, exists := e.vocabSizes[key]; exists {
+		return size, nil
+	}
+
+	size, err := tokenizer.GetVocabSize()
+	if err != nil {
+		return 0, fmt.Errorf("error getting vocab size for %s: %w", tokenizer.Name(), err)
+	}
+
+	if e.vocabSizes == nil {
+		e.vocabSizes = make(map[string]int)
+	}
+	e.vocabSizes[key] = size
+
+	return size, nil
 }
 
-// AnalyzeBatch performs analysis on multiple documents
-func (e *Engine) AnalyzeBatch(ctx context.Context, documents []string, tokenizer tokenizers.Tokenizer) ([]*AnalysisResult, error) {
-	var results []*AnalysisResult
+// AnalyzeBatch performs analysis on multiple documents. Documents that fail are
+// recorded in the result's Errors and the batch continues. The context is checked
+// between documents; if it ends, the documents analyzed so far are returned together
+// with the context's error and the rest are counted as Skipped.
+func (e *Engine) AnalyzeBatch(ctx context.Context, documents []string, tokenizer tokenizers.Tokenizer) (*BatchResult, error) {
+	batch := &BatchResult{
+		Results: make([]*AnalysisResult, 0, len(documents)),
+	}
+
+	for i, document := range documents {
+		select {
+		case <-ctx.Done():
+			batch.Skipped = len(documents) - i
+			return batch, ctx.Err()
+		default:
+		}
 
-	for _, document := range documents {
 		result, err := e.AnalyzeDocument(ctx, document, tokenizer)
 		if err != nil {
-			return nil, fmt.Errorf("error analyzing document: %w", err)
+			batch.Errors = append(batch.Errors, &DocumentError{Index: i, Err: err})
+			continue
 		}
-		results = append(results, result)
+		batch.Results = append(batch.Results, result)
 	}
 
-	return results, nil
+	return batch, nil
 }
 
 // CalculateEntropy calculates Shannon entropy for the given tokens
@@ -164,27 +560,7 @@ func (e *Engine) CalculateEntropy(tokens []tokenizers.Token) (float64, error) {
 
 // CalculateRollingEntropy calculates entropy over sliding windows
 func (e *Engine) CalculateRollingEntropy(tokens []tokenizers.Token) ([]float64, error) {
-	if len(tokens) == 0 {
-		return []float64{}, nil
-	}
-
-	windowSize := e.config.EntropyWindowSize
-	if windowSize <= 0 {
-		windowSize = 100 // Default window size
-	}
-
-	var rollingEntropy []float64
-
-	for i := 0; i <= len(tokens)-windowSize; i++ {
-		windowTokens := tokens[i : i+windowSize]
-		entropy, err := e.CalculateEntropy(windowTokens)
-		if err != nil {
-			return nil, err
-		}
-		rollingEntropy = append(rollingEntropy, entropy)
-	}
-
-	return rollingEntropy, nil
+	return newEntropyCalculator(e.config).CalculateRollingEntropy(tokens)
 }
 
 // CalculateCompressionRatio calculates the compression ratio
@@ -288,15 +664,73 @@ func (e *Engine) calculateJaccardDistance(tokens1, tokens2 []string) float64 {
 	return 1.0 - jaccardSimilarity
 }
 
-// GetMetricNames returns the list of available metrics
+// newEntropyCalculator creates an entropy calculator from an engine configuration
+func newEntropyCalculator(config EngineConfig) *EntropyCalculator {
+	calc := NewEntropyCalculator(config.EntropyWindowSize, config.NormalizeEntropy)
+	calc.SetStride(config.EntropyStride)
+	calc.SetByteCounts(config.ByteCharCounts)
+	calc.SetRollingStats(!config.NoRollingEntropy)
+	if config.EntropyCISeed != 0 {
+		calc.SetSeed(config.EntropyCISeed)
+	}
+	return calc
+}
+
+// newDriftCalculator creates a drift calculator honoring the engine's length cutoffs
+// and space marker normalization
+func (e *Engine) newDriftCalculator() *DriftCalculator {
+	calc := NewDriftCalculator(0.5)
+	if e.config.MaxEditDistanceTokens != 0 {
+		calc.SetMaxEditDistanceTokens(e.config.MaxEditDistanceTokens)
+	}
+	if e.config.MaxAlignmentTokens != 0 {
+		calc.SetMaxAlignmentTokens(e.config.MaxAlignmentTokens)
+	}
+	if len(e.config.StripSpaceMarkers) > 0 {
+		calc.SetStripSpaceMarkers(e.config.StripSpaceMarkers...)
+	}
+	return calc
+}
+
+// GetMetricNames returns the list of available document metrics, followed by the
+// metrics that document hooks such as plugins have added so far
 func (e *Engine) GetMetricNames() []string {
+	return append(e.builtinMetricNames(), e.addedMetricNames()...)
+}
+
+// builtinMetricNames returns the metrics the engine itself calculates
+func (e *Engine) builtinMetricNames() []string {
+	// The MATTR key carries the configured window
+	mattrWindow := e.config.MATTRWindow
+	if mattrWindow <= 0 {
+		mattrWindow = DefaultMATTRWindow
+	}
+	mattr := &DiversityStats{MATTRWindow: mattrWindow}
+
 	return []string{
 		"token_count",
+		"unique_token_ids",
+		"vocab_coverage",
+		"max_token_id_ratio",
+		"id_entropy",
+		"id_mean",
+		"id_median",
+		"id_above_50pct_vocab",
+		"id_above_90pct_vocab",
+		"id_special_count",
+		"cost_estimate_usd",
+		"context_window_utilization",
 		"entropy_global_entropy",
 		"entropy_bigram_entropy",
+		"entropy_trigram_entropy",
 		"entropy_vocab_normalized_entropy",
 		"entropy_token_normalized_entropy",
 		"entropy_char_normalized_entropy",
+		"entropy_char_normalized_entropy_bytes",
+		"entropy_char_normalized_entropy_runes",
+		"entropy_ci_mean",
+		"entropy_ci_lower",
+		"entropy_ci_upper",
 		"entropy_rolling_entropy_mean",
 		"entropy_rolling_entropy_std",
 		"compression_compression_ratio",
@@ -305,53 +739,136 @@ func (e *Engine) GetMetricNames() []string {
 		"compression_avg_token_size",
 		"compression_token_density",
 		"compression_char_density",
+		"compression_char_density_bytes",
+		"compression_char_density_runes",
+		"compression_token_density_runes",
+		"compression_gzip_size",
+		"compression_gzip_ratio",
+		"compression_tokenization_vs_gzip",
+		"compression_encoding_varint_size",
+		"compression_encoding_varint_ratio",
+		"compression_encoding_entropy_bound_size",
+		"compression_encoding_entropy_bound_ratio",
 		"reuse_reuse_ratio",
 		"reuse_vocabulary_efficiency",
 		"reuse_reuse_efficiency",
 		"reuse_entropy_efficiency",
 		"reuse_compression_efficiency",
+		"reuse_zipf_exponent",
+		"reuse_zipf_r2",
+		"reuse_top10_mass",
+		"reuse_top100_mass",
+		"reuse_mtld",
+		"reuse_" + mattr.MetricName(),
+		"fertility_word_count",
+		"fertility_mean_tokens_per_word",
+		"fertility_median_tokens_per_word",
+		"fertility_max_tokens_per_word",
+		"fertility_whole_word_ratio",
+		"fertility_split_3plus_ratio",
+		"composition_word_ratio",
+		"composition_punctuation_ratio",
+		"composition_whitespace_bearing_ratio",
+		"composition_mixed_ratio",
+		"composition_boundary_spanning_count",
+		"composition_boundary_spanning_ratio",
+		"numeric_number_count",
+		"numeric_avg_tokens_per_number",
+		"numeric_single_token_ratio",
+		"numeric_per_digit_ratio",
+		"perturbation_lowercase_token_delta",
+		"perturbation_lowercase_jaccard",
+		"perturbation_nfc_token_delta",
+		"perturbation_nfc_jaccard",
+		"perturbation_nfd_token_delta",
+		"perturbation_nfd_jaccard",
+		"perturbation_whitespace_token_delta",
+		"perturbation_whitespace_jaccard",
+	}
+}
+
+// GetDriftMetricNames returns the metrics CompareTokenizers reports for each pair of
+// tokenizers in TokenizerPair.Drift. They compare two tokenizations, so unlike the
+// metrics of GetMetricNames they never appear in a document's AnalysisResult.
+func (e *Engine) GetDriftMetricNames() []string {
+	return []string{
 		"drift_jaccard_distance",
 		"drift_alignment_score",
-		"drift_position_drift",
-		"drift_length_drift",
-		"drift_content_similarity",
+		"drift_alignment_approximate",
+		"drift_alignment_position_drift",
+		"drift_alignment_length_drift",
+		"drift_alignment_content_similarity",
+		"drift_token_count_drift",
+		"drift_avg_length_drift",
+		"drift_vocab_overlap",
+		"drift_kl_divergence",
+		"drift_js_divergence",
+		"drift_edit_distance",
+		"drift_normalized_edit_distance",
+		"drift_boundary_precision",
+		"drift_boundary_recall",
+		"drift_boundary_f1",
+		"drift_boundary_unique_1",
+		"drift_boundary_unique_2",
 	}
 }
 
 // CompareTokenizers performs cross-tokenizer comparison analysis
-func (e *Engine) CompareTokenizers(ctx context.Context, document string, tokenizers []tokenizers.Tokenizer) (map[string]interface{}, error) {
+func (e *Engine) CompareTokenizers(ctx context.Context, document string, tokenizers []tokenizers.Tokenizer) (*ComparisonResult, error) {
 	if len(tokenizers) < 2 {
 		return nil, fmt.Errorf("at least 2 tokenizers required for comparison")
 	}
 
 	// Analyze document with each tokenizer
 	results := make([]*AnalysisResult, len(tokenizers))
+	names := make([]string, len(tokenizers))
 	for i, tokenizer := range tokenizers {
 		result, err := e.AnalyzeDocument(ctx, document, tokenizer)
 		if err != nil {
 			return nil, fmt.Errorf("error analyzing with tokenizer %s: %w", tokenizer.Name(), err)
 		}
 		results[i] = result
+		names[i] = result.TokenizerName
 	}
 
 	// Calculate drift between tokenizers
-	driftCalc := NewDriftCalculator(0.5)
-	comparison := make(map[string]interface{})
+	driftCalc := e.newDriftCalculator()
+	comparison := &ComparisonResult{
+		Tokenizers: names,
+		Pairs:      make([]TokenizerPair, 0, len(results)*(len(results)-1)/2),
+		Results:    results,
+	}
 
 	// Compare each pair of tokenizers
 	for i := 0; i < len(results); i++ {
 		for j := i + 1; j < len(results); j++ {
-			pairName := fmt.Sprintf("%s_vs_%s", results[i].TokenizerName, results[j].TokenizerName)
+			driftStats, err := driftCalc.CalculateDriftStats(results[i].Tokenization, results[j].Tokenization)
+			if err != nil {
+				continue
+			}
+
+			pair := TokenizerPair{
+				A:     results[i].TokenizerName,
+				B:     results[j].TokenizerName,
+				Drift: driftStats,
+			}
 
-			if driftStats, err := driftCalc.CalculateDriftStats(results[i].Tokenization, results[j].Tokenization); err == nil {
-				comparison[pairName] = driftStats
+			// Record why boundary and edit distance metrics are absent rather than reporting zeros
+			notes := make(map[string]interface{})
+			if !hasTokenOffsets(results[i].Tokenization.Tokens) || !hasTokenOffsets(results[j].Tokenization.Tokens) {
+				notes["boundary_metrics"] = "skipped: " + ErrMissingOffsets.Error()
+			}
+			if driftCalc.exceedsEditDistanceCutoff(len(results[i].Tokenization.Tokens), len(results[j].Tokenization.Tokens)) {
+				notes["edit_distance"] = fmt.Sprintf("skipped: %s (limit %d)", ErrSequenceTooLong, driftCalc.maxEditDistanceTokens)
+			}
+			if len(notes) > 0 {
+				pair.Metadata = notes
 			}
+
+			comparison.Pairs = append(comparison.Pairs, pair)
 		}
 	}
 
-	// Add individual results
-	comparison["individual_results"] = results
-
 	return comparison, nil
 }
 
@@ -361,5 +878,31 @@ func (e *Engine) ValidateConfig() error {
 		return fmt.Errorf("entropy window size must be non-negative")
 	}
 
+	if e.config.EntropyStride < 0 {
+		return fmt.Errorf("entropy stride must be non-negative")
+	}
+
+	if _, err := NewNumericCalculator(e.config.NumericPattern); err != nil {
+		return err
+	}
+
+	if e.config.MATTRWindow < 0 {
+		return fmt.Errorf("MATTR window must be non-negative")
+	}
+
+	if e.config.EntropyCIIterations < 0 {
+		return fmt.Errorf("entropy CI iterations must be non-negative")
+	}
+
+	if c := e.config.EntropyCIConfidence; c < 0 || c >= 1 {
+		return fmt.Errorf("entropy CI confidence must be in [0, 1)")
+	}
+
+	for name, override := range e.config.TokenizerOverrides {
+		if override.EntropyWindowSize != nil && *override.EntropyWindowSize < 0 {
+			return fmt.Errorf("entropy window size for %s must be non-negative", name)
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,176 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/logger"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+func TestAnalyzeTokenizationMatchesAnalyzeDocument(t *testing.T) {
+	engine := NewEngine(EngineConfig{EntropyWindowSize: 10})
+	tokenizer := newFailingTokenizer(t, "")
+	document := "the cat sat on the mat 42 times."
+
+	want, err := engine.AnalyzeDocument(context.Background(), document, tokenizer)
+	if err != nil {
+		t.Fatalf("AnalyzeDocument returned error: %v", err)
+	}
+
+	tokenization, err := tokenizer.MockTokenizer.Tokenize(context.Background(), document)
+	if err != nil {
+		t.Fatalf("Tokenize returned error: %v", err)
+	}
+	calls := tokenizer.calls
+	got, err := engine.AnalyzeTokenization(context.Background(), document, tokenization, tokenizer)
+	if err != nil {
+		t.Fatalf("AnalyzeTokenization returned error: %v", err)
+	}
+
+	if tokenizer.calls != calls {
+		t.Error("AnalyzeTokenization tokenized the document again")
+	}
+	if got.TokenCount != want.TokenCount || len(got.Metrics) != len(want.Metrics) {
+		t.Fatalf("got %d tokens and %d metrics, want %d and %d", got.TokenCount, len(got.Metrics), want.TokenCount, len(want.Metrics))
+	}
+	for name, metric := range want.Metrics {
+		// Some metrics sum over maps, so allow for summation order
+		if math.Abs(got.Metrics[name].Value-metric.Value) > floatTolerance {
+			t.Errorf("%s = %v, want %v", name, got.Metrics[name].Value, metric.Value)
+		}
+	}
+	if got.Tokenization != tokenization {
+		t.Error("the result should carry the given tokenization")
+	}
+
+	if _, err := engine.AnalyzeTokenization(context.Background(), document, nil, tokenizers.NewMockTokenizer("mock")); err == nil {
+		t.Error("expected an error for a missing tokenization")
+	}
+}
+
+func TestTokenizerOverrides(t *testing.T) {
+	window, normalize := 2000, false
+	engine := NewEngine(EngineConfig{
+		EntropyWindowSize: 10,
+		NormalizeEntropy:  true,
+		TokenizerOverrides: map[string]ParameterOverrides{
+			"char": {EntropyWindowSize: &window, NormalizeEntropy: &normalize, Metrics: []string{"token_count", "entropy"}},
+		},
+	})
+	document := "the cat sat on the mat and the dog sat on the log"
+
+	char := tokenizers.NewCharTokenizer("char")
+	if err := char.Initialize(tokenizers.TokenizerConfig{Name: "char"}); err != nil {
+		t.Fatal(err)
+	}
+	overridden, err := engine.AnalyzeDocument(context.Background(), document, char)
+	if err != nil {
+		t.Fatalf("AnalyzeDocument returned error: %v", err)
+	}
+	defaults, err := engine.AnalyzeDocument(context.Background(), document, tokenizers.NewMockTokenizer("mock"))
+	if err != nil {
+		t.Fatalf("AnalyzeDocument returned error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		result *AnalysisResult
+		want   AnalysisParameters
+	}{
+		{"overridden", overridden, AnalysisParameters{EntropyWindowSize: 2000, NormalizeEntropy: false, Metrics: []string{"token_count", "entropy"}, Overridden: true}},
+		{"defaults", defaults, AnalysisParameters{EntropyWindowSize: 10, NormalizeEntropy: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.result.Metadata["analysis_parameters"].(AnalysisParameters)
+			if !ok || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("analysis_parameters = %+v, want %+v", tt.result.Metadata["analysis_parameters"], tt.want)
+			}
+		})
+	}
+
+	for name := range overridden.Metrics {
+		if name != "token_count" && !strings.HasPrefix(name, "entropy_") {
+			t.Errorf("metric %s should not be selected", name)
+		}
+	}
+	if _, ok := overridden.Metrics["entropy_global_entropy"]; !ok {
+		t.Error("the entropy family should be selected")
+	}
+	if _, ok := defaults.Metrics["compression_compression_ratio"]; !ok {
+		t.Error("tokenizers without overrides should keep every metric")
+	}
+	// Without normalization, entropy is in bits rather than at most 1
+	if entropy := overridden.Metrics["entropy_global_entropy"].Value; entropy <= 1 {
+		t.Errorf("unnormalized entropy = %v, want bits above 1", entropy)
+	}
+
+	negative := -1
+	invalid := NewEngine(EngineConfig{TokenizerOverrides: map[string]ParameterOverrides{"char": {EntropyWindowSize: &negative}}})
+	if err := invalid.ValidateConfig(); err == nil {
+		t.Error("expected an error for a negative override window")
+	}
+}
+
+func TestEngineSkipsPatternsAndRollingEntropy(t *testing.T) {
+	document := "the cat sat on the mat and the dog sat on the log"
+	analyze := func(config EngineConfig) *AnalysisResult {
+		t.Helper()
+		result, err := NewEngine(config).AnalyzeDocument(context.Background(), document, tokenizers.NewMockTokenizer("mock"))
+		if err != nil {
+			t.Fatalf("AnalyzeDocument returned error: %v", err)
+		}
+		return result
+	}
+
+	full := analyze(EngineConfig{EntropyWindowSize: 3})
+	if _, ok := full.Metrics["entropy_rolling_entropy_mean"]; !ok {
+		t.Fatal("expected rolling entropy by default")
+	}
+	if full.Metrics["reuse_reuse_ratio"].Metadata == nil {
+		t.Fatal("expected reuse pattern payloads by default")
+	}
+
+	quick := analyze(EngineConfig{EntropyWindowSize: 3, NoRollingEntropy: true, NoReusePatterns: true, Profile: "quick"})
+	if _, ok := quick.Metrics["entropy_rolling_entropy_mean"]; ok {
+		t.Error("rolling entropy should be skipped")
+	}
+	if payload := quick.Metrics["reuse_reuse_ratio"].Metadata; payload["patterns"] != nil {
+		t.Errorf("reuse patterns should be skipped, got %v", payload)
+	}
+	if parameters, _ := quick.Metadata["analysis_parameters"].(AnalysisParameters); parameters.Profile != "quick" {
+		t.Errorf("analysis_parameters = %+v, want the quick profile", quick.Metadata["analysis_parameters"])
+	}
+}
+
+func TestAnalyzeDocumentLogsToContext(t *testing.T) {
+	log, err := logger.New("debug", "text", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	ctx := logger.NewContext(context.Background(), log.WithRequestID("req-1"))
+
+	engine := NewEngine(EngineConfig{EntropyWindowSize: 10})
+	if _, err := engine.AnalyzeDocument(ctx, "the cat sat on the mat", tokenizers.NewMockTokenizer("mock")); err != nil {
+		t.Fatalf("AnalyzeDocument returned error: %v", err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{"event=tokenizer_start", "event=tokenizer_complete", "metric_name=token_count"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("log is missing %q:\n%s", want, output)
+		}
+	}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if !strings.Contains(line, "request_id=req-1") {
+			t.Errorf("line %q lacks the request ID", line)
+		}
+	}
+}
@@ -1,25 +1,42 @@
 package metrics
 
 import (
+	"fmt"
 	"math"
+	"math/rand"
+	"sort"
+	"time"
+	"unicode/utf8"
 
+	"github.com/RevBooyah/TokEntropyDrift/internal/stats"
 	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
 )
 
 // EntropyCalculator handles various entropy calculations
 type EntropyCalculator struct {
 	windowSize int
+	stride     int
 	normalize  bool
+	byteCounts bool
+	noRolling  bool
+	rng        *rand.Rand
 }
 
 // NewEntropyCalculator creates a new entropy calculator
 func NewEntropyCalculator(windowSize int, normalize bool) *EntropyCalculator {
 	return &EntropyCalculator{
 		windowSize: windowSize,
+		stride:     1,
 		normalize:  normalize,
 	}
 }
 
+// SetRollingStats sets whether CalculateEntropyStats includes the rolling entropy
+// statistics, which it does unless turned off
+func (e *EntropyCalculator) SetRollingStats(enabled bool) {
+	e.noRolling = !enabled
+}
+
 // CalculateGlobalEntropy calculates Shannon entropy over the entire token sequence
 func (e *EntropyCalculator) CalculateGlobalEntropy(tokens []tokenizers.Token) (float64, error) {
 	if len(tokens) == 0 {
@@ -27,100 +44,306 @@ func (e *EntropyCalculator) CalculateGlobalEntropy(tokens []tokenizers.Token) (f
 	}
 
 	// Count token frequencies
-	tokenFreq := make(map[string]int)
+	freq := newSlidingFrequency()
 	for _, token := range tokens {
-		tokenFreq[token.Text]++
+		freq.add(token.Text)
 	}
 
-	// Calculate entropy
-	entropy := 0.0
-	totalTokens := float64(len(tokens))
+	return e.windowEntropy(freq, len(tokens)), nil
+}
+
+// CalculateRollingEntropy calculates entropy over sliding windows. The window's token
+// frequencies are updated incrementally as it advances, so the cost is linear in the
+// number of tokens rather than proportional to tokens × window size. When a stride
+// greater than one is configured, only every stride-th window position is reported.
+// Only integer counts are updated incrementally, so each value equals
+// CalculateGlobalEntropy over the same window exactly.
+func (e *EntropyCalculator) CalculateRollingEntropy(tokens []tokenizers.Token) ([]float64, error) {
+	if len(tokens) == 0 {
+		return []float64{}, nil
+	}
+
+	windowSize, stride := e.RollingWindow(len(tokens))
+
+	window := newSlidingFrequency()
+	for _, token := range tokens[:windowSize] {
+		window.add(token.Text)
+	}
+
+	windowCount := len(tokens) - windowSize + 1
+	rollingEntropy := make([]float64, 0, (windowCount+stride-1)/stride)
+
+	for start := 0; start < windowCount; start++ {
+		if start%stride == 0 {
+			rollingEntropy = append(rollingEntropy, e.windowEntropy(window, windowSize))
+		}
+
+		if start+windowSize >= len(tokens) {
+			break
+		}
+
+		window.remove(tokens[start].Text)
+		window.add(tokens[start+windowSize].Text)
+	}
+
+	return rollingEntropy, nil
+}
+
+// RollingWindow returns the window size and stride CalculateRollingEntropy uses for a
+// sequence of tokenCount tokens; the i-th value it reports covers the window starting
+// at token i*stride
+func (e *EntropyCalculator) RollingWindow(tokenCount int) (windowSize, stride int) {
+	windowSize = e.windowSize
+	if windowSize <= 0 {
+		windowSize = 100 // Default window size
+	}
+
+	if windowSize > tokenCount {
+		windowSize = tokenCount
+	}
+
+	stride = e.stride
+	if stride <= 0 {
+		stride = 1
+	}
+	return windowSize, stride
+}
+
+// SetStride sets the step between reported rolling entropy windows
+func (e *EntropyCalculator) SetStride(stride int) {
+	e.stride = stride
+}
+
+// SetByteCounts selects whether character counts use bytes instead of runes
+func (e *EntropyCalculator) SetByteCounts(byteCounts bool) {
+	e.byteCounts = byteCounts
+}
+
+// SetSeed seeds the random source used for bootstrap resampling, making
+// confidence intervals reproducible
+func (e *EntropyCalculator) SetSeed(seed int64) {
+	e.rng = rand.New(rand.NewSource(seed))
+}
+
+// Default bootstrap settings used when CalculateEntropyWithCI receives zero values
+const (
+	DefaultBootstrapIterations = 1000
+	DefaultBootstrapConfidence = 0.95
+)
+
+// CalculateEntropyWithCI estimates a confidence interval for global entropy by
+// bootstrap resampling: tokens are drawn with replacement, global entropy is
+// recomputed for each sample, and the percentile interval of those values is
+// reported as ci_lower/ci_upper alongside their mean (ci_mean). The cost is
+// proportional to iterations × token count.
+func (e *EntropyCalculator) CalculateEntropyWithCI(tokens []tokenizers.Token, iterations int, confidence float64) (map[string]float64, error) {
+	if iterations == 0 {
+		iterations = DefaultBootstrapIterations
+	}
+	if confidence == 0 {
+		confidence = DefaultBootstrapConfidence
+	}
+	if iterations < 0 {
+		return nil, fmt.Errorf("bootstrap iterations must be positive, got %d", iterations)
+	}
+	if confidence <= 0 || confidence >= 1 {
+		return nil, fmt.Errorf("bootstrap confidence must be between 0 and 1, got %v", confidence)
+	}
 
-	for _, freq := range tokenFreq {
-		probability := float64(freq) / totalTokens
-		if probability > 0 {
-			entropy -= probability * math.Log2(probability)
+	metrics := make(map[string]float64)
+	if len(tokens) == 0 {
+		return metrics, nil
+	}
+
+	if e.rng == nil {
+		e.SetSeed(time.Now().UnixNano())
+	}
+
+	// Resample token type indices rather than tokens so each iteration only
+	// touches a counts slice
+	typeIndex := make(map[string]int)
+	types := make([]int, len(tokens))
+	for i, token := range tokens {
+		index, exists := typeIndex[token.Text]
+		if !exists {
+			index = len(typeIndex)
+			typeIndex[token.Text] = index
+		}
+		types[i] = index
+	}
+
+	counts := make([]int, len(typeIndex))
+	samples := make([]float64, iterations)
+	for i := range samples {
+		for j := range counts {
+			counts[j] = 0
+		}
+		for range tokens {
+			counts[types[e.rng.Intn(len(types))]]++
 		}
+		samples[i] = e.countsEntropy(counts, len(tokens))
+	}
+
+	alpha := (1 - confidence) / 2 * 100
+	bounds := stats.Percentiles(samples, alpha, 100-alpha)
+
+	metrics["ci_mean"] = stats.Mean(samples)
+	metrics["ci_lower"] = bounds[0]
+	metrics["ci_upper"] = bounds[1]
+
+	return metrics, nil
+}
+
+// countsEntropy computes the entropy of a token count distribution, applying the
+// same normalization as CalculateGlobalEntropy
+func (e *EntropyCalculator) countsEntropy(counts []int, total int) float64 {
+	entropy := 0.0
+	observed := 0
+	for _, count := range counts {
+		if count == 0 {
+			continue
+		}
+		observed++
+		probability := float64(count) / float64(total)
+		entropy -= probability * math.Log2(probability)
 	}
 
-	// Normalize if requested
 	if e.normalize {
-		maxEntropy := math.Log2(float64(len(tokenFreq)))
+		maxEntropy := math.Log2(float64(observed))
 		if maxEntropy > 0 {
 			entropy = entropy / maxEntropy
 		}
 	}
 
-	return entropy, nil
+	return entropy
 }
 
-// CalculateRollingEntropy calculates entropy over sliding windows
-func (e *EntropyCalculator) CalculateRollingEntropy(tokens []tokenizers.Token) ([]float64, error) {
-	if len(tokens) == 0 {
-		return []float64{}, nil
+// windowEntropy computes the entropy of windowSize tokens from their counts,
+// applying the configured normalization
+func (e *EntropyCalculator) windowEntropy(window *slidingFrequency, windowSize int) float64 {
+	size := float64(windowSize)
+
+	// Tokens sharing a count contribute equally, so sum once per distinct count.
+	// Going through the counts in increasing order makes the result independent
+	// of map iteration order.
+	counts := make([]int, 0, len(window.countTypes))
+	for count := range window.countTypes {
+		counts = append(counts, count)
 	}
+	sort.Ints(counts)
 
-	windowSize := e.windowSize
-	if windowSize <= 0 {
-		windowSize = 100 // Default window size
+	entropy := 0.0
+	for _, count := range counts {
+		probability := float64(count) / size
+		entropy -= float64(window.countTypes[count]) * probability * math.Log2(probability)
 	}
 
-	if windowSize > len(tokens) {
-		windowSize = len(tokens)
+	if e.normalize {
+		maxEntropy := math.Log2(float64(len(window.freq)))
+		if maxEntropy > 0 {
+			entropy = entropy / maxEntropy
+		}
 	}
 
-	var rollingEntropy []float64
+	return entropy
+}
 
-	for i := 0; i <= len(tokens)-windowSize; i++ {
-		windowTokens := tokens[i : i+windowSize]
-		entropy, err := e.CalculateGlobalEntropy(windowTokens)
-		if err != nil {
-			return nil, err
-		}
-		rollingEntropy = append(rollingEntropy, entropy)
+// slidingFrequency tracks token counts in a sliding window together with how many
+// distinct tokens have each count
+type slidingFrequency struct {
+	freq       map[string]int
+	countTypes map[int]int
+}
+
+func newSlidingFrequency() *slidingFrequency {
+	return &slidingFrequency{
+		freq:       make(map[string]int),
+		countTypes: make(map[int]int),
 	}
+}
 
-	return rollingEntropy, nil
+func (s *slidingFrequency) add(text string) {
+	count := s.freq[text]
+	s.moveCount(count, count+1)
+	s.freq[text] = count + 1
+}
+
+func (s *slidingFrequency) remove(text string) {
+	count := s.freq[text]
+	if count == 0 {
+		return
+	}
+	s.moveCount(count, count-1)
+	if count == 1 {
+		delete(s.freq, text)
+	} else {
+		s.freq[text] = count - 1
+	}
+}
+
+// moveCount records one distinct token going from count from to count to
+func (s *slidingFrequency) moveCount(from, to int) {
+	if from > 0 {
+		if s.countTypes[from] == 1 {
+			delete(s.countTypes, from)
+		} else {
+			s.countTypes[from]--
+		}
+	}
+	if to > 0 {
+		s.countTypes[to]++
+	}
 }
 
-// CalculateBigramEntropy calculates conditional entropy of token pairs
+// maxNgramOrder is the largest n supported by CalculateNgramEntropy
+const maxNgramOrder = 4
+
+// ngramKey identifies an n-gram (or its context) by its token texts
+type ngramKey [maxNgramOrder]string
+
+// CalculateBigramEntropy calculates the conditional entropy H(X2|X1) of token pairs
 func (e *EntropyCalculator) CalculateBigramEntropy(tokens []tokenizers.Token) (float64, error) {
-	if len(tokens) < 2 {
+	return e.CalculateNgramEntropy(tokens, 2)
+}
+
+// CalculateNgramEntropy calculates the conditional entropy H(Xn|X1..Xn-1) in bits,
+// i.e. the uncertainty of a token given the n-1 tokens preceding it. For n=1 this
+// is the plain (unnormalized) Shannon entropy of the token distribution.
+func (e *EntropyCalculator) CalculateNgramEntropy(tokens []tokenizers.Token, n int) (float64, error) {
+	if n < 1 || n > maxNgramOrder {
+		return 0.0, fmt.Errorf("n-gram order must be between 1 and %d, got %d", maxNgramOrder, n)
+	}
+
+	if len(tokens) < n {
 		return 0.0, nil
 	}
 
-	// Count bigram frequencies
-	bigramFreq := make(map[string]int)
-	unigramFreq := make(map[string]int)
+	// Count n-grams and their (n-1)-token contexts
+	ngramFreq := make(map[ngramKey]int)
+	contextFreq := make(map[ngramKey]int)
+
+	for i := 0; i+n <= len(tokens); i++ {
+		var ngram, context ngramKey
+		for j := 0; j < n; j++ {
+			ngram[j] = tokens[i+j].Text
+		}
+		copy(context[:n-1], ngram[:n-1])
 
-	for i := 0; i < len(tokens)-1; i++ {
-		bigram := tokens[i].Text + " " + tokens[i+1].Text
-		bigramFreq[bigram]++
-		unigramFreq[tokens[i].Text]++
+		ngramFreq[ngram]++
+		contextFreq[context]++
 	}
-	unigramFreq[tokens[len(tokens)-1].Text]++ // Count last token
 
-	// Calculate conditional entropy
+	// H = -sum p(context, x) * log2 p(x | context)
 	entropy := 0.0
-	totalBigrams := float64(len(tokens) - 1)
-
-	for bigram, freq := range bigramFreq {
-		// Split bigram to get first token
-		firstToken := tokens[0].Text // Default, will be updated
-		for i := 0; i < len(tokens)-1; i++ {
-			if tokens[i].Text+" "+tokens[i+1].Text == bigram {
-				firstToken = tokens[i].Text
-				break
-			}
-		}
+	totalNgrams := float64(len(tokens) - n + 1)
 
-		bigramProb := float64(freq) / totalBigrams
-		unigramProb := float64(unigramFreq[firstToken]) / float64(len(tokens))
+	for ngram, freq := range ngramFreq {
+		var context ngramKey
+		copy(context[:n-1], ngram[:n-1])
 
-		if bigramProb > 0 && unigramProb > 0 {
-			conditionalProb := bigramProb / unigramProb
-			entropy -= bigramProb * math.Log2(conditionalProb)
-		}
+		jointProb := float64(freq) / totalNgrams
+		conditionalProb := float64(freq) / float64(contextFreq[context])
+		entropy -= jointProb * math.Log2(conditionalProb)
 	}
 
 	return entropy, nil
@@ -154,11 +377,18 @@ func (e *EntropyCalculator) CalculateNormalizedEntropy(tokens []tokenizers.Token
 		}
 		return entropy, nil
 
-	case "character_count":
-		// Normalize by character count
+	case "character_count", "character_count_bytes", "character_count_runes":
+		// Normalize by character count, in runes unless byte counts are configured
+		byteCounts := e.byteCounts
+		if normalizationType == "character_count_bytes" {
+			byteCounts = true
+		} else if normalizationType == "character_count_runes" {
+			byteCounts = false
+		}
+
 		charCount := 0
 		for _, token := range tokens {
-			charCount += len(token.Text)
+			charCount += textLength(token.Text, byteCounts)
 		}
 		maxEntropy := math.Log2(float64(charCount))
 		if maxEntropy > 0 {
@@ -173,88 +403,62 @@ func (e *EntropyCalculator) CalculateNormalizedEntropy(tokens []tokenizers.Token
 
 // CalculateEntropyStats calculates comprehensive entropy statistics
 func (e *EntropyCalculator) CalculateEntropyStats(tokens []tokenizers.Token) (map[string]float64, error) {
-	stats := make(map[string]float64)
+	metrics := make(map[string]float64)
 
 	// Global entropy
 	if globalEntropy, err := e.CalculateGlobalEntropy(tokens); err == nil {
-		stats["global_entropy"] = globalEntropy
+		metrics["global_entropy"] = globalEntropy
 	}
 
 	// Bigram entropy
 	if bigramEntropy, err := e.CalculateBigramEntropy(tokens); err == nil {
-		stats["bigram_entropy"] = bigramEntropy
+		metrics["bigram_entropy"] = bigramEntropy
+	}
+
+	// Trigram entropy
+	if trigramEntropy, err := e.CalculateNgramEntropy(tokens, 3); err == nil {
+		metrics["trigram_entropy"] = trigramEntropy
 	}
 
 	// Normalized entropies
 	if vocabNormEntropy, err := e.CalculateNormalizedEntropy(tokens, "vocab_size"); err == nil {
-		stats["vocab_normalized_entropy"] = vocabNormEntropy
+		metrics["vocab_normalized_entropy"] = vocabNormEntropy
 	}
 
 	if tokenNormEntropy, err := e.CalculateNormalizedEntropy(tokens, "token_count"); err == nil {
-		stats["token_normalized_entropy"] = tokenNormEntropy
+		metrics["token_normalized_entropy"] = tokenNormEntropy
 	}
 
 	if charNormEntropy, err := e.CalculateNormalizedEntropy(tokens, "character_count"); err == nil {
-		stats["char_normalized_entropy"] = charNormEntropy
+		metrics["char_normalized_entropy"] = charNormEntropy
 	}
 
-	// Rolling entropy statistics
-	if rollingEntropy, err := e.CalculateRollingEntropy(tokens); err == nil && len(rollingEntropy) > 0 {
-		stats["rolling_entropy_mean"] = calculateMean(rollingEntropy)
-		stats["rolling_entropy_std"] = calculateStd(rollingEntropy)
-		stats["rolling_entropy_min"] = calculateMin(rollingEntropy)
-		stats["rolling_entropy_max"] = calculateMax(rollingEntropy)
+	if byteNormEntropy, err := e.CalculateNormalizedEntropy(tokens, "character_count_bytes"); err == nil {
+		metrics["char_normalized_entropy_bytes"] = byteNormEntropy
 	}
 
-	return stats, nil
-}
-
-// Helper functions for statistics
-func calculateMean(values []float64) float64 {
-	if len(values) == 0 {
-		return 0.0
+	if runeNormEntropy, err := e.CalculateNormalizedEntropy(tokens, "character_count_runes"); err == nil {
+		metrics["char_normalized_entropy_runes"] = runeNormEntropy
 	}
-	sum := 0.0
-	for _, v := range values {
-		sum += v
-	}
-	return sum / float64(len(values))
-}
 
-func calculateStd(values []float64) float64 {
-	if len(values) == 0 {
-		return 0.0
+	// Rolling entropy statistics
+	if e.noRolling {
+		return metrics, nil
 	}
-	mean := calculateMean(values)
-	sum := 0.0
-	for _, v := range values {
-		sum += (v - mean) * (v - mean)
+	if rollingEntropy, err := e.CalculateRollingEntropy(tokens); err == nil && len(rollingEntropy) > 0 {
+		metrics["rolling_entropy_mean"] = stats.Mean(rollingEntropy)
+		metrics["rolling_entropy_std"] = stats.Std(rollingEntropy)
+		metrics["rolling_entropy_min"] = stats.Min(rollingEntropy)
+		metrics["rolling_entropy_max"] = stats.Max(rollingEntropy)
 	}
-	return math.Sqrt(sum / float64(len(values)))
-}
 
-func calculateMin(values []float64) float64 {
-	if len(values) == 0 {
-		return 0.0
-	}
-	min := values[0]
-	for _, v := range values {
-		if v < min {
-			min = v
-		}
-	}
-	return min
+	return metrics, nil
 }
 
-func calculateMax(values []float64) float64 {
-	if len(values) == 0 {
-		return 0.0
-	}
-	max := values[0]
-	for _, v := range values {
-		if v > max {
-			max = v
-		}
+// textLength returns the length of text in runes, or in bytes when byteCounts is set
+func textLength(text string, byteCounts bool) int {
+	if byteCounts {
+		return len(text)
 	}
-	return max
+	return utf8.RuneCountInString(text)
 }
@@ -0,0 +1,281 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+func TestNgramEntropy(t *testing.T) {
+	tests := []struct {
+		name     string
+		sequence string
+		n        int
+		want     float64
+	}{
+		{
+			// Every token is fully determined by its predecessor
+			name:     "deterministic bigrams",
+			sequence: "a b a b a b",
+			n:        2,
+			want:     0.0,
+		},
+		{
+			// Context "a" is followed by "b" or "c" with equal probability:
+			// H = p(a,b)*1 + p(a,c)*1 = 1/3 + 1/3
+			name:     "branching bigram context",
+			sequence: "a b a c",
+			n:        2,
+			want:     2.0 / 3.0,
+		},
+		{
+			// Context "a" occurs at two positions with different successors:
+			// H = p(a,b)*1 + p(a,c)*1 = 1/5 + 1/5
+			name:     "shared context across positions",
+			sequence: "x a b y a c",
+			n:        2,
+			want:     (1.0 / 5.0) * 2,
+		},
+		{
+			// Context "a b" is followed by "c" or "d": H = 2 * (1/4 * 1)
+			name:     "branching trigram context",
+			sequence: "a b c a b d",
+			n:        3,
+			want:     0.5,
+		},
+		{
+			name:     "unigram is shannon entropy",
+			sequence: "a b c d",
+			n:        1,
+			want:     2.0,
+		},
+		{
+			name:     "sequence shorter than order",
+			sequence: "a b",
+			n:        3,
+			want:     0.0,
+		},
+	}
+
+	calc := NewEntropyCalculator(100, false)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := tokenizationFromTexts(strings.Fields(tt.sequence)...).Tokens
+			got, err := calc.CalculateNgramEntropy(tokens, tt.n)
+			if err != nil {
+				t.Fatalf("CalculateNgramEntropy returned error: %v", err)
+			}
+			if math.Abs(got-tt.want) > floatTolerance {
+				t.Errorf("CalculateNgramEntropy(%q, %d) = %v, want %v", tt.sequence, tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNgramEntropyRejectsInvalidOrder(t *testing.T) {
+	calc := NewEntropyCalculator(100, false)
+	tokens := tokenizationFromTexts("a", "b", "c", "d", "e").Tokens
+
+	for _, n := range []int{0, maxNgramOrder + 1} {
+		if _, err := calc.CalculateNgramEntropy(tokens, n); err == nil {
+			t.Errorf("expected error for n-gram order %d", n)
+		}
+	}
+}
+
+// recomputedRollingEntropy is the reference implementation that recomputes the
+// entropy of every window from scratch
+func recomputedRollingEntropy(calc *EntropyCalculator, tokens []tokenizers.Token, windowSize, stride int) []float64 {
+	if windowSize > len(tokens) {
+		windowSize = len(tokens)
+	}
+
+	var result []float64
+	for i := 0; i <= len(tokens)-windowSize; i += stride {
+		entropy, _ := calc.CalculateGlobalEntropy(tokens[i : i+windowSize])
+		result = append(result, entropy)
+	}
+	return result
+}
+
+func TestRollingEntropyMatchesRecomputation(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	vocab := []string{"a", "b", "c", "d", "e", "f", "g"}
+	texts := make([]string, 300)
+	for i := range texts {
+		// Skew the distribution so windows have varying entropy
+		texts[i] = vocab[rng.Intn(1+rng.Intn(len(vocab)))]
+	}
+	tokens := tokenizationFromTexts(texts...).Tokens
+
+	for _, normalize := range []bool{false, true} {
+		for _, windowSize := range []int{1, 2, 7, 50, 300, 500} {
+			for _, stride := range []int{1, 3, 10} {
+				name := fmt.Sprintf("normalize=%v/window=%d/stride=%d", normalize, windowSize, stride)
+				t.Run(name, func(t *testing.T) {
+					calc := NewEntropyCalculator(windowSize, normalize)
+					calc.SetStride(stride)
+
+					got, err := calc.CalculateRollingEntropy(tokens)
+					if err != nil {
+						t.Fatalf("CalculateRollingEntropy returned error: %v", err)
+					}
+
+					want := recomputedRollingEntropy(calc, tokens, windowSize, stride)
+					if len(got) != len(want) {
+						t.Fatalf("got %d windows, want %d", len(got), len(want))
+					}
+					for i := range want {
+						if got[i] != want[i] {
+							t.Errorf("window %d: got %v, want %v", i, got[i], want[i])
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestCharNormalizedEntropyOnMixedScripts(t *testing.T) {
+	// Two distinct tokens give 1 bit of entropy; "дом" + "家" is 4 runes and 9 bytes
+	tokens := tokenizationFromTexts("дом", "家").Tokens
+
+	tests := []struct {
+		name       string
+		byteCounts bool
+		want       float64
+	}{
+		{name: "runes", byteCounts: false, want: 1 / math.Log2(4)},
+		{name: "bytes", byteCounts: true, want: 1 / math.Log2(9)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calc := NewEntropyCalculator(100, false)
+			calc.SetByteCounts(tt.byteCounts)
+
+			stats, err := calc.CalculateEntropyStats(tokens)
+			if err != nil {
+				t.Fatalf("CalculateEntropyStats returned error: %v", err)
+			}
+
+			if got := stats["char_normalized_entropy"]; math.Abs(got-tt.want) > floatTolerance {
+				t.Errorf("char_normalized_entropy = %v, want %v", got, tt.want)
+			}
+			if got := stats["char_normalized_entropy_runes"]; math.Abs(got-1/math.Log2(4)) > floatTolerance {
+				t.Errorf("char_normalized_entropy_runes = %v, want %v", got, 1/math.Log2(4))
+			}
+			if got := stats["char_normalized_entropy_bytes"]; math.Abs(got-1/math.Log2(9)) > floatTolerance {
+				t.Errorf("char_normalized_entropy_bytes = %v, want %v", got, 1/math.Log2(9))
+			}
+		})
+	}
+}
+
+func TestEntropyWithCI(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	texts := make([]string, 200)
+	for i := range texts {
+		texts[i] = string(rune('a' + rng.Intn(6)))
+	}
+	tokens := tokenizationFromTexts(texts...).Tokens
+
+	calc := NewEntropyCalculator(100, false)
+	calc.SetSeed(1)
+	ci, err := calc.CalculateEntropyWithCI(tokens, 500, 0.9)
+	if err != nil {
+		t.Fatalf("CalculateEntropyWithCI returned error: %v", err)
+	}
+
+	point, _ := calc.CalculateGlobalEntropy(tokens)
+	if !(ci["ci_lower"] <= ci["ci_mean"] && ci["ci_mean"] <= ci["ci_upper"]) {
+		t.Errorf("expected lower <= mean <= upper, got %v", ci)
+	}
+	if point < ci["ci_lower"] || point > ci["ci_upper"] {
+		t.Errorf("point estimate %v outside interval [%v, %v]", point, ci["ci_lower"], ci["ci_upper"])
+	}
+	if ci["ci_upper"]-ci["ci_lower"] <= 0 {
+		t.Errorf("expected a non-empty interval, got %v", ci)
+	}
+
+	// The same seed reproduces the same interval
+	again := NewEntropyCalculator(100, false)
+	again.SetSeed(1)
+	repeat, err := again.CalculateEntropyWithCI(tokens, 500, 0.9)
+	if err != nil {
+		t.Fatalf("CalculateEntropyWithCI returned error: %v", err)
+	}
+	for key, value := range ci {
+		if repeat[key] != value {
+			t.Errorf("%s = %v on rerun, want %v", key, repeat[key], value)
+		}
+	}
+
+	// A single repeated token has no uncertainty
+	constant, err := calc.CalculateEntropyWithCI(tokenizationFromTexts("x", "x", "x").Tokens, 50, 0.95)
+	if err != nil {
+		t.Fatalf("CalculateEntropyWithCI returned error: %v", err)
+	}
+	if constant["ci_lower"] != 0 || constant["ci_upper"] != 0 {
+		t.Errorf("expected a zero-width interval at 0, got %v", constant)
+	}
+}
+
+func TestEntropyWithCIRejectsInvalidArguments(t *testing.T) {
+	tokens := tokenizationFromTexts("a", "b").Tokens
+	calc := NewEntropyCalculator(100, false)
+
+	for _, tt := range []struct {
+		name       string
+		iterations int
+		confidence float64
+	}{
+		{name: "negative iterations", iterations: -1, confidence: 0.95},
+		{name: "confidence of one", iterations: 10, confidence: 1},
+		{name: "negative confidence", iterations: 10, confidence: -0.5},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := calc.CalculateEntropyWithCI(tokens, tt.iterations, tt.confidence); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestEntropyCIReachesAnalysisResult(t *testing.T) {
+	tokenizer := tokenizers.NewMockTokenizer("mock")
+	if err := tokenizer.Initialize(tokenizers.TokenizerConfig{Name: "mock", Type: "custom"}); err != nil {
+		t.Fatalf("failed to initialize mock tokenizer: %v", err)
+	}
+
+	document := "the cat sat on the mat and the dog sat on the log"
+	analyze := func(config EngineConfig) *AnalysisResult {
+		result, err := NewEngine(config).AnalyzeDocument(context.Background(), document, tokenizer)
+		if err != nil {
+			t.Fatalf("AnalyzeDocument returned error: %v", err)
+		}
+		return result
+	}
+
+	if _, ok := analyze(EngineConfig{EntropyWindowSize: 10}).Metrics["entropy_ci_lower"]; ok {
+		t.Errorf("entropy_ci_lower reported without EntropyCI enabled")
+	}
+
+	config := EngineConfig{EntropyWindowSize: 10, EntropyCI: true, EntropyCIIterations: 200, EntropyCISeed: 3}
+	first, second := analyze(config), analyze(config)
+	for _, key := range []string{"entropy_ci_lower", "entropy_ci_upper", "entropy_ci_mean"} {
+		metric, ok := first.Metrics[key]
+		if !ok {
+			t.Errorf("missing %s", key)
+			continue
+		}
+		if second.Metrics[key].Value != metric.Value {
+			t.Errorf("%s not reproducible with a fixed seed: %v vs %v", key, metric.Value, second.Metrics[key].Value)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package metrics
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/stats"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// FertilityCalculator measures how many subword tokens a tokenizer produces per word
+type FertilityCalculator struct{}
+
+// NewFertilityCalculator creates a new fertility calculator
+func NewFertilityCalculator() *FertilityCalculator {
+	return &FertilityCalculator{}
+}
+
+// textSpan represents a half-open [Start, End) byte range in a document
+type textSpan struct {
+	Start int
+	End   int
+}
+
+// CalculateFertilityStats calculates tokens-per-word statistics for a document
+func (f *FertilityCalculator) CalculateFertilityStats(document string, tokens []tokenizers.Token) (map[string]float64, error) {
+	metrics := make(map[string]float64)
+
+	words := splitWordSpans(document)
+	if len(words) == 0 || len(tokens) == 0 {
+		return metrics, nil
+	}
+
+	// Use the tokenizer's offsets when present, otherwise align tokens to the text
+	var tokenSpans []textSpan
+	if hasTokenOffsets(tokens) {
+		tokenSpans = make([]textSpan, len(tokens))
+		for i, token := range tokens {
+			tokenSpans[i] = textSpan{Start: token.StartPos, End: token.EndPos}
+		}
+	} else {
+		tokenSpans = alignTokensToText(document, tokens)
+	}
+
+	counts := countTokensPerSpan(words, tokenSpans)
+
+	// Only words covered by at least one token contribute to the statistics
+	pieces := make([]int, 0, len(counts))
+	for _, count := range counts {
+		if count > 0 {
+			pieces = append(pieces, count)
+		}
+	}
+
+	if len(pieces) == 0 {
+		return metrics, nil
+	}
+
+	whole := 0
+	split3Plus := 0
+	for _, count := range pieces {
+		if count == 1 {
+			whole++
+		}
+		if count >= 3 {
+			split3Plus++
+		}
+	}
+
+	totalWords := float64(len(pieces))
+	metrics["word_count"] = totalWords
+	metrics["mean_tokens_per_word"] = stats.Mean(pieces)
+	metrics["median_tokens_per_word"] = stats.Median(pieces)
+	metrics["max_tokens_per_word"] = float64(stats.Max(pieces))
+	metrics["whole_word_ratio"] = float64(whole) / totalWords
+	metrics["split_3plus_ratio"] = float64(split3Plus) / totalWords
+
+	return metrics, nil
+}
+
+// splitWordSpans returns the byte spans of whitespace-delimited words in the text
+func splitWordSpans(text string) []textSpan {
+	var spans []textSpan
+	start := -1
+
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				spans = append(spans, textSpan{Start: start, End: i})
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+
+	if start >= 0 {
+		spans = append(spans, textSpan{Start: start, End: len(text)})
+	}
+
+	return spans
+}
+
+// hasTokenOffsets reports whether the tokens carry usable character offsets.
+// Adapters without position information leave every StartPos at zero.
+func hasTokenOffsets(tokens []tokenizers.Token) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+
+	for _, token := range tokens {
+		if token.StartPos != 0 {
+			return true
+		}
+	}
+
+	// A single token starting at zero is valid as long as it has an end position
+	return len(tokens) == 1 && tokens[0].EndPos > 0
+}
+
+// alignTokensToText approximates token spans by greedily matching each token's text
+// against the document from left to right. Tokens that cannot be located get an
+// empty span at the current position.
+func alignTokensToText(document string, tokens []tokenizers.Token) []textSpan {
+	spans := make([]textSpan, len(tokens))
+	cursor := 0
+
+	for i, token := range tokens {
+		spans[i] = textSpan{Start: cursor, End: cursor}
+
+		for _, candidate := range tokenTextCandidates(token.Text) {
+			if idx := strings.Index(document[cursor:], candidate); idx >= 0 {
+				start := cursor + idx
+				spans[i] = textSpan{Start: start, End: start + len(candidate)}
+				cursor = start + len(candidate)
+				break
+			}
+		}
+	}
+
+	return spans
+}
+
+// tokenTextCandidates returns the token text along with variants stripped of common
+// subword markers (GPT-2 "Ġ", SentencePiece "▁", WordPiece "##")
+func tokenTextCandidates(text string) []string {
+	candidates := []string{}
+	if text != "" {
+		candidates = append(candidates, text)
+	}
+
+	stripped := strings.TrimPrefix(text, "##")
+	stripped = strings.ReplaceAll(stripped, "Ġ", " ")
+	stripped = strings.ReplaceAll(stripped, "▁", " ")
+	if stripped != text && stripped != "" {
+		candidates = append(candidates, stripped)
+	}
+
+	if trimmed := strings.TrimSpace(stripped); trimmed != stripped && trimmed != "" {
+		candidates = append(candidates, trimmed)
+	}
+
+	return candidates
+}
+
+// countTokensPerSpan counts how many token spans overlap each of the given spans
+func countTokensPerSpan(spans []textSpan, tokenSpans []textSpan) []int {
+	counts := make([]int, len(spans))
+
+	for _, token := range tokenSpans {
+		if token.End <= token.Start {
+			continue
+		}
+
+		// Find the first span that ends after the token starts
+		i := sort.Search(len(spans), func(i int) bool {
+			return spans[i].End > token.Start
+		})
+
+		for ; i < len(spans) && spans[i].Start < token.End; i++ {
+			counts[i]++
+		}
+	}
+
+	return counts
+}
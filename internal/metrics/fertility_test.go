@@ -0,0 +1,225 @@
+package metrics
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestFertilityStats(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		tokens   []string
+		spans    [][2]int
+		want     map[string]float64
+	}{
+		{
+			// Words the | unhappiness | of | cats split 1 | 3 | 1 | 2, read from the offsets
+			name:     "offsets",
+			document: "the unhappiness of cats",
+			spans:    [][2]int{{0, 3}, {3, 6}, {6, 11}, {11, 15}, {15, 18}, {18, 20}, {20, 23}},
+			want: map[string]float64{
+				"word_count":             4,
+				"mean_tokens_per_word":   1.75,
+				"median_tokens_per_word": 1.5,
+				"max_tokens_per_word":    3,
+				"whole_word_ratio":       0.5,
+				"split_3plus_ratio":      0.25,
+			},
+		},
+		{
+			// Without offsets "Ġwor" is found as " wor", so hello | world split 2 | 2
+			name:     "text matching",
+			document: "hello world",
+			tokens:   []string{"hel", "lo", "Ġwor", "ld"},
+			want: map[string]float64{
+				"word_count":             2,
+				"mean_tokens_per_word":   2,
+				"median_tokens_per_word": 2,
+				"max_tokens_per_word":    2,
+				"whole_word_ratio":       0,
+				"split_3plus_ratio":      0,
+			},
+		},
+		{
+			// Each "ab" matches the next occurrence rather than the first
+			name:     "repeated substrings",
+			document: "ab ab ab",
+			tokens:   []string{"ab", "ab", "ab"},
+			want: map[string]float64{
+				"word_count":             3,
+				"mean_tokens_per_word":   1,
+				"median_tokens_per_word": 1,
+				"max_tokens_per_word":    1,
+				"whole_word_ratio":       1,
+				"split_3plus_ratio":      0,
+			},
+		},
+		{
+			// "xyz" gets an empty span and counts towards no word
+			name:     "token missing from text",
+			document: "cat dog",
+			tokens:   []string{"cat", "xyz", "do", "g"},
+			want: map[string]float64{
+				"word_count":             2,
+				"mean_tokens_per_word":   1.5,
+				"median_tokens_per_word": 1.5,
+				"max_tokens_per_word":    2,
+				"whole_word_ratio":       0.5,
+				"split_3plus_ratio":      0,
+			},
+		},
+		{
+			name:     "no token matches",
+			document: "cat dog",
+			tokens:   []string{"xyz", "uvw"},
+			want:     map[string]float64{},
+		},
+		{
+			name:   "empty document",
+			tokens: []string{"a"},
+			want:   map[string]float64{},
+		},
+		{
+			name:     "whitespace document",
+			document: " \n\t",
+			tokens:   []string{" "},
+			want:     map[string]float64{},
+		},
+		{
+			name:     "no tokens",
+			document: "cat dog",
+			want:     map[string]float64{},
+		},
+	}
+
+	calc := NewFertilityCalculator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokenization := tokenizationFromTexts(tt.tokens...)
+			if tt.spans != nil {
+				tokenization = tokenizationFromSpans(tt.document, tt.spans...)
+			}
+
+			got, err := calc.CalculateFertilityStats(tt.document, tokenization.Tokens)
+			if err != nil {
+				t.Fatalf("CalculateFertilityStats returned error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("CalculateFertilityStats = %v, want %v", got, tt.want)
+			}
+			for key, want := range tt.want {
+				if math.Abs(got[key]-want) > floatTolerance {
+					t.Errorf("%s = %v, want %v", key, got[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitWordSpans(t *testing.T) {
+	tests := []struct {
+		text string
+		want []textSpan
+	}{
+		{text: "", want: nil},
+		{text: "  \n", want: nil},
+		{text: "one", want: []textSpan{{0, 3}}},
+		{text: " one  two\n", want: []textSpan{{1, 4}, {6, 9}}},
+		// é and ö take two bytes each
+		{text: "héllo\twörld", want: []textSpan{{0, 6}, {7, 13}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := splitWordSpans(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitWordSpans(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlignTokensToText(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		tokens   []string
+		want     []textSpan
+	}{
+		{
+			name:     "markers stripped",
+			document: "a cat sat",
+			tokens:   []string{"a", "Ġcat", "▁sat"},
+			want:     []textSpan{{0, 1}, {1, 5}, {5, 9}},
+		},
+		{
+			name:     "wordpiece continuation",
+			document: "playing",
+			tokens:   []string{"play", "##ing"},
+			want:     []textSpan{{0, 4}, {4, 7}},
+		},
+		{
+			name:     "repeated substrings",
+			document: "ab ab ab",
+			tokens:   []string{"ab", "ab", "ab"},
+			want:     []textSpan{{0, 2}, {3, 5}, {6, 8}},
+		},
+		{
+			// The unmatched token stays at the cursor and later tokens keep matching
+			name:     "unmatched token",
+			document: "cat dog",
+			tokens:   []string{"cat", "xyz", "dog"},
+			want:     []textSpan{{0, 3}, {3, 3}, {4, 7}},
+		},
+		{
+			// A match behind the cursor is not found again
+			name:     "out of order",
+			document: "cat dog",
+			tokens:   []string{"dog", "cat"},
+			want:     []textSpan{{4, 7}, {7, 7}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := alignTokensToText(tt.document, tokenizationFromTexts(tt.tokens...).Tokens)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("alignTokensToText = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountTokensPerSpan(t *testing.T) {
+	words := []textSpan{{0, 5}, {6, 10}}
+
+	tests := []struct {
+		name   string
+		tokens []textSpan
+		want   []int
+	}{
+		{name: "no tokens", want: []int{0, 0}},
+		{name: "one token per word", tokens: []textSpan{{0, 5}, {5, 10}}, want: []int{1, 1}},
+		{
+			// The middle token crosses the gap and counts for both words
+			name:   "token spanning words",
+			tokens: []textSpan{{0, 2}, {2, 7}, {7, 10}},
+			want:   []int{2, 2},
+		},
+		{
+			// Whitespace between words and empty spans count for nothing
+			name:   "whitespace and empty spans",
+			tokens: []textSpan{{0, 5}, {5, 6}, {8, 8}, {6, 10}},
+			want:   []int{1, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := countTokensPerSpan(words, tt.tokens); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("countTokensPerSpan = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
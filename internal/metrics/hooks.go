@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PluginMetricPrefix starts the names of metrics that plugins add to analysis results,
+// as plugin_{plugin}_{metric}
+const PluginMetricPrefix = "plugin_"
+
+// DocumentHook computes extra metrics for a document the engine has analyzed, such as
+// those of plugins. It sees the engine's own metrics in result and returns metrics to
+// add alongside them; an error is recorded in the result's metadata under
+// "hook_errors", and any metrics returned with it are still added. Hooks run
+// concurrently when documents are analyzed in parallel.
+type DocumentHook func(ctx context.Context, result *AnalysisResult) ([]MetricResult, error)
+
+// AddDocumentHook registers a hook to run on every document the engine analyzes.
+// Register hooks before analysis starts.
+func (e *Engine) AddDocumentHook(hook DocumentHook) {
+	e.hookMu.Lock()
+	defer e.hookMu.Unlock()
+	e.hooks = append(e.hooks, hook)
+}
+
+// runHooks adds the metrics of every registered hook to result. A metric whose name is
+// already taken gets a numeric suffix rather than replacing the existing metric.
+func (e *Engine) runHooks(ctx context.Context, result *AnalysisResult) {
+	e.hookMu.RLock()
+	hooks := e.hooks
+	e.hookMu.RUnlock()
+	if len(hooks) == 0 {
+		return
+	}
+
+	var hookErrors []string
+	var added []string
+	for _, hook := range hooks {
+		metrics, err := hook(ctx, result)
+		if err != nil {
+			hookErrors = append(hookErrors, err.Error())
+		}
+		for _, metric := range metrics {
+			name := uniqueMetricName(result.Metrics, metric.MetricName)
+			metric.MetricName = name
+			if metric.TokenizerName == "" {
+				metric.TokenizerName = result.TokenizerName
+			}
+			result.Metrics[name] = metric
+			added = append(added, name)
+		}
+	}
+
+	if len(hookErrors) > 0 {
+		if result.Metadata == nil {
+			result.Metadata = make(map[string]interface{})
+		}
+		result.Metadata["hook_errors"] = hookErrors
+	}
+
+	if len(added) > 0 {
+		e.hookMu.Lock()
+		if e.hookMetricNames == nil {
+			e.hookMetricNames = make(map[string]bool)
+		}
+		for _, name := range added {
+			e.hookMetricNames[name] = true
+		}
+		e.hookMu.Unlock()
+	}
+}
+
+// addedMetricNames returns the names of the metrics hooks have added so far, sorted
+func (e *Engine) addedMetricNames() []string {
+	e.hookMu.RLock()
+	defer e.hookMu.RUnlock()
+
+	names := make([]string, 0, len(e.hookMetricNames))
+	for name := range e.hookMetricNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// uniqueMetricName returns name, or name with the first free numeric suffix from _2
+// when metrics already has it
+func uniqueMetricName(metrics map[string]MetricResult, name string) string {
+	if _, exists := metrics[name]; !exists {
+		return name
+	}
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s_%d", name, i)
+		if _, exists := metrics[candidate]; !exists {
+			return candidate
+		}
+	}
+}
+
+// IsPluginMetric reports whether a metric name was added by a plugin
+func IsPluginMetric(name string) bool {
+	return strings.HasPrefix(name, PluginMetricPrefix)
+}
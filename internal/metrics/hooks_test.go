@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDocumentHooks(t *testing.T) {
+	engine := NewEngine(EngineConfig{EntropyWindowSize: 10})
+	engine.AddDocumentHook(func(ctx context.Context, result *AnalysisResult) ([]MetricResult, error) {
+		if result.Tokenization == nil || len(result.Metrics) == 0 {
+			t.Error("the hook should see the tokenization and the engine's metrics")
+		}
+		return []MetricResult{
+			{MetricName: "plugin_a_score", Value: float64(result.TokenCount)},
+			{MetricName: "plugin_a_score", Value: 2},
+			{MetricName: "token_count", Value: 3},
+		}, nil
+	})
+	engine.AddDocumentHook(func(ctx context.Context, result *AnalysisResult) ([]MetricResult, error) {
+		return []MetricResult{{MetricName: "plugin_a_score", Value: 4}}, errors.New("plugin b failed")
+	})
+
+	result, err := engine.AnalyzeDocument(context.Background(), "the cat sat", newFailingTokenizer(t, ""))
+	if err != nil {
+		t.Fatalf("AnalyzeDocument returned error: %v", err)
+	}
+
+	// Repeated names get suffixes instead of replacing earlier metrics
+	want := map[string]float64{
+		"plugin_a_score":   float64(result.TokenCount),
+		"plugin_a_score_2": 2,
+		"plugin_a_score_3": 4,
+		"token_count":      float64(result.TokenCount),
+		"token_count_2":    3,
+	}
+	for name, value := range want {
+		metric, ok := result.Metrics[name]
+		if !ok || metric.Value != value || metric.MetricName != name || metric.TokenizerName != "mock" {
+			t.Errorf("%s = %+v, %v, want value %v from mock", name, metric, ok, value)
+		}
+	}
+	if got := result.Metadata["hook_errors"]; !reflect.DeepEqual(got, []string{"plugin b failed"}) {
+		t.Errorf("hook_errors = %v, want the failing hook", got)
+	}
+
+	names := engine.GetMetricNames()
+	added := names[len(names)-4:]
+	if !reflect.DeepEqual(added, []string{"plugin_a_score", "plugin_a_score_2", "plugin_a_score_3", "token_count_2"}) {
+		t.Errorf("GetMetricNames ends with %v, want the hook metrics", added)
+	}
+	if !IsPluginMetric("plugin_a_score") || IsPluginMetric("token_count") {
+		t.Error("IsPluginMetric should match only the plugin prefix")
+	}
+}
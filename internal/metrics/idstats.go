@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"math"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/stats"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// IDStatsCalculator measures how a document's tokens are spread over the tokenizer's
+// ID space. BPE tokenizers assign higher IDs to later, rarer merges, so heavy use of
+// the top of the ID range indicates unusual text for the tokenizer.
+type IDStatsCalculator struct {
+	vocabSize  int
+	specialIDs map[int]bool
+}
+
+// NewIDStatsCalculator creates a new ID statistics calculator. The vocabulary size
+// enables the high-ID ratios and specialIDs enables the special token count; either
+// may be left empty.
+func NewIDStatsCalculator(vocabSize int, specialIDs []int) *IDStatsCalculator {
+	special := make(map[int]bool, len(specialIDs))
+	for _, id := range specialIDs {
+		special[id] = true
+	}
+
+	return &IDStatsCalculator{
+		vocabSize:  vocabSize,
+		specialIDs: special,
+	}
+}
+
+// CalculateIDStats calculates statistics over token IDs. No statistics are reported
+// when every ID is zero, as happens with adapters that do not expose real IDs.
+func (c *IDStatsCalculator) CalculateIDStats(tokens []tokenizers.Token) (map[string]float64, error) {
+	metrics := make(map[string]float64)
+
+	ids := make([]int, len(tokens))
+	hasIDs := false
+	for i, token := range tokens {
+		ids[i] = token.ID
+		if token.ID != 0 {
+			hasIDs = true
+		}
+	}
+
+	if !hasIDs {
+		return metrics, nil
+	}
+
+	// Shannon entropy of the ID distribution
+	idFreq := make(map[int]int)
+	for _, id := range ids {
+		idFreq[id]++
+	}
+	entropy := 0.0
+	for _, freq := range idFreq {
+		probability := float64(freq) / float64(len(ids))
+		entropy -= probability * math.Log2(probability)
+	}
+
+	metrics["entropy"] = entropy
+	metrics["mean"] = stats.Mean(ids)
+	metrics["median"] = stats.Median(ids)
+
+	if c.vocabSize > 0 {
+		above50, above90 := 0, 0
+		for _, id := range ids {
+			if float64(id) > 0.5*float64(c.vocabSize) {
+				above50++
+			}
+			if float64(id) > 0.9*float64(c.vocabSize) {
+				above90++
+			}
+		}
+		metrics["above_50pct_vocab"] = float64(above50) / float64(len(ids))
+		metrics["above_90pct_vocab"] = float64(above90) / float64(len(ids))
+	}
+
+	if len(c.specialIDs) > 0 {
+		special := 0
+		for _, id := range ids {
+			if c.specialIDs[id] {
+				special++
+			}
+		}
+		metrics["special_count"] = float64(special)
+	}
+
+	return metrics, nil
+}
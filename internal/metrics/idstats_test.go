@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+func tokensWithIDs(ids ...int) []tokenizers.Token {
+	tokens := make([]tokenizers.Token, len(ids))
+	for i, id := range ids {
+		tokens[i] = tokenizers.Token{Text: "t", ID: id}
+	}
+	return tokens
+}
+
+func TestIDStats(t *testing.T) {
+	tests := []struct {
+		name       string
+		ids        []int
+		vocabSize  int
+		specialIDs []int
+		want       map[string]float64
+	}{
+		{
+			name: "all zero IDs are skipped",
+			ids:  []int{0, 0, 0},
+			want: map[string]float64{},
+		},
+		{
+			name: "no tokens",
+			want: map[string]float64{},
+		},
+		{
+			name: "without vocab size or special IDs",
+			ids:  []int{1, 2, 3, 4},
+			want: map[string]float64{"entropy": 2, "mean": 2.5, "median": 2.5},
+		},
+		{
+			// 60 and 95 are above half of 100; only 95 is above 90%
+			name:       "high IDs and special tokens",
+			ids:        []int{10, 60, 95, 0, 0},
+			vocabSize:  100,
+			specialIDs: []int{0},
+			want: map[string]float64{
+				"entropy":           -3*(0.2*math.Log2(0.2)) - 0.4*math.Log2(0.4),
+				"mean":              33,
+				"median":            10,
+				"above_50pct_vocab": 0.4,
+				"above_90pct_vocab": 0.2,
+				"special_count":     2,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewIDStatsCalculator(tt.vocabSize, tt.specialIDs).CalculateIDStats(tokensWithIDs(tt.ids...))
+			if err != nil {
+				t.Fatalf("CalculateIDStats returned error: %v", err)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Errorf("got %d metrics %v, want %d", len(got), got, len(tt.want))
+			}
+			for key, want := range tt.want {
+				if math.Abs(got[key]-want) > floatTolerance {
+					t.Errorf("%s = %v, want %v", key, got[key], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package metrics
+
+// Sampling parameters for approximateLCS. The sampled work is bounded by
+// alignmentSampleSegments × alignmentSegmentTokens² comparisons.
+const (
+	alignmentSegmentTokens  = 1000
+	alignmentSampleSegments = 16
+)
+
+// lcsLength returns the length of the longest common subsequence of a and b using
+// two DP rows over the shorter sequence, so memory is O(min(n, m))
+func lcsLength(a, b []string) int {
+	if len(b) > len(a) {
+		a, b = b, a
+	}
+	return lcsRow(a, b)[len(b)]
+}
+
+// lcsRow returns the last row of the LCS length table of a against b, i.e. the
+// LCS length of a with every prefix of b
+func lcsRow(a, b []string) []int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				curr[j] = prev[j-1] + 1
+			} else if prev[j] >= curr[j-1] {
+				curr[j] = prev[j]
+			} else {
+				curr[j] = curr[j-1]
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev
+}
+
+// lcsPairs returns the index pairs (i, j) with a[i] == b[j] that make up a longest
+// common subsequence, in increasing order. It uses Hirschberg's divide and conquer
+// algorithm, so memory stays linear while time remains O(n × m).
+func lcsPairs(a, b []string) [][2]int {
+	pairs := make([][2]int, 0)
+	hirschberg(a, b, 0, 0, &pairs)
+	return pairs
+}
+
+// hirschberg appends the LCS pairs of a and b, offset by the positions of a and b
+// within the original sequences
+func hirschberg(a, b []string, offsetA, offsetB int, pairs *[][2]int) {
+	if len(a) == 0 || len(b) == 0 {
+		return
+	}
+
+	if len(a) == 1 {
+		for j, text := range b {
+			if text == a[0] {
+				*pairs = append(*pairs, [2]int{offsetA, offsetB + j})
+				return
+			}
+		}
+		return
+	}
+
+	// Split a in half and find where the optimal path crosses the middle row by
+	// combining forward scores for the top half with reverse scores for the bottom half
+	mid := len(a) / 2
+	forward := lcsRow(a[:mid], b)
+	backward := lcsRow(reversed(a[mid:]), reversed(b))
+
+	split := 0
+	best := -1
+	for j := 0; j <= len(b); j++ {
+		if score := forward[j] + backward[len(b)-j]; score > best {
+			best = score
+			split = j
+		}
+	}
+
+	hirschberg(a[:mid], b[:split], offsetA, offsetB, pairs)
+	hirschberg(a[mid:], b[split:], offsetA+mid, offsetB+split, pairs)
+}
+
+// reversed returns a reversed copy of texts
+func reversed(texts []string) []string {
+	result := make([]string, len(texts))
+	for i, text := range texts {
+		result[len(texts)-1-i] = text
+	}
+	return result
+}
+
+// approximateLCS estimates the LCS length of long sequences. Both sequences are cut
+// into the same number of proportional segments, the exact LCS is computed for an
+// evenly spaced sample of corresponding segment pairs, and the sum is scaled up to
+// the full segment count. Matches that cross segment boundaries are missed, so the
+// estimate tends to be slightly low.
+func approximateLCS(a, b []string) int {
+	longer := len(a)
+	if len(b) > longer {
+		longer = len(b)
+	}
+
+	segments := (longer + alignmentSegmentTokens - 1) / alignmentSegmentTokens
+	samples := alignmentSampleSegments
+	if segments < samples {
+		samples = segments
+	}
+
+	return chunkedLCS(a, b, segments, samples)
+}
+
+// chunkedLCS sums the LCS of sampled corresponding segments and scales the sum by
+// segments/samples
+func chunkedLCS(a, b []string, segments, samples int) int {
+	if segments == 0 || samples == 0 {
+		return 0
+	}
+
+	total := 0
+	for s := 0; s < samples; s++ {
+		segment := s * segments / samples
+		startA, endA := segment*len(a)/segments, (segment+1)*len(a)/segments
+		startB, endB := segment*len(b)/segments, (segment+1)*len(b)/segments
+		total += lcsLength(a[startA:endA], b[startB:endB])
+	}
+
+	return total * segments / samples
+}
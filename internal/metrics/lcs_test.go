@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// naiveLCS fills the full (n+1)×(m+1) table
+func naiveLCS(a, b []string) int {
+	dp := make([][]int, len(a)+1)
+	for i := range dp {
+		dp[i] = make([]int, len(b)+1)
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				dp[i][j] = dp[i-1][j-1] + 1
+			} else if dp[i-1][j] > dp[i][j-1] {
+				dp[i][j] = dp[i-1][j]
+			} else {
+				dp[i][j] = dp[i][j-1]
+			}
+		}
+	}
+	return dp[len(a)][len(b)]
+}
+
+func randomTexts(rng *rand.Rand, n, vocab int) []string {
+	texts := make([]string, n)
+	for i := range texts {
+		texts[i] = string(rune('a' + rng.Intn(vocab)))
+	}
+	return texts
+}
+
+func TestLCSMatchesFullTable(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	for trial := 0; trial < 200; trial++ {
+		a := randomTexts(rng, rng.Intn(40), 1+rng.Intn(5))
+		b := randomTexts(rng, rng.Intn(40), 1+rng.Intn(5))
+		want := naiveLCS(a, b)
+
+		if got := lcsLength(a, b); got != want {
+			t.Fatalf("trial %d: lcsLength(%v, %v) = %d, want %d", trial, a, b, got, want)
+		}
+
+		pairs := lcsPairs(a, b)
+		if len(pairs) != want {
+			t.Fatalf("trial %d: lcsPairs returned %d pairs, want %d", trial, len(pairs), want)
+		}
+		for k, pair := range pairs {
+			if a[pair[0]] != b[pair[1]] {
+				t.Fatalf("trial %d: pair %v does not match: %q vs %q", trial, pair, a[pair[0]], b[pair[1]])
+			}
+			if k > 0 && (pair[0] <= pairs[k-1][0] || pair[1] <= pairs[k-1][1]) {
+				t.Fatalf("trial %d: pairs not strictly increasing: %v", trial, pairs)
+			}
+		}
+	}
+}
+
+func TestApproximateLCS(t *testing.T) {
+	rng := rand.New(rand.NewSource(9))
+	a := randomTexts(rng, 40000, 4)
+
+	// Identical sequences align perfectly even when sampled
+	if got := approximateLCS(a, a); got != len(a) {
+		t.Errorf("approximateLCS of identical sequences = %d, want %d", got, len(a))
+	}
+
+	// Sampling never claims more than the shorter sequence
+	b := randomTexts(rng, 30000, 4)
+	if got := approximateLCS(a, b); got <= 0 || got > len(b) {
+		t.Errorf("approximateLCS = %d, want within (0, %d]", got, len(b))
+	}
+}
+
+func TestTokenAlignmentApproximatesLongInputs(t *testing.T) {
+	calc := NewDriftCalculator(0.5)
+	calc.SetMaxAlignmentTokens(3)
+
+	short := tokenizationFromTexts("a", "b", "c").Tokens
+	long := tokenizationFromTexts("a", "b", "c", "d").Tokens
+
+	metrics, err := calc.CalculateTokenAlignment(short, short)
+	if err != nil {
+		t.Fatalf("CalculateTokenAlignment returned error: %v", err)
+	}
+	if _, ok := metrics["approximate"]; ok {
+		t.Errorf("expected an exact alignment within the cutoff")
+	}
+
+	metrics, err = calc.CalculateTokenAlignment(long, long)
+	if err != nil {
+		t.Fatalf("CalculateTokenAlignment returned error: %v", err)
+	}
+	if metrics["approximate"] != 1 {
+		t.Errorf("expected approximate = 1 beyond the cutoff, got %v", metrics)
+	}
+	if metrics["score"] != 1 {
+		t.Errorf("score = %v, want 1 for identical sequences", metrics["score"])
+	}
+}
+
+// BenchmarkLCSLength reports allocations per call; bytes/op grow linearly with
+// sequence length since only two DP rows are kept
+func BenchmarkLCSLength(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	for _, n := range []int{1000, 2000, 4000} {
+		x := randomTexts(rng, n, 8)
+		y := randomTexts(rng, n, 8)
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				lcsLength(x, y)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package metrics
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/stats"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// DefaultNumericPattern matches integers, decimals and comma-grouped thousands
+const DefaultNumericPattern = `\d{1,3}(?:,\d{3})+(?:\.\d+)?|\d+(?:\.\d+)?`
+
+// subwordMarkers strips the GPT-2, SentencePiece and WordPiece subword markers
+var subwordMarkers = strings.NewReplacer("Ġ", " ", "▁", " ", "##", "")
+
+// NumericCalculator measures how tokenizers split numeric literals
+type NumericCalculator struct {
+	pattern *regexp.Regexp
+}
+
+// NewNumericCalculator creates a numeric calculator using the given pattern for numeric
+// spans, or DefaultNumericPattern when the pattern is empty
+func NewNumericCalculator(pattern string) (*NumericCalculator, error) {
+	if pattern == "" {
+		pattern = DefaultNumericPattern
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid numeric pattern: %w", err)
+	}
+
+	return &NumericCalculator{
+		pattern: re,
+	}, nil
+}
+
+// CalculateNumericStats calculates how many tokens each numeric literal in the document
+// is split into. Token offsets are used when available; otherwise runs of consecutive
+// numeric-looking tokens are treated as one literal.
+func (n *NumericCalculator) CalculateNumericStats(document string, tokens []tokenizers.Token) (map[string]float64, error) {
+	metrics := make(map[string]float64)
+
+	var tokenCounts, digitCounts []int
+	if hasTokenOffsets(tokens) {
+		tokenCounts, digitCounts = n.countFromOffsets(document, tokens)
+	} else {
+		tokenCounts, digitCounts = n.countFromTokenText(tokens)
+	}
+
+	if len(tokenCounts) == 0 {
+		return metrics, nil
+	}
+
+	singleToken := 0
+	perDigit := 0
+	for i, count := range tokenCounts {
+		if count == 1 {
+			singleToken++
+		}
+		if digitCounts[i] > 1 && count >= digitCounts[i] {
+			perDigit++
+		}
+	}
+
+	total := float64(len(tokenCounts))
+	metrics["number_count"] = total
+	metrics["avg_tokens_per_number"] = stats.Mean(tokenCounts)
+	metrics["single_token_ratio"] = float64(singleToken) / total
+	metrics["per_digit_ratio"] = float64(perDigit) / total
+
+	return metrics, nil
+}
+
+// countFromOffsets locates numeric spans in the document and counts overlapping tokens
+func (n *NumericCalculator) countFromOffsets(document string, tokens []tokenizers.Token) ([]int, []int) {
+	matches := n.pattern.FindAllStringIndex(document, -1)
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	spans := make([]textSpan, len(matches))
+	digits := make([]int, len(matches))
+	for i, match := range matches {
+		spans[i] = textSpan{Start: match[0], End: match[1]}
+		digits[i] = countDigits(document[match[0]:match[1]])
+	}
+
+	tokenSpans := make([]textSpan, len(tokens))
+	for i, token := range tokens {
+		tokenSpans[i] = textSpan{Start: token.StartPos, End: token.EndPos}
+	}
+
+	counts := countTokensPerSpan(spans, tokenSpans)
+
+	// Drop spans no token covers, e.g. when offsets do not reach that far
+	tokenCounts := make([]int, 0, len(counts))
+	digitCounts := make([]int, 0, len(counts))
+	for i, count := range counts {
+		if count > 0 {
+			tokenCounts = append(tokenCounts, count)
+			digitCounts = append(digitCounts, digits[i])
+		}
+	}
+
+	return tokenCounts, digitCounts
+}
+
+// countFromTokenText groups consecutive tokens whose text matches the numeric pattern,
+// or is a lone separator between such tokens, into numeric literals
+func (n *NumericCalculator) countFromTokenText(tokens []tokenizers.Token) ([]int, []int) {
+	var tokenCounts, digitCounts []int
+	run, runDigits := 0, 0
+
+	flush := func() {
+		if run > 0 && runDigits > 0 {
+			tokenCounts = append(tokenCounts, run)
+			digitCounts = append(digitCounts, runDigits)
+		}
+		run, runDigits = 0, 0
+	}
+
+	for i, token := range tokens {
+		text := strings.TrimSpace(subwordMarkers.Replace(token.Text))
+		leadingSpace := strings.HasPrefix(token.Text, " ") || strings.HasPrefix(token.Text, "Ġ") || strings.HasPrefix(token.Text, "▁")
+
+		isNumber := text != "" && n.pattern.FindString(text) == text
+		isSeparator := (text == "," || text == ".") && run > 0 && i+1 < len(tokens) && countDigits(tokens[i+1].Text) > 0
+
+		switch {
+		case isNumber && (run == 0 || !leadingSpace):
+			run++
+			runDigits += countDigits(text)
+		case isNumber:
+			// A leading space starts a new literal
+			flush()
+			run, runDigits = 1, countDigits(text)
+		case isSeparator:
+			run++
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return tokenCounts, digitCounts
+}
+
+// countDigits counts the decimal digits in text
+func countDigits(text string) int {
+	count := 0
+	for _, r := range text {
+		if unicode.IsDigit(r) {
+			count++
+		}
+	}
+	return count
+}
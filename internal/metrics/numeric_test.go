@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+func TestNumericStatsWithOffsets(t *testing.T) {
+	// "12345" is split per digit into five tokens, "1,000" is one token and "3.5"
+	// is split into "3", ".", "5"
+	document := "pay 12345 or 1,000 then 3.5"
+	spans := [][2]int{
+		{0, 3},
+		{4, 5}, {5, 6}, {6, 7}, {7, 8}, {8, 9},
+		{10, 12},
+		{13, 18},
+		{19, 23},
+		{24, 25}, {25, 26}, {26, 27},
+	}
+
+	calc, err := NewNumericCalculator("")
+	if err != nil {
+		t.Fatalf("NewNumericCalculator returned error: %v", err)
+	}
+
+	stats, err := calc.CalculateNumericStats(document, tokenizationFromSpans(document, spans...).Tokens)
+	if err != nil {
+		t.Fatalf("CalculateNumericStats returned error: %v", err)
+	}
+
+	want := map[string]float64{
+		"number_count":          3,
+		"avg_tokens_per_number": 3,
+		"single_token_ratio":    1.0 / 3.0,
+		"per_digit_ratio":       2.0 / 3.0,
+	}
+	for key, value := range want {
+		if got := stats[key]; math.Abs(got-value) > floatTolerance {
+			t.Errorf("%s = %v, want %v", key, got, value)
+		}
+	}
+}
+
+func TestNumericStatsFromTokenText(t *testing.T) {
+	// GPT-2 style tokens without offsets: "Ġ2024" whole, "Ġ98" + "765" split in two
+	tokens := []tokenizers.Token{
+		{Text: "In"}, {Text: "Ġ2024"}, {Text: "Ġwe"}, {Text: "Ġsold"}, {Text: "Ġ98"}, {Text: "765"}, {Text: "Ġunits"},
+	}
+
+	calc, err := NewNumericCalculator("")
+	if err != nil {
+		t.Fatalf("NewNumericCalculator returned error: %v", err)
+	}
+
+	stats, err := calc.CalculateNumericStats("", tokens)
+	if err != nil {
+		t.Fatalf("CalculateNumericStats returned error: %v", err)
+	}
+
+	want := map[string]float64{
+		"number_count":          2,
+		"avg_tokens_per_number": 1.5,
+		"single_token_ratio":    0.5,
+		"per_digit_ratio":       0,
+	}
+	for key, value := range want {
+		if got := stats[key]; math.Abs(got-value) > floatTolerance {
+			t.Errorf("%s = %v, want %v", key, got, value)
+		}
+	}
+}
+
+func TestNumericPatternValidation(t *testing.T) {
+	if _, err := NewNumericCalculator("(["); err == nil {
+		t.Error("expected error for invalid numeric pattern")
+	}
+
+	engine := NewEngine(EngineConfig{NumericPattern: "(["})
+	if err := engine.ValidateConfig(); err == nil {
+		t.Error("expected ValidateConfig to reject an invalid numeric pattern")
+	}
+}
+
+func TestEngineNumericPattern(t *testing.T) {
+	document := "pay 1,000 now"
+	tokenization := tokenizationFromSpans(document, [2]int{0, 3}, [2]int{3, 5}, [2]int{5, 6}, [2]int{6, 9}, [2]int{9, 13})
+	tokenizer := tokenizers.NewCharTokenizer("char")
+
+	tests := []struct {
+		name    string
+		pattern string
+		want    float64 // numeric_number_count; -1 when the metric is absent
+	}{
+		{name: "default", pattern: "", want: 1},
+		{name: "digit runs", pattern: `\d+`, want: 2},
+		{name: "invalid", pattern: "([", want: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngine(EngineConfig{NumericPattern: tt.pattern})
+			// The compiled pattern is reused for every document
+			for i := 0; i < 2; i++ {
+				result, err := engine.AnalyzeTokenization(context.Background(), document, tokenization, tokenizer)
+				if err != nil {
+					t.Fatalf("AnalyzeTokenization returned error: %v", err)
+				}
+				got := -1.0
+				if metric, ok := result.Metrics["numeric_number_count"]; ok {
+					got = metric.Value
+				}
+				if got != tt.want {
+					t.Errorf("numeric_number_count = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// perturbation is a superficial rewrite of a document used to probe tokenizer sensitivity
+type perturbation struct {
+	name  string
+	apply func(string) string
+}
+
+// perturbations lists the document variants compared against the original
+var perturbations = []perturbation{
+	{name: "lowercase", apply: strings.ToLower},
+	{name: "nfc", apply: norm.NFC.String},
+	{name: "nfd", apply: norm.NFD.String},
+	{name: "whitespace", apply: func(text string) string {
+		return strings.Join(strings.Fields(text), " ")
+	}},
+}
+
+// AnalyzePerturbations tokenizes the document and a set of superficially altered
+// variants (lowercased, NFC, NFD, whitespace-collapsed) and reports, for each variant,
+// the token count delta and the Jaccard distance from the original tokenization
+func (e *Engine) AnalyzePerturbations(ctx context.Context, document string, tokenizer tokenizers.Tokenizer) (map[string]float64, error) {
+	original, err := tokenizer.Tokenize(ctx, document)
+	if err != nil {
+		return nil, fmt.Errorf("error tokenizing document: %w", err)
+	}
+
+	return e.analyzePerturbations(ctx, document, original.Tokens, tokenizer)
+}
+
+// analyzePerturbations compares already tokenized original tokens against each variant
+func (e *Engine) analyzePerturbations(ctx context.Context, document string, original []tokenizers.Token, tokenizer tokenizers.Tokenizer) (map[string]float64, error) {
+	stats := make(map[string]float64)
+	driftCalc := NewDriftCalculator(0.5)
+
+	for _, p := range perturbations {
+		variant, err := tokenizer.Tokenize(ctx, p.apply(document))
+		if err != nil {
+			return nil, fmt.Errorf("error tokenizing %s variant: %w", p.name, err)
+		}
+
+		jaccard, err := driftCalc.CalculateJaccardDistance(original, variant.Tokens)
+		if err != nil {
+			return nil, fmt.Errorf("error comparing %s variant: %w", p.name, err)
+		}
+
+		stats[p.name+"_token_delta"] = float64(len(variant.Tokens) - len(original))
+		stats[p.name+"_jaccard"] = jaccard
+	}
+
+	return stats, nil
+}
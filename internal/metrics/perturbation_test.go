@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+func TestAnalyzePerturbations(t *testing.T) {
+	tokenizer := tokenizers.NewMockTokenizer("mock")
+	if err := tokenizer.Initialize(tokenizers.TokenizerConfig{Name: "mock", Type: "custom"}); err != nil {
+		t.Fatalf("failed to initialize mock tokenizer: %v", err)
+	}
+
+	engine := NewEngine(EngineConfig{EntropyWindowSize: 10})
+
+	// "Café" is written with a precomposed é, so only NFD changes its encoding
+	stats, err := engine.AnalyzePerturbations(context.Background(), "Hello  Café world", tokenizer)
+	if err != nil {
+		t.Fatalf("AnalyzePerturbations returned error: %v", err)
+	}
+
+	want := map[string]float64{
+		"lowercase_token_delta":  0,
+		"lowercase_jaccard":      1 - 1.0/5.0, // only "world" is shared among 5 types
+		"nfc_token_delta":        0,
+		"nfc_jaccard":            0,
+		"nfd_token_delta":        0,
+		"nfd_jaccard":            1 - 2.0/4.0, // "Café" differs, "Hello" and "world" match
+		"whitespace_token_delta": 0,
+		"whitespace_jaccard":     0,
+	}
+
+	for key, value := range want {
+		got, ok := stats[key]
+		if !ok {
+			t.Errorf("missing %s", key)
+			continue
+		}
+		if got != value {
+			t.Errorf("%s = %v, want %v", key, got, value)
+		}
+	}
+}
+
+func TestAnalyzeDocumentPerturbationsOptIn(t *testing.T) {
+	tokenizer := tokenizers.NewMockTokenizer("mock")
+	if err := tokenizer.Initialize(tokenizers.TokenizerConfig{Name: "mock", Type: "custom"}); err != nil {
+		t.Fatalf("failed to initialize mock tokenizer: %v", err)
+	}
+
+	for _, enabled := range []bool{false, true} {
+		engine := NewEngine(EngineConfig{EntropyWindowSize: 10, Perturbations: enabled})
+		result, err := engine.AnalyzeDocument(context.Background(), "Some Text", tokenizer)
+		if err != nil {
+			t.Fatalf("AnalyzeDocument returned error: %v", err)
+		}
+
+		if _, ok := result.Metrics["perturbation_lowercase_jaccard"]; ok != enabled {
+			t.Errorf("perturbation metrics present = %v with Perturbations = %v", ok, enabled)
+		}
+	}
+}
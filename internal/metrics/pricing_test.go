@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+func TestAnalyzeDocumentPricing(t *testing.T) {
+	tests := []struct {
+		name            string
+		tokenizerName   string
+		parameters      map[string]string
+		wantCost        float64
+		wantUtilization float64
+		wantExceeds     bool
+		wantPricing     bool
+	}{
+		{
+			name:          "no pricing info",
+			tokenizerName: "mock",
+			wantPricing:   false,
+		},
+		{
+			name:            "built-in model defaults",
+			tokenizerName:   "gpt-4",
+			wantCost:        10.0 / 1000.0 * 0.03,
+			wantUtilization: 10.0 / 8192.0,
+			wantPricing:     true,
+		},
+		{
+			name:            "configured overrides",
+			tokenizerName:   "mock",
+			parameters:      map[string]string{"price_per_1k_tokens": "2", "context_window": "8"},
+			wantCost:        0.02,
+			wantUtilization: 10.0 / 8.0,
+			wantExceeds:     true,
+			wantPricing:     true,
+		},
+	}
+
+	document := strings.Repeat("word ", 10)
+	engine := NewEngine(EngineConfig{EntropyWindowSize: 10})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokenizer := tokenizers.NewMockTokenizer(tt.tokenizerName)
+			config := tokenizers.TokenizerConfig{Name: tt.tokenizerName, Type: "custom", Parameters: tt.parameters}
+			if err := tokenizer.Initialize(config); err != nil {
+				t.Fatalf("failed to initialize mock tokenizer: %v", err)
+			}
+
+			result, err := engine.AnalyzeDocument(context.Background(), document, tokenizer)
+			if err != nil {
+				t.Fatalf("AnalyzeDocument returned error: %v", err)
+			}
+
+			cost, hasCost := result.Metrics["cost_estimate_usd"]
+			utilization, hasUtilization := result.Metrics["context_window_utilization"]
+			if hasCost != tt.wantPricing || hasUtilization != tt.wantPricing {
+				t.Fatalf("pricing metrics present = (%v, %v), want %v", hasCost, hasUtilization, tt.wantPricing)
+			}
+			if !tt.wantPricing {
+				return
+			}
+
+			if math.Abs(cost.Value-tt.wantCost) > floatTolerance {
+				t.Errorf("cost_estimate_usd = %v, want %v", cost.Value, tt.wantCost)
+			}
+			if math.Abs(utilization.Value-tt.wantUtilization) > floatTolerance {
+				t.Errorf("context_window_utilization = %v, want %v", utilization.Value, tt.wantUtilization)
+			}
+			if exceeds := utilization.Metadata["exceeds_context"]; exceeds != tt.wantExceeds {
+				t.Errorf("exceeds_context = %v, want %v", exceeds, tt.wantExceeds)
+			}
+		})
+	}
+}
@@ -1,24 +1,41 @@
 package metrics
 
 import (
+	"fmt"
 	"math"
 	"sort"
 
+	"github.com/RevBooyah/TokEntropyDrift/internal/stats"
 	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
 )
 
+// DefaultMATTRWindow is the moving-average type-token ratio window used unless
+// overridden with SetMATTRWindow
+const DefaultMATTRWindow = 50
+
+// mtldThreshold is the type-token ratio at which MTLD closes a factor, as proposed
+// by McCarthy and Jarvis (2010)
+const mtldThreshold = 0.72
+
 // ReuseCalculator handles token reuse and frequency analysis
 type ReuseCalculator struct {
 	includePatterns bool
+	mattrWindow     int
 }
 
 // NewReuseCalculator creates a new reuse calculator
 func NewReuseCalculator(includePatterns bool) *ReuseCalculator {
 	return &ReuseCalculator{
 		includePatterns: includePatterns,
+		mattrWindow:     DefaultMATTRWindow,
 	}
 }
 
+// SetMATTRWindow sets the window size for the moving-average type-token ratio
+func (r *ReuseCalculator) SetMATTRWindow(window int) {
+	r.mattrWindow = window
+}
+
 // CalculateTokenReuse calculates basic token reuse metrics
 func (r *ReuseCalculator) CalculateTokenReuse(tokens []tokenizers.Token) (float64, error) {
 	if len(tokens) == 0 {
@@ -40,12 +57,11 @@ func (r *ReuseCalculator) CalculateTokenReuse(tokens []tokenizers.Token) (float6
 	return reuseRatio, nil
 }
 
-// CalculateTokenFrequency calculates detailed token frequency statistics
-func (r *ReuseCalculator) CalculateTokenFrequency(tokens []tokenizers.Token) (map[string]interface{}, error) {
-	stats := make(map[string]interface{})
-
+// CalculateTokenFrequency calculates detailed token frequency statistics.
+// It returns nil when there are no tokens.
+func (r *ReuseCalculator) CalculateTokenFrequency(tokens []tokenizers.Token) (*FrequencyStats, error) {
 	if len(tokens) == 0 {
-		return stats, nil
+		return nil, nil
 	}
 
 	// Count token frequencies
@@ -59,63 +75,53 @@ func (r *ReuseCalculator) CalculateTokenFrequency(tokens []tokenizers.Token) (ma
 	for _, freq := range tokenFreq {
 		frequencies = append(frequencies, freq)
 	}
-	sort.Ints(frequencies)
-
-	// Basic statistics
-	stats["unique_tokens"] = len(tokenFreq)
-	stats["total_tokens"] = len(tokens)
-	stats["reuse_ratio"] = 1.0 - (float64(len(tokenFreq)) / float64(len(tokens)))
-
-	// Frequency distribution
-	stats["min_frequency"] = frequencies[0]
-	stats["max_frequency"] = frequencies[len(frequencies)-1]
-	stats["median_frequency"] = calculateMedian(frequencies)
-	stats["mean_frequency"] = calculateMeanInt(frequencies)
-	stats["frequency_std"] = calculateStdInt(frequencies)
-
-	// Most frequent tokens
-	mostFrequent := r.getMostFrequentTokens(tokenFreq, 10)
-	stats["most_frequent_tokens"] = mostFrequent
-
-	// Frequency percentiles
-	stats["freq_percentile_25"] = calculatePercentile(frequencies, 25)
-	stats["freq_percentile_50"] = calculatePercentile(frequencies, 50)
-	stats["freq_percentile_75"] = calculatePercentile(frequencies, 75)
-	stats["freq_percentile_90"] = calculatePercentile(frequencies, 90)
-	stats["freq_percentile_95"] = calculatePercentile(frequencies, 95)
-
-	return stats, nil
+	percentiles := stats.Percentiles(frequencies, 25, 50, 75, 90, 95)
+
+	return &FrequencyStats{
+		// Basic statistics
+		UniqueTokens: len(tokenFreq),
+		TotalTokens:  len(tokens),
+		ReuseRatio:   1.0 - (float64(len(tokenFreq)) / float64(len(tokens))),
+
+		// Frequency distribution
+		MinFrequency:    stats.Min(frequencies),
+		MaxFrequency:    stats.Max(frequencies),
+		MedianFrequency: stats.Median(frequencies),
+		MeanFrequency:   stats.Mean(frequencies),
+		FrequencyStd:    stats.Std(frequencies),
+
+		// Most frequent tokens
+		MostFrequentTokens: r.getMostFrequentTokens(tokenFreq, 10),
+
+		// Frequency percentiles
+		Percentile25: percentiles[0],
+		Percentile50: percentiles[1],
+		Percentile75: percentiles[2],
+		Percentile90: percentiles[3],
+		Percentile95: percentiles[4],
+	}, nil
 }
 
-// CalculateReusePatterns analyzes patterns in token reuse
-func (r *ReuseCalculator) CalculateReusePatterns(tokens []tokenizers.Token) (map[string]interface{}, error) {
-	patterns := make(map[string]interface{})
-
+// CalculateReusePatterns analyzes patterns in token reuse.
+// It returns nil when there are no tokens.
+func (r *ReuseCalculator) CalculateReusePatterns(tokens []tokenizers.Token) (*ReusePatterns, error) {
 	if len(tokens) == 0 {
-		return patterns, nil
+		return nil, nil
 	}
 
-	// Analyze consecutive reuse patterns
-	consecutivePatterns := r.analyzeConsecutivePatterns(tokens)
-	patterns["consecutive_patterns"] = consecutivePatterns
-
-	// Analyze distance patterns
-	distancePatterns := r.analyzeDistancePatterns(tokens)
-	patterns["distance_patterns"] = distancePatterns
-
-	// Analyze burst patterns
-	burstPatterns := r.analyzeBurstPatterns(tokens)
-	patterns["burst_patterns"] = burstPatterns
-
-	return patterns, nil
+	return &ReusePatterns{
+		Consecutive: r.analyzeConsecutivePatterns(tokens),
+		Distance:    r.analyzeDistancePatterns(tokens),
+		Burst:       r.analyzeBurstPatterns(tokens),
+	}, nil
 }
 
 // analyzeConsecutivePatterns analyzes consecutive token reuse
-func (r *ReuseCalculator) analyzeConsecutivePatterns(tokens []tokenizers.Token) map[string]interface{} {
-	patterns := make(map[string]interface{})
+func (r *ReuseCalculator) analyzeConsecutivePatterns(tokens []tokenizers.Token) ConsecutiveStats {
+	var stats ConsecutiveStats
 
 	if len(tokens) < 2 {
-		return patterns
+		return stats
 	}
 
 	consecutiveCount := 0
@@ -128,20 +134,14 @@ func (r *ReuseCalculator) analyzeConsecutivePatterns(tokens []tokenizers.Token)
 		totalConsecutive++
 	}
 
-	patterns["consecutive_reuse_ratio"] = float64(consecutiveCount) / float64(totalConsecutive)
-	patterns["consecutive_reuse_count"] = consecutiveCount
+	stats.ReuseRatio = float64(consecutiveCount) / float64(totalConsecutive)
+	stats.ReuseCount = consecutiveCount
 
-	return patterns
+	return stats
 }
 
 // analyzeDistancePatterns analyzes distance between token reuse
-func (r *ReuseCalculator) analyzeDistancePatterns(tokens []tokenizers.Token) map[string]interface{} {
-	patterns := make(map[string]interface{})
-
-	if len(tokens) == 0 {
-		return patterns
-	}
-
+func (r *ReuseCalculator) analyzeDistancePatterns(tokens []tokenizers.Token) DistanceStats {
 	// Track last occurrence of each token
 	lastOccurrence := make(map[string]int)
 	distances := make([]int, 0)
@@ -155,34 +155,22 @@ func (r *ReuseCalculator) analyzeDistancePatterns(tokens []tokenizers.Token) map
 	}
 
 	if len(distances) == 0 {
-		patterns["avg_reuse_distance"] = 0.0
-		patterns["min_reuse_distance"] = 0
-		patterns["max_reuse_distance"] = 0
-		return patterns
+		return DistanceStats{}
 	}
 
 	// Calculate distance statistics
-	sort.Ints(distances)
-	patterns["avg_reuse_distance"] = calculateMeanInt(distances)
-	patterns["min_reuse_distance"] = distances[0]
-	patterns["max_reuse_distance"] = distances[len(distances)-1]
-	patterns["median_reuse_distance"] = calculateMedianInt(distances)
-
-	return patterns
+	return DistanceStats{
+		Avg:    stats.Mean(distances),
+		Min:    stats.Min(distances),
+		Max:    stats.Max(distances),
+		Median: stats.Median(distances),
+	}
 }
 
 // analyzeBurstPatterns analyzes burst patterns in token usage
-func (r *ReuseCalculator) analyzeBurstPatterns(tokens []tokenizers.Token) map[string]interface{} {
-	patterns := make(map[string]interface{})
-
+func (r *ReuseCalculator) analyzeBurstPatterns(tokens []tokenizers.Token) BurstStats {
 	if len(tokens) == 0 {
-		return patterns
-	}
-
-	// Count token frequencies
-	tokenFreq := make(map[string]int)
-	for _, token := range tokens {
-		tokenFreq[token.Text]++
+		return BurstStats{}
 	}
 
 	// Find burst tokens (tokens that appear multiple times in sequence)
@@ -209,24 +197,20 @@ func (r *ReuseCalculator) analyzeBurstPatterns(tokens []tokenizers.Token) map[st
 
 	// Calculate burst statistics
 	if len(burstTokens) == 0 {
-		patterns["burst_count"] = 0
-		patterns["max_burst_size"] = 0
-		patterns["avg_burst_size"] = 0.0
-		return patterns
+		return BurstStats{}
 	}
 
 	burstSizes := make([]int, 0, len(burstTokens))
 	for _, size := range burstTokens {
 		burstSizes = append(burstSizes, size)
 	}
-	sort.Ints(burstSizes)
 
-	patterns["burst_count"] = len(burstTokens)
-	patterns["max_burst_size"] = burstSizes[len(burstSizes)-1]
-	patterns["avg_burst_size"] = calculateMeanInt(burstSizes)
-	patterns["median_burst_size"] = calculateMedianInt(burstSizes)
-
-	return patterns
+	return BurstStats{
+		Count:      len(burstTokens),
+		MaxSize:    stats.Max(burstSizes),
+		AvgSize:    stats.Mean(burstSizes),
+		MedianSize: stats.Median(burstSizes),
+	}
 }
 
 // CalculateReuseEfficiency calculates efficiency metrics related to token reuse
@@ -276,126 +260,268 @@ func (r *ReuseCalculator) CalculateReuseEfficiency(tokens []tokenizers.Token) (m
 }
 
 // CalculateReuseStats calculates comprehensive reuse statistics
-func (r *ReuseCalculator) CalculateReuseStats(tokens []tokenizers.Token) (map[string]interface{}, error) {
-	stats := make(map[string]interface{})
+func (r *ReuseCalculator) CalculateReuseStats(tokens []tokenizers.Token) (*ReuseStats, error) {
+	stats := &ReuseStats{}
 
 	// Basic reuse ratio
 	if reuseRatio, err := r.CalculateTokenReuse(tokens); err == nil {
-		stats["reuse_ratio"] = reuseRatio
+		stats.ReuseRatio = reuseRatio
 	}
 
 	// Token frequency analysis
 	if freqStats, err := r.CalculateTokenFrequency(tokens); err == nil {
-		for k, v := range freqStats {
-			stats["freq_"+k] = v
-		}
+		stats.Frequency = freqStats
 	}
 
 	// Reuse patterns
 	if r.includePatterns {
 		if patternStats, err := r.CalculateReusePatterns(tokens); err == nil {
-			for k, v := range patternStats {
-				stats["pattern_"+k] = v
-			}
+			stats.Patterns = patternStats
 		}
 	}
 
+	// Zipf distribution fit
+	if zipfStats, err := r.CalculateZipfFit(tokens); err == nil {
+		stats.Zipf = zipfStats
+	}
+
 	// Reuse efficiency
 	if efficiencyStats, err := r.CalculateReuseEfficiency(tokens); err == nil {
-		for k, v := range efficiencyStats {
-			stats["efficiency_"+k] = v
-		}
+		stats.Efficiency = efficiencyStats
+	}
+
+	// Order-dependent lexical diversity
+	if diversityStats, err := r.CalculateLexicalDiversity(tokens); err == nil {
+		stats.Diversity = diversityStats
 	}
 
 	return stats, nil
 }
 
-// Helper functions
-func (r *ReuseCalculator) getMostFrequentTokens(tokenFreq map[string]int, count int) []map[string]interface{} {
-	type tokenFreqPair struct {
-		token string
-		freq  int
+// CalculateLexicalDiversity calculates MTLD and the moving-average type-token ratio.
+// It returns nil when there are no tokens.
+func (r *ReuseCalculator) CalculateLexicalDiversity(tokens []tokenizers.Token) (*DiversityStats, error) {
+	if len(tokens) == 0 {
+		return nil, nil
 	}
 
-	pairs := make([]tokenFreqPair, 0, len(tokenFreq))
-	for token, freq := range tokenFreq {
-		pairs = append(pairs, tokenFreqPair{token, freq})
+	mtld, err := r.CalculateMTLD(tokens)
+	if err != nil {
+		return nil, err
 	}
 
-	// Sort by frequency (descending)
-	sort.Slice(pairs, func(i, j int) bool {
-		return pairs[i].freq > pairs[j].freq
-	})
+	window := r.mattrWindow
+	if window <= 0 {
+		window = DefaultMATTRWindow
+	}
 
-	// Take top N
-	if count > len(pairs) {
-		count = len(pairs)
+	mattr, plainTTR, err := r.CalculateMATTR(tokens, window)
+	if err != nil {
+		return nil, err
 	}
 
-	result := make([]map[string]interface{}, count)
-	for i := 0; i < count; i++ {
-		result[i] = map[string]interface{}{
-			"token":     pairs[i].token,
-			"frequency": pairs[i].freq,
-		}
+	return &DiversityStats{
+		MTLD:        mtld,
+		MATTR:       mattr,
+		MATTRWindow: window,
+		PlainTTR:    plainTTR,
+	}, nil
+}
+
+// CalculateMTLD calculates the Measure of Textual Lexical Diversity: the mean length
+// of the sequential segments over which the type-token ratio stays above 0.72,
+// averaged over a forward and a backward pass. Higher values mean more diverse text.
+// A sequence too short to close any (even partial) factor reports zero.
+func (r *ReuseCalculator) CalculateMTLD(tokens []tokenizers.Token) (float64, error) {
+	if len(tokens) == 0 {
+		return 0.0, nil
+	}
+
+	texts := make([]string, len(tokens))
+	for i, token := range tokens {
+		texts[i] = token.Text
 	}
 
-	return result
+	forward := mtldPass(texts)
+	backward := mtldPass(reversed(texts))
+
+	return (forward + backward) / 2, nil
 }
 
-func calculateMedian(values []int) float64 {
-	if len(values) == 0 {
+// mtldPass runs one directional MTLD pass: factors are counted each time the running
+// type-token ratio falls to the threshold, and the remainder adds a partial factor
+// proportional to how far its ratio has dropped towards the threshold
+func mtldPass(texts []string) float64 {
+	factors := 0.0
+	types := make(map[string]bool)
+	count := 0
+
+	for _, text := range texts {
+		types[text] = true
+		count++
+		if float64(len(types))/float64(count) <= mtldThreshold {
+			factors++
+			types = make(map[string]bool)
+			count = 0
+		}
+	}
+
+	if count > 0 {
+		ttr := float64(len(types)) / float64(count)
+		factors += (1 - ttr) / (1 - mtldThreshold)
+	}
+
+	if factors == 0 {
 		return 0.0
 	}
+	return float64(len(texts)) / factors
+}
+
+// CalculateMATTR calculates the moving-average type-token ratio: the mean TTR over
+// every window of the given size. When the sequence is shorter than the window the
+// plain TTR of the whole sequence is returned and plainTTR is set.
+func (r *ReuseCalculator) CalculateMATTR(tokens []tokenizers.Token, window int) (mattr float64, plainTTR bool, err error) {
+	if window <= 0 {
+		return 0.0, false, fmt.Errorf("MATTR window must be positive, got %d", window)
+	}
 
-	sort.Ints(values)
-	if len(values)%2 == 0 {
-		return float64(values[len(values)/2-1]+values[len(values)/2]) / 2.0
+	if len(tokens) == 0 {
+		return 0.0, false, nil
 	}
-	return float64(values[len(values)/2])
+
+	if len(tokens) < window {
+		unique := make(map[string]bool)
+		for _, token := range tokens {
+			unique[token.Text] = true
+		}
+		return float64(len(unique)) / float64(len(tokens)), true, nil
+	}
+
+	// Slide the window, maintaining type counts incrementally
+	counts := make(map[string]int)
+	for _, token := range tokens[:window] {
+		counts[token.Text]++
+	}
+
+	total := float64(len(counts))
+	windows := 1
+	for i := window; i < len(tokens); i++ {
+		outgoing := tokens[i-window].Text
+		counts[outgoing]--
+		if counts[outgoing] == 0 {
+			delete(counts, outgoing)
+		}
+		counts[tokens[i].Text]++
+
+		total += float64(len(counts))
+		windows++
+	}
+
+	return total / float64(windows) / float64(window), false, nil
 }
 
-func calculateMedianInt(values []int) int {
-	if len(values) == 0 {
-		return 0
+// CalculateZipfFit fits a power law f(r) ∝ r^-s to the rank-frequency distribution of
+// tokens using least squares on log-log axes. It reports the exponent s, the R² of
+// the fit and the share of all tokens covered by the 10 and 100 most frequent types.
+// Distributions with a single type or uniform frequencies have no slope to fit and
+// report an exponent and R² of zero.
+func (r *ReuseCalculator) CalculateZipfFit(tokens []tokenizers.Token) (map[string]float64, error) {
+	stats := make(map[string]float64)
+
+	if len(tokens) == 0 {
+		return stats, nil
+	}
+
+	tokenFreq := make(map[string]int)
+	for _, token := range tokens {
+		tokenFreq[token.Text]++
 	}
 
-	sort.Ints(values)
-	if len(values)%2 == 0 {
-		return (values[len(values)/2-1] + values[len(values)/2]) / 2
+	frequencies := make([]int, 0, len(tokenFreq))
+	for _, freq := range tokenFreq {
+		frequencies = append(frequencies, freq)
 	}
-	return values[len(values)/2]
+	sort.Sort(sort.Reverse(sort.IntSlice(frequencies)))
+
+	// Mass covered by the most frequent types
+	total := float64(len(tokens))
+	stats["top10_mass"] = float64(sumTopInt(frequencies, 10)) / total
+	stats["top100_mass"] = float64(sumTopInt(frequencies, 100)) / total
+
+	stats["zipf_exponent"] = 0.0
+	stats["zipf_r2"] = 0.0
+
+	if len(frequencies) < 2 {
+		return stats, nil
+	}
+
+	// Least-squares regression of log(frequency) on log(rank)
+	n := float64(len(frequencies))
+	xs := make([]float64, len(frequencies))
+	ys := make([]float64, len(frequencies))
+	meanX, meanY := 0.0, 0.0
+	for i, freq := range frequencies {
+		xs[i] = math.Log(float64(i + 1))
+		ys[i] = math.Log(float64(freq))
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX /= n
+	meanY /= n
+
+	sxx, sxy, syy := 0.0, 0.0, 0.0
+	for i := range xs {
+		dx := xs[i] - meanX
+		dy := ys[i] - meanY
+		sxx += dx * dx
+		sxy += dx * dy
+		syy += dy * dy
+	}
+
+	// Uniform frequencies give a flat line with nothing to explain
+	if sxx == 0 || syy == 0 {
+		return stats, nil
+	}
+
+	slope := sxy / sxx
+	stats["zipf_exponent"] = -slope
+	stats["zipf_r2"] = (sxy * sxy) / (sxx * syy)
+
+	return stats, nil
 }
 
-func calculateMeanInt(values []int) float64 {
-	if len(values) == 0 {
-		return 0.0
+// sumTopInt sums the first n values of a slice sorted in descending order
+func sumTopInt(values []int, n int) int {
+	if n > len(values) {
+		n = len(values)
 	}
+
 	sum := 0
-	for _, v := range values {
+	for _, v := range values[:n] {
 		sum += v
 	}
-	return float64(sum) / float64(len(values))
+	return sum
 }
 
-func calculatePercentile(values []int, percentile int) int {
-	if len(values) == 0 {
-		return 0
+// Helper functions
+func (r *ReuseCalculator) getMostFrequentTokens(tokenFreq map[string]int, count int) []TokenFrequency {
+	pairs := make([]TokenFrequency, 0, len(tokenFreq))
+	for token, freq := range tokenFreq {
+		pairs = append(pairs, TokenFrequency{Token: token, Frequency: freq})
 	}
 
-	sort.Ints(values)
-	index := int(float64(percentile) / 100.0 * float64(len(values)-1))
-	return values[index]
-}
+	// Sort by frequency (descending), breaking ties by token for stable output
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Frequency != pairs[j].Frequency {
+			return pairs[i].Frequency > pairs[j].Frequency
+		}
+		return pairs[i].Token < pairs[j].Token
+	})
 
-func calculateStdInt(values []int) float64 {
-	if len(values) == 0 {
-		return 0.0
-	}
-	mean := calculateMeanInt(values)
-	sum := 0.0
-	for _, v := range values {
-		sum += (float64(v) - mean) * (float64(v) - mean)
+	// Take top N
+	if count > len(pairs) {
+		count = len(pairs)
 	}
-	return math.Sqrt(sum / float64(len(values)))
+
+	return pairs[:count]
 }
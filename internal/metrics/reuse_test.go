@@ -0,0 +1,315 @@
+package metrics
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+func TestZipfFit(t *testing.T) {
+	tests := []struct {
+		name         string
+		counts       []int
+		wantExponent float64
+		wantR2       float64
+		wantTop10    float64
+	}{
+		{
+			name:         "single type",
+			counts:       []int{5},
+			wantExponent: 0,
+			wantR2:       0,
+			wantTop10:    1,
+		},
+		{
+			name:         "all unique",
+			counts:       []int{1, 1, 1, 1},
+			wantExponent: 0,
+			wantR2:       0,
+			wantTop10:    1,
+		},
+		{
+			// f(r) = 12 / r is an exact Zipf law with exponent 1
+			name:         "exact zipf",
+			counts:       []int{12, 6, 4, 3},
+			wantExponent: 1,
+			wantR2:       1,
+			wantTop10:    1,
+		},
+	}
+
+	calc := NewReuseCalculator(false)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var texts []string
+			for i, count := range tt.counts {
+				for j := 0; j < count; j++ {
+					texts = append(texts, strings.Repeat("t", i+1))
+				}
+			}
+
+			stats, err := calc.CalculateZipfFit(tokenizationFromTexts(texts...).Tokens)
+			if err != nil {
+				t.Fatalf("CalculateZipfFit returned error: %v", err)
+			}
+
+			for key, want := range map[string]float64{
+				"zipf_exponent": tt.wantExponent,
+				"zipf_r2":       tt.wantR2,
+				"top10_mass":    tt.wantTop10,
+			} {
+				got := stats[key]
+				if math.IsNaN(got) || math.Abs(got-want) > floatTolerance {
+					t.Errorf("%s = %v, want %v", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestZipfTopMass(t *testing.T) {
+	// 12 types: the first appears 9 times, the rest once each
+	texts := []string{}
+	for i := 0; i < 9; i++ {
+		texts = append(texts, "common")
+	}
+	for i := 0; i < 11; i++ {
+		texts = append(texts, strings.Repeat("r", i+1))
+	}
+
+	stats, err := NewReuseCalculator(false).CalculateZipfFit(tokenizationFromTexts(texts...).Tokens)
+	if err != nil {
+		t.Fatalf("CalculateZipfFit returned error: %v", err)
+	}
+
+	if got, want := stats["top10_mass"], 18.0/20.0; math.Abs(got-want) > floatTolerance {
+		t.Errorf("top10_mass = %v, want %v", got, want)
+	}
+	if got := stats["top100_mass"]; math.Abs(got-1) > floatTolerance {
+		t.Errorf("top100_mass = %v, want 1", got)
+	}
+}
+
+func TestTokenFrequencyPercentiles(t *testing.T) {
+	// Frequencies are a:4, b:1, c:2, d:3, listed out of order to catch helpers
+	// that depend on (or change) the ordering of their input
+	tokens := tokenizationFromTexts("a", "b", "c", "a", "d", "c", "a", "d", "d", "a").Tokens
+
+	freq, err := NewReuseCalculator(false).CalculateTokenFrequency(tokens)
+	if err != nil {
+		t.Fatalf("CalculateTokenFrequency returned error: %v", err)
+	}
+
+	for name, check := range map[string][2]float64{
+		"median": {freq.MedianFrequency, 2.5},
+		"p25":    {freq.Percentile25, 1.75},
+		"p50":    {freq.Percentile50, 2.5},
+		"p75":    {freq.Percentile75, 3.25},
+		"p95":    {freq.Percentile95, 3.85},
+		"min":    {float64(freq.MinFrequency), 1},
+		"max":    {float64(freq.MaxFrequency), 4},
+	} {
+		if math.Abs(check[0]-check[1]) > floatTolerance {
+			t.Errorf("%s = %v, want %v", name, check[0], check[1])
+		}
+	}
+}
+
+func TestReuseStatsReachAnalysisResult(t *testing.T) {
+	tokenizer := tokenizers.NewMockTokenizer("mock")
+	if err := tokenizer.Initialize(tokenizers.TokenizerConfig{Name: "mock", Type: "custom"}); err != nil {
+		t.Fatalf("failed to initialize mock tokenizer: %v", err)
+	}
+
+	engine := NewEngine(EngineConfig{EntropyWindowSize: 10})
+	result, err := engine.AnalyzeDocument(context.Background(), "a a b a c c", tokenizer)
+	if err != nil {
+		t.Fatalf("AnalyzeDocument returned error: %v", err)
+	}
+
+	// Integer statistics and pattern sub-metrics are flattened into metrics
+	for key, want := range map[string]float64{
+		"reuse_freq_unique_tokens":      3,
+		"reuse_freq_freq_percentile_50": 2,
+		"reuse_pattern_burst_count":     2,
+		"reuse_pattern_max_burst_size":  2,
+	} {
+		metric, ok := result.Metrics[key]
+		if !ok {
+			t.Errorf("missing %s", key)
+			continue
+		}
+		if metric.Value != want {
+			t.Errorf("%s = %v, want %v", key, metric.Value, want)
+		}
+	}
+
+	top, ok := result.Metrics["reuse_reuse_ratio"].Metadata["most_frequent_tokens"].([]TokenFrequency)
+	if !ok || len(top) == 0 {
+		t.Fatalf("expected most_frequent_tokens in reuse_reuse_ratio metadata")
+	}
+	if top[0] != (TokenFrequency{Token: "a", Frequency: 3}) {
+		t.Errorf("most frequent token = %+v, want a x3", top[0])
+	}
+}
+
+func TestMTLD(t *testing.T) {
+	tests := []struct {
+		name  string
+		texts []string
+		want  float64
+	}{
+		{
+			// Every second token drops the TTR to 0.5, closing a factor: 4 tokens / 2 factors
+			name:  "immediate repetition",
+			texts: []string{"a", "a", "a", "a"},
+			want:  2,
+		},
+		{
+			// TTR reaches 2/3 on every third token in both directions: 6 / 2
+			name:  "alternating pair",
+			texts: []string{"a", "b", "a", "b", "a", "b"},
+			want:  3,
+		},
+		{
+			// No full factor; the remainder has TTR 0.75 and contributes
+			// (1 - 0.75) / (1 - 0.72) of a factor in each direction
+			name:  "partial factor only",
+			texts: []string{"a", "b", "c", "a"},
+			want:  4 / ((1 - 0.75) / (1 - 0.72)),
+		},
+		{
+			// All tokens distinct: no factor can close
+			name:  "all unique",
+			texts: []string{"a", "b", "c"},
+			want:  0,
+		},
+	}
+
+	calc := NewReuseCalculator(false)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := calc.CalculateMTLD(tokenizationFromTexts(tt.texts...).Tokens)
+			if err != nil {
+				t.Fatalf("CalculateMTLD returned error: %v", err)
+			}
+			if math.Abs(got-tt.want) > floatTolerance {
+				t.Errorf("MTLD = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMATTR(t *testing.T) {
+	tests := []struct {
+		name     string
+		texts    []string
+		window   int
+		want     float64
+		plainTTR bool
+	}{
+		{
+			name:   "alternating pair in windows of two",
+			texts:  []string{"a", "b", "a", "b"},
+			window: 2,
+			want:   1,
+		},
+		{
+			// Windows "a b a" and "b a b" both have TTR 2/3
+			name:   "alternating pair in windows of three",
+			texts:  []string{"a", "b", "a", "b"},
+			window: 3,
+			want:   2.0 / 3.0,
+		},
+		{
+			// Windows: "a a" 1/2, "a b" 1, "b c" 1 -> mean 5/6
+			name:   "varying windows",
+			texts:  []string{"a", "a", "b", "c"},
+			window: 2,
+			want:   5.0 / 6.0,
+		},
+		{
+			name:     "shorter than window falls back to TTR",
+			texts:    []string{"a", "a", "b"},
+			window:   10,
+			want:     2.0 / 3.0,
+			plainTTR: true,
+		},
+	}
+
+	calc := NewReuseCalculator(false)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, plainTTR, err := calc.CalculateMATTR(tokenizationFromTexts(tt.texts...).Tokens, tt.window)
+			if err != nil {
+				t.Fatalf("CalculateMATTR returned error: %v", err)
+			}
+			if math.Abs(got-tt.want) > floatTolerance {
+				t.Errorf("MATTR = %v, want %v", got, tt.want)
+			}
+			if plainTTR != tt.plainTTR {
+				t.Errorf("plainTTR = %v, want %v", plainTTR, tt.plainTTR)
+			}
+		})
+	}
+
+	if _, _, err := calc.CalculateMATTR(tokenizationFromTexts("a").Tokens, 0); err == nil {
+		t.Errorf("expected an error for a zero window")
+	}
+}
+
+func TestDiversityReachesAnalysisResult(t *testing.T) {
+	tokenizer := tokenizers.NewMockTokenizer("mock")
+	if err := tokenizer.Initialize(tokenizers.TokenizerConfig{Name: "mock", Type: "custom"}); err != nil {
+		t.Fatalf("failed to initialize mock tokenizer: %v", err)
+	}
+
+	engine := NewEngine(EngineConfig{EntropyWindowSize: 10, MATTRWindow: 20})
+	result, err := engine.AnalyzeDocument(context.Background(), "a a b a c c", tokenizer)
+	if err != nil {
+		t.Fatalf("AnalyzeDocument returned error: %v", err)
+	}
+
+	if _, ok := result.Metrics["reuse_mtld"]; !ok {
+		t.Errorf("missing reuse_mtld")
+	}
+
+	mattr, ok := result.Metrics["reuse_mattr_w20"]
+	if !ok {
+		t.Fatalf("missing reuse_mattr_w20")
+	}
+	if mattr.Value != 0.5 {
+		t.Errorf("reuse_mattr_w20 = %v, want plain TTR 0.5", mattr.Value)
+	}
+	if mattr.Metadata["plain_ttr"] != true {
+		t.Errorf("expected plain_ttr metadata flag, got %v", mattr.Metadata)
+	}
+}
+
+func TestMATTRMetricNameFollowsWindow(t *testing.T) {
+	tests := []struct {
+		window int
+		want   string
+	}{
+		{window: 0, want: "reuse_mattr_w50"},
+		{window: 20, want: "reuse_mattr_w20"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			engine := NewEngine(EngineConfig{MATTRWindow: tt.window})
+			var names []string
+			for _, name := range engine.GetMetricNames() {
+				if strings.HasPrefix(name, "reuse_mattr") {
+					names = append(names, name)
+				}
+			}
+			if len(names) != 1 || names[0] != tt.want {
+				t.Errorf("MATTR metric names = %v, want [%s]", names, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package metrics
+
+import "fmt"
+
+// TokenFrequency pairs a token with its number of occurrences
+type TokenFrequency struct {
+	Token     string `json:"token"`
+	Frequency int    `json:"frequency"`
+}
+
+// FrequencyStats summarizes the token frequency distribution of a document
+type FrequencyStats struct {
+	UniqueTokens       int              `json:"unique_tokens"`
+	TotalTokens        int              `json:"total_tokens"`
+	ReuseRatio         float64          `json:"reuse_ratio"`
+	MinFrequency       int              `json:"min_frequency"`
+	MaxFrequency       int              `json:"max_frequency"`
+	MedianFrequency    float64          `json:"median_frequency"`
+	MeanFrequency      float64          `json:"mean_frequency"`
+	FrequencyStd       float64          `json:"frequency_std"`
+	MostFrequentTokens []TokenFrequency `json:"most_frequent_tokens"`
+	Percentile25       float64          `json:"freq_percentile_25"`
+	Percentile50       float64          `json:"freq_percentile_50"`
+	Percentile75       float64          `json:"freq_percentile_75"`
+	Percentile90       float64          `json:"freq_percentile_90"`
+	Percentile95       float64          `json:"freq_percentile_95"`
+}
+
+// Metrics returns the numeric fields keyed by their JSON names
+func (f *FrequencyStats) Metrics() map[string]float64 {
+	return map[string]float64{
+		"unique_tokens":      float64(f.UniqueTokens),
+		"total_tokens":       float64(f.TotalTokens),
+		"reuse_ratio":        f.ReuseRatio,
+		"min_frequency":      float64(f.MinFrequency),
+		"max_frequency":      float64(f.MaxFrequency),
+		"median_frequency":   f.MedianFrequency,
+		"mean_frequency":     f.MeanFrequency,
+		"frequency_std":      f.FrequencyStd,
+		"freq_percentile_25": f.Percentile25,
+		"freq_percentile_50": f.Percentile50,
+		"freq_percentile_75": f.Percentile75,
+		"freq_percentile_90": f.Percentile90,
+		"freq_percentile_95": f.Percentile95,
+	}
+}
+
+// ConsecutiveStats describes immediate repetition of the same token
+type ConsecutiveStats struct {
+	ReuseRatio float64 `json:"consecutive_reuse_ratio"`
+	ReuseCount int     `json:"consecutive_reuse_count"`
+}
+
+// DistanceStats describes the gap, in tokens, between repeated occurrences of a token
+type DistanceStats struct {
+	Avg    float64 `json:"avg_reuse_distance"`
+	Min    int     `json:"min_reuse_distance"`
+	Max    int     `json:"max_reuse_distance"`
+	Median float64 `json:"median_reuse_distance"`
+}
+
+// BurstStats describes runs of the same token repeated back to back
+type BurstStats struct {
+	Count      int     `json:"burst_count"`
+	MaxSize    int     `json:"max_burst_size"`
+	AvgSize    float64 `json:"avg_burst_size"`
+	MedianSize float64 `json:"median_burst_size"`
+}
+
+// ReusePatterns groups the sequential reuse analyses
+type ReusePatterns struct {
+	Consecutive ConsecutiveStats `json:"consecutive_patterns"`
+	Distance    DistanceStats    `json:"distance_patterns"`
+	Burst       BurstStats       `json:"burst_patterns"`
+}
+
+// Metrics returns the numeric fields keyed by their JSON names
+func (p *ReusePatterns) Metrics() map[string]float64 {
+	return map[string]float64{
+		"consecutive_reuse_ratio": p.Consecutive.ReuseRatio,
+		"consecutive_reuse_count": float64(p.Consecutive.ReuseCount),
+		"avg_reuse_distance":      p.Distance.Avg,
+		"min_reuse_distance":      float64(p.Distance.Min),
+		"max_reuse_distance":      float64(p.Distance.Max),
+		"median_reuse_distance":   p.Distance.Median,
+		"burst_count":             float64(p.Burst.Count),
+		"max_burst_size":          float64(p.Burst.MaxSize),
+		"avg_burst_size":          p.Burst.AvgSize,
+		"median_burst_size":       p.Burst.MedianSize,
+	}
+}
+
+// DiversityStats holds order-dependent lexical diversity measures
+type DiversityStats struct {
+	MTLD        float64 `json:"mtld"`
+	MATTR       float64 `json:"mattr"`
+	MATTRWindow int     `json:"mattr_window"`
+	PlainTTR    bool    `json:"plain_ttr"` // sequence shorter than the window; MATTR is the plain TTR
+}
+
+// MetricName returns the metric key of the MATTR value, which includes the window size
+func (d *DiversityStats) MetricName() string {
+	return fmt.Sprintf("mattr_w%d", d.MATTRWindow)
+}
+
+// Metrics returns MTLD and MATTR keyed as mtld and mattr_w{window}
+func (d *DiversityStats) Metrics() map[string]float64 {
+	return map[string]float64{
+		"mtld":         d.MTLD,
+		d.MetricName(): d.MATTR,
+	}
+}
+
+// ReuseStats holds the complete reuse analysis of a document
+type ReuseStats struct {
+	ReuseRatio float64            `json:"reuse_ratio"`
+	Frequency  *FrequencyStats    `json:"frequency,omitempty"`
+	Patterns   *ReusePatterns     `json:"patterns,omitempty"`
+	Zipf       map[string]float64 `json:"zipf,omitempty"`
+	Efficiency map[string]float64 `json:"efficiency,omitempty"`
+	Diversity  *DiversityStats    `json:"diversity,omitempty"`
+}
+
+// Metrics flattens all numeric statistics into a single map. Frequency statistics are
+// prefixed with "freq_", patterns with "pattern_" and efficiency with "efficiency_".
+func (s *ReuseStats) Metrics() map[string]float64 {
+	metrics := map[string]float64{
+		"reuse_ratio": s.ReuseRatio,
+	}
+
+	if s.Frequency != nil {
+		for k, v := range s.Frequency.Metrics() {
+			metrics["freq_"+k] = v
+		}
+	}
+
+	if s.Patterns != nil {
+		for k, v := range s.Patterns.Metrics() {
+			metrics["pattern_"+k] = v
+		}
+	}
+
+	for k, v := range s.Zipf {
+		metrics[k] = v
+	}
+
+	for k, v := range s.Efficiency {
+		metrics["efficiency_"+k] = v
+	}
+
+	if s.Diversity != nil {
+		for k, v := range s.Diversity.Metrics() {
+			metrics[k] = v
+		}
+	}
+
+	return metrics
+}
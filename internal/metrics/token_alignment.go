@@ -0,0 +1,241 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// Kinds of alignment groups
+const (
+	AlignmentOneToOne   = "one_to_one"
+	AlignmentOneToMany  = "one_to_many"
+	AlignmentManyToOne  = "many_to_one"
+	AlignmentManyToMany = "many_to_many"
+	AlignmentUnaligned  = "unaligned"
+)
+
+// AlignmentPair links a token of the first tokenization to a token of the second whose
+// span of the text overlaps it. Indexes refer to the tokenizations' token slices.
+type AlignmentPair struct {
+	Index1 int    `json:"index1"`
+	Index2 int    `json:"index2"`
+	Text1  string `json:"text1"`
+	Text2  string `json:"text2"`
+	Start  int    `json:"start"` // byte offset where the two tokens start to overlap
+	End    int    `json:"end"`   // byte offset where the overlap ends
+}
+
+// AlignmentGroup is a set of tokens from both tokenizations connected by overlaps:
+// one token split into several (one_to_many), several merged into one (many_to_one),
+// crossing boundaries (many_to_many), or a token with no counterpart (unaligned)
+type AlignmentGroup struct {
+	Kind    string `json:"kind"`
+	Tokens1 []int  `json:"tokens1"`
+	Tokens2 []int  `json:"tokens2"`
+}
+
+// TokenAlignment maps the tokens of two tokenizations onto each other over a span of
+// the text
+type TokenAlignment struct {
+	Tokenizer1 string           `json:"tokenizer1"`
+	Tokenizer2 string           `json:"tokenizer2"`
+	Start      int              `json:"start"`
+	End        int              `json:"end"`
+	Tokens1    []int            `json:"tokens1"` // indexes of the aligned tokens of the first tokenization, in text order
+	Tokens2    []int            `json:"tokens2"`
+	Pairs      []AlignmentPair  `json:"pairs"`
+	Groups     []AlignmentGroup `json:"groups"`
+	Notes      []string         `json:"notes,omitempty"` // tokens left out for lack of offsets
+}
+
+// AlignTokens pairs the tokens of two tokenizations whose byte offsets overlap within
+// the span [start, end) of the text, and groups connected tokens by how they map onto
+// each other. An end of zero or less means the end of the text. Tokens without usable
+// offsets cannot be placed, so they are skipped and counted in the alignment's notes;
+// a tokenization without any offsets contributes no tokens.
+func (d *DriftCalculator) AlignTokens(result1, result2 *tokenizers.TokenizationResult, start, end int) (*TokenAlignment, error) {
+	if result1 == nil || result2 == nil {
+		return nil, fmt.Errorf("both tokenization results must be provided")
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end <= 0 {
+		end = max(alignmentTextEnd(result1), alignmentTextEnd(result2))
+	}
+	if start > end {
+		return nil, fmt.Errorf("alignment span %d-%d is empty", start, end)
+	}
+
+	alignment := &TokenAlignment{
+		Tokenizer1: result1.Tokenizer,
+		Tokenizer2: result2.Tokenizer,
+		Start:      start,
+		End:        end,
+	}
+
+	var note string
+	alignment.Tokens1, note = alignableTokens(result1, start, end)
+	if note != "" {
+		alignment.Notes = append(alignment.Notes, note)
+	}
+	alignment.Tokens2, note = alignableTokens(result2, start, end)
+	if note != "" {
+		alignment.Notes = append(alignment.Notes, note)
+	}
+
+	tokens1, tokens2 := result1.Tokens, result2.Tokens
+
+	// Both index lists are in text order, so the tokens of the second tokenization that
+	// end before a token of the first never overlap a later one either
+	first := 0
+	for _, i := range alignment.Tokens1 {
+		token1 := tokens1[i]
+		for first < len(alignment.Tokens2) && tokens2[alignment.Tokens2[first]].EndPos <= token1.StartPos {
+			first++
+		}
+		for _, j := range alignment.Tokens2[first:] {
+			token2 := tokens2[j]
+			if token2.StartPos >= token1.EndPos {
+				break
+			}
+			if token2.EndPos <= token1.StartPos {
+				continue
+			}
+			alignment.Pairs = append(alignment.Pairs, AlignmentPair{
+				Index1: i,
+				Index2: j,
+				Text1:  token1.Text,
+				Text2:  token2.Text,
+				Start:  max(token1.StartPos, token2.StartPos),
+				End:    min(token1.EndPos, token2.EndPos),
+			})
+		}
+	}
+
+	alignment.Groups = alignmentGroups(alignment.Tokens1, alignment.Tokens2, alignment.Pairs)
+	return alignment, nil
+}
+
+// alignmentTextEnd returns the length of the tokenized text, falling back to the end
+// of the last token when the document is not recorded
+func alignmentTextEnd(result *tokenizers.TokenizationResult) int {
+	end := len(result.Document)
+	for _, token := range result.Tokens {
+		end = max(end, token.EndPos)
+	}
+	return end
+}
+
+// alignableTokens returns the indexes of the tokens overlapping [start, end) in text
+// order, and a note when tokens without usable offsets had to be skipped
+func alignableTokens(result *tokenizers.TokenizationResult, start, end int) ([]int, string) {
+	if !hasTokenOffsets(result.Tokens) {
+		if len(result.Tokens) == 0 {
+			return nil, ""
+		}
+		return nil, fmt.Sprintf("skipped all %d tokens of %s: %s", len(result.Tokens), result.Tokenizer, ErrMissingOffsets)
+	}
+
+	var indexes []int
+	skipped := 0
+	for i, token := range result.Tokens {
+		// Special tokens such as [CLS] cover no text
+		if token.StartPos < 0 || token.EndPos <= token.StartPos {
+			skipped++
+			continue
+		}
+		if token.StartPos < end && token.EndPos > start {
+			indexes = append(indexes, i)
+		}
+	}
+	sort.SliceStable(indexes, func(a, b int) bool {
+		return result.Tokens[indexes[a]].StartPos < result.Tokens[indexes[b]].StartPos
+	})
+
+	if skipped > 0 {
+		return indexes, fmt.Sprintf("skipped %d tokens of %s without offsets", skipped, result.Tokenizer)
+	}
+	return indexes, ""
+}
+
+// alignmentGroups joins tokens connected by pairs into groups, in text order of their
+// first token
+func alignmentGroups(tokens1, tokens2 []int, pairs []AlignmentPair) []AlignmentGroup {
+	// Union-find over the aligned tokens of both sides; the second side's tokens are
+	// numbered after the first's
+	position1 := make(map[int]int, len(tokens1))
+	for k, i := range tokens1 {
+		position1[i] = k
+	}
+	position2 := make(map[int]int, len(tokens2))
+	for k, j := range tokens2 {
+		position2[j] = len(tokens1) + k
+	}
+
+	parent := make([]int, len(tokens1)+len(tokens2))
+	for k := range parent {
+		parent[k] = k
+	}
+	var find func(int) int
+	find = func(k int) int {
+		if parent[k] != k {
+			parent[k] = find(parent[k])
+		}
+		return parent[k]
+	}
+	for _, pair := range pairs {
+		a, b := find(position1[pair.Index1]), find(position2[pair.Index2])
+		if a != b {
+			parent[max(a, b)] = min(a, b)
+		}
+	}
+
+	// Roots are the smallest member, so walking both sides in order visits groups in
+	// the order they first appear on the first side, then unaligned second-side tokens
+	index := make(map[int]int)
+	var groups []AlignmentGroup
+	add := func(k int, first bool, token int) {
+		root := find(k)
+		g, exists := index[root]
+		if !exists {
+			g = len(groups)
+			index[root] = g
+			groups = append(groups, AlignmentGroup{})
+		}
+		if first {
+			groups[g].Tokens1 = append(groups[g].Tokens1, token)
+		} else {
+			groups[g].Tokens2 = append(groups[g].Tokens2, token)
+		}
+	}
+	for k, i := range tokens1 {
+		add(k, true, i)
+	}
+	for k, j := range tokens2 {
+		add(len(tokens1)+k, false, j)
+	}
+
+	for g := range groups {
+		groups[g].Kind = alignmentKind(len(groups[g].Tokens1), len(groups[g].Tokens2))
+	}
+	return groups
+}
+
+// alignmentKind classifies a group by how many tokens each side contributes
+func alignmentKind(count1, count2 int) string {
+	switch {
+	case count1 == 0 || count2 == 0:
+		return AlignmentUnaligned
+	case count1 == 1 && count2 == 1:
+		return AlignmentOneToOne
+	case count1 == 1:
+		return AlignmentOneToMany
+	case count2 == 1:
+		return AlignmentManyToOne
+	default:
+		return AlignmentManyToMany
+	}
+}
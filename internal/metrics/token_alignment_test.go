@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// namedTokenization sets the tokenizer name of a tokenization
+func namedTokenization(tokenizer string, result *tokenizers.TokenizationResult) *tokenizers.TokenizationResult {
+	result.Tokenizer = tokenizer
+	return result
+}
+
+func TestAlignTokens(t *testing.T) {
+	document := "unbelievable cat sat"
+	words := namedTokenization("words", tokenizationFromSpans(document, [2]int{0, 12}, [2]int{12, 16}, [2]int{16, 20}))
+	pieces := namedTokenization("pieces", tokenizationFromSpans(document, [2]int{0, 2}, [2]int{2, 9}, [2]int{9, 12}, [2]int{12, 16}, [2]int{16, 18}, [2]int{18, 20}))
+	shifted := namedTokenization("shifted", tokenizationFromSpans(document, [2]int{0, 12}, [2]int{12, 14}, [2]int{14, 17}, [2]int{17, 20}))
+
+	calc := NewDriftCalculator(0.5)
+
+	tests := []struct {
+		name       string
+		result1    *tokenizers.TokenizationResult
+		result2    *tokenizers.TokenizationResult
+		start, end int
+		wantKinds  []string
+		wantPairs  int
+	}{
+		{"one to many", words, pieces, 0, 0, []string{AlignmentOneToMany, AlignmentOneToOne, AlignmentOneToMany}, 6},
+		{"many to one", pieces, words, 0, 0, []string{AlignmentManyToOne, AlignmentOneToOne, AlignmentManyToOne}, 6},
+		{"crossing boundaries", words, shifted, 0, 0, []string{AlignmentOneToOne, AlignmentManyToMany}, 5},
+		{"span", words, pieces, 12, 16, []string{AlignmentOneToOne}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alignment, err := calc.AlignTokens(tt.result1, tt.result2, tt.start, tt.end)
+			if err != nil {
+				t.Fatalf("AlignTokens returned error: %v", err)
+			}
+			var kinds []string
+			for _, group := range alignment.Groups {
+				kinds = append(kinds, group.Kind)
+			}
+			if !reflect.DeepEqual(kinds, tt.wantKinds) {
+				t.Errorf("group kinds = %v, want %v", kinds, tt.wantKinds)
+			}
+			if len(alignment.Pairs) != tt.wantPairs {
+				t.Errorf("got %d pairs, want %d", len(alignment.Pairs), tt.wantPairs)
+			}
+			if len(alignment.Notes) != 0 {
+				t.Errorf("unexpected notes %v", alignment.Notes)
+			}
+		})
+	}
+
+	alignment, _ := calc.AlignTokens(words, pieces, 0, 0)
+	if want := (AlignmentPair{Index1: 0, Index2: 1, Text1: "unbelievable", Text2: "believa", Start: 2, End: 9}); alignment.Pairs[1] != want {
+		t.Errorf("second pair = %+v, want %+v", alignment.Pairs[1], want)
+	}
+}
+
+func TestAlignTokensSkipsTokensWithoutOffsets(t *testing.T) {
+	document := "hi there"
+	words := namedTokenization("words", tokenizationFromSpans(document, [2]int{0, 2}, [2]int{2, 8}))
+	calc := NewDriftCalculator(0.5)
+
+	// A special token covering no text cannot be placed
+	special := namedTokenization("special", tokenizationFromSpans(document, [2]int{0, 2}, [2]int{2, 8}))
+	special.Tokens = append([]tokenizers.Token{{Text: "[CLS]"}}, special.Tokens...)
+
+	alignment, err := calc.AlignTokens(words, special, 0, 0)
+	if err != nil {
+		t.Fatalf("AlignTokens returned error: %v", err)
+	}
+	if len(alignment.Pairs) != 2 || len(alignment.Notes) != 1 || !strings.Contains(alignment.Notes[0], "skipped 1 tokens of special") {
+		t.Errorf("pairs = %v, notes = %v, want 2 pairs and a note about the special token", alignment.Pairs, alignment.Notes)
+	}
+
+	// Without any offsets, every token is left out and the other side is unaligned
+	plain := tokenizationFromTexts("hi", " there")
+	plain.Tokenizer = "plain"
+	alignment, err = calc.AlignTokens(words, plain, 0, 0)
+	if err != nil {
+		t.Fatalf("AlignTokens returned error: %v", err)
+	}
+	if len(alignment.Pairs) != 0 || len(alignment.Notes) != 1 || !strings.Contains(alignment.Notes[0], ErrMissingOffsets.Error()) {
+		t.Errorf("pairs = %v, notes = %v, want no pairs and a missing offsets note", alignment.Pairs, alignment.Notes)
+	}
+	for _, group := range alignment.Groups {
+		if group.Kind != AlignmentUnaligned {
+			t.Errorf("group %+v is not unaligned", group)
+		}
+	}
+
+	if _, err := calc.AlignTokens(words, nil, 0, 0); err == nil {
+		t.Error("AlignTokens accepted a missing tokenization")
+	}
+	if _, err := calc.AlignTokens(words, words, 5, 2); err == nil {
+		t.Error("AlignTokens accepted an empty span")
+	}
+}
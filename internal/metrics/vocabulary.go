@@ -0,0 +1,201 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// vocabularySampleSize is how many tokens unique to each vocabulary are listed
+const vocabularySampleSize = 50
+
+// maxVocabularyLength is the last bucket of the token length distributions, holding
+// every token at least that long
+const maxVocabularyLength = 16
+
+// VocabularyComparison compares the full vocabularies of two tokenizers, as opposed
+// to the tokens they produced for a document. Tokens are compared after normalizing
+// word boundary markers (see normalizeVocabToken), so "Ġthe", "▁the" and " the" match.
+type VocabularyComparison struct {
+	A     string `json:"a"`
+	B     string `json:"b"`
+	SizeA int    `json:"size_a"` // distinct normalized tokens
+	SizeB int    `json:"size_b"`
+
+	Shared             int     `json:"shared"`
+	OverlapCoefficient float64 `json:"overlap_coefficient"` // shared / smaller size
+	Jaccard            float64 `json:"jaccard"`             // shared / union
+
+	UniqueToA       int      `json:"unique_to_a"`
+	UniqueToB       int      `json:"unique_to_b"`
+	UniqueToASample []string `json:"unique_to_a_sample"` // the shortest, then alphabetically first
+	UniqueToBSample []string `json:"unique_to_b_sample"`
+
+	// Length distributions give the share of tokens of each length in characters,
+	// not counting a leading space; the last bucket holds every longer token
+	MeanLengthA         float64   `json:"mean_length_a"`
+	MeanLengthB         float64   `json:"mean_length_b"`
+	LengthDistributionA []float64 `json:"length_distribution_a"`
+	LengthDistributionB []float64 `json:"length_distribution_b"`
+	LengthDistance      float64   `json:"length_distance"` // total variation distance, 0 to 1
+}
+
+// CompareVocabularies compares the vocabularies of two tokenizers. If either cannot
+// list its vocabulary, the error wraps tokenizers.ErrUnsupported.
+func (e *Engine) CompareVocabularies(ctx context.Context, tokA, tokB tokenizers.Tokenizer) (*VocabularyComparison, error) {
+	vocabA, err := tokenizers.Vocabulary(ctx, tokA)
+	if err != nil {
+		return nil, fmt.Errorf("error loading vocabulary of %s: %w", tokA.Name(), err)
+	}
+	vocabB, err := tokenizers.Vocabulary(ctx, tokB)
+	if err != nil {
+		return nil, fmt.Errorf("error loading vocabulary of %s: %w", tokB.Name(), err)
+	}
+	return compareVocabularies(tokA.Name(), tokB.Name(), normalizeVocab(vocabA), normalizeVocab(vocabB)), nil
+}
+
+// AddVocabularyComparisons compares the vocabularies of each pair in comparison, whose
+// tokenizers are given in the same order, loading each vocabulary once. Pairs with a
+// tokenizer that cannot list its vocabulary are left without one and noted in their
+// metadata; other errors are returned.
+func (e *Engine) AddVocabularyComparisons(ctx context.Context, comparison *ComparisonResult, tokenizerList []tokenizers.Tokenizer) error {
+	vocabs := make(map[string]map[string]bool, len(tokenizerList))
+	unsupported := make(map[string]error)
+	for _, tokenizer := range tokenizerList {
+		vocab, err := tokenizers.Vocabulary(ctx, tokenizer)
+		if errors.Is(err, tokenizers.ErrUnsupported) {
+			unsupported[tokenizer.Name()] = err
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error loading vocabulary of %s: %w", tokenizer.Name(), err)
+		}
+		vocabs[tokenizer.Name()] = normalizeVocab(vocab)
+	}
+
+	for i := range comparison.Pairs {
+		pair := &comparison.Pairs[i]
+		vocabA, okA := vocabs[pair.A]
+		vocabB, okB := vocabs[pair.B]
+		if !okA || !okB {
+			reason := unsupported[pair.A]
+			if reason == nil {
+				reason = unsupported[pair.B]
+			}
+			if reason != nil {
+				if pair.Metadata == nil {
+					pair.Metadata = make(map[string]interface{})
+				}
+				pair.Metadata["vocabulary"] = "skipped: " + reason.Error()
+			}
+			continue
+		}
+		pair.Vocabulary = compareVocabularies(pair.A, pair.B, vocabA, vocabB)
+	}
+	return nil
+}
+
+// normalizeVocabToken writes a token's word boundary marker as a leading space:
+// byte-level BPE's "Ġ" and SentencePiece's "▁" become " ", and WordPiece's "##"
+// continuation prefix is dropped, leaving its word-initial pieces unmarked. Other
+// byte-level characters are compared as they are.
+func normalizeVocabToken(token string) string {
+	switch {
+	case strings.HasPrefix(token, "Ġ"):
+		return " " + strings.TrimPrefix(token, "Ġ")
+	case strings.HasPrefix(token, "▁"):
+		return " " + strings.TrimPrefix(token, "▁")
+	case strings.HasPrefix(token, "##") && len(token) > 2:
+		return strings.TrimPrefix(token, "##")
+	default:
+		return token
+	}
+}
+
+// normalizeVocab returns the set of normalized tokens of vocab
+func normalizeVocab(vocab map[string]int) map[string]bool {
+	normalized := make(map[string]bool, len(vocab))
+	for token := range vocab {
+		normalized[normalizeVocabToken(token)] = true
+	}
+	return normalized
+}
+
+// compareVocabularies compares two sets of normalized tokens
+func compareVocabularies(a, b string, vocabA, vocabB map[string]bool) *VocabularyComparison {
+	comparison := &VocabularyComparison{A: a, B: b, SizeA: len(vocabA), SizeB: len(vocabB)}
+
+	var uniqueA, uniqueB []string
+	for token := range vocabA {
+		if vocabB[token] {
+			comparison.Shared++
+		} else {
+			uniqueA = append(uniqueA, token)
+		}
+	}
+	for token := range vocabB {
+		if !vocabA[token] {
+			uniqueB = append(uniqueB, token)
+		}
+	}
+
+	if smaller := min(len(vocabA), len(vocabB)); smaller > 0 {
+		comparison.OverlapCoefficient = float64(comparison.Shared) / float64(smaller)
+	}
+	if union := len(vocabA) + len(vocabB) - comparison.Shared; union > 0 {
+		comparison.Jaccard = float64(comparison.Shared) / float64(union)
+	}
+
+	comparison.UniqueToA, comparison.UniqueToB = len(uniqueA), len(uniqueB)
+	comparison.UniqueToASample = vocabularySample(uniqueA)
+	comparison.UniqueToBSample = vocabularySample(uniqueB)
+
+	comparison.LengthDistributionA, comparison.MeanLengthA = lengthDistribution(vocabA)
+	comparison.LengthDistributionB, comparison.MeanLengthB = lengthDistribution(vocabB)
+	for i := range comparison.LengthDistributionA {
+		comparison.LengthDistance += math.Abs(comparison.LengthDistributionA[i]-comparison.LengthDistributionB[i]) / 2
+	}
+
+	return comparison
+}
+
+// vocabularySample returns up to vocabularySampleSize tokens, shortest first, so the
+// sample shows the basic units one vocabulary has and the other lacks
+func vocabularySample(tokens []string) []string {
+	sort.Slice(tokens, func(i, j int) bool {
+		if li, lj := utf8.RuneCountInString(tokens[i]), utf8.RuneCountInString(tokens[j]); li != lj {
+			return li < lj
+		}
+		return tokens[i] < tokens[j]
+	})
+	if len(tokens) > vocabularySampleSize {
+		tokens = tokens[:vocabularySampleSize]
+	}
+	return append([]string{}, tokens...)
+}
+
+// lengthDistribution returns the share of tokens of each length from 0 to
+// maxVocabularyLength, and their mean length
+func lengthDistribution(vocab map[string]bool) ([]float64, float64) {
+	distribution := make([]float64, maxVocabularyLength+1)
+	if len(vocab) == 0 {
+		return distribution, 0
+	}
+
+	total := 0
+	for token := range vocab {
+		length := utf8.RuneCountInString(strings.TrimPrefix(token, " "))
+		total += length
+		distribution[min(length, maxVocabularyLength)]++
+	}
+	for i := range distribution {
+		distribution[i] /= float64(len(vocab))
+	}
+	return distribution, float64(total) / float64(len(vocab))
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// vocabTokenizer is a mock tokenizer that lists a fixed vocabulary
+type vocabTokenizer struct {
+	*tokenizers.MockTokenizer
+	vocab map[string]int
+}
+
+func (v *vocabTokenizer) GetVocab(ctx context.Context) (map[string]int, error) {
+	return v.vocab, nil
+}
+
+func newVocabTokenizer(name string, vocab map[string]int) *vocabTokenizer {
+	return &vocabTokenizer{MockTokenizer: tokenizers.NewMockTokenizer(name), vocab: vocab}
+}
+
+func TestCompareVocabularies(t *testing.T) {
+	// Byte-level BPE, and SentencePiece with WordPiece-style continuations
+	bpe := newVocabTokenizer("bpe", map[string]int{"Ġthe": 1, "Ġcat": 2, "a": 3, "ing": 4})
+	spm := newVocabTokenizer("spm", map[string]int{"▁the": 1, "##ing": 2, "b": 3})
+
+	comparison, err := NewEngine(EngineConfig{}).CompareVocabularies(context.Background(), bpe, spm)
+	if err != nil {
+		t.Fatalf("CompareVocabularies returned error: %v", err)
+	}
+
+	if comparison.SizeA != 4 || comparison.SizeB != 3 || comparison.Shared != 2 {
+		t.Errorf("sizes %d and %d with %d shared, want 4 and 3 with 2", comparison.SizeA, comparison.SizeB, comparison.Shared)
+	}
+	checks := []struct {
+		name      string
+		got, want float64
+	}{
+		{"overlap_coefficient", comparison.OverlapCoefficient, 2.0 / 3},
+		{"jaccard", comparison.Jaccard, 2.0 / 5},
+		{"mean_length_a", comparison.MeanLengthA, 10.0 / 4},
+		{"mean_length_b", comparison.MeanLengthB, 7.0 / 3},
+		// A has a quarter of one-character tokens and B a third
+		{"length_distance", comparison.LengthDistance, 1.0/3 - 1.0/4},
+	}
+	for _, check := range checks {
+		if math.Abs(check.got-check.want) > floatTolerance {
+			t.Errorf("%s = %v, want %v", check.name, check.got, check.want)
+		}
+	}
+	if want := []string{"a", " cat"}; comparison.UniqueToA != 2 || !reflect.DeepEqual(comparison.UniqueToASample, want) {
+		t.Errorf("unique to A = %d %q, want 2 %q", comparison.UniqueToA, comparison.UniqueToASample, want)
+	}
+	if want := []string{"b"}; comparison.UniqueToB != 1 || !reflect.DeepEqual(comparison.UniqueToBSample, want) {
+		t.Errorf("unique to B = %d %q, want 1 %q", comparison.UniqueToB, comparison.UniqueToBSample, want)
+	}
+
+	if _, err := NewEngine(EngineConfig{}).CompareVocabularies(context.Background(), bpe, tokenizers.NewMockTokenizer("mock")); !errors.Is(err, tokenizers.ErrUnsupported) {
+		t.Errorf("CompareVocabularies error = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestAddVocabularyComparisons(t *testing.T) {
+	selected := []tokenizers.Tokenizer{
+		newVocabTokenizer("a", map[string]int{"x": 1, "y": 2}),
+		newVocabTokenizer("b", map[string]int{"x": 1}),
+		tokenizers.NewMockTokenizer("mock"),
+	}
+	comparison := &ComparisonResult{
+		Tokenizers: []string{"a", "b", "mock"},
+		Pairs:      []TokenizerPair{{A: "a", B: "b"}, {A: "a", B: "mock"}, {A: "b", B: "mock"}},
+	}
+
+	if err := NewEngine(EngineConfig{}).AddVocabularyComparisons(context.Background(), comparison, selected); err != nil {
+		t.Fatalf("AddVocabularyComparisons returned error: %v", err)
+	}
+
+	if vocabulary := comparison.Pair("a", "b").Vocabulary; vocabulary == nil || vocabulary.OverlapCoefficient != 1 {
+		t.Errorf("a vs b vocabulary = %+v, want an overlap coefficient of 1", vocabulary)
+	}
+	for _, other := range []string{"a", "b"} {
+		pair := comparison.Pair(other, "mock")
+		if pair.Vocabulary != nil {
+			t.Errorf("%s vs mock should have no vocabulary comparison", other)
+		}
+		if note, _ := pair.Metadata["vocabulary"].(string); !strings.HasPrefix(note, "skipped: ") {
+			t.Errorf("%s vs mock metadata = %v, want the vocabulary noted as skipped", other, pair.Metadata)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package parallel
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// Pipeline stage names, reported with the results that failed in them
+const (
+	StageTokenize = "tokenize"
+	StageAnalyze  = "analyze"
+)
+
+// PipelineConfig holds configuration for a tokenize→metrics pipeline
+type PipelineConfig struct {
+	TokenizeWorkers int           `json:"tokenize_workers"` // Goroutines tokenizing documents
+	AnalyzeWorkers  int           `json:"analyze_workers"`  // Goroutines calculating metrics
+	QueueSize       int           `json:"queue_size"`       // Tokenizations waiting for analysis before tokenizing blocks
+	Timeout         time.Duration `json:"timeout"`          // Timeout for processing
+
+	ProgressInterval time.Duration `json:"progress_interval"`  // How often to report progress
+	AbortAfterErrors int           `json:"abort_after_errors"` // Stop once this many documents fail; 0 never stops
+}
+
+// StageStats holds statistics about one pipeline stage
+type StageStats struct {
+	Workers        int           `json:"workers"`
+	Items          int           `json:"items"`            // items the stage finished, including failures
+	FailedItems    int           `json:"failed_items"`     // items that failed in this stage
+	BusyTime       time.Duration `json:"busy_time"`        // time workers spent on items, summed over workers
+	ItemsPerSecond float64       `json:"items_per_second"` // Items over the pipeline's wall time
+	Utilization    float64       `json:"utilization"`      // BusyTime over Workers × wall time; near 1 marks the bottleneck
+}
+
+// PipelineStats holds statistics about a pipeline run
+type PipelineStats struct {
+	TotalItems     int           `json:"total_items"`
+	ProcessedItems int           `json:"processed_items"`
+	FailedItems    int           `json:"failed_items"`
+	SkippedItems   int           `json:"skipped_items"`     // never started because the context ended
+	Aborted        bool          `json:"aborted,omitempty"` // stopped by AbortAfterErrors
+	StartTime      time.Time     `json:"start_time"`
+	EndTime        time.Time     `json:"end_time"`
+	Duration       time.Duration `json:"duration"`
+	Tokenize       StageStats    `json:"tokenize"`
+	Analyze        StageStats    `json:"analyze"`
+}
+
+// PipelineResult is the analysis of one input document. Index is the document's
+// position in the input; on failure Stage names the stage that failed.
+type PipelineResult struct {
+	Index  int                     `json:"index"`
+	Result *metrics.AnalysisResult `json:"result,omitempty"`
+	Stage  string                  `json:"stage,omitempty"`
+	Err    error                   `json:"-"`
+}
+
+// Pipeline tokenizes documents and calculates their metrics in two concurrent stages
+// connected by a bounded queue. When analysis falls behind, the full queue blocks the
+// tokenizer workers, so at most QueueSize tokenizations wait in memory.
+type Pipeline struct {
+	config PipelineConfig
+	engine *metrics.Engine
+}
+
+// NewPipeline creates a new tokenize→metrics pipeline
+func NewPipeline(config PipelineConfig, engine *metrics.Engine) *Pipeline {
+	// Set reasonable defaults
+	if config.TokenizeWorkers <= 0 {
+		config.TokenizeWorkers = runtime.NumCPU()
+	}
+	if config.AnalyzeWorkers <= 0 {
+		config.AnalyzeWorkers = runtime.NumCPU()
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = 2 * config.AnalyzeWorkers
+	}
+	if config.ProgressInterval <= 0 {
+		config.ProgressInterval = DefaultProgressInterval
+	}
+
+	return &Pipeline{
+		config: config,
+		engine: engine,
+	}
+}
+
+// Run analyzes texts and returns one PipelineResult per text, in the order of texts.
+// The results are all held in memory; use RunEach to handle them as they finish.
+// progressCallback may be nil.
+func (p *Pipeline) Run(ctx context.Context, texts []string, tokenizer tokenizers.Tokenizer, progressCallback ProgressCallback) ([]PipelineResult, PipelineStats) {
+	results := make([]PipelineResult, len(texts))
+	stats := p.RunEach(ctx, texts, tokenizer, progressCallback, func(result PipelineResult) {
+		results[result.Index] = result
+	})
+	return results, stats
+}
+
+// RunEach analyzes texts and calls handle with each result as it finishes, in no
+// particular order. handle is called from one goroutine at a time; a slow handle
+// slows the pipeline rather than letting results pile up. Texts not started before
+// the context ends, or before AbortAfterErrors documents have failed, are reported
+// with the context's error or ErrTooManyErrors after the others. progressCallback,
+// when set, runs every ProgressInterval and once more when the run ends, from the
+// same goroutine as handle.
+func (p *Pipeline) RunEach(ctx context.Context, texts []string, tokenizer tokenizers.Tokenizer, progressCallback ProgressCallback, handle func(PipelineResult)) PipelineStats {
+	stats := PipelineStats{
+		TotalItems: len(texts),
+		StartTime:  time.Now(),
+	}
+
+	// Create context with timeout if specified
+	if p.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.config.Timeout)
+		defer cancel()
+	}
+
+	// Workers share this context, so reaching the error threshold stops all of them
+	ctx, abort := context.WithCancelCause(ctx)
+	defer abort(nil)
+
+	indices := make(chan int)
+	queue := make(chan tokenizedItem, p.config.QueueSize)
+	results := make(chan PipelineResult)
+	var tokenizeStage, analyzeStage stageCounter
+
+	// Feed document indices until the input runs out or the context ends
+	fed := 0
+	go func() {
+		defer close(indices)
+		for ; fed < len(texts); fed++ {
+			select {
+			case indices <- fed:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var tokenizeWG sync.WaitGroup
+	for w := 0; w < p.config.TokenizeWorkers; w++ {
+		tokenizeWG.Add(1)
+		go func() {
+			defer tokenizeWG.Done()
+			for index := range indices {
+				began := time.Now()
+				tokenization, err := tokenizer.Tokenize(ctx, texts[index])
+				tokenizeStage.record(time.Since(began), err)
+				if err != nil {
+					results <- PipelineResult{Index: index, Stage: StageTokenize, Err: err}
+					continue
+				}
+				// Blocks while the queue is full, which is the pipeline's back-pressure
+				queue <- tokenizedItem{index: index, tokenization: tokenization}
+			}
+		}()
+	}
+
+	var analyzeWG sync.WaitGroup
+	for w := 0; w < p.config.AnalyzeWorkers; w++ {
+		analyzeWG.Add(1)
+		go func() {
+			defer analyzeWG.Done()
+			for item := range queue {
+				if err := ctx.Err(); err != nil {
+					analyzeStage.record(0, err)
+					results <- PipelineResult{Index: item.index, Stage: StageAnalyze, Err: err}
+					continue
+				}
+				began := time.Now()
+				result, err := p.engine.AnalyzeTokenization(ctx, texts[item.index], item.tokenization, tokenizer)
+				analyzeStage.record(time.Since(began), err)
+				if err != nil {
+					results <- PipelineResult{Index: item.index, Stage: StageAnalyze, Err: err}
+					continue
+				}
+				results <- PipelineResult{Index: item.index, Result: result}
+			}
+		}()
+	}
+
+	go func() {
+		tokenizeWG.Wait()
+		close(queue)
+		analyzeWG.Wait()
+		close(results)
+	}()
+
+	var progress <-chan time.Time
+	if progressCallback != nil {
+		ticker := time.NewTicker(p.config.ProgressInterval)
+		defer ticker.Stop()
+		progress = ticker.C
+	}
+	report := func() {
+		progressCallback(stats.ProcessedItems, stats.FailedItems, stats.TotalItems, time.Since(stats.StartTime))
+	}
+
+collect:
+	for {
+		select {
+		case result, ok := <-results:
+			if !ok {
+				break collect
+			}
+			if result.Err == nil {
+				stats.ProcessedItems++
+			} else {
+				stats.FailedItems++
+				if p.config.AbortAfterErrors > 0 && stats.FailedItems == p.config.AbortAfterErrors {
+					stats.Aborted = true
+					abort(fmt.Errorf("%w: %d documents failed", ErrTooManyErrors, stats.FailedItems))
+				}
+			}
+			handle(result)
+		case <-progress:
+			report()
+		}
+	}
+
+	// The feeder has exited once results is closed, so fed is final
+	for index := fed; index < len(texts); index++ {
+		stats.SkippedItems++
+		handle(PipelineResult{Index: index, Err: context.Cause(ctx)})
+	}
+	if progressCallback != nil {
+		report()
+	}
+
+	stats.EndTime = time.Now()
+	stats.Duration = stats.EndTime.Sub(stats.StartTime)
+	stats.Tokenize = tokenizeStage.stats(p.config.TokenizeWorkers, stats.Duration)
+	stats.Analyze = analyzeStage.stats(p.config.AnalyzeWorkers, stats.Duration)
+
+	return stats
+}
+
+// tokenizedItem is a tokenization waiting in the queue for analysis
+type tokenizedItem struct {
+	index        int
+	tokenization *tokenizers.TokenizationResult
+}
+
+// stageCounter accumulates the work of one stage's workers
+type stageCounter struct {
+	items  atomic.Int64
+	failed atomic.Int64
+	busy   atomic.Int64 // nanoseconds
+}
+
+// record counts one item that took elapsed and failed with err, if not nil
+func (c *stageCounter) record(elapsed time.Duration, err error) {
+	c.items.Add(1)
+	c.busy.Add(int64(elapsed))
+	if err != nil {
+		c.failed.Add(1)
+	}
+}
+
+// stats summarizes the stage over a run that took duration
+func (c *stageCounter) stats(workers int, duration time.Duration) StageStats {
+	stats := StageStats{
+		Workers:     workers,
+		Items:       int(c.items.Load()),
+		FailedItems: int(c.failed.Load()),
+		BusyTime:    time.Duration(c.busy.Load()),
+	}
+	if duration > 0 {
+		stats.ItemsPerSecond = float64(stats.Items) / duration.Seconds()
+		stats.Utilization = stats.BusyTime.Seconds() / (float64(workers) * duration.Seconds())
+	}
+	return stats
+}
@@ -0,0 +1,171 @@
+package parallel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// trackingTokenizer fails on one text and counts tokenizations that have not yet
+// been analyzed, to observe the pipeline's back-pressure
+type trackingTokenizer struct {
+	*tokenizers.WhitespaceTokenizer
+	failOn   string
+	inFlight *atomic.Int64
+	peak     atomic.Int64
+}
+
+func (t *trackingTokenizer) Tokenize(ctx context.Context, text string) (*tokenizers.TokenizationResult, error) {
+	if text == t.failOn {
+		return nil, errors.New("tokenizer crashed")
+	}
+	n := t.inFlight.Add(1)
+	for {
+		peak := t.peak.Load()
+		if n <= peak || t.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	return t.WhitespaceTokenizer.Tokenize(ctx, text)
+}
+
+func newTrackingTokenizer(t *testing.T, failOn string) *trackingTokenizer {
+	t.Helper()
+	tokenizer := tokenizers.NewWhitespaceTokenizer("words")
+	if err := tokenizer.Initialize(tokenizers.TokenizerConfig{Name: "words", Type: "custom"}); err != nil {
+		t.Fatalf("Initialize returned error: %v", err)
+	}
+	return &trackingTokenizer{WhitespaceTokenizer: tokenizer, failOn: failOn, inFlight: &atomic.Int64{}}
+}
+
+func TestPipelineRun(t *testing.T) {
+	texts := make([]string, 200)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("doc%d", i) + strings.Repeat(" word", i%7)
+	}
+	texts[42] = "bad"
+
+	tokenizer := newTrackingTokenizer(t, "bad")
+	pipeline := NewPipeline(PipelineConfig{TokenizeWorkers: 4, AnalyzeWorkers: 3}, metrics.NewEngine(metrics.EngineConfig{EntropyWindowSize: 10}))
+	results, stats := pipeline.Run(context.Background(), texts, tokenizer, nil)
+
+	for i, item := range results {
+		if item.Index != i {
+			t.Fatalf("results[%d] has index %d", i, item.Index)
+		}
+		if i == 42 {
+			if item.Err == nil || item.Stage != StageTokenize {
+				t.Errorf("results[42] = %+v, want a tokenize failure", item)
+			}
+			continue
+		}
+		if item.Err != nil {
+			t.Fatalf("results[%d] failed in %s: %v", i, item.Stage, item.Err)
+		}
+		if item.Result.Document != texts[i] || item.Result.TokenCount != 1+i%7 {
+			t.Errorf("results[%d] analyzed %q with %d tokens, want %q", i, item.Result.Document, item.Result.TokenCount, texts[i])
+		}
+		if _, ok := item.Result.Metrics["entropy_global_entropy"]; !ok {
+			t.Errorf("results[%d] has no entropy metrics", i)
+		}
+	}
+
+	if stats.ProcessedItems != 199 || stats.FailedItems != 1 || stats.SkippedItems != 0 {
+		t.Errorf("stats = %+v, want 199 processed and 1 failed", stats)
+	}
+	if stats.Tokenize.Items != 200 || stats.Tokenize.FailedItems != 1 || stats.Analyze.Items != 199 {
+		t.Errorf("stage stats = %+v / %+v, want 200 tokenized and 199 analyzed", stats.Tokenize, stats.Analyze)
+	}
+	if stats.Tokenize.Workers != 4 || stats.Analyze.Workers != 3 || stats.Analyze.ItemsPerSecond <= 0 {
+		t.Errorf("stage stats = %+v / %+v, want worker counts and throughput", stats.Tokenize, stats.Analyze)
+	}
+}
+
+func TestPipelineBackPressure(t *testing.T) {
+	texts := make([]string, 300)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("doc%d", i)
+	}
+
+	tokenizer := newTrackingTokenizer(t, "")
+	pipeline := NewPipeline(PipelineConfig{TokenizeWorkers: 8, AnalyzeWorkers: 1, QueueSize: 4}, metrics.NewEngine(metrics.EngineConfig{EntropyWindowSize: 10}))
+
+	// A slow consumer stalls the analyzer, which must stall the tokenizers in turn
+	stats := pipeline.RunEach(context.Background(), texts, tokenizer, nil, func(result PipelineResult) {
+		tokenizer.inFlight.Add(-1)
+		time.Sleep(100 * time.Microsecond)
+	})
+
+	if stats.ProcessedItems != len(texts) {
+		t.Fatalf("stats = %+v, want every text processed", stats)
+	}
+	// Queued items, plus one held by each tokenizer and the analyzer
+	if peak, limit := tokenizer.peak.Load(), int64(4+8+1+1); peak > limit {
+		t.Errorf("%d tokenizations waited at once, want at most %d", peak, limit)
+	}
+}
+
+func TestPipelineCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tokenizer := newTrackingTokenizer(t, "")
+	pipeline := NewPipeline(PipelineConfig{TokenizeWorkers: 2, AnalyzeWorkers: 2}, metrics.NewEngine(metrics.EngineConfig{EntropyWindowSize: 10}))
+	results, stats := pipeline.Run(ctx, []string{"a", "b", "c", "d"}, tokenizer, nil)
+
+	if stats.ProcessedItems != 0 || stats.FailedItems+stats.SkippedItems != 4 {
+		t.Errorf("stats = %+v, want every text failed or skipped", stats)
+	}
+	for i, item := range results {
+		if item.Index != i || !errors.Is(item.Err, context.Canceled) {
+			t.Errorf("results[%d] = %+v, want a cancellation error", i, item)
+		}
+	}
+}
+
+func TestPipelineAbortAfterErrors(t *testing.T) {
+	texts := make([]string, 500)
+	for i := range texts {
+		texts[i] = "bad"
+	}
+
+	var last [3]int
+	reports := 0
+	progress := func(processed, failed, total int, elapsed time.Duration) {
+		reports++
+		last = [3]int{processed, failed, total}
+	}
+
+	tokenizer := newTrackingTokenizer(t, "bad")
+	pipeline := NewPipeline(PipelineConfig{TokenizeWorkers: 4, AnalyzeWorkers: 2, AbortAfterErrors: 5}, metrics.NewEngine(metrics.EngineConfig{EntropyWindowSize: 10}))
+	results, stats := pipeline.Run(context.Background(), texts, tokenizer, progress)
+
+	if !stats.Aborted {
+		t.Error("expected the run to be aborted")
+	}
+	if stats.FailedItems+stats.SkippedItems != len(texts) {
+		t.Errorf("stats = %+v, want every text failed or skipped", stats)
+	}
+	skipped := 0
+	for i, item := range results {
+		if item.Index != i || item.Err == nil {
+			t.Fatalf("results[%d] = %+v, want a failure", i, item)
+		}
+		if errors.Is(item.Err, ErrTooManyErrors) {
+			skipped++
+		}
+	}
+	if skipped < len(texts)-5-4 {
+		t.Errorf("%d texts report ErrTooManyErrors, want all that were not attempted", skipped)
+	}
+	if reports == 0 || last != [3]int{0, stats.FailedItems, len(texts)} {
+		t.Errorf("last progress report = %v after %d reports, want the final counts", last, reports)
+	}
+}
@@ -2,19 +2,38 @@ package parallel
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/RevBooyah/TokEntropyDrift/internal/streaming"
 	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
 )
 
+// DefaultProgressInterval is how often the progress callback runs when no interval
+// is configured
+const DefaultProgressInterval = time.Second
+
+// ErrTooManyErrors is the error of items left unprocessed because the failure count
+// reached AbortAfterErrors
+var ErrTooManyErrors = errors.New("processing aborted after too many errors")
+
+// ProgressCallback reports progress of a run as (processed, failed, total, elapsed).
+// It shares the streaming analyzer's signature so one renderer can serve both.
+type ProgressCallback = streaming.ProgressCallback
+
 // ProcessorConfig holds configuration for parallel processing
 type ProcessorConfig struct {
 	MaxWorkers    int           `json:"max_workers"`    // Maximum number of worker goroutines
 	BatchSize     int           `json:"batch_size"`     // Number of items per batch
 	Timeout       time.Duration `json:"timeout"`        // Timeout for processing
 	EnableMetrics bool          `json:"enable_metrics"` // Whether to collect processing metrics
+
+	ProgressInterval time.Duration `json:"progress_interval"`  // How often to report progress
+	AbortAfterErrors int           `json:"abort_after_errors"` // Stop once this many items fail; 0 never stops
 }
 
 // ProcessingStats holds statistics about parallel processing
@@ -26,12 +45,18 @@ type ProcessingStats struct {
 	EndTime        time.Time     `json:"end_time"`
 	Duration       time.Duration `json:"duration"`
 	WorkersUsed    int           `json:"workers_used"`
+	Aborted        bool          `json:"aborted,omitempty"` // stopped by AbortAfterErrors
 }
 
-// Processor provides parallel processing capabilities
+// Processor provides parallel processing capabilities. Workers count items with
+// atomic counters, so GetStats can be called while processing is running.
 type Processor struct {
 	config ProcessorConfig
-	stats  ProcessingStats
+
+	mu        sync.Mutex // guards stats
+	stats     ProcessingStats
+	processed atomic.Int64
+	failed    atomic.Int64
 }
 
 // NewProcessor creates a new parallel processor
@@ -43,35 +68,121 @@ func NewProcessor(config ProcessorConfig) *Processor {
 	if config.BatchSize <= 0 {
 		config.BatchSize = 100
 	}
+	if config.ProgressInterval <= 0 {
+		config.ProgressInterval = DefaultProgressInterval
+	}
 
 	return &Processor{
 		config: config,
 	}
 }
 
-// processItems processes items in parallel using the provided function
+// ItemResult is the outcome of processing one input item. Index is the item's
+// position in the input; exactly one of Result and Err is set.
+type ItemResult struct {
+	Index  int                            `json:"index"`
+	Result *tokenizers.TokenizationResult `json:"result,omitempty"`
+	Err    error                          `json:"-"`
+}
+
+// BatchError is the error of each item in a batch whose TokenizeBatch call failed
+// as a whole. Start and End are the batch's half-open range of input indices.
+type BatchError struct {
+	Start int
+	End   int
+	Err   error
+}
+
+// Error names the batch and the error it failed with
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch of items %d-%d: %v", e.Start, e.End-1, e.Err)
+}
+
+// Unwrap returns the error of the batch
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// recordFunc stores the outcome of the item at index
+type recordFunc func(index int, result *tokenizers.TokenizationResult, err error)
+
+// processItems processes items in parallel using the provided function, one call
+// per item
 func (p *Processor) processItems(
 	ctx context.Context,
 	items []string,
 	processFunc func(context.Context, string) (*tokenizers.TokenizationResult, error),
-) ([]*tokenizers.TokenizationResult, []error, ProcessingStats) {
+	progressCallback ProgressCallback,
+) ([]ItemResult, ProcessingStats) {
+
+	return p.run(ctx, items, progressCallback, func(ctx context.Context, start int, batch []string, record recordFunc) {
+		for i, item := range batch {
+			if ctx.Err() != nil {
+				record(start+i, nil, context.Cause(ctx))
+				continue
+			}
+			result, err := processFunc(ctx, item)
+			record(start+i, result, err)
+		}
+	})
+}
 
+// processBatches processes items in parallel using the provided function, one call
+// per batch. A failed call fails every item in its batch with a BatchError.
+func (p *Processor) processBatches(
+	ctx context.Context,
+	items []string,
+	batchFunc func(context.Context, []string) ([]*tokenizers.TokenizationResult, error),
+	progressCallback ProgressCallback,
+) ([]ItemResult, ProcessingStats) {
+
+	return p.run(ctx, items, progressCallback, func(ctx context.Context, start int, batch []string, record recordFunc) {
+		results, err := batchFunc(ctx, batch)
+		if err == nil && len(results) != len(batch) {
+			err = fmt.Errorf("got %d results for %d items", len(results), len(batch))
+		}
+		for i := range batch {
+			switch {
+			case err != nil:
+				record(start+i, nil, &BatchError{Start: start, End: start + len(batch), Err: err})
+			case results[i] == nil:
+				record(start+i, nil, fmt.Errorf("no result for item %d", start+i))
+			default:
+				record(start+i, results[i], nil)
+			}
+		}
+	})
+}
+
+// run splits items into batches of BatchSize and hands each to processBatch on a
+// worker, which must record the outcome of every item in its batch. The returned
+// slice holds one ItemResult per item, in input order. Batches not started before the
+// context ends fail with the context's error, or ErrTooManyErrors once
+// AbortAfterErrors items have failed. progressCallback, when set, runs every
+// ProgressInterval and once more when the run ends.
+func (p *Processor) run(
+	ctx context.Context,
+	items []string,
+	progressCallback ProgressCallback,
+	processBatch func(ctx context.Context, start int, batch []string, record recordFunc),
+) ([]ItemResult, ProcessingStats) {
+
+	p.mu.Lock()
 	p.stats = ProcessingStats{
 		TotalItems:  len(items),
 		StartTime:   time.Now(),
 		WorkersUsed: p.config.MaxWorkers,
 	}
+	p.processed.Store(0)
+	p.failed.Store(0)
+	p.mu.Unlock()
 
+	// Each worker writes only the entries of its own batch
+	results := make([]ItemResult, len(items))
 	if len(items) == 0 {
-		p.stats.EndTime = time.Now()
-		p.stats.Duration = p.stats.EndTime.Sub(p.stats.StartTime)
-		return []*tokenizers.TokenizationResult{}, []error{}, p.stats
+		return results, p.finish()
 	}
 
-	// Create channels for results and errors
-	resultChan := make(chan *tokenizers.TokenizationResult, len(items))
-	errorChan := make(chan error, len(items))
-
 	// Create context with timeout if specified
 	if p.config.Timeout > 0 {
 		var cancel context.CancelFunc
@@ -79,91 +190,132 @@ func (p *Processor) processItems(
 		defer cancel()
 	}
 
+	// Workers share this context, so reaching the error threshold stops all of them
+	ctx, abort := context.WithCancelCause(ctx)
+	defer abort(nil)
+	var aborted atomic.Bool
+	record := func(index int, result *tokenizers.TokenizationResult, err error) {
+		results[index] = ItemResult{Index: index, Result: result, Err: err}
+		if err == nil {
+			p.processed.Add(1)
+			return
+		}
+		failed := p.failed.Add(1)
+		if p.config.AbortAfterErrors > 0 && failed == int64(p.config.AbortAfterErrors) {
+			aborted.Store(true)
+			abort(fmt.Errorf("%w: %d items failed", ErrTooManyErrors, failed))
+		}
+	}
+
+	if progressCallback != nil {
+		stopProgress := p.reportProgress(progressCallback)
+		defer func() {
+			stopProgress()
+			stats := p.GetStats()
+			progressCallback(stats.ProcessedItems, stats.FailedItems, stats.TotalItems, stats.Duration)
+		}()
+	}
+
 	// Start worker goroutines
 	var wg sync.WaitGroup
 	semaphore := make(chan struct{}, p.config.MaxWorkers)
 
-	for i := 0; i < len(items); i += p.config.BatchSize {
-		end := i + p.config.BatchSize
-		if end > len(items) {
-			end = len(items)
-		}
-
-		batch := items[i:end]
-
+	start := 0
+	for _, batch := range p.createBatches(items, p.config.BatchSize) {
 		wg.Add(1)
-		go func(batchItems []string) {
+		go func(start int, batch []string) {
 			defer wg.Done()
 			semaphore <- struct{}{}        // Acquire semaphore
 			defer func() { <-semaphore }() // Release semaphore
 
-			for _, item := range batchItems {
-				select {
-				case <-ctx.Done():
-					errorChan <- ctx.Err()
-					return
-				default:
-					result, err := processFunc(ctx, item)
-					if err != nil {
-						errorChan <- err
-						p.stats.FailedItems++
-					} else {
-						resultChan <- result
-						p.stats.ProcessedItems++
-					}
+			if ctx.Err() != nil {
+				for i := range batch {
+					record(start+i, nil, context.Cause(ctx))
 				}
+				return
 			}
-		}(batch)
+			processBatch(ctx, start, batch, record)
+		}(start, batch)
+		start += len(batch)
 	}
 
 	// Wait for all workers to complete
 	wg.Wait()
-	close(resultChan)
-	close(errorChan)
 
-	// Collect results and errors
-	var results []*tokenizers.TokenizationResult
-	var errors []error
+	p.mu.Lock()
+	p.stats.Aborted = aborted.Load()
+	p.mu.Unlock()
 
-	for result := range resultChan {
-		results = append(results, result)
-	}
+	return results, p.finish()
+}
 
-	for err := range errorChan {
-		errors = append(errors, err)
+// reportProgress calls progressCallback with the current statistics every
+// ProgressInterval until the returned stop function is called
+func (p *Processor) reportProgress(progressCallback ProgressCallback) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(p.config.ProgressInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				stats := p.GetStats()
+				progressCallback(stats.ProcessedItems, stats.FailedItems, stats.TotalItems, stats.Duration)
+			}
+		}
+	}()
+
+	// Wait for the reporter to exit so the final report is never concurrent with it
+	return func() {
+		close(done)
+		<-stopped
 	}
+}
 
+// finish records the end of a run and returns its final statistics
+func (p *Processor) finish() ProcessingStats {
+	p.mu.Lock()
 	p.stats.EndTime = time.Now()
 	p.stats.Duration = p.stats.EndTime.Sub(p.stats.StartTime)
+	p.mu.Unlock()
 
-	return results, errors, p.stats
+	return p.GetStats()
 }
 
-// ProcessTokenizations processes tokenization in parallel
+// ProcessTokenizations processes tokenization in parallel, returning one ItemResult
+// per text in the order of texts. progressCallback may be nil.
 func (p *Processor) ProcessTokenizations(
 	ctx context.Context,
 	texts []string,
 	tokenizer tokenizers.Tokenizer,
-) ([]*tokenizers.TokenizationResult, []error, ProcessingStats) {
+	progressCallback ProgressCallback,
+) ([]ItemResult, ProcessingStats) {
 
 	processFunc := func(ctx context.Context, text string) (*tokenizers.TokenizationResult, error) {
 		return tokenizer.Tokenize(ctx, text)
 	}
 
-	results, errors, stats := p.processItems(ctx, texts, processFunc)
-	return results, errors, stats
+	return p.processItems(ctx, texts, processFunc, progressCallback)
 }
 
-// ProcessTokenizationsBatch processes tokenization in parallel using batch processing
+// ProcessTokenizationsBatch processes tokenization in parallel with one
+// TokenizeBatch call per batch of BatchSize texts, for tokenizers that handle a
+// batch more cheaply than its texts one by one. Results are in the order of texts;
+// the texts of a failed batch share its BatchError.
 func (p *Processor) ProcessTokenizationsBatch(
 	ctx context.Context,
 	texts []string,
 	tokenizer tokenizers.Tokenizer,
-) ([]*tokenizers.TokenizationResult, []error, ProcessingStats) {
+	progressCallback ProgressCallback,
+) ([]ItemResult, ProcessingStats) {
 
-	// For now, just use the regular processing method
-	// TODO: Implement proper batch processing
-	return p.ProcessTokenizations(ctx, texts, tokenizer)
+	return p.processBatches(ctx, texts, tokenizer.TokenizeBatch, progressCallback)
 }
 
 // createBatches splits a slice into batches of the specified size
@@ -179,9 +331,19 @@ func (p *Processor) createBatches(items []string, batchSize int) [][]string {
 	return batches
 }
 
-// GetStats returns the current processing statistics
+// GetStats returns a snapshot of the processing statistics. During a run the item
+// counts are those finished so far and Duration is the time elapsed.
 func (p *Processor) GetStats() ProcessingStats {
-	return p.stats
+	p.mu.Lock()
+	stats := p.stats
+	p.mu.Unlock()
+
+	stats.ProcessedItems = int(p.processed.Load())
+	stats.FailedItems = int(p.failed.Load())
+	if stats.EndTime.IsZero() && !stats.StartTime.IsZero() {
+		stats.Duration = time.Since(stats.StartTime)
+	}
+	return stats
 }
 
 // GetOptimalWorkerCount returns the optimal number of workers based on system resources
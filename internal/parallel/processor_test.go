@@ -0,0 +1,394 @@
+package parallel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/cache"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// failEveryNth returns a process function that fails items whose number is a
+// multiple of n
+func failEveryNth(n int) func(context.Context, string) (*tokenizers.TokenizationResult, error) {
+	return func(ctx context.Context, item string) (*tokenizers.TokenizationResult, error) {
+		i, _ := strconv.Atoi(item)
+		if i%n == 0 {
+			return nil, fmt.Errorf("item %d failed", i)
+		}
+		return &tokenizers.TokenizationResult{Document: item}, nil
+	}
+}
+
+func TestProcessItemsStatsUnderConcurrency(t *testing.T) {
+	items := make([]string, 5000)
+	for i := range items {
+		items[i] = strconv.Itoa(i)
+	}
+
+	processor := NewProcessor(ProcessorConfig{MaxWorkers: 32, BatchSize: 7})
+
+	// Read stats concurrently with the run; the race detector checks the snapshot
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				stats := processor.GetStats()
+				if stats.ProcessedItems+stats.FailedItems > stats.TotalItems && stats.TotalItems > 0 {
+					t.Errorf("snapshot counts %d+%d items of %d", stats.ProcessedItems, stats.FailedItems, stats.TotalItems)
+					return
+				}
+			}
+		}
+	}()
+
+	results, stats := processor.processItems(context.Background(), items, failEveryNth(10), nil)
+	close(done)
+	wg.Wait()
+
+	if stats.ProcessedItems+stats.FailedItems != stats.TotalItems {
+		t.Errorf("processed %d + failed %d != total %d", stats.ProcessedItems, stats.FailedItems, stats.TotalItems)
+	}
+	failed := 0
+	for _, item := range results {
+		if item.Err != nil {
+			failed++
+		}
+	}
+	if stats.FailedItems != 500 || failed != 500 {
+		t.Errorf("got %d failed items and %d errors, want 500", stats.FailedItems, failed)
+	}
+	if final := processor.GetStats(); final != stats {
+		t.Errorf("GetStats after the run = %+v, want %+v", final, stats)
+	}
+}
+
+func TestProcessItemsEmpty(t *testing.T) {
+	processor := NewProcessor(ProcessorConfig{})
+	results, stats := processor.processItems(context.Background(), nil, failEveryNth(2), nil)
+
+	if len(results) != 0 {
+		t.Errorf("got %d results for no items", len(results))
+	}
+	if stats.TotalItems != 0 || stats.EndTime.IsZero() {
+		t.Errorf("stats = %+v, want a finished run of no items", stats)
+	}
+}
+
+func TestProcessTokenizationsPreservesOrder(t *testing.T) {
+	tokenizer := tokenizers.NewWhitespaceTokenizer("words")
+	if err := tokenizer.Initialize(tokenizers.TokenizerConfig{Name: "words", Type: "custom"}); err != nil {
+		t.Fatalf("Initialize returned error: %v", err)
+	}
+
+	texts := make([]string, 500)
+	for i := range texts {
+		// Each document has a distinct text and i+1 tokens
+		texts[i] = fmt.Sprintf("doc%d", i) + strings.Repeat(" x", i)
+	}
+
+	processor := NewProcessor(ProcessorConfig{MaxWorkers: 16, BatchSize: 3})
+	results, stats := processor.ProcessTokenizations(context.Background(), texts, tokenizer, nil)
+
+	if len(results) != len(texts) || stats.ProcessedItems != len(texts) {
+		t.Fatalf("got %d results and %d processed items for %d texts", len(results), stats.ProcessedItems, len(texts))
+	}
+	for i, item := range results {
+		if item.Index != i || item.Err != nil {
+			t.Fatalf("results[%d] = index %d, error %v", i, item.Index, item.Err)
+		}
+		if item.Result.Document != texts[i] || len(item.Result.Tokens) != i+1 {
+			t.Errorf("results[%d] holds %q with %d tokens, want %q", i, item.Result.Document, len(item.Result.Tokens), texts[i])
+		}
+	}
+}
+
+func TestProcessItemsAttributesErrors(t *testing.T) {
+	items := make([]string, 100)
+	for i := range items {
+		items[i] = strconv.Itoa(i)
+	}
+
+	processor := NewProcessor(ProcessorConfig{MaxWorkers: 8, BatchSize: 4})
+	results, _ := processor.processItems(context.Background(), items, failEveryNth(7), nil)
+
+	for i, item := range results {
+		wantErr := i%7 == 0
+		if (item.Err != nil) != wantErr || (item.Result != nil) == wantErr {
+			t.Errorf("results[%d] = %+v, want failure %v", i, item, wantErr)
+			continue
+		}
+		if wantErr && item.Err.Error() != fmt.Sprintf("item %d failed", i) {
+			t.Errorf("results[%d] has error %q from another item", i, item.Err)
+		}
+		if !wantErr && item.Result.Document != items[i] {
+			t.Errorf("results[%d] holds document %q", i, item.Result.Document)
+		}
+	}
+}
+
+func TestProcessItemsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	processor := NewProcessor(ProcessorConfig{MaxWorkers: 2, BatchSize: 5})
+	results, stats := processor.processItems(ctx, []string{"1", "2", "3", "4", "5", "6"}, failEveryNth(100), nil)
+
+	for i, item := range results {
+		if item.Index != i || !errors.Is(item.Err, context.Canceled) {
+			t.Errorf("results[%d] = %+v, want a cancellation error", i, item)
+		}
+	}
+	if stats.FailedItems != 6 || stats.ProcessedItems != 0 {
+		t.Errorf("stats = %+v, want every item failed", stats)
+	}
+}
+
+func TestProcessItemsAbortAfterErrors(t *testing.T) {
+	items := make([]string, 1000)
+	for i := range items {
+		items[i] = strconv.Itoa(i)
+	}
+
+	var calls atomic.Int64
+	alwaysFail := func(ctx context.Context, item string) (*tokenizers.TokenizationResult, error) {
+		calls.Add(1)
+		return nil, errors.New("bad tokenizer config")
+	}
+
+	processor := NewProcessor(ProcessorConfig{MaxWorkers: 4, BatchSize: 10, AbortAfterErrors: 5})
+	results, stats := processor.processItems(context.Background(), items, alwaysFail, nil)
+
+	if !stats.Aborted {
+		t.Error("expected the run to be aborted")
+	}
+	if n := calls.Load(); n < 5 || n > 5+4 {
+		t.Errorf("process function called %d times, want the threshold plus at most one per worker", n)
+	}
+	if len(results) != len(items) || stats.FailedItems != len(items) {
+		t.Errorf("got %d results and %d failed items, want every item accounted for", len(results), stats.FailedItems)
+	}
+	skipped := 0
+	for i, item := range results {
+		if item.Index != i || item.Err == nil {
+			t.Fatalf("results[%d] = %+v, want a failure", i, item)
+		}
+		if errors.Is(item.Err, ErrTooManyErrors) {
+			skipped++
+		}
+	}
+	if skipped < len(items)-5-4 {
+		t.Errorf("%d items report ErrTooManyErrors, want all that were not attempted", skipped)
+	}
+}
+
+func TestProcessItemsBelowErrorThreshold(t *testing.T) {
+	items := make([]string, 50)
+	for i := range items {
+		items[i] = strconv.Itoa(i)
+	}
+
+	processor := NewProcessor(ProcessorConfig{MaxWorkers: 4, BatchSize: 5, AbortAfterErrors: 10})
+	_, stats := processor.processItems(context.Background(), items, failEveryNth(10), nil)
+
+	if stats.Aborted || stats.FailedItems != 5 || stats.ProcessedItems != 45 {
+		t.Errorf("stats = %+v, want 5 failures without aborting", stats)
+	}
+}
+
+func TestProcessItemsProgress(t *testing.T) {
+	items := make([]string, 60)
+	for i := range items {
+		items[i] = strconv.Itoa(i)
+	}
+	slow := func(ctx context.Context, item string) (*tokenizers.TokenizationResult, error) {
+		time.Sleep(time.Millisecond)
+		return failEveryNth(6)(ctx, item)
+	}
+
+	type report struct{ processed, failed, total int }
+	var reports []report
+	progress := func(processed, failed, total int, elapsed time.Duration) {
+		reports = append(reports, report{processed, failed, total})
+	}
+
+	processor := NewProcessor(ProcessorConfig{MaxWorkers: 2, BatchSize: 5, ProgressInterval: 5 * time.Millisecond})
+	processor.processItems(context.Background(), items, slow, progress)
+
+	if len(reports) < 2 {
+		t.Fatalf("got %d progress reports, want periodic reports and a final one", len(reports))
+	}
+	for i := 1; i < len(reports); i++ {
+		if reports[i].processed+reports[i].failed < reports[i-1].processed+reports[i-1].failed {
+			t.Errorf("progress went backwards: %+v then %+v", reports[i-1], reports[i])
+		}
+	}
+	if last := reports[len(reports)-1]; last != (report{processed: 50, failed: 10, total: 60}) {
+		t.Errorf("final report = %+v, want the finished counts", last)
+	}
+}
+
+// batchCountingTokenizer counts TokenizeBatch calls and fails batches containing a
+// text equal to failOn
+type batchCountingTokenizer struct {
+	*tokenizers.WhitespaceTokenizer
+	calls  atomic.Int64
+	failOn string
+	short  bool // return one result too few
+}
+
+func (b *batchCountingTokenizer) TokenizeBatch(ctx context.Context, texts []string) ([]*tokenizers.TokenizationResult, error) {
+	b.calls.Add(1)
+	for _, text := range texts {
+		if text == b.failOn {
+			return nil, errors.New("bad input in batch")
+		}
+	}
+	results, err := b.WhitespaceTokenizer.TokenizeBatch(ctx, texts)
+	if b.short && err == nil {
+		results = results[:len(results)-1]
+	}
+	return results, err
+}
+
+func newBatchCountingTokenizer(t testing.TB) *batchCountingTokenizer {
+	t.Helper()
+	tokenizer := tokenizers.NewWhitespaceTokenizer("words")
+	if err := tokenizer.Initialize(tokenizers.TokenizerConfig{Name: "words", Type: "custom"}); err != nil {
+		t.Fatalf("Initialize returned error: %v", err)
+	}
+	return &batchCountingTokenizer{WhitespaceTokenizer: tokenizer}
+}
+
+func TestProcessTokenizationsBatch(t *testing.T) {
+	texts := make([]string, 103)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("doc%d", i) + strings.Repeat(" x", i%5)
+	}
+
+	tokenizer := newBatchCountingTokenizer(t)
+	processor := NewProcessor(ProcessorConfig{MaxWorkers: 4, BatchSize: 10})
+	results, stats := processor.ProcessTokenizationsBatch(context.Background(), texts, tokenizer, nil)
+
+	if n := tokenizer.calls.Load(); n != 11 {
+		t.Errorf("TokenizeBatch called %d times, want once per batch of 10", n)
+	}
+	if stats.ProcessedItems != len(texts) || stats.FailedItems != 0 {
+		t.Errorf("stats = %+v, want every text processed", stats)
+	}
+	for i, item := range results {
+		if item.Index != i || item.Err != nil || item.Result.Document != texts[i] {
+			t.Errorf("results[%d] = %+v, want the result for %q", i, item, texts[i])
+		}
+	}
+}
+
+func TestProcessTokenizationsBatchErrors(t *testing.T) {
+	texts := make([]string, 25)
+	for i := range texts {
+		texts[i] = strconv.Itoa(i)
+	}
+
+	t.Run("failed batch", func(t *testing.T) {
+		tokenizer := newBatchCountingTokenizer(t)
+		tokenizer.failOn = "13"
+
+		processor := NewProcessor(ProcessorConfig{MaxWorkers: 3, BatchSize: 10})
+		results, stats := processor.ProcessTokenizationsBatch(context.Background(), texts, tokenizer, nil)
+
+		if stats.FailedItems != 10 || stats.ProcessedItems != 15 {
+			t.Errorf("stats = %+v, want only the second batch failed", stats)
+		}
+		for i, item := range results {
+			var batchErr *BatchError
+			inFailedBatch := i >= 10 && i < 20
+			if inFailedBatch != errors.As(item.Err, &batchErr) {
+				t.Errorf("results[%d] has error %v, want a batch error %v", i, item.Err, inFailedBatch)
+				continue
+			}
+			if inFailedBatch && (batchErr.Start != 10 || batchErr.End != 20) {
+				t.Errorf("results[%d] blames batch %d-%d, want 10-20", i, batchErr.Start, batchErr.End)
+			}
+		}
+	})
+
+	t.Run("missing results", func(t *testing.T) {
+		tokenizer := newBatchCountingTokenizer(t)
+		tokenizer.short = true
+
+		processor := NewProcessor(ProcessorConfig{MaxWorkers: 3, BatchSize: 10})
+		results, stats := processor.ProcessTokenizationsBatch(context.Background(), texts, tokenizer, nil)
+
+		if stats.FailedItems != len(texts) {
+			t.Errorf("stats = %+v, want every batch failed", stats)
+		}
+		if err := results[0].Err; err == nil || !strings.Contains(err.Error(), "got 9 results for 10 items") {
+			t.Errorf("results[0] has error %v, want a result count mismatch", err)
+		}
+	})
+}
+
+// callCostTokenizer adds a fixed cost to every call, like the process round trip of a
+// tokenizer running outside Go
+type callCostTokenizer struct {
+	tokenizers.Tokenizer
+	cost time.Duration
+}
+
+func (c *callCostTokenizer) Tokenize(ctx context.Context, text string) (*tokenizers.TokenizationResult, error) {
+	time.Sleep(c.cost)
+	return c.Tokenizer.Tokenize(ctx, text)
+}
+
+func (c *callCostTokenizer) TokenizeBatch(ctx context.Context, texts []string) ([]*tokenizers.TokenizationResult, error) {
+	time.Sleep(c.cost)
+	return c.Tokenizer.TokenizeBatch(ctx, texts)
+}
+
+func BenchmarkProcessTokenizations(b *testing.B) {
+	texts := make([]string, 2000)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("document %d with a few words of text", i)
+	}
+
+	mock := tokenizers.NewMockTokenizer("mock")
+	if err := mock.Initialize(tokenizers.TokenizerConfig{Name: "mock", Type: "custom"}); err != nil {
+		b.Fatalf("Initialize returned error: %v", err)
+	}
+	cached := tokenizers.NewCachedTokenizer(&callCostTokenizer{Tokenizer: mock, cost: 20 * time.Microsecond}, cache.CacheConfig{MaxSize: 10000})
+	defer cached.Close()
+
+	processor := NewProcessor(ProcessorConfig{MaxWorkers: 4, BatchSize: 100})
+	modes := []struct {
+		name    string
+		process func(context.Context, []string, tokenizers.Tokenizer, ProgressCallback) ([]ItemResult, ProcessingStats)
+	}{
+		{"per-item", processor.ProcessTokenizations},
+		{"batch", processor.ProcessTokenizationsBatch},
+	}
+
+	for _, mode := range modes {
+		b.Run(mode.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				// Start cold so every text reaches the tokenizer
+				cached.ClearCache()
+				if _, stats := mode.process(context.Background(), texts, cached, nil); stats.FailedItems != 0 {
+					b.Fatalf("%d items failed", stats.FailedItems)
+				}
+			}
+		})
+	}
+}
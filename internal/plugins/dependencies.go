@@ -0,0 +1,119 @@
+package plugins
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DependsOnKey is the PluginInfo metadata key listing, comma-separated, the plugins
+// whose results a plugin needs
+const DependsOnKey = "depends_on"
+
+// DependencyResultsKey is the AnalysisContext.Config key under which a plugin receives
+// the results of the plugins it depends on, as a map[string][]MetricResult by name
+const DependencyResultsKey = "plugin_results"
+
+// Dependencies returns the names of the plugins listed under DependsOnKey
+func (i PluginInfo) Dependencies() []string {
+	var dependencies []string
+	for _, name := range strings.Split(i.Metadata[DependsOnKey], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			dependencies = append(dependencies, name)
+		}
+	}
+	return dependencies
+}
+
+// dependencyOrder sorts the registered plugins so that each follows the registered
+// plugins it depends on, breaking ties by name. Plugins in or behind a dependency
+// cycle are returned separately, sorted by name. The caller must hold r.mu.
+func (r *Registry) dependencyOrder() (order []string, cyclic []string) {
+	pending := make(map[string]int, len(r.plugins))
+	dependents := make(map[string][]string)
+	for name, plugin := range r.plugins {
+		pending[name] = 0
+		for _, dependency := range plugin.Info().Dependencies() {
+			if _, exists := r.plugins[dependency]; exists {
+				pending[name]++
+				dependents[dependency] = append(dependents[dependency], name)
+			}
+		}
+	}
+
+	var ready []string
+	for name, count := range pending {
+		if count == 0 {
+			ready = append(ready, name)
+		}
+	}
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		name := ready[0]
+		ready = ready[1:]
+		order = append(order, name)
+		for _, dependent := range dependents[name] {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+		delete(pending, name)
+	}
+
+	for name := range pending {
+		cyclic = append(cyclic, name)
+	}
+	sort.Strings(cyclic)
+	return order, cyclic
+}
+
+// checkDependencies returns an error describing a dependency cycle among the
+// registered plugins, if there is one. The caller must hold r.mu.
+func (r *Registry) checkDependencies() error {
+	_, cyclic := r.dependencyOrder()
+	if len(cyclic) == 0 {
+		return nil
+	}
+
+	// Follow dependencies from a plugin left over until one repeats
+	path := []string{}
+	seen := make(map[string]int)
+	left := make(map[string]bool, len(cyclic))
+	for _, name := range cyclic {
+		left[name] = true
+	}
+	for name := cyclic[0]; ; {
+		if start, repeated := seen[name]; repeated {
+			path = append(path[start:], name)
+			break
+		}
+		seen[name] = len(path)
+		path = append(path, name)
+		for _, dependency := range r.plugins[name].Info().Dependencies() {
+			if left[dependency] {
+				name = dependency
+				break
+			}
+		}
+	}
+	return fmt.Errorf("plugin dependency cycle: %s", strings.Join(path, " -> "))
+}
+
+// withDependencyResults returns a copy of ctx whose Config also holds the results of
+// the plugin's dependencies
+func withDependencyResults(ctx *AnalysisContext, results map[string][]MetricResult) *AnalysisContext {
+	if len(results) == 0 {
+		return ctx
+	}
+
+	config := make(map[string]interface{}, len(ctx.Config)+1)
+	for key, value := range ctx.Config {
+		config[key] = value
+	}
+	config[DependencyResultsKey] = results
+
+	pluginCtx := *ctx
+	pluginCtx.Config = config
+	return &pluginCtx
+}
@@ -0,0 +1,288 @@
+package examples
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/plugins"
+)
+
+// UndeterminedLanguage is reported for lines without enough letters to detect a script
+const UndeterminedLanguage = "und"
+
+// LatinLanguage is reported for Latin-script lines that are neither clearly English
+// nor clearly German
+const LatinLanguage = "latin"
+
+// OtherLanguage is reported for lines in scripts the detector does not know
+const OtherLanguage = "other"
+
+// scriptLanguages maps the scripts the detector counts to the language reported for
+// lines mostly written in them. Latin, Han and kana are decided separately.
+var scriptLanguages = []struct {
+	script   *unicode.RangeTable
+	language string
+}{
+	{unicode.Cyrillic, "ru"},
+	{unicode.Greek, "el"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Thai, "th"},
+	{unicode.Hangul, "ko"},
+}
+
+// Function words that tell English and German Latin-script lines apart
+var (
+	englishWords = wordSet("the", "and", "is", "are", "of", "to", "in", "it", "that", "with", "for", "this", "was", "on", "be", "not", "you", "have")
+	germanWords  = wordSet("der", "die", "das", "und", "ist", "sind", "nicht", "ich", "mit", "ein", "eine", "zu", "von", "auf", "den", "dem", "sie", "es", "für", "auch")
+)
+
+// LanguageBreakdownAnalyzer is an example plugin that detects the language of each
+// line from its script and function words, and reports token metrics per language
+type LanguageBreakdownAnalyzer struct {
+	*plugins.BasePlugin
+}
+
+// NewLanguageBreakdownAnalyzer creates a new language breakdown plugin
+func NewLanguageBreakdownAnalyzer() *LanguageBreakdownAnalyzer {
+	info := plugins.PluginInfo{
+		Name:        "language_breakdown",
+		Version:     "1.0.0",
+		Description: "Breaks token metrics down by the detected language of each line",
+		Author:      "TokEntropyDrift Team",
+		Tags:        []string{"analysis", "tokens", "language"},
+		Metadata: map[string]string{
+			"category": "token_analysis",
+		},
+	}
+
+	return &LanguageBreakdownAnalyzer{
+		BasePlugin: plugins.NewBasePlugin(info),
+	}
+}
+
+// ValidateConfig validates the plugin configuration
+func (l *LanguageBreakdownAnalyzer) ValidateConfig(config map[string]interface{}) error {
+	if value, exists := config["min_letters"]; exists {
+		switch minLetters := value.(type) {
+		case int:
+			if minLetters < 1 {
+				return fmt.Errorf("min_letters must be a positive integer")
+			}
+		case float64:
+			if minLetters < 1 || minLetters != float64(int(minLetters)) {
+				return fmt.Errorf("min_letters must be a positive integer")
+			}
+		default:
+			return fmt.Errorf("min_letters must be a positive integer")
+		}
+	}
+	return nil
+}
+
+// languageLines accumulates the lines and tokens of one language
+type languageLines struct {
+	lines      int
+	tokens     int
+	tokenRunes int // characters in the tokens, without surrounding whitespace
+}
+
+// CalculateMetrics calculates per-language token metrics, named lang_{code}_{metric}.
+// Tokens are assigned to lines by their offsets, so tokenizations without offsets
+// produce no metrics.
+func (l *LanguageBreakdownAnalyzer) CalculateMetrics(ctx *plugins.AnalysisContext) ([]plugins.MetricResult, error) {
+	if ctx.Tokenization == nil || len(ctx.Tokenization.Tokens) == 0 {
+		return []plugins.MetricResult{}, nil
+	}
+	document := ctx.Document
+	minLetters := l.GetConfigInt("min_letters", 1)
+
+	// Detect each non-blank line's language; lineStarts[i] is where line i begins
+	var lineStarts []int
+	var lineLanguages []string
+	languages := make(map[string]*languageLines)
+	for start := 0; start < len(document); {
+		end := strings.IndexByte(document[start:], '\n')
+		if end < 0 {
+			end = len(document)
+		} else {
+			end += start
+		}
+
+		line := document[start:end]
+		language := ""
+		if strings.TrimSpace(line) != "" {
+			language = detectLanguage(line, minLetters)
+			if languages[language] == nil {
+				languages[language] = &languageLines{}
+			}
+			languages[language].lines++
+		}
+		lineStarts = append(lineStarts, start)
+		lineLanguages = append(lineLanguages, language)
+		start = end + 1
+	}
+
+	// Assign each token to the line of its first non-space byte
+	assigned := 0
+	for _, token := range ctx.Tokenization.Tokens {
+		if token.EndPos <= token.StartPos || token.EndPos > len(document) {
+			continue
+		}
+		span := document[token.StartPos:token.EndPos]
+		position := token.StartPos
+		if trimmed := strings.TrimLeftFunc(span, unicode.IsSpace); trimmed != "" {
+			position += len(span) - len(trimmed)
+		}
+
+		line := sort.SearchInts(lineStarts, position+1) - 1
+		if line < 0 || lineLanguages[line] == "" {
+			continue
+		}
+		stats := languages[lineLanguages[line]]
+		stats.tokens++
+		stats.tokenRunes += utf8.RuneCountInString(strings.TrimSpace(span))
+		assigned++
+	}
+	if assigned == 0 {
+		return []plugins.MetricResult{}, nil
+	}
+
+	codes := make([]string, 0, len(languages))
+	for code := range languages {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	results := make([]plugins.MetricResult, 0, 4*len(codes))
+	for _, code := range codes {
+		stats := languages[code]
+		meanLength := 0.0
+		if stats.tokens > 0 {
+			meanLength = float64(stats.tokenRunes) / float64(stats.tokens)
+		}
+		prefix := "lang_" + code + "_"
+		results = append(results,
+			plugins.MetricResult{
+				Name:  prefix + "line_count",
+				Value: float64(stats.lines),
+				Unit:  "lines",
+			},
+			plugins.MetricResult{
+				Name:  prefix + "token_share",
+				Value: float64(stats.tokens) / float64(assigned),
+				Unit:  "ratio",
+			},
+			plugins.MetricResult{
+				Name:  prefix + "mean_tokens_per_line",
+				Value: float64(stats.tokens) / float64(stats.lines),
+				Unit:  "tokens",
+			},
+			plugins.MetricResult{
+				Name:  prefix + "mean_token_length",
+				Value: meanLength,
+				Unit:  "characters",
+			},
+		)
+	}
+
+	return results, nil
+}
+
+// DetectLanguage guesses the language of a line from the script most of its letters
+// are written in. Japanese is told from Chinese by its kana, and English from German
+// by function words and umlauts; other Latin-script lines are LatinLanguage.
+func DetectLanguage(line string) string {
+	return detectLanguage(line, 1)
+}
+
+// detectLanguage is DetectLanguage with a minimum number of letters, below which the
+// line is UndeterminedLanguage
+func detectLanguage(line string, minLetters int) string {
+	counts := make(map[string]int)
+	letters := 0
+	for _, r := range line {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			counts[LatinLanguage]++
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r), r == 'ー':
+			counts["kana"]++
+		case unicode.Is(unicode.Han, r):
+			counts["han"]++
+		default:
+			language := OtherLanguage
+			for _, entry := range scriptLanguages {
+				if unicode.Is(entry.script, r) {
+					language = entry.language
+					break
+				}
+			}
+			counts[language]++
+		}
+	}
+	if letters < minLetters || letters == 0 {
+		return UndeterminedLanguage
+	}
+
+	// Kanji and kana together are Japanese; Han without kana is Chinese
+	if counts["kana"] > 0 {
+		counts["ja"] = counts["kana"] + counts["han"]
+	} else if counts["han"] > 0 {
+		counts["zh"] = counts["han"]
+	}
+	delete(counts, "kana")
+	delete(counts, "han")
+
+	best := ""
+	for language, count := range counts {
+		if count > counts[best] || (count == counts[best] && language < best) {
+			best = language
+		}
+	}
+
+	if best == LatinLanguage {
+		return detectLatinLanguage(line)
+	}
+	return best
+}
+
+// detectLatinLanguage tells English from German by their function words, counting
+// umlauts and ß as German evidence
+func detectLatinLanguage(line string) string {
+	english, german := 0, 0
+	for _, word := range strings.FieldsFunc(strings.ToLower(line), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		if englishWords[word] {
+			english++
+		}
+		if germanWords[word] {
+			german++
+		}
+		german += strings.Count(word, "ä") + strings.Count(word, "ö") + strings.Count(word, "ü") + strings.Count(word, "ß")
+	}
+
+	switch {
+	case english > german:
+		return "en"
+	case german > english:
+		return "de"
+	default:
+		return LatinLanguage
+	}
+}
+
+// wordSet builds a set of words
+func wordSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[word] = true
+	}
+	return set
+}
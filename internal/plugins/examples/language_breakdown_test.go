@@ -0,0 +1,151 @@
+package examples
+
+import (
+	"math"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/plugins"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"The quick brown fox jumps over the lazy dog", "en"},
+		{"This is not what you want", "en"},
+		{"Der schnelle braune Fuchs springt über den faulen Hund", "de"},
+		{"Ich bin nicht müde", "de"},
+		{"Schöne Grüße", "de"},
+		{"Lorem ipsum dolor sit amet", LatinLanguage},
+		{"東京は日本の首都です", "ja"},
+		{"コンピューター", "ja"},
+		{"北京是中国的首都", "zh"},
+		{"서울은 한국의 수도입니다", "ko"},
+		{"Москва — столица России", "ru"},
+		{"Η Αθήνα είναι η πρωτεύουσα", "el"},
+		{"القاهرة هي عاصمة مصر", "ar"},
+		{"ירושלים היא עיר", "he"},
+		{"नई दिल्ली भारत की राजधानी है", "hi"},
+		{"กรุงเทพมหานคร", "th"},
+		// Mixed lines go to the script most letters are in
+		{"Tokyo の天気は晴れです", "ja"},
+		{"The word 東京 means eastern capital", "en"},
+		{"Der Begriff 東京 ist ein Ortsname", "de"},
+		{"12345 + 678 = ?", UndeterminedLanguage},
+		{"", UndeterminedLanguage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if got := DetectLanguage(tt.line); got != tt.want {
+				t.Errorf("DetectLanguage(%q) = %s, want %s", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLanguageBreakdownAnalyzer(t *testing.T) {
+	tests := []struct {
+		name         string
+		document     string
+		tokenization func(t *testing.T, document string) *tokenizers.TokenizationResult
+		config       map[string]interface{}
+		want         map[string]float64
+	}{
+		{
+			name:         "mock words",
+			document:     "The cat is on the mat\nDer Hund ist nicht hier\n\n東京は日本の首都です\n",
+			tokenization: mockTokenization,
+			want: map[string]float64{
+				"lang_en_line_count":           1,
+				"lang_en_token_share":          0.5,
+				"lang_en_mean_tokens_per_line": 6,
+				"lang_en_mean_token_length":    16.0 / 6,
+				"lang_de_token_share":          5.0 / 12,
+				"lang_de_mean_tokens_per_line": 5,
+				"lang_ja_token_share":          1.0 / 12,
+				"lang_ja_mean_token_length":    10,
+			},
+		},
+		{
+			name:     "byte-level BPE",
+			document: "hello world\nこんにちは",
+			tokenization: func(t *testing.T, document string) *tokenizers.TokenizationResult {
+				// A token carrying the newline belongs to the line its text starts on
+				return &tokenizers.TokenizationResult{Tokens: []tokenizers.Token{
+					tokenSpan("hello", 0, 5), tokenSpan("Ġworld", 5, 11),
+					tokenSpan("Ċこん", 11, 18), tokenSpan("にちは", 18, 27),
+				}}
+			},
+			want: map[string]float64{
+				"lang_latin_token_share":       0.5,
+				"lang_latin_mean_token_length": 5,
+				"lang_ja_token_share":          0.5,
+				"lang_ja_mean_tokens_per_line": 2,
+				"lang_ja_mean_token_length":    2.5,
+			},
+		},
+		{
+			name:         "min letters",
+			document:     "OK\nThis is the longer line",
+			tokenization: mockTokenization,
+			config:       map[string]interface{}{"min_letters": 3},
+			want: map[string]float64{
+				"lang_und_line_count":  1,
+				"lang_und_token_share": 1.0 / 6,
+				"lang_en_token_share":  5.0 / 6,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := NewLanguageBreakdownAnalyzer()
+			config := tt.config
+			if config == nil {
+				config = map[string]interface{}{}
+			}
+			if err := plugin.ValidateConfig(config); err != nil {
+				t.Fatalf("ValidateConfig returned error: %v", err)
+			}
+			if err := plugin.Initialize(config); err != nil {
+				t.Fatalf("Initialize returned error: %v", err)
+			}
+
+			results, err := plugin.CalculateMetrics(&plugins.AnalysisContext{
+				Document:     tt.document,
+				Tokenization: tt.tokenization(t, tt.document),
+			})
+			if err != nil {
+				t.Fatalf("CalculateMetrics returned error: %v", err)
+			}
+
+			got := make(map[string]float64, len(results))
+			for _, result := range results {
+				got[result.Name] = result.Value
+			}
+			for name, want := range tt.want {
+				value, ok := got[name]
+				if !ok || math.Abs(value-want) > 1e-9 {
+					t.Errorf("%s = %v, %v, want %v", name, value, ok, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLanguageBreakdownAnalyzerValidateConfig(t *testing.T) {
+	plugin := NewLanguageBreakdownAnalyzer()
+	for _, value := range []interface{}{0, -2, 1.5, "three"} {
+		if err := plugin.ValidateConfig(map[string]interface{}{"min_letters": value}); err == nil {
+			t.Errorf("ValidateConfig accepted min_letters %v", value)
+		}
+	}
+	for _, value := range []interface{}{1, 3.0} {
+		if err := plugin.ValidateConfig(map[string]interface{}{"min_letters": value}); err != nil {
+			t.Errorf("ValidateConfig rejected min_letters %v: %v", value, err)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package examples
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/plugins"
+	"github.com/RevBooyah/TokEntropyDrift/internal/stats"
+)
+
+// DefaultWordPattern splits documents into whitespace-separated words
+const DefaultWordPattern = `\S+`
+
+// DefaultTopFragmentedWords is how many of the most fragmented words are reported
+const DefaultTopFragmentedWords = 10
+
+// continuationPrefix marks WordPiece tokens that continue the previous token's word
+const continuationPrefix = "##"
+
+// SubwordFragmentationAnalyzer is an example plugin that measures how many tokens each
+// word is split into, using the tokens' offsets
+type SubwordFragmentationAnalyzer struct {
+	*plugins.BasePlugin
+	wordPattern *regexp.Regexp
+	topK        int
+}
+
+// FragmentedWord is a word and the most tokens it was split into in a document
+type FragmentedWord struct {
+	Word        string `json:"word"`
+	Pieces      int    `json:"pieces"`
+	Occurrences int    `json:"occurrences"`
+}
+
+// NewSubwordFragmentationAnalyzer creates a new subword fragmentation plugin
+func NewSubwordFragmentationAnalyzer() *SubwordFragmentationAnalyzer {
+	info := plugins.PluginInfo{
+		Name:        "subword_fragmentation",
+		Version:     "1.0.0",
+		Description: "Measures how many tokens each word is split into",
+		Author:      "TokEntropyDrift Team",
+		Tags:        []string{"analysis", "tokens", "subwords"},
+		Metadata: map[string]string{
+			"category": "token_analysis",
+		},
+	}
+
+	return &SubwordFragmentationAnalyzer{
+		BasePlugin:  plugins.NewBasePlugin(info),
+		wordPattern: regexp.MustCompile(DefaultWordPattern),
+		topK:        DefaultTopFragmentedWords,
+	}
+}
+
+// Initialize stores the configuration and compiles the word pattern
+func (s *SubwordFragmentationAnalyzer) Initialize(config map[string]interface{}) error {
+	if err := s.BasePlugin.Initialize(config); err != nil {
+		return err
+	}
+
+	pattern, err := regexp.Compile(s.GetConfigString("word_pattern", DefaultWordPattern))
+	if err != nil {
+		return fmt.Errorf("invalid word_pattern: %w", err)
+	}
+	s.wordPattern = pattern
+	s.topK = s.GetConfigInt("top_k", DefaultTopFragmentedWords)
+
+	return nil
+}
+
+// ValidateConfig validates the plugin configuration
+func (s *SubwordFragmentationAnalyzer) ValidateConfig(config map[string]interface{}) error {
+	if value, exists := config["word_pattern"]; exists {
+		pattern, ok := value.(string)
+		if !ok || pattern == "" {
+			return fmt.Errorf("word_pattern must be a non-empty regular expression")
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid word_pattern: %w", err)
+		}
+	}
+
+	if value, exists := config["top_k"]; exists {
+		switch topK := value.(type) {
+		case int:
+			if topK < 0 {
+				return fmt.Errorf("top_k must be a non-negative integer")
+			}
+		case float64:
+			if topK < 0 || topK != float64(int(topK)) {
+				return fmt.Errorf("top_k must be a non-negative integer")
+			}
+		default:
+			return fmt.Errorf("top_k must be a non-negative integer")
+		}
+	}
+
+	return nil
+}
+
+// CalculateMetrics calculates subword fragmentation metrics. Tokenizations without
+// token offsets produce no metrics.
+func (s *SubwordFragmentationAnalyzer) CalculateMetrics(ctx *plugins.AnalysisContext) ([]plugins.MetricResult, error) {
+	if ctx.Tokenization == nil || len(ctx.Tokenization.Tokens) == 0 {
+		return []plugins.MetricResult{}, nil
+	}
+	tokens := ctx.Tokenization.Tokens
+
+	hasOffsets := false
+	continuations := 0
+	for _, token := range tokens {
+		if token.EndPos > token.StartPos {
+			hasOffsets = true
+		}
+		if strings.HasPrefix(token.Text, continuationPrefix) {
+			continuations++
+		}
+	}
+	if !hasOffsets {
+		return []plugins.MetricResult{}, nil
+	}
+
+	// Count the tokens overlapping each word; words are in document order
+	words := s.wordPattern.FindAllStringIndex(ctx.Document, -1)
+	pieces := make([]int, len(words))
+	for _, token := range tokens {
+		first := sort.Search(len(words), func(i int) bool { return words[i][1] > token.StartPos })
+		for i := first; i < len(words) && words[i][0] < token.EndPos; i++ {
+			pieces[i]++
+		}
+	}
+
+	// Only words covered by at least one token contribute
+	var covered []int
+	buckets := [4]int{} // words in 1, 2, 3 and 4+ pieces
+	fragmented := make(map[string]*FragmentedWord)
+	for i, count := range pieces {
+		if count == 0 {
+			continue
+		}
+		covered = append(covered, count)
+		buckets[min(count, 4)-1]++
+
+		if count > 1 {
+			word := ctx.Document[words[i][0]:words[i][1]]
+			entry, exists := fragmented[word]
+			if !exists {
+				entry = &FragmentedWord{Word: word}
+				fragmented[word] = entry
+			}
+			entry.Occurrences++
+			entry.Pieces = max(entry.Pieces, count)
+		}
+	}
+	if len(covered) == 0 {
+		return []plugins.MetricResult{}, nil
+	}
+
+	wordCount := float64(len(covered))
+	return []plugins.MetricResult{
+		{
+			Name:  "word_count",
+			Value: wordCount,
+			Unit:  "words",
+		},
+		{
+			Name:  "mean_pieces_per_word",
+			Value: stats.Mean(covered),
+			Unit:  "tokens",
+		},
+		{
+			Name:  "max_pieces_per_word",
+			Value: float64(stats.Max(covered)),
+			Unit:  "tokens",
+			Metadata: map[string]interface{}{
+				"top_fragmented_words": s.topFragmented(fragmented),
+			},
+		},
+		{
+			Name:  "one_piece_ratio",
+			Value: float64(buckets[0]) / wordCount,
+			Unit:  "ratio",
+		},
+		{
+			Name:  "two_piece_ratio",
+			Value: float64(buckets[1]) / wordCount,
+			Unit:  "ratio",
+		},
+		{
+			Name:  "three_piece_ratio",
+			Value: float64(buckets[2]) / wordCount,
+			Unit:  "ratio",
+		},
+		{
+			Name:  "four_plus_piece_ratio",
+			Value: float64(buckets[3]) / wordCount,
+			Unit:  "ratio",
+		},
+		{
+			Name:  "continuation_token_ratio",
+			Value: float64(continuations) / float64(len(tokens)),
+			Unit:  "ratio",
+		},
+	}, nil
+}
+
+// topFragmented returns the top K words by pieces, then by occurrences
+func (s *SubwordFragmentationAnalyzer) topFragmented(fragmented map[string]*FragmentedWord) []FragmentedWord {
+	words := make([]FragmentedWord, 0, len(fragmented))
+	for _, entry := range fragmented {
+		words = append(words, *entry)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if words[i].Pieces != words[j].Pieces {
+			return words[i].Pieces > words[j].Pieces
+		}
+		if words[i].Occurrences != words[j].Occurrences {
+			return words[i].Occurrences > words[j].Occurrences
+		}
+		return words[i].Word < words[j].Word
+	})
+
+	if len(words) > s.topK {
+		words = words[:s.topK]
+	}
+	return words
+}
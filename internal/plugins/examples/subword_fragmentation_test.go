@@ -0,0 +1,192 @@
+package examples
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/plugins"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// tokenSpan builds a token covering document[start:end]
+func tokenSpan(text string, start, end int) tokenizers.Token {
+	return tokenizers.Token{Text: text, StartPos: start, EndPos: end}
+}
+
+func mockTokenization(t *testing.T, document string) *tokenizers.TokenizationResult {
+	t.Helper()
+	mock := tokenizers.NewMockTokenizer("mock")
+	if err := mock.Initialize(tokenizers.TokenizerConfig{Name: "mock", Type: "custom"}); err != nil {
+		t.Fatalf("failed to initialize mock tokenizer: %v", err)
+	}
+	tokenization, err := mock.Tokenize(context.Background(), document)
+	if err != nil {
+		t.Fatalf("Tokenize returned error: %v", err)
+	}
+	return tokenization
+}
+
+func TestSubwordFragmentationAnalyzer(t *testing.T) {
+	tests := []struct {
+		name         string
+		document     string
+		tokenization func(t *testing.T, document string) *tokenizers.TokenizationResult
+		config       map[string]interface{}
+		want         map[string]float64
+		wantTop      []FragmentedWord
+	}{
+		{
+			name:         "mock words",
+			document:     "hello big world",
+			tokenization: mockTokenization,
+			want: map[string]float64{
+				"word_count":               3,
+				"mean_pieces_per_word":     1,
+				"max_pieces_per_word":      1,
+				"one_piece_ratio":          1,
+				"two_piece_ratio":          0,
+				"continuation_token_ratio": 0,
+			},
+			wantTop: []FragmentedWord{},
+		},
+		{
+			name:     "byte-level BPE",
+			document: "tokenization is unbelievable tokenization",
+			tokenization: func(t *testing.T, document string) *tokenizers.TokenizationResult {
+				// GPT-2 style tokens carry the preceding space
+				return &tokenizers.TokenizationResult{Tokens: []tokenizers.Token{
+					tokenSpan("token", 0, 5), tokenSpan("ization", 5, 12),
+					tokenSpan("Ġis", 12, 15),
+					tokenSpan("Ġun", 15, 18), tokenSpan("believ", 18, 24), tokenSpan("able", 24, 28),
+					tokenSpan("Ġtoken", 28, 34), tokenSpan("ization", 34, 41),
+				}}
+			},
+			want: map[string]float64{
+				"word_count":               4,
+				"mean_pieces_per_word":     2,
+				"max_pieces_per_word":      3,
+				"one_piece_ratio":          0.25,
+				"two_piece_ratio":          0.5,
+				"three_piece_ratio":        0.25,
+				"four_plus_piece_ratio":    0,
+				"continuation_token_ratio": 0,
+			},
+			wantTop: []FragmentedWord{
+				{Word: "unbelievable", Pieces: 3, Occurrences: 1},
+				{Word: "tokenization", Pieces: 2, Occurrences: 2},
+			},
+		},
+		{
+			name:     "WordPiece with top_k",
+			document: "playing footballers",
+			tokenization: func(t *testing.T, document string) *tokenizers.TokenizationResult {
+				return &tokenizers.TokenizationResult{Tokens: []tokenizers.Token{
+					tokenSpan("[CLS]", 0, 0),
+					tokenSpan("play", 0, 4), tokenSpan("##ing", 4, 7),
+					tokenSpan("foot", 8, 12), tokenSpan("##ball", 12, 16), tokenSpan("##er", 16, 18), tokenSpan("##s", 18, 19),
+					tokenSpan("[SEP]", 0, 0),
+				}}
+			},
+			config: map[string]interface{}{"top_k": 1},
+			want: map[string]float64{
+				"word_count":               2,
+				"max_pieces_per_word":      4,
+				"two_piece_ratio":          0.5,
+				"four_plus_piece_ratio":    0.5,
+				"continuation_token_ratio": 0.5,
+			},
+			wantTop: []FragmentedWord{{Word: "footballers", Pieces: 4, Occurrences: 1}},
+		},
+		{
+			name:     "custom word pattern",
+			document: "don't-stop",
+			tokenization: func(t *testing.T, document string) *tokenizers.TokenizationResult {
+				return &tokenizers.TokenizationResult{Tokens: []tokenizers.Token{
+					tokenSpan("don", 0, 3), tokenSpan("'t", 3, 5), tokenSpan("-", 5, 6), tokenSpan("stop", 6, 10),
+				}}
+			},
+			config: map[string]interface{}{"word_pattern": `[A-Za-z]+`},
+			want: map[string]float64{
+				"word_count":      3,
+				"one_piece_ratio": 1,
+			},
+			wantTop: []FragmentedWord{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := NewSubwordFragmentationAnalyzer()
+			config := tt.config
+			if config == nil {
+				config = map[string]interface{}{}
+			}
+			if err := plugin.ValidateConfig(config); err != nil {
+				t.Fatalf("ValidateConfig returned error: %v", err)
+			}
+			if err := plugin.Initialize(config); err != nil {
+				t.Fatalf("Initialize returned error: %v", err)
+			}
+
+			results, err := plugin.CalculateMetrics(&plugins.AnalysisContext{
+				Document:     tt.document,
+				Tokenization: tt.tokenization(t, tt.document),
+			})
+			if err != nil {
+				t.Fatalf("CalculateMetrics returned error: %v", err)
+			}
+
+			got := make(map[string]plugins.MetricResult, len(results))
+			for _, result := range results {
+				got[result.Name] = result
+			}
+			for name, want := range tt.want {
+				if got[name].Value != want {
+					t.Errorf("%s = %v, want %v", name, got[name].Value, want)
+				}
+			}
+			top := got["max_pieces_per_word"].Metadata["top_fragmented_words"]
+			if !reflect.DeepEqual(top, tt.wantTop) {
+				t.Errorf("top_fragmented_words = %+v, want %+v", top, tt.wantTop)
+			}
+		})
+	}
+}
+
+func TestSubwordFragmentationAnalyzerNoOffsets(t *testing.T) {
+	plugin := NewSubwordFragmentationAnalyzer()
+	results, err := plugin.CalculateMetrics(&plugins.AnalysisContext{
+		Document:     "two words",
+		Tokenization: &tokenizers.TokenizationResult{Tokens: []tokenizers.Token{{Text: "two"}, {Text: "words"}}},
+	})
+	if err != nil || len(results) != 0 {
+		t.Errorf("CalculateMetrics = %v, %v, want no metrics without offsets", results, err)
+	}
+}
+
+func TestSubwordFragmentationAnalyzerValidateConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr bool
+	}{
+		{"defaults", map[string]interface{}{}, false},
+		{"valid", map[string]interface{}{"word_pattern": `\w+`, "top_k": 5}, false},
+		{"yaml float", map[string]interface{}{"top_k": 5.0}, false},
+		{"bad pattern", map[string]interface{}{"word_pattern": `[a-`}, true},
+		{"empty pattern", map[string]interface{}{"word_pattern": ""}, true},
+		{"negative top_k", map[string]interface{}{"top_k": -1}, true},
+		{"fractional top_k", map[string]interface{}{"top_k": 2.5}, true},
+		{"string top_k", map[string]interface{}{"top_k": "ten"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewSubwordFragmentationAnalyzer().ValidateConfig(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateConfig error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
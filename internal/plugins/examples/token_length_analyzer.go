@@ -3,9 +3,9 @@ package examples
 import (
 	"fmt"
 	"math"
-	"sort"
 
 	"github.com/RevBooyah/TokEntropyDrift/internal/plugins"
+	"github.com/RevBooyah/TokEntropyDrift/internal/stats"
 )
 
 // TokenLengthAnalyzer is an example plugin that analyzes token length distributions
@@ -45,38 +45,18 @@ func (t *TokenLengthAnalyzer) CalculateMetrics(ctx *plugins.AnalysisContext) ([]
 		lengths[i] = len(token.Text)
 	}
 
-	// Sort lengths for percentile calculations
-	sort.Ints(lengths)
-
 	// Calculate basic statistics
 	totalTokens := len(lengths)
-	sum := 0
-	for _, length := range lengths {
-		sum += length
-	}
-
-	mean := float64(sum) / float64(totalTokens)
-
-	// Calculate variance and standard deviation
-	variance := 0.0
-	for _, length := range lengths {
-		diff := float64(length) - mean
-		variance += diff * diff
-	}
-	variance /= float64(totalTokens)
-	stdDev := math.Sqrt(variance)
+	mean := stats.Mean(lengths)
+	stdDev := stats.Std(lengths)
 
 	// Calculate percentiles
-	p25 := calculatePercentile(lengths, 25)
-	p50 := calculatePercentile(lengths, 50)
-	p75 := calculatePercentile(lengths, 75)
-	p90 := calculatePercentile(lengths, 90)
-	p95 := calculatePercentile(lengths, 95)
-	p99 := calculatePercentile(lengths, 99)
+	percentiles := stats.Percentiles(lengths, 25, 50, 75, 90, 95, 99)
+	p25, p50, p75, p90, p95, p99 := percentiles[0], percentiles[1], percentiles[2], percentiles[3], percentiles[4], percentiles[5]
 
 	// Calculate min and max
-	min := lengths[0]
-	max := lengths[len(lengths)-1]
+	min := stats.Min(lengths)
+	max := stats.Max(lengths)
 
 	// Calculate length distribution
 	lengthCounts := make(map[int]int)
@@ -138,32 +118,32 @@ func (t *TokenLengthAnalyzer) CalculateMetrics(ctx *plugins.AnalysisContext) ([]
 		},
 		{
 			Name:  "median_length",
-			Value: float64(p50),
+			Value: p50,
 			Unit:  "characters",
 		},
 		{
 			Name:  "p25_length",
-			Value: float64(p25),
+			Value: p25,
 			Unit:  "characters",
 		},
 		{
 			Name:  "p75_length",
-			Value: float64(p75),
+			Value: p75,
 			Unit:  "characters",
 		},
 		{
 			Name:  "p90_length",
-			Value: float64(p90),
+			Value: p90,
 			Unit:  "characters",
 		},
 		{
 			Name:  "p95_length",
-			Value: float64(p95),
+			Value: p95,
 			Unit:  "characters",
 		},
 		{
 			Name:  "p99_length",
-			Value: float64(p99),
+			Value: p99,
 			Unit:  "characters",
 		},
 		{
@@ -227,27 +207,6 @@ func (t *TokenLengthAnalyzer) ValidateConfig(config map[string]interface{}) erro
 	return nil
 }
 
-// calculatePercentile calculates the nth percentile of a sorted slice
-func calculatePercentile(sorted []int, percentile int) int {
-	if len(sorted) == 0 {
-		return 0
-	}
-
-	index := float64(percentile) / 100.0 * float64(len(sorted)-1)
-	if index == float64(int(index)) {
-		return sorted[int(index)]
-	}
-
-	lower := int(index)
-	upper := lower + 1
-	if upper >= len(sorted) {
-		return sorted[lower]
-	}
-
-	weight := index - float64(lower)
-	return int(float64(sorted[lower])*(1-weight) + float64(sorted[upper])*weight)
-}
-
 // calculateEntropy calculates the entropy of a distribution
 func calculateEntropy(counts map[int]int, total int) float64 {
 	entropy := 0.0
@@ -0,0 +1,194 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// ManifestSuffix ends the file names of exec plugin manifests in the plugin directory
+const ManifestSuffix = ".plugin.json"
+
+// DefaultExecTimeout bounds one run of an exec plugin's command
+const DefaultExecTimeout = time.Minute
+
+// maxExecOutputBytes bounds the output read from one run of an exec plugin
+const maxExecOutputBytes = 16 << 20
+
+// ExecManifest describes an exec plugin: a program run once per analysis
+type ExecManifest struct {
+	Name        string            `json:"name"`
+	Version     string            `json:"version"`
+	Description string            `json:"description"`
+	Author      string            `json:"author"`
+	Tags        []string          `json:"tags"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Command     string            `json:"command"`           // run in the manifest's directory
+	Timeout     string            `json:"timeout,omitempty"` // e.g. "30s"; DefaultExecTimeout if empty
+}
+
+// ExecPlugin runs an external program as a plugin. The program reads the analysis
+// context as JSON on stdin, with the plugin's configuration merged into "config":
+//
+//	{"document": "...", "tokenization": {...}, "tokenizer_name": "gpt2", "config": {...}}
+//
+// and writes a JSON array of metric results on stdout:
+//
+//	[{"name": "mean_token_length", "value": 3.2, "unit": "characters"}]
+//
+// A non-zero exit status fails the run, with stderr in the error. See
+// examples/plugins/token_stats.py.
+type ExecPlugin struct {
+	*BasePlugin
+	command []string
+	dir     string
+	timeout time.Duration
+}
+
+// NewExecPlugin creates an exec plugin from its manifest. The command runs in dir.
+func NewExecPlugin(manifest ExecManifest, dir string) (*ExecPlugin, error) {
+	if manifest.Name == "" {
+		return nil, fmt.Errorf("plugin manifest has no name")
+	}
+	command, err := tokenizers.SplitCommand(manifest.Command)
+	if err != nil {
+		return nil, err
+	}
+	if len(command) == 0 {
+		return nil, fmt.Errorf("plugin manifest for %s has no command", manifest.Name)
+	}
+
+	timeout := DefaultExecTimeout
+	if manifest.Timeout != "" {
+		if timeout, err = parseTimeout(manifest.Timeout); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ExecPlugin{
+		BasePlugin: NewBasePlugin(PluginInfo{
+			Name:        manifest.Name,
+			Version:     manifest.Version,
+			Description: manifest.Description,
+			Author:      manifest.Author,
+			Tags:        manifest.Tags,
+			Metadata:    manifest.Metadata,
+		}),
+		command: command,
+		dir:     dir,
+		timeout: timeout,
+	}, nil
+}
+
+// LoadExecManifest reads an exec plugin manifest and creates its plugin, running the
+// command in the manifest's directory
+func LoadExecManifest(path string) (*ExecPlugin, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest ExecManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse plugin manifest: %w", err)
+	}
+
+	return NewExecPlugin(manifest, filepath.Dir(path))
+}
+
+// Initialize stores the configuration; a timeout option overrides the manifest's
+func (e *ExecPlugin) Initialize(config map[string]interface{}) error {
+	if err := e.BasePlugin.Initialize(config); err != nil {
+		return err
+	}
+	if value := e.GetConfigString("timeout", ""); value != "" {
+		timeout, err := parseTimeout(value)
+		if err != nil {
+			return err
+		}
+		e.timeout = timeout
+	}
+	return nil
+}
+
+// Timeout returns how long one run of the command may take. The registry uses it as
+// the plugin's time budget.
+func (e *ExecPlugin) Timeout() time.Duration {
+	return e.timeout
+}
+
+// CalculateMetrics runs the command on the analysis context
+func (e *ExecPlugin) CalculateMetrics(ctx *AnalysisContext) ([]MetricResult, error) {
+	// The plugin's own configuration is the base; the analysis may override it
+	config := make(map[string]interface{}, len(e.GetConfig())+len(ctx.Config))
+	for key, value := range e.GetConfig() {
+		config[key] = value
+	}
+	for key, value := range ctx.Config {
+		config[key] = value
+	}
+	request := *ctx
+	request.Config = config
+
+	payload, err := json.Marshal(&request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode analysis context: %w", err)
+	}
+
+	parent := ctx.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	runCtx, cancel := context.WithTimeout(parent, e.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(runCtx, e.command[0], e.command[1:]...)
+	cmd.Dir = e.dir
+	cmd.Stdin = bytes.NewReader(payload)
+	stdout := tokenizers.NewLimitedBuffer(maxExecOutputBytes)
+	cmd.Stdout = stdout
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	// Don't wait on children that inherited the output pipes after a kill
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+	switch {
+	case parent.Err() != nil:
+		return nil, parent.Err()
+	case errors.Is(runCtx.Err(), context.DeadlineExceeded):
+		return nil, fmt.Errorf("plugin command timed out after %s", e.timeout)
+	case stdout.Exceeded():
+		return nil, fmt.Errorf("plugin command output exceeds %d bytes", maxExecOutputBytes)
+	case err != nil:
+		message := strings.TrimSpace(stderr.String())
+		if message != "" {
+			return nil, fmt.Errorf("plugin command failed: %w: %s", err, message)
+		}
+		return nil, fmt.Errorf("plugin command failed: %w", err)
+	}
+
+	var results []MetricResult
+	if err := json.Unmarshal(stdout.Bytes(), &results); err != nil {
+		return nil, fmt.Errorf("failed to parse plugin output: %w", err)
+	}
+	return results, nil
+}
+
+// parseTimeout parses a positive duration
+func parseTimeout(value string) (time.Duration, error) {
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout: %s", value)
+	}
+	return timeout, nil
+}
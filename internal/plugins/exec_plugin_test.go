@@ -0,0 +1,234 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// writeExecPlugin writes a shell script plugin and its manifest to dir
+func writeExecPlugin(t *testing.T, dir, name, script, timeout string) string {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name+".sh"), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+	manifest, err := json.Marshal(ExecManifest{
+		Name:    name,
+		Version: "1.0.0",
+		Command: "sh " + name + ".sh",
+		Timeout: timeout,
+	})
+	if err != nil {
+		t.Fatalf("failed to encode manifest: %v", err)
+	}
+	path := filepath.Join(dir, name+ManifestSuffix)
+	if err := os.WriteFile(path, manifest, 0644); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+	return path
+}
+
+func newAnalysisContext() *AnalysisContext {
+	return &AnalysisContext{
+		Document: "hello world",
+		Tokenization: &tokenizers.TokenizationResult{
+			Tokens: []tokenizers.Token{{Text: "hello", ID: 1}, {Text: "world", ID: 2}},
+		},
+		TokenizerName: "words",
+		Config:        map[string]interface{}{"mode": "analysis"},
+	}
+}
+
+func TestExecPluginCalculateMetrics(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		timeout string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "metrics",
+			script: `echo '[{"name":"score","value":1.5,"unit":"bits"},{"name":"count","value":2}]'`,
+			want:   "score=1.5,count=2",
+		},
+		{
+			name:   "no metrics",
+			script: `cat >/dev/null; echo '[]'`,
+			want:   "",
+		},
+		{
+			name:    "failure",
+			script:  `echo "model not found" >&2; exit 3`,
+			wantErr: "model not found",
+		},
+		{
+			name:    "bad output",
+			script:  `echo 'not json'`,
+			wantErr: "failed to parse plugin output",
+		},
+		{
+			name:    "timeout",
+			script:  `sleep 5`,
+			timeout: "100ms",
+			wantErr: "timed out after 100ms",
+		},
+		{
+			name:    "output limit",
+			script:  `head -c 17000000 /dev/zero`,
+			wantErr: "output exceeds 16777216 bytes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			name := strings.ReplaceAll(tt.name, " ", "_")
+			p, err := LoadExecManifest(writeExecPlugin(t, dir, name, tt.script, tt.timeout))
+			if err != nil {
+				t.Fatalf("LoadExecManifest returned error: %v", err)
+			}
+			if err := p.Initialize(map[string]interface{}{}); err != nil {
+				t.Fatalf("Initialize returned error: %v", err)
+			}
+
+			start := time.Now()
+			results, err := p.CalculateMetrics(newAnalysisContext())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("CalculateMetrics error = %v, want one containing %q", err, tt.wantErr)
+				}
+				if elapsed := time.Since(start); elapsed > 3*time.Second {
+					t.Errorf("CalculateMetrics took %s", elapsed)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CalculateMetrics returned error: %v", err)
+			}
+
+			var got []string
+			for _, result := range results {
+				got = append(got, fmt.Sprintf("%s=%g", result.Name, result.Value))
+			}
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("metrics = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecPluginInput(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.json")
+	p, err := LoadExecManifest(writeExecPlugin(t, dir, "echo", `cat > input.json; echo '[]'`, ""))
+	if err != nil {
+		t.Fatalf("LoadExecManifest returned error: %v", err)
+	}
+	if err := p.Initialize(map[string]interface{}{"mode": "plugin", "threshold": 0.5}); err != nil {
+		t.Fatalf("Initialize returned error: %v", err)
+	}
+	if _, err := p.CalculateMetrics(newAnalysisContext()); err != nil {
+		t.Fatalf("CalculateMetrics returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(input)
+	if err != nil {
+		t.Fatalf("the command did not run in the manifest directory: %v", err)
+	}
+	var request struct {
+		Document      string `json:"document"`
+		TokenizerName string `json:"tokenizer_name"`
+		Tokenization  struct {
+			Tokens []struct {
+				Text string `json:"text"`
+			} `json:"tokens"`
+		} `json:"tokenization"`
+		Config map[string]interface{} `json:"config"`
+	}
+	if err := json.Unmarshal(data, &request); err != nil {
+		t.Fatalf("plugin input is not JSON: %v", err)
+	}
+
+	if request.Document != "hello world" || request.TokenizerName != "words" || len(request.Tokenization.Tokens) != 2 {
+		t.Errorf("plugin input = %s, want the analysis context", data)
+	}
+	// The analysis options override the plugin's configuration
+	if request.Config["mode"] != "analysis" || request.Config["threshold"] != 0.5 {
+		t.Errorf("plugin config = %v, want the merged configuration", request.Config)
+	}
+}
+
+func TestExecPluginConfigTimeout(t *testing.T) {
+	dir := t.TempDir()
+	p, err := LoadExecManifest(writeExecPlugin(t, dir, "slow", `sleep 5`, ""))
+	if err != nil {
+		t.Fatalf("LoadExecManifest returned error: %v", err)
+	}
+	registry := NewRegistry()
+	if err := registry.Register(p); err != nil {
+		t.Fatalf("Register returned error: %v", err)
+	}
+	if err := registry.Configure("slow", map[string]interface{}{"timeout": "soon"}); err == nil {
+		t.Error("Configure accepted an invalid timeout")
+	}
+	if err := registry.Configure("slow", map[string]interface{}{"timeout": "100ms"}); err != nil {
+		t.Fatalf("Configure returned error: %v", err)
+	}
+
+	if _, err := p.CalculateMetrics(newAnalysisContext()); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("CalculateMetrics error = %v, want a timeout", err)
+	}
+}
+
+func TestNewExecPluginInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest ExecManifest
+	}{
+		{"no name", ExecManifest{Command: "true"}},
+		{"no command", ExecManifest{Name: "empty"}},
+		{"unterminated quote", ExecManifest{Name: "quote", Command: `sh "run.sh`}},
+		{"bad timeout", ExecManifest{Name: "slow", Command: "true", Timeout: "-1s"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewExecPlugin(tt.manifest, t.TempDir()); err == nil {
+				t.Error("NewExecPlugin returned no error")
+			}
+		})
+	}
+}
+
+func TestLoadDirectoryExecPlugins(t *testing.T) {
+	dir := t.TempDir()
+	writeExecPlugin(t, dir, "shell", `echo '[{"name":"score","value":2}]'`, "")
+	if err := os.WriteFile(filepath.Join(dir, "broken"+ManifestSuffix), []byte("{"), 0644); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+
+	registry := NewRegistry()
+	loaded, failures := registry.LoadDirectory(dir, nil)
+	if strings.Join(loaded, ",") != "shell" {
+		t.Errorf("loaded %v, want shell", loaded)
+	}
+	if len(failures) != 1 || filepath.Base(failures[0].Path) != "broken"+ManifestSuffix {
+		t.Errorf("failures = %v, want the broken manifest", failures)
+	}
+
+	results, err := registry.ExecuteMetrics(newAnalysisContext())
+	if err != nil {
+		t.Fatalf("ExecuteMetrics returned error: %v", err)
+	}
+	metrics := results["shell"]
+	if len(metrics) != 1 || metrics[0].Name != "score" || metrics[0].Value != 2 || metrics[0].Timestamp.IsZero() {
+		t.Errorf("shell metrics = %+v, want a timestamped score of 2", metrics)
+	}
+}
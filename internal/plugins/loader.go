@@ -0,0 +1,139 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"plugin"
+	"sort"
+	"strings"
+)
+
+// ConstructorSymbol is the symbol a plugin file must export: a function with the
+// signature func() plugins.Plugin
+const ConstructorSymbol = "NewPlugin"
+
+// LoadError reports a plugin file that could not be loaded
+type LoadError struct {
+	Path string
+	Err  error
+}
+
+// Error implements the error interface
+func (e *LoadError) Error() string {
+	return fmt.Sprintf("plugin %s: %v", e.Path, e.Err)
+}
+
+// Unwrap returns the underlying load error
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+// MarshalJSON encodes the error message alongside the file path
+func (e *LoadError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Path  string `json:"path"`
+		Error string `json:"error"`
+	}{
+		Path:  e.Path,
+		Error: e.Err.Error(),
+	})
+}
+
+// openPlugin opens a plugin file and returns its constructor. Tests replace it, since
+// building real plugin files needs the exact toolchain and dependencies of the host.
+var openPlugin = func(path string) (func() Plugin, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	symbol, err := p.Lookup(ConstructorSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	constructor, ok := symbol.(func() Plugin)
+	if !ok {
+		return nil, fmt.Errorf("%s has type %T, want func() plugins.Plugin", ConstructorSymbol, symbol)
+	}
+
+	return constructor, nil
+}
+
+// LoadDirectory loads every .so file in dir as a Go plugin and every *.plugin.json
+// manifest as an exec plugin, registers the plugins and initializes each with its
+// entry in configs, or an empty configuration. A file that fails to load is reported
+// in the returned errors and does not stop the others. It returns the names of the
+// plugins it registered.
+func (r *Registry) LoadDirectory(dir string, configs map[string]map[string]interface{}) ([]string, []*LoadError) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, []*LoadError{{Path: dir, Err: err}}
+	}
+	var paths []string
+	for _, pattern := range []string{"*.so", "*" + ManifestSuffix} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, []*LoadError{{Path: dir, Err: err}}
+		}
+		paths = append(paths, matches...)
+	}
+	sort.Strings(paths)
+
+	var loaded []string
+	var failures []*LoadError
+	for _, path := range paths {
+		name, err := r.loadFile(path, configs)
+		if err != nil {
+			failures = append(failures, &LoadError{Path: path, Err: err})
+			continue
+		}
+		loaded = append(loaded, name)
+	}
+
+	return loaded, failures
+}
+
+// loadFile loads, registers and configures the plugin in one file
+func (r *Registry) loadFile(path string, configs map[string]map[string]interface{}) (string, error) {
+	p, err := openFile(path)
+	if err != nil {
+		return "", err
+	}
+	if err := r.Register(p); err != nil {
+		return "", err
+	}
+
+	name := p.Info().Name
+	config := configs[name]
+	if config == nil {
+		config = make(map[string]interface{})
+	}
+	if err := r.Configure(name, config); err != nil {
+		r.mu.Lock()
+		delete(r.plugins, name)
+		delete(r.configs, name)
+		r.mu.Unlock()
+		return "", err
+	}
+
+	return name, nil
+}
+
+// openFile creates the plugin in a Go plugin file or an exec plugin manifest
+func openFile(path string) (Plugin, error) {
+	if strings.HasSuffix(path, ManifestSuffix) {
+		return LoadExecManifest(path)
+	}
+
+	constructor, err := openPlugin(path)
+	if err != nil {
+		return nil, err
+	}
+	p := constructor()
+	if p == nil {
+		return nil, fmt.Errorf("%s returned nil", ConstructorSymbol)
+	}
+	return p, nil
+}
@@ -0,0 +1,99 @@
+package plugins
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// configRecorder records the configuration it was initialized with
+type configRecorder struct {
+	*BasePlugin
+	initialized bool
+}
+
+func (c *configRecorder) Initialize(config map[string]interface{}) error {
+	c.initialized = true
+	return c.BasePlugin.Initialize(config)
+}
+
+func (c *configRecorder) ValidateConfig(config map[string]interface{}) error {
+	if _, ok := config["invalid"]; ok {
+		return errors.New("invalid option")
+	}
+	return nil
+}
+
+func (c *configRecorder) CalculateMetrics(ctx *AnalysisContext) ([]MetricResult, error) {
+	return nil, nil
+}
+
+// useFakePlugins makes openPlugin build plugins from files named after them, so the
+// loader can be tested without building real plugin files
+func useFakePlugins(t *testing.T, created map[string]*configRecorder) {
+	t.Helper()
+	original := openPlugin
+	openPlugin = func(path string) (func() Plugin, error) {
+		name := strings.TrimSuffix(filepath.Base(path), ".so")
+		if strings.HasPrefix(name, "broken") {
+			return nil, errors.New("plugin was built with a different version of package")
+		}
+		return func() Plugin {
+			p := &configRecorder{BasePlugin: NewBasePlugin(PluginInfo{Name: strings.TrimPrefix(name, "dup_"), Version: "1.0.0"})}
+			created[name] = p
+			return p
+		}, nil
+	}
+	t.Cleanup(func() { openPlugin = original })
+}
+
+func TestLoadDirectory(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"alpha.so", "beta.so", "broken.so", "dup_alpha.so", "invalid.so", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	created := make(map[string]*configRecorder)
+	useFakePlugins(t, created)
+
+	registry := NewRegistry()
+	loaded, failures := registry.LoadDirectory(dir, map[string]map[string]interface{}{
+		"alpha":   {"threshold": 0.5},
+		"invalid": {"invalid": true},
+	})
+
+	if strings.Join(loaded, ",") != "alpha,beta" {
+		t.Errorf("loaded %v, want alpha and beta", loaded)
+	}
+	var failed []string
+	for _, failure := range failures {
+		failed = append(failed, filepath.Base(failure.Path))
+	}
+	if strings.Join(failed, ",") != "broken.so,dup_alpha.so,invalid.so" {
+		t.Errorf("failed files %v, want broken.so, dup_alpha.so and invalid.so", failed)
+	}
+
+	if got := created["alpha"].GetConfigFloat("threshold", 0); got != 0.5 {
+		t.Errorf("alpha threshold = %v, want its configured 0.5", got)
+	}
+	if !created["beta"].initialized {
+		t.Error("beta was not initialized")
+	}
+	if registry.IsRegistered("invalid") {
+		t.Error("a plugin rejecting its configuration stayed registered")
+	}
+	if infos := registry.ListInfo(); len(infos) != 2 || infos[0].Name != "alpha" || infos[1].Name != "beta" {
+		t.Errorf("ListInfo = %+v, want alpha and beta", infos)
+	}
+}
+
+func TestLoadDirectoryMissing(t *testing.T) {
+	registry := NewRegistry()
+	loaded, failures := registry.LoadDirectory(filepath.Join(t.TempDir(), "missing"), nil)
+	if len(loaded) != 0 || len(failures) != 1 || !errors.Is(failures[0], os.ErrNotExist) {
+		t.Errorf("got %v loaded and failures %v, want one not-exist failure", loaded, failures)
+	}
+}
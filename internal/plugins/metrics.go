@@ -0,0 +1,135 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+)
+
+// PluginErrorsKey is the AnalysisResult metadata key under which DocumentHook records
+// the plugins that failed on a document, as a map[string]string of messages by name
+const PluginErrorsKey = "plugin_errors"
+
+// DocumentHook returns a metrics engine hook that runs the registered plugins on each
+// analyzed document, with its real tokenization, and adds their results to the
+// document's metrics. Plugins that fail are recorded under PluginErrorsKey.
+func (r *Registry) DocumentHook() metrics.DocumentHook {
+	return func(ctx context.Context, result *metrics.AnalysisResult) ([]metrics.MetricResult, error) {
+		pluginResults, err := r.ExecuteMetrics(&AnalysisContext{
+			Document:      result.Document,
+			Tokenization:  result.Tokenization,
+			TokenizerName: result.TokenizerName,
+			Config:        make(map[string]interface{}),
+			Context:       ctx,
+		})
+		if failures := FailureMessages(err); failures != nil {
+			if result.Metadata == nil {
+				result.Metadata = make(map[string]interface{})
+			}
+			result.Metadata[PluginErrorsKey] = failures
+			err = nil
+		}
+		return EngineMetrics(pluginResults, result.TokenizerName), err
+	}
+}
+
+// FailureMessages returns the error messages of the failed plugins by name when err is
+// an ExecutionErrors, and nil otherwise
+func FailureMessages(err error) map[string]string {
+	var failures ExecutionErrors
+	if !errors.As(err, &failures) {
+		return nil
+	}
+
+	messages := make(map[string]string, len(failures))
+	for name, failure := range failures {
+		messages[name] = failure.Error()
+	}
+	return messages
+}
+
+// DocumentResults recovers the plugin results DocumentHook added to an analyzed
+// document, by plugin name and in metric name order, along with the plugins that
+// failed on it. It is the inverse of EngineMetrics, except that timestamps are not
+// kept.
+func DocumentResults(result *metrics.AnalysisResult) (map[string][]MetricResult, map[string]string) {
+	names := make([]string, 0, len(result.Metrics))
+	for name := range result.Metrics {
+		if metrics.IsPluginMetric(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	results := make(map[string][]MetricResult)
+	for _, name := range names {
+		metric := result.Metrics[name]
+		plugin, ok := metric.Metadata["plugin"].(string)
+		if !ok {
+			continue
+		}
+		pluginMetric, _ := metric.Metadata["plugin_metric"].(string)
+		if pluginMetric == "" {
+			pluginMetric = strings.TrimPrefix(name, metrics.PluginMetricPrefix+plugin+"_")
+		}
+		unit, _ := metric.Metadata["unit"].(string)
+
+		var metadata map[string]interface{}
+		for key, value := range metric.Metadata {
+			if key == "plugin" || key == "plugin_metric" || key == "unit" {
+				continue
+			}
+			if metadata == nil {
+				metadata = make(map[string]interface{})
+			}
+			metadata[key] = value
+		}
+
+		results[plugin] = append(results[plugin], MetricResult{
+			Name:     pluginMetric,
+			Value:    metric.Value,
+			Unit:     unit,
+			Metadata: metadata,
+		})
+	}
+
+	failures, _ := result.Metadata[PluginErrorsKey].(map[string]string)
+	return results, failures
+}
+
+// EngineMetrics converts plugin results into engine metrics named
+// plugin_{plugin}_{metric}, ordered by plugin name. The plugin, its own name for the
+// metric and the unit are kept in each metric's metadata.
+func EngineMetrics(results map[string][]MetricResult, tokenizerName string) []metrics.MetricResult {
+	names := make([]string, 0, len(results))
+	for name := range results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var converted []metrics.MetricResult
+	for _, name := range names {
+		for _, result := range results[name] {
+			metadata := make(map[string]interface{}, len(result.Metadata)+3)
+			for key, value := range result.Metadata {
+				metadata[key] = value
+			}
+			metadata["plugin"] = name
+			metadata["plugin_metric"] = result.Name
+			if result.Unit != "" {
+				metadata["unit"] = result.Unit
+			}
+
+			converted = append(converted, metrics.MetricResult{
+				MetricName:    metrics.PluginMetricPrefix + name + "_" + result.Name,
+				TokenizerName: tokenizerName,
+				Value:         result.Value,
+				Metadata:      metadata,
+			})
+		}
+	}
+	return converted
+}
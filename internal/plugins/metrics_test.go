@@ -0,0 +1,78 @@
+package plugins
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// tokenCountPlugin reports the number of tokens it was given under a fixed name
+type tokenCountPlugin struct {
+	*BasePlugin
+	metric string
+}
+
+func (p *tokenCountPlugin) CalculateMetrics(ctx *AnalysisContext) ([]MetricResult, error) {
+	if ctx.Tokenization == nil {
+		return nil, nil
+	}
+	return []MetricResult{{
+		Name:     p.metric,
+		Value:    float64(len(ctx.Tokenization.Tokens)),
+		Unit:     "tokens",
+		Metadata: map[string]interface{}{"source": "test"},
+	}}, nil
+}
+
+func TestRegistryDocumentHook(t *testing.T) {
+	registry := NewRegistry()
+	// Both plugins produce plugin_a_b_c, which must not collide
+	for name, metric := range map[string]string{"a": "b_c", "a_b": "c"} {
+		if err := registry.Register(&tokenCountPlugin{BasePlugin: NewBasePlugin(PluginInfo{Name: name}), metric: metric}); err != nil {
+			t.Fatalf("Register returned error: %v", err)
+		}
+	}
+	if err := registry.Register(newBehaviorPlugin("failing", "fail")); err != nil {
+		t.Fatalf("Register returned error: %v", err)
+	}
+
+	tokenizer := tokenizers.NewWhitespaceTokenizer("words")
+	if err := tokenizer.Initialize(tokenizers.TokenizerConfig{Name: "words", Type: "custom"}); err != nil {
+		t.Fatalf("Initialize returned error: %v", err)
+	}
+	engine := metrics.NewEngine(metrics.EngineConfig{EntropyWindowSize: 10})
+	engine.AddDocumentHook(registry.DocumentHook())
+
+	result, err := engine.AnalyzeDocument(context.Background(), "one two three", tokenizer)
+	if err != nil {
+		t.Fatalf("AnalyzeDocument returned error: %v", err)
+	}
+
+	for name, plugin := range map[string]string{"plugin_a_b_c": "a", "plugin_a_b_c_2": "a_b"} {
+		metric, ok := result.Metrics[name]
+		if !ok || metric.Value != 3 || metric.TokenizerName != "words" {
+			t.Errorf("%s = %+v, want 3 tokens from words", name, metric)
+			continue
+		}
+		if metric.Metadata["plugin"] != plugin || metric.Metadata["unit"] != "tokens" || metric.Metadata["source"] != "test" {
+			t.Errorf("%s metadata = %v, want plugin %s with its unit and metadata", name, metric.Metadata, plugin)
+		}
+	}
+	if _, ok := result.Metadata["hook_errors"]; ok {
+		t.Errorf("hook_errors = %v, want plugin failures kept per plugin", result.Metadata["hook_errors"])
+	}
+
+	pluginResults, failures := DocumentResults(result)
+	if got := failures["failing"]; !strings.Contains(got, "bad input") {
+		t.Errorf("failures[failing] = %q, want the plugin's error", got)
+	}
+	for plugin, metric := range map[string]string{"a": "b_c", "a_b": "c"} {
+		got := pluginResults[plugin]
+		if len(got) != 1 || got[0].Name != metric || got[0].Value != 3 || got[0].Unit != "tokens" || got[0].Metadata["source"] != "test" {
+			t.Errorf("results[%s] = %+v, want %s = 3 tokens", plugin, got, metric)
+		}
+	}
+}
@@ -1,23 +1,33 @@
 package plugins
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Registry manages plugin registration and execution
 type Registry struct {
-	plugins map[string]Plugin
-	configs map[string]map[string]interface{}
-	mu      sync.RWMutex
+	plugins        map[string]Plugin
+	configs        map[string]map[string]interface{}
+	maxConcurrency int
+	mu             sync.RWMutex
+
+	// calculations counts each plugin's CalculateMetrics calls still in flight,
+	// including ones runPlugin gave up on after a timeout
+	calculations map[string]*atomic.Int64
 }
 
 // NewRegistry creates a new plugin registry
 func NewRegistry() *Registry {
 	return &Registry{
-		plugins: make(map[string]Plugin),
-		configs: make(map[string]map[string]interface{}),
+		plugins:      make(map[string]Plugin),
+		configs:      make(map[string]map[string]interface{}),
+		calculations: make(map[string]*atomic.Int64),
 	}
 }
 
@@ -36,9 +46,22 @@ func (r *Registry) Register(plugin Plugin) error {
 	}
 
 	r.plugins[info.Name] = plugin
+	if err := r.checkDependencies(); err != nil {
+		delete(r.plugins, info.Name)
+		return err
+	}
+	r.calculations[info.Name] = new(atomic.Int64)
 	return nil
 }
 
+// SetMaxConcurrency sets how many plugins ExecuteMetrics may run at once; plugins still
+// wait for the plugins they depend on. Values below 2 run plugins one at a time.
+func (r *Registry) SetMaxConcurrency(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxConcurrency = n
+}
+
 // Unregister removes a plugin from the registry
 func (r *Registry) Unregister(name string) error {
 	r.mu.Lock()
@@ -56,6 +79,7 @@ func (r *Registry) Unregister(name string) error {
 
 	delete(r.plugins, name)
 	delete(r.configs, name)
+	delete(r.calculations, name)
 	return nil
 }
 
@@ -85,7 +109,7 @@ func (r *Registry) List() []string {
 	return names
 }
 
-// ListInfo returns information about all registered plugins
+// ListInfo returns information about all registered plugins, ordered by name
 func (r *Registry) ListInfo() []PluginInfo {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -94,11 +118,15 @@ func (r *Registry) ListInfo() []PluginInfo {
 	for _, plugin := range r.plugins {
 		infos = append(infos, plugin.Info())
 	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
 
 	return infos
 }
 
-// Configure sets configuration for a plugin
+// Configure sets configuration for a plugin. A configuration that fails validation or
+// initialization is rejected and the plugin keeps its previous one. Configure also
+// refuses while a calculation that timed out is still running, since reinitializing
+// the plugin under it would race with that calculation.
 func (r *Registry) Configure(name string, config map[string]interface{}) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -108,71 +136,273 @@ func (r *Registry) Configure(name string, config map[string]interface{}) error {
 		return fmt.Errorf("plugin %s is not registered", name)
 	}
 
+	// Holding the lock excludes running executions, so any calculation still in
+	// flight was abandoned after a timeout
+	if abandoned := r.calculations[name].Load(); abandoned > 0 {
+		return fmt.Errorf("plugin %s is still running %d timed out calculations", name, abandoned)
+	}
+
 	// Validate configuration
 	if err := plugin.ValidateConfig(config); err != nil {
 		return fmt.Errorf("invalid configuration for plugin %s: %w", name, err)
 	}
+	if value, ok := config["timeout"]; ok {
+		text, isString := value.(string)
+		if !isString {
+			return fmt.Errorf("invalid configuration for plugin %s: invalid timeout option: %v", name, value)
+		}
+		if _, err := parseTimeout(text); err != nil {
+			return fmt.Errorf("invalid configuration for plugin %s: %w", name, err)
+		}
+	}
 
-	// Store configuration
-	r.configs[name] = config
+	previous, configured := r.configs[name]
+	restore := func() {
+		if !configured {
+			previous = make(map[string]interface{})
+		}
+		// The previous configuration was accepted before, so this is not expected to fail
+		plugin.Initialize(previous)
+	}
 
 	// Initialize plugin with new configuration
 	if err := plugin.Initialize(config); err != nil {
+		restore()
 		return fmt.Errorf("error initializing plugin %s: %w", name, err)
 	}
 
+	// Initializing may have changed the plugin's declared dependencies
+	if err := r.checkDependencies(); err != nil {
+		restore()
+		return fmt.Errorf("invalid configuration for plugin %s: %w", name, err)
+	}
+
+	r.configs[name] = config
 	return nil
 }
 
-// ExecuteMetrics runs metric calculations for all plugins
+// Reconfigure replaces the configuration of a plugin while analyses may be running. It
+// waits for running executions to finish and holds new ones back until the plugin is
+// reinitialized, so every execution sees either the old or the new configuration. Like
+// Configure, it fails while a calculation abandoned after a timeout is still running;
+// the caller may retry once it ends. The registry keeps its own copy of config.
+func (r *Registry) Reconfigure(name string, config map[string]interface{}) error {
+	copied := make(map[string]interface{}, len(config))
+	for key, value := range config {
+		copied[key] = value
+	}
+	return r.Configure(name, copied)
+}
+
+// Config returns a copy of the configuration a plugin was last configured with
+func (r *Registry) Config(name string) (map[string]interface{}, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, exists := r.plugins[name]; !exists {
+		return nil, fmt.Errorf("plugin %s is not registered", name)
+	}
+
+	config := make(map[string]interface{}, len(r.configs[name]))
+	for key, value := range r.configs[name] {
+		config[key] = value
+	}
+	return config, nil
+}
+
+// DefaultPluginTimeout bounds one plugin's metric calculation unless its configuration
+// sets a "timeout" option
+const DefaultPluginTimeout = 30 * time.Second
+
+// timeoutPlugin is implemented by plugins that set their own default time budget
+type timeoutPlugin interface {
+	Timeout() time.Duration
+}
+
+// ExecutionErrors maps the names of plugins that failed during ExecuteMetrics to their
+// errors
+type ExecutionErrors map[string]error
+
+// Error implements the error interface, listing the failed plugins by name
+func (e ExecutionErrors) Error() string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	messages := make([]string, len(names))
+	for i, name := range names {
+		messages[i] = fmt.Sprintf("%s: %v", name, e[name])
+	}
+	return fmt.Sprintf("%d plugins failed: %s", len(e), strings.Join(messages, "; "))
+}
+
+// ExecuteMetrics runs metric calculations for all plugins. Each plugin runs in
+// isolation: a plugin that fails, panics or exceeds its timeout is left out of the
+// results and reported in the returned ExecutionErrors, and the others still run.
+// A plugin runs after the plugins it depends on and receives their results under
+// DependencyResultsKey in its Config; if one of them fails, so does the plugin.
+// Independent plugins run in parallel up to the registry's maximum concurrency.
 func (r *Registry) ExecuteMetrics(ctx *AnalysisContext) (map[string][]MetricResult, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	results := make(map[string][]MetricResult)
+	order, cyclic := r.dependencyOrder()
 
-	for name, plugin := range r.plugins {
-		metrics, err := plugin.CalculateMetrics(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("error executing plugin %s: %w", name, err)
-		}
+	// Each run's outcome is written before done is closed
+	type pluginRun struct {
+		done    chan struct{}
+		metrics []MetricResult
+		err     error
+	}
+	runs := make(map[string]*pluginRun, len(order))
+	for _, name := range order {
+		runs[name] = &pluginRun{done: make(chan struct{})}
+	}
 
-		// Add timestamp to metrics if not present
-		for i := range metrics {
-			if metrics[i].Timestamp.IsZero() {
-				metrics[i].Timestamp = time.Now()
+	slots := make(chan struct{}, max(r.maxConcurrency, 1))
+	var wg sync.WaitGroup
+	for _, name := range order {
+		wg.Add(1)
+		go func(name string, run *pluginRun) {
+			defer wg.Done()
+			defer close(run.done)
+
+			// Wait for dependencies before taking a slot, so waiting plugins never
+			// hold up the ones they wait for
+			dependencyResults := make(map[string][]MetricResult)
+			for _, dependency := range r.plugins[name].Info().Dependencies() {
+				dependencyRun, exists := runs[dependency]
+				if !exists {
+					if _, registered := r.plugins[dependency]; !registered {
+						run.err = fmt.Errorf("dependency %s is not registered", dependency)
+					} else {
+						run.err = fmt.Errorf("dependency %s is in or depends on a dependency cycle", dependency)
+					}
+					return
+				}
+				<-dependencyRun.done
+				if dependencyRun.err != nil {
+					run.err = fmt.Errorf("dependency %s failed", dependency)
+					return
+				}
+				dependencyResults[dependency] = dependencyRun.metrics
 			}
-		}
 
-		results[name] = metrics
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			run.metrics, run.err = r.runPlugin(name, withDependencyResults(ctx, dependencyResults))
+		}(name, runs[name])
+	}
+	wg.Wait()
+
+	results := make(map[string][]MetricResult)
+	failures := make(ExecutionErrors)
+	for _, name := range cyclic {
+		failures[name] = fmt.Errorf("plugin is in or depends on a dependency cycle")
+	}
+	for name, run := range runs {
+		if run.err != nil {
+			failures[name] = run.err
+			continue
+		}
+		results[name] = run.metrics
 	}
 
+	if len(failures) > 0 {
+		return results, failures
+	}
 	return results, nil
 }
 
-// ExecuteMetricsForPlugin runs metric calculations for a specific plugin
+// ExecuteMetricsForPlugin runs metric calculations for a specific plugin, with the same
+// isolation as ExecuteMetrics
 func (r *Registry) ExecuteMetricsForPlugin(name string, ctx *AnalysisContext) ([]MetricResult, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	plugin, exists := r.plugins[name]
-	if !exists {
+	if _, exists := r.plugins[name]; !exists {
 		return nil, fmt.Errorf("plugin %s is not registered", name)
 	}
 
-	metrics, err := plugin.CalculateMetrics(ctx)
+	metrics, err := r.runPlugin(name, ctx)
 	if err != nil {
 		return nil, fmt.Errorf("error executing plugin %s: %w", name, err)
 	}
+	return metrics, nil
+}
+
+// runPlugin calculates one plugin's metrics in its own goroutine, turning a panic into
+// an error and giving up once the plugin's timeout passes. The plugin sees the timeout
+// as the deadline of ctx.Context; a plugin that ignores it keeps running in the
+// background after runPlugin returns, and is counted in r.calculations until it ends.
+func (r *Registry) runPlugin(name string, ctx *AnalysisContext) ([]MetricResult, error) {
+	plugin := r.plugins[name]
+	timeout := DefaultPluginTimeout
+	if timed, ok := plugin.(timeoutPlugin); ok {
+		timeout = timed.Timeout()
+	}
+	if value, ok := r.configs[name]["timeout"].(string); ok {
+		// Configure has already rejected invalid timeouts
+		if configured, err := parseTimeout(value); err == nil {
+			timeout = configured
+		}
+	}
+
+	parent := ctx.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	runCtx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	pluginCtx := *ctx
+	pluginCtx.Context = runCtx
+
+	type outcome struct {
+		metrics []MetricResult
+		err     error
+	}
+	done := make(chan outcome, 1)
+
+	// The count drops before the outcome is sent, so a run that finished in time is
+	// never counted once runPlugin returns
+	calculations := r.calculations[name]
+	calculations.Add(1)
+	go func() {
+		var result outcome
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				result = outcome{err: fmt.Errorf("plugin panicked: %v", recovered)}
+			}
+			calculations.Add(-1)
+			done <- result
+		}()
+		result.metrics, result.err = plugin.CalculateMetrics(&pluginCtx)
+	}()
+
+	var result outcome
+	select {
+	case result = <-done:
+	case <-runCtx.Done():
+		if parent.Err() != nil {
+			return nil, parent.Err()
+		}
+		return nil, fmt.Errorf("plugin timed out after %s", timeout)
+	}
+	if result.err != nil {
+		return nil, result.err
+	}
 
 	// Add timestamp to metrics if not present
-	for i := range metrics {
-		if metrics[i].Timestamp.IsZero() {
-			metrics[i].Timestamp = time.Now()
+	for i := range result.metrics {
+		if result.metrics[i].Timestamp.IsZero() {
+			result.metrics[i].Timestamp = time.Now()
 		}
 	}
 
-	return metrics, nil
+	return result.metrics, nil
 }
 
 // Close cleans up all plugins
@@ -0,0 +1,429 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// behaviorPlugin returns a fixed metric, fails, panics or sleeps until its context ends
+type behaviorPlugin struct {
+	*BasePlugin
+	behavior string
+}
+
+func newBehaviorPlugin(name, behavior string) *behaviorPlugin {
+	return &behaviorPlugin{BasePlugin: NewBasePlugin(PluginInfo{Name: name}), behavior: behavior}
+}
+
+func (b *behaviorPlugin) CalculateMetrics(ctx *AnalysisContext) ([]MetricResult, error) {
+	switch b.behavior {
+	case "fail":
+		return nil, errors.New("bad input")
+	case "panic":
+		var counts map[string]int
+		counts["token"]++
+	case "sleep":
+		<-ctx.Context.Done()
+		return nil, ctx.Context.Err()
+	case "hang":
+		time.Sleep(5 * time.Second)
+	case "empty":
+		return nil, nil
+	}
+	return []MetricResult{{Name: b.behavior + "_metric", Value: 1}}, nil
+}
+
+func TestRegistryExecuteMetricsIsolation(t *testing.T) {
+	registry := NewRegistry()
+	for name, behavior := range map[string]string{
+		"good":     "ok",
+		"empty":    "empty",
+		"failing":  "fail",
+		"panicky":  "panic",
+		"sleepy":   "sleep",
+		"hanging":  "hang",
+		"patient":  "ok",
+		"unbudget": "ok",
+	} {
+		if err := registry.Register(newBehaviorPlugin(name, behavior)); err != nil {
+			t.Fatalf("Register returned error: %v", err)
+		}
+	}
+	for _, name := range []string{"sleepy", "hanging"} {
+		if err := registry.Configure(name, map[string]interface{}{"timeout": "50ms"}); err != nil {
+			t.Fatalf("Configure returned error: %v", err)
+		}
+	}
+
+	start := time.Now()
+	results, err := registry.ExecuteMetrics(&AnalysisContext{Document: "text"})
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("ExecuteMetrics took %s, want the hanging plugin abandoned", elapsed)
+	}
+
+	var failures ExecutionErrors
+	if !errors.As(err, &failures) {
+		t.Fatalf("ExecuteMetrics error = %v, want ExecutionErrors", err)
+	}
+	wantErrors := map[string]string{
+		"failing": "bad input",
+		"panicky": "plugin panicked",
+		"sleepy":  "timed out after 50ms",
+		"hanging": "timed out after 50ms",
+	}
+	if len(failures) != len(wantErrors) {
+		t.Errorf("failures = %v, want %d failed plugins", failures, len(wantErrors))
+	}
+	for name, want := range wantErrors {
+		if got := failures[name]; got == nil || !strings.Contains(got.Error(), want) {
+			t.Errorf("failures[%s] = %v, want one containing %q", name, got, want)
+		}
+		if _, ok := results[name]; ok {
+			t.Errorf("results include failed plugin %s", name)
+		}
+	}
+
+	for _, name := range []string{"good", "patient", "unbudget"} {
+		if metrics := results[name]; len(metrics) != 1 || metrics[0].Timestamp.IsZero() {
+			t.Errorf("results[%s] = %+v, want one timestamped metric", name, metrics)
+		}
+	}
+	// A plugin with no metrics is present, unlike a failed one
+	if metrics, ok := results["empty"]; !ok || len(metrics) != 0 {
+		t.Errorf("results[empty] = %v, %v, want present and empty", metrics, ok)
+	}
+}
+
+func TestRegistryExecuteMetricsCancelled(t *testing.T) {
+	registry := NewRegistry()
+	if err := registry.Register(newBehaviorPlugin("sleepy", "sleep")); err != nil {
+		t.Fatalf("Register returned error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := registry.ExecuteMetrics(&AnalysisContext{Context: ctx})
+
+	var failures ExecutionErrors
+	if !errors.As(err, &failures) || !errors.Is(failures["sleepy"], context.Canceled) {
+		t.Errorf("ExecuteMetrics error = %v, want sleepy cancelled", err)
+	}
+}
+
+func TestRegistryExecuteMetricsForPlugin(t *testing.T) {
+	registry := NewRegistry()
+	if err := registry.Register(newBehaviorPlugin("panicky", "panic")); err != nil {
+		t.Fatalf("Register returned error: %v", err)
+	}
+
+	if _, err := registry.ExecuteMetricsForPlugin("panicky", &AnalysisContext{}); err == nil || !strings.Contains(err.Error(), "plugin panicked") {
+		t.Errorf("ExecuteMetricsForPlugin error = %v, want the panic", err)
+	}
+	if _, err := registry.ExecuteMetricsForPlugin("missing", &AnalysisContext{}); err == nil {
+		t.Error("ExecuteMetricsForPlugin ran a plugin that is not registered")
+	}
+}
+
+func TestRegistryConfigureTimeout(t *testing.T) {
+	registry := NewRegistry()
+	if err := registry.Register(newBehaviorPlugin("good", "ok")); err != nil {
+		t.Fatalf("Register returned error: %v", err)
+	}
+
+	for _, timeout := range []interface{}{"soon", "-1s", 30} {
+		if err := registry.Configure("good", map[string]interface{}{"timeout": timeout}); err == nil {
+			t.Errorf("Configure accepted timeout %v", timeout)
+		}
+	}
+}
+
+// chainPlugin reports one more than the sum of its dependencies' first metrics, and
+// tracks how many plugins run at once
+type chainPlugin struct {
+	*BasePlugin
+	running *atomic.Int64
+	peak    *atomic.Int64
+}
+
+func newChainPlugin(name, dependsOn string, running, peak *atomic.Int64) *chainPlugin {
+	info := PluginInfo{Name: name}
+	if dependsOn != "" {
+		info.Metadata = map[string]string{DependsOnKey: dependsOn}
+	}
+	return &chainPlugin{BasePlugin: NewBasePlugin(info), running: running, peak: peak}
+}
+
+func (c *chainPlugin) CalculateMetrics(ctx *AnalysisContext) ([]MetricResult, error) {
+	n := c.running.Add(1)
+	defer c.running.Add(-1)
+	for {
+		peak := c.peak.Load()
+		if n <= peak || c.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	value := 1.0
+	if results, ok := ctx.Config[DependencyResultsKey].(map[string][]MetricResult); ok {
+		for _, metrics := range results {
+			value += metrics[0].Value
+		}
+	}
+	return []MetricResult{{Name: "depth", Value: value}}, nil
+}
+
+func TestRegistryExecuteMetricsDependencies(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxConcurrency int
+		minPeak        int64
+		maxPeak        int64
+	}{
+		{"sequential", 0, 1, 1},
+		{"parallel", 3, 2, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			running, peak := &atomic.Int64{}, &atomic.Int64{}
+			registry := NewRegistry()
+			registry.SetMaxConcurrency(tt.maxConcurrency)
+			// d depends on b and c, which depend on a; e, f and g are independent
+			for _, plugin := range []*chainPlugin{
+				newChainPlugin("d", "b, c", running, peak),
+				newChainPlugin("b", "a", running, peak),
+				newChainPlugin("c", "a", running, peak),
+				newChainPlugin("a", "", running, peak),
+				newChainPlugin("e", "", running, peak),
+				newChainPlugin("f", "", running, peak),
+				newChainPlugin("g", "", running, peak),
+			} {
+				if err := registry.Register(plugin); err != nil {
+					t.Fatalf("Register returned error: %v", err)
+				}
+			}
+
+			results, err := registry.ExecuteMetrics(&AnalysisContext{Config: map[string]interface{}{}})
+			if err != nil {
+				t.Fatalf("ExecuteMetrics returned error: %v", err)
+			}
+			want := map[string]float64{"a": 1, "b": 2, "c": 2, "d": 5, "e": 1, "f": 1, "g": 1}
+			for name, value := range want {
+				if metrics := results[name]; len(metrics) != 1 || metrics[0].Value != value {
+					t.Errorf("results[%s] = %+v, want depth %v", name, metrics, value)
+				}
+			}
+			if got := peak.Load(); got < tt.minPeak || got > tt.maxPeak {
+				t.Errorf("%d plugins ran at once, want %d to %d", got, tt.minPeak, tt.maxPeak)
+			}
+		})
+	}
+}
+
+func TestRegistryExecuteMetricsFailedDependency(t *testing.T) {
+	running, peak := &atomic.Int64{}, &atomic.Int64{}
+	registry := NewRegistry()
+	for _, plugin := range []Plugin{
+		newBehaviorPlugin("failing", "fail"),
+		newChainPlugin("dependent", "failing", running, peak),
+		newChainPlugin("orphan", "missing", running, peak),
+	} {
+		if err := registry.Register(plugin); err != nil {
+			t.Fatalf("Register returned error: %v", err)
+		}
+	}
+
+	_, err := registry.ExecuteMetrics(&AnalysisContext{})
+	var failures ExecutionErrors
+	if !errors.As(err, &failures) {
+		t.Fatalf("ExecuteMetrics error = %v, want ExecutionErrors", err)
+	}
+	for name, want := range map[string]string{
+		"failing":   "bad input",
+		"dependent": "dependency failing failed",
+		"orphan":    "dependency missing is not registered",
+	} {
+		if got := failures[name]; got == nil || !strings.Contains(got.Error(), want) {
+			t.Errorf("failures[%s] = %v, want one containing %q", name, got, want)
+		}
+	}
+}
+
+func TestRegistryDependencyCycle(t *testing.T) {
+	running, peak := &atomic.Int64{}, &atomic.Int64{}
+	registry := NewRegistry()
+	for _, plugin := range []*chainPlugin{
+		newChainPlugin("a", "c", running, peak),
+		newChainPlugin("b", "a", running, peak),
+	} {
+		if err := registry.Register(plugin); err != nil {
+			t.Fatalf("Register returned error: %v", err)
+		}
+	}
+
+	err := registry.Register(newChainPlugin("c", "b", running, peak))
+	if err == nil || !strings.Contains(err.Error(), "a -> c -> b -> a") {
+		t.Errorf("Register error = %v, want the cycle a -> c -> b -> a", err)
+	}
+	if registry.IsRegistered("c") {
+		t.Error("the plugin closing the cycle should not stay registered")
+	}
+
+	if err := registry.Register(newChainPlugin("self", "self", running, peak)); err == nil {
+		t.Error("Register accepted a plugin depending on itself")
+	}
+}
+
+// thresholdPlugin reports its "low" and "high" options, rejects low above high and
+// fails to initialize when "broken" is set
+type thresholdPlugin struct {
+	*BasePlugin
+}
+
+func (p *thresholdPlugin) ValidateConfig(config map[string]interface{}) error {
+	low, _ := config["low"].(float64)
+	high, _ := config["high"].(float64)
+	if low > high {
+		return errors.New("low must not exceed high")
+	}
+	return nil
+}
+
+func (p *thresholdPlugin) Initialize(config map[string]interface{}) error {
+	if config["broken"] == true {
+		return errors.New("cannot initialize")
+	}
+	return p.BasePlugin.Initialize(config)
+}
+
+func (p *thresholdPlugin) CalculateMetrics(ctx *AnalysisContext) ([]MetricResult, error) {
+	return []MetricResult{
+		{Name: "low", Value: p.GetConfigFloat("low", 0)},
+		{Name: "high", Value: p.GetConfigFloat("high", 0)},
+	}, nil
+}
+
+func TestRegistryReconfigure(t *testing.T) {
+	registry := NewRegistry()
+	if err := registry.Register(&thresholdPlugin{BasePlugin: NewBasePlugin(PluginInfo{Name: "threshold"})}); err != nil {
+		t.Fatalf("Register returned error: %v", err)
+	}
+	config := map[string]interface{}{"low": 1.0, "high": 2.0}
+	if err := registry.Reconfigure("threshold", config); err != nil {
+		t.Fatalf("Reconfigure returned error: %v", err)
+	}
+	config["low"] = 5.0 // the registry keeps its own copy
+
+	for name, bad := range map[string]map[string]interface{}{
+		"invalid":     {"low": 3.0, "high": 2.0},
+		"broken":      {"low": 1.0, "high": 4.0, "broken": true},
+		"bad timeout": {"timeout": "soon"},
+	} {
+		if err := registry.Reconfigure("threshold", bad); err == nil {
+			t.Errorf("Reconfigure accepted the %s configuration", name)
+		}
+	}
+	if err := registry.Reconfigure("missing", config); err == nil {
+		t.Error("Reconfigure configured a plugin that is not registered")
+	}
+
+	got, err := registry.Config("threshold")
+	if err != nil || got["low"] != 1.0 || got["high"] != 2.0 || len(got) != 2 {
+		t.Errorf("Config = %v, %v, want the last accepted configuration", got, err)
+	}
+	results, err := registry.ExecuteMetrics(&AnalysisContext{})
+	if err != nil {
+		t.Fatalf("ExecuteMetrics returned error: %v", err)
+	}
+	if metrics := results["threshold"]; metrics[0].Value != 1 || metrics[1].Value != 2 {
+		t.Errorf("results = %+v, want the plugin still initialized with low 1 and high 2", metrics)
+	}
+}
+
+func TestRegistryReconfigureDuringExecution(t *testing.T) {
+	registry := NewRegistry()
+	if err := registry.Register(&thresholdPlugin{BasePlugin: NewBasePlugin(PluginInfo{Name: "threshold"})}); err != nil {
+		t.Fatalf("Register returned error: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			value := float64(i)
+			if err := registry.Reconfigure("threshold", map[string]interface{}{"low": value, "high": value}); err != nil {
+				t.Errorf("Reconfigure returned error: %v", err)
+				return
+			}
+		}
+	}()
+
+	// Every run must see low and high from the same configuration
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		results, err := registry.ExecuteMetrics(&AnalysisContext{})
+		if err != nil {
+			t.Fatalf("ExecuteMetrics returned error: %v", err)
+		}
+		if metrics := results["threshold"]; metrics[0].Value != metrics[1].Value {
+			t.Fatalf("run saw low %v and high %v, want one configuration", metrics[0].Value, metrics[1].Value)
+		}
+	}
+}
+
+// stuckPlugin ignores its context and returns only once release is closed
+type stuckPlugin struct {
+	*BasePlugin
+	release chan struct{}
+}
+
+func (p *stuckPlugin) CalculateMetrics(ctx *AnalysisContext) ([]MetricResult, error) {
+	<-p.release
+	return nil, nil
+}
+
+func TestRegistryConfigureAfterAbandonedRun(t *testing.T) {
+	registry := NewRegistry()
+	plugin := &stuckPlugin{BasePlugin: NewBasePlugin(PluginInfo{Name: "stuck"}), release: make(chan struct{})}
+	if err := registry.Register(plugin); err != nil {
+		t.Fatalf("Register returned error: %v", err)
+	}
+	if err := registry.Configure("stuck", map[string]interface{}{"timeout": "50ms"}); err != nil {
+		t.Fatalf("Configure returned error: %v", err)
+	}
+
+	if _, err := registry.ExecuteMetricsForPlugin("stuck", &AnalysisContext{}); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("ExecuteMetricsForPlugin error = %v, want a timeout", err)
+	}
+
+	// The abandoned calculation is still running, so the plugin must not be reinitialized
+	config := map[string]interface{}{"timeout": "1s"}
+	if err := registry.Reconfigure("stuck", config); err == nil || !strings.Contains(err.Error(), "still running 1 timed out") {
+		t.Errorf("Reconfigure error = %v, want a refusal while the calculation runs", err)
+	}
+	if got, _ := registry.Config("stuck"); got["timeout"] != "50ms" {
+		t.Errorf("Config = %v, want the previous configuration kept", got)
+	}
+
+	close(plugin.release)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		err := registry.Reconfigure("stuck", config)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Reconfigure still failing after the calculation ended: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
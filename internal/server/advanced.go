@@ -0,0 +1,144 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/advanced"
+	"github.com/RevBooyah/TokEntropyDrift/internal/cache"
+	"github.com/RevBooyah/TokEntropyDrift/internal/logger"
+)
+
+// AdvancedAnalysisRequest is an analysis request for the advanced pipeline. The
+// strategy is chosen from the number of documents the upload splits into, unless the
+// request forces one.
+type AdvancedAnalysisRequest struct {
+	AnalysisRequest
+	ForceStreaming bool `json:"force_streaming"`
+	ForceParallel  bool `json:"force_parallel"`
+}
+
+// AdvancedAnalysisResponse holds the advanced analysis of a document by each tokenizer
+type AdvancedAnalysisResponse struct {
+	ID         string                                      `json:"id"`
+	RequestID  string                                      `json:"request_id"` // progress is sent to /api/v1/ws?request_id=
+	DocumentID string                                      `json:"document_id"`
+	Documents  int                                         `json:"documents"` // documents the upload was split into
+	Results    map[string]*advanced.AdvancedAnalysisResult `json:"results"`   // tokenizer ID -> analysis
+	Errors     map[string]string                           `json:"errors,omitempty"`
+	CacheStats *cache.CacheStats                           `json:"cache_stats,omitempty"` // of all tokenizers, if caching is enabled
+	Timestamp  time.Time                                   `json:"timestamp"`
+}
+
+// strategy returns the strategy the request forces, or "" to let the manager choose
+func (req AdvancedAnalysisRequest) strategy() (string, error) {
+	switch {
+	case req.ForceStreaming && req.ForceParallel:
+		return "", errors.New("force_streaming and force_parallel cannot both be set")
+	case req.ForceStreaming:
+		return advanced.StrategyStreaming, nil
+	case req.ForceParallel:
+		return advanced.StrategyParallel, nil
+	default:
+		return "", nil
+	}
+}
+
+// handleAnalyzeAdvanced analyzes every document of an upload with the cache, parallel,
+// streaming and plugin features configured. Progress is published to WebSocket clients
+// following the request's ID.
+func (s *Server) handleAnalyzeAdvanced(w http.ResponseWriter, r *http.Request) {
+	st := s.acquire()
+	defer st.release()
+
+	var req AdvancedAnalysisRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	strategy, err := req.strategy()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	requestLog := logger.FromContext(ctx)
+	requestID := w.Header().Get(requestIDHeader)
+	requestLog.LogAnalysisStart(req.DocumentID, req.TokenizerIDs)
+
+	manager, err := st.advancedManager(s.pluginRegistry)
+	if err != nil {
+		requestLog.LogError("advanced_manager", err, nil)
+		http.Error(w, "Advanced analysis unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	documents, err := s.loadDocumentByID(st, req.DocumentID)
+	if err != nil {
+		requestLog.LogError("document_load", err, map[string]interface{}{"document_id": req.DocumentID})
+		writeDocumentError(w, req.DocumentID, err)
+		return
+	}
+	texts := make([]string, len(documents))
+	for i, document := range documents {
+		texts[i] = document.Content
+	}
+
+	response := AdvancedAnalysisResponse{
+		ID:         fmt.Sprintf("analysis_%d", time.Now().Unix()),
+		RequestID:  requestID,
+		DocumentID: req.DocumentID,
+		Documents:  len(documents),
+		Results:    make(map[string]*advanced.AdvancedAnalysisResult),
+	}
+	failures := make(map[string]string)
+
+	for _, tokenizerID := range req.TokenizerIDs {
+		tokenizer, err := s.createTokenizer(st, tokenizerID)
+		if err != nil {
+			requestLog.LogError("tokenizer_create", err, map[string]interface{}{"tokenizer_name": tokenizerID})
+			failures[tokenizerID] = err.Error()
+			continue
+		}
+		// Registered once per state, so cache statistics accumulate across requests
+		if _, err := manager.GetTokenizer(tokenizerID); err != nil {
+			manager.RegisterTokenizer(tokenizerID, tokenizer)
+		}
+
+		result, err := manager.AnalyzeWithStrategy(ctx, texts, tokenizerID, strategy, s.progress.progressFor(requestID, tokenizerID))
+		if errors.Is(err, advanced.ErrStrategyDisabled) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			requestLog.LogError("analysis", err, map[string]interface{}{"tokenizer_name": tokenizerID, "document_id": req.DocumentID})
+			failures[tokenizerID] = err.Error()
+			continue
+		}
+		s.progress.complete(requestID, tokenizerID, result)
+
+		// The server's configuration is not the client's to see
+		result.Config = nil
+		response.Results[tokenizerID] = result
+	}
+
+	requestLog.LogAnalysisComplete(map[string]interface{}{
+		"document_id": req.DocumentID,
+		"documents":   len(documents),
+		"results":     len(response.Results),
+		"failures":    len(failures),
+	})
+
+	response.CacheStats = manager.GetCacheStats()
+	response.Timestamp = time.Now()
+	if len(failures) > 0 {
+		response.Errors = failures
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
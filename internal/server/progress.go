@@ -0,0 +1,139 @@
+package server
+
+import (
+	"sync"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/advanced"
+)
+
+// progressBuffer is how many events a WebSocket client can fall behind by before
+// further events are dropped for it
+const progressBuffer = 64
+
+// ProgressEvent reports the progress of an advanced analysis to WebSocket clients.
+// Parallel runs count documents out of Total; streaming runs count chunks, with the
+// lines read so far and a Total of -1. The last event of each tokenizer's run has
+// Type "complete".
+type ProgressEvent struct {
+	Type      string `json:"type"` // "progress" or "complete"
+	RequestID string `json:"request_id"`
+	Tokenizer string `json:"tokenizer"`
+	Strategy  string `json:"strategy"`
+	Processed int    `json:"processed"`
+	Failed    int    `json:"failed"`
+	Total     int    `json:"total"`
+	Lines     int    `json:"lines,omitempty"`
+	ElapsedMS int64  `json:"elapsed_ms"`
+}
+
+// newProgressEvent converts an analysis's progress report to an event
+func newProgressEvent(requestID, tokenizer string, progress advanced.Progress) ProgressEvent {
+	return ProgressEvent{
+		Type:      "progress",
+		RequestID: requestID,
+		Tokenizer: tokenizer,
+		Strategy:  progress.Strategy,
+		Processed: progress.Processed,
+		Failed:    progress.Failed,
+		Total:     progress.Total,
+		Lines:     progress.Lines,
+		ElapsedMS: progress.Elapsed.Milliseconds(),
+	}
+}
+
+// progressHub fans analysis progress out to the WebSocket clients following it.
+// Publishing never blocks: a client too slow to keep up misses events rather than
+// stalling the analysis.
+type progressHub struct {
+	mu          sync.Mutex
+	subscribers map[*progressSubscriber]struct{}
+	closed      bool
+}
+
+// progressSubscriber receives the events of one request, or of all requests when
+// requestID is empty. events is closed when the hub shuts down.
+type progressSubscriber struct {
+	requestID string
+	events    chan ProgressEvent
+}
+
+// newProgressHub creates a hub with no subscribers
+func newProgressHub() *progressHub {
+	return &progressHub{subscribers: make(map[*progressSubscriber]struct{})}
+}
+
+// subscribe follows the events of requestID, or of every request if it is empty
+func (h *progressHub) subscribe(requestID string) *progressSubscriber {
+	sub := &progressSubscriber{requestID: requestID, events: make(chan ProgressEvent, progressBuffer)}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(sub.events)
+		return sub
+	}
+	h.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// unsubscribe stops sending events to sub
+func (h *progressHub) unsubscribe(sub *progressSubscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// publish sends event to the subscribers following its request
+func (h *progressHub) publish(event ProgressEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subscribers {
+		if sub.requestID != "" && sub.requestID != event.RequestID {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+		}
+	}
+}
+
+// close ends every subscription, so the WebSocket connections, which the HTTP
+// server's shutdown does not track, close too
+func (h *progressHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for sub := range h.subscribers {
+		delete(h.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// progressFor returns the progress callback of one tokenizer's run in a request
+func (h *progressHub) progressFor(requestID, tokenizer string) func(advanced.Progress) {
+	return func(progress advanced.Progress) {
+		h.publish(newProgressEvent(requestID, tokenizer, progress))
+	}
+}
+
+// complete publishes the final event of one tokenizer's run
+func (h *progressHub) complete(requestID, tokenizer string, result *advanced.AdvancedAnalysisResult) {
+	event := ProgressEvent{
+		Type:      "complete",
+		RequestID: requestID,
+		Tokenizer: tokenizer,
+		Strategy:  result.Strategy,
+		Processed: len(result.Results),
+		Failed:    len(result.Errors),
+		Total:     len(result.Results) + len(result.Errors) + result.Skipped,
+		ElapsedMS: result.Duration.Milliseconds(),
+	}
+	if stream := result.StreamingStats; stream != nil {
+		event.Processed, event.Failed, event.Total = stream.ProcessedChunks, stream.FailedChunks, stream.TotalChunks
+		event.Lines = stream.TotalLines
+	}
+	h.publish(event)
+}
@@ -0,0 +1,354 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/advanced"
+	"github.com/RevBooyah/TokEntropyDrift/internal/cache"
+	"github.com/RevBooyah/TokEntropyDrift/internal/config"
+	"github.com/RevBooyah/TokEntropyDrift/internal/logger"
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+	"github.com/RevBooyah/TokEntropyDrift/internal/plugins"
+	"github.com/RevBooyah/TokEntropyDrift/internal/streaming"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+	"github.com/RevBooyah/TokEntropyDrift/internal/visualization"
+	"github.com/sirupsen/logrus"
+)
+
+// restartSections are the config sections a reload cannot change: the listening
+// address, the directories the routes serve, the loaded plugins and the logger
+var restartSections = []string{"server", "output", "plugins", "logging"}
+
+// serverState is the configuration requests run with and what is built from it. A
+// reload swaps in a new state; requests already running keep the one they acquired.
+type serverState struct {
+	config        *config.Config
+	metricsEngine *metrics.Engine
+	vizEngine     *visualization.VisualizationEngine
+	streamConfig  streaming.StreamConfig
+
+	// analyses is the metrics engine's analysis cache, so re-uploaded text is not
+	// tokenized again; nil when the cache is disabled
+	analyses *cache.Cache
+
+	// advanced runs the advanced analyses, created on first use
+	advancedOnce sync.Once
+	advanced     *advanced.AdvancedManager
+	advancedErr  error
+
+	inflight sync.WaitGroup
+}
+
+// ReloadResult reports the changed settings a reload applied, and those it rejected
+// because they only take effect on restart
+type ReloadResult struct {
+	Applied  []string  `json:"applied"`
+	Rejected []string  `json:"rejected"`
+	Warnings []string  `json:"warnings,omitempty"` // tokenizers that failed to initialize and kept their old configuration
+	Time     time.Time `json:"time"`
+}
+
+// SetConfigSource sets the config file and load options Reload reads the
+// configuration with. Without a source, Reload searches config.ConfigSearchPaths.
+func (s *Server) SetConfigSource(configPath string, options ...config.LoadOption) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	s.configPath = configPath
+	s.loadOptions = options
+}
+
+// acquire returns the current state for a request, which must release it when done
+func (s *Server) acquire() *serverState {
+	s.stateMu.RLock()
+	defer s.stateMu.RUnlock()
+	s.state.inflight.Add(1)
+	return s.state
+}
+
+// release marks a request that acquired the state as done
+func (st *serverState) release() {
+	st.inflight.Done()
+}
+
+// current returns the current state without holding it, for settings a reload never
+// changes
+func (s *Server) current() *serverState {
+	s.stateMu.RLock()
+	defer s.stateMu.RUnlock()
+	return s.state
+}
+
+// newState builds the engines and streaming settings of a configuration. The analysis
+// cache is shared with the previous state when given, and otherwise created if enabled.
+func (s *Server) newState(cfg *config.Config, analyses *cache.Cache) *serverState {
+	metricsEngine := metrics.NewEngine(cfg.EngineConfig())
+	// Plugin metrics join each document's results as plugin_{name}_{metric}
+	if s.pluginRegistry.GetPluginCount() > 0 {
+		metricsEngine.AddDocumentHook(s.pluginRegistry.DocumentHook())
+	}
+
+	// The palette overrides mirror visualization.Palette field for field
+	palette := visualization.Palette(cfg.Visualization.Palette)
+	vizEngine := visualization.NewVisualizationEngine(visualization.VisualizationConfig{
+		Theme:       cfg.Visualization.Theme,
+		ImageSize:   cfg.Visualization.ImageSize,
+		FileType:    cfg.Visualization.FileType,
+		Interactive: cfg.Visualization.Interactive,
+		OutputDir:   filepath.Join(cfg.Output.Directory, "visualizations"),
+		Timezone:    cfg.Visualization.Timezone,
+
+		OfflineAssets: cfg.Visualization.OfflineAssets,
+		SelfContained: cfg.Visualization.SelfContained,
+
+		DisableDataExport: cfg.Visualization.DisableDataExport,
+
+		HeatmapNormalize: cfg.Visualization.HeatmapNormalize,
+		HeatmapLogScale:  cfg.Visualization.HeatmapLogScale,
+
+		Palette: &palette,
+	})
+
+	// Streamed request bodies are analyzed like streamed files
+	streamConfig := streaming.StreamConfig{
+		ChunkSize:  cfg.Streaming.ChunkSize,
+		BufferSize: cfg.Streaming.BufferSize,
+		ChunkMode:  streaming.ChunkMode(cfg.Streaming.ChunkMode),
+		Delimiter:  cfg.Streaming.Delimiter,
+		WeightBy:   cfg.Streaming.WeightBy,
+
+		MaxMemoryMB:        cfg.Streaming.MaxMemoryMB,
+		RetainChunkResults: cfg.Streaming.RetainChunkResults,
+
+		Drift: streaming.DriftConfig{
+			Enabled:        cfg.Streaming.Drift.Enabled,
+			BaselineChunks: cfg.Streaming.Drift.BaselineChunks,
+			Decay:          cfg.Streaming.Drift.Decay,
+			ZThreshold:     cfg.Streaming.Drift.ZThreshold,
+		},
+	}
+
+	if analyses == nil && cfg.Cache.Enabled && cfg.Cache.Analysis.Enabled {
+		ttl, _ := time.ParseDuration(cfg.Cache.Analysis.TTL)
+		cleanupInterval, _ := time.ParseDuration(cfg.Cache.CleanupInterval)
+		analyses = cache.NewCache(cache.CacheConfig{
+			MaxSize:         cfg.Cache.Analysis.MaxSize,
+			MaxBytes:        int64(cfg.Cache.Analysis.MaxSizeMB) << 20,
+			Shards:          cfg.Cache.Shards,
+			TTL:             ttl,
+			CleanupInterval: cleanupInterval,
+			EnableStats:     cfg.Cache.EnableStats,
+		})
+	}
+	// Results are keyed by the engine's settings, so a shared cache never serves a
+	// result analyzed under the previous ones
+	metricsEngine.SetAnalysisCache(analyses)
+
+	return &serverState{
+		config:        cfg,
+		metricsEngine: metricsEngine,
+		vizEngine:     vizEngine,
+		streamConfig:  streamConfig,
+		analyses:      analyses,
+	}
+}
+
+// advancedManager returns the state's advanced analysis manager, creating it on first
+// use. It analyzes with the state's engine, which runs the server's plugins, so the
+// plugin directory is not loaded a second time.
+func (st *serverState) advancedManager(pluginRegistry *plugins.Registry) (*advanced.AdvancedManager, error) {
+	st.advancedOnce.Do(func() {
+		st.advanced, st.advancedErr = advanced.NewAdvancedManager(st.config, st.metricsEngine, advanced.WithPluginRegistry(pluginRegistry))
+	})
+	return st.advanced, st.advancedErr
+}
+
+// Reload reads and validates the configuration again and applies the changes that
+// are safe at runtime: input, tokenizer, analysis, cache, parallel, streaming and
+// visualization settings. Changes to the restart sections are rejected and keep their
+// running values. Requests already running finish with the configuration they
+// started with; tokenizers and a cache replaced by the reload are closed once they
+// have. An invalid configuration is an error and changes nothing.
+func (s *Server) Reload() (*ReloadResult, error) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	next, err := config.LoadConfig(s.configPath, s.loadOptions...)
+	if err != nil {
+		return nil, err
+	}
+	if err := next.ValidateConfig(); err != nil {
+		return nil, err
+	}
+
+	previous := s.current()
+	result := &ReloadResult{Applied: []string{}, Rejected: []string{}, Time: time.Now()}
+	for _, key := range config.ChangedSettings(previous.config, next) {
+		if isRestartSetting(key) {
+			result.Rejected = append(result.Rejected, key)
+		} else {
+			result.Applied = append(result.Applied, key)
+		}
+	}
+	next.Server = previous.config.Server
+	next.Output = previous.config.Output
+	next.Plugins = previous.config.Plugins
+	next.Logging = previous.config.Logging
+
+	if len(result.Applied) == 0 {
+		s.logReload(result)
+		return result, nil
+	}
+
+	retired, warnings := s.reloadTokenizers(previous.config, next)
+	result.Warnings = warnings
+
+	analyses := previous.analyses
+	if !reflect.DeepEqual(previous.config.Cache, next.Cache) {
+		analyses = nil
+	}
+	state := s.newState(next, analyses)
+
+	s.stateMu.Lock()
+	s.state = state
+	s.stateMu.Unlock()
+
+	// Close what the old state alone used once its requests are done
+	go func() {
+		previous.inflight.Wait()
+		for _, tokenizer := range retired {
+			tokenizer.Close()
+		}
+		if previous.analyses != nil && previous.analyses != state.analyses {
+			previous.analyses.Close()
+		}
+		if previous.advanced != nil {
+			previous.advanced.Close()
+		}
+	}()
+
+	s.logReload(result)
+	return result, nil
+}
+
+// reloadTokenizers registers the tokenizers whose configuration changed, returning the
+// ones they replaced. Changed tokenizers that are enabled are initialized now, as at
+// startup; others are unregistered, to be created with their new configuration on
+// first use. An enabled tokenizer that fails to initialize keeps its old one.
+func (s *Server) reloadTokenizers(previous, next *config.Config) ([]tokenizers.Tokenizer, []string) {
+	enabled := make(map[string]bool)
+	for _, name := range next.Tokenizers.Enabled {
+		enabled[name] = true
+	}
+	wasEnabled := make(map[string]bool)
+	for _, name := range previous.Tokenizers.Enabled {
+		wasEnabled[name] = true
+	}
+	names := make(map[string]bool)
+	for name := range previous.Tokenizers.Configs {
+		names[name] = true
+	}
+	for name := range next.Tokenizers.Configs {
+		names[name] = true
+	}
+	for name := range enabled {
+		names[name] = true
+	}
+
+	var retired []tokenizers.Tokenizer
+	var warnings []string
+	for name := range names {
+		changed := !reflect.DeepEqual(previous.Tokenizers.ConfigFor(name), next.Tokenizers.ConfigFor(name))
+		if !changed && !(enabled[name] && !wasEnabled[name]) {
+			continue
+		}
+		if !enabled[name] {
+			if old, err := s.tokenizerRegistry.Get(name); err == nil {
+				s.tokenizerRegistry.Unregister(name)
+				retired = append(retired, old)
+			}
+			continue
+		}
+		if !changed {
+			// Newly enabled with the same configuration; keep one created on demand
+			if _, err := s.tokenizerRegistry.Get(name); err == nil {
+				continue
+			}
+		}
+
+		tokenizer, err := tokenizers.NewConfiguredTokenizer(next.Tokenizers.ConfigFor(name))
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		old, err := s.tokenizerRegistry.Replace(name, tokenizer)
+		if err != nil {
+			tokenizer.Close()
+			warnings = append(warnings, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		if old != nil {
+			retired = append(retired, old)
+		}
+	}
+	return retired, warnings
+}
+
+// isRestartSetting reports whether a setting key belongs to a restart section
+func isRestartSetting(key string) bool {
+	for _, section := range restartSections {
+		if key == section || strings.HasPrefix(key, section+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// logReload logs what a reload applied and rejected
+func (s *Server) logReload(result *ReloadResult) {
+	reloadLog := s.logger.ForModule(logger.ModuleServer)
+	if len(result.Applied) == 0 && len(result.Rejected) == 0 {
+		reloadLog.Info("Config reloaded: no changes")
+		return
+	}
+	reloadLog.WithFields(logrus.Fields{"applied": result.Applied, "rejected": result.Rejected}).Info("Config reloaded")
+	if len(result.Rejected) > 0 {
+		reloadLog.LogWarning("config_reload", "rejected settings take effect only after a restart", map[string]interface{}{"rejected": result.Rejected})
+	}
+	for _, warning := range result.Warnings {
+		reloadLog.LogWarning("config_reload", warning, nil)
+	}
+}
+
+// reloadOnSignal reloads the configuration each time the process receives SIGHUP
+func (s *Server) reloadOnSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	for range signals {
+		if _, err := s.Reload(); err != nil {
+			s.logger.ForModule(logger.ModuleServer).LogError("config_reload", err, nil)
+		}
+	}
+}
+
+// handleReload reloads the configuration and reports what was applied and rejected
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	result, err := s.Reload()
+	if err != nil {
+		logger.FromContext(r.Context()).LogError("config_reload", err, nil)
+		http.Error(w, fmt.Sprintf("Config reload failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
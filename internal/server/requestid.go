@@ -0,0 +1,45 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/logger"
+)
+
+// requestIDHeader carries the request ID, so a client or proxy can supply its own and
+// quote it when reporting a problem
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds a supplied request ID; longer ones are replaced
+const maxRequestIDLength = 64
+
+// withRequestID tags each request with an ID, taken from its X-Request-ID header or
+// generated, and echoes it in the response. The request's context carries a logger
+// that adds the ID to every event, for handlers and the engines they call. Each
+// request is an analysis of its own for log sampling.
+func (s *Server) withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if id == "" || len(id) > maxRequestIDLength {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+
+		ctx := logger.NewContext(r.Context(), s.logger.ForModule(logger.ModuleServer).WithRequestID(id).ForAnalysis())
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// newRequestID returns a random request ID, or one made from the time should the
+// system have no randomness to give
+func newRequestID() string {
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(id[:])
+}
@@ -1,35 +1,58 @@
 package server
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
-	"log"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/RevBooyah/TokEntropyDrift/internal/config"
+	"github.com/RevBooyah/TokEntropyDrift/internal/export"
 	"github.com/RevBooyah/TokEntropyDrift/internal/loader"
+	"github.com/RevBooyah/TokEntropyDrift/internal/logger"
 	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+	"github.com/RevBooyah/TokEntropyDrift/internal/plugins"
+	"github.com/RevBooyah/TokEntropyDrift/internal/streaming"
 	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
 	"github.com/RevBooyah/TokEntropyDrift/internal/visualization"
 	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
 )
 
 // Server represents the web dashboard server
 type Server struct {
-	config            *config.Config
 	router            *mux.Router
 	tokenizerRegistry *tokenizers.TokenizerRegistry
-	metricsEngine     *metrics.Engine
-	vizEngine         *visualization.VisualizationEngine
+	pluginRegistry    *plugins.Registry
 	uploadDir         string
 	sessions          map[string]*Session
+
+	// state is the configuration requests run with, replaced by Reload
+	stateMu sync.RWMutex
+	state   *serverState
+
+	// reloadMu serializes reloads; configPath and loadOptions are where they read
+	// the configuration from
+	reloadMu    sync.Mutex
+	configPath  string
+	loadOptions []config.LoadOption
+
+	// logger writes the configured log file, closed by Shutdown
+	logger     *logger.Logger
+	httpServer *http.Server
+
+	// progress sends the progress of advanced analyses to WebSocket clients
+	progress *progressHub
 }
 
 // Session represents a user session
@@ -52,64 +75,96 @@ type AnalysisRequest struct {
 type AnalysisResponse struct {
 	ID             string                               `json:"id"`
 	DocumentID     string                               `json:"document_id"`
+	ContentID      string                               `json:"content_id"` // hash of the analyzed text
 	Results        []*metrics.AnalysisResult            `json:"results"`
 	Visualizations []*visualization.VisualizationResult `json:"visualizations"`
+	Errors         map[string]string                    `json:"errors,omitempty"` // tokenizer ID -> failure
 	Timestamp      time.Time                            `json:"timestamp"`
 }
 
+// PluginStatus describes a loaded plugin and the configuration it currently runs with
+type PluginStatus struct {
+	plugins.PluginInfo
+	Config map[string]interface{} `json:"config"`
+}
+
 // NewServer creates a new web server instance
-func NewServer(cfg *config.Config) *Server {
+func NewServer(cfg *config.Config) (*Server, error) {
 	// Create upload directory
 	uploadDir := filepath.Join(cfg.Output.Directory, "uploads")
 	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		log.Fatalf("Failed to create upload directory: %v", err)
+		return nil, fmt.Errorf("failed to create upload directory: %w", err)
 	}
 
 	// Create visualizations directory
 	vizDir := filepath.Join(cfg.Output.Directory, "visualizations")
 	if err := os.MkdirAll(vizDir, 0755); err != nil {
-		log.Fatalf("Failed to create visualizations directory: %v", err)
+		return nil, fmt.Errorf("failed to create visualizations directory: %w", err)
 	}
 
-	// Register all available tokenizers with the global registry
-	if err := tokenizers.RegisterAllTokenizers(); err != nil {
-		log.Printf("Warning: Failed to register some tokenizers: %v", err)
+	serverLogger, err := logger.New(cfg.Logging.Level, cfg.Logging.Format, cfg.Logging.File,
+		logger.WithRotation(logger.Rotation{
+			MaxSizeMB:  cfg.Logging.MaxSizeMB,
+			MaxBackups: cfg.Logging.MaxBackups,
+			MaxAgeDays: cfg.Logging.MaxAgeDays,
+			Compress:   cfg.Logging.Compress,
+		}),
+		logger.WithModuleLevels(cfg.Logging.Levels),
+		logger.WithSampling(logger.Sampling{Every: cfg.Logging.Sampling.Every, First: cfg.Logging.Sampling.First}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create logger: %w", err)
 	}
+	serverLog := serverLogger.ForModule(logger.ModuleServer)
 
-	metricsEngine := metrics.NewEngine(metrics.EngineConfig{
-		EntropyWindowSize: cfg.Analysis.EntropyWindowSize,
-		NormalizeEntropy:  cfg.Analysis.NormalizeEntropy,
-		CompressionRatio:  cfg.Analysis.CompressionRatio,
-		DriftDetection:    cfg.Analysis.DriftDetection,
-	})
-	vizEngine := visualization.NewVisualizationEngine(visualization.VisualizationConfig{
-		Theme:       cfg.Visualization.Theme,
-		ImageSize:   cfg.Visualization.ImageSize,
-		FileType:    cfg.Visualization.FileType,
-		Interactive: cfg.Visualization.Interactive,
-		OutputDir:   vizDir,
-	})
+	// Initialize the enabled tokenizers with their configured parameters; others are
+	// created on first use
+	if err := tokenizers.RegisterConfiguredTokenizers(tokenizers.GlobalRegistry, cfg.Tokenizers.EnabledConfigs()); err != nil {
+		serverLog.LogWarning("tokenizer_register", err.Error(), nil)
+	}
 
+	// Load plugins from the plugin directory; a file that fails is skipped
+	pluginRegistry := plugins.NewRegistry()
+	pluginRegistry.SetMaxConcurrency(cfg.Plugins.MaxConcurrency)
+	if cfg.Plugins.Enabled && cfg.Plugins.AutoLoad {
+		loaded, failures := pluginRegistry.LoadDirectory(cfg.Plugins.PluginDirectory, cfg.Plugins.Configs)
+		for _, failure := range failures {
+			serverLog.LogWarning("plugin_load", failure.Error(), nil)
+		}
+		if len(loaded) > 0 {
+			serverLog.WithField("plugins", loaded).Info("Loaded plugins")
+		}
+	}
 	server := &Server{
-		config:            cfg,
 		router:            mux.NewRouter(),
 		tokenizerRegistry: tokenizers.GlobalRegistry,
-		metricsEngine:     metricsEngine,
-		vizEngine:         vizEngine,
+		pluginRegistry:    pluginRegistry,
 		uploadDir:         uploadDir,
 		sessions:          make(map[string]*Session),
+		logger:            serverLogger,
+		progress:          newProgressHub(),
 	}
+	server.state = server.newState(cfg, nil)
 
 	server.setupRoutes()
-	return server
+	// The address is a restart setting, so it is fixed for the server's lifetime
+	server.httpServer = &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
+		Handler: server.router,
+	}
+	// WebSocket connections are taken over from the HTTP server, which does not wait
+	// for them on shutdown
+	server.httpServer.RegisterOnShutdown(server.progress.close)
+	return server, nil
 }
 
 // setupRoutes configures all the HTTP routes
 func (s *Server) setupRoutes() {
+	s.router.Use(s.withRequestID)
+
 	// Static file serving
 	s.router.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir("web/static"))))
 	s.router.PathPrefix("/uploads/").Handler(http.StripPrefix("/uploads/", http.FileServer(http.Dir(s.uploadDir))))
-	s.router.PathPrefix("/visualizations/").Handler(http.StripPrefix("/visualizations/", http.FileServer(http.Dir(filepath.Join(s.config.Output.Directory, "visualizations")))))
+	s.router.PathPrefix("/visualizations/").Handler(http.StripPrefix("/visualizations/", http.FileServer(http.Dir(filepath.Join(s.current().config.Output.Directory, "visualizations")))))
 
 	// API routes
 	api := s.router.PathPrefix("/api/v1").Subrouter()
@@ -123,21 +178,36 @@ func (s *Server) setupRoutes() {
 	// Tokenizer management
 	api.HandleFunc("/tokenizers", s.handleListTokenizers).Methods("GET")
 	api.HandleFunc("/tokenizers/{id}", s.handleGetTokenizer).Methods("GET")
+	api.HandleFunc("/tokenizers/{id}/health", s.handleTokenizerHealth).Methods("GET")
+	api.HandleFunc("/tokenizers/{id}/vocab", s.handleTokenizerVocab).Methods("GET")
+
+	// Plugin information
+	api.HandleFunc("/plugins", s.handleListPlugins).Methods("GET")
+	api.HandleFunc("/plugins/{id}", s.handleGetPlugin).Methods("GET")
+	api.HandleFunc("/plugins/{id}/config", s.handleUpdatePluginConfig).Methods("PUT")
 
 	// Analysis endpoints
 	api.HandleFunc("/analyze", s.handleAnalyze).Methods("POST")
+	api.HandleFunc("/analyze/stream", s.handleAnalyzeStream).Methods("POST")
+	api.HandleFunc("/analyze/advanced", s.handleAnalyzeAdvanced).Methods("POST")
 	api.HandleFunc("/analyses", s.handleListAnalyses).Methods("GET")
 	api.HandleFunc("/analyses/{id}", s.handleGetAnalysis).Methods("GET")
+	api.HandleFunc("/export", s.handleExport).Methods("POST")
+	api.HandleFunc("/tokenize", s.handleTokenize).Methods("POST")
 
 	// Visualization endpoints
 	api.HandleFunc("/visualizations/heatmap", s.handleGenerateHeatmap).Methods("POST")
 	api.HandleFunc("/visualizations/drift", s.handleGenerateDriftViz).Methods("POST")
 	api.HandleFunc("/visualizations/entropy", s.handleGenerateEntropyViz).Methods("POST")
+	api.HandleFunc("/visualizations/scatter", s.handleGenerateScatterPlot).Methods("POST")
 
 	// Session management
 	api.HandleFunc("/session", s.handleGetSession).Methods("GET")
 	api.HandleFunc("/session", s.handleCreateSession).Methods("POST")
 
+	// Administration
+	api.HandleFunc("/admin/reload", s.handleReload).Methods("POST")
+
 	// WebSocket for real-time updates
 	api.HandleFunc("/ws", s.handleWebSocket)
 
@@ -148,11 +218,21 @@ func (s *Server) setupRoutes() {
 	s.router.HandleFunc("/visualize", s.handleVisualizeView).Methods("GET")
 }
 
-// Start starts the web server
+// Start starts the web server. After Shutdown it returns http.ErrServerClosed.
 func (s *Server) Start() error {
-	addr := fmt.Sprintf("%s:%d", s.config.Server.Host, s.config.Server.Port)
-	log.Printf("Starting TokEntropyDrift dashboard server on %s", addr)
-	return http.ListenAndServe(addr, s.router)
+	go s.reloadOnSignal()
+	s.logger.ForModule(logger.ModuleServer).WithField("address", s.httpServer.Addr).Info("Starting TokEntropyDrift dashboard server")
+	return s.httpServer.ListenAndServe()
+}
+
+// Shutdown stops the web server once its requests have finished, or ctx is done, and
+// closes the log file
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.httpServer.Shutdown(ctx)
+	if closeErr := s.logger.Close(); closeErr != nil && err == nil {
+		err = fmt.Errorf("failed to close log file: %w", closeErr)
+	}
+	return err
 }
 
 // handleDashboard serves the main dashboard page
@@ -165,7 +245,7 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 
 	data := map[string]interface{}{
 		"Title":  "TokEntropyDrift Dashboard",
-		"Config": s.config,
+		"Config": s.current().config,
 	}
 
 	w.Header().Set("Content-Type", "text/html")
@@ -182,7 +262,7 @@ func (s *Server) handleCompareView(w http.ResponseWriter, r *http.Request) {
 
 	data := map[string]interface{}{
 		"Title":  "Tokenizer Comparison",
-		"Config": s.config,
+		"Config": s.current().config,
 	}
 
 	w.Header().Set("Content-Type", "text/html")
@@ -199,7 +279,7 @@ func (s *Server) handleVisualizeView(w http.ResponseWriter, r *http.Request) {
 
 	data := map[string]interface{}{
 		"Title":  "Visualization Studio",
-		"Config": s.config,
+		"Config": s.current().config,
 	}
 
 	w.Header().Set("Content-Type", "text/html")
@@ -208,6 +288,9 @@ func (s *Server) handleVisualizeView(w http.ResponseWriter, r *http.Request) {
 
 // handleFileUpload handles file uploads
 func (s *Server) handleFileUpload(w http.ResponseWriter, r *http.Request) {
+	st := s.acquire()
+	defer st.release()
+
 	// Parse multipart form
 	if err := r.ParseMultipartForm(32 << 20); err != nil {
 		http.Error(w, "Failed to parse form", http.StatusBadRequest)
@@ -240,13 +323,16 @@ func (s *Server) handleFileUpload(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Load and validate document
-	docLoader := loader.NewLoader(s.config.Input.FileType)
+	docLoader := st.newLoader(st.config.Input.FileType)
 	documents, err := docLoader.LoadDocuments(filepath)
 	if err != nil {
 		os.Remove(filepath) // Clean up invalid file
 		http.Error(w, fmt.Sprintf("Invalid file: %v", err), http.StatusBadRequest)
 		return
 	}
+	for _, skipped := range docLoader.SkippedRows() {
+		logger.FromContext(r.Context()).LogWarning("upload_row_skipped", skipped.Error(), map[string]interface{}{"file": header.Filename})
+	}
 
 	if len(documents) == 0 {
 		os.Remove(filepath) // Clean up empty file
@@ -280,6 +366,7 @@ func (s *Server) handleFileUpload(w http.ResponseWriter, r *http.Request) {
 		"lines":            totalLines,
 		"chars":            totalChars,
 		"whitespace_chars": whitespaceChars,
+		"sampling":         docLoader.Sampling(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -288,6 +375,9 @@ func (s *Server) handleFileUpload(w http.ResponseWriter, r *http.Request) {
 
 // handleListDocuments lists uploaded documents
 func (s *Server) handleListDocuments(w http.ResponseWriter, r *http.Request) {
+	st := s.acquire()
+	defer st.release()
+
 	files, err := os.ReadDir(s.uploadDir)
 	if err != nil {
 		http.Error(w, "Failed to read upload directory", http.StatusInternalServerError)
@@ -304,7 +394,7 @@ func (s *Server) handleListDocuments(w http.ResponseWriter, r *http.Request) {
 
 			// Load document to calculate statistics
 			filePath := filepath.Join(s.uploadDir, file.Name())
-			docLoader := loader.NewLoader(s.config.Input.FileType)
+			docLoader := st.newLoader(st.config.Input.FileType)
 			loadedDocs, err := docLoader.LoadDocuments(filePath)
 
 			var totalLines, totalChars, whitespaceChars int
@@ -345,6 +435,9 @@ func (s *Server) handleListDocuments(w http.ResponseWriter, r *http.Request) {
 
 // handleGetDocument retrieves a specific document
 func (s *Server) handleGetDocument(w http.ResponseWriter, r *http.Request) {
+	st := s.acquire()
+	defer st.release()
+
 	vars := mux.Vars(r)
 	docID := vars["id"]
 
@@ -359,7 +452,7 @@ func (s *Server) handleGetDocument(w http.ResponseWriter, r *http.Request) {
 	for _, file := range files {
 		if !file.IsDir() && strings.HasPrefix(file.Name(), docID) {
 			filepath := filepath.Join(s.uploadDir, file.Name())
-			docLoader := loader.NewLoader(s.config.Input.FileType)
+			docLoader := st.newLoader(st.config.Input.FileType)
 			documents, err = docLoader.LoadDocuments(filepath)
 			if err != nil {
 				http.Error(w, "Failed to load document", http.StatusInternalServerError)
@@ -405,7 +498,8 @@ func (s *Server) handleDeleteDocument(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "Document not found", http.StatusNotFound)
 }
 
-// handleListTokenizers lists available tokenizers
+// handleListTokenizers lists available tokenizers. With probe=true, each is health
+// checked, concurrently, and marked available or not with the reason.
 func (s *Server) handleListTokenizers(w http.ResponseWriter, r *http.Request) {
 	availableTokenizers := tokenizers.GetAvailableTokenizers()
 
@@ -420,6 +514,25 @@ func (s *Server) handleListTokenizers(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
+	if r.URL.Query().Get("probe") == "true" {
+		st := s.acquire()
+		defer st.release()
+
+		var wg sync.WaitGroup
+		for _, tokenizer := range response {
+			wg.Add(1)
+			go func(tokenizer map[string]interface{}) {
+				defer wg.Done()
+				health := s.probeTokenizer(r.Context(), st, tokenizer["id"].(string))
+				tokenizer["available"] = health.Available
+				if health.Error != "" {
+					tokenizer["error"] = health.Error
+				}
+			}(tokenizer)
+		}
+		wg.Wait()
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
@@ -447,79 +560,228 @@ func (s *Server) handleGetTokenizer(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(tokenizer)
 }
 
+// TokenizerHealth reports whether a tokenizer can tokenize now, and if not, why
+type TokenizerHealth struct {
+	ID        string `json:"id"`
+	Available bool   `json:"available"`
+	Error     string `json:"error,omitempty"`
+}
+
+// handleTokenizerHealth creates the tokenizer if needed and runs its health check,
+// answering 503 if it is unavailable
+func (s *Server) handleTokenizerHealth(w http.ResponseWriter, r *http.Request) {
+	tokenizerID := mux.Vars(r)["id"]
+	if !tokenizers.ValidateTokenizerName(tokenizerID) {
+		http.Error(w, "Tokenizer not found", http.StatusNotFound)
+		return
+	}
+
+	st := s.acquire()
+	defer st.release()
+
+	health := s.probeTokenizer(r.Context(), st, tokenizerID)
+	w.Header().Set("Content-Type", "application/json")
+	if !health.Available {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(health)
+}
+
+// handleTokenizerVocab returns a tokenizer's vocabulary as token text to ID, or 501 if
+// the tokenizer cannot list it
+func (s *Server) handleTokenizerVocab(w http.ResponseWriter, r *http.Request) {
+	tokenizerID := mux.Vars(r)["id"]
+	if !tokenizers.ValidateTokenizerName(tokenizerID) {
+		http.Error(w, "Tokenizer not found", http.StatusNotFound)
+		return
+	}
+
+	st := s.acquire()
+	defer st.release()
+
+	tokenizer, err := s.createTokenizer(st, tokenizerID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Tokenizer unavailable: %v", err), http.StatusServiceUnavailable)
+		return
+	}
+	vocab, err := tokenizers.Vocabulary(r.Context(), tokenizer)
+	if errors.Is(err, tokenizers.ErrUnsupported) {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).LogError("tokenizer_vocab", err, map[string]interface{}{"tokenizer_name": tokenizerID})
+		http.Error(w, fmt.Sprintf("Failed to load vocabulary: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":    tokenizerID,
+		"size":  len(vocab),
+		"vocab": vocab,
+	})
+}
+
+// probeTokenizer health checks a tokenizer, which fails if it cannot be created
+func (s *Server) probeTokenizer(ctx context.Context, st *serverState, tokenizerID string) TokenizerHealth {
+	health := TokenizerHealth{ID: tokenizerID, Available: true}
+	tokenizer, err := s.createTokenizer(st, tokenizerID)
+	if err == nil {
+		err = tokenizers.CheckHealth(ctx, tokenizer)
+	}
+	if err != nil {
+		health.Available = false
+		health.Error = err.Error()
+		logger.FromContext(ctx).LogWarning("tokenizer_health", err.Error(), map[string]interface{}{"tokenizer_name": tokenizerID})
+	}
+	return health
+}
+
+// handleListPlugins lists the loaded plugins with their current configuration
+func (s *Server) handleListPlugins(w http.ResponseWriter, r *http.Request) {
+	infos := s.pluginRegistry.ListInfo()
+	statuses := make([]PluginStatus, 0, len(infos))
+	for _, info := range infos {
+		config, err := s.pluginRegistry.Config(info.Name)
+		if err != nil {
+			// Unloaded since it was listed
+			continue
+		}
+		statuses = append(statuses, PluginStatus{PluginInfo: info, Config: config})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statuses)
+}
+
+// handleGetPlugin retrieves plugin details
+func (s *Server) handleGetPlugin(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	plugin, err := s.pluginRegistry.Get(vars["id"])
+	if err != nil {
+		http.Error(w, "Plugin not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plugin.Info())
+}
+
+// handleUpdatePluginConfig replaces a plugin's configuration without restarting the
+// server. A configuration the plugin rejects leaves the current one in place.
+func (s *Server) handleUpdatePluginConfig(w http.ResponseWriter, r *http.Request) {
+	st := s.acquire()
+	defer st.release()
+
+	vars := mux.Vars(r)
+	name := vars["id"]
+	if !s.pluginRegistry.IsRegistered(name) {
+		http.Error(w, "Plugin not found", http.StatusNotFound)
+		return
+	}
+
+	var config map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.pluginRegistry.Reconfigure(name, config); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid plugin configuration: %v", err), http.StatusBadRequest)
+		return
+	}
+	// Cached results carry the plugin's metrics under its old configuration
+	if st.analyses != nil {
+		st.analyses.Clear()
+	}
+
+	plugin, err := s.pluginRegistry.Get(name)
+	if err != nil {
+		http.Error(w, "Plugin not found", http.StatusNotFound)
+		return
+	}
+	current, err := s.pluginRegistry.Config(name)
+	if err != nil {
+		http.Error(w, "Plugin not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(PluginStatus{PluginInfo: plugin.Info(), Config: current})
+}
+
 // handleAnalyze performs analysis on uploaded documents
 func (s *Server) handleAnalyze(w http.ResponseWriter, r *http.Request) {
+	st := s.acquire()
+	defer st.release()
+
 	var req AnalysisRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	log.Printf("Analysis request: DocumentID=%s, TokenizerIDs=%v, Metrics=%v", req.DocumentID, req.TokenizerIDs, req.Metrics)
+	// The engine logs each tokenizer run and metric to the request's logger
+	ctx := r.Context()
+	requestLog := logger.FromContext(ctx)
+	requestLog.LogAnalysisStart(req.DocumentID, req.TokenizerIDs)
 
 	// Load document
-	documents, err := s.loadDocumentByID(req.DocumentID)
+	documents, err := s.loadDocumentByID(st, req.DocumentID)
 	if err != nil {
-		log.Printf("Failed to load document %s: %v", req.DocumentID, err)
-		http.Error(w, "Document not found", http.StatusNotFound)
+		requestLog.LogError("document_load", err, map[string]interface{}{"document_id": req.DocumentID})
+		writeDocumentError(w, req.DocumentID, err)
 		return
 	}
 
-	document := documents[0].Content
-	log.Printf("Loaded document with %d characters", len(document))
+	document := documents[0]
 
 	// Perform analysis
 	results := make([]*metrics.AnalysisResult, 0)
-	ctx := context.Background()
+	failures := make(map[string]string)
 
 	for _, tokenizerID := range req.TokenizerIDs {
-		log.Printf("Processing tokenizer: %s", tokenizerID)
-
-		if !tokenizers.ValidateTokenizerName(tokenizerID) {
-			log.Printf("Invalid tokenizer name: %s", tokenizerID)
-			continue
-		}
-
-		// Get tokenizer from registry
-		tokenizer, err := s.tokenizerRegistry.Get(tokenizerID)
+		// Get tokenizer from registry, creating it on first use
+		tokenizer, err := s.createTokenizer(st, tokenizerID)
 		if err != nil {
-			log.Printf("Tokenizer %s not found in registry, creating new one: %v", tokenizerID, err)
-			// Try to create and register the tokenizer
-			tokenizer, err = s.createTokenizer(tokenizerID)
-			if err != nil {
-				log.Printf("Failed to create tokenizer %s: %v", tokenizerID, err)
-				continue
-			}
+			requestLog.LogError("tokenizer_create", err, map[string]interface{}{"tokenizer_name": tokenizerID})
+			failures[tokenizerID] = err.Error()
+			continue
 		}
 
-		log.Printf("Using tokenizer: %s", tokenizer.Name())
-
 		// Analyze document
-		result, err := s.metricsEngine.AnalyzeDocument(ctx, document, tokenizer)
+		result, err := st.metricsEngine.AnalyzeDocument(ctx, document.Content, tokenizer)
 		if err != nil {
-			log.Printf("Failed to analyze document with tokenizer %s: %v", tokenizerID, err)
+			requestLog.LogError("analysis", err, map[string]interface{}{"tokenizer_name": tokenizerID, "document_id": req.DocumentID})
+			failures[tokenizerID] = err.Error()
 			continue
 		}
 
-		log.Printf("Analysis successful for tokenizer %s: %d tokens", tokenizerID, result.TokenCount)
 		results = append(results, result)
 	}
 
-	log.Printf("Analysis completed with %d results", len(results))
+	requestLog.LogAnalysisComplete(map[string]interface{}{
+		"document_id": req.DocumentID,
+		"content_id":  document.ID,
+		"characters":  len(document.Content),
+		"results":     len(results),
+		"failures":    len(failures),
+	})
 
 	// Generate visualizations
+	vizEngine := st.vizEngine.WithLogger(requestLog)
 	visualizations := make([]*visualization.VisualizationResult, 0)
 	for _, result := range results {
 		// Generate heatmap
 		heatmapData := visualization.HeatmapData{
-			XLabels:    []string{"Tokens", "Entropy", "Compression"},
-			YLabels:    []string{result.TokenizerName},
-			Values:     [][]float64{{float64(result.TokenCount), result.Metrics["entropy_shannon"].Value, result.Metrics["compression_ratio"].Value}},
-			ColorScale: "Viridis",
-			Title:      "Analysis Results",
+			XLabels: []string{"Tokens", "Entropy", "Compression"},
+			YLabels: []string{result.TokenizerName},
+			Values:  [][]float64{{float64(result.TokenCount), result.Metrics["entropy_shannon"].Value, result.Metrics["compression_ratio"].Value}},
+			Title:   "Analysis Results",
 		}
 
-		viz, err := s.vizEngine.GenerateHeatmap(heatmapData, "entropy")
+		viz, err := vizEngine.GenerateHeatmap(heatmapData, "entropy")
 		if err == nil {
 			visualizations = append(visualizations, viz)
 		}
@@ -528,17 +790,109 @@ func (s *Server) handleAnalyze(w http.ResponseWriter, r *http.Request) {
 	response := AnalysisResponse{
 		ID:             fmt.Sprintf("analysis_%d", time.Now().Unix()),
 		DocumentID:     req.DocumentID,
+		ContentID:      document.ID,
 		Results:        results,
 		Visualizations: visualizations,
 		Timestamp:      time.Now(),
 	}
+	if len(failures) > 0 {
+		response.Errors = failures
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-// loadDocumentByID loads a document by its ID
-func (s *Server) loadDocumentByID(docID string) ([]loader.Document, error) {
+// handleAnalyzeStream analyzes the request body as a stream of text, so large corpora
+// can be piped to the server without uploading them first. The tokenizer is named by
+// the tokenizer query parameter; a gzip body is decompressed.
+func (s *Server) handleAnalyzeStream(w http.ResponseWriter, r *http.Request) {
+	st := s.acquire()
+	defer st.release()
+
+	tokenizerID := r.URL.Query().Get("tokenizer")
+	if tokenizerID == "" {
+		http.Error(w, "tokenizer query parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	tokenizer, err := s.createTokenizer(st, tokenizerID)
+	if err != nil {
+		logger.FromContext(r.Context()).LogError("tokenizer_create", err, map[string]interface{}{"tokenizer_name": tokenizerID})
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	body := io.Reader(r.Body)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, "Invalid gzip body", http.StatusBadRequest)
+			return
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	analyzer := streaming.NewStreamAnalyzer(st.streamConfig, st.metricsEngine)
+	result, err := analyzer.AnalyzeStream(r.Context(), body, tokenizer, nil)
+	status := http.StatusOK
+	if err != nil {
+		// The body broke off; report what was analyzed before it did
+		logger.FromContext(r.Context()).LogError("stream_analysis", err, map[string]interface{}{"tokenizer_name": tokenizerID, "bytes_read": result.BytesRead})
+		status = http.StatusBadRequest
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
+
+// newLoader creates a loader for a file type with the configured split mode, JSONL
+// fields, CSV options, file globs, deduplication, sampling and remote input
+func (st *serverState) newLoader(fileType string) *loader.Loader {
+	input := st.config.Input
+	// ValidateConfig has already rejected invalid delimiters and timeouts
+	delimiter, _ := loader.ParseCSVDelimiter(input.CSVDelimiter)
+	timeout, _ := time.ParseDuration(input.Remote.Timeout)
+	cacheDir := input.Remote.CacheDir
+	if cacheDir == "" {
+		cacheDir = filepath.Join(st.config.Output.Directory, "remote")
+	}
+	return loader.NewLoader(fileType,
+		loader.WithDedup(input.Dedup),
+		loader.WithInclude(input.Include...),
+		loader.WithExclude(input.Exclude...),
+		loader.WithSplitMode(loader.SplitMode(input.SplitMode)),
+		loader.WithDelimiter(input.Delimiter),
+		loader.WithTextFields(input.JSONLTextFields...),
+		loader.WithTextSeparator(input.JSONLTextSeparator),
+		loader.WithStrictFields(input.JSONLStrict),
+		loader.WithMetadataFields(input.JSONLMetadataFields...),
+		loader.WithCSVDelimiter(delimiter),
+		loader.WithContentColumn(input.CSVContentColumn),
+		loader.WithCSVHeader(input.CSVHasHeader),
+		loader.WithLazyQuotes(input.CSVLazyQuotes),
+		loader.WithCSVRows(input.CSVSkipRows, input.CSVMaxRows),
+		loader.WithSkipInvalidRows(input.CSVSkipInvalidRows),
+		loader.WithLengthRange(input.MinChars, input.MaxChars),
+		loader.WithMaxDocuments(input.MaxDocuments),
+		loader.WithSample(input.SampleN, input.SampleSeed),
+		loader.WithMaxTotalBytes(input.MaxTotalBytes),
+		loader.WithRemote(input.Remote.Enabled),
+		loader.WithRemotePrivateAddresses(input.Remote.AllowPrivate),
+		loader.WithRemoteTimeout(timeout),
+		loader.WithRemoteMaxBytes(input.Remote.MaxBytes),
+		loader.WithRemoteCacheDir(cacheDir))
+}
+
+// loadDocumentByID loads a document by its ID: an uploaded file, or an http:// or
+// https:// URL whose format is detected from its content type or extension
+func (s *Server) loadDocumentByID(st *serverState, docID string) ([]loader.Document, error) {
+	if loader.IsRemote(docID) {
+		return st.newLoader(loader.FileTypeAuto).LoadDocuments(docID)
+	}
+
 	files, err := os.ReadDir(s.uploadDir)
 	if err != nil {
 		return nil, err
@@ -547,7 +901,7 @@ func (s *Server) loadDocumentByID(docID string) ([]loader.Document, error) {
 	for _, file := range files {
 		if !file.IsDir() && strings.HasPrefix(file.Name(), docID) {
 			filepath := filepath.Join(s.uploadDir, file.Name())
-			docLoader := loader.NewLoader(s.config.Input.FileType)
+			docLoader := st.newLoader(st.config.Input.FileType)
 			return docLoader.LoadDocuments(filepath)
 		}
 	}
@@ -555,41 +909,180 @@ func (s *Server) loadDocumentByID(docID string) ([]loader.Document, error) {
 	return nil, fmt.Errorf("document not found")
 }
 
-// createTokenizer creates and registers a new tokenizer
-func (s *Server) createTokenizer(tokenizerID string) (tokenizers.Tokenizer, error) {
-	var tokenizer tokenizers.Tokenizer
+// writeDocumentError reports a document that failed to load. An upload is not found;
+// a URL's error names it and the HTTP status it failed with.
+func writeDocumentError(w http.ResponseWriter, docID string, err error) {
+	if loader.IsRemote(docID) {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	http.Error(w, "Document not found", http.StatusNotFound)
+}
+
+// createTokenizer returns the registered tokenizer for tokenizerID, creating the
+// real adapter from its configuration if needed. Concurrent requests for the same ID
+// share one tokenizer. An unavailable backend is an error; there is no fallback.
+func (s *Server) createTokenizer(st *serverState, tokenizerID string) (tokenizers.Tokenizer, error) {
+	return s.tokenizerRegistry.GetOrCreate(tokenizerID, func() (tokenizers.Tokenizer, error) {
+		if !st.config.Tokenizers.IsKnown(tokenizerID) {
+			return nil, fmt.Errorf("unknown tokenizer %s", tokenizerID)
+		}
+
+		s.logger.ForModule(logger.ModuleServer).WithField("tokenizer_name", tokenizerID).Info("Tokenizer not found in registry, creating new one")
 
-	switch tokenizerID {
-	case "mock":
-		tokenizer = tokenizers.NewMockTokenizer(tokenizerID)
-	case "gpt2":
-		// For now, fall back to mock tokenizer for GPT-2
-		// In a real implementation, you would create the actual GPT-2 tokenizer
-		tokenizer = tokenizers.NewMockTokenizer(tokenizerID)
-	default:
-		// For any other tokenizer, try to create a mock tokenizer as fallback
-		tokenizer = tokenizers.NewMockTokenizer(tokenizerID)
+		tokenizer, err := tokenizers.NewConfiguredTokenizer(st.config.Tokenizers.ConfigFor(tokenizerID))
+		if err != nil {
+			if backend := tokenizers.GetTokenizerBackend(tokenizerID); backend != "unknown" {
+				return nil, fmt.Errorf("%s backend unavailable: %w", backend, err)
+			}
+			return nil, err
+		}
+
+		return tokenizer, nil
+	})
+}
+
+// handleExport analyzes every document of an upload with each tokenizer and returns
+// the results as a file in an export format, by default output.format
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	st := s.acquire()
+	defer st.release()
+
+	var req struct {
+		DocumentID   string   `json:"document_id"`
+		TokenizerIDs []string `json:"tokenizer_ids"`
+		Format       string   `json:"format"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Format == "" {
+		req.Format = st.config.Output.Format
+	}
+	if req.Format == "" {
+		req.Format = export.FormatCSV
+	}
+
+	// Load document
+	documents, err := s.loadDocumentByID(st, req.DocumentID)
+	if err != nil {
+		writeDocumentError(w, req.DocumentID, err)
+		return
+	}
+
+	// Analyze the whole corpus before responding, so a failure is still an error status
+	var buf bytes.Buffer
+	writer, err := export.NewWriter(&buf, req.Format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	requestLog := logger.FromContext(ctx)
+	for _, tokenizerID := range req.TokenizerIDs {
+		tokenizer, err := s.createTokenizer(st, tokenizerID)
+		if err != nil {
+			requestLog.LogError("tokenizer_create", err, map[string]interface{}{"tokenizer_name": tokenizerID})
+			continue
+		}
+
+		corpus, err := st.metricsEngine.AnalyzeDocuments(ctx, documents, tokenizer)
+		if err != nil {
+			requestLog.LogError("analysis", err, map[string]interface{}{"tokenizer_name": tokenizerID})
+			continue
+		}
+		if err := corpus.Err(); err != nil {
+			requestLog.LogError("analysis", err, map[string]interface{}{"tokenizer_name": tokenizerID, "failed_count": corpus.FailedCount})
+		}
+		for _, result := range corpus.Documents {
+			if err := writer.Write(result); err != nil {
+				http.Error(w, fmt.Sprintf("Failed to export results: %v", err), http.StatusInternalServerError)
+				return
+			}
+		}
 	}
+	if err := writer.Close(); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to export results: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	filename := strings.TrimSuffix(filepath.Base(req.DocumentID), filepath.Ext(req.DocumentID)) + export.Extension(req.Format)
+	w.Header().Set("Content-Type", export.ContentType(req.Format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Write(buf.Bytes())
+}
+
+// handleTokenize tokenizes every document of an upload with each tokenizer and streams
+// the tokens back as JSON lines, one per document per tokenizer
+func (s *Server) handleTokenize(w http.ResponseWriter, r *http.Request) {
+	st := s.acquire()
+	defer st.release()
 
-	// Initialize the tokenizer with default config
-	config := tokenizers.TokenizerConfig{
-		Name: tokenizerID,
-		Type: tokenizers.GetTokenizerType(tokenizerID),
-		Parameters: map[string]string{
-			"vocab_size": "1000",
-		},
+	var req struct {
+		DocumentID   string   `json:"document_id"`
+		TokenizerIDs []string `json:"tokenizer_ids"`
+		IDsOnly      bool     `json:"ids_only"`
+		Summary      bool     `json:"summary"`
 	}
 
-	if err := tokenizer.Initialize(config); err != nil {
-		return nil, fmt.Errorf("failed to initialize tokenizer %s: %w", tokenizerID, err)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.TokenizerIDs) == 0 {
+		http.Error(w, "tokenizer_ids is required", http.StatusBadRequest)
+		return
+	}
+
+	documents, err := s.loadDocumentByID(st, req.DocumentID)
+	if err != nil {
+		writeDocumentError(w, req.DocumentID, err)
+		return
+	}
+
+	// Resolve every tokenizer up front, while a failure can still be an error status
+	var tokenizerList []tokenizers.Tokenizer
+	for _, tokenizerID := range req.TokenizerIDs {
+		tokenizer, err := s.createTokenizer(st, tokenizerID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		tokenizerList = append(tokenizerList, tokenizer)
 	}
 
-	// Register the tokenizer with the registry
-	if err := s.tokenizerRegistry.Register(tokenizerID, tokenizer); err != nil {
-		return nil, fmt.Errorf("failed to register tokenizer %s: %w", tokenizerID, err)
+	w.Header().Set("Content-Type", export.ContentType(export.FormatJSONL))
+	dump := export.NewTokenDump(newFlushWriter(w), export.TokenDumpOptions{IDsOnly: req.IDsOnly, Summary: req.Summary})
+	if err := export.TokenizeTo(r.Context(), dump, documents, tokenizerList); err != nil {
+		// The response has started; end it without the summary so clients can tell
+		logger.FromContext(r.Context()).LogError("tokenize", err, map[string]interface{}{"document_id": req.DocumentID})
+		return
+	}
+	if err := dump.Close(); err != nil {
+		logger.FromContext(r.Context()).LogError("token_summary", err, map[string]interface{}{"document_id": req.DocumentID})
 	}
+}
+
+// flushWriter sends each write to the client as soon as it is made
+type flushWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
 
-	return tokenizer, nil
+func newFlushWriter(w http.ResponseWriter) *flushWriter {
+	flusher, _ := w.(http.Flusher)
+	return &flushWriter{w: w, flusher: flusher}
+}
+
+// Write writes p and flushes it
+func (f *flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if f.flusher != nil {
+		f.flusher.Flush()
+	}
+	return n, err
 }
 
 // handleListAnalyses lists previous analyses
@@ -612,10 +1105,15 @@ func (s *Server) handleGetAnalysis(w http.ResponseWriter, r *http.Request) {
 
 // handleGenerateHeatmap generates heatmap visualizations
 func (s *Server) handleGenerateHeatmap(w http.ResponseWriter, r *http.Request) {
+	st := s.acquire()
+	defer st.release()
+
 	var req struct {
 		DocumentID string   `json:"document_id"`
 		Tokenizers []string `json:"tokenizers"`
 		Type       string   `json:"type"`
+		Normalize  string   `json:"normalize"` // defaults to visualization.heatmap_normalize
+		LogScale   *bool    `json:"log_scale"` // defaults to visualization.heatmap_log_scale
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -623,42 +1121,45 @@ func (s *Server) handleGenerateHeatmap(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	switch req.Normalize {
+	case "", visualization.NormalizeNone, visualization.NormalizeRow, visualization.NormalizeColumn, visualization.NormalizeZScore:
+	default:
+		http.Error(w, fmt.Sprintf("Invalid normalize: %s (use none, row, column or zscore)", req.Normalize), http.StatusBadRequest)
+		return
+	}
+
 	// Load document
-	documents, err := s.loadDocumentByID(req.DocumentID)
+	documents, err := s.loadDocumentByID(st, req.DocumentID)
 	if err != nil {
-		http.Error(w, "Document not found", http.StatusNotFound)
+		writeDocumentError(w, req.DocumentID, err)
 		return
 	}
 
-	document := documents[0].Content
+	document := documents[0]
 
 	// Generate heatmap data
 	var xLabels []string
 	var yLabels []string
 	var values [][]float64
 
-	ctx := context.Background()
+	ctx := r.Context()
+	requestLog := logger.FromContext(ctx)
 	for _, tokenizerID := range req.Tokenizers {
-		log.Printf("Processing tokenizer for heatmap: %s", tokenizerID)
+		requestLog.WithField("tokenizer_name", tokenizerID).Debug("Processing tokenizer for heatmap")
 
-		if !tokenizers.ValidateTokenizerName(tokenizerID) {
-			log.Printf("Invalid tokenizer name: %s", tokenizerID)
-			continue
-		}
-
-		tokenizer, err := s.tokenizerRegistry.Get(tokenizerID)
+		tokenizer, err := s.createTokenizer(st, tokenizerID)
 		if err != nil {
-			log.Printf("Failed to get tokenizer %s: %v", tokenizerID, err)
+			requestLog.LogError("tokenizer_create", err, map[string]interface{}{"tokenizer_name": tokenizerID})
 			continue
 		}
 
-		result, err := s.metricsEngine.AnalyzeDocument(ctx, document, tokenizer)
+		result, err := st.metricsEngine.AnalyzeDocument(ctx, document.Content, tokenizer)
 		if err != nil {
-			log.Printf("Failed to analyze document with tokenizer %s: %v", tokenizerID, err)
+			requestLog.LogError("analysis", err, map[string]interface{}{"tokenizer_name": tokenizerID})
 			continue
 		}
 
-		log.Printf("Successfully analyzed document with tokenizer %s: %d tokens", tokenizerID, result.TokenCount)
+		requestLog.WithFields(logrus.Fields{"tokenizer_name": tokenizerID, "token_count": result.TokenCount}).Debug("Analyzed document for heatmap")
 
 		yLabels = append(yLabels, tokenizerID)
 		if len(xLabels) == 0 {
@@ -684,8 +1185,12 @@ func (s *Server) handleGenerateHeatmap(w http.ResponseWriter, r *http.Request) {
 			compressionValue = compressionMetric.Value
 		}
 
-		log.Printf("Tokenizer %s - Tokens: %d, Entropy: %.3f, Compression: %.3f",
-			tokenizerID, result.TokenCount, entropyValue, compressionValue)
+		requestLog.WithFields(logrus.Fields{
+			"tokenizer_name": tokenizerID,
+			"token_count":    result.TokenCount,
+			"entropy":        entropyValue,
+			"compression":    compressionValue,
+		}).Debug("Heatmap row")
 
 		values = append(values, []float64{
 			float64(result.TokenCount),
@@ -696,24 +1201,31 @@ func (s *Server) handleGenerateHeatmap(w http.ResponseWriter, r *http.Request) {
 
 	// Check if we have any data to visualize
 	if len(values) == 0 {
-		log.Printf("No valid analysis results found for heatmap generation. Requested tokenizers: %v", req.Tokenizers)
+		requestLog.LogWarning("heatmap", "no valid analysis results", map[string]interface{}{"tokenizers": req.Tokenizers})
 		http.Error(w, "No valid analysis results found for heatmap generation", http.StatusBadRequest)
 		return
 	}
 
-	log.Printf("Generated heatmap data with %d tokenizers", len(values))
+	requestLog.WithField("tokenizer_count", len(values)).Debug("Generated heatmap data")
 
 	heatmapData := visualization.HeatmapData{
-		XLabels:    xLabels,
-		YLabels:    yLabels,
-		Values:     values,
-		ColorScale: "Viridis",
-		Title:      fmt.Sprintf("Analysis Heatmap - %s", req.Type),
+		XLabels:   xLabels,
+		YLabels:   yLabels,
+		Values:    values,
+		Title:     fmt.Sprintf("Analysis Heatmap - %s", req.Type),
+		Normalize: st.config.Visualization.HeatmapNormalize,
+		LogScale:  st.config.Visualization.HeatmapLogScale,
+	}
+	if req.Normalize != "" {
+		heatmapData.Normalize = req.Normalize
+	}
+	if req.LogScale != nil {
+		heatmapData.LogScale = *req.LogScale
 	}
 
-	viz, err := s.vizEngine.GenerateHeatmap(heatmapData, req.Type)
+	viz, err := st.vizEngine.WithLogger(logger.FromContext(r.Context())).GenerateHeatmap(heatmapData, req.Type)
 	if err != nil {
-		log.Printf("Failed to generate heatmap: %v", err)
+		requestLog.LogError("heatmap", err, map[string]interface{}{"type": req.Type})
 		http.Error(w, fmt.Sprintf("Failed to generate heatmap: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -729,10 +1241,237 @@ func (s *Server) handleGenerateHeatmap(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(viz)
 }
 
-// handleGenerateDriftViz generates drift visualizations
+// handleGenerateDriftViz compares tokenizers on a document and renders a pairwise drift heatmap
 func (s *Server) handleGenerateDriftViz(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement drift visualization generation
-	http.Error(w, "Not implemented", http.StatusNotImplemented)
+	st := s.acquire()
+	defer st.release()
+
+	var req struct {
+		DocumentID string   `json:"document_id"`
+		Tokenizers []string `json:"tokenizers"`
+		Metric     string   `json:"metric"`
+		Table      bool     `json:"table"`      // also summarize every document in Markdown and LaTeX tables
+		Metrics    []string `json:"metrics"`    // table columns; defaults to output.tables.metrics
+		Vocabulary bool     `json:"vocabulary"` // also compare each pair's full vocabularies
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Metric == "" {
+		req.Metric = "drift_jaccard_distance"
+	}
+	if !isDriftMetric(st.metricsEngine, req.Metric) {
+		http.Error(w, fmt.Sprintf("Unknown drift metric %q", req.Metric), http.StatusBadRequest)
+		return
+	}
+
+	// Load document
+	documents, err := s.loadDocumentByID(st, req.DocumentID)
+	if err != nil {
+		writeDocumentError(w, req.DocumentID, err)
+		return
+	}
+
+	document := documents[0].Content
+	requestLog := logger.FromContext(r.Context())
+
+	// Resolve tokenizers
+	selected := make([]tokenizers.Tokenizer, 0, len(req.Tokenizers))
+	for _, tokenizerID := range req.Tokenizers {
+		tokenizer, err := s.createTokenizer(st, tokenizerID)
+		if err != nil {
+			requestLog.LogError("tokenizer_create", err, map[string]interface{}{"tokenizer_name": tokenizerID})
+			continue
+		}
+		selected = append(selected, tokenizer)
+	}
+
+	comparison, err := st.metricsEngine.CompareTokenizers(r.Context(), document, selected)
+	if err != nil {
+		requestLog.LogError("tokenizer_comparison", err, nil)
+		http.Error(w, fmt.Sprintf("Failed to compare tokenizers: %v", err), http.StatusBadRequest)
+		return
+	}
+	// Pairs with a tokenizer that cannot list its vocabulary are noted as skipped
+	if req.Vocabulary {
+		if err := st.metricsEngine.AddVocabularyComparisons(r.Context(), comparison, selected); err != nil {
+			requestLog.LogError("vocabulary_comparison", err, nil)
+		}
+	}
+
+	viz, err := st.vizEngine.WithLogger(logger.FromContext(r.Context())).GenerateComparisonHeatmap(comparison, req.Metric)
+	if err != nil {
+		requestLog.LogError("comparison_heatmap", err, map[string]interface{}{"metric": req.Metric})
+		http.Error(w, fmt.Sprintf("Failed to generate comparison heatmap: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Convert filepath to web-accessible URL
+	if viz.Filepath != "" {
+		viz.Filepath = "/visualizations/" + filepath.Base(viz.Filepath)
+	}
+
+	response := map[string]interface{}{
+		"comparison":    comparison,
+		"visualization": viz,
+	}
+
+	// Per-document drift series between the first two tokenizers across every loaded line
+	docs := make([]string, len(documents))
+	for i, doc := range documents {
+		docs[i] = doc.Content
+	}
+
+	driftCalc := metrics.NewDriftCalculator(0.5)
+	if corpusDrift, err := driftCalc.CalculateCorpusDrift(r.Context(), docs, selected[0], selected[1]); err != nil {
+		requestLog.LogError("corpus_drift", err, nil)
+	} else {
+		response["corpus_drift"] = corpusDrift
+		if driftViz, err := st.vizEngine.WithLogger(logger.FromContext(r.Context())).GenerateDriftVisualization(visualization.NewDriftData(corpusDrift)); err != nil {
+			requestLog.LogError("drift_visualization", err, nil)
+		} else {
+			driftViz.Filepath = "/visualizations/" + filepath.Base(driftViz.Filepath)
+			response["drift_visualization"] = driftViz
+		}
+	}
+
+	if req.Table {
+		table, err := s.summaryTable(r.Context(), st, documents, selected, req.Metrics)
+		if err != nil {
+			requestLog.LogError("summary_table", err, nil)
+		} else {
+			response["table"] = table
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// isDriftMetric reports whether metric is one of the engine's pairwise drift metrics
+func isDriftMetric(engine *metrics.Engine, metric string) bool {
+	for _, name := range engine.GetDriftMetricNames() {
+		if name == metric {
+			return true
+		}
+	}
+	return false
+}
+
+// summaryTable analyzes every document with each tokenizer and renders the mean of
+// each metric as Markdown and LaTeX tables, also written to the reports directory
+func (s *Server) summaryTable(ctx context.Context, st *serverState, documents []loader.Document, selected []tokenizers.Tokenizer, metricNames []string) (map[string]interface{}, error) {
+	var results []*metrics.AnalysisResult
+	for _, tokenizer := range selected {
+		corpus, err := st.metricsEngine.AnalyzeDocuments(ctx, documents, tokenizer)
+		if err != nil {
+			return nil, err
+		}
+		if err := corpus.Err(); err != nil {
+			logger.FromContext(ctx).LogError("analysis", err, map[string]interface{}{"tokenizer_name": tokenizer.Name(), "failed_count": corpus.FailedCount})
+		}
+		results = append(results, corpus.Documents...)
+	}
+
+	tables := st.config.Output.Tables
+	if len(metricNames) == 0 {
+		metricNames = tables.Metrics
+	}
+	options := export.TableOptions{Digits: tables.Digits, Std: tables.Std, Bold: tables.Bold}
+	table := export.Summarize(results, metricNames)
+
+	name := fmt.Sprintf("summary_%d", time.Now().Unix())
+	paths, err := export.WriteTables(st.config.GetReportPath(), name, table, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"markdown": table.Markdown(options),
+		"latex":    table.LaTeX(options),
+		"files":    paths,
+	}, nil
+}
+
+// handleGenerateScatterPlot plots two metrics against each other for every line of a
+// document, colored by tokenizer
+func (s *Server) handleGenerateScatterPlot(w http.ResponseWriter, r *http.Request) {
+	st := s.acquire()
+	defer st.release()
+
+	var req struct {
+		DocumentID string   `json:"document_id"`
+		Tokenizers []string `json:"tokenizers"`
+		XMetric    string   `json:"x_metric"`
+		YMetric    string   `json:"y_metric"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.XMetric == "" || req.YMetric == "" {
+		http.Error(w, "x_metric and y_metric are required", http.StatusBadRequest)
+		return
+	}
+
+	// Load document
+	documents, err := s.loadDocumentByID(st, req.DocumentID)
+	if err != nil {
+		writeDocumentError(w, req.DocumentID, err)
+		return
+	}
+
+	// Analyze every line with each tokenizer, so each line becomes a point
+	results := make([]*metrics.AnalysisResult, 0)
+	ctx := r.Context()
+	requestLog := logger.FromContext(ctx)
+	for _, tokenizerID := range req.Tokenizers {
+		tokenizer, err := s.createTokenizer(st, tokenizerID)
+		if err != nil {
+			requestLog.LogError("tokenizer_create", err, map[string]interface{}{"tokenizer_name": tokenizerID})
+			continue
+		}
+
+		corpus, err := st.metricsEngine.AnalyzeDocuments(ctx, documents, tokenizer)
+		if err != nil {
+			requestLog.LogError("analysis", err, map[string]interface{}{"tokenizer_name": tokenizerID})
+			continue
+		}
+		if err := corpus.Err(); err != nil {
+			requestLog.LogError("analysis", err, map[string]interface{}{"tokenizer_name": tokenizerID, "failed_count": corpus.FailedCount})
+		}
+		results = append(results, corpus.Documents...)
+	}
+
+	if len(results) == 0 {
+		http.Error(w, "No valid analysis results found for scatter plot generation", http.StatusBadRequest)
+		return
+	}
+
+	scatterData, err := visualization.NewScatterData(results, req.XMetric, req.YMetric)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	viz, err := st.vizEngine.WithLogger(logger.FromContext(r.Context())).GenerateScatterPlot(scatterData)
+	if err != nil {
+		requestLog.LogError("scatter_plot", err, nil)
+		http.Error(w, fmt.Sprintf("Failed to generate scatter plot: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Convert filepath to web-accessible URL
+	if viz.Filepath != "" {
+		viz.Filepath = "/visualizations/" + filepath.Base(viz.Filepath)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(viz)
 }
 
 // handleGenerateEntropyViz generates entropy visualizations
@@ -798,8 +1537,41 @@ func (s *Server) handleCreateSession(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// handleWebSocket handles WebSocket connections for real-time updates
+// handleWebSocket sends the progress of advanced analyses as JSON ProgressEvents. With
+// a request_id query parameter only that request's events are sent; a client can pick
+// the ID and send it as its analysis request's X-Request-ID header.
 func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement WebSocket support for real-time updates
-	http.Error(w, "WebSocket not implemented", http.StatusNotImplemented)
+	conn, err := upgradeWebSocket(w, r, s.current().config.Server.AllowedOrigins)
+	if err != nil {
+		logger.FromContext(r.Context()).LogError("websocket_upgrade", err, nil)
+		return
+	}
+	defer conn.Close()
+
+	sub := s.progress.subscribe(r.URL.Query().Get("request_id"))
+	defer s.progress.unsubscribe(sub)
+
+	closed := make(chan struct{})
+	go func() {
+		conn.readLoop()
+		close(closed)
+	}()
+
+	for {
+		select {
+		case event, ok := <-sub.events:
+			if !ok {
+				return
+			}
+			message, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if err := conn.WriteText(message); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
 }
@@ -0,0 +1,426 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/config"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// unhealthyTokenizer is a mock tokenizer whose backend is down
+type unhealthyTokenizer struct {
+	*tokenizers.MockTokenizer
+}
+
+func (u *unhealthyTokenizer) HealthCheck(ctx context.Context) error {
+	return errors.New("backend is down")
+}
+
+// writeServerConfig writes a configuration enabling only the mock tokenizer, with its
+// output under a temporary directory, and returns its path. extra is appended to it.
+func writeServerConfig(t *testing.T, extra string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ted.config.yaml")
+	content := fmt.Sprintf(`
+output:
+  directory: %q
+tokenizers:
+  enabled: ["mock"]
+plugins:
+  enabled: false
+%s`, filepath.Join(dir, "output"), extra)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newTestServer creates a server from the configuration at path, reloading from it,
+// with a registry of its own holding a healthy mock tokenizer and gpt2 as an unhealthy
+// one
+func newTestServer(t *testing.T, path string) *Server {
+	t.Helper()
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	s, err := NewServer(cfg)
+	if err != nil {
+		t.Fatalf("NewServer returned error: %v", err)
+	}
+	t.Cleanup(func() { s.Shutdown(context.Background()) })
+	s.SetConfigSource(path)
+
+	s.tokenizerRegistry = tokenizers.NewTokenizerRegistry()
+	s.tokenizerRegistry.Register("mock", tokenizers.NewMockTokenizer("mock"))
+	s.tokenizerRegistry.Register("gpt2", &unhealthyTokenizer{tokenizers.NewMockTokenizer("gpt2")})
+	return s
+}
+
+// serve sends a request to the server's routes and returns the response
+func serve(s *Server, method, target string, body io.Reader) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	s.router.ServeHTTP(recorder, httptest.NewRequest(method, target, body))
+	return recorder
+}
+
+func TestTokenizerHealth(t *testing.T) {
+	s := newTestServer(t, writeServerConfig(t, ""))
+
+	tests := []struct {
+		id        string
+		status    int
+		available bool
+	}{
+		{"mock", http.StatusOK, true},
+		{"gpt2", http.StatusServiceUnavailable, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			response := serve(s, "GET", "/api/v1/tokenizers/"+tt.id+"/health", nil)
+			if response.Code != tt.status {
+				t.Fatalf("status = %d, want %d", response.Code, tt.status)
+			}
+			var health TokenizerHealth
+			if err := json.NewDecoder(response.Body).Decode(&health); err != nil {
+				t.Fatal(err)
+			}
+			if health.ID != tt.id || health.Available != tt.available || (health.Error != "") == tt.available {
+				t.Errorf("health = %+v, want available %v with an error only if not", health, tt.available)
+			}
+		})
+	}
+
+	if response := serve(s, "GET", "/api/v1/tokenizers/nonexistent/health", nil); response.Code != http.StatusNotFound {
+		t.Errorf("status of an unknown tokenizer = %d, want %d", response.Code, http.StatusNotFound)
+	}
+}
+
+func TestListTokenizersProbe(t *testing.T) {
+	s := newTestServer(t, writeServerConfig(t, ""))
+
+	decode := func(response *httptest.ResponseRecorder) map[string]map[string]interface{} {
+		t.Helper()
+		var list []map[string]interface{}
+		if err := json.NewDecoder(response.Body).Decode(&list); err != nil {
+			t.Fatal(err)
+		}
+		byID := make(map[string]map[string]interface{})
+		for _, tokenizer := range list {
+			byID[tokenizer["id"].(string)] = tokenizer
+		}
+		return byID
+	}
+
+	listed := decode(serve(s, "GET", "/api/v1/tokenizers", nil))
+	if _, probed := listed["mock"]["available"]; probed {
+		t.Error("tokenizers should only be probed with probe=true")
+	}
+
+	probed := decode(serve(s, "GET", "/api/v1/tokenizers?probe=true", nil))
+	if available, _ := probed["mock"]["available"].(bool); !available {
+		t.Errorf("mock = %v, want available", probed["mock"])
+	}
+	if available, _ := probed["gpt2"]["available"].(bool); available || probed["gpt2"]["error"] == nil {
+		t.Errorf("gpt2 = %v, want unavailable with an error", probed["gpt2"])
+	}
+}
+
+func TestTokenizerVocabUnsupported(t *testing.T) {
+	s := newTestServer(t, writeServerConfig(t, ""))
+
+	// The mock tokenizer cannot list its vocabulary
+	response := serve(s, "GET", "/api/v1/tokenizers/mock/vocab", nil)
+	if response.Code != http.StatusNotImplemented {
+		t.Fatalf("status = %d, want %d", response.Code, http.StatusNotImplemented)
+	}
+	if !strings.Contains(response.Body.String(), tokenizers.ErrUnsupported.Error()) {
+		t.Errorf("body = %q, want it to mention %q", response.Body.String(), tokenizers.ErrUnsupported)
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := writeServerConfig(t, "analysis:\n  entropy_window_size: 100\n")
+	s := newTestServer(t, path)
+
+	// Change a runtime setting and a restart one
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := strings.Replace(string(data), "entropy_window_size: 100", "entropy_window_size: 250", 1) + "server:\n  port: 9999\n"
+	if err := os.WriteFile(path, []byte(changed), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	response := serve(s, "POST", "/api/v1/admin/reload", nil)
+	if response.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", response.Code, http.StatusOK, response.Body.String())
+	}
+	var result ReloadResult
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(result.Applied, ",") != "analysis.entropy_window_size" || strings.Join(result.Rejected, ",") != "server.port" {
+		t.Errorf("applied %v and rejected %v, want the window size applied and the port rejected", result.Applied, result.Rejected)
+	}
+	if cfg := s.current().config; cfg.Analysis.EntropyWindowSize != 250 || cfg.Server.Port == 9999 {
+		t.Errorf("running with window %d and port %d, want 250 and the old port", cfg.Analysis.EntropyWindowSize, cfg.Server.Port)
+	}
+
+	// An invalid configuration changes nothing
+	if err := os.WriteFile(path, []byte("analysis: [not a section"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if response := serve(s, "POST", "/api/v1/admin/reload", nil); response.Code != http.StatusBadRequest {
+		t.Errorf("status of an invalid config = %d, want %d", response.Code, http.StatusBadRequest)
+	}
+	if window := s.current().config.Analysis.EntropyWindowSize; window != 250 {
+		t.Errorf("window = %d after a failed reload, want 250", window)
+	}
+}
+
+// dialWebSocket sends a WebSocket handshake to the test server from origin, if not
+// empty, and returns the connection and the handshake's response
+func dialWebSocket(t *testing.T, server *httptest.Server, target, origin string) (net.Conn, *bufio.Reader, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	request, _ := http.NewRequest("GET", server.URL+target, nil)
+	request.Header.Set("Connection", "Upgrade")
+	request.Header.Set("Upgrade", "websocket")
+	request.Header.Set("Sec-WebSocket-Version", "13")
+	request.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	if origin != "" {
+		request.Header.Set("Origin", origin)
+	}
+	if err := request.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, reader, response
+}
+
+func TestWebSocketProgress(t *testing.T) {
+	s := newTestServer(t, writeServerConfig(t, ""))
+	server := httptest.NewServer(s.router)
+	defer server.Close()
+
+	conn, reader, response := dialWebSocket(t, server, "/api/v1/ws?request_id=req-1", server.URL)
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d, want %d", response.StatusCode, http.StatusSwitchingProtocols)
+	}
+	// The accept key of RFC 6455's example key
+	sum := sha1.Sum([]byte("dGhlIHNhbXBsZSBub25jZQ==" + websocketGUID))
+	if accept := response.Header.Get("Sec-WebSocket-Accept"); accept != base64.StdEncoding.EncodeToString(sum[:]) || accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Sec-WebSocket-Accept = %q", accept)
+	}
+
+	// Publish once the handler has subscribed; another request's event is not sent
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		s.progress.mu.Lock()
+		subscribed := len(s.progress.subscribers)
+		s.progress.mu.Unlock()
+		if subscribed == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("handler never subscribed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	s.progress.publish(ProgressEvent{Type: "progress", RequestID: "req-2", Tokenizer: "mock"})
+	s.progress.publish(ProgressEvent{Type: "progress", RequestID: "req-1", Tokenizer: "mock", Processed: 3, Total: 10})
+
+	// One unmasked, final text frame holding the event
+	var head [2]byte
+	if _, err := io.ReadFull(reader, head[:]); err != nil {
+		t.Fatal(err)
+	}
+	if head[0] != 0x80|opText || head[1]&0x80 != 0 {
+		t.Fatalf("frame header = %#x %#x, want a final text frame without a mask", head[0], head[1])
+	}
+	payload := make([]byte, head[1]&0x7F)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		t.Fatal(err)
+	}
+	var event ProgressEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		t.Fatalf("payload %q is not an event: %v", payload, err)
+	}
+	if event.RequestID != "req-1" || event.Processed != 3 || event.Total != 10 {
+		t.Errorf("event = %+v, want req-1's progress", event)
+	}
+
+	// A masked close frame from the client is answered with a close frame
+	mask := []byte{1, 2, 3, 4}
+	if _, err := conn.Write(append([]byte{0x80 | opClose, 0x80}, mask...)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(reader, head[:]); err != nil {
+		t.Fatal(err)
+	}
+	if head[0] != 0x80|opClose || binary.BigEndian.Uint16(head[:])&0x7F != 0 {
+		t.Errorf("reply = %#x %#x, want an empty close frame", head[0], head[1])
+	}
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	s := newTestServer(t, writeServerConfig(t, "server:\n  allowed_origins: [\"https://tools.example.com\"]\n"))
+	server := httptest.NewServer(s.router)
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		origin string
+		status int
+	}{
+		{"same host", server.URL, http.StatusSwitchingProtocols},
+		{"allowed", "https://tools.example.com", http.StatusSwitchingProtocols},
+		{"no origin", "", http.StatusSwitchingProtocols},
+		{"other site", "https://evil.example.com", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, response := dialWebSocket(t, server, "/api/v1/ws", tt.origin)
+			if response.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", response.StatusCode, tt.status)
+			}
+		})
+	}
+
+	// A plain request is not an upgrade
+	if response := serve(s, "GET", "/api/v1/ws", nil); response.Code != http.StatusBadRequest {
+		t.Errorf("status of a plain request = %d, want %d", response.Code, http.StatusBadRequest)
+	}
+}
+
+func TestDriftVisualizationMetric(t *testing.T) {
+	s := newTestServer(t, writeServerConfig(t, ""))
+	if err := os.WriteFile(filepath.Join(s.uploadDir, "doc1_sample.txt"), []byte("the quick brown fox\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		metric string
+		status int
+	}{
+		{"", http.StatusOK},
+		{"drift_js_divergence", http.StatusOK},
+		{"drift_alignment_score", http.StatusOK},
+		{"entropy", http.StatusBadRequest},
+		{"../../../tmp/drift_js_divergence", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.metric, func(t *testing.T) {
+			body := fmt.Sprintf(`{"document_id":"doc1","tokenizers":["mock","gpt2"],"metric":%q}`, tt.metric)
+			response := serve(s, "POST", "/api/v1/visualizations/drift", strings.NewReader(body))
+			if response.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", response.Code, tt.status, response.Body.String())
+			}
+		})
+	}
+}
+
+func TestAnalyzeReportsTokenizerFailures(t *testing.T) {
+	s := newTestServer(t, writeServerConfig(t, ""))
+	if err := os.WriteFile(filepath.Join(s.uploadDir, "doc1_sample.txt"), []byte("the quick brown fox\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Configured, but the local BPE tokenizer's files are missing, so it fails to initialize
+	missing := t.TempDir()
+	s.current().config.Tokenizers.Configs = map[string]config.TokenizerDef{"broken-bpe": {
+		Type: "bpe",
+		Parameters: map[string]string{
+			"vocab_path":  filepath.Join(missing, "vocab.json"),
+			"merges_path": filepath.Join(missing, "merges.txt"),
+		},
+	}}
+
+	body := `{"document_id":"doc1","tokenizer_ids":["mock","nonexistent","broken-bpe"]}`
+	response := serve(s, "POST", "/api/v1/analyze", strings.NewReader(body))
+	if response.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", response.Code, http.StatusOK, response.Body.String())
+	}
+	var analysis AnalysisResponse
+	if err := json.NewDecoder(response.Body).Decode(&analysis); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(analysis.Results) != 1 || analysis.Results[0].TokenizerName != "mock" {
+		t.Errorf("results = %v, want only mock's", analysis.Results)
+	}
+	for id, want := range map[string]string{"nonexistent": "unknown tokenizer", "broken-bpe": "vocab.json"} {
+		if !strings.Contains(analysis.Errors[id], want) {
+			t.Errorf("error for %s = %q, want it to contain %q", id, analysis.Errors[id], want)
+		}
+	}
+	if len(analysis.Errors) != 2 {
+		t.Errorf("errors = %v, want one for each failing tokenizer", analysis.Errors)
+	}
+	if _, err := s.tokenizerRegistry.Get("broken-bpe"); err == nil {
+		t.Error("a tokenizer that failed to initialize was registered")
+	}
+}
+
+func TestAnalyzeRemoteDocument(t *testing.T) {
+	var fetched atomic.Bool
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched.Store(true)
+		w.Write([]byte("the quick brown fox\n"))
+	}))
+	defer target.Close()
+
+	tests := []struct {
+		name    string
+		extra   string
+		status  int
+		wantErr string
+	}{
+		{"disabled by default", "", http.StatusBadGateway, "remote input is disabled"},
+		// The target listens on loopback, like a service only the server can reach
+		{"private address", "input:\n  remote:\n    enabled: true\n", http.StatusBadGateway, "private address"},
+		{"private address allowed", "input:\n  remote:\n    enabled: true\n    allow_private: true\n", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetched.Store(false)
+			s := newTestServer(t, writeServerConfig(t, tt.extra))
+			body := fmt.Sprintf(`{"document_id":%q,"tokenizer_ids":["mock"]}`, target.URL+"/doc.txt")
+			response := serve(s, "POST", "/api/v1/analyze", strings.NewReader(body))
+			if response.Code != tt.status || !strings.Contains(response.Body.String(), tt.wantErr) {
+				t.Fatalf("status = %d, want %d with %q: %s", response.Code, tt.status, tt.wantErr, response.Body.String())
+			}
+			if fetched.Load() != (tt.wantErr == "") {
+				t.Errorf("target fetched = %v, want only when allowed", fetched.Load())
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// websocketGUID is appended to the client's key to compute the handshake's accept key
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes (RFC 6455, section 5.2)
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xA
+)
+
+// maxClientFrame bounds the frames read from a client, which only ever sends control
+// frames to a progress feed
+const maxClientFrame = 4096
+
+// websocketWriteTimeout bounds each frame written, so a client that stopped reading
+// cannot hold its connection open
+const websocketWriteTimeout = 10 * time.Second
+
+// websocketConn is a server-side WebSocket connection that sends text messages. Only
+// the subset of RFC 6455 a push feed needs is implemented: unfragmented text frames
+// out, and close and ping frames in.
+type websocketConn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	writeMu sync.Mutex
+}
+
+// upgradeWebSocket completes the WebSocket handshake of r and takes over its
+// connection. Browsers let any page open a WebSocket to any host, so a handshake from
+// a page of another origin than r's host is refused unless allowedOrigins lists it. On
+// failure it has already written the HTTP error.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request, allowedOrigins []string) (*websocketConn, error) {
+	if !headerHasToken(r.Header, "Connection", "upgrade") || !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		http.Error(w, "WebSocket upgrade required", http.StatusBadRequest)
+		return nil, errors.New("not a websocket upgrade")
+	}
+	if origin := r.Header.Get("Origin"); !originAllowed(origin, r.Host, allowedOrigins) {
+		http.Error(w, "Origin not allowed", http.StatusForbidden)
+		return nil, fmt.Errorf("origin %s not allowed", origin)
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "Unsupported WebSocket version", http.StatusUpgradeRequired)
+		return nil, errors.New("unsupported websocket version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "Missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, errors.New("missing websocket key")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "WebSocket not supported", http.StatusInternalServerError)
+		return nil, errors.New("response writer cannot be hijacked")
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to take over connection: %w", err)
+	}
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	handshake := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n"
+	conn.SetWriteDeadline(time.Now().Add(websocketWriteTimeout))
+	if _, err := rw.WriteString(handshake); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to write handshake: %w", err)
+	}
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to write handshake: %w", err)
+	}
+	return &websocketConn{conn: conn, reader: rw.Reader}, nil
+}
+
+// originAllowed reports whether a page of origin may open a WebSocket to host: one of
+// the same host, or of an allowed origin. Clients other than browsers send no origin.
+func originAllowed(origin, host string, allowedOrigins []string) bool {
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, host) {
+		return true
+	}
+	for _, allowed := range allowedOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// headerHasToken reports whether a comma-separated header contains token
+func headerHasToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WriteText sends message as one text frame
+func (c *websocketConn) WriteText(message []byte) error {
+	return c.writeFrame(opText, message)
+}
+
+// writeFrame sends one unfragmented, unmasked frame, as a server's frames are
+func (c *websocketConn) writeFrame(opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode}
+	switch length := len(payload); {
+	case length < 126:
+		header = append(header, byte(length))
+	case length <= 0xFFFF:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(length))
+	default:
+		header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(length))
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(websocketWriteTimeout))
+	if _, err := c.conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// readLoop reads the client's frames until it closes the connection or breaks off,
+// answering pings, then returns. Data frames are discarded.
+func (c *websocketConn) readLoop() {
+	for {
+		opcode, payload, err := c.readFrame()
+		if err != nil {
+			return
+		}
+		switch opcode {
+		case opClose:
+			c.writeFrame(opClose, nil)
+			return
+		case opPing:
+			c.writeFrame(opPong, payload)
+		}
+	}
+}
+
+// readFrame reads one frame from the client and unmasks its payload
+func (c *websocketConn) readFrame() (byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.reader, head[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode := head[0] & 0x0F
+	masked := head[1]&0x80 != 0
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	// Clients must mask their frames
+	if !masked || length > maxClientFrame {
+		return 0, nil, errors.New("invalid client frame")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return opcode, payload, nil
+}
+
+// Close sends a close frame and closes the connection
+func (c *websocketConn) Close() error {
+	c.writeFrame(opClose, nil)
+	return c.conn.Close()
+}
@@ -0,0 +1,129 @@
+// Package stats provides summary statistics shared by the metric calculators.
+// None of the functions modify the slices passed to them.
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// Number is the set of element types the statistics helpers accept
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64
+}
+
+// Sum returns the sum of values as a float64
+func Sum[T Number](values []T) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += float64(v)
+	}
+	return sum
+}
+
+// Mean returns the arithmetic mean of values, or 0 when values is empty
+func Mean[T Number](values []T) float64 {
+	if len(values) == 0 {
+		return 0.0
+	}
+	return Sum(values) / float64(len(values))
+}
+
+// Variance returns the population variance of values, or 0 when values is empty
+func Variance[T Number](values []T) float64 {
+	if len(values) == 0 {
+		return 0.0
+	}
+	mean := Mean(values)
+	sum := 0.0
+	for _, v := range values {
+		diff := float64(v) - mean
+		sum += diff * diff
+	}
+	return sum / float64(len(values))
+}
+
+// Std returns the population standard deviation of values, or 0 when values is empty
+func Std[T Number](values []T) float64 {
+	return math.Sqrt(Variance(values))
+}
+
+// Min returns the smallest of values, or the zero value when values is empty
+func Min[T Number](values []T) T {
+	var min T
+	for i, v := range values {
+		if i == 0 || v < min {
+			min = v
+		}
+	}
+	return min
+}
+
+// Max returns the largest of values, or the zero value when values is empty
+func Max[T Number](values []T) T {
+	var max T
+	for i, v := range values {
+		if i == 0 || v > max {
+			max = v
+		}
+	}
+	return max
+}
+
+// Median returns the median of values, averaging the two middle values when the
+// count is even, or 0 when values is empty
+func Median[T Number](values []T) float64 {
+	return Percentile(values, 50)
+}
+
+// Percentile returns the p-th percentile (0-100) of values using linear
+// interpolation between closest ranks, or 0 when values is empty
+func Percentile[T Number](values []T, p float64) float64 {
+	return Percentiles(values, p)[0]
+}
+
+// Percentiles returns the requested percentiles of values, sorting a copy of the
+// input only once. Percentiles outside 0-100 are clamped.
+func Percentiles[T Number](values []T, ps ...float64) []float64 {
+	results := make([]float64, len(ps))
+	if len(values) == 0 {
+		return results
+	}
+
+	sorted := Sorted(values)
+	for i, p := range ps {
+		results[i] = percentileOfSorted(sorted, p)
+	}
+	return results
+}
+
+// Sorted returns an ascending copy of values converted to float64
+func Sorted[T Number](values []T) []float64 {
+	sorted := make([]float64, len(values))
+	for i, v := range values {
+		sorted[i] = float64(v)
+	}
+	sort.Float64s(sorted)
+	return sorted
+}
+
+// percentileOfSorted interpolates the p-th percentile of ascending values
+func percentileOfSorted(sorted []float64, p float64) float64 {
+	if p <= 0 {
+		return sorted[0]
+	}
+	if p >= 100 {
+		return sorted[len(sorted)-1]
+	}
+
+	rank := p / 100.0 * float64(len(sorted)-1)
+	lower := int(rank)
+	if lower >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+
+	fraction := rank - float64(lower)
+	return sorted[lower] + fraction*(sorted[lower+1]-sorted[lower])
+}
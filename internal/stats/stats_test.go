@@ -0,0 +1,195 @@
+package stats
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+const floatTolerance = 1e-9
+
+func TestSummaryStatistics(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []float64
+		mean     float64
+		std      float64
+		min      float64
+		max      float64
+		median   float64
+		p25, p90 float64
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:   "single value",
+			values: []float64{3},
+			mean:   3, min: 3, max: 3, median: 3, p25: 3, p90: 3,
+		},
+		{
+			// Even count: median averages the two middle values
+			name:   "even count",
+			values: []float64{4, 1, 3, 2},
+			mean:   2.5, std: math.Sqrt(1.25), min: 1, max: 4, median: 2.5, p25: 1.75, p90: 3.7,
+		},
+		{
+			name:   "odd count with negatives",
+			values: []float64{-2, 0, 5},
+			mean:   1, std: math.Sqrt(26.0 / 3.0), min: -2, max: 5, median: 0, p25: -1, p90: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checks := map[string][2]float64{
+				"mean":   {Mean(tt.values), tt.mean},
+				"std":    {Std(tt.values), tt.std},
+				"min":    {Min(tt.values), tt.min},
+				"max":    {Max(tt.values), tt.max},
+				"median": {Median(tt.values), tt.median},
+				"p25":    {Percentile(tt.values, 25), tt.p25},
+				"p90":    {Percentile(tt.values, 90), tt.p90},
+			}
+			for name, check := range checks {
+				if math.Abs(check[0]-check[1]) > floatTolerance {
+					t.Errorf("%s = %v, want %v", name, check[0], check[1])
+				}
+			}
+		})
+	}
+}
+
+func TestIntegerInputs(t *testing.T) {
+	values := []int{5, 1, 4, 2}
+
+	if got := Min(values); got != 1 {
+		t.Errorf("Min = %d, want 1", got)
+	}
+	if got := Max(values); got != 5 {
+		t.Errorf("Max = %d, want 5", got)
+	}
+	if got := Mean(values); got != 3 {
+		t.Errorf("Mean = %v, want 3", got)
+	}
+	// Interpolated rather than truncated to an element
+	if got := Median(values); got != 3 {
+		t.Errorf("Median = %v, want 3", got)
+	}
+	if got := Percentile(values, 50); got != 3 {
+		t.Errorf("Percentile(50) = %v, want 3", got)
+	}
+}
+
+// TestMatchesNaiveReferences checks the helpers against straightforward
+// reference implementations on random inputs, and that inputs are never mutated
+func TestMatchesNaiveReferences(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	percentiles := []float64{0, 10, 25, 33.3, 50, 75, 90, 95, 99, 100}
+
+	for trial := 0; trial < 200; trial++ {
+		n := 1 + rng.Intn(50)
+		ints := make([]int, n)
+		floats := make([]float64, n)
+		for i := range ints {
+			ints[i] = rng.Intn(20) - 5
+			floats[i] = rng.NormFloat64() * 10
+		}
+		originalInts := append([]int(nil), ints...)
+		originalFloats := append([]float64(nil), floats...)
+
+		for _, values := range [][]float64{floats, intsToFloats(ints)} {
+			sorted := append([]float64(nil), values...)
+			sort.Float64s(sorted)
+
+			if got, want := Mean(values), naiveMean(values); math.Abs(got-want) > floatTolerance {
+				t.Fatalf("trial %d: Mean = %v, want %v", trial, got, want)
+			}
+			if got, want := Std(values), naiveStd(values); math.Abs(got-want) > floatTolerance {
+				t.Fatalf("trial %d: Std = %v, want %v", trial, got, want)
+			}
+			if got, want := Min(values), sorted[0]; got != want {
+				t.Fatalf("trial %d: Min = %v, want %v", trial, got, want)
+			}
+			if got, want := Max(values), sorted[len(sorted)-1]; got != want {
+				t.Fatalf("trial %d: Max = %v, want %v", trial, got, want)
+			}
+			if got, want := Median(values), naiveMedian(sorted); math.Abs(got-want) > floatTolerance {
+				t.Fatalf("trial %d: Median = %v, want %v", trial, got, want)
+			}
+
+			got := Percentiles(values, percentiles...)
+			for i, p := range percentiles {
+				want := naivePercentile(sorted, p)
+				if math.Abs(got[i]-want) > floatTolerance {
+					t.Fatalf("trial %d: Percentile(%v) = %v, want %v", trial, p, got[i], want)
+				}
+				if single := Percentile(values, p); single != got[i] {
+					t.Fatalf("trial %d: Percentile(%v) = %v, Percentiles gave %v", trial, p, single, got[i])
+				}
+			}
+		}
+
+		// Integer-typed calls must agree with the float path
+		if got, want := Median(ints), Median(intsToFloats(ints)); got != want {
+			t.Fatalf("trial %d: Median(ints) = %v, want %v", trial, got, want)
+		}
+		if got, want := float64(Max(ints)), Max(intsToFloats(ints)); got != want {
+			t.Fatalf("trial %d: Max(ints) = %v, want %v", trial, got, want)
+		}
+
+		for i := range ints {
+			if ints[i] != originalInts[i] || floats[i] != originalFloats[i] {
+				t.Fatalf("trial %d: input mutated at index %d", trial, i)
+			}
+		}
+	}
+}
+
+func intsToFloats(values []int) []float64 {
+	floats := make([]float64, len(values))
+	for i, v := range values {
+		floats[i] = float64(v)
+	}
+	return floats
+}
+
+func naiveMean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func naiveStd(values []float64) float64 {
+	mean := naiveMean(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += math.Pow(v-mean, 2)
+	}
+	return math.Sqrt(sum / float64(len(values)))
+}
+
+func naiveMedian(sorted []float64) float64 {
+	n := len(sorted)
+	if n%2 == 0 {
+		return (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	return sorted[n/2]
+}
+
+// naivePercentile scans for the two closest ranks and interpolates between them
+func naivePercentile(sorted []float64, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	for i := range sorted {
+		if float64(i) == rank {
+			return sorted[i]
+		}
+		if float64(i) < rank && rank < float64(i+1) {
+			return sorted[i] + (rank-float64(i))*(sorted[i+1]-sorted[i])
+		}
+	}
+	return sorted[len(sorted)-1]
+}
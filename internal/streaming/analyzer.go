@@ -2,15 +2,40 @@ package streaming
 
 import (
 	"bufio"
+	"bytes"
+	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+	"github.com/RevBooyah/TokEntropyDrift/internal/stats"
 	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
 )
 
+// ChunkMode selects how the lines of a chunk become documents
+type ChunkMode string
+
+const (
+	// ChunkModeConcatenate analyzes all lines of a chunk as one document
+	ChunkModeConcatenate ChunkMode = "concatenate"
+	// ChunkModePerLine analyzes each non-blank line as a document, like the loader
+	ChunkModePerLine ChunkMode = "per_line"
+	// ChunkModeDelimiter analyzes the text between delimiter lines as a document;
+	// ChunkSize then counts documents rather than lines
+	ChunkModeDelimiter ChunkMode = "delimiter"
+)
+
+// Chunk weights for StreamConfig.WeightBy
+const (
+	WeightByTokens = "tokens"
+	WeightByLines  = "lines"
+)
+
 // StreamConfig holds configuration for streaming analysis
 type StreamConfig struct {
 	ChunkSize        int           `json:"chunk_size"`        // Number of lines per chunk
@@ -19,30 +44,84 @@ type StreamConfig struct {
 	EnableProgress   bool          `json:"enable_progress"`   // Whether to show progress updates
 	ProgressInterval int           `json:"progress_interval"` // Progress update interval in chunks
 	Timeout          time.Duration `json:"timeout"`           // Timeout for processing
+
+	// RetainChunkResults keeps every chunk result in StreamResult.ChunkResults, up to
+	// MaxMemoryMB of them; otherwise only aggregates stay in memory
+	RetainChunkResults bool `json:"retain_chunk_results"`
+
+	ChunkMode ChunkMode `json:"chunk_mode"` // How chunk lines become documents; defaults to concatenate
+	Delimiter string    `json:"delimiter"`  // Document separator line in delimiter mode; empty means a blank line
+	WeightBy  string    `json:"weight_by"`  // Chunk weight for weighted means: tokens (default) or lines
+
+	Drift DriftConfig `json:"drift"` // Per-chunk drift against a baseline distribution
 }
 
 // StreamResult represents the result of streaming analysis
 type StreamResult struct {
-	TotalChunks       int                       `json:"total_chunks"`
-	ProcessedChunks   int                       `json:"processed_chunks"`
-	FailedChunks      int                       `json:"failed_chunks"`
-	TotalLines        int                       `json:"total_lines"`
-	ProcessedLines    int                       `json:"processed_lines"`
-	StartTime         time.Time                 `json:"start_time"`
-	EndTime           time.Time                 `json:"end_time"`
-	Duration          time.Duration             `json:"duration"`
-	ChunkResults      []*metrics.AnalysisResult `json:"chunk_results"`
-	AggregatedMetrics map[string]float64        `json:"aggregated_metrics"`
-	Errors            []string                  `json:"errors"`
+	TotalChunks       int                        `json:"total_chunks"`
+	ProcessedChunks   int                        `json:"processed_chunks"`
+	FailedChunks      int                        `json:"failed_chunks"`
+	TotalLines        int                        `json:"total_lines"`
+	TotalDocuments    int                        `json:"total_documents"` // documents analyzed in processed chunks
+	BytesRead         int64                      `json:"bytes_read"`      // bytes read from the input, after decompression
+	ProcessedLines    int                        `json:"processed_lines"`
+	StartTime         time.Time                  `json:"start_time"`
+	EndTime           time.Time                  `json:"end_time"`
+	Duration          time.Duration              `json:"duration"`
+	ChunkResults      []*metrics.AnalysisResult  `json:"chunk_results"`
+	AggregatedMetrics map[string]MetricAggregate `json:"aggregated_metrics"`
+	Drift             []ChunkDrift               `json:"drift,omitempty"` // one entry per chunk compared with the baseline
+	Summary           StreamSummary              `json:"summary"`
+	Errors            []string                   `json:"errors"`
+
+	// Truncated is set when retaining another chunk result would have exceeded
+	// MaxMemoryMB; ChunkResults then holds only the first chunks
+	Truncated     bool  `json:"truncated,omitempty"`
+	RetainedBytes int64 `json:"retained_bytes,omitempty"` // estimated size of ChunkResults
 }
 
+// StdinPath is the input path that reads standard input
+const StdinPath = "-"
+
+// ReadProgressCallback is called with the bytes read so far and the read rate, for
+// input whose total size is unknown
+type ReadProgressCallback func(bytesRead int64, bytesPerSecond float64, duration time.Duration)
+
 // ProgressCallback is called to report progress during streaming analysis
 type ProgressCallback func(chunk int, total int, lines int, duration time.Duration)
 
+// MetricAggregate summarizes one metric over a stream. Mean averages over documents;
+// WeightedMean weights each chunk by its tokens or lines; the spread is across chunks.
+type MetricAggregate struct {
+	Chunks       int     `json:"chunks"`
+	Documents    int     `json:"documents"`
+	Mean         float64 `json:"mean"`
+	WeightedMean float64 `json:"weighted_mean"`
+	Std          float64 `json:"std"`
+	Min          float64 `json:"min"`
+	P25          float64 `json:"p25"`
+	P50          float64 `json:"p50"`
+	P75          float64 `json:"p75"`
+	P90          float64 `json:"p90"`
+	P95          float64 `json:"p95"`
+	Max          float64 `json:"max"`
+}
+
+// StreamSummary holds the bookkeeping of a streaming run
+type StreamSummary struct {
+	SuccessRate float64 `json:"success_rate"` // processed chunks as a percentage of all chunks
+	TotalTokens int     `json:"total_tokens"` // tokens in processed chunks
+	WeightBy    string  `json:"weight_by"`
+
+	BytesPerSecond float64 `json:"bytes_per_second"`         // read rate over the whole run
+	FlaggedChunks  int     `json:"flagged_chunks,omitempty"` // chunks whose drift exceeded the z-score threshold
+}
+
 // StreamAnalyzer provides streaming analysis capabilities
 type StreamAnalyzer struct {
-	config StreamConfig
-	engine *metrics.Engine
+	config       StreamConfig
+	engine       *metrics.Engine
+	readProgress ReadProgressCallback
 }
 
 // NewStreamAnalyzer creates a new streaming analyzer
@@ -60,6 +139,18 @@ func NewStreamAnalyzer(config StreamConfig, engine *metrics.Engine) *StreamAnaly
 	if config.ProgressInterval <= 0 {
 		config.ProgressInterval = 10
 	}
+	if config.ChunkMode == "" {
+		config.ChunkMode = ChunkModeConcatenate
+	}
+	if config.WeightBy == "" {
+		config.WeightBy = WeightByTokens
+	}
+	if config.Drift.BaselineChunks <= 0 {
+		config.Drift.BaselineChunks = 10
+	}
+	if config.Drift.ZThreshold <= 0 {
+		config.Drift.ZThreshold = 3
+	}
 
 	return &StreamAnalyzer{
 		config: config,
@@ -75,72 +166,162 @@ func (s *StreamAnalyzer) AnalyzeStream(
 	progressCallback ProgressCallback,
 ) (*StreamResult, error) {
 
+	return s.AnalyzeStreamTo(ctx, reader, tokenizer, nil, progressCallback)
+}
+
+// AnalyzeStreamTo analyzes a stream of text data, handing each chunk result to sink
+// as soon as it is produced. sink may be nil; the caller closes it.
+func (s *StreamAnalyzer) AnalyzeStreamTo(
+	ctx context.Context,
+	reader io.Reader,
+	tokenizer tokenizers.Tokenizer,
+	sink ChunkSink,
+	progressCallback ProgressCallback,
+) (*StreamResult, error) {
+
 	result := &StreamResult{
 		StartTime:         time.Now(),
 		ChunkResults:      make([]*metrics.AnalysisResult, 0),
-		AggregatedMetrics: make(map[string]float64),
+		AggregatedMetrics: make(map[string]MetricAggregate),
 		Errors:            make([]string, 0),
 	}
+	aggregate := newStreamAggregate()
+	var drift *driftTracker
+	if s.config.Drift.Enabled {
+		drift = newDriftTracker(s.config.Drift)
+	}
 
-	// Create buffered reader
-	bufReader := bufio.NewReaderSize(reader, s.config.BufferSize)
+	// Create buffered reader, counting the bytes it takes from the input
+	counter := &countingReader{reader: reader}
+	bufReader := bufio.NewReaderSize(counter, s.config.BufferSize)
 
 	// Process chunks
 	chunkNum := 0
 	lineCount := 0
 
+	// finish records the totals and aggregates of the chunks processed so far
+	finish := func() {
+		result.TotalChunks = chunkNum
+		result.TotalLines = lineCount
+		result.EndTime = time.Now()
+		result.Duration = result.EndTime.Sub(result.StartTime)
+		result.BytesRead = counter.n
+		s.aggregateMetrics(result, aggregate)
+	}
+
 	for {
-		select {
-		case <-ctx.Done():
-			return result, ctx.Err()
-		default:
-			// Read chunk
-			chunk, err := s.readChunk(bufReader)
-			if err == io.EOF {
-				break
+		if err := ctx.Err(); err != nil {
+			finish()
+			return result, err
+		}
+
+		// Read chunk
+		chunk, lines, err := s.readChunk(bufReader)
+		if err != nil {
+			// A broken stream fails the same way on every read, so stop here
+			result.Errors = append(result.Errors, fmt.Sprintf("Error reading chunk %d: %v", chunkNum, err))
+			result.FailedChunks++
+			chunkNum++
+			lineCount += lines
+			finish()
+			return result, fmt.Errorf("error reading chunk %d: %w", chunkNum-1, err)
+		}
+		if len(chunk) == 0 {
+			// Trailing delimiter lines hold no document
+			lineCount += lines
+			break
+		}
+
+		// Process chunk
+		analysis, err := s.processChunk(ctx, chunk, tokenizer, chunkNum)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Error processing chunk %d: %v", chunkNum, err))
+			result.FailedChunks++
+		} else {
+			chunkResult := analysis.result
+			weight := chunkResult.TokenCount
+			if s.config.WeightBy == WeightByLines {
+				weight = lines
 			}
-			if err != nil {
-				result.Errors = append(result.Errors, fmt.Sprintf("Error reading chunk %d: %v", chunkNum, err))
-				result.FailedChunks++
-				chunkNum++
-				continue
+			aggregate.add(chunkResult, analysis.documents, weight)
+			result.ProcessedChunks++
+			result.TotalDocuments += analysis.documents
+
+			if drift != nil {
+				if chunkDrift, compared := drift.observe(chunkNum, lineCount+1, analysis.tokenCounts); compared {
+					result.Drift = append(result.Drift, chunkDrift)
+				}
 			}
 
-			if len(chunk) == 0 {
-				break
+			if sink != nil {
+				if err := sink.WriteChunk(chunkResult); err != nil {
+					result.Errors = append(result.Errors, fmt.Sprintf("Error writing chunk %d: %v", chunkNum, err))
+					chunkNum++
+					lineCount += lines
+					finish()
+					return result, fmt.Errorf("error writing chunk %d: %w", chunkNum-1, err)
+				}
 			}
-
-			// Process chunk
-			chunkResult, err := s.processChunk(ctx, chunk, tokenizer, chunkNum)
-			if err != nil {
-				result.Errors = append(result.Errors, fmt.Sprintf("Error processing chunk %d: %v", chunkNum, err))
-				result.FailedChunks++
-			} else {
-				result.ChunkResults = append(result.ChunkResults, chunkResult)
-				result.ProcessedChunks++
+			if s.config.RetainChunkResults {
+				s.retain(result, chunkResult)
 			}
+		}
 
-			lineCount += len(chunk)
-			chunkNum++
+		lineCount += lines
+		chunkNum++
 
-			// Report progress
-			if s.config.EnableProgress && progressCallback != nil && chunkNum%s.config.ProgressInterval == 0 {
-				progressCallback(chunkNum, -1, lineCount, time.Since(result.StartTime))
+		// Report progress
+		if s.config.EnableProgress && chunkNum%s.config.ProgressInterval == 0 {
+			elapsed := time.Since(result.StartTime)
+			if progressCallback != nil {
+				progressCallback(chunkNum, -1, lineCount, elapsed)
+			}
+			if s.readProgress != nil {
+				s.readProgress(counter.n, float64(counter.n)/elapsed.Seconds(), elapsed)
 			}
 		}
 	}
 
-	result.TotalChunks = chunkNum
-	result.TotalLines = lineCount
-	result.EndTime = time.Now()
-	result.Duration = result.EndTime.Sub(result.StartTime)
-
-	// Aggregate metrics
-	s.aggregateMetrics(result)
+	finish()
 
 	return result, nil
 }
 
+// retain keeps chunkResult in result.ChunkResults unless that would take the
+// retained results past MaxMemoryMB, in which case the result is marked truncated
+// and no further chunks are kept
+func (s *StreamAnalyzer) retain(result *StreamResult, chunkResult *metrics.AnalysisResult) {
+	if result.Truncated {
+		return
+	}
+
+	size := estimateResultBytes(chunkResult)
+	if result.RetainedBytes+size > int64(s.config.MaxMemoryMB)<<20 {
+		result.Truncated = true
+		return
+	}
+	result.ChunkResults = append(result.ChunkResults, chunkResult)
+	result.RetainedBytes += size
+}
+
+// estimateResultBytes roughly estimates the memory held by an analysis result: its
+// text, its tokens and its metrics
+func estimateResultBytes(result *metrics.AnalysisResult) int64 {
+	const (
+		tokenOverhead  = 64  // Token struct and slice slot
+		metricOverhead = 128 // map entry, MetricResult and names
+	)
+
+	size := int64(len(result.Document))
+	if result.Tokenization != nil {
+		for _, token := range result.Tokenization.Tokens {
+			size += int64(len(token.Text)) + tokenOverhead
+		}
+	}
+	size += int64(len(result.Metrics)) * metricOverhead
+	return size
+}
+
 // AnalyzeFile analyzes a file using streaming
 func (s *StreamAnalyzer) AnalyzeFile(
 	ctx context.Context,
@@ -149,106 +330,292 @@ func (s *StreamAnalyzer) AnalyzeFile(
 	progressCallback ProgressCallback,
 ) (*StreamResult, error) {
 
+	return s.AnalyzeFileTo(ctx, filePath, tokenizer, nil, progressCallback)
+}
+
+// AnalyzeFileTo analyzes a file using streaming, handing each chunk result to sink
+func (s *StreamAnalyzer) AnalyzeFileTo(
+	ctx context.Context,
+	filePath string,
+	tokenizer tokenizers.Tokenizer,
+	sink ChunkSink,
+	progressCallback ProgressCallback,
+) (*StreamResult, error) {
+
 	file, err := openFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("error opening file %s: %w", filePath, err)
 	}
 	defer file.Close()
 
-	return s.AnalyzeStream(ctx, file, tokenizer, progressCallback)
+	return s.AnalyzeStreamTo(ctx, file, tokenizer, sink, progressCallback)
 }
 
-// readChunk reads a chunk of lines from the reader
-func (s *StreamAnalyzer) readChunk(reader *bufio.Reader) ([]string, error) {
+// readChunk reads the next chunk from the reader and the number of lines it took.
+// The chunk holds up to ChunkSize lines, or in delimiter mode up to ChunkSize
+// documents with their lines joined.
+func (s *StreamAnalyzer) readChunk(reader *bufio.Reader) ([]string, int, error) {
 	var chunk []string
+	var document []string
+	lines := 0
 
 	for len(chunk) < s.config.ChunkSize {
 		line, err := reader.ReadString('\n')
-		if err == io.EOF {
-			if line != "" {
-				chunk = append(chunk, line)
-			}
-			break
+		if err != nil && err != io.EOF {
+			return chunk, lines, err
 		}
-		if err != nil {
-			return chunk, err
+		if line == "" {
+			break
 		}
+		lines++
 
 		// Remove trailing newline
 		if len(line) > 0 && line[len(line)-1] == '\n' {
 			line = line[:len(line)-1]
 		}
 
-		chunk = append(chunk, line)
+		if s.config.ChunkMode != ChunkModeDelimiter {
+			chunk = append(chunk, line)
+		} else if strings.TrimSpace(line) == strings.TrimSpace(s.config.Delimiter) {
+			if len(document) > 0 {
+				chunk = append(chunk, strings.Join(document, "\n"))
+				document = nil
+			}
+		} else {
+			document = append(document, line)
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	// The stream ended inside a document
+	if len(document) > 0 {
+		chunk = append(chunk, strings.Join(document, "\n"))
 	}
 
-	return chunk, nil
+	return chunk, lines, nil
+}
+
+// chunkAnalysis is the analysis of one chunk
+type chunkAnalysis struct {
+	result      *metrics.AnalysisResult
+	documents   int
+	tokenCounts map[string]int // occurrences of each token, kept for drift detection
 }
 
-// processChunk processes a single chunk of text
+// processChunk processes a single chunk of text. In per_line and delimiter mode the
+// chunk result's metrics are the means over its documents.
 func (s *StreamAnalyzer) processChunk(
 	ctx context.Context,
 	chunk []string,
 	tokenizer tokenizers.Tokenizer,
 	chunkNum int,
-) (*metrics.AnalysisResult, error) {
-
-	// Combine chunk lines into a single document
-	document := ""
-	for i, line := range chunk {
-		if i > 0 {
-			document += "\n"
+) (*chunkAnalysis, error) {
+
+	var texts []string
+	switch s.config.ChunkMode {
+	case ChunkModeConcatenate:
+		// Combine chunk lines into a single document
+		texts = []string{strings.Join(chunk, "\n")}
+
+	case ChunkModePerLine, ChunkModeDelimiter:
+		texts = make([]string, 0, len(chunk))
+		for _, text := range chunk {
+			// Blank lines are not documents, as in the loader
+			if text = strings.TrimSpace(text); text != "" {
+				texts = append(texts, text)
+			}
 		}
-		document += line
+
+	default:
+		return nil, fmt.Errorf("unknown chunk mode %q", s.config.ChunkMode)
 	}
 
-	// Analyze the chunk
-	result, err := s.engine.AnalyzeDocument(ctx, document, tokenizer)
-	if err != nil {
-		return nil, err
+	analysis := &chunkAnalysis{documents: len(texts)}
+	if s.config.Drift.Enabled {
+		analysis.tokenCounts = make(map[string]int)
+	}
+
+	if s.config.ChunkMode == ChunkModeConcatenate {
+		result, err := s.engine.AnalyzeDocument(ctx, texts[0], tokenizer)
+		if err != nil {
+			return nil, err
+		}
+		analysis.countTokens(result)
+		analysis.result = result
+	} else {
+		result, err := s.analyzeDocuments(ctx, texts, tokenizer, analysis)
+		if err != nil {
+			return nil, err
+		}
+		analysis.result = result
 	}
 
 	// Add chunk metadata
+	result := analysis.result
 	if result.Metadata == nil {
 		result.Metadata = make(map[string]interface{})
 	}
 	result.Metadata["chunk_number"] = chunkNum
 	result.Metadata["chunk_size"] = len(chunk)
-	result.Metadata["chunk_lines"] = chunk
+	result.Metadata["documents"] = analysis.documents
 
-	return result, nil
+	return analysis, nil
 }
 
-// aggregateMetrics aggregates metrics across all chunks
-func (s *StreamAnalyzer) aggregateMetrics(result *StreamResult) {
-	if len(result.ChunkResults) == 0 {
+// countTokens adds the tokens of a document's analysis to the chunk's token counts,
+// if they are kept
+func (c *chunkAnalysis) countTokens(result *metrics.AnalysisResult) {
+	if c.tokenCounts == nil || result.Tokenization == nil {
 		return
 	}
+	for _, token := range result.Tokenization.Tokens {
+		c.tokenCounts[token.Text]++
+	}
+}
+
+// analyzeDocuments analyzes each text as a document and combines the analyses into
+// one result holding the texts, their total token count and their mean metrics. Not
+// every metric applies to every document, so each metric's metadata records how many
+// documents its mean covers.
+func (s *StreamAnalyzer) analyzeDocuments(
+	ctx context.Context,
+	texts []string,
+	tokenizer tokenizers.Tokenizer,
+	chunk *chunkAnalysis,
+) (*metrics.AnalysisResult, error) {
+
+	result := &metrics.AnalysisResult{
+		Document:      strings.Join(texts, "\n"),
+		TokenizerName: tokenizer.Name(),
+		Metrics:       make(map[string]metrics.MetricResult),
+	}
+
+	sums := make(map[string]float64)
+	counts := make(map[string]int)
+	for i, text := range texts {
+		analysis, err := s.engine.AnalyzeDocument(ctx, text, tokenizer)
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %w", i, err)
+		}
+		result.TokenCount += analysis.TokenCount
+		chunk.countTokens(analysis)
+		for metricName, metric := range analysis.Metrics {
+			sums[metricName] += metric.Value
+			counts[metricName]++
+		}
+	}
+
+	for metricName, sum := range sums {
+		result.Metrics[metricName] = metrics.MetricResult{
+			MetricName:    metricName,
+			TokenizerName: result.TokenizerName,
+			Value:         sum / float64(counts[metricName]),
+			Metadata:      map[string]interface{}{"documents": counts[metricName]},
+		}
+	}
+
+	return result, nil
+}
+
+// streamAggregate keeps running sums of the chunk metrics, so chunk results need
+// not stay in memory to be aggregated. Only one value per metric and chunk is kept,
+// for the spread across chunks.
+type streamAggregate struct {
+	metrics     map[string]*metricAccumulator
+	totalTokens int
+}
+
+// metricAccumulator accumulates one metric over the chunks
+type metricAccumulator struct {
+	documents   int
+	documentSum float64 // values times the documents they cover
+	weight      float64
+	weightedSum float64 // values times their chunk's weight
+	values      []float64
+}
+
+// newStreamAggregate creates an empty aggregate
+func newStreamAggregate() *streamAggregate {
+	return &streamAggregate{
+		metrics: make(map[string]*metricAccumulator),
+	}
+}
 
-	// Initialize aggregation maps
-	metricSums := make(map[string]float64)
-	metricCounts := make(map[string]int)
+// add adds the metrics of one chunk of documents with the given weight. A metric
+// covering fewer documents than the chunk says so in its metadata.
+func (a *streamAggregate) add(chunkResult *metrics.AnalysisResult, documents, weight int) {
+	a.totalTokens += chunkResult.TokenCount
 
-	// Aggregate metrics from all chunks
-	for _, chunkResult := range result.ChunkResults {
-		for metricName, metric := range chunkResult.Metrics {
-			metricSums[metricName] += metric.Value
-			metricCounts[metricName]++
+	for metricName, metric := range chunkResult.Metrics {
+		covered := documents
+		if n, ok := metric.Metadata["documents"].(int); ok {
+			covered = n
 		}
+
+		acc := a.metrics[metricName]
+		if acc == nil {
+			acc = &metricAccumulator{}
+			a.metrics[metricName] = acc
+		}
+		acc.documents += covered
+		acc.documentSum += metric.Value * float64(covered)
+		acc.weight += float64(weight)
+		acc.weightedSum += metric.Value * float64(weight)
+		acc.values = append(acc.values, metric.Value)
+	}
+}
+
+// aggregate summarizes the accumulated metric
+func (acc *metricAccumulator) aggregate() MetricAggregate {
+	percentiles := stats.Percentiles(acc.values, 25, 50, 75, 90, 95)
+
+	result := MetricAggregate{
+		Chunks:    len(acc.values),
+		Documents: acc.documents,
+		Std:       stats.Std(acc.values),
+		Min:       stats.Min(acc.values),
+		P25:       percentiles[0],
+		P50:       percentiles[1],
+		P75:       percentiles[2],
+		P90:       percentiles[3],
+		P95:       percentiles[4],
+		Max:       stats.Max(acc.values),
+	}
+	if acc.documents > 0 {
+		result.Mean = acc.documentSum / float64(acc.documents)
 	}
+	if acc.weight > 0 {
+		result.WeightedMean = acc.weightedSum / acc.weight
+	} else {
+		result.WeightedMean = result.Mean
+	}
+	return result
+}
 
-	// Calculate averages
-	for metricName, sum := range metricSums {
-		if count := metricCounts[metricName]; count > 0 {
-			result.AggregatedMetrics[metricName] = sum / float64(count)
+// aggregateMetrics aggregates metrics across all chunks
+func (s *StreamAnalyzer) aggregateMetrics(result *StreamResult, aggregate *streamAggregate) {
+	result.Summary = StreamSummary{
+		TotalTokens: aggregate.totalTokens,
+		WeightBy:    s.config.WeightBy,
+	}
+	if result.Duration > 0 {
+		result.Summary.BytesPerSecond = float64(result.BytesRead) / result.Duration.Seconds()
+	}
+	for _, drift := range result.Drift {
+		if drift.Flagged {
+			result.Summary.FlaggedChunks++
 		}
 	}
+	if result.TotalChunks > 0 {
+		result.Summary.SuccessRate = float64(result.ProcessedChunks) / float64(result.TotalChunks) * 100
+	}
 
-	// Add summary metrics
-	result.AggregatedMetrics["total_chunks"] = float64(result.TotalChunks)
-	result.AggregatedMetrics["processed_chunks"] = float64(result.ProcessedChunks)
-	result.AggregatedMetrics["failed_chunks"] = float64(result.FailedChunks)
-	result.AggregatedMetrics["success_rate"] = float64(result.ProcessedChunks) / float64(result.TotalChunks) * 100
+	for metricName, acc := range aggregate.metrics {
+		result.AggregatedMetrics[metricName] = acc.aggregate()
+	}
 }
 
 // GetConfig returns the current configuration
@@ -261,9 +628,86 @@ func (s *StreamAnalyzer) SetConfig(config StreamConfig) {
 	s.config = config
 }
 
-// openFile opens a file for reading (placeholder for actual implementation)
+// SetReadProgressCallback sets a callback reporting the bytes read, alongside the
+// chunk progress. It suits standard input, where no percentage can be given.
+func (s *StreamAnalyzer) SetReadProgressCallback(callback ReadProgressCallback) {
+	s.readProgress = callback
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+// Read reads from the underlying reader and counts the bytes
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// gzipMagic and zstdMagic are the first bytes of gzip and zstd streams
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// openFile opens a file for reading, or standard input for StdinPath. Gzip input is
+// decompressed transparently; it is recognized by its magic bytes, so the extension
+// does not matter.
 func openFile(filePath string) (io.ReadCloser, error) {
-	// This would be implemented to actually open files
-	// For now, return an error to indicate it needs implementation
-	return nil, fmt.Errorf("file opening not implemented yet")
+	file := os.Stdin
+	if filePath != StdinPath {
+		var err error
+		if file, err = os.Open(filePath); err != nil {
+			return nil, err
+		}
+	}
+
+	// A short file peeks fewer bytes and is simply not compressed
+	buffered := bufio.NewReader(file)
+	input := &inputFile{Reader: buffered, file: file}
+	magic, _ := buffered.Peek(len(zstdMagic))
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			input.Close()
+			return nil, fmt.Errorf("failed to read gzip header: %w", err)
+		}
+		input.Reader, input.decompressor = gz, gz
+	case bytes.HasPrefix(magic, zstdMagic):
+		input.Close()
+		return nil, fmt.Errorf("zstd compressed input is not supported; decompress it first, e.g. with zstd -d")
+	case strings.EqualFold(filepath.Ext(filePath), ".gz"):
+		input.Close()
+		return nil, fmt.Errorf("file has a .gz extension but is not gzip data")
+	}
+
+	return input, nil
+}
+
+// inputFile reads an opened file, through a decompressor for compressed input, and
+// closes both
+type inputFile struct {
+	io.Reader
+	decompressor io.Closer
+	file         *os.File
+}
+
+// Close closes the decompressor, if any, and the file. Standard input stays open.
+func (f *inputFile) Close() error {
+	var err error
+	if f.decompressor != nil {
+		err = f.decompressor.Close()
+	}
+	if f.file == os.Stdin {
+		return err
+	}
+	if closeErr := f.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
 }
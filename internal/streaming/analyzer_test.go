@@ -0,0 +1,552 @@
+package streaming
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+	"github.com/RevBooyah/TokEntropyDrift/internal/stats"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// newTestAnalyzer returns an analyzer with small chunks and a whitespace tokenizer
+func newTestAnalyzer(t *testing.T, config StreamConfig) (*StreamAnalyzer, tokenizers.Tokenizer) {
+	t.Helper()
+	tokenizer := tokenizers.NewWhitespaceTokenizer("words")
+	if err := tokenizer.Initialize(tokenizers.TokenizerConfig{Name: "words", Type: "custom"}); err != nil {
+		t.Fatalf("Initialize returned error: %v", err)
+	}
+	return NewStreamAnalyzer(config, metrics.NewEngine(metrics.EngineConfig{EntropyWindowSize: 10})), tokenizer
+}
+
+// sampleCorpus returns lines lines of distinguishable text
+func sampleCorpus(lines int) string {
+	var b strings.Builder
+	for i := 0; i < lines; i++ {
+		fmt.Fprintf(&b, "line %d has %s words\n", i, strings.Repeat("many ", i%4))
+	}
+	return b.String()
+}
+
+func writeGzip(t *testing.T, path, content string) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create %s: %v", path, err)
+	}
+	defer file.Close()
+	writer := gzip.NewWriter(file)
+	if _, err := writer.Write([]byte(content)); err != nil {
+		t.Fatalf("failed to write gzip data: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("failed to finish gzip data: %v", err)
+	}
+}
+
+// assertSameAggregates compares aggregates, allowing for entropy summing over maps
+// in any order
+func assertSameAggregates(t *testing.T, got, want map[string]MetricAggregate) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d aggregated metrics, want %d", len(got), len(want))
+	}
+	for name, w := range want {
+		g := got[name]
+		if g.Chunks != w.Chunks || g.Documents != w.Documents {
+			t.Errorf("aggregated %s covers %d chunks and %d documents, want %d and %d", name, g.Chunks, g.Documents, w.Chunks, w.Documents)
+		}
+		for _, field := range []struct {
+			name      string
+			got, want float64
+		}{
+			{"mean", g.Mean, w.Mean},
+			{"weighted mean", g.WeightedMean, w.WeightedMean},
+			{"std", g.Std, w.Std},
+			{"p50", g.P50, w.P50},
+		} {
+			if math.Abs(field.got-field.want) > 1e-9 {
+				t.Errorf("aggregated %s %s = %v, want %v", name, field.name, field.got, field.want)
+			}
+		}
+	}
+}
+
+func TestAnalyzeFilePlainAndGzip(t *testing.T) {
+	dir := t.TempDir()
+	corpus := sampleCorpus(23)
+
+	plainPath := filepath.Join(dir, "corpus.txt")
+	if err := os.WriteFile(plainPath, []byte(corpus), 0644); err != nil {
+		t.Fatalf("failed to write corpus: %v", err)
+	}
+	gzipPath := filepath.Join(dir, "corpus.txt.gz")
+	writeGzip(t, gzipPath, corpus)
+	// Detection uses the magic bytes, not the extension
+	renamedPath := filepath.Join(dir, "corpus.data")
+	writeGzip(t, renamedPath, corpus)
+
+	analyzer, tokenizer := newTestAnalyzer(t, StreamConfig{ChunkSize: 5, RetainChunkResults: true})
+	plain, err := analyzer.AnalyzeFile(context.Background(), plainPath, tokenizer, nil)
+	if err != nil {
+		t.Fatalf("AnalyzeFile returned error for the plain file: %v", err)
+	}
+	if plain.TotalLines != 23 || plain.TotalChunks != 5 || plain.ProcessedChunks != 5 {
+		t.Fatalf("plain result has %d lines in %d chunks (%d processed), want 23 in 5",
+			plain.TotalLines, plain.TotalChunks, plain.ProcessedChunks)
+	}
+
+	for _, path := range []string{gzipPath, renamedPath} {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			compressed, err := analyzer.AnalyzeFile(context.Background(), path, tokenizer, nil)
+			if err != nil {
+				t.Fatalf("AnalyzeFile returned error: %v", err)
+			}
+			if compressed.TotalLines != plain.TotalLines || len(compressed.ChunkResults) != len(plain.ChunkResults) {
+				t.Fatalf("got %d lines in %d chunks, want %d in %d", compressed.TotalLines, len(compressed.ChunkResults),
+					plain.TotalLines, len(plain.ChunkResults))
+			}
+			for i, chunk := range compressed.ChunkResults {
+				want := plain.ChunkResults[i]
+				if chunk.Document != want.Document || chunk.TokenCount != want.TokenCount {
+					t.Errorf("chunk %d = %q (%d tokens), want %q (%d tokens)", i, chunk.Document, chunk.TokenCount, want.Document, want.TokenCount)
+				}
+			}
+			assertSameAggregates(t, compressed.AggregatedMetrics, plain.AggregatedMetrics)
+		})
+	}
+}
+
+func TestOpenFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	zstdPath := filepath.Join(dir, "corpus.zst")
+	if err := os.WriteFile(zstdPath, []byte{0x28, 0xb5, 0x2f, 0xfd, 0x00}, 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	fakeGzipPath := filepath.Join(dir, "corpus.gz")
+	if err := os.WriteFile(fakeGzipPath, []byte("plain text"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	truncatedPath := filepath.Join(dir, "truncated.gz")
+	if err := os.WriteFile(truncatedPath, []byte{0x1f, 0x8b}, 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{"missing file", filepath.Join(dir, "missing.txt"), "no such file"},
+		{"zstd", zstdPath, "zstd compressed input is not supported"},
+		{"gz extension without gzip data", fakeGzipPath, "not gzip data"},
+		{"truncated gzip header", truncatedPath, "gzip header"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := openFile(tt.path)
+			if err == nil {
+				file.Close()
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %q does not mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAnalyzeFileCorruptGzip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "corpus.gz")
+	writeGzip(t, path, sampleCorpus(50))
+
+	// Truncate the stream after its header and part of the data
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if err := os.WriteFile(path, data[:len(data)/2], 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	analyzer, tokenizer := newTestAnalyzer(t, StreamConfig{ChunkSize: 5})
+	result, err := analyzer.AnalyzeFile(context.Background(), path, tokenizer, nil)
+	if err == nil {
+		t.Fatal("expected an error for a truncated gzip stream")
+	}
+	if result == nil || len(result.Errors) != 1 || result.FailedChunks != 1 {
+		t.Errorf("result = %+v, want the read error recorded once", result)
+	}
+}
+
+// failingSink fails every write after the first limit
+type failingSink struct {
+	limit   int
+	written int
+}
+
+func (f *failingSink) WriteChunk(result *metrics.AnalysisResult) error {
+	if f.written == f.limit {
+		return errors.New("disk full")
+	}
+	f.written++
+	return nil
+}
+
+func (f *failingSink) Close() error { return nil }
+
+func TestAnalyzeStreamToSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chunks.jsonl")
+	sink, err := NewJSONLFileSink(path)
+	if err != nil {
+		t.Fatalf("NewJSONLFileSink returned error: %v", err)
+	}
+
+	analyzer, tokenizer := newTestAnalyzer(t, StreamConfig{ChunkSize: 4})
+	result, err := analyzer.AnalyzeStreamTo(context.Background(), strings.NewReader(sampleCorpus(18)), tokenizer, sink, nil)
+	if err != nil {
+		t.Fatalf("AnalyzeStreamTo returned error: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	if len(result.ChunkResults) != 0 || result.Truncated {
+		t.Errorf("retained %d chunk results, want none by default", len(result.ChunkResults))
+	}
+	if result.ProcessedChunks != 5 || result.Summary.SuccessRate != 100 {
+		t.Errorf("processed %d chunks (%v%%), want all 5", result.ProcessedChunks, result.Summary.SuccessRate)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open chunk results: %v", err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	chunks := 0
+	for ; scanner.Scan(); chunks++ {
+		var chunk metrics.AnalysisResult
+		if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+			t.Fatalf("line %d is not a chunk result: %v", chunks+1, err)
+		}
+		if number, _ := chunk.Metadata["chunk_number"].(float64); int(number) != chunks {
+			t.Errorf("line %d holds chunk %v", chunks+1, chunk.Metadata["chunk_number"])
+		}
+		if len(chunk.Metrics) == 0 {
+			t.Errorf("line %d has no metrics", chunks+1)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("failed to read chunk results: %v", err)
+	}
+	if chunks != 5 {
+		t.Errorf("sink holds %d chunks, want 5", chunks)
+	}
+}
+
+func TestAnalyzeStreamSinkError(t *testing.T) {
+	analyzer, tokenizer := newTestAnalyzer(t, StreamConfig{ChunkSize: 4})
+	result, err := analyzer.AnalyzeStreamTo(context.Background(), strings.NewReader(sampleCorpus(18)), tokenizer, &failingSink{limit: 2}, nil)
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Fatalf("AnalyzeStreamTo returned %v, want the sink's error", err)
+	}
+	if result.TotalChunks != 3 || len(result.Errors) != 1 {
+		t.Errorf("result has %d chunks and errors %v, want 3 chunks and one error", result.TotalChunks, result.Errors)
+	}
+}
+
+func TestAnalyzeStreamRetentionLimit(t *testing.T) {
+	// Enough tokens that their estimated size passes 1 MB
+	corpus := strings.Repeat("alpha beta gamma delta epsilon zeta eta theta\n", 4000)
+
+	retaining, tokenizer := newTestAnalyzer(t, StreamConfig{ChunkSize: 500, MaxMemoryMB: 1, RetainChunkResults: true})
+	result, err := retaining.AnalyzeStream(context.Background(), strings.NewReader(corpus), tokenizer, nil)
+	if err != nil {
+		t.Fatalf("AnalyzeStream returned error: %v", err)
+	}
+	if !result.Truncated || len(result.ChunkResults) == 0 || len(result.ChunkResults) >= result.ProcessedChunks {
+		t.Fatalf("retained %d of %d chunks (truncated %v), want a truncated prefix",
+			len(result.ChunkResults), result.ProcessedChunks, result.Truncated)
+	}
+	if result.RetainedBytes > 1<<20 {
+		t.Errorf("retained an estimated %d bytes, want at most 1 MB", result.RetainedBytes)
+	}
+	for i, chunk := range result.ChunkResults {
+		if chunk.Metadata["chunk_number"] != i {
+			t.Errorf("ChunkResults[%d] holds chunk %v", i, chunk.Metadata["chunk_number"])
+		}
+	}
+
+	// Retention must not change the aggregates
+	streaming, _ := newTestAnalyzer(t, StreamConfig{ChunkSize: 500})
+	unretained, err := streaming.AnalyzeStream(context.Background(), strings.NewReader(corpus), tokenizer, nil)
+	if err != nil {
+		t.Fatalf("AnalyzeStream returned error: %v", err)
+	}
+	assertSameAggregates(t, unretained.AggregatedMetrics, result.AggregatedMetrics)
+}
+
+func TestAnalyzeStreamChunkModes(t *testing.T) {
+	lines := "alpha beta\n\ngamma gamma gamma\ndelta\n---\nepsilon zeta eta\ntheta\n\niota iota\nkappa"
+
+	tests := []struct {
+		name      string
+		config    StreamConfig
+		documents []string
+		chunks    int
+	}{
+		{
+			name:      "concatenate",
+			config:    StreamConfig{ChunkSize: 4},
+			documents: []string{"alpha beta\n\ngamma gamma gamma\ndelta", "---\nepsilon zeta eta\ntheta\n", "iota iota\nkappa"},
+			chunks:    3,
+		},
+		{
+			name:   "per_line",
+			config: StreamConfig{ChunkSize: 4, ChunkMode: ChunkModePerLine},
+			documents: []string{"alpha beta", "gamma gamma gamma", "delta", "---", "epsilon zeta eta",
+				"theta", "iota iota", "kappa"},
+			chunks: 3,
+		},
+		{
+			name:      "blank line delimiter",
+			config:    StreamConfig{ChunkSize: 2, ChunkMode: ChunkModeDelimiter},
+			documents: []string{"alpha beta", "gamma gamma gamma\ndelta\n---\nepsilon zeta eta\ntheta", "iota iota\nkappa"},
+			chunks:    2,
+		},
+		{
+			name:      "custom delimiter",
+			config:    StreamConfig{ChunkSize: 1, ChunkMode: ChunkModeDelimiter, Delimiter: "---"},
+			documents: []string{"alpha beta\n\ngamma gamma gamma\ndelta", "epsilon zeta eta\ntheta\n\niota iota\nkappa"},
+			chunks:    2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer, tokenizer := newTestAnalyzer(t, tt.config)
+			result, err := analyzer.AnalyzeStream(context.Background(), strings.NewReader(lines), tokenizer, nil)
+			if err != nil {
+				t.Fatalf("AnalyzeStream returned error: %v", err)
+			}
+			if result.TotalLines != 10 || result.ProcessedChunks != tt.chunks || result.TotalDocuments != len(tt.documents) {
+				t.Fatalf("got %d lines in %d chunks with %d documents, want 10 lines in %d chunks with %d documents",
+					result.TotalLines, result.ProcessedChunks, result.TotalDocuments, tt.chunks, len(tt.documents))
+			}
+
+			// The aggregates are means over documents, however the chunks split them
+			engine := metrics.NewEngine(metrics.EngineConfig{EntropyWindowSize: 10})
+			sums := make(map[string]float64)
+			counts := make(map[string]int)
+			for _, document := range tt.documents {
+				analysis, err := engine.AnalyzeDocument(context.Background(), document, tokenizer)
+				if err != nil {
+					t.Fatalf("AnalyzeDocument returned error: %v", err)
+				}
+				for name, metric := range analysis.Metrics {
+					sums[name] += metric.Value
+					counts[name]++
+				}
+			}
+			for name, sum := range sums {
+				want := sum / float64(counts[name])
+				if got := result.AggregatedMetrics[name].Mean; math.Abs(got-want) > 1e-9 {
+					t.Errorf("aggregated %s = %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzeStreamUnknownChunkMode(t *testing.T) {
+	analyzer, tokenizer := newTestAnalyzer(t, StreamConfig{ChunkMode: "paragraphs"})
+	result, err := analyzer.AnalyzeStream(context.Background(), strings.NewReader("alpha\nbeta\n"), tokenizer, nil)
+	if err != nil {
+		t.Fatalf("AnalyzeStream returned error: %v", err)
+	}
+	if result.FailedChunks != 1 || len(result.Errors) != 1 || !strings.Contains(result.Errors[0], "unknown chunk mode") {
+		t.Errorf("result has %d failed chunks and errors %v, want the unknown mode reported", result.FailedChunks, result.Errors)
+	}
+}
+
+func TestAnalyzeStreamWeightedAggregates(t *testing.T) {
+	// Chunks of very different sizes: the last holds a single short line
+	var b strings.Builder
+	for i := 0; i < 9; i++ {
+		fmt.Fprintf(&b, "line %d %s\n", i, strings.Repeat("word ", 10*i))
+	}
+	corpus := b.String()
+
+	for _, weightBy := range []string{WeightByTokens, WeightByLines} {
+		t.Run(weightBy, func(t *testing.T) {
+			analyzer, tokenizer := newTestAnalyzer(t, StreamConfig{ChunkSize: 4, RetainChunkResults: true, WeightBy: weightBy})
+			result, err := analyzer.AnalyzeStream(context.Background(), strings.NewReader(corpus), tokenizer, nil)
+			if err != nil {
+				t.Fatalf("AnalyzeStream returned error: %v", err)
+			}
+			if len(result.ChunkResults) != 3 {
+				t.Fatalf("retained %d chunks, want 3", len(result.ChunkResults))
+			}
+
+			chunkLines := []int{4, 4, 1}
+			tokens := 0
+			for _, chunk := range result.ChunkResults {
+				tokens += chunk.TokenCount
+			}
+			if result.Summary.TotalTokens != tokens || result.Summary.WeightBy != weightBy || result.Summary.SuccessRate != 100 {
+				t.Errorf("summary = %+v, want %d tokens weighted by %s", result.Summary, tokens, weightBy)
+			}
+
+			aggregate, ok := result.AggregatedMetrics["entropy_global_entropy"]
+			if !ok {
+				t.Fatalf("no aggregate for entropy_global_entropy")
+			}
+			var values []float64
+			var weighted, weights float64
+			for i, chunk := range result.ChunkResults {
+				value := chunk.Metrics["entropy_global_entropy"].Value
+				weight := float64(chunk.TokenCount)
+				if weightBy == WeightByLines {
+					weight = float64(chunkLines[i])
+				}
+				values = append(values, value)
+				weighted += value * weight
+				weights += weight
+			}
+
+			if aggregate.Chunks != 3 || aggregate.Documents != 3 {
+				t.Errorf("aggregate covers %d chunks and %d documents, want 3 and 3", aggregate.Chunks, aggregate.Documents)
+			}
+			for _, field := range []struct {
+				name      string
+				got, want float64
+			}{
+				{"mean", aggregate.Mean, stats.Mean(values)},
+				{"weighted mean", aggregate.WeightedMean, weighted / weights},
+				{"std", aggregate.Std, stats.Std(values)},
+				{"min", aggregate.Min, stats.Min(values)},
+				{"p50", aggregate.P50, stats.Median(values)},
+				{"max", aggregate.Max, stats.Max(values)},
+			} {
+				if math.Abs(field.got-field.want) > 1e-9 {
+					t.Errorf("%s = %v, want %v", field.name, field.got, field.want)
+				}
+			}
+			if math.Abs(aggregate.WeightedMean-aggregate.Mean) < 1e-6 {
+				t.Errorf("weighted mean %v equals the mean; the chunks should weigh differently", aggregate.WeightedMean)
+			}
+		})
+	}
+}
+
+// replaceStdin points os.Stdin at a pipe fed with content until the test ends
+func replaceStdin(t *testing.T, content []byte) {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	go func() {
+		writer.Write(content)
+		writer.Close()
+	}()
+
+	stdin := os.Stdin
+	os.Stdin = reader
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		reader.Close()
+	})
+}
+
+func TestAnalyzeFileStdin(t *testing.T) {
+	corpus := sampleCorpus(23)
+	var compressed strings.Builder
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(corpus))
+	gz.Close()
+
+	for _, input := range []struct {
+		name    string
+		content []byte
+	}{
+		{"plain", []byte(corpus)},
+		{"gzip", []byte(compressed.String())},
+	} {
+		t.Run(input.name, func(t *testing.T) {
+			replaceStdin(t, input.content)
+
+			analyzer, tokenizer := newTestAnalyzer(t, StreamConfig{ChunkSize: 5, EnableProgress: true, ProgressInterval: 2})
+			var reads []int64
+			analyzer.SetReadProgressCallback(func(bytesRead int64, bytesPerSecond float64, duration time.Duration) {
+				if bytesPerSecond <= 0 {
+					t.Errorf("read rate %v, want a positive rate", bytesPerSecond)
+				}
+				reads = append(reads, bytesRead)
+			})
+
+			result, err := analyzer.AnalyzeFile(context.Background(), StdinPath, tokenizer, nil)
+			if err != nil {
+				t.Fatalf("AnalyzeFile returned error: %v", err)
+			}
+			if result.TotalLines != 23 || result.ProcessedChunks != 5 || result.BytesRead != int64(len(corpus)) {
+				t.Errorf("read %d bytes in %d lines and %d chunks, want %d bytes in 23 lines and 5 chunks",
+					result.BytesRead, result.TotalLines, result.ProcessedChunks, len(corpus))
+			}
+			if len(reads) != 2 || result.Summary.BytesPerSecond <= 0 {
+				t.Errorf("got read progress %v and rate %v, want two reports and a rate", reads, result.Summary.BytesPerSecond)
+			}
+
+			// Standard input belongs to the process and stays open
+			if _, err := os.Stdin.Stat(); err != nil {
+				t.Errorf("stdin was closed: %v", err)
+			}
+		})
+	}
+}
+
+// brokenPipeReader returns its data and then fails, like a pipe whose writer died
+type brokenPipeReader struct {
+	data *strings.Reader
+}
+
+func (b *brokenPipeReader) Read(p []byte) (int, error) {
+	if b.data.Len() == 0 {
+		return 0, io.ErrClosedPipe
+	}
+	return b.data.Read(p)
+}
+
+func TestAnalyzeStreamBrokenPipe(t *testing.T) {
+	// Eleven complete lines, then a line cut off by the failure
+	corpus := sampleCorpus(11) + "cut off mid"
+
+	analyzer, tokenizer := newTestAnalyzer(t, StreamConfig{ChunkSize: 5})
+	result, err := analyzer.AnalyzeStream(context.Background(), &brokenPipeReader{data: strings.NewReader(corpus)}, tokenizer, nil)
+	if !errors.Is(err, io.ErrClosedPipe) {
+		t.Fatalf("AnalyzeStream returned %v, want the pipe error", err)
+	}
+	if result.ProcessedChunks != 2 || result.FailedChunks != 1 || result.TotalLines != 11 {
+		t.Errorf("result has %d processed and %d failed chunks over %d lines, want 2 and 1 over 11",
+			result.ProcessedChunks, result.FailedChunks, result.TotalLines)
+	}
+	if result.BytesRead != int64(len(corpus)) || len(result.AggregatedMetrics) == 0 {
+		t.Errorf("read %d bytes with %d aggregates, want %d bytes and the completed chunks aggregated",
+			result.BytesRead, len(result.AggregatedMetrics), len(corpus))
+	}
+}
@@ -0,0 +1,195 @@
+package streaming
+
+import "math"
+
+// DriftConfig holds configuration for drift detection while streaming. The baseline
+// is either the token distribution of the first BaselineChunks chunks or, when Decay
+// is set, an exponentially decayed distribution of every chunk seen so far.
+type DriftConfig struct {
+	Enabled        bool    `json:"enabled"`
+	BaselineChunks int     `json:"baseline_chunks"` // Chunks forming a fixed baseline
+	Decay          float64 `json:"decay"`           // Weight of each new chunk in a rolling baseline, in (0, 1]
+	ZThreshold     float64 `json:"z_threshold"`     // Flag chunks whose JS divergence z-score exceeds this
+}
+
+// ChunkDrift is the drift of one chunk from the baseline. ZScore compares the chunk's
+// JS divergence with that of the chunks compared before it.
+type ChunkDrift struct {
+	Chunk           int     `json:"chunk"`
+	StartLine       int     `json:"start_line"`
+	Tokens          int     `json:"tokens"`
+	JaccardDistance float64 `json:"jaccard_distance"` // between the chunk and baseline vocabularies
+	JSDivergence    float64 `json:"js_divergence"`    // base 2, so between 0 and 1
+	EntropyDelta    float64 `json:"entropy_delta"`    // chunk minus baseline unigram entropy, in bits
+	ZScore          float64 `json:"z_score"`
+	Flagged         bool    `json:"flagged,omitempty"`
+}
+
+// minBaselineProbability drops tokens that have decayed out of a rolling baseline, so
+// its vocabulary does not grow without bound
+const minBaselineProbability = 1e-9
+
+// driftTracker maintains the baseline distribution and the running statistics of the
+// JS divergences used for z-scores
+type driftTracker struct {
+	config DriftConfig
+
+	baseline       map[string]float64 // token counts while a fixed baseline fills, then probabilities
+	baselineChunks int
+
+	// Welford's running mean and variance of the JS divergences so far
+	compared int
+	mean     float64
+	m2       float64
+}
+
+// newDriftTracker creates a tracker with an empty baseline
+func newDriftTracker(config DriftConfig) *driftTracker {
+	return &driftTracker{
+		config:   config,
+		baseline: make(map[string]float64),
+	}
+}
+
+// observe compares a chunk's token counts with the baseline and then updates the
+// baseline. It returns false while the chunk only builds the baseline.
+func (d *driftTracker) observe(chunkNum, startLine int, counts map[string]int) (ChunkDrift, bool) {
+	chunk := normalize(counts)
+	if len(chunk) == 0 {
+		return ChunkDrift{}, false
+	}
+
+	rolling := d.config.Decay > 0
+	if !rolling && d.baselineChunks < d.config.BaselineChunks {
+		for token, count := range counts {
+			d.baseline[token] += float64(count)
+		}
+		d.baselineChunks++
+		if d.baselineChunks == d.config.BaselineChunks {
+			d.baseline = normalizeFloat(d.baseline)
+		}
+		return ChunkDrift{}, false
+	}
+	if rolling && d.baselineChunks == 0 {
+		d.baseline = chunk
+		d.baselineChunks++
+		return ChunkDrift{}, false
+	}
+
+	baseline := d.baseline
+	drift := ChunkDrift{
+		Chunk:           chunkNum,
+		StartLine:       startLine,
+		JaccardDistance: jaccardDistance(chunk, baseline),
+		JSDivergence:    jsDivergence(chunk, baseline),
+		EntropyDelta:    shannonEntropy(chunk) - shannonEntropy(baseline),
+	}
+	for _, count := range counts {
+		drift.Tokens += count
+	}
+
+	// Score against the chunks before this one, then include it
+	if d.compared >= 2 {
+		if std := math.Sqrt(d.m2 / float64(d.compared-1)); std > 0 {
+			drift.ZScore = (drift.JSDivergence - d.mean) / std
+		}
+	}
+	drift.Flagged = drift.ZScore > d.config.ZThreshold
+	d.compared++
+	delta := drift.JSDivergence - d.mean
+	d.mean += delta / float64(d.compared)
+	d.m2 += delta * (drift.JSDivergence - d.mean)
+
+	if rolling {
+		d.decay(chunk)
+	}
+
+	return drift, true
+}
+
+// decay blends the chunk distribution into the rolling baseline
+func (d *driftTracker) decay(chunk map[string]float64) {
+	for token, p := range d.baseline {
+		p *= 1 - d.config.Decay
+		if p < minBaselineProbability {
+			delete(d.baseline, token)
+			continue
+		}
+		d.baseline[token] = p
+	}
+	for token, p := range chunk {
+		d.baseline[token] += d.config.Decay * p
+	}
+}
+
+// normalize turns token counts into probabilities
+func normalize(counts map[string]int) map[string]float64 {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	distribution := make(map[string]float64, len(counts))
+	if total == 0 {
+		return distribution
+	}
+	for token, count := range counts {
+		distribution[token] = float64(count) / float64(total)
+	}
+	return distribution
+}
+
+// normalizeFloat turns accumulated weights into probabilities
+func normalizeFloat(weights map[string]float64) map[string]float64 {
+	total := 0.0
+	for _, weight := range weights {
+		total += weight
+	}
+	distribution := make(map[string]float64, len(weights))
+	if total == 0 {
+		return distribution
+	}
+	for token, weight := range weights {
+		distribution[token] = weight / total
+	}
+	return distribution
+}
+
+// jaccardDistance is one minus the Jaccard similarity of the two vocabularies
+func jaccardDistance(p, q map[string]float64) float64 {
+	if len(p) == 0 && len(q) == 0 {
+		return 0
+	}
+	shared := 0
+	for token := range p {
+		if _, ok := q[token]; ok {
+			shared++
+		}
+	}
+	return 1 - float64(shared)/float64(len(p)+len(q)-shared)
+}
+
+// jsDivergence is the Jensen-Shannon divergence of two distributions in bits
+func jsDivergence(p, q map[string]float64) float64 {
+	divergence := 0.0
+	for token, pp := range p {
+		m := (pp + q[token]) / 2
+		divergence += pp * math.Log2(pp/m) / 2
+	}
+	for token, qq := range q {
+		m := (p[token] + qq) / 2
+		divergence += qq * math.Log2(qq/m) / 2
+	}
+	// Rounding can leave a tiny negative value for identical distributions
+	return math.Max(0, divergence)
+}
+
+// shannonEntropy is the entropy of a distribution in bits
+func shannonEntropy(distribution map[string]float64) float64 {
+	entropy := 0.0
+	for _, p := range distribution {
+		if p > 0 {
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy
+}
@@ -0,0 +1,114 @@
+package streaming
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestDistributionDistances(t *testing.T) {
+	p := map[string]float64{"a": 0.5, "b": 0.5}
+	q := map[string]float64{"c": 0.25, "d": 0.75}
+	r := map[string]float64{"a": 0.5, "c": 0.5}
+
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"js identical", jsDivergence(p, p), 0},
+		{"js disjoint", jsDivergence(p, q), 1},
+		{"js half shared", jsDivergence(p, r), 0.5},
+		{"jaccard identical", jaccardDistance(p, p), 0},
+		{"jaccard disjoint", jaccardDistance(p, q), 1},
+		{"jaccard one of three shared", jaccardDistance(p, r), 2.0 / 3},
+		{"entropy uniform", shannonEntropy(p), 1},
+		{"entropy skewed", shannonEntropy(q), -(0.25*math.Log2(0.25) + 0.75*math.Log2(0.75))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if math.Abs(tt.got-tt.want) > 1e-12 {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+// driftCorpus returns chunks of four lines drawn from a shared vocabulary, with the
+// chunk at shift drawn from an unrelated one
+func driftCorpus(chunks, shift int) string {
+	var b strings.Builder
+	for i := 0; i < chunks; i++ {
+		for line := 0; line < 4; line++ {
+			for word := 0; word < 6; word++ {
+				if i == shift {
+					fmt.Fprintf(&b, "novel%d ", (line*6+word)%11)
+				} else {
+					fmt.Fprintf(&b, "w%d ", (i*7+line*3+word*word)%20)
+				}
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+func TestAnalyzeStreamDrift(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     ChunkMode
+		drift    DriftConfig
+		compared int
+	}{
+		{"fixed baseline", ChunkModeConcatenate, DriftConfig{Enabled: true, BaselineChunks: 3}, 12 - 3},
+		{"rolling baseline", ChunkModeConcatenate, DriftConfig{Enabled: true, Decay: 0.3}, 12 - 1},
+		{"per line", ChunkModePerLine, DriftConfig{Enabled: true, BaselineChunks: 3}, 12 - 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer, tokenizer := newTestAnalyzer(t, StreamConfig{ChunkSize: 4, ChunkMode: tt.mode, Drift: tt.drift})
+			result, err := analyzer.AnalyzeStream(context.Background(), strings.NewReader(driftCorpus(12, 9)), tokenizer, nil)
+			if err != nil {
+				t.Fatalf("AnalyzeStream returned error: %v", err)
+			}
+			if len(result.Drift) != tt.compared {
+				t.Fatalf("got %d drift entries, want %d", len(result.Drift), tt.compared)
+			}
+
+			for i, drift := range result.Drift {
+				wantChunk := 12 - tt.compared + i
+				if drift.Chunk != wantChunk || drift.StartLine != 4*wantChunk+1 || drift.Tokens != 24 {
+					t.Errorf("entry %d = chunk %d at line %d with %d tokens, want chunk %d at line %d with 24",
+						i, drift.Chunk, drift.StartLine, drift.Tokens, wantChunk, 4*wantChunk+1)
+				}
+				if drift.Chunk == 9 {
+					if drift.JaccardDistance != 1 || math.Abs(drift.JSDivergence-1) > 1e-9 || !drift.Flagged {
+						t.Errorf("shifted chunk = %+v, want a flagged, fully disjoint chunk", drift)
+					}
+					continue
+				}
+				if drift.Flagged || drift.JSDivergence >= 0.9 {
+					t.Errorf("chunk %d = %+v, want a small unflagged drift", drift.Chunk, drift)
+				}
+			}
+			if result.Summary.FlaggedChunks != 1 {
+				t.Errorf("summary flags %d chunks, want 1", result.Summary.FlaggedChunks)
+			}
+		})
+	}
+}
+
+func TestAnalyzeStreamDriftDisabled(t *testing.T) {
+	analyzer, tokenizer := newTestAnalyzer(t, StreamConfig{ChunkSize: 4})
+	result, err := analyzer.AnalyzeStream(context.Background(), strings.NewReader(driftCorpus(6, 4)), tokenizer, nil)
+	if err != nil {
+		t.Fatalf("AnalyzeStream returned error: %v", err)
+	}
+	if len(result.Drift) != 0 || result.Summary.FlaggedChunks != 0 {
+		t.Errorf("got %d drift entries without drift detection", len(result.Drift))
+	}
+}
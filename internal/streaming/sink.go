@@ -0,0 +1,69 @@
+package streaming
+
+import (
+	"io"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/export"
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+)
+
+// ChunkSink receives each chunk result as the streaming analyzer produces it, so
+// results can leave memory as soon as they are aggregated
+type ChunkSink interface {
+	// WriteChunk stores the result of one chunk
+	WriteChunk(result *metrics.AnalysisResult) error
+
+	// Close flushes anything buffered and releases the sink's resources
+	Close() error
+}
+
+// ExportSink writes each chunk result with an export writer
+type ExportSink struct {
+	writer export.Writer
+}
+
+// JSONLSink writes each chunk result as one line of JSON
+type JSONLSink = ExportSink
+
+// NewExportSink creates a sink writing chunk results with writer, which the sink closes
+func NewExportSink(writer export.Writer) *ExportSink {
+	return &ExportSink{writer: writer}
+}
+
+// NewFileSink creates a sink writing chunk results to a new file at path, replacing
+// any file already there, in the export format of its extension: long CSV for .csv,
+// Parquet for .parquet and JSON lines otherwise
+func NewFileSink(path string) (*ExportSink, error) {
+	writer, err := export.Create(path, "")
+	if err != nil {
+		return nil, err
+	}
+	return NewExportSink(writer), nil
+}
+
+// NewJSONLSink creates a sink writing JSON lines to w. Closing the sink flushes it
+// but does not close w.
+func NewJSONLSink(w io.Writer) *JSONLSink {
+	writer, _ := export.NewWriter(w, export.FormatJSONL)
+	return NewExportSink(writer)
+}
+
+// NewJSONLFileSink creates a sink writing JSON lines to a new file at path,
+// replacing any file already there
+func NewJSONLFileSink(path string) (*JSONLSink, error) {
+	writer, err := export.Create(path, export.FormatJSONL)
+	if err != nil {
+		return nil, err
+	}
+	return NewExportSink(writer), nil
+}
+
+// WriteChunk writes the result of one chunk
+func (e *ExportSink) WriteChunk(result *metrics.AnalysisResult) error {
+	return e.writer.Write(result)
+}
+
+// Close flushes buffered results and closes the file of a file sink
+func (e *ExportSink) Close() error {
+	return e.writer.Close()
+}
@@ -0,0 +1,266 @@
+package tokenizers
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// charVocabSize is the number of Unicode code points
+const charVocabSize = unicode.MaxRune + 1
+
+// defaultWhitespaceVocabSize is the number of hash buckets for whitespace token IDs
+const defaultWhitespaceVocabSize = 1 << 16
+
+// CharTokenizer is a baseline tokenizer that emits one token per rune. Token IDs are
+// code points; invalid UTF-8 bytes become U+FFFD tokens one byte wide.
+type CharTokenizer struct {
+	*BaseTokenizer
+}
+
+// NewCharTokenizer creates a new character tokenizer
+func NewCharTokenizer(name string) *CharTokenizer {
+	return &CharTokenizer{
+		BaseTokenizer: NewBaseTokenizer(name),
+	}
+}
+
+// Tokenize splits text into runes
+func (c *CharTokenizer) Tokenize(ctx context.Context, text string) (*TokenizationResult, error) {
+	tokens := make([]Token, 0, utf8.RuneCountInString(text))
+	for pos, r := range text {
+		_, size := utf8.DecodeRuneInString(text[pos:])
+		tokens = append(tokens, Token{
+			Text:     text[pos : pos+size],
+			ID:       int(r),
+			StartPos: pos,
+			EndPos:   pos + size,
+		})
+	}
+
+	return baselineResult(c.Name(), text, tokens, charVocabSize), nil
+}
+
+// TokenizeBatch tokenizes multiple documents
+func (c *CharTokenizer) TokenizeBatch(ctx context.Context, texts []string) ([]*TokenizationResult, error) {
+	return tokenizeEach(ctx, texts, c.Tokenize)
+}
+
+// GetVocabSize returns the number of Unicode code points
+func (c *CharTokenizer) GetVocabSize() (int, error) {
+	return charVocabSize, nil
+}
+
+// ByteTokenizer is a baseline tokenizer that emits one token per UTF-8 byte, with
+// the byte value as its ID
+type ByteTokenizer struct {
+	*BaseTokenizer
+}
+
+// NewByteTokenizer creates a new byte tokenizer
+func NewByteTokenizer(name string) *ByteTokenizer {
+	return &ByteTokenizer{
+		BaseTokenizer: NewBaseTokenizer(name),
+	}
+}
+
+// Tokenize splits text into bytes. Bytes inside a multi-byte character are tokens of
+// their own, so their text is not valid UTF-8 on its own.
+func (b *ByteTokenizer) Tokenize(ctx context.Context, text string) (*TokenizationResult, error) {
+	tokens := make([]Token, len(text))
+	for i := 0; i < len(text); i++ {
+		tokens[i] = Token{
+			Text:     text[i : i+1],
+			ID:       int(text[i]),
+			StartPos: i,
+			EndPos:   i + 1,
+		}
+	}
+
+	return baselineResult(b.Name(), text, tokens, 256), nil
+}
+
+// TokenizeBatch tokenizes multiple documents
+func (b *ByteTokenizer) TokenizeBatch(ctx context.Context, texts []string) ([]*TokenizationResult, error) {
+	return tokenizeEach(ctx, texts, b.Tokenize)
+}
+
+// GetVocabSize returns the number of byte values
+func (b *ByteTokenizer) GetVocabSize() (int, error) {
+	return 256, nil
+}
+
+// WhitespaceTokenizer is a baseline tokenizer that splits on Unicode whitespace and
+// separates punctuation and symbols from words. Token IDs are hashes of the token
+// text modulo the vocab_size parameter.
+type WhitespaceTokenizer struct {
+	*BaseTokenizer
+	vocabSize int
+}
+
+// NewWhitespaceTokenizer creates a new whitespace tokenizer
+func NewWhitespaceTokenizer(name string) *WhitespaceTokenizer {
+	return &WhitespaceTokenizer{
+		BaseTokenizer: NewBaseTokenizer(name),
+		vocabSize:     defaultWhitespaceVocabSize,
+	}
+}
+
+// Initialize sets up the whitespace tokenizer
+func (w *WhitespaceTokenizer) Initialize(config TokenizerConfig) error {
+	if err := w.BaseTokenizer.Initialize(config); err != nil {
+		return err
+	}
+
+	if value, ok := config.Parameters["vocab_size"]; ok {
+		vocabSize, err := strconv.Atoi(value)
+		if err != nil || vocabSize <= 0 {
+			return fmt.Errorf("invalid vocab_size parameter: %s", value)
+		}
+		w.vocabSize = vocabSize
+	}
+
+	return nil
+}
+
+// Tokenize splits text into words, punctuation and symbols
+func (w *WhitespaceTokenizer) Tokenize(ctx context.Context, text string) (*TokenizationResult, error) {
+	tokens := make([]Token, 0)
+	for _, span := range splitWords(text) {
+		piece := text[span.start:span.end]
+		hash := fnv.New32a()
+		hash.Write([]byte(piece))
+		tokens = append(tokens, Token{
+			Text:     piece,
+			ID:       int(hash.Sum32() % uint32(w.vocabSize)),
+			StartPos: span.start,
+			EndPos:   span.end,
+		})
+	}
+
+	return baselineResult(w.Name(), text, tokens, w.vocabSize), nil
+}
+
+// TokenizeBatch tokenizes multiple documents
+func (w *WhitespaceTokenizer) TokenizeBatch(ctx context.Context, texts []string) ([]*TokenizationResult, error) {
+	return tokenizeEach(ctx, texts, w.Tokenize)
+}
+
+// GetVocabSize returns the number of hash buckets for token IDs
+func (w *WhitespaceTokenizer) GetVocabSize() (int, error) {
+	return w.vocabSize, nil
+}
+
+// wordSpan is a byte range of a word or symbol found by splitWords
+type wordSpan struct {
+	start, end int
+}
+
+// splitWords finds the words and symbols in text. A word is a run of letters, digits
+// and combining marks. Any other non-space rune is a token of its own, extended over
+// the marks, emoji modifiers and zero-width joiner sequences that follow it, so an
+// emoji like 👍🏽 or 👩‍💻 stays whole.
+func splitWords(text string) []wordSpan {
+	var spans []wordSpan
+	for pos := 0; pos < len(text); {
+		r, size := utf8.DecodeRuneInString(text[pos:])
+		if unicode.IsSpace(r) {
+			pos += size
+			continue
+		}
+
+		start := pos
+		pos += size
+		if isWordRune(r) {
+			for pos < len(text) {
+				next, nextSize := utf8.DecodeRuneInString(text[pos:])
+				if !isWordRune(next) {
+					break
+				}
+				pos += nextSize
+			}
+		} else {
+			pos = extendSymbol(text, pos)
+		}
+		spans = append(spans, wordSpan{start: start, end: pos})
+	}
+	return spans
+}
+
+// isWordRune reports whether r continues a word
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+// extendSymbol returns the end of the symbol cluster that continues at pos
+func extendSymbol(text string, pos int) int {
+	for pos < len(text) {
+		r, size := utf8.DecodeRuneInString(text[pos:])
+		switch {
+		case unicode.IsMark(r) || (r >= 0x1F3FB && r <= 0x1F3FF):
+			// Combining marks, variation selectors and skin tone modifiers
+			pos += size
+		case r == '\u200d' && pos+size < len(text):
+			// A zero-width joiner pulls in the rune after it
+			_, nextSize := utf8.DecodeRuneInString(text[pos+size:])
+			pos += size + nextSize
+		default:
+			return pos
+		}
+	}
+	return pos
+}
+
+// baselineResult builds the result for one of the baseline tokenizers
+func baselineResult(name, text string, tokens []Token, vocabSize int) *TokenizationResult {
+	metadata := map[string]interface{}{
+		"tokenizer_type": "baseline",
+		"vocab_size":     vocabSize,
+	}
+	if warning := validateTokenOffsets(text, tokens); warning != "" {
+		metadata["offset_warning"] = warning
+	}
+
+	return &TokenizationResult{
+		Document:  text,
+		Tokens:    tokens,
+		Tokenizer: name,
+		Metadata:  metadata,
+	}
+}
+
+// tokenizeEach tokenizes texts one at a time, stopping if ctx ends
+func tokenizeEach(ctx context.Context, texts []string, tokenize func(context.Context, string) (*TokenizationResult, error)) ([]*TokenizationResult, error) {
+	results := make([]*TokenizationResult, len(texts))
+
+	for i, text := range texts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result, err := tokenize(ctx, text)
+		if err != nil {
+			return nil, fmt.Errorf("error tokenizing document %d: %w", i, err)
+		}
+		results[i] = result
+	}
+
+	return results, nil
+}
+
+// RegisterCharTokenizer registers the character tokenizer with the global registry
+func RegisterCharTokenizer() error {
+	return RegisterGlobal("char", newBuiltinTokenizer("char"))
+}
+
+// RegisterByteTokenizer registers the byte tokenizer with the global registry
+func RegisterByteTokenizer() error {
+	return RegisterGlobal("byte", newBuiltinTokenizer("byte"))
+}
+
+// RegisterWhitespaceTokenizer registers the whitespace tokenizer with the global registry
+func RegisterWhitespaceTokenizer() error {
+	return RegisterGlobal("whitespace", newBuiltinTokenizer("whitespace"))
+}
@@ -0,0 +1,138 @@
+package tokenizers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// spanTexts slices each token's offsets out of the document
+func spanTexts(result *TokenizationResult) []string {
+	texts := make([]string, len(result.Tokens))
+	for i, token := range result.Tokens {
+		texts[i] = result.Document[token.StartPos:token.EndPos]
+	}
+	return texts
+}
+
+func TestCharTokenizer(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"ascii", "abc", []string{"a", "b", "c"}},
+		{"multi-byte", "héllo", []string{"h", "é", "l", "l", "o"}},
+		{"emoji", "a🙂b", []string{"a", "🙂", "b"}},
+		// e followed by U+0301 COMBINING ACUTE ACCENT is two runes
+		{"combining", "e\u0301!", []string{"e", "\u0301", "!"}},
+		{"invalid utf-8", "a\xffb", []string{"a", "\xff", "b"}},
+		{"empty", "", []string{}},
+	}
+
+	tokenizer := NewCharTokenizer("char")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tokenizer.Tokenize(context.Background(), tt.text)
+			if err != nil {
+				t.Fatalf("Tokenize returned error: %v", err)
+			}
+			if got := spanTexts(result); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("offsets give %q, want %q", got, tt.want)
+			}
+			if got := tokenTexts(result); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("token texts = %q, want %q", got, tt.want)
+			}
+			if warning := validateTokenOffsets(tt.text, result.Tokens); warning != "" {
+				t.Errorf("invalid offsets: %s", warning)
+			}
+		})
+	}
+
+	result, _ := tokenizer.Tokenize(context.Background(), "é🙂")
+	if result.Tokens[0].ID != 0xE9 || result.Tokens[1].ID != 0x1F642 {
+		t.Errorf("IDs = %d, %d, want the code points", result.Tokens[0].ID, result.Tokens[1].ID)
+	}
+}
+
+func TestByteTokenizer(t *testing.T) {
+	tokenizer := NewByteTokenizer("byte")
+	text := "é🙂"
+
+	result, err := tokenizer.Tokenize(context.Background(), text)
+	if err != nil {
+		t.Fatalf("Tokenize returned error: %v", err)
+	}
+	if len(result.Tokens) != len(text) {
+		t.Fatalf("got %d tokens, want %d", len(result.Tokens), len(text))
+	}
+	for i, token := range result.Tokens {
+		if token.StartPos != i || token.EndPos != i+1 || token.ID != int(text[i]) {
+			t.Errorf("token %d = %+v, want byte %d at %d-%d", i, token, text[i], i, i+1)
+		}
+	}
+	if warning := validateTokenOffsets(text, result.Tokens); warning != "" {
+		t.Errorf("invalid offsets: %s", warning)
+	}
+}
+
+func TestWhitespaceTokenizer(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"words", "hello  world", []string{"hello", "world"}},
+		{"punctuation", "Hello, world!", []string{"Hello", ",", "world", "!"}},
+		{"contraction", "don't", []string{"don", "'", "t"}},
+		{"numbers", "v1.2 costs $30", []string{"v1", ".", "2", "costs", "$", "30"}},
+		{"unicode spaces", "a\u00a0b\u3000c\td", []string{"a", "b", "c", "d"}},
+		{"cjk", "日本語、テキスト", []string{"日本語", "、", "テキスト"}},
+		{"combining", "cafe\u0301 ok", []string{"cafe\u0301", "ok"}},
+		{"emoji", "hi🙂!", []string{"hi", "🙂", "!"}},
+		{"emoji modifier", "ok 👍🏽", []string{"ok", "👍🏽"}},
+		{"zwj sequence", "👩\u200d💻 code", []string{"👩\u200d💻", "code"}},
+		{"variation selector", "❤\ufe0f❤", []string{"❤\ufe0f", "❤"}},
+		{"empty", "", []string{}},
+	}
+
+	tokenizer := NewWhitespaceTokenizer("whitespace")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tokenizer.Tokenize(context.Background(), tt.text)
+			if err != nil {
+				t.Fatalf("Tokenize returned error: %v", err)
+			}
+			if got := spanTexts(result); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("offsets give %q, want %q", got, tt.want)
+			}
+			if got := tokenTexts(result); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("token texts = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWhitespaceTokenizerIDs(t *testing.T) {
+	tokenizer := NewWhitespaceTokenizer("whitespace")
+	if err := tokenizer.Initialize(TokenizerConfig{Name: "whitespace", Type: "custom", Parameters: map[string]string{"vocab_size": "97"}}); err != nil {
+		t.Fatalf("Initialize returned error: %v", err)
+	}
+
+	result, err := tokenizer.Tokenize(context.Background(), "same other same")
+	if err != nil {
+		t.Fatalf("Tokenize returned error: %v", err)
+	}
+	if result.Tokens[0].ID != result.Tokens[2].ID {
+		t.Error("equal words got different IDs")
+	}
+	for _, token := range result.Tokens {
+		if token.ID < 0 || token.ID >= 97 {
+			t.Errorf("ID %d is outside the vocabulary", token.ID)
+		}
+	}
+
+	if err := tokenizer.Initialize(TokenizerConfig{Name: "whitespace", Type: "custom", Parameters: map[string]string{"vocab_size": "0"}}); err == nil {
+		t.Error("expected an error for vocab_size 0")
+	}
+}
@@ -0,0 +1,343 @@
+package tokenizers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// LocalBPETokenizer is a pure-Go GPT-2 style byte-level BPE tokenizer that loads
+// vocab.json and merges.txt from local files
+type LocalBPETokenizer struct {
+	*BaseTokenizer
+	vocab       map[string]int
+	ranks       map[[2]string]int
+	byteEncoder [256]string
+
+	cacheMu sync.RWMutex
+	cache   map[string][]string
+}
+
+// NewLocalBPETokenizer creates a new local BPE tokenizer
+func NewLocalBPETokenizer(name string) *LocalBPETokenizer {
+	return &LocalBPETokenizer{
+		BaseTokenizer: NewBaseTokenizer(name),
+		byteEncoder:   bytesToUnicode(),
+		cache:         make(map[string][]string),
+	}
+}
+
+// Initialize loads the vocabulary and merges from the vocab_path and merges_path
+// parameters
+func (b *LocalBPETokenizer) Initialize(config TokenizerConfig) error {
+	if err := b.BaseTokenizer.Initialize(config); err != nil {
+		return err
+	}
+
+	vocabPath := config.Parameters["vocab_path"]
+	mergesPath := config.Parameters["merges_path"]
+	if vocabPath == "" || mergesPath == "" {
+		return fmt.Errorf("vocab_path and merges_path parameters are required")
+	}
+
+	vocab, err := loadBPEVocab(vocabPath)
+	if err != nil {
+		return err
+	}
+	ranks, err := loadBPEMerges(mergesPath)
+	if err != nil {
+		return err
+	}
+
+	b.vocab = vocab
+	b.ranks = ranks
+	b.cacheMu.Lock()
+	b.cache = make(map[string][]string)
+	b.cacheMu.Unlock()
+
+	return nil
+}
+
+// Tokenize tokenizes a single document. Offsets are byte positions in text.
+func (b *LocalBPETokenizer) Tokenize(ctx context.Context, text string) (*TokenizationResult, error) {
+	if b.vocab == nil {
+		return nil, fmt.Errorf("tokenizer %s is not initialized", b.Name())
+	}
+
+	tokens := make([]Token, 0)
+	for _, word := range pretokenizeGPT2(text) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		pos := word.start
+		for _, symbol := range b.bpe(text[word.start:word.end]) {
+			id, ok := b.vocab[symbol]
+			if !ok {
+				return nil, fmt.Errorf("token %q is not in the vocabulary", symbol)
+			}
+
+			// Every symbol rune stands for one byte of the original text
+			length := utf8.RuneCountInString(symbol)
+			tokens = append(tokens, Token{
+				Text:     text[pos : pos+length],
+				ID:       id,
+				StartPos: pos,
+				EndPos:   pos + length,
+				Metadata: map[string]string{
+					"tokenizer": "bpe-local",
+				},
+			})
+			pos += length
+		}
+	}
+
+	metadata := map[string]interface{}{
+		"vocab_size": len(b.vocab),
+	}
+	if warning := validateTokenOffsets(text, tokens); warning != "" {
+		metadata["offset_warning"] = warning
+	}
+
+	return &TokenizationResult{
+		Document:  text,
+		Tokens:    tokens,
+		Tokenizer: b.Name(),
+		Metadata:  metadata,
+	}, nil
+}
+
+// TokenizeBatch tokenizes multiple documents
+func (b *LocalBPETokenizer) TokenizeBatch(ctx context.Context, texts []string) ([]*TokenizationResult, error) {
+	results := make([]*TokenizationResult, len(texts))
+
+	for i, text := range texts {
+		result, err := b.Tokenize(ctx, text)
+		if err != nil {
+			return nil, fmt.Errorf("error tokenizing document %d: %w", i, err)
+		}
+		results[i] = result
+	}
+
+	return results, nil
+}
+
+// GetVocabSize returns the vocabulary size
+func (b *LocalBPETokenizer) GetVocabSize() (int, error) {
+	if b.vocab == nil {
+		return 0, fmt.Errorf("tokenizer %s is not initialized", b.Name())
+	}
+	return len(b.vocab), nil
+}
+
+// Close cleans up resources
+func (b *LocalBPETokenizer) Close() error {
+	b.cacheMu.Lock()
+	b.cache = make(map[string][]string)
+	b.cacheMu.Unlock()
+	return nil
+}
+
+// bpe splits one pre-tokenized word into vocabulary symbols by repeatedly merging the
+// adjacent pair with the lowest merge rank
+func (b *LocalBPETokenizer) bpe(word string) []string {
+	b.cacheMu.RLock()
+	cached, ok := b.cache[word]
+	b.cacheMu.RUnlock()
+	if ok {
+		return cached
+	}
+
+	symbols := make([]string, len(word))
+	for i := 0; i < len(word); i++ {
+		symbols[i] = b.byteEncoder[word[i]]
+	}
+
+	for len(symbols) > 1 {
+		best := -1
+		var bestPair [2]string
+		for i := 0; i < len(symbols)-1; i++ {
+			pair := [2]string{symbols[i], symbols[i+1]}
+			if rank, ok := b.ranks[pair]; ok && (best < 0 || rank < best) {
+				best = rank
+				bestPair = pair
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		// Merge every occurrence of the best pair, left to right
+		merged := make([]string, 0, len(symbols))
+		for i := 0; i < len(symbols); i++ {
+			if i < len(symbols)-1 && symbols[i] == bestPair[0] && symbols[i+1] == bestPair[1] {
+				merged = append(merged, bestPair[0]+bestPair[1])
+				i++
+			} else {
+				merged = append(merged, symbols[i])
+			}
+		}
+		symbols = merged
+	}
+
+	b.cacheMu.Lock()
+	b.cache[word] = symbols
+	b.cacheMu.Unlock()
+
+	return symbols
+}
+
+// bytesToUnicode maps every byte to a printable rune the way GPT-2 does: printable
+// Latin-1 bytes map to themselves and the rest to runes from U+0100 upwards, which
+// is why a space appears as "Ġ" in the vocabulary
+func bytesToUnicode() [256]string {
+	var encoder [256]string
+	next := 0
+	for i := 0; i < 256; i++ {
+		if (i >= '!' && i <= '~') || (i >= 0xA1 && i <= 0xAC) || (i >= 0xAE && i <= 0xFF) {
+			encoder[i] = string(rune(i))
+		} else {
+			encoder[i] = string(rune(256 + next))
+			next++
+		}
+	}
+	return encoder
+}
+
+// loadBPEVocab reads a vocab.json mapping token strings to IDs
+func loadBPEVocab(path string) (map[string]int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vocab file: %w", err)
+	}
+
+	var vocab map[string]int
+	if err := json.Unmarshal(data, &vocab); err != nil {
+		return nil, fmt.Errorf("failed to parse vocab file: %w", err)
+	}
+	return vocab, nil
+}
+
+// loadBPEMerges reads a merges.txt file; the line order gives the merge rank
+func loadBPEMerges(path string) (map[[2]string]int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open merges file: %w", err)
+	}
+	defer file.Close()
+
+	ranks := make(map[[2]string]int)
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#version") {
+			continue
+		}
+
+		parts := strings.Split(line, " ")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid merge on line %d: %q", lineNum, line)
+		}
+		pair := [2]string{parts[0], parts[1]}
+		if _, exists := ranks[pair]; !exists {
+			ranks[pair] = len(ranks)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read merges file: %w", err)
+	}
+
+	return ranks, nil
+}
+
+// pretokenSpan is a byte range of the input produced by pre-tokenization
+type pretokenSpan struct {
+	start, end int
+}
+
+// gpt2Contractions are matched before anything else at an apostrophe
+var gpt2Contractions = []string{"'s", "'t", "'re", "'ve", "'m", "'ll", "'d"}
+
+// pretokenizeGPT2 splits text the way GPT-2's pattern does:
+//
+//	's|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+(?!\S)|\s+
+//
+// Go's regexp has no lookahead, so the alternatives are applied by hand in order
+func pretokenizeGPT2(text string) []pretokenSpan {
+	spans := make([]pretokenSpan, 0)
+
+	for i := 0; i < len(text); {
+		end := matchGPT2Pretoken(text, i)
+		spans = append(spans, pretokenSpan{start: i, end: end})
+		i = end
+	}
+
+	return spans
+}
+
+// matchGPT2Pretoken returns the end of the pre-token starting at i
+func matchGPT2Pretoken(text string, i int) int {
+	if text[i] == '\'' {
+		for _, contraction := range gpt2Contractions {
+			if strings.HasPrefix(text[i:], contraction) {
+				return i + len(contraction)
+			}
+		}
+	}
+
+	// An optional single space followed by letters, numbers or other symbols
+	start := i
+	if text[i] == ' ' && i+1 < len(text) {
+		start = i + 1
+	}
+	r, _ := utf8.DecodeRuneInString(text[start:])
+	switch {
+	case unicode.IsLetter(r):
+		return scanRunes(text, start, unicode.IsLetter)
+	case unicode.IsNumber(r):
+		return scanRunes(text, start, unicode.IsNumber)
+	case !unicode.IsSpace(r):
+		return scanRunes(text, start, isGPT2Symbol)
+	}
+
+	// Whitespace: the run minus its last character when a non-space follows, so that
+	// character can prefix the next word; otherwise the whole run
+	end := scanRunes(text, i, unicode.IsSpace)
+	if end == len(text) {
+		return end
+	}
+	_, lastSize := utf8.DecodeLastRuneInString(text[i:end])
+	if end-lastSize > i {
+		return end - lastSize
+	}
+	return end
+}
+
+// isGPT2Symbol matches [^\s\p{L}\p{N}]
+func isGPT2Symbol(r rune) bool {
+	return !unicode.IsSpace(r) && !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+// scanRunes returns the end of the run of runes starting at i that satisfy match
+func scanRunes(text string, i int, match func(rune) bool) int {
+	for i < len(text) {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !match(r) {
+			break
+		}
+		i += size
+	}
+	return i
+}
+
+// RegisterLocalBPETokenizer registers the local BPE tokenizer. It must be initialized
+// with vocab_path and merges_path before use.
+func RegisterLocalBPETokenizer() error {
+	return RegisterGlobal("bpe-local", newBuiltinTokenizer("bpe-local"))
+}
@@ -0,0 +1,206 @@
+package tokenizers
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// newFixtureBPE loads testdata/bpe: the 256 byte symbols with IDs equal to their byte
+// value, plus merges.txt's merges numbered from 256 in order
+func newFixtureBPE(t *testing.T) *LocalBPETokenizer {
+	t.Helper()
+	tokenizer := NewLocalBPETokenizer("bpe-local")
+	err := tokenizer.Initialize(TokenizerConfig{
+		Name: "bpe-local",
+		Type: "bpe",
+		Parameters: map[string]string{
+			"vocab_path":  filepath.Join("testdata", "bpe", "vocab.json"),
+			"merges_path": filepath.Join("testdata", "bpe", "merges.txt"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Initialize returned error: %v", err)
+	}
+	return tokenizer
+}
+
+func TestPretokenizeGPT2(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Hello world", []string{"Hello", " world"}},
+		{"it's 2024!", []string{"it", "'s", " 2024", "!"}},
+		{"we'll see", []string{"we", "'ll", " see"}},
+		{"a  b", []string{"a", " ", " b"}},
+		{"a\n\nb", []string{"a", "\n", "\n", "b"}},
+		{"end  ", []string{"end", "  "}},
+		{"x?!'s", []string{"x", "?!'", "s"}},
+		{"héllo wörld", []string{"héllo", " wörld"}},
+		{"日本語 テキスト", []string{"日本語", " テキスト"}},
+		{"a 🙂", []string{"a", " 🙂"}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			var got []string
+			for _, span := range pretokenizeGPT2(tt.text) {
+				got = append(got, tt.text[span.start:span.end])
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pretokenizeGPT2(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalBPETokenize(t *testing.T) {
+	tokenizer := newFixtureBPE(t)
+
+	tests := []struct {
+		text  string
+		texts []string
+		ids   []int
+	}{
+		// "h e" outranks "l l", and "he ll" is not a merge
+		{"hello the", []string{"he", "ll", "o", " the"}, []int{257, 259, 'o', 258}},
+		{"yell", []string{"y", "ell"}, []int{'y', 260}},
+		// "é" is two bytes with no merges, so each byte is a token
+		{"hé", []string{"h", "\xc3", "\xa9"}, []int{'h', 0xC3, 0xA9}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			result, err := tokenizer.Tokenize(context.Background(), tt.text)
+			if err != nil {
+				t.Fatalf("Tokenize returned error: %v", err)
+			}
+
+			var texts []string
+			var ids []int
+			pos := 0
+			for _, token := range result.Tokens {
+				texts = append(texts, token.Text)
+				ids = append(ids, token.ID)
+				if token.StartPos != pos || tt.text[token.StartPos:token.EndPos] != token.Text {
+					t.Errorf("token %q has offsets %d-%d, want start %d", token.Text, token.StartPos, token.EndPos, pos)
+				}
+				pos = token.EndPos
+			}
+			if pos != len(tt.text) {
+				t.Errorf("tokens cover %d bytes, want %d", pos, len(tt.text))
+			}
+			if !reflect.DeepEqual(texts, tt.texts) {
+				t.Errorf("token texts = %q, want %q", texts, tt.texts)
+			}
+			if !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("token IDs = %v, want %v", ids, tt.ids)
+			}
+		})
+	}
+
+	vocabSize, err := tokenizer.GetVocabSize()
+	if err != nil || vocabSize != 263 {
+		t.Errorf("GetVocabSize = %d, %v, want 263", vocabSize, err)
+	}
+}
+
+func TestLocalBPERequiresPaths(t *testing.T) {
+	tokenizer := NewLocalBPETokenizer("bpe-local")
+	if err := tokenizer.Initialize(TokenizerConfig{Name: "bpe-local", Type: "bpe"}); err == nil {
+		t.Error("expected an error without vocab_path and merges_path")
+	}
+	if _, err := tokenizer.Tokenize(context.Background(), "text"); err == nil {
+		t.Error("expected an error tokenizing before initialization")
+	}
+}
+
+// gpt2GoldenCase is an entry of testdata/bpe/gpt2_golden.json: a text with the
+// pre-tokens GPT-2's pattern splits it into and, when known, its token IDs
+type gpt2GoldenCase struct {
+	Text      string   `json:"text"`
+	IDs       []int    `json:"ids"`
+	Pretokens []string `json:"pretokens"`
+}
+
+func readGPT2Golden(t *testing.T) []gpt2GoldenCase {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "bpe", "gpt2_golden.json"))
+	if err != nil {
+		t.Fatalf("failed to read golden file: %v", err)
+	}
+	var cases []gpt2GoldenCase
+	if err := json.Unmarshal(data, &cases); err != nil {
+		t.Fatalf("failed to parse golden file: %v", err)
+	}
+	return cases
+}
+
+// TestPretokenizeGPT2Golden checks the pre-tokenizer on the golden texts, which
+// cover contractions, digits, runs of whitespace, non-ASCII letters and emoji. The
+// whitespace lookahead of GPT-2's pattern, \s+(?!\S), is the part most easily got
+// wrong.
+func TestPretokenizeGPT2Golden(t *testing.T) {
+	for _, c := range readGPT2Golden(t) {
+		t.Run(c.Text, func(t *testing.T) {
+			var got []string
+			pos := 0
+			for _, span := range pretokenizeGPT2(c.Text) {
+				if span.start != pos || span.end <= span.start {
+					t.Fatalf("span %d-%d does not follow the previous one at %d", span.start, span.end, pos)
+				}
+				got = append(got, c.Text[span.start:span.end])
+				pos = span.end
+			}
+			if !reflect.DeepEqual(got, c.Pretokens) {
+				t.Errorf("pretokenizeGPT2(%q) = %q, want %q", c.Text, got, c.Pretokens)
+			}
+		})
+	}
+}
+
+// TestLocalBPEMatchesGPT2Golden compares against token IDs produced by tiktoken's gpt2
+// encoding. The GPT-2 vocabulary is too large to keep in the repository, so the test
+// runs only when TED_GPT2_BPE_DIR points at a directory with its vocab.json and
+// merges.txt. Golden texts without IDs only check pre-tokenization.
+func TestLocalBPEMatchesGPT2Golden(t *testing.T) {
+	dir := os.Getenv("TED_GPT2_BPE_DIR")
+	if dir == "" {
+		t.Skip("TED_GPT2_BPE_DIR not set")
+	}
+
+	tokenizer := NewLocalBPETokenizer("gpt2-local")
+	err := tokenizer.Initialize(TokenizerConfig{
+		Name: "gpt2-local",
+		Type: "bpe",
+		Parameters: map[string]string{
+			"vocab_path":  filepath.Join(dir, "vocab.json"),
+			"merges_path": filepath.Join(dir, "merges.txt"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Initialize returned error: %v", err)
+	}
+
+	for _, c := range readGPT2Golden(t) {
+		if c.IDs == nil {
+			continue
+		}
+		result, err := tokenizer.Tokenize(context.Background(), c.Text)
+		if err != nil {
+			t.Fatalf("Tokenize(%q) returned error: %v", c.Text, err)
+		}
+		ids := make([]int, len(result.Tokens))
+		for i, token := range result.Tokens {
+			ids[i] = token.ID
+		}
+		if !reflect.DeepEqual(ids, c.IDs) {
+			t.Errorf("Tokenize(%q) IDs = %v, want %v", c.Text, ids, c.IDs)
+		}
+	}
+}
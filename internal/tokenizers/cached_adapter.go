@@ -3,18 +3,40 @@ package tokenizers
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 
 	"github.com/RevBooyah/TokEntropyDrift/internal/cache"
+	"github.com/RevBooyah/TokEntropyDrift/internal/logger"
 )
 
+// tokenBytes approximates the bytes a token takes besides its text: its ID and
+// positions, and the headers of its text and metadata
+const tokenBytes = 48
+
+// CacheSize returns the approximate size of the result in bytes, by which caches
+// bound their memory: the document's length and that of every token
+func (r *TokenizationResult) CacheSize() int64 {
+	size := int64(len(r.Document) + len(r.Tokenizer))
+	for _, token := range r.Tokens {
+		size += tokenBytes + int64(len(token.Text))
+	}
+	return size
+}
+
 // CachedTokenizer wraps a tokenizer with caching functionality
 type CachedTokenizer struct {
 	tokenizer Tokenizer
 	cache     *cache.Cache
 	name      string
+
+	// shared is set when other tokenizers use the cache too, so it is not this
+	// tokenizer's to close; hits and misses count this tokenizer's own lookups
+	shared bool
+	hits   atomic.Int64
+	misses atomic.Int64
 }
 
-// NewCachedTokenizer creates a new cached tokenizer wrapper
+// NewCachedTokenizer creates a new cached tokenizer wrapper with a cache of its own
 func NewCachedTokenizer(tokenizer Tokenizer, cacheConfig cache.CacheConfig) *CachedTokenizer {
 	return &CachedTokenizer{
 		tokenizer: tokenizer,
@@ -23,6 +45,19 @@ func NewCachedTokenizer(tokenizer Tokenizer, cacheConfig cache.CacheConfig) *Cac
 	}
 }
 
+// NewCachedTokenizerWithCache creates a cached tokenizer wrapper that stores its
+// tokenizations in shared, a cache other tokenizers may use as well. Entries are keyed
+// by tokenizer name and configuration, so tokenizers never hit each other's entries.
+// Closing the tokenizer leaves the cache open for its owner to close.
+func NewCachedTokenizerWithCache(tokenizer Tokenizer, shared *cache.Cache) *CachedTokenizer {
+	return &CachedTokenizer{
+		tokenizer: tokenizer,
+		cache:     shared,
+		name:      fmt.Sprintf("cached_%s", tokenizer.Name()),
+		shared:    true,
+	}
+}
+
 // Name returns the cached tokenizer name
 func (c *CachedTokenizer) Name() string {
 	return c.name
@@ -33,21 +68,40 @@ func (c *CachedTokenizer) Type() string {
 	return c.tokenizer.Type()
 }
 
-// Initialize initializes the underlying tokenizer
+// Initialize initializes the underlying tokenizer. Entries cached under a previous
+// configuration can no longer be hit, so they are dropped when the fingerprint changes.
 func (c *CachedTokenizer) Initialize(config TokenizerConfig) error {
-	return c.tokenizer.Initialize(config)
+	previous := c.tokenizer.ConfigFingerprint()
+	if err := c.tokenizer.Initialize(config); err != nil {
+		return err
+	}
+	// Other tokenizers' entries in a shared cache are left alone
+	if c.tokenizer.ConfigFingerprint() != previous && !c.shared {
+		c.cache.Clear()
+	}
+	return nil
+}
+
+// ConfigFingerprint returns the fingerprint of the underlying tokenizer's configuration
+func (c *CachedTokenizer) ConfigFingerprint() string {
+	return c.tokenizer.ConfigFingerprint()
+}
+
+// cacheKey returns the cache key for text, namespaced by the underlying tokenizer's
+// name and configuration so differently configured tokenizers never share entries
+func (c *CachedTokenizer) cacheKey(text string) string {
+	return cache.GenerateKey(c.tokenizer.Name()+"@"+c.tokenizer.ConfigFingerprint(), text)
 }
 
 // Tokenize tokenizes text with caching
 func (c *CachedTokenizer) Tokenize(ctx context.Context, text string) (*TokenizationResult, error) {
 	// Generate cache key
-	cacheKey := cache.GenerateKey(c.tokenizer.Name(), text)
+	cacheKey := c.cacheKey(text)
 
 	// Try to get from cache first
-	if cached, found := c.cache.Get(cacheKey); found {
-		if result, ok := cached.(*TokenizationResult); ok {
-			return result, nil
-		}
+	if result, ok := c.lookup(cacheKey); ok {
+		logger.FromContext(ctx).ForModule(logger.ModuleTokenizers).WithField("tokenizer_name", c.Name()).Debug("Tokenization served from cache")
+		return result, nil
 	}
 
 	// Not in cache, tokenize and cache the result
@@ -69,12 +123,9 @@ func (c *CachedTokenizer) TokenizeBatch(ctx context.Context, texts []string) ([]
 
 	// Check cache for each text
 	for i, text := range texts {
-		cacheKey := cache.GenerateKey(c.tokenizer.Name(), text)
-		if cached, found := c.cache.Get(cacheKey); found {
-			if result, ok := cached.(*TokenizationResult); ok {
-				results[i] = result
-				continue
-			}
+		if result, ok := c.lookup(c.cacheKey(text)); ok {
+			results[i] = result
+			continue
 		}
 		uncachedIndices = append(uncachedIndices, i)
 	}
@@ -101,7 +152,7 @@ func (c *CachedTokenizer) TokenizeBatch(ctx context.Context, texts []string) ([]
 		results[idx] = result
 
 		// Cache the result
-		cacheKey := cache.GenerateKey(c.tokenizer.Name(), texts[idx])
+		cacheKey := c.cacheKey(texts[idx])
 		c.cache.Set(cacheKey, result)
 	}
 
@@ -113,18 +164,46 @@ func (c *CachedTokenizer) GetVocabSize() (int, error) {
 	return c.tokenizer.GetVocabSize()
 }
 
-// Close closes both the cache and the underlying tokenizer
+// Pricing returns the pricing of the underlying tokenizer, if it reports any
+func (c *CachedTokenizer) Pricing() (PricingInfo, error) {
+	if priced, ok := c.tokenizer.(PricedTokenizer); ok {
+		return priced.Pricing()
+	}
+	return PricingInfo{}, nil
+}
+
+// Close closes the underlying tokenizer, and the cache unless it is shared
 func (c *CachedTokenizer) Close() error {
-	c.cache.Close()
+	if !c.shared {
+		c.cache.Close()
+	}
 	return c.tokenizer.Close()
 }
 
-// GetCacheStats returns cache statistics
+// GetCacheStats returns cache statistics. Hits and misses are this tokenizer's own
+// lookups; the size, limit and evictions are those of the whole cache, which a shared
+// cache has in common with other tokenizers.
 func (c *CachedTokenizer) GetCacheStats() cache.CacheStats {
-	return c.cache.GetStats()
+	stats := c.cache.GetStats()
+	stats.Hits = c.hits.Load()
+	stats.Misses = c.misses.Load()
+	return stats
 }
 
-// ClearCache clears the tokenizer cache
+// ClearCache clears the tokenizer cache. A shared cache is cleared for every tokenizer
+// using it.
 func (c *CachedTokenizer) ClearCache() {
 	c.cache.Clear()
 }
+
+// lookup returns the cached tokenization for key, counting the hit or miss
+func (c *CachedTokenizer) lookup(key string) (*TokenizationResult, bool) {
+	if cached, found := c.cache.Get(key); found {
+		if result, ok := cached.(*TokenizationResult); ok {
+			c.hits.Add(1)
+			return result, true
+		}
+	}
+	c.misses.Add(1)
+	return nil, false
+}
@@ -0,0 +1,180 @@
+package tokenizers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/cache"
+)
+
+// countingTokenizer counts the documents that reach the wrapped tokenizer
+type countingTokenizer struct {
+	*WhitespaceTokenizer
+	calls int
+}
+
+func (c *countingTokenizer) Tokenize(ctx context.Context, text string) (*TokenizationResult, error) {
+	c.calls++
+	return c.WhitespaceTokenizer.Tokenize(ctx, text)
+}
+
+func (c *countingTokenizer) TokenizeBatch(ctx context.Context, texts []string) ([]*TokenizationResult, error) {
+	return tokenizeEach(ctx, texts, c.Tokenize)
+}
+
+func whitespaceConfig(vocabSize string) TokenizerConfig {
+	return TokenizerConfig{Name: "words", Type: "custom", Parameters: map[string]string{"vocab_size": vocabSize}}
+}
+
+func TestFingerprintConfig(t *testing.T) {
+	base := TokenizerConfig{Name: "gpt2", Type: "bpe", Parameters: map[string]string{"model": "gpt2", "add_special_tokens": "true"}}
+
+	tests := []struct {
+		name  string
+		other TokenizerConfig
+		equal bool
+	}{
+		{"same parameters", TokenizerConfig{Name: "gpt2", Type: "bpe", Parameters: map[string]string{"add_special_tokens": "true", "model": "gpt2"}}, true},
+		{"different name", TokenizerConfig{Name: "other", Type: "bpe", Parameters: map[string]string{"model": "gpt2", "add_special_tokens": "true"}}, true},
+		{"different parameter", TokenizerConfig{Name: "gpt2", Type: "bpe", Parameters: map[string]string{"model": "gpt2", "add_special_tokens": "false"}}, false},
+		{"extra parameter", TokenizerConfig{Name: "gpt2", Type: "bpe", Parameters: map[string]string{"model": "gpt2", "add_special_tokens": "true", "model_path": "/models"}}, false},
+		{"different type", TokenizerConfig{Name: "gpt2", Type: "wordpiece", Parameters: map[string]string{"model": "gpt2", "add_special_tokens": "true"}}, false},
+		{"model file", TokenizerConfig{Name: "gpt2", Type: "bpe", ModelFile: "a.model", Parameters: map[string]string{"model": "gpt2", "add_special_tokens": "true"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			equal := FingerprintConfig(base) == FingerprintConfig(tt.other)
+			if equal != tt.equal {
+				t.Errorf("fingerprints equal = %v, want %v", equal, tt.equal)
+			}
+		})
+	}
+
+	empty := TokenizerConfig{Type: "custom", Parameters: map[string]string{}}
+	if FingerprintConfig(empty) != FingerprintConfig(TokenizerConfig{Type: "custom"}) {
+		t.Error("empty and nil parameters should have the same fingerprint")
+	}
+}
+
+func TestCachedTokenizerReinitialize(t *testing.T) {
+	underlying := &countingTokenizer{WhitespaceTokenizer: NewWhitespaceTokenizer("words")}
+	cached := NewCachedTokenizer(underlying, cache.CacheConfig{MaxSize: 100})
+	defer cached.Close()
+	ctx := context.Background()
+
+	if err := cached.Initialize(whitespaceConfig("1000000")); err != nil {
+		t.Fatalf("Initialize returned error: %v", err)
+	}
+	first, err := cached.Tokenize(ctx, "hello")
+	if err != nil {
+		t.Fatalf("Tokenize returned error: %v", err)
+	}
+	if _, err := cached.Tokenize(ctx, "hello"); err != nil {
+		t.Fatalf("Tokenize returned error: %v", err)
+	}
+	if underlying.calls != 1 {
+		t.Fatalf("underlying tokenizer called %d times, want 1 with a warm cache", underlying.calls)
+	}
+
+	if err := cached.Initialize(whitespaceConfig("7")); err != nil {
+		t.Fatalf("Initialize returned error: %v", err)
+	}
+	second, err := cached.Tokenize(ctx, "hello")
+	if err != nil {
+		t.Fatalf("Tokenize returned error: %v", err)
+	}
+	if underlying.calls != 2 {
+		t.Errorf("underlying tokenizer called %d times, want a miss after reconfiguring", underlying.calls)
+	}
+	if second.Tokens[0].ID >= 7 || second == first {
+		t.Errorf("got token ID %d, want a fresh result with vocab_size 7", second.Tokens[0].ID)
+	}
+
+	// Re-initializing with the same configuration keeps the cache
+	if err := cached.Initialize(whitespaceConfig("7")); err != nil {
+		t.Fatalf("Initialize returned error: %v", err)
+	}
+	if _, err := cached.TokenizeBatch(ctx, []string{"hello"}); err != nil {
+		t.Fatalf("TokenizeBatch returned error: %v", err)
+	}
+	if underlying.calls != 2 {
+		t.Errorf("underlying tokenizer called %d times, want a hit for an unchanged configuration", underlying.calls)
+	}
+}
+
+func TestCachedTokenizerNamespacesByFingerprint(t *testing.T) {
+	underlying := &countingTokenizer{WhitespaceTokenizer: NewWhitespaceTokenizer("words")}
+	cached := NewCachedTokenizer(underlying, cache.CacheConfig{MaxSize: 100})
+	defer cached.Close()
+	ctx := context.Background()
+
+	if err := cached.Initialize(whitespaceConfig("1000000")); err != nil {
+		t.Fatalf("Initialize returned error: %v", err)
+	}
+	if _, err := cached.TokenizeBatch(ctx, []string{"hello", "world"}); err != nil {
+		t.Fatalf("TokenizeBatch returned error: %v", err)
+	}
+
+	// Reconfiguring the wrapped tokenizer directly bypasses the wrapper, but its
+	// fingerprint still moves the cache to new keys
+	if err := underlying.Initialize(whitespaceConfig("5")); err != nil {
+		t.Fatalf("Initialize returned error: %v", err)
+	}
+	results, err := cached.TokenizeBatch(ctx, []string{"hello", "world"})
+	if err != nil {
+		t.Fatalf("TokenizeBatch returned error: %v", err)
+	}
+	if underlying.calls != 4 {
+		t.Errorf("underlying tokenizer called %d times, want 4", underlying.calls)
+	}
+	for _, result := range results {
+		if id := result.Tokens[0].ID; id >= 5 {
+			t.Errorf("got token ID %d from a stale entry, want one below vocab_size 5", id)
+		}
+	}
+	if cached.ConfigFingerprint() != FingerprintConfig(whitespaceConfig("5")) {
+		t.Error("ConfigFingerprint should report the wrapped tokenizer's configuration")
+	}
+}
+
+func TestCachedTokenizersShareCache(t *testing.T) {
+	shared := cache.NewCache(cache.CacheConfig{MaxSize: 100, EnableStats: true})
+	defer shared.Close()
+	words := &countingTokenizer{WhitespaceTokenizer: NewWhitespaceTokenizer("words")}
+	chars := NewCharTokenizer("chars")
+	cachedWords := NewCachedTokenizerWithCache(words, shared)
+	cachedChars := NewCachedTokenizerWithCache(chars, shared)
+	ctx := context.Background()
+
+	for _, text := range []string{"hello world", "hello world", "bye"} {
+		if _, err := cachedWords.Tokenize(ctx, text); err != nil {
+			t.Fatalf("Tokenize returned error: %v", err)
+		}
+	}
+	// The same text under another tokenizer is not a hit
+	if _, err := cachedChars.Tokenize(ctx, "hello world"); err != nil {
+		t.Fatalf("Tokenize returned error: %v", err)
+	}
+	if words.calls != 2 {
+		t.Errorf("underlying tokenizer called %d times, want 2", words.calls)
+	}
+
+	if stats := cachedWords.GetCacheStats(); stats.Hits != 1 || stats.Misses != 2 {
+		t.Errorf("words stats = %+v, want 1 hit and 2 misses", stats)
+	}
+	if stats := cachedChars.GetCacheStats(); stats.Hits != 0 || stats.Misses != 1 {
+		t.Errorf("chars stats = %+v, want 1 miss", stats)
+	}
+	if stats := shared.GetStats(); stats.Size != 3 {
+		t.Errorf("shared cache has %d entries, want 3", stats.Size)
+	}
+
+	// Closing a tokenizer leaves the shared cache to the other
+	if err := cachedChars.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if _, err := cachedWords.Tokenize(ctx, "bye"); err != nil || words.calls != 2 {
+		t.Errorf("Tokenize after the other tokenizer closed = %v with %d calls, want a hit", err, words.calls)
+	}
+}
@@ -0,0 +1,290 @@
+package tokenizers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCommandTimeout bounds one run of an external tokenizer command
+const DefaultCommandTimeout = time.Minute
+
+// DefaultCommandMaxOutputBytes bounds the output read from one run of an external
+// tokenizer command
+const DefaultCommandMaxOutputBytes = 64 << 20
+
+// CommandTokenizer runs an external program to tokenize text. The program reads
+// {"texts": [...]} on stdin and writes a JSON array on stdout with one element per
+// text, in order, each shaped like a TokenizationResult:
+//
+//	[{"tokens": [{"text": "Hi", "id": 17, "start_pos": 0, "end_pos": 2}], "metadata": {}}]
+//
+// Positions are byte offsets into the UTF-8 text. An element may instead carry an
+// "error" string to fail that text. See examples/command_tokenizer.py.
+type CommandTokenizer struct {
+	*BaseTokenizer
+	command        []string
+	timeout        time.Duration
+	maxOutputBytes int64
+	maxBatchBytes  int
+	vocabSize      int
+}
+
+// NewCommandTokenizer creates a new external command tokenizer
+func NewCommandTokenizer(name string) *CommandTokenizer {
+	return &CommandTokenizer{
+		BaseTokenizer:  NewBaseTokenizer(name),
+		timeout:        DefaultCommandTimeout,
+		maxOutputBytes: DefaultCommandMaxOutputBytes,
+		maxBatchBytes:  DefaultMaxBatchBytes,
+	}
+}
+
+// Initialize reads the command from the config's Command field or the command
+// parameter, and the optional timeout, max_output_bytes, max_batch_bytes and
+// vocab_size parameters
+func (c *CommandTokenizer) Initialize(config TokenizerConfig) error {
+	if err := c.BaseTokenizer.Initialize(config); err != nil {
+		return err
+	}
+
+	commandLine := config.Command
+	if command, ok := config.Parameters["command"]; ok {
+		commandLine = command
+	}
+	command, err := SplitCommand(commandLine)
+	if err != nil {
+		return err
+	}
+	if len(command) == 0 {
+		return fmt.Errorf("command is required for command tokenizer %s", config.Name)
+	}
+	c.command = command
+
+	if value, ok := config.Parameters["timeout"]; ok {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf("invalid timeout parameter: %s", value)
+		}
+		c.timeout = timeout
+	}
+
+	if value, ok := config.Parameters["max_output_bytes"]; ok {
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || size <= 0 {
+			return fmt.Errorf("invalid max_output_bytes parameter: %s", value)
+		}
+		c.maxOutputBytes = size
+	}
+
+	maxBatchBytes, err := parseMaxBatchBytes(config.Parameters, c.maxBatchBytes)
+	if err != nil {
+		return err
+	}
+	c.maxBatchBytes = maxBatchBytes
+
+	if value, ok := config.Parameters["vocab_size"]; ok {
+		vocabSize, err := strconv.Atoi(value)
+		if err != nil || vocabSize <= 0 {
+			return fmt.Errorf("invalid vocab_size parameter: %s", value)
+		}
+		c.vocabSize = vocabSize
+	}
+
+	return nil
+}
+
+// Tokenize tokenizes a single document with the external command
+func (c *CommandTokenizer) Tokenize(ctx context.Context, text string) (*TokenizationResult, error) {
+	results, err := c.TokenizeBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// TokenizeBatch tokenizes multiple documents, running the command once per batch of
+// at most max_batch_bytes of text
+func (c *CommandTokenizer) TokenizeBatch(ctx context.Context, texts []string) ([]*TokenizationResult, error) {
+	if len(c.command) == 0 {
+		return nil, fmt.Errorf("tokenizer %s is not initialized", c.Name())
+	}
+
+	results := make([]*TokenizationResult, len(texts))
+	for _, batch := range splitBatches(texts, c.maxBatchBytes) {
+		if err := c.runBatch(ctx, texts[batch.start:batch.end], results[batch.start:batch.end]); err != nil {
+			return nil, fmt.Errorf("command tokenizer error for documents %d-%d: %w", batch.start, batch.end-1, err)
+		}
+	}
+
+	return results, nil
+}
+
+// commandResult is one element of the command's output array
+type commandResult struct {
+	Tokens   []Token                `json:"tokens"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+}
+
+// runBatch runs the command once for texts and stores a result per text in results
+func (c *CommandTokenizer) runBatch(ctx context.Context, texts []string, results []*TokenizationResult) error {
+	payload, err := json.Marshal(struct {
+		Texts []string `json:"texts"`
+	}{Texts: texts})
+	if err != nil {
+		return fmt.Errorf("failed to encode batch: %w", err)
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(runCtx, c.command[0], c.command[1:]...)
+	cmd.Stdin = bytes.NewReader(payload)
+	stdout := NewLimitedBuffer(c.maxOutputBytes)
+	cmd.Stdout = stdout
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	// Don't wait on children that inherited the output pipes after a kill
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+	switch {
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case errors.Is(runCtx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("command timed out after %s", c.timeout)
+	case stdout.Exceeded():
+		return fmt.Errorf("command output exceeds %d bytes", c.maxOutputBytes)
+	case err != nil:
+		return fmt.Errorf("command failed: %w%s", err, formatStderr(stderr.String()))
+	}
+
+	var elements []commandResult
+	if err := json.Unmarshal(stdout.Bytes(), &elements); err != nil {
+		return fmt.Errorf("failed to parse command output: %w", err)
+	}
+	if len(elements) != len(texts) {
+		return fmt.Errorf("command returned %d results for %d texts", len(elements), len(texts))
+	}
+
+	for i, element := range elements {
+		if element.Error != "" {
+			return fmt.Errorf("item %d: %s", i, element.Error)
+		}
+
+		metadata := element.Metadata
+		if metadata == nil {
+			metadata = make(map[string]interface{})
+		}
+		metadata["tokenizer_type"] = "command"
+		if warning := validateTokenOffsets(texts[i], element.Tokens); warning != "" {
+			metadata["offset_warning"] = warning
+		}
+
+		results[i] = &TokenizationResult{
+			Document:  texts[i],
+			Tokens:    element.Tokens,
+			Tokenizer: c.Name(),
+			Metadata:  metadata,
+		}
+	}
+
+	return nil
+}
+
+// ErrOutputLimit stops copying command output once a LimitedBuffer is full
+var ErrOutputLimit = errors.New("output limit exceeded")
+
+// LimitedBuffer collects up to a limit of bytes and fails writes beyond that, which
+// ends the copy from a command's stdout. The buffer is not embedded so io.Copy cannot
+// bypass Write through bytes.Buffer's ReadFrom.
+type LimitedBuffer struct {
+	buf      bytes.Buffer
+	limit    int64
+	exceeded bool
+}
+
+// NewLimitedBuffer creates a buffer that holds at most limit bytes
+func NewLimitedBuffer(limit int64) *LimitedBuffer {
+	return &LimitedBuffer{limit: limit}
+}
+
+// Write appends p unless that would take the buffer past its limit
+func (b *LimitedBuffer) Write(p []byte) (int, error) {
+	if int64(b.buf.Len()+len(p)) > b.limit {
+		b.exceeded = true
+		return 0, ErrOutputLimit
+	}
+	return b.buf.Write(p)
+}
+
+// Bytes returns the collected output
+func (b *LimitedBuffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
+
+// Exceeded reports whether a write was refused for going past the limit
+func (b *LimitedBuffer) Exceeded() bool {
+	return b.exceeded
+}
+
+// GetVocabSize returns the vocab_size parameter; the protocol has no way to ask the
+// command for it
+func (c *CommandTokenizer) GetVocabSize() (int, error) {
+	if c.vocabSize == 0 {
+		return 0, fmt.Errorf("vocabulary size of command tokenizer %s is unknown; set the vocab_size parameter", c.Name())
+	}
+	return c.vocabSize, nil
+}
+
+// SplitCommand splits a command line into arguments. Single and double quotes group
+// words and a backslash escapes the next character outside single quotes; no other
+// shell syntax is interpreted.
+func SplitCommand(line string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != '\'' && r == '\\' && i+1 < len(runes):
+			i++
+			current.WriteRune(runes[i])
+			inArg = true
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in command: %s", line)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
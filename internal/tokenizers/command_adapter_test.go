@@ -0,0 +1,200 @@
+package tokenizers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newShellTokenizer writes script to an executable shell script and returns a
+// command tokenizer that runs it with parameters
+func newShellTokenizer(t *testing.T, script string, parameters map[string]string) *CommandTokenizer {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	path := filepath.Join(t.TempDir(), "tokenize me.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+
+	tokenizer := NewCommandTokenizer("command")
+	err := tokenizer.Initialize(TokenizerConfig{
+		Name:       "command",
+		Type:       "custom",
+		Command:    "'" + path + "'",
+		Parameters: parameters,
+	})
+	if err != nil {
+		t.Fatalf("Initialize returned error: %v", err)
+	}
+	return tokenizer
+}
+
+func TestCommandTokenizer(t *testing.T) {
+	// Ignores its input and answers for the two texts below
+	script := `cat > /dev/null
+echo '[{"tokens": [{"text": "hi", "id": 1, "start_pos": 0, "end_pos": 2}], "metadata": {"model": "sh"}},'
+echo ' {"tokens": [{"text": "yo", "id": 2, "start_pos": 0, "end_pos": 2}]}]'
+`
+	tokenizer := newShellTokenizer(t, script, map[string]string{"vocab_size": "10"})
+
+	results, err := tokenizer.TokenizeBatch(context.Background(), []string{"hi", "yo!"})
+	if err != nil {
+		t.Fatalf("TokenizeBatch returned error: %v", err)
+	}
+	if got := tokenTexts(results[0]); !reflect.DeepEqual(got, []string{"hi"}) {
+		t.Errorf("tokens = %q, want [hi]", got)
+	}
+	if results[1].Document != "yo!" || results[1].Tokenizer != "command" {
+		t.Errorf("result = %+v, want the document and tokenizer name filled in", results[1])
+	}
+	if results[0].Metadata["model"] != "sh" {
+		t.Errorf("metadata = %v, want the command's metadata kept", results[0].Metadata)
+	}
+	if _, ok := results[1].Metadata["offset_warning"]; !ok {
+		t.Error("expected an offset warning for the uncovered '!'")
+	}
+
+	if size, err := tokenizer.GetVocabSize(); err != nil || size != 10 {
+		t.Errorf("GetVocabSize = %d, %v, want 10", size, err)
+	}
+}
+
+func TestCommandTokenizerReadsInput(t *testing.T) {
+	// Echoes the request back inside an error, proving the texts arrive on stdin
+	script := `printf '[{"error": %s}]' "$(cat | sed 's/"/\\"/g; s/^/"/; s/$/"/')"
+`
+	tokenizer := newShellTokenizer(t, script, nil)
+
+	_, err := tokenizer.Tokenize(context.Background(), "hello")
+	if err == nil || !strings.Contains(err.Error(), `{"texts":["hello"]}`) {
+		t.Errorf("expected the request in the error, got %v", err)
+	}
+}
+
+func TestCommandTokenizerFailures(t *testing.T) {
+	tests := []struct {
+		name       string
+		script     string
+		parameters map[string]string
+		want       string
+	}{
+		{"exit status", "echo broken model >&2\nexit 3\n", nil, "broken model"},
+		{"invalid json", "echo not json\n", nil, "failed to parse command output"},
+		{"wrong count", "echo '[]'\n", nil, "returned 0 results for 1 texts"},
+		{"item error", `echo '[{"error": "unsupported script"}]'` + "\n", nil, "item 0: unsupported script"},
+		{"timeout", "sleep 5\n", map[string]string{"timeout": "100ms"}, "timed out after 100ms"},
+		{"output limit", "head -c 100000 /dev/zero\n", map[string]string{"max_output_bytes": "1000"}, "exceeds 1000 bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokenizer := newShellTokenizer(t, "cat > /dev/null\n"+tt.script, tt.parameters)
+			_, err := tokenizer.Tokenize(context.Background(), "text")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error mentioning %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	buffer := NewLimitedBuffer(5)
+	if _, err := buffer.Write([]byte("abc")); err != nil {
+		t.Fatalf("Write within the limit returned error: %v", err)
+	}
+	if _, err := buffer.Write([]byte("de")); err != nil || buffer.Exceeded() {
+		t.Fatalf("Write up to the limit = %v, exceeded %v", err, buffer.Exceeded())
+	}
+
+	// io.Copy goes through Write and stops at the first refused chunk
+	if _, err := io.Copy(buffer, strings.NewReader("f")); !errors.Is(err, ErrOutputLimit) {
+		t.Errorf("copy past the limit = %v, want ErrOutputLimit", err)
+	}
+	if !buffer.Exceeded() || string(buffer.Bytes()) != "abcde" {
+		t.Errorf("buffer = %q, exceeded %v, want the first 5 bytes and exceeded", buffer.Bytes(), buffer.Exceeded())
+	}
+}
+
+func TestCommandTokenizerRequiresCommand(t *testing.T) {
+	tokenizer := NewCommandTokenizer("command")
+	if err := tokenizer.Initialize(TokenizerConfig{Name: "command", Type: "custom"}); err == nil {
+		t.Error("expected an error without a command")
+	}
+	if err := tokenizer.Initialize(TokenizerConfig{Name: "command", Type: "custom", Command: `tok "unterminated`}); err == nil {
+		t.Error("expected an error for an unterminated quote")
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"python3 tok.py", []string{"python3", "tok.py"}},
+		{`  spaced   out  `, []string{"spaced", "out"}},
+		{`"/opt/my tools/tok" --model 'a b'`, []string{"/opt/my tools/tok", "--model", "a b"}},
+		{`tok a\ b 'it\s' "say \"hi\""`, []string{"tok", "a b", `it\s`, `say "hi"`}},
+		{`tok ""`, []string{"tok", ""}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, err := SplitCommand(tt.line)
+			if err != nil {
+				t.Fatalf("SplitCommand returned error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitCommand(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandTokenizerExample(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not available")
+	}
+	example, err := filepath.Abs(filepath.Join("..", "..", "examples", "command_tokenizer.py"))
+	if err != nil {
+		t.Fatalf("failed to resolve example path: %v", err)
+	}
+
+	tokenizer, err := NewConfiguredTokenizer(TokenizerConfig{
+		Name:    "example-command",
+		Type:    "custom",
+		Command: python + " '" + example + "'",
+	})
+	if err != nil {
+		t.Fatalf("NewConfiguredTokenizer returned error: %v", err)
+	}
+	if _, ok := tokenizer.(*CommandTokenizer); !ok {
+		t.Fatalf("got %T, want a command tokenizer", tokenizer)
+	}
+
+	texts := []string{"Héllo, wörld!", "naïve 🙂 test"}
+	results, err := tokenizer.TokenizeBatch(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("TokenizeBatch returned error: %v", err)
+	}
+	if got, want := tokenTexts(results[0]), []string{"Héllo", ",", "wörld", "!"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tokens = %q, want %q", got, want)
+	}
+	for i, result := range results {
+		for _, token := range result.Tokens {
+			if texts[i][token.StartPos:token.EndPos] != token.Text {
+				t.Errorf("token %q has offsets %d-%d that slice %q", token.Text, token.StartPos, token.EndPos, texts[i][token.StartPos:token.EndPos])
+			}
+		}
+	}
+}
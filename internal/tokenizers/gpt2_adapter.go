@@ -2,19 +2,16 @@ package tokenizers
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"os"
-	"os/exec"
-	"path/filepath"
-	"strings"
 )
 
 // GPT2Tokenizer implements the Tokenizer interface for GPT-2/GPT-3.5/GPT-4 models
 type GPT2Tokenizer struct {
 	*BaseTokenizer
-	modelName  string
-	pythonPath string
+	modelName     string
+	pythonPath    string
+	venvPath      string
+	maxBatchBytes int
 }
 
 // NewGPT2Tokenizer creates a new GPT-2 tokenizer
@@ -23,9 +20,22 @@ func NewGPT2Tokenizer(name string) *GPT2Tokenizer {
 		BaseTokenizer: NewBaseTokenizer(name),
 		modelName:     "gpt2",
 		pythonPath:    "python3",
+		maxBatchBytes: DefaultMaxBatchBytes,
 	}
 }
 
+// gptModels are the models the tiktoken script accepts
+var gptModels = map[string]bool{
+	"gpt2":          true,
+	"gpt2-medium":   true,
+	"gpt2-large":    true,
+	"gpt2-xl":       true,
+	"gpt-3.5-turbo": true,
+	"gpt-4":         true,
+	"gpt-4-turbo":   true,
+	"gpt-4o":        true,
+}
+
 // Initialize sets up the GPT-2 tokenizer
 func (g *GPT2Tokenizer) Initialize(config TokenizerConfig) error {
 	if err := g.BaseTokenizer.Initialize(config); err != nil {
@@ -37,124 +47,69 @@ func (g *GPT2Tokenizer) Initialize(config TokenizerConfig) error {
 		g.modelName = model
 	}
 
-	// Set Python path from config
-	if pythonPath, ok := config.Parameters["python_path"]; ok {
-		g.pythonPath = pythonPath
-	}
+	// Set Python interpreter and virtualenv from config
+	g.pythonPath, g.venvPath = pythonParameters(config.Parameters, g.pythonPath, g.venvPath)
 
-	// Validate model name
-	validModels := map[string]bool{
-		"gpt2":          true,
-		"gpt2-medium":   true,
-		"gpt2-large":    true,
-		"gpt2-xl":       true,
-		"gpt-3.5-turbo": true,
-		"gpt-4":         true,
+	maxBatchBytes, err := parseMaxBatchBytes(config.Parameters, g.maxBatchBytes)
+	if err != nil {
+		return err
 	}
+	g.maxBatchBytes = maxBatchBytes
 
-	if !validModels[g.modelName] {
+	// Validate model name
+	if !gptModels[g.modelName] {
 		return fmt.Errorf("invalid GPT model: %s", g.modelName)
 	}
 
-	return nil
+	return probePython(g.pythonPath, g.venvPath, "tiktoken")
 }
 
 // Tokenize tokenizes a single document using tiktoken
 func (g *GPT2Tokenizer) Tokenize(ctx context.Context, text string) (*TokenizationResult, error) {
-	// Create Python script for tokenization
-	script := fmt.Sprintf(`
-import tiktoken
-import json
-import sys
-
-try:
-    # Read text from stdin
-    text = sys.stdin.read()
-    
-    # Initialize tokenizer
-    encoding = tiktoken.encoding_for_model("%s")
-    
-    # Tokenize text
-    tokens = encoding.encode(text)
-    
-    # Get token texts
-    token_texts = []
-    for token_id in tokens:
-        token_text = encoding.decode([token_id])
-        token_texts.append({
-            "id": token_id,
-            "text": token_text,
-            "start_pos": 0,  # tiktoken doesn't provide position info
-            "end_pos": len(token_text)
-        })
-    
-    # Create result
-    result = {
-        "document": text,
-        "tokens": token_texts,
-        "tokenizer": "%s",
-        "metadata": {
-            "model": "%s",
-            "vocab_size": encoding.n_vocab
-        }
-    }
-    
-    print(json.dumps(result))
-    
-except Exception as e:
-    print(json.dumps({"error": str(e)}), file=sys.stderr)
-    sys.exit(1)
-`, g.modelName, g.Name(), g.modelName)
-
-	// Execute Python script with virtual environment
-	cmd := exec.CommandContext(ctx, g.pythonPath, "-c", script)
-	cmd.Stdin = strings.NewReader(text)
-
-	// Set virtual environment variables
-	cmd.Env = append(os.Environ(),
-		"VIRTUAL_ENV="+filepath.Join(".", "venv"),
-		"PATH="+filepath.Join(".", "venv", "bin")+":"+os.Getenv("PATH"),
-	)
-
-	output, err := cmd.Output()
+	results, err := g.TokenizeBatch(ctx, []string{text})
 	if err != nil {
-		// Try to get error output
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return nil, fmt.Errorf("tiktoken error: %s", string(exitErr.Stderr))
-		}
-		return nil, fmt.Errorf("failed to execute tiktoken: %w", err)
+		return nil, err
 	}
+	return results[0], nil
+}
 
-	// Parse JSON output
-	var result struct {
-		Document string `json:"document"`
-		Tokens   []struct {
-			ID       int    `json:"id"`
-			Text     string `json:"text"`
-			StartPos int    `json:"start_pos"`
-			EndPos   int    `json:"end_pos"`
-		} `json:"tokens"`
-		Tokenizer string                 `json:"tokenizer"`
-		Metadata  map[string]interface{} `json:"metadata"`
-		Error     string                 `json:"error,omitempty"`
+// TokenizeBatch tokenizes multiple documents, running one Python process per batch
+// of at most maxBatchBytes of text
+func (g *GPT2Tokenizer) TokenizeBatch(ctx context.Context, texts []string) ([]*TokenizationResult, error) {
+	results := make([]*TokenizationResult, len(texts))
+	env := scriptEnv(g.venvPath, g.modelName, "")
+
+	for _, batch := range splitBatches(texts, g.maxBatchBytes) {
+		batchTexts := texts[batch.start:batch.end]
+		err := runPythonBatch(ctx, g.pythonPath, gpt2BatchScript, env, batchTexts, func(i int, result *pythonTokenization) error {
+			results[batch.start+i] = g.convertResult(batchTexts[i], result)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("tiktoken error for documents %d-%d: %w", batch.start, batch.end-1, err)
+		}
 	}
 
-	if err := json.Unmarshal(output, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse tiktoken output: %w", err)
-	}
+	return results, nil
+}
 
-	if result.Error != "" {
-		return nil, fmt.Errorf("tiktoken error: %s", result.Error)
+// convertResult converts a script result line to our token format. tiktoken reports
+// no positions, so byte offsets are rebuilt from each token's byte length and checked
+// against the document.
+func (g *GPT2Tokenizer) convertResult(text string, result *pythonTokenization) *TokenizationResult {
+	lengths := make([]int, len(result.Tokens))
+	for i, t := range result.Tokens {
+		lengths[i] = t.ByteLength
 	}
+	offsets := offsetsFromByteLengths(text, lengths)
 
-	// Convert to our token format
 	tokens := make([]Token, len(result.Tokens))
 	for i, t := range result.Tokens {
 		tokens[i] = Token{
 			Text:     t.Text,
 			ID:       t.ID,
-			StartPos: t.StartPos,
-			EndPos:   t.EndPos,
+			StartPos: offsets[i][0],
+			EndPos:   offsets[i][1],
 			Metadata: map[string]string{
 				"tokenizer": "gpt2",
 				"model":     g.modelName,
@@ -162,70 +117,33 @@ except Exception as e:
 		}
 	}
 
+	metadata := map[string]interface{}{
+		"model":      g.modelName,
+		"vocab_size": result.VocabSize,
+	}
+	if warning := validateTokenOffsets(text, tokens); warning != "" {
+		metadata["offset_warning"] = warning
+	}
+
 	return &TokenizationResult{
-		Document:  result.Document,
+		Document:  text,
 		Tokens:    tokens,
-		Tokenizer: result.Tokenizer,
-		Metadata:  result.Metadata,
-	}, nil
-}
-
-// TokenizeBatch tokenizes multiple documents
-func (g *GPT2Tokenizer) TokenizeBatch(ctx context.Context, texts []string) ([]*TokenizationResult, error) {
-	results := make([]*TokenizationResult, len(texts))
-
-	for i, text := range texts {
-		result, err := g.Tokenize(ctx, text)
-		if err != nil {
-			return nil, fmt.Errorf("error tokenizing document %d: %w", i, err)
-		}
-		results[i] = result
+		Tokenizer: g.Name(),
+		Metadata:  metadata,
 	}
-
-	return results, nil
 }
 
 // GetVocabSize returns the vocabulary size
 func (g *GPT2Tokenizer) GetVocabSize() (int, error) {
-	// Create Python script to get vocab size
-	script := fmt.Sprintf(`
-import tiktoken
-import json
-
-try:
-    encoding = tiktoken.encoding_for_model("%s")
-    print(json.dumps({"vocab_size": encoding.n_vocab}))
-except Exception as e:
-    print(json.dumps({"error": str(e)}))
-`, g.modelName)
-
-	cmd := exec.Command(g.pythonPath, "-c", script)
-
-	// Set virtual environment variables
-	cmd.Env = append(os.Environ(),
-		"VIRTUAL_ENV="+filepath.Join(".", "venv"),
-		"PATH="+filepath.Join(".", "venv", "bin")+":"+os.Getenv("PATH"),
-	)
-
-	output, err := cmd.Output()
+	vocabSize := 0
+	err := runPythonBatch(context.Background(), g.pythonPath, gpt2BatchScript, scriptEnv(g.venvPath, g.modelName, ""), []string{""}, func(_ int, result *pythonTokenization) error {
+		vocabSize = result.VocabSize
+		return nil
+	})
 	if err != nil {
 		return 0, fmt.Errorf("failed to get vocab size: %w", err)
 	}
-
-	var result struct {
-		VocabSize int    `json:"vocab_size"`
-		Error     string `json:"error,omitempty"`
-	}
-
-	if err := json.Unmarshal(output, &result); err != nil {
-		return 0, fmt.Errorf("failed to parse vocab size output: %w", err)
-	}
-
-	if result.Error != "" {
-		return 0, fmt.Errorf("error getting vocab size: %s", result.Error)
-	}
-
-	return result.VocabSize, nil
+	return vocabSize, nil
 }
 
 // Close cleans up resources
@@ -236,20 +154,15 @@ func (g *GPT2Tokenizer) Close() error {
 
 // RegisterGPT2Tokenizer registers the GPT-2 tokenizer with the global registry
 func RegisterGPT2Tokenizer() error {
-	gpt2Tokenizer := NewGPT2Tokenizer("gpt2")
-	return RegisterGlobal("gpt2", gpt2Tokenizer)
+	return RegisterGlobal("gpt2", newBuiltinTokenizer("gpt2"))
 }
 
 // RegisterGPT35Tokenizer registers the GPT-3.5 tokenizer
 func RegisterGPT35Tokenizer() error {
-	gpt35Tokenizer := NewGPT2Tokenizer("gpt-3.5-turbo")
-	gpt35Tokenizer.modelName = "gpt-3.5-turbo"
-	return RegisterGlobal("gpt-3.5-turbo", gpt35Tokenizer)
+	return RegisterGlobal("gpt-3.5-turbo", newBuiltinTokenizer("gpt-3.5-turbo"))
 }
 
 // RegisterGPT4Tokenizer registers the GPT-4 tokenizer
 func RegisterGPT4Tokenizer() error {
-	gpt4Tokenizer := NewGPT2Tokenizer("gpt-4")
-	gpt4Tokenizer.modelName = "gpt-4"
-	return RegisterGlobal("gpt-4", gpt4Tokenizer)
+	return RegisterGlobal("gpt-4", newBuiltinTokenizer("gpt-4"))
 }
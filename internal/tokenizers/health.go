@@ -0,0 +1,81 @@
+package tokenizers
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// healthCheckTimeout bounds a health check's smoke tokenization. It allows for a
+// Python worker loading its model, which the first check of a tokenizer may start.
+const healthCheckTimeout = 10 * time.Second
+
+// healthCheckText is tokenized by health checks; every vocabulary has it as one token
+const healthCheckText = "a"
+
+// HealthChecker is implemented by tokenizers whose backend can stop working after
+// Initialize, such as a Python environment or a remote endpoint
+type HealthChecker interface {
+	// HealthCheck returns why the tokenizer cannot tokenize now, or nil
+	HealthCheck(ctx context.Context) error
+}
+
+// HealthCheck reports the tokenizer as healthy; adapters with a backend override it
+func (b *BaseTokenizer) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
+// CheckHealth runs the tokenizer's health check, if it has one
+func CheckHealth(ctx context.Context, tokenizer Tokenizer) error {
+	if checker, ok := tokenizer.(HealthChecker); ok {
+		return checker.HealthCheck(ctx)
+	}
+	return nil
+}
+
+// smokeTokenize checks that tokenizer can tokenize healthCheckText within
+// healthCheckTimeout
+func smokeTokenize(ctx context.Context, tokenizer Tokenizer) error {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	result, err := tokenizer.Tokenize(ctx, healthCheckText)
+	if err != nil {
+		return fmt.Errorf("tokenizer %s is unavailable: %w", tokenizer.Name(), err)
+	}
+	if len(result.Tokens) == 0 {
+		return fmt.Errorf("tokenizer %s returned no tokens for %q", tokenizer.Name(), healthCheckText)
+	}
+	return nil
+}
+
+// HealthCheck tokenizes one token with tiktoken
+func (g *GPT2Tokenizer) HealthCheck(ctx context.Context) error {
+	return smokeTokenize(ctx, g)
+}
+
+// HealthCheck tokenizes one token with the transformers worker
+func (h *HuggingFaceTokenizer) HealthCheck(ctx context.Context) error {
+	return smokeTokenize(ctx, h)
+}
+
+// HealthCheck tokenizes one token with the sentencepiece worker
+func (s *SentencePieceTokenizer) HealthCheck(ctx context.Context) error {
+	return smokeTokenize(ctx, s)
+}
+
+// HealthCheck tokenizes one token with the configured command
+func (c *CommandTokenizer) HealthCheck(ctx context.Context) error {
+	return smokeTokenize(ctx, c)
+}
+
+// HealthCheck tokenizes one token with the endpoint, or with local tiktoken
+func (o *OpenAITokenizer) HealthCheck(ctx context.Context) error {
+	return smokeTokenize(ctx, o)
+}
+
+// HealthCheck checks the underlying tokenizer, bypassing the cache, which could
+// still answer for a backend that has gone away
+func (c *CachedTokenizer) HealthCheck(ctx context.Context) error {
+	return CheckHealth(ctx, c.tokenizer)
+}
@@ -0,0 +1,95 @@
+package tokenizers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/cache"
+)
+
+func TestCheckHealth(t *testing.T) {
+	healthy := `cat > /dev/null
+echo '[{"tokens": [{"text": "a", "id": 1, "start_pos": 0, "end_pos": 1}]}]'
+`
+	tests := []struct {
+		name      string
+		tokenizer func(t *testing.T) Tokenizer
+		wantErr   string
+	}{
+		{
+			name:      "no backend",
+			tokenizer: func(t *testing.T) Tokenizer { return NewMockTokenizer("mock") },
+		},
+		{
+			name:      "command",
+			tokenizer: func(t *testing.T) Tokenizer { return newShellTokenizer(t, healthy, nil) },
+		},
+		{
+			name: "failing command",
+			tokenizer: func(t *testing.T) Tokenizer {
+				return newShellTokenizer(t, "echo 'model not found' >&2; exit 3", nil)
+			},
+			wantErr: "model not found",
+		},
+		{
+			name: "no tokens",
+			tokenizer: func(t *testing.T) Tokenizer {
+				return newShellTokenizer(t, `cat > /dev/null; echo '[{"tokens": []}]'`, nil)
+			},
+			wantErr: "returned no tokens",
+		},
+		{
+			name: "cached",
+			tokenizer: func(t *testing.T) Tokenizer {
+				return NewCachedTokenizer(newShellTokenizer(t, healthy, nil), cache.CacheConfig{MaxSize: 10})
+			},
+		},
+		{
+			name: "cached failing command",
+			tokenizer: func(t *testing.T) Tokenizer {
+				return NewCachedTokenizer(newShellTokenizer(t, "exit 1", nil), cache.CacheConfig{MaxSize: 10})
+			},
+			wantErr: "unavailable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokenizer := tt.tokenizer(t)
+			defer tokenizer.Close()
+
+			err := CheckHealth(context.Background(), tokenizer)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("CheckHealth returned error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CheckHealth error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCachedTokenizerHealthBypassesCache(t *testing.T) {
+	inner := newShellTokenizer(t, `cat > /dev/null
+echo '[{"tokens": [{"text": "a", "id": 1, "start_pos": 0, "end_pos": 1}]}]'
+`, nil)
+	tokenizer := NewCachedTokenizer(inner, cache.CacheConfig{MaxSize: 10})
+	defer tokenizer.Close()
+
+	if err := CheckHealth(context.Background(), tokenizer); err != nil {
+		t.Fatalf("CheckHealth returned error: %v", err)
+	}
+	if _, err := tokenizer.Tokenize(context.Background(), healthCheckText); err != nil {
+		t.Fatalf("Tokenize returned error: %v", err)
+	}
+
+	// The backend goes away; the cache would still answer for it
+	inner.command = []string{"false"}
+	if CheckHealth(context.Background(), tokenizer) == nil {
+		t.Error("expected the health check to reach the failing backend")
+	}
+}
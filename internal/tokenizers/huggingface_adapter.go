@@ -4,10 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
-	"os/exec"
-	"path/filepath"
-	"strings"
+	"sync"
 )
 
 // HuggingFaceTokenizer implements the Tokenizer interface for HuggingFace tokenizers
@@ -15,8 +12,13 @@ type HuggingFaceTokenizer struct {
 	*BaseTokenizer
 	modelName     string
 	pythonPath    string
+	venvPath      string
 	modelPath     string
 	tokenizerType string
+	maxBatchBytes int
+
+	workerMu sync.Mutex
+	pyWorker *pythonWorker
 }
 
 // NewHuggingFaceTokenizer creates a new HuggingFace tokenizer
@@ -25,6 +27,7 @@ func NewHuggingFaceTokenizer(name string) *HuggingFaceTokenizer {
 		BaseTokenizer: NewBaseTokenizer(name),
 		pythonPath:    "python3",
 		tokenizerType: "bpe",
+		maxBatchBytes: DefaultMaxBatchBytes,
 	}
 }
 
@@ -44,16 +47,20 @@ func (h *HuggingFaceTokenizer) Initialize(config TokenizerConfig) error {
 		h.modelPath = modelPath
 	}
 
-	// Set Python path from config
-	if pythonPath, ok := config.Parameters["python_path"]; ok {
-		h.pythonPath = pythonPath
-	}
+	// Set Python interpreter and virtualenv from config
+	h.pythonPath, h.venvPath = pythonParameters(config.Parameters, h.pythonPath, h.venvPath)
 
 	// Set tokenizer type from config
 	if tokenizerType, ok := config.Parameters["tokenizer_type"]; ok {
 		h.tokenizerType = tokenizerType
 	}
 
+	maxBatchBytes, err := parseMaxBatchBytes(config.Parameters, h.maxBatchBytes)
+	if err != nil {
+		return err
+	}
+	h.maxBatchBytes = maxBatchBytes
+
 	// Validate tokenizer type
 	validTypes := map[string]bool{
 		"bpe":       true,
@@ -65,113 +72,81 @@ func (h *HuggingFaceTokenizer) Initialize(config TokenizerConfig) error {
 		return fmt.Errorf("invalid tokenizer type: %s", h.tokenizerType)
 	}
 
+	if err := probePython(h.pythonPath, h.venvPath, "transformers"); err != nil {
+		return err
+	}
+
+	// Start the worker with the new settings so the model is loaded once up front
+	h.Close()
+	if err := h.worker().Start(context.Background()); err != nil {
+		return fmt.Errorf("failed to start huggingface worker: %w", err)
+	}
+
 	return nil
 }
 
+// worker returns the persistent Python process for this tokenizer, creating it on
+// first use so that tokenizers registered without Initialize still work
+func (h *HuggingFaceTokenizer) worker() *pythonWorker {
+	h.workerMu.Lock()
+	defer h.workerMu.Unlock()
+
+	if h.pyWorker == nil {
+		env := scriptEnv(h.venvPath, h.modelName, h.modelPath)
+		h.pyWorker = newPythonWorker(h.pythonPath, huggingFaceWorkerScript, env)
+	}
+	return h.pyWorker
+}
+
 // Tokenize tokenizes a single document using HuggingFace tokenizers
 func (h *HuggingFaceTokenizer) Tokenize(ctx context.Context, text string) (*TokenizationResult, error) {
-	// Create Python script for tokenization
-	script := fmt.Sprintf(`
-from transformers import AutoTokenizer
-import json
-import sys
-
-try:
-    # Read text from stdin
-    text = sys.stdin.read()
-    
-    # Initialize tokenizer
-    if "%s":
-        tokenizer = AutoTokenizer.from_pretrained("%s")
-    else:
-        tokenizer = AutoTokenizer.from_pretrained("%s")
-    
-    # Tokenize text
-    encoding = tokenizer(text, return_offsets_mapping=True, add_special_tokens=False)
-    
-    # Extract tokens and positions
-    tokens = encoding.tokens()
-    offset_mapping = encoding.offset_mapping
-    input_ids = encoding.input_ids
-    
-    # Create token objects
-    token_objects = []
-    for i, (token, (start, end)) in enumerate(zip(tokens, offset_mapping)):
-        token_objects.append({
-            "id": input_ids[i] if i < len(input_ids) else 0,
-            "text": token,
-            "start_pos": start,
-            "end_pos": end
-        })
-    
-    # Create result
-    result = {
-        "document": text,
-        "tokens": token_objects,
-        "tokenizer": "%s",
-        "metadata": {
-            "model": "%s",
-            "tokenizer_type": "%s",
-            "vocab_size": tokenizer.vocab_size
-        }
-    }
-    
-    print(json.dumps(result))
-    
-except Exception as e:
-    print(json.dumps({"error": str(e)}), file=sys.stderr)
-    sys.exit(1)
-`, h.modelPath, h.modelPath, h.modelName, h.Name(), h.modelName, h.tokenizerType)
-
-	// Execute Python script with virtual environment
-	cmd := exec.CommandContext(ctx, h.pythonPath, "-c", script)
-	cmd.Stdin = strings.NewReader(text)
-
-	// Set virtual environment variables
-	cmd.Env = append(os.Environ(),
-		"VIRTUAL_ENV="+filepath.Join(".", "venv"),
-		"PATH="+filepath.Join(".", "venv", "bin")+":"+os.Getenv("PATH"),
-	)
-
-	output, err := cmd.Output()
+	results, err := h.TokenizeBatch(ctx, []string{text})
 	if err != nil {
-		// Try to get error output
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return nil, fmt.Errorf("huggingface tokenizer error: %s", string(exitErr.Stderr))
-		}
-		return nil, fmt.Errorf("failed to execute huggingface tokenizer: %w", err)
-	}
-
-	// Parse JSON output
-	var result struct {
-		Document string `json:"document"`
-		Tokens   []struct {
-			ID       int    `json:"id"`
-			Text     string `json:"text"`
-			StartPos int    `json:"start_pos"`
-			EndPos   int    `json:"end_pos"`
-		} `json:"tokens"`
-		Tokenizer string                 `json:"tokenizer"`
-		Metadata  map[string]interface{} `json:"metadata"`
-		Error     string                 `json:"error,omitempty"`
+		return nil, err
 	}
+	return results[0], nil
+}
 
-	if err := json.Unmarshal(output, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse huggingface tokenizer output: %w", err)
+// TokenizeBatch tokenizes multiple documents, sending the worker one request per
+// batch of at most maxBatchBytes of text
+func (h *HuggingFaceTokenizer) TokenizeBatch(ctx context.Context, texts []string) ([]*TokenizationResult, error) {
+	results := make([]*TokenizationResult, len(texts))
+	worker := h.worker()
+
+	for _, batch := range splitBatches(texts, h.maxBatchBytes) {
+		batchTexts := texts[batch.start:batch.end]
+		request := struct {
+			Texts []string `json:"texts"`
+		}{Texts: batchTexts}
+
+		err := worker.CallEach(ctx, request, len(batchTexts), func(i int, line []byte) error {
+			var result pythonTokenization
+			if err := json.Unmarshal(line, &result); err != nil {
+				return fmt.Errorf("failed to parse huggingface tokenizer output: %w", err)
+			}
+			results[batch.start+i] = h.convertResult(batchTexts[i], &result)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("huggingface tokenizer error for documents %d-%d: %w", batch.start, batch.end-1, err)
+		}
 	}
 
-	if result.Error != "" {
-		return nil, fmt.Errorf("huggingface tokenizer error: %s", result.Error)
-	}
+	return results, nil
+}
 
-	// Convert to our token format
+// convertResult converts a worker result line to our token format. The worker reports
+// offsets as Python string indexes, which count code points, so they are converted to
+// byte offsets and checked against the document.
+func (h *HuggingFaceTokenizer) convertResult(text string, result *pythonTokenization) *TokenizationResult {
+	runes := newRuneOffsets(text)
 	tokens := make([]Token, len(result.Tokens))
 	for i, t := range result.Tokens {
 		tokens[i] = Token{
 			Text:     t.Text,
 			ID:       t.ID,
-			StartPos: t.StartPos,
-			EndPos:   t.EndPos,
+			StartPos: runes.byteOffset(t.StartPos),
+			EndPos:   runes.byteOffset(t.EndPos),
 			Metadata: map[string]string{
 				"tokenizer":      "huggingface",
 				"model":          h.modelName,
@@ -180,110 +155,66 @@ except Exception as e:
 		}
 	}
 
+	metadata := map[string]interface{}{
+		"model":          h.modelName,
+		"tokenizer_type": h.tokenizerType,
+		"vocab_size":     result.VocabSize,
+	}
+	if warning := validateTokenOffsets(text, tokens); warning != "" {
+		metadata["offset_warning"] = warning
+	}
+
 	return &TokenizationResult{
-		Document:  result.Document,
+		Document:  text,
 		Tokens:    tokens,
-		Tokenizer: result.Tokenizer,
-		Metadata:  result.Metadata,
-	}, nil
-}
-
-// TokenizeBatch tokenizes multiple documents
-func (h *HuggingFaceTokenizer) TokenizeBatch(ctx context.Context, texts []string) ([]*TokenizationResult, error) {
-	results := make([]*TokenizationResult, len(texts))
-
-	for i, text := range texts {
-		result, err := h.Tokenize(ctx, text)
-		if err != nil {
-			return nil, fmt.Errorf("error tokenizing document %d: %w", i, err)
-		}
-		results[i] = result
+		Tokenizer: h.Name(),
+		Metadata:  metadata,
 	}
-
-	return results, nil
 }
 
-// GetVocabSize returns the vocabulary size
+// GetVocabSize returns the vocabulary size reported by the worker when it loaded the
+// tokenizer
 func (h *HuggingFaceTokenizer) GetVocabSize() (int, error) {
-	// Create Python script to get vocab size
-	script := fmt.Sprintf(`
-from transformers import AutoTokenizer
-import json
-
-try:
-    if "%s":
-        tokenizer = AutoTokenizer.from_pretrained("%s")
-    else:
-        tokenizer = AutoTokenizer.from_pretrained("%s")
-    
-    print(json.dumps({"vocab_size": tokenizer.vocab_size}))
-except Exception as e:
-    print(json.dumps({"error": str(e)}))
-`, h.modelPath, h.modelPath, h.modelName)
-
-	cmd := exec.Command(h.pythonPath, "-c", script)
-
-	// Set virtual environment variables
-	cmd.Env = append(os.Environ(),
-		"VIRTUAL_ENV="+filepath.Join(".", "venv"),
-		"PATH="+filepath.Join(".", "venv", "bin")+":"+os.Getenv("PATH"),
-	)
-
-	output, err := cmd.Output()
-	if err != nil {
-		return 0, fmt.Errorf("failed to get vocab size: %w", err)
-	}
-
-	var result struct {
-		VocabSize int    `json:"vocab_size"`
-		Error     string `json:"error,omitempty"`
+	var handshake struct {
+		VocabSize int `json:"vocab_size"`
 	}
 
-	if err := json.Unmarshal(output, &result); err != nil {
-		return 0, fmt.Errorf("failed to parse vocab size output: %w", err)
-	}
-
-	if result.Error != "" {
-		return 0, fmt.Errorf("error getting vocab size: %s", result.Error)
+	if err := h.worker().Handshake(context.Background(), &handshake); err != nil {
+		return 0, fmt.Errorf("failed to get vocab size: %w", err)
 	}
 
-	return result.VocabSize, nil
+	return handshake.VocabSize, nil
 }
 
-// Close cleans up resources
+// Close stops the worker process
 func (h *HuggingFaceTokenizer) Close() error {
-	// Nothing to clean up for HuggingFace tokenizer
-	return nil
+	h.workerMu.Lock()
+	defer h.workerMu.Unlock()
+
+	if h.pyWorker == nil {
+		return nil
+	}
+	err := h.pyWorker.Close()
+	h.pyWorker = nil
+	return err
 }
 
 // RegisterRoBERTaTokenizer registers the RoBERTa tokenizer
 func RegisterRoBERTaTokenizer() error {
-	robertaTokenizer := NewHuggingFaceTokenizer("roberta-base")
-	robertaTokenizer.modelName = "roberta-base"
-	robertaTokenizer.tokenizerType = "bpe"
-	return RegisterGlobal("roberta-base", robertaTokenizer)
+	return RegisterGlobal("roberta-base", newBuiltinTokenizer("roberta-base"))
 }
 
 // RegisterGPTNeoTokenizer registers the GPT-Neo tokenizer
 func RegisterGPTNeoTokenizer() error {
-	gptNeoTokenizer := NewHuggingFaceTokenizer("EleutherAI/gpt-neo-125M")
-	gptNeoTokenizer.modelName = "EleutherAI/gpt-neo-125M"
-	gptNeoTokenizer.tokenizerType = "bpe"
-	return RegisterGlobal("gpt-neo", gptNeoTokenizer)
+	return RegisterGlobal("gpt-neo", newBuiltinTokenizer("gpt-neo"))
 }
 
 // RegisterBERTTokenizer registers the BERT tokenizer
 func RegisterBERTTokenizer() error {
-	bertTokenizer := NewHuggingFaceTokenizer("bert-base-uncased")
-	bertTokenizer.modelName = "bert-base-uncased"
-	bertTokenizer.tokenizerType = "wordpiece"
-	return RegisterGlobal("bert-base", bertTokenizer)
+	return RegisterGlobal("bert-base", newBuiltinTokenizer("bert-base"))
 }
 
 // RegisterDistilBERTTokenizer registers the DistilBERT tokenizer
 func RegisterDistilBERTTokenizer() error {
-	distilBertTokenizer := NewHuggingFaceTokenizer("distilbert-base-uncased")
-	distilBertTokenizer.modelName = "distilbert-base-uncased"
-	distilBertTokenizer.tokenizerType = "wordpiece"
-	return RegisterGlobal("distilbert-base", distilBertTokenizer)
+	return RegisterGlobal("distilbert-base", newBuiltinTokenizer("distilbert-base"))
 }
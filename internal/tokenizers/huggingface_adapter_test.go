@@ -0,0 +1,51 @@
+package tokenizers
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+)
+
+func newBenchmarkHuggingFaceTokenizer(b *testing.B) *HuggingFaceTokenizer {
+	b.Helper()
+	if err := exec.Command("python3", "-c", "import transformers").Run(); err != nil {
+		b.Skip("python3 with transformers not available")
+	}
+
+	tokenizer := NewHuggingFaceTokenizer("bert-base")
+	err := tokenizer.Initialize(TokenizerConfig{
+		Name:       "bert-base",
+		Type:       "wordpiece",
+		Parameters: map[string]string{"model": "bert-base-uncased", "tokenizer_type": "wordpiece"},
+	})
+	if err != nil {
+		b.Skipf("huggingface tokenizer unavailable: %v", err)
+	}
+	b.Cleanup(func() { tokenizer.Close() })
+	return tokenizer
+}
+
+// BenchmarkHuggingFaceTokenize compares per-document latency with a persistent worker
+// against starting a fresh Python process (and re-importing transformers) per document
+func BenchmarkHuggingFaceTokenize(b *testing.B) {
+	tokenizer := newBenchmarkHuggingFaceTokenizer(b)
+	ctx := context.Background()
+	text := "The quick brown fox jumps over the lazy dog."
+
+	b.Run("persistent_worker", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := tokenizer.Tokenize(ctx, text); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("process_per_document", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			tokenizer.Close()
+			if _, err := tokenizer.Tokenize(ctx, text); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -2,10 +2,16 @@ package tokenizers
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"sync"
 )
 
-// Token represents a single token with metadata
+// Token represents a single token with metadata. StartPos and EndPos are byte offsets
+// into the UTF-8 document, so Document[StartPos:EndPos] is the text the token covers;
+// adapters convert whatever positions their backend reports to bytes.
 type Token struct {
 	Text      string            `json:"text"`
 	ID        int               `json:"id"`
@@ -52,6 +58,11 @@ type Tokenizer interface {
 	
 	// GetVocabSize returns the vocabulary size of the tokenizer
 	GetVocabSize() (int, error)
+
+	// ConfigFingerprint returns a stable hash of the configuration the tokenizer was
+	// initialized with, so results from differently configured tokenizers that share
+	// a name can be told apart
+	ConfigFingerprint() string
 	
 	// Close cleans up any resources used by the tokenizer
 	Close() error
@@ -59,8 +70,9 @@ type Tokenizer interface {
 
 // BaseTokenizer provides common functionality for tokenizer implementations
 type BaseTokenizer struct {
-	name   string
-	config TokenizerConfig
+	name        string
+	config      TokenizerConfig
+	fingerprint string
 }
 
 // NewBaseTokenizer creates a new base tokenizer
@@ -83,9 +95,33 @@ func (b *BaseTokenizer) Type() string {
 // Initialize sets up the base tokenizer configuration
 func (b *BaseTokenizer) Initialize(config TokenizerConfig) error {
 	b.config = config
+	b.fingerprint = FingerprintConfig(config)
 	return nil
 }
 
+// ConfigFingerprint returns the fingerprint of the configuration given to Initialize
+func (b *BaseTokenizer) ConfigFingerprint() string {
+	if b.fingerprint == "" {
+		return FingerprintConfig(b.config)
+	}
+	return b.fingerprint
+}
+
+// FingerprintConfig hashes everything in config except its name: the type, files,
+// command and parameters. Parameters are hashed in key order, so equal configs always
+// have equal fingerprints.
+func FingerprintConfig(config TokenizerConfig) string {
+	config.Name = ""
+	if len(config.Parameters) == 0 {
+		config.Parameters = nil
+	}
+	// Marshaling a struct of strings and a string map cannot fail, and encoding/json
+	// writes map keys in sorted order
+	encoded, _ := json.Marshal(config)
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:8])
+}
+
 // Close provides a default implementation for cleanup
 func (b *BaseTokenizer) Close() error {
 	// Default implementation does nothing
@@ -117,15 +153,26 @@ func ValidateConfig(config TokenizerConfig) error {
 	return nil
 }
 
-// TokenizerRegistry manages available tokenizers
+// TokenizerRegistry manages available tokenizers. It is safe for concurrent use.
 type TokenizerRegistry struct {
+	mu         sync.RWMutex
 	tokenizers map[string]Tokenizer
+	pending    map[string]*pendingTokenizer
+}
+
+// pendingTokenizer is a tokenizer being created by GetOrCreate; callers asking for
+// the same name wait on done instead of creating a second one
+type pendingTokenizer struct {
+	done      chan struct{}
+	tokenizer Tokenizer
+	err       error
 }
 
 // NewTokenizerRegistry creates a new tokenizer registry
 func NewTokenizerRegistry() *TokenizerRegistry {
 	return &TokenizerRegistry{
 		tokenizers: make(map[string]Tokenizer),
+		pending:    make(map[string]*pendingTokenizer),
 	}
 }
 
@@ -139,6 +186,9 @@ func (r *TokenizerRegistry) Register(name string, tokenizer Tokenizer) error {
 		return fmt.Errorf("tokenizer cannot be nil")
 	}
 	
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if _, exists := r.tokenizers[name]; exists {
 		return fmt.Errorf("tokenizer %s already registered", name)
 	}
@@ -147,8 +197,76 @@ func (r *TokenizerRegistry) Register(name string, tokenizer Tokenizer) error {
 	return nil
 }
 
+// Replace registers a tokenizer under name, returning the tokenizer it replaced or
+// nil. The caller owns the returned tokenizer and should close it.
+func (r *TokenizerRegistry) Replace(name string, tokenizer Tokenizer) (Tokenizer, error) {
+	if name == "" {
+		return nil, fmt.Errorf("tokenizer name cannot be empty")
+	}
+
+	if tokenizer == nil {
+		return nil, fmt.Errorf("tokenizer cannot be nil")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous := r.tokenizers[name]
+	r.tokenizers[name] = tokenizer
+	return previous, nil
+}
+
+// GetOrCreate returns the tokenizer registered under name, creating and registering
+// it with create if there is none. Concurrent callers for the same name share a
+// single call to create; a failed create registers nothing.
+func (r *TokenizerRegistry) GetOrCreate(name string, create func() (Tokenizer, error)) (Tokenizer, error) {
+	if name == "" {
+		return nil, fmt.Errorf("tokenizer name cannot be empty")
+	}
+
+	r.mu.Lock()
+	if tokenizer, exists := r.tokenizers[name]; exists {
+		r.mu.Unlock()
+		return tokenizer, nil
+	}
+	if pending, exists := r.pending[name]; exists {
+		r.mu.Unlock()
+		<-pending.done
+		return pending.tokenizer, pending.err
+	}
+	pending := &pendingTokenizer{done: make(chan struct{})}
+	r.pending[name] = pending
+	r.mu.Unlock()
+
+	// Create outside the lock; starting a Python backend can take seconds
+	tokenizer, err := create()
+	if err == nil && tokenizer == nil {
+		err = fmt.Errorf("tokenizer %s: create returned nil", name)
+	}
+
+	r.mu.Lock()
+	delete(r.pending, name)
+	if err == nil {
+		if existing, exists := r.tokenizers[name]; exists {
+			// Registered directly while we were creating; keep that one
+			tokenizer.Close()
+			tokenizer = existing
+		} else {
+			r.tokenizers[name] = tokenizer
+		}
+	}
+	r.mu.Unlock()
+
+	pending.tokenizer, pending.err = tokenizer, err
+	close(pending.done)
+	return tokenizer, err
+}
+
 // Get retrieves a tokenizer by name
 func (r *TokenizerRegistry) Get(name string) (Tokenizer, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	tokenizer, exists := r.tokenizers[name]
 	if !exists {
 		return nil, fmt.Errorf("tokenizer %s not found", name)
@@ -159,6 +277,9 @@ func (r *TokenizerRegistry) Get(name string) (Tokenizer, error) {
 
 // List returns all registered tokenizer names
 func (r *TokenizerRegistry) List() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	names := make([]string, 0, len(r.tokenizers))
 	for name := range r.tokenizers {
 		names = append(names, name)
@@ -168,6 +289,9 @@ func (r *TokenizerRegistry) List() []string {
 
 // Unregister removes a tokenizer from the registry
 func (r *TokenizerRegistry) Unregister(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if _, exists := r.tokenizers[name]; !exists {
 		return fmt.Errorf("tokenizer %s not found", name)
 	}
@@ -44,6 +44,7 @@ func (m *MockTokenizer) Tokenize(ctx context.Context, text string) (*Tokenizatio
 	words := strings.Fields(text)
 	tokens := make([]Token, len(words))
 	
+	pos := 0
 	for i, word := range words {
 		// Simple hash-based token ID
 		tokenID := 0
@@ -51,9 +52,10 @@ func (m *MockTokenizer) Tokenize(ctx context.Context, text string) (*Tokenizatio
 			tokenID = (tokenID*31 + int(char)) % m.vocabSize
 		}
 		
-		// Find position in original text
-		startPos := strings.Index(text, word)
+		// Find position in original text, after the previous word
+		startPos := pos + strings.Index(text[pos:], word)
 		endPos := startPos + len(word)
+		pos = endPos
 		
 		tokens[i] = Token{
 			Text:     word,
@@ -67,14 +69,19 @@ func (m *MockTokenizer) Tokenize(ctx context.Context, text string) (*Tokenizatio
 		}
 	}
 	
+	metadata := map[string]interface{}{
+		"tokenizer_type": "mock",
+		"vocab_size":     m.vocabSize,
+	}
+	if warning := validateTokenOffsets(text, tokens); warning != "" {
+		metadata["offset_warning"] = warning
+	}
+	
 	return &TokenizationResult{
 		Document:  text,
 		Tokens:    tokens,
 		Tokenizer: m.Name(),
-		Metadata: map[string]interface{}{
-			"tokenizer_type": "mock",
-			"vocab_size":     m.vocabSize,
-		},
+		Metadata:  metadata,
 	}, nil
 }
 
@@ -106,6 +113,5 @@ func (m *MockTokenizer) Close() error {
 
 // RegisterMockTokenizer registers the mock tokenizer with the global registry
 func RegisterMockTokenizer() error {
-	mockTokenizer := NewMockTokenizer("mock")
-	return RegisterGlobal("mock", mockTokenizer)
+	return RegisterGlobal("mock", newBuiltinTokenizer("mock"))
 } 
\ No newline at end of file
@@ -0,0 +1,149 @@
+package tokenizers
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// sentencePieceSpace is the character SentencePiece pieces use in place of spaces
+const sentencePieceSpace = "\u2581"
+
+// offsetsFromByteLengths assigns byte offsets to tokens that encode text back to back,
+// given each token's length in bytes. Byte-level BPE tokens can split a multi-byte
+// character; such a boundary is widened to the character's edges, so both tokens
+// cover the whole character and every offset is a valid slice of text.
+func offsetsFromByteLengths(text string, lengths []int) [][2]int {
+	offsets := make([][2]int, len(lengths))
+
+	pos := 0
+	for i, length := range lengths {
+		start := minOffset(pos, len(text))
+		end := minOffset(pos+length, len(text))
+		pos += length
+
+		for start > 0 && start < len(text) && !utf8.RuneStart(text[start]) {
+			start--
+		}
+		for end < len(text) && !utf8.RuneStart(text[end]) {
+			end++
+		}
+		offsets[i] = [2]int{start, end}
+	}
+
+	return offsets
+}
+
+// runeOffsets maps code point indexes, which Python uses to index strings, to byte
+// offsets into a text. The index one past the last code point maps to the text's length.
+type runeOffsets []int
+
+// newRuneOffsets returns the byte offset of every code point of text
+func newRuneOffsets(text string) runeOffsets {
+	offsets := make(runeOffsets, 0, len(text)+1)
+	for pos := range text {
+		offsets = append(offsets, pos)
+	}
+	return append(offsets, len(text))
+}
+
+// byteOffset returns the byte offset of code point index i, clamped to the text
+func (r runeOffsets) byteOffset(i int) int {
+	if i < 0 {
+		return 0
+	}
+	if i >= len(r) {
+		return r[len(r)-1]
+	}
+	return r[i]
+}
+
+// sentencePieceLengths returns how many bytes of text each SentencePiece piece covers,
+// for offsetsFromByteLengths. Normalization drops and collapses whitespace, so a piece
+// starting with "▁" takes all the whitespace before it, and whatever is left at the end
+// goes to the last piece. A piece that does not match the text, such as "<unk>" or a
+// character changed by normalization, takes one character, and a byte fallback piece
+// such as "<0xE6>" one byte.
+func sentencePieceLengths(text string, pieces []string) []int {
+	lengths := make([]int, len(pieces))
+
+	pos := 0
+	for i, piece := range pieces {
+		start := pos
+		surface, spaced := strings.CutPrefix(piece, sentencePieceSpace)
+		if spaced {
+			for pos < len(text) {
+				r, size := utf8.DecodeRuneInString(text[pos:])
+				if !unicode.IsSpace(r) {
+					break
+				}
+				pos += size
+			}
+		}
+		surface = strings.ReplaceAll(surface, sentencePieceSpace, " ")
+
+		switch {
+		case strings.HasPrefix(text[pos:], surface):
+			pos += len(surface)
+		case len(piece) == 6 && strings.HasPrefix(piece, "<0x") && piece[5] == '>':
+			pos = minOffset(pos+1, len(text))
+		case pos < len(text):
+			_, size := utf8.DecodeRuneInString(text[pos:])
+			pos += size
+		}
+		lengths[i] = pos - start
+	}
+
+	if len(lengths) > 0 {
+		lengths[len(lengths)-1] += len(text) - pos
+	}
+	return lengths
+}
+
+// validateTokenOffsets checks that token offsets are in range, that start positions
+// never decrease and that the tokens cover the whole document, leaving out nothing but
+// whitespace, which word-level tokenizers drop. It returns a description of the first
+// problem found, or "" when the offsets are sound.
+func validateTokenOffsets(document string, tokens []Token) string {
+	if len(tokens) == 0 {
+		if !isSpace(document) {
+			return "no tokens cover the document"
+		}
+		return ""
+	}
+
+	covered := 0
+	for i, token := range tokens {
+		if token.StartPos < 0 || token.EndPos > len(document) || token.StartPos > token.EndPos {
+			return fmt.Sprintf("token %d has invalid offsets %d-%d", i, token.StartPos, token.EndPos)
+		}
+		if i > 0 && token.StartPos < tokens[i-1].StartPos {
+			return fmt.Sprintf("token %d starts at %d, before the previous token", i, token.StartPos)
+		}
+		if token.StartPos > covered && !isSpace(document[covered:token.StartPos]) {
+			return fmt.Sprintf("bytes %d-%d are not covered by any token", covered, token.StartPos)
+		}
+		if token.EndPos > covered {
+			covered = token.EndPos
+		}
+	}
+
+	if covered < len(document) && !isSpace(document[covered:]) {
+		return fmt.Sprintf("bytes %d-%d are not covered by any token", covered, len(document))
+	}
+	return ""
+}
+
+// isSpace reports whether text consists only of whitespace
+func isSpace(text string) bool {
+	return strings.TrimSpace(text) == ""
+}
+
+// minOffset returns the smaller of two offsets
+func minOffset(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
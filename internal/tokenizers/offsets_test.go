@@ -0,0 +1,167 @@
+package tokenizers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOffsetsFromByteLengths(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		lengths []int
+		want    [][2]int
+	}{
+		{"ascii", "hello world", []int{5, 6}, [][2]int{{0, 5}, {5, 11}}},
+		{"whole characters", "héllo", []int{1, 2, 3}, [][2]int{{0, 1}, {1, 3}, {3, 6}}},
+		// "é" is bytes 1-2; a boundary at byte 2 is widened to 1-3 on both sides
+		{"split character", "héllo", []int{2, 2, 2}, [][2]int{{0, 3}, {1, 4}, {4, 6}}},
+		// the 4-byte emoji split into byte tokens all map to the emoji
+		{"emoji bytes", "a🙂", []int{1, 1, 1, 1, 1}, [][2]int{{0, 1}, {1, 5}, {1, 5}, {1, 5}, {1, 5}}},
+		{"lengths overrun text", "ab", []int{1, 5}, [][2]int{{0, 1}, {1, 2}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := offsetsFromByteLengths(tt.text, tt.lengths)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("offsetsFromByteLengths(%q, %v) = %v, want %v", tt.text, tt.lengths, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuneOffsets(t *testing.T) {
+	runes := newRuneOffsets("naïve 日本")
+	for _, tt := range []struct{ index, want int }{
+		{0, 0}, {2, 2}, {3, 4}, {6, 7}, {7, 10}, {8, 13}, {9, 13}, {-1, 0},
+	} {
+		if got := runes.byteOffset(tt.index); got != tt.want {
+			t.Errorf("byteOffset(%d) = %d, want %d", tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestSentencePieceLengths(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		pieces []string
+		want   [][2]int
+	}{
+		{"dummy prefix", "hello world", []string{"▁hello", "▁world"}, [][2]int{{0, 5}, {5, 11}}},
+		{"multibyte", "naïve 日本", []string{"▁na", "ï", "ve", "▁日本"}, [][2]int{{0, 2}, {2, 4}, {4, 6}, {6, 13}}},
+		{"collapsed whitespace", "a  b ", []string{"▁a", "▁b"}, [][2]int{{0, 1}, {1, 5}}},
+		{"standalone space", "a 1", []string{"▁a", "▁", "1"}, [][2]int{{0, 1}, {1, 2}, {2, 3}}},
+		// "日" is bytes e6 97 a5; byte fallback pieces each map to the whole character
+		{"byte fallback", "x日", []string{"▁x", "<0xE6>", "<0x97>", "<0xA5>"}, [][2]int{{0, 1}, {1, 4}, {1, 4}, {1, 4}}},
+		// NFKC turns "ｈ" into "h", which no longer matches the text
+		{"normalized character", "ｈi", []string{"▁hi"}, [][2]int{{0, 4}}},
+		{"unknown", "a☃b", []string{"▁a", "<unk>", "b"}, [][2]int{{0, 1}, {1, 4}, {4, 5}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := offsetsFromByteLengths(tt.text, sentencePieceLengths(tt.text, tt.pieces))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("offsets of %q in %q = %v, want %v", tt.pieces, tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateTokenOffsets(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		spans    [][2]int
+		want     string
+	}{
+		{"covers document", "hello world", [][2]int{{0, 5}, {5, 11}}, ""},
+		{"overlapping split character", "héllo", [][2]int{{0, 3}, {1, 4}, {4, 6}}, ""},
+		{"empty document", "", nil, ""},
+		{"no tokens", "abc", nil, "no tokens"},
+		{"all zero starts", "hello world", [][2]int{{0, 5}, {0, 6}}, "not covered"},
+		{"decreasing", "abcdef", [][2]int{{0, 3}, {3, 6}, {2, 4}}, "before the previous token"},
+		{"gap", "abcdef", [][2]int{{0, 2}, {3, 6}}, "bytes 2-3"},
+		{"whitespace gap", "ab  cd\n", [][2]int{{0, 2}, {4, 6}}, ""},
+		{"whitespace document", " \n", nil, ""},
+		{"short", "abcdef", [][2]int{{0, 4}}, "bytes 4-6"},
+		{"out of range", "abc", [][2]int{{0, 4}}, "invalid offsets"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := make([]Token, len(tt.spans))
+			for i, span := range tt.spans {
+				tokens[i] = Token{StartPos: span[0], EndPos: span[1]}
+			}
+
+			got := validateTokenOffsets(tt.document, tokens)
+			if tt.want == "" && got != "" {
+				t.Errorf("validateTokenOffsets = %q, want no warning", got)
+			}
+			if tt.want != "" && !strings.Contains(got, tt.want) {
+				t.Errorf("validateTokenOffsets = %q, want it to mention %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeTiktokenScript mimics the GPT-2 script with one token per two UTF-8 bytes,
+// so multi-byte characters get split. For "short" it drops the last token.
+const fakeTiktokenScript = `
+import json
+import sys
+
+texts = json.loads(sys.stdin.buffer.read())["texts"]
+for text in texts:
+    data = text.encode("utf-8")
+    tokens = []
+    for i in range(0, len(data), 2):
+        piece = data[i:i + 2]
+        tokens.append({"id": i, "text": piece.decode("utf-8", errors="replace"), "byte_length": len(piece)})
+    if text == "short":
+        tokens = tokens[:-1]
+    print(json.dumps({"tokens": tokens, "vocab_size": 50257}), flush=True)
+`
+
+func TestGPT2TokenizeOffsets(t *testing.T) {
+	python, _ := newFakePython(t, fakeTiktokenScript)
+
+	tokenizer := NewGPT2Tokenizer("gpt2")
+	err := tokenizer.Initialize(TokenizerConfig{
+		Name:       "gpt2",
+		Type:       "bpe",
+		Parameters: map[string]string{"python_path": python},
+	})
+	if err != nil {
+		t.Fatalf("Initialize returned error: %v", err)
+	}
+
+	result, err := tokenizer.Tokenize(context.Background(), "héllo")
+	if err != nil {
+		t.Fatalf("Tokenize returned error: %v", err)
+	}
+
+	var spans [][2]int
+	for _, token := range result.Tokens {
+		spans = append(spans, [2]int{token.StartPos, token.EndPos})
+	}
+	if want := [][2]int{{0, 3}, {1, 4}, {4, 6}}; !reflect.DeepEqual(spans, want) {
+		t.Errorf("offsets = %v, want %v", spans, want)
+	}
+	if warning, ok := result.Metadata["offset_warning"]; ok {
+		t.Errorf("unexpected offset warning: %v", warning)
+	}
+
+	result, err = tokenizer.Tokenize(context.Background(), "short")
+	if err != nil {
+		t.Fatalf("Tokenize returned error: %v", err)
+	}
+	if _, ok := result.Metadata["offset_warning"]; !ok {
+		t.Error("expected an offset warning when tokens do not cover the document")
+	}
+}
@@ -7,24 +7,48 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/logger"
+	"github.com/sirupsen/logrus"
 )
 
-// OpenAITokenizer implements the Tokenizer interface for OpenAI API
+// maxRetryDelay caps the wait between retries of a token counting request
+const maxRetryDelay = 30 * time.Second
+
+// openAIVocabSizes are the vocabulary sizes of the encodings behind OpenAI models,
+// reported when tokens come from an endpoint rather than local tiktoken
+var openAIVocabSizes = map[string]int{
+	"gpt-3.5-turbo": 100277,
+	"gpt-4":         100277,
+	"gpt-4-turbo":   100277,
+	"gpt-4o":        200019,
+}
+
+// OpenAITokenizer tokenizes text the way OpenAI models do. OpenAI has no public
+// tokenize endpoint, so by default it uses the model's encoding through local
+// tiktoken. Setting the endpoint parameter sends text instead to a compatible token
+// counting endpoint, such as one exposed by an API proxy, with retries on 429 and 5xx.
 type OpenAITokenizer struct {
 	*BaseTokenizer
-	apiKey     string
-	apiBase    string
-	modelName  string
-	httpClient *http.Client
+	apiKey       string
+	endpoint     string
+	modelName    string
+	maxRetries   int
+	retryBackoff time.Duration
+	httpClient   *http.Client
+	local        *GPT2Tokenizer
 }
 
 // NewOpenAITokenizer creates a new OpenAI API tokenizer
 func NewOpenAITokenizer(name string) *OpenAITokenizer {
 	return &OpenAITokenizer{
 		BaseTokenizer: NewBaseTokenizer(name),
-		apiBase:       "https://api.openai.com/v1",
 		modelName:     "gpt-3.5-turbo",
+		maxRetries:    3,
+		retryBackoff:  500 * time.Millisecond,
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
@@ -37,14 +61,19 @@ func (o *OpenAITokenizer) Initialize(config TokenizerConfig) error {
 		return err
 	}
 
-	// Set API key from config
+	// Set API key from config; it is sent to the endpoint when one is configured
 	if apiKey, ok := config.Parameters["api_key"]; ok {
 		o.apiKey = apiKey
 	}
 
-	// Set API base URL from config
-	if apiBase, ok := config.Parameters["api_base"]; ok {
-		o.apiBase = apiBase
+	if _, ok := config.Parameters["api_base"]; ok {
+		return fmt.Errorf("api_base is no longer supported: OpenAI has no tokenize endpoint; " +
+			"remove it to use local tiktoken, or set endpoint to the full URL of a compatible token counting endpoint")
+	}
+
+	// Set endpoint URL from config
+	if endpoint, ok := config.Parameters["endpoint"]; ok {
+		o.endpoint = endpoint
 	}
 
 	// Set model name from config
@@ -52,61 +81,80 @@ func (o *OpenAITokenizer) Initialize(config TokenizerConfig) error {
 		o.modelName = model
 	}
 
-	// Validate required fields
-	if o.apiKey == "" {
-		return fmt.Errorf("OpenAI API key is required")
+	if value, ok := config.Parameters["max_retries"]; ok {
+		retries, err := strconv.Atoi(value)
+		if err != nil || retries < 0 {
+			return fmt.Errorf("invalid max_retries parameter: %s", value)
+		}
+		o.maxRetries = retries
 	}
 
-	return nil
-}
+	if value, ok := config.Parameters["retry_backoff"]; ok {
+		backoff, err := time.ParseDuration(value)
+		if err != nil || backoff < 0 {
+			return fmt.Errorf("invalid retry_backoff parameter: %s", value)
+		}
+		o.retryBackoff = backoff
+	}
 
-// Tokenize tokenizes a single document using OpenAI API
-func (o *OpenAITokenizer) Tokenize(ctx context.Context, text string) (*TokenizationResult, error) {
-	// Create request payload
-	payload := map[string]interface{}{
-		"model": o.modelName,
-		"input": text,
+	if value, ok := config.Parameters["timeout"]; ok {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf("invalid timeout parameter: %s", value)
+		}
+		o.httpClient.Timeout = timeout
 	}
 
-	jsonPayload, err := json.Marshal(payload)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request payload: %w", err)
+	if o.endpoint != "" {
+		if o.local != nil {
+			o.local.Close()
+			o.local = nil
+		}
+		return nil
 	}
 
-	// Create HTTP request
-	url := fmt.Sprintf("%s/tokenize", o.apiBase)
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonPayload))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	// Without an endpoint, tokenize with the model's tiktoken encoding
+	local := NewGPT2Tokenizer(o.Name())
+	localConfig := config
+	localConfig.Parameters = make(map[string]string, len(config.Parameters)+1)
+	for key, value := range config.Parameters {
+		localConfig.Parameters[key] = value
+	}
+	localConfig.Parameters["model"] = o.modelName
+	if err := local.Initialize(localConfig); err != nil {
+		return fmt.Errorf("failed to set up local tiktoken for %s: %w", o.modelName, err)
 	}
+	o.local = local
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	return nil
+}
 
-	// Make request
-	resp, err := o.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make API request: %w", err)
+// Tokenize tokenizes a single document
+func (o *OpenAITokenizer) Tokenize(ctx context.Context, text string) (*TokenizationResult, error) {
+	if o.endpoint == "" {
+		local, err := o.localTokenizer()
+		if err != nil {
+			return nil, err
+		}
+		result, err := local.Tokenize(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		result.Metadata["backend"] = "tiktoken"
+		return result, nil
 	}
-	defer resp.Body.Close()
 
-	// Read response
-	body, err := io.ReadAll(resp.Body)
+	payload, err := json.Marshal(map[string]interface{}{
+		"model": o.modelName,
+		"input": text,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, fmt.Errorf("failed to marshal request payload: %w", err)
 	}
 
-	// Check for errors
-	if resp.StatusCode != http.StatusOK {
-		var errorResp struct {
-			Error struct {
-				Message string `json:"message"`
-			} `json:"error"`
-		}
-		if err := json.Unmarshal(body, &errorResp); err != nil {
-			return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
-		}
-		return nil, fmt.Errorf("API error: %s", errorResp.Error.Message)
+	body, header, retries, err := o.post(ctx, payload)
+	if err != nil {
+		return nil, err
 	}
 
 	// Parse response
@@ -138,27 +186,183 @@ func (o *OpenAITokenizer) Tokenize(ctx context.Context, text string) (*Tokenizat
 			Metadata: map[string]string{
 				"tokenizer": "openai_api",
 				"model":     o.modelName,
-				"api_base":  o.apiBase,
 			},
 		}
 	}
 
+	metadata := map[string]interface{}{
+		"model":     o.modelName,
+		"endpoint":  o.endpoint,
+		"backend":   "api",
+		"tokenizer": "openai_api",
+		"retries":   retries,
+	}
+	if limits := rateLimitHeaders(header); len(limits) > 0 {
+		metadata["rate_limit"] = limits
+	}
+	if warning := validateTokenOffsets(text, tokens); warning != "" {
+		metadata["offset_warning"] = warning
+	}
+
 	return &TokenizationResult{
 		Document:  text,
 		Tokens:    tokens,
 		Tokenizer: o.Name(),
-		Metadata: map[string]interface{}{
-			"model":     o.modelName,
-			"api_base":  o.apiBase,
-			"tokenizer": "openai_api",
-		},
+		Metadata:  metadata,
 	}, nil
 }
 
+// APIError is a failed response from the token counting endpoint
+type APIError struct {
+	StatusCode int
+	Message    string
+	Retries    int               // retries made before giving up
+	RateLimit  map[string]string // x-ratelimit-* headers of the last response
+}
+
+// Error formats the status, message and any rate limit reset times
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("API error (status %d): %s", e.StatusCode, e.Message)
+	if e.Retries > 0 {
+		msg += fmt.Sprintf(" after %d retries", e.Retries)
+	}
+	for _, key := range []string{"reset-requests", "reset-tokens"} {
+		if value, ok := e.RateLimit[key]; ok {
+			msg += fmt.Sprintf("; rate limit %s in %s", key, value)
+		}
+	}
+	return msg
+}
+
+// post sends payload to the endpoint, retrying 429 and 5xx responses with
+// exponential backoff. It returns the body and headers of the successful response
+// and the number of retries it took.
+func (o *OpenAITokenizer) post(ctx context.Context, payload []byte) ([]byte, http.Header, int, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "POST", o.endpoint, bytes.NewReader(payload))
+		if err != nil {
+			return nil, nil, attempt, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		if o.apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+o.apiKey)
+		}
+
+		resp, err := o.httpClient.Do(req)
+		if err != nil {
+			return nil, nil, attempt, fmt.Errorf("failed to make API request: %w", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, nil, attempt, fmt.Errorf("failed to read response body: %w", err)
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			return body, resp.Header, attempt, nil
+		}
+
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		if !retryable || attempt >= o.maxRetries {
+			return nil, resp.Header, attempt, &APIError{
+				StatusCode: resp.StatusCode,
+				Message:    apiErrorMessage(body),
+				Retries:    attempt,
+				RateLimit:  rateLimitHeaders(resp.Header),
+			}
+		}
+
+		delay := o.retryDelay(attempt, resp.Header.Get("Retry-After"))
+		logger.FromContext(ctx).ForModule(logger.ModuleTokenizers).WithFields(logrus.Fields{
+			"event":          "tokenizer_retry",
+			"tokenizer_name": o.Name(),
+			"status_code":    resp.StatusCode,
+			"attempt":        attempt + 1,
+			"delay_ms":       delay.Milliseconds(),
+		}).Warn("Retrying token counting request")
+		select {
+		case <-ctx.Done():
+			return nil, nil, attempt, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// retryDelay returns how long to wait before retry attempt+1: the server's
+// Retry-After seconds when given, otherwise retryBackoff doubled for each attempt
+func (o *OpenAITokenizer) retryDelay(attempt int, retryAfter string) time.Duration {
+	if seconds, err := strconv.Atoi(strings.TrimSpace(retryAfter)); err == nil && seconds >= 0 {
+		delay := time.Duration(seconds) * time.Second
+		if delay > maxRetryDelay {
+			return maxRetryDelay
+		}
+		return delay
+	}
+
+	delay := o.retryBackoff
+	for i := 0; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return delay
+}
+
+// apiErrorMessage extracts the message from an OpenAI style error body, falling back
+// to the raw body
+func apiErrorMessage(body []byte) string {
+	var errorResp struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errorResp); err == nil && errorResp.Error.Message != "" {
+		return errorResp.Error.Message
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// rateLimitHeaders collects the x-ratelimit-* headers, keyed without the prefix
+func rateLimitHeaders(header http.Header) map[string]string {
+	limits := make(map[string]string)
+	for key, values := range header {
+		lower := strings.ToLower(key)
+		if strings.HasPrefix(lower, "x-ratelimit-") && len(values) > 0 {
+			limits[strings.TrimPrefix(lower, "x-ratelimit-")] = values[0]
+		}
+	}
+	return limits
+}
+
+// localTokenizer returns the tiktoken tokenizer, which exists once Initialize has run
+func (o *OpenAITokenizer) localTokenizer() (*GPT2Tokenizer, error) {
+	if o.local == nil {
+		return nil, fmt.Errorf("tokenizer %s is not initialized", o.Name())
+	}
+	return o.local, nil
+}
+
 // TokenizeBatch tokenizes multiple documents
 func (o *OpenAITokenizer) TokenizeBatch(ctx context.Context, texts []string) ([]*TokenizationResult, error) {
+	if o.endpoint == "" {
+		local, err := o.localTokenizer()
+		if err != nil {
+			return nil, err
+		}
+		results, err := local.TokenizeBatch(ctx, texts)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range results {
+			result.Metadata["backend"] = "tiktoken"
+		}
+		return results, nil
+	}
+
 	results := make([]*TokenizationResult, len(texts))
-	
+
 	for i, text := range texts {
 		result, err := o.Tokenize(ctx, text)
 		if err != nil {
@@ -166,21 +370,22 @@ func (o *OpenAITokenizer) TokenizeBatch(ctx context.Context, texts []string) ([]
 		}
 		results[i] = result
 	}
-	
+
 	return results, nil
 }
 
-// GetVocabSize returns the vocabulary size (approximate for OpenAI models)
+// GetVocabSize returns the vocabulary size: exact from tiktoken, or the known size
+// of the model's encoding when using an endpoint
 func (o *OpenAITokenizer) GetVocabSize() (int, error) {
-	// OpenAI doesn't provide vocab size via API, so we return approximate values
-	vocabSizes := map[string]int{
-		"gpt-3.5-turbo": 100277,
-		"gpt-4":         100277,
-		"gpt-4-turbo":   100277,
-		"gpt-4o":        100277,
+	if o.endpoint == "" {
+		local, err := o.localTokenizer()
+		if err != nil {
+			return 0, err
+		}
+		return local.GetVocabSize()
 	}
 
-	if vocabSize, ok := vocabSizes[o.modelName]; ok {
+	if vocabSize, ok := openAIVocabSizes[o.modelName]; ok {
 		return vocabSize, nil
 	}
 
@@ -190,12 +395,13 @@ func (o *OpenAITokenizer) GetVocabSize() (int, error) {
 
 // Close cleans up resources
 func (o *OpenAITokenizer) Close() error {
-	// Nothing to clean up for OpenAI API tokenizer
+	if o.local != nil {
+		return o.local.Close()
+	}
 	return nil
 }
 
 // RegisterOpenAITokenizer registers the OpenAI API tokenizer
 func RegisterOpenAITokenizer() error {
-	openAITokenizer := NewOpenAITokenizer("openai-api")
-	return RegisterGlobal("openai-api", openAITokenizer)
-} 
\ No newline at end of file
+	return RegisterGlobal("openai-api", newBuiltinTokenizer("openai-api"))
+}
@@ -0,0 +1,244 @@
+package tokenizers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newEndpointTokenizer returns an OpenAI tokenizer pointed at server with a short
+// retry backoff
+func newEndpointTokenizer(t *testing.T, server *httptest.Server, parameters map[string]string) *OpenAITokenizer {
+	t.Helper()
+	merged := map[string]string{
+		"endpoint":      server.URL + "/v1/tokenize",
+		"api_key":       "sk-test",
+		"retry_backoff": "1ms",
+	}
+	for key, value := range parameters {
+		merged[key] = value
+	}
+
+	tokenizer := NewOpenAITokenizer("openai-api")
+	if err := tokenizer.Initialize(TokenizerConfig{Name: "openai-api", Type: "bpe", Parameters: merged}); err != nil {
+		t.Fatalf("Initialize returned error: %v", err)
+	}
+	return tokenizer
+}
+
+// writeTokens answers a tokenize request by splitting the input on spaces
+func writeTokens(t *testing.T, w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model string `json:"model"`
+		Input string `json:"input"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		t.Errorf("failed to decode request: %v", err)
+	}
+	type token struct {
+		ID   int    `json:"id"`
+		Text string `json:"text"`
+	}
+	var tokens []token
+	for i, word := range strings.SplitAfter(req.Input, " ") {
+		tokens = append(tokens, token{ID: i, Text: word})
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"tokens": tokens})
+}
+
+func TestOpenAIEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer sk-test" {
+			t.Errorf("Authorization = %q, want the API key", got)
+		}
+		if r.URL.Path != "/v1/tokenize" {
+			t.Errorf("path = %q, want the configured endpoint", r.URL.Path)
+		}
+		w.Header().Set("X-Ratelimit-Remaining-Requests", "99")
+		w.Header().Set("X-Ratelimit-Reset-Tokens", "6ms")
+		writeTokens(t, w, r)
+	}))
+	defer server.Close()
+
+	tokenizer := newEndpointTokenizer(t, server, nil)
+	result, err := tokenizer.Tokenize(context.Background(), "hello big world")
+	if err != nil {
+		t.Fatalf("Tokenize returned error: %v", err)
+	}
+
+	if got := len(result.Tokens); got != 3 {
+		t.Fatalf("got %d tokens, want 3", got)
+	}
+	if result.Tokens[2].StartPos != 10 || result.Tokens[2].EndPos != 15 {
+		t.Errorf("last token offsets = %d-%d, want 10-15", result.Tokens[2].StartPos, result.Tokens[2].EndPos)
+	}
+	limits, _ := result.Metadata["rate_limit"].(map[string]string)
+	if limits["remaining-requests"] != "99" || limits["reset-tokens"] != "6ms" {
+		t.Errorf("rate_limit metadata = %v, want the x-ratelimit headers", result.Metadata["rate_limit"])
+	}
+	if result.Metadata["retries"] != 0 {
+		t.Errorf("retries = %v, want 0", result.Metadata["retries"])
+	}
+}
+
+func TestOpenAIEndpointRetries(t *testing.T) {
+	tests := []struct {
+		name        string
+		statuses    []int // responses before success; the rest succeed
+		maxRetries  string
+		wantErr     int // expected APIError status, 0 for success
+		wantCalls   int32
+		wantRetries int
+	}{
+		{"rate limited then ok", []int{429, 429}, "3", 0, 3, 2},
+		{"server errors then ok", []int{500, 503}, "3", 0, 3, 2},
+		{"retries exhausted", []int{503, 503, 503}, "2", 503, 3, 2},
+		{"client error not retried", []int{400}, "3", 400, 1, 0},
+		{"no retries", []int{429}, "0", 429, 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt32(&calls, 1)
+				if int(n) <= len(tt.statuses) {
+					w.Header().Set("X-Ratelimit-Reset-Requests", "1s")
+					w.WriteHeader(tt.statuses[n-1])
+					w.Write([]byte(`{"error": {"message": "slow down"}}`))
+					return
+				}
+				writeTokens(t, w, r)
+			}))
+			defer server.Close()
+
+			tokenizer := newEndpointTokenizer(t, server, map[string]string{"max_retries": tt.maxRetries})
+			result, err := tokenizer.Tokenize(context.Background(), "a b")
+
+			if calls != tt.wantCalls {
+				t.Errorf("server called %d times, want %d", calls, tt.wantCalls)
+			}
+			if tt.wantErr == 0 {
+				if err != nil {
+					t.Fatalf("Tokenize returned error: %v", err)
+				}
+				if result.Metadata["retries"] != tt.wantRetries {
+					t.Errorf("retries = %v, want %d", result.Metadata["retries"], tt.wantRetries)
+				}
+				return
+			}
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected an APIError, got %v", err)
+			}
+			if apiErr.StatusCode != tt.wantErr || apiErr.Retries != tt.wantRetries {
+				t.Errorf("APIError = %+v, want status %d after %d retries", apiErr, tt.wantErr, tt.wantRetries)
+			}
+			if apiErr.Message != "slow down" || apiErr.RateLimit["reset-requests"] != "1s" {
+				t.Errorf("APIError = %+v, want the message and rate limit headers", apiErr)
+			}
+			if !strings.Contains(err.Error(), "reset-requests in 1s") {
+				t.Errorf("error %q does not mention the rate limit reset", err)
+			}
+		})
+	}
+}
+
+func TestOpenAIRetryDelay(t *testing.T) {
+	tokenizer := NewOpenAITokenizer("openai-api")
+	tokenizer.retryBackoff = 100 * time.Millisecond
+
+	tests := []struct {
+		attempt    int
+		retryAfter string
+		want       time.Duration
+	}{
+		{0, "", 100 * time.Millisecond},
+		{1, "", 200 * time.Millisecond},
+		{3, "", 800 * time.Millisecond},
+		{20, "", maxRetryDelay},
+		{0, "2", 2 * time.Second},
+		{0, "3600", maxRetryDelay},
+		{1, "Wed, 21 Oct 2015 07:28:00 GMT", 200 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		if got := tokenizer.retryDelay(tt.attempt, tt.retryAfter); got != tt.want {
+			t.Errorf("retryDelay(%d, %q) = %v, want %v", tt.attempt, tt.retryAfter, got, tt.want)
+		}
+	}
+}
+
+func TestOpenAIRetryStopsOnCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "20")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	tokenizer := newEndpointTokenizer(t, server, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := tokenizer.Tokenize(ctx, "text")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the context error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Tokenize waited %v after the context ended", elapsed)
+	}
+}
+
+func TestOpenAIRejectsAPIBase(t *testing.T) {
+	tokenizer := NewOpenAITokenizer("openai-api")
+	err := tokenizer.Initialize(TokenizerConfig{
+		Name:       "openai-api",
+		Type:       "bpe",
+		Parameters: map[string]string{"api_base": "https://api.openai.com/v1"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "endpoint") {
+		t.Errorf("expected an error pointing at the endpoint parameter, got %v", err)
+	}
+}
+
+func TestOpenAILocalTiktoken(t *testing.T) {
+	useScriptStubs(t)
+
+	tokenizer := NewOpenAITokenizer("openai-api")
+	err := tokenizer.Initialize(TokenizerConfig{
+		Name:       "openai-api",
+		Type:       "bpe",
+		Parameters: map[string]string{"model": "gpt-4"},
+	})
+	if err != nil {
+		t.Fatalf("Initialize returned error: %v", err)
+	}
+	defer tokenizer.Close()
+
+	result, err := tokenizer.Tokenize(context.Background(), "hé")
+	if err != nil {
+		t.Fatalf("Tokenize returned error: %v", err)
+	}
+	if result.Tokenizer != "openai-api" || result.Metadata["backend"] != "tiktoken" {
+		t.Errorf("result = %q with metadata %v, want openai-api via tiktoken", result.Tokenizer, result.Metadata)
+	}
+	if len(result.Tokens) != 3 {
+		t.Errorf("got %d tokens, want one per byte from the stub encoding", len(result.Tokens))
+	}
+	if size, err := tokenizer.GetVocabSize(); err != nil || size != 256 {
+		t.Errorf("GetVocabSize = %d, %v, want the stub's 256", size, err)
+	}
+
+	uninitialized := NewOpenAITokenizer("openai-api")
+	if _, err := uninitialized.Tokenize(context.Background(), "x"); err == nil {
+		t.Error("expected an error before Initialize")
+	}
+}
@@ -0,0 +1,60 @@
+package tokenizers
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// PricingInfo describes API pricing and context limits for a tokenizer's model.
+// Zero values mean the information is unknown.
+type PricingInfo struct {
+	PricePer1KTokens float64 `json:"price_per_1k_tokens,omitempty"`
+	ContextWindow    int     `json:"context_window,omitempty"`
+}
+
+// PricedTokenizer is implemented by tokenizers that can report model pricing
+type PricedTokenizer interface {
+	// Pricing returns the pricing and context window for the tokenizer's model
+	Pricing() (PricingInfo, error)
+}
+
+// defaultPricing holds list input-token prices (USD) and context windows for known
+// models. Override with the "price_per_1k_tokens" and "context_window" parameters.
+var defaultPricing = map[string]PricingInfo{
+	"gpt-3.5-turbo": {PricePer1KTokens: 0.0005, ContextWindow: 16385},
+	"gpt-4":         {PricePer1KTokens: 0.03, ContextWindow: 8192},
+	"gpt-4-turbo":   {PricePer1KTokens: 0.01, ContextWindow: 128000},
+	"gpt-4o":        {PricePer1KTokens: 0.0025, ContextWindow: 128000},
+}
+
+// ResolvePricing combines the built-in defaults for a model with configured overrides
+func ResolvePricing(model string, parameters map[string]string) (PricingInfo, error) {
+	info := defaultPricing[model]
+
+	if value, ok := parameters["price_per_1k_tokens"]; ok {
+		price, err := strconv.ParseFloat(value, 64)
+		if err != nil || price < 0 {
+			return PricingInfo{}, fmt.Errorf("invalid price_per_1k_tokens parameter: %q", value)
+		}
+		info.PricePer1KTokens = price
+	}
+
+	if value, ok := parameters["context_window"]; ok {
+		window, err := strconv.Atoi(value)
+		if err != nil || window < 0 {
+			return PricingInfo{}, fmt.Errorf("invalid context_window parameter: %q", value)
+		}
+		info.ContextWindow = window
+	}
+
+	return info, nil
+}
+
+// Pricing returns pricing for the configured model, falling back to the tokenizer name
+func (b *BaseTokenizer) Pricing() (PricingInfo, error) {
+	model := b.config.Parameters["model"]
+	if model == "" {
+		model = b.name
+	}
+	return ResolvePricing(model, b.config.Parameters)
+}
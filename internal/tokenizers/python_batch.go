@@ -0,0 +1,145 @@
+package tokenizers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+)
+
+// DefaultMaxBatchBytes bounds the combined size of the texts sent to a Python process
+// in one batch; larger inputs are split into several batches
+const DefaultMaxBatchBytes = 4 << 20
+
+// pythonToken is a token as written by the Python adapter scripts
+type pythonToken struct {
+	ID         int    `json:"id"`
+	Text       string `json:"text"`
+	StartPos   int    `json:"start_pos"`             // in code points, as Python indexes strings
+	EndPos     int    `json:"end_pos"`               // in code points
+	ByteLength int    `json:"byte_length,omitempty"` // for scripts that report lengths instead of positions
+}
+
+// pythonTokenization is one result line written by the Python adapter scripts
+type pythonTokenization struct {
+	Tokens    []pythonToken `json:"tokens"`
+	VocabSize int           `json:"vocab_size,omitempty"`
+}
+
+// batchRange is a half-open range of indices into the texts of a batch request
+type batchRange struct {
+	start, end int
+}
+
+// splitBatches groups consecutive texts so that each group's combined length stays
+// within maxBytes. A text larger than maxBytes gets a group of its own.
+func splitBatches(texts []string, maxBytes int) []batchRange {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBatchBytes
+	}
+
+	var batches []batchRange
+	start, size := 0, 0
+	for i, text := range texts {
+		if i > start && size+len(text) > maxBytes {
+			batches = append(batches, batchRange{start: start, end: i})
+			start, size = i, 0
+		}
+		size += len(text)
+	}
+	if start < len(texts) {
+		batches = append(batches, batchRange{start: start, end: len(texts)})
+	}
+
+	return batches
+}
+
+// parseMaxBatchBytes reads the max_batch_bytes parameter, returning current when it
+// is not set
+func parseMaxBatchBytes(parameters map[string]string, current int) (int, error) {
+	value, ok := parameters["max_batch_bytes"]
+	if !ok {
+		return current, nil
+	}
+
+	size, err := strconv.Atoi(value)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("invalid max_batch_bytes parameter: %s", value)
+	}
+	return size, nil
+}
+
+// runPythonBatch starts script once with env, writes {"texts": [...]} to its stdin and
+// calls handle with the result line the script writes for each text, in order. A line
+// with an "error" field fails that text; all lines are still read.
+func runPythonBatch(ctx context.Context, pythonPath, script string, env []string, texts []string, handle func(i int, result *pythonTokenization) error) error {
+	payload, err := json.Marshal(struct {
+		Texts []string `json:"texts"`
+	}{Texts: texts})
+	if err != nil {
+		return fmt.Errorf("failed to encode batch: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, pythonPath, "-c", script)
+	cmd.Env = env
+	cmd.Stdin = bytes.NewReader(payload)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open python stdout: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start python: %w", err)
+	}
+
+	var firstErr error
+	count := 0
+	reader := bufio.NewReader(stdout)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			if firstErr == nil && count < len(texts) {
+				firstErr = handleBatchLine(count, line, handle)
+			}
+			count++
+		}
+		if readErr != nil {
+			break
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("python process failed: %w%s", err, formatStderr(stderr.String()))
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+	if count != len(texts) {
+		return fmt.Errorf("python process returned %d results for %d texts", count, len(texts))
+	}
+
+	return nil
+}
+
+// handleBatchLine decodes one result line and passes it to handle
+func handleBatchLine(i int, line []byte, handle func(i int, result *pythonTokenization) error) error {
+	var result struct {
+		pythonTokenization
+		Error string `json:"error,omitempty"`
+	}
+	if err := json.Unmarshal(line, &result); err != nil {
+		return fmt.Errorf("failed to parse result for item %d: %w", i, err)
+	}
+	if result.Error != "" {
+		return fmt.Errorf("item %d: %s", i, result.Error)
+	}
+	return handle(i, &result.pythonTokenization)
+}
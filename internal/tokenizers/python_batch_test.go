@@ -0,0 +1,173 @@
+package tokenizers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeBatchScript stands in for the adapter scripts: it splits each text on spaces
+// and reports an error for the text "fail"
+const fakeBatchScript = `
+import json
+import sys
+
+texts = json.loads(sys.stdin.buffer.read())["texts"]
+for text in texts:
+    if text == "fail":
+        print(json.dumps({"error": "cannot tokenize"}), flush=True)
+        continue
+    tokens = []
+    pos = 0
+    for i, word in enumerate(text.split(" ")):
+        tokens.append({"id": i, "text": word, "start_pos": pos, "end_pos": pos + len(word)})
+        pos += len(word) + 1
+    print(json.dumps({"tokens": tokens, "vocab_size": 42}), flush=True)
+`
+
+func TestSplitBatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		texts    []string
+		maxBytes int
+		want     []batchRange
+	}{
+		{"empty", nil, 10, nil},
+		{"fits in one", []string{"ab", "cd", "ef"}, 10, []batchRange{{0, 3}}},
+		{"exact fit", []string{"abcde", "fghij"}, 10, []batchRange{{0, 2}}},
+		{"splits", []string{"abcd", "efgh", "ijkl"}, 8, []batchRange{{0, 2}, {2, 3}}},
+		{"oversized text alone", []string{"ab", "abcdefghijkl", "cd"}, 5, []batchRange{{0, 1}, {1, 2}, {2, 3}}},
+		{"default limit", []string{"a", "b"}, 0, []batchRange{{0, 2}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitBatches(tt.texts, tt.maxBytes)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitBatches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// newFakePython writes an executable that runs script in place of the adapter's
+// script and records each invocation in the returned file. The interpreter probe,
+// which passes module names after its script, runs for real against the stubs.
+func newFakePython(t *testing.T, script string) (string, string) {
+	t.Helper()
+	python := useScriptStubs(t)
+
+	dir := t.TempDir()
+	scriptPath := filepath.Join(dir, "fake.py")
+	callsPath := filepath.Join(dir, "calls")
+	if err := os.WriteFile(scriptPath, []byte(script), 0644); err != nil {
+		t.Fatalf("failed to write fake script: %v", err)
+	}
+
+	wrapper := filepath.Join(dir, "python")
+	content := "#!/bin/sh\nif [ $# -gt 2 ]; then exec '" + python + "' \"$@\"; fi\n" +
+		"echo call >> '" + callsPath + "'\nexec '" + python + "' '" + scriptPath + "'\n"
+	if err := os.WriteFile(wrapper, []byte(content), 0755); err != nil {
+		t.Fatalf("failed to write fake python: %v", err)
+	}
+	return wrapper, callsPath
+}
+
+func countCalls(t *testing.T, callsPath string) int {
+	t.Helper()
+	data, err := os.ReadFile(callsPath)
+	if err != nil {
+		return 0
+	}
+	return strings.Count(string(data), "call")
+}
+
+func TestRunPythonBatch(t *testing.T) {
+	python, _ := newFakePython(t, fakeBatchScript)
+
+	var got [][]string
+	err := runPythonBatch(context.Background(), python, "", os.Environ(), []string{"a b", "c"}, func(i int, result *pythonTokenization) error {
+		texts := make([]string, len(result.Tokens))
+		for j, token := range result.Tokens {
+			texts[j] = token.Text
+		}
+		got = append(got, texts)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("runPythonBatch returned error: %v", err)
+	}
+
+	want := [][]string{{"a", "b"}, {"c"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tokens = %v, want %v", got, want)
+	}
+
+	err = runPythonBatch(context.Background(), python, "", os.Environ(), []string{"a", "fail", "b"}, func(int, *pythonTokenization) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "item 1: cannot tokenize") {
+		t.Errorf("expected an error for item 1, got %v", err)
+	}
+}
+
+func TestSentencePieceTokenizeBatchSplitsByBytes(t *testing.T) {
+	python, callsPath := newFakePython(t, fakeBatchScript)
+	modelPath := filepath.Join(t.TempDir(), "fake.model")
+	if err := os.WriteFile(modelPath, []byte("model"), 0644); err != nil {
+		t.Fatalf("failed to write model: %v", err)
+	}
+
+	tokenizer := NewSentencePieceTokenizer("fake-spm")
+	err := tokenizer.Initialize(TokenizerConfig{
+		Name: "fake-spm",
+		Type: "unigram",
+		Parameters: map[string]string{
+			"python_path":     python,
+			"model_path":      modelPath,
+			"max_batch_bytes": "12",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Initialize returned error: %v", err)
+	}
+
+	texts := []string{"one two", "three", "four five", "six", "seven eight"}
+	results, err := tokenizer.TokenizeBatch(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("TokenizeBatch returned error: %v", err)
+	}
+
+	if len(results) != len(texts) {
+		t.Fatalf("got %d results, want %d", len(results), len(texts))
+	}
+	for i, result := range results {
+		if result.Document != texts[i] {
+			t.Errorf("result %d document = %q, want %q", i, result.Document, texts[i])
+		}
+		if result.Metadata["vocab_size"] != 42 {
+			t.Errorf("result %d vocab_size = %v, want 42", i, result.Metadata["vocab_size"])
+		}
+	}
+	if got := len(results[2].Tokens); got != 2 {
+		t.Errorf("document 2 has %d tokens, want 2", got)
+	}
+
+	// 12 bytes fit "one two"+"three", "four five"+"six", then "seven eight"
+	if calls := countCalls(t, callsPath); calls != 3 {
+		t.Errorf("python started %d times, want 3", calls)
+	}
+}
+
+func TestInvalidMaxBatchBytes(t *testing.T) {
+	tokenizer := NewSentencePieceTokenizer("fake-spm")
+	err := tokenizer.Initialize(TokenizerConfig{
+		Name:       "fake-spm",
+		Type:       "unigram",
+		Parameters: map[string]string{"max_batch_bytes": "-1"},
+	})
+	if err == nil {
+		t.Error("expected an error for a negative max_batch_bytes")
+	}
+}
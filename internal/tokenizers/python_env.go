@@ -0,0 +1,109 @@
+package tokenizers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// pythonProbeTimeout bounds the interpreter probe; importing transformers can take
+// several seconds on a cold start
+const pythonProbeTimeout = 2 * time.Minute
+
+// pythonProbeScript imports each module named on the command line and reports the
+// ones that fail
+const pythonProbeScript = `
+import importlib
+import json
+import sys
+
+missing = []
+for name in sys.argv[1:]:
+    try:
+        importlib.import_module(name)
+    except Exception as e:
+        missing.append({"module": name, "error": str(e)})
+
+print(json.dumps({"missing": missing}))
+`
+
+// pythonParameters applies the python_path and venv_path parameters. Without an
+// explicit python_path, a configured virtualenv's own interpreter is used.
+func pythonParameters(parameters map[string]string, pythonPath, venvPath string) (string, string) {
+	if venv, ok := parameters["venv_path"]; ok {
+		venvPath = venv
+		if venvPath != "" {
+			pythonPath = filepath.Join(venvPath, "bin", "python")
+		}
+	}
+	if python, ok := parameters["python_path"]; ok && python != "" {
+		pythonPath = python
+	}
+	return pythonPath, venvPath
+}
+
+// venvEnv returns the process environment, with the virtualenv at venvPath activated
+// when one is configured. With no venv the environment is passed through unchanged,
+// so conda or system interpreters work as they do in the shell.
+func venvEnv(venvPath string) []string {
+	env := os.Environ()
+	if venvPath == "" {
+		return env
+	}
+	return append(env,
+		"VIRTUAL_ENV="+venvPath,
+		"PATH="+filepath.Join(venvPath, "bin")+string(os.PathListSeparator)+os.Getenv("PATH"),
+	)
+}
+
+// probePython checks that the interpreter starts and can import every module, so a
+// misconfigured environment fails at Initialize with the full list of what is missing
+// rather than on the first Tokenize call
+func probePython(pythonPath, venvPath string, modules ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), pythonProbeTimeout)
+	defer cancel()
+
+	args := append([]string{"-c", pythonProbeScript}, modules...)
+	cmd := exec.CommandContext(ctx, pythonPath, args...)
+	cmd.Env = venvEnv(venvPath)
+
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return fmt.Errorf("python interpreter %s failed: %w%s", pythonPath, err, formatStderr(string(exitErr.Stderr)))
+		}
+		return fmt.Errorf("python interpreter %s is not usable: %w", pythonPath, err)
+	}
+
+	var result struct {
+		Missing []struct {
+			Module string `json:"module"`
+			Error  string `json:"error"`
+		} `json:"missing"`
+	}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return fmt.Errorf("failed to parse python probe output: %w", err)
+	}
+
+	if len(result.Missing) > 0 {
+		missing := make([]string, len(result.Missing))
+		for i, m := range result.Missing {
+			missing[i] = fmt.Sprintf("%s (%s)", m.Module, m.Error)
+		}
+		hint := "set venv_path or python_path to an environment that has them"
+		if venvPath != "" {
+			hint = fmt.Sprintf("install them into the virtualenv at %s", venvPath)
+		}
+		return fmt.Errorf("python interpreter %s is missing required packages: %s; %s",
+			pythonPath, strings.Join(missing, ", "), hint)
+	}
+
+	return nil
+}
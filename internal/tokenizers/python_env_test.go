@@ -0,0 +1,81 @@
+package tokenizers
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPythonParameters(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters map[string]string
+		wantPython string
+		wantVenv   string
+	}{
+		{"defaults", map[string]string{}, "python3", ""},
+		{"venv only", map[string]string{"venv_path": "/envs/ted"}, filepath.Join("/envs/ted", "bin", "python"), "/envs/ted"},
+		{"python path wins", map[string]string{"venv_path": "/envs/ted", "python_path": "/usr/bin/python3.11"}, "/usr/bin/python3.11", "/envs/ted"},
+		{"empty venv clears", map[string]string{"venv_path": ""}, "python3", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			python, venv := pythonParameters(tt.parameters, "python3", "")
+			if python != tt.wantPython || venv != tt.wantVenv {
+				t.Errorf("pythonParameters = %q, %q, want %q, %q", python, venv, tt.wantPython, tt.wantVenv)
+			}
+		})
+	}
+}
+
+func TestVenvEnv(t *testing.T) {
+	t.Setenv("VIRTUAL_ENV", "")
+	t.Setenv("PATH", "/usr/bin")
+
+	for _, entry := range venvEnv("") {
+		if strings.HasPrefix(entry, "VIRTUAL_ENV=") && entry != "VIRTUAL_ENV=" {
+			t.Errorf("unexpected %s without a venv", entry)
+		}
+	}
+
+	env := venvEnv("/envs/ted")
+	cmd := exec.Command("true")
+	cmd.Env = env
+	values := map[string]string{}
+	for _, entry := range cmd.Environ() {
+		if key, value, ok := strings.Cut(entry, "="); ok {
+			values[key] = value
+		}
+	}
+	if values["VIRTUAL_ENV"] != "/envs/ted" {
+		t.Errorf("VIRTUAL_ENV = %q, want /envs/ted", values["VIRTUAL_ENV"])
+	}
+	if !strings.HasPrefix(values["PATH"], filepath.Join("/envs/ted", "bin")) {
+		t.Errorf("PATH = %q, want the venv bin directory first", values["PATH"])
+	}
+}
+
+func TestProbePython(t *testing.T) {
+	python := useScriptStubs(t)
+
+	if err := probePython(python, "", "json", "tiktoken"); err != nil {
+		t.Errorf("probePython returned error for available modules: %v", err)
+	}
+
+	err := probePython(python, "", "json", "ted_missing_one", "ted_missing_two")
+	if err == nil {
+		t.Fatal("expected an error for missing modules")
+	}
+	for _, module := range []string{"ted_missing_one", "ted_missing_two"} {
+		if !strings.Contains(err.Error(), module) {
+			t.Errorf("error %q does not mention %s", err, module)
+		}
+	}
+
+	err = probePython(filepath.Join(t.TempDir(), "missing-python"), "")
+	if err == nil || !strings.Contains(err.Error(), "not usable") {
+		t.Errorf("expected an error for a missing interpreter, got %v", err)
+	}
+}
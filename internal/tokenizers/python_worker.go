@@ -0,0 +1,274 @@
+package tokenizers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// workerMaxRestarts is how many times a request is retried on a fresh process after
+// the worker crashes
+const workerMaxRestarts = 1
+
+// pythonWorker runs a long-lived Python process that loads its tokenizer once and then
+// answers newline-delimited JSON requests on stdin with one JSON line on stdout. The
+// script must write a handshake line once it is ready, or a line with an "error" field
+// and exit if it cannot start.
+type pythonWorker struct {
+	pythonPath string
+	script     string
+	env        []string
+
+	mu        sync.Mutex
+	cmd       *exec.Cmd
+	stdin     *bufio.Writer
+	stdinPipe io.WriteCloser
+	stdout    *bufio.Reader
+	stderr    *bytes.Buffer
+	handshake json.RawMessage
+}
+
+// workerError is the error field a worker script may include in any response
+type workerError struct {
+	Error string `json:"error,omitempty"`
+}
+
+// newPythonWorker creates a worker; the process is started on first use
+func newPythonWorker(pythonPath, script string, env []string) *pythonWorker {
+	return &pythonWorker{
+		pythonPath: pythonPath,
+		script:     script,
+		env:        env,
+	}
+}
+
+// Start launches the process if it is not already running and waits for its handshake
+func (w *pythonWorker) Start(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.startLocked(ctx)
+}
+
+// Handshake decodes the line the worker wrote when it became ready, starting the
+// worker if needed
+func (w *pythonWorker) Handshake(ctx context.Context, v interface{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.startLocked(ctx); err != nil {
+		return err
+	}
+	return json.Unmarshal(w.handshake, v)
+}
+
+// Call sends one request and decodes the single response line into resp
+func (w *pythonWorker) Call(ctx context.Context, req, resp interface{}) error {
+	return w.CallEach(ctx, req, 1, func(_ int, line []byte) error {
+		if err := json.Unmarshal(line, resp); err != nil {
+			return fmt.Errorf("failed to parse worker response: %w", err)
+		}
+		return nil
+	})
+}
+
+// CallEach sends one request that the worker answers with n response lines and calls
+// handle with each line in order. Every line is read even after an error so the
+// worker stays in step; the first error is returned. If the process dies mid-request
+// it is restarted and the request retried. If ctx ends first, the process is killed
+// so that the next call starts a fresh one.
+func (w *pythonWorker) CallEach(ctx context.Context, req interface{}, n int, handle func(i int, line []byte) error) error {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to encode worker request: %w", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for attempt := 0; ; attempt++ {
+		if err := w.startLocked(ctx); err != nil {
+			return err
+		}
+
+		firstErr, err := w.exchangeLocked(ctx, payload, n, handle)
+		if err == nil {
+			return firstErr
+		}
+
+		stderr := w.stopLocked()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if attempt >= workerMaxRestarts {
+			return fmt.Errorf("python worker failed: %w%s", err, formatStderr(stderr))
+		}
+		logger.FromContext(ctx).ForModule(logger.ModuleTokenizers).WithFields(logrus.Fields{
+			"event":       "python_worker_restart",
+			"python_path": w.pythonPath,
+			"error":       err.Error(),
+		}).Warn("Python worker failed, retrying on a new process")
+	}
+}
+
+// exchangeLocked writes one request line and reads n response lines. It returns the
+// first error reported by the script or by handle, and separately any I/O error that
+// leaves the process unusable.
+func (w *pythonWorker) exchangeLocked(ctx context.Context, payload []byte, n int, handle func(i int, line []byte) error) (error, error) {
+	if _, err := w.stdin.Write(append(payload, '\n')); err != nil {
+		return nil, err
+	}
+	if err := w.stdin.Flush(); err != nil {
+		return nil, err
+	}
+
+	var firstErr error
+	for i := 0; i < n; i++ {
+		line, err := w.readLineLocked(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if firstErr != nil {
+			continue
+		}
+
+		var status workerError
+		if err := json.Unmarshal(line, &status); err != nil {
+			firstErr = fmt.Errorf("failed to parse worker response: %w", err)
+			continue
+		}
+		if status.Error != "" {
+			if n > 1 {
+				firstErr = fmt.Errorf("worker error for item %d: %s", i, status.Error)
+			} else {
+				firstErr = fmt.Errorf("worker error: %s", status.Error)
+			}
+			continue
+		}
+		firstErr = handle(i, line)
+	}
+
+	return firstErr, nil
+}
+
+// Close stops the worker process
+func (w *pythonWorker) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopLocked()
+	return nil
+}
+
+// startLocked launches the process and reads its handshake; the caller holds mu
+func (w *pythonWorker) startLocked(ctx context.Context) error {
+	if w.cmd != nil {
+		return nil
+	}
+
+	cmd := exec.Command(w.pythonPath, "-u", "-c", w.script)
+	cmd.Env = w.env
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open worker stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open worker stdout: %w", err)
+	}
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start python worker: %w", err)
+	}
+
+	w.cmd = cmd
+	w.stdin = bufio.NewWriter(stdin)
+	w.stdinPipe = stdin
+	w.stdout = bufio.NewReader(stdout)
+	w.stderr = stderr
+
+	line, err := w.readLineLocked(ctx)
+	if err != nil {
+		stderrText := w.stopLocked()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("python worker exited during startup: %w%s", err, formatStderr(stderrText))
+	}
+
+	var status workerError
+	if err := json.Unmarshal(line, &status); err != nil {
+		w.stopLocked()
+		return fmt.Errorf("failed to parse worker handshake: %w", err)
+	}
+	if status.Error != "" {
+		w.stopLocked()
+		return fmt.Errorf("python worker failed to start: %s", status.Error)
+	}
+
+	w.handshake = line
+	return nil
+}
+
+// readLineLocked reads the next stdout line, killing the process if ctx ends first
+// so that the blocked read returns
+func (w *pythonWorker) readLineLocked(ctx context.Context) ([]byte, error) {
+	type readResult struct {
+		line []byte
+		err  error
+	}
+
+	stdout := w.stdout
+	done := make(chan readResult, 1)
+	go func() {
+		line, err := stdout.ReadBytes('\n')
+		done <- readResult{line: line, err: err}
+	}()
+
+	select {
+	case result := <-done:
+		return result.line, result.err
+	case <-ctx.Done():
+		w.cmd.Process.Kill()
+		<-done
+		return nil, ctx.Err()
+	}
+}
+
+// stopLocked kills the process, waits for it and returns what it wrote to stderr
+func (w *pythonWorker) stopLocked() string {
+	if w.cmd == nil {
+		return ""
+	}
+
+	w.stdinPipe.Close()
+	w.cmd.Process.Kill()
+	w.cmd.Wait()
+	stderr := w.stderr.String()
+
+	w.cmd = nil
+	w.stdin = nil
+	w.stdinPipe = nil
+	w.stdout = nil
+	w.stderr = nil
+	w.handshake = nil
+	return stderr
+}
+
+// formatStderr appends the worker's stderr output to an error message
+func formatStderr(stderr string) string {
+	stderr = strings.TrimSpace(stderr)
+	if stderr == "" {
+		return ""
+	}
+	return ": " + stderr
+}
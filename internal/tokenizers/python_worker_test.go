@@ -0,0 +1,199 @@
+package tokenizers
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// echoWorkerScript echoes each request's text, or each of its texts on separate lines.
+// "crash" kills the process the first time it is seen, "fail" reports an error and
+// "sleep" never answers.
+const echoWorkerScript = `
+import json
+import os
+import sys
+import time
+
+def send(message):
+    sys.stdout.write(json.dumps(message) + "\n")
+    sys.stdout.flush()
+
+send({"ready": True, "pid": os.getpid()})
+
+for line in sys.stdin.buffer:
+    request = json.loads(line)
+    if "texts" in request:
+        for text in request["texts"]:
+            if text == "fail":
+                send({"error": "bad input"})
+            else:
+                send({"echo": text, "pid": os.getpid()})
+        continue
+    text = request["text"]
+    marker = os.environ["WORKER_MARKER"]
+    if text == "crash" and not os.path.exists(marker):
+        open(marker, "w").close()
+        os._exit(3)
+    if text == "fail":
+        send({"error": "bad input"})
+        continue
+    if text == "sleep":
+        time.sleep(30)
+    send({"echo": text, "pid": os.getpid()})
+`
+
+type echoRequest struct {
+	Text string `json:"text"`
+}
+
+type echoResponse struct {
+	Echo string `json:"echo"`
+	PID  int    `json:"pid"`
+}
+
+func newEchoWorker(t *testing.T) *pythonWorker {
+	t.Helper()
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not available")
+	}
+	marker := filepath.Join(t.TempDir(), "crashed")
+	worker := newPythonWorker(python, echoWorkerScript, append(os.Environ(), "WORKER_MARKER="+marker))
+	t.Cleanup(func() { worker.Close() })
+	return worker
+}
+
+func TestPythonWorkerReusesProcess(t *testing.T) {
+	worker := newEchoWorker(t)
+	ctx := context.Background()
+
+	var first, second echoResponse
+	if err := worker.Call(ctx, echoRequest{Text: "héllo wörld"}, &first); err != nil {
+		t.Fatalf("Call returned error: %v", err)
+	}
+	if err := worker.Call(ctx, echoRequest{Text: "line one\nline two"}, &second); err != nil {
+		t.Fatalf("Call returned error: %v", err)
+	}
+
+	if first.Echo != "héllo wörld" || second.Echo != "line one\nline two" {
+		t.Errorf("unexpected echoes: %q, %q", first.Echo, second.Echo)
+	}
+	if first.PID != second.PID {
+		t.Errorf("expected one process for both requests, got pids %d and %d", first.PID, second.PID)
+	}
+}
+
+func TestPythonWorkerCallEach(t *testing.T) {
+	worker := newEchoWorker(t)
+	ctx := context.Background()
+
+	request := struct {
+		Texts []string `json:"texts"`
+	}{Texts: []string{"a", "fail", "c"}}
+
+	var seen []int
+	err := worker.CallEach(ctx, request, len(request.Texts), func(i int, line []byte) error {
+		seen = append(seen, i)
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "item 1") {
+		t.Fatalf("expected an error for item 1, got %v", err)
+	}
+	if !reflect.DeepEqual(seen, []int{0}) {
+		t.Errorf("handled items %v, want [0]", seen)
+	}
+
+	// The remaining lines were drained, so the next request gets its own response
+	var resp echoResponse
+	if err := worker.Call(ctx, echoRequest{Text: "next"}, &resp); err != nil {
+		t.Fatalf("Call returned error: %v", err)
+	}
+	if resp.Echo != "next" {
+		t.Errorf("echo = %q, want %q", resp.Echo, "next")
+	}
+}
+
+func TestPythonWorkerRestartsAfterCrash(t *testing.T) {
+	worker := newEchoWorker(t)
+
+	var before, after echoResponse
+	if err := worker.Call(context.Background(), echoRequest{Text: "a"}, &before); err != nil {
+		t.Fatalf("Call returned error: %v", err)
+	}
+	if err := worker.Call(context.Background(), echoRequest{Text: "crash"}, &after); err != nil {
+		t.Fatalf("expected the request to be retried on a new process, got %v", err)
+	}
+
+	if after.Echo != "crash" {
+		t.Errorf("echo = %q, want %q", after.Echo, "crash")
+	}
+	if before.PID == after.PID {
+		t.Errorf("expected a restarted process, pid stayed %d", before.PID)
+	}
+}
+
+func TestPythonWorkerReportsScriptErrors(t *testing.T) {
+	worker := newEchoWorker(t)
+
+	var resp echoResponse
+	err := worker.Call(context.Background(), echoRequest{Text: "fail"}, &resp)
+	if err == nil || !strings.Contains(err.Error(), "bad input") {
+		t.Fatalf("expected the script's error, got %v", err)
+	}
+
+	// A reported error leaves the process running
+	if err := worker.Call(context.Background(), echoRequest{Text: "ok"}, &resp); err != nil {
+		t.Fatalf("Call after script error returned error: %v", err)
+	}
+}
+
+func TestPythonWorkerCancellation(t *testing.T) {
+	worker := newEchoWorker(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	var resp echoResponse
+	start := time.Now()
+	err := worker.Call(ctx, echoRequest{Text: "sleep"}, &resp)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Call error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("cancellation took %v", elapsed)
+	}
+
+	// The killed worker is replaced on the next call
+	if err := worker.Call(context.Background(), echoRequest{Text: "again"}, &resp); err != nil {
+		t.Fatalf("Call after cancellation returned error: %v", err)
+	}
+	if resp.Echo != "again" {
+		t.Errorf("echo = %q, want %q", resp.Echo, "again")
+	}
+}
+
+func TestPythonWorkerStartupError(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not available")
+	}
+
+	script := `import json, sys
+sys.stdout.write(json.dumps({"error": "No module named 'transformers'"}) + "\n")
+sys.exit(1)
+`
+	worker := newPythonWorker(python, script, os.Environ())
+	defer worker.Close()
+
+	err = worker.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "transformers") {
+		t.Fatalf("expected startup error naming the missing module, got %v", err)
+	}
+}
@@ -23,6 +23,10 @@ func RegisterAllTokenizers() error {
 		{"mt5-base", RegisterMT5Tokenizer},
 		{"albert-base", RegisterALBERTTokenizer},
 		{"openai-api", RegisterOpenAITokenizer},
+		{"bpe-local", RegisterLocalBPETokenizer},
+		{"char", RegisterCharTokenizer},
+		{"byte", RegisterByteTokenizer},
+		{"whitespace", RegisterWhitespaceTokenizer},
 	}
 
 	var errors []string
@@ -39,6 +43,155 @@ func RegisterAllTokenizers() error {
 	return nil
 }
 
+// newBuiltinTokenizer creates the named built-in tokenizer with its default model,
+// or returns nil when name is not a built-in tokenizer
+func newBuiltinTokenizer(name string) Tokenizer {
+	switch name {
+	case "mock":
+		return NewMockTokenizer("mock")
+	case "gpt2", "gpt-3.5-turbo", "gpt-4":
+		tokenizer := NewGPT2Tokenizer(name)
+		tokenizer.modelName = name
+		return tokenizer
+	case "roberta-base":
+		return newHuggingFaceModel("roberta-base", "bpe")
+	case "gpt-neo":
+		return newHuggingFaceModel("EleutherAI/gpt-neo-125M", "bpe")
+	case "bert-base":
+		return newHuggingFaceModel("bert-base-uncased", "wordpiece")
+	case "distilbert-base":
+		return newHuggingFaceModel("distilbert-base-uncased", "wordpiece")
+	case "t5-base":
+		return newSentencePieceModel("t5-base", "t5-base", "unigram")
+	case "mt5-base":
+		return newSentencePieceModel("mt5-base", "google/mt5-base", "unigram")
+	case "albert-base":
+		return newSentencePieceModel("albert-base-v2", "albert-base-v2", "unigram")
+	case "openai-api":
+		return NewOpenAITokenizer("openai-api")
+	case "bpe-local":
+		return NewLocalBPETokenizer("bpe-local")
+	case "char":
+		return NewCharTokenizer("char")
+	case "byte":
+		return NewByteTokenizer("byte")
+	case "whitespace":
+		return NewWhitespaceTokenizer("whitespace")
+	}
+	return nil
+}
+
+// newHuggingFaceModel creates a HuggingFace tokenizer named after its model
+func newHuggingFaceModel(model, tokenizerType string) *HuggingFaceTokenizer {
+	tokenizer := NewHuggingFaceTokenizer(model)
+	tokenizer.modelName = model
+	tokenizer.tokenizerType = tokenizerType
+	return tokenizer
+}
+
+// newSentencePieceModel creates a SentencePiece tokenizer for a model path, which for
+// the built-ins is a HuggingFace repository ID resolved through the local cache
+func newSentencePieceModel(name, modelPath, modelType string) *SentencePieceTokenizer {
+	tokenizer := NewSentencePieceTokenizer(name)
+	tokenizer.modelPath = modelPath
+	tokenizer.modelType = modelType
+	return tokenizer
+}
+
+// newTokenizerForType picks an adapter for a configured tokenizer that is not built
+// in, based on its type and parameters. A tokenizer with a command runs it, whatever
+// its type.
+func newTokenizerForType(config TokenizerConfig) (Tokenizer, error) {
+	if config.Command != "" || config.Parameters["command"] != "" {
+		return NewCommandTokenizer(config.Name), nil
+	}
+
+	switch config.Type {
+	case "bpe":
+		switch {
+		case config.Parameters["vocab_path"] != "":
+			return NewLocalBPETokenizer(config.Name), nil
+		case config.Parameters["api_key"] != "" || config.Parameters["endpoint"] != "":
+			return NewOpenAITokenizer(config.Name), nil
+		case gptModels[config.Parameters["model"]]:
+			return NewGPT2Tokenizer(config.Name), nil
+		default:
+			return NewHuggingFaceTokenizer(config.Name), nil
+		}
+	case "wordpiece":
+		return NewHuggingFaceTokenizer(config.Name), nil
+	case "spiece", "unigram":
+		return NewSentencePieceTokenizer(config.Name), nil
+	case "custom":
+		if config.LibraryPath != "" {
+			return nil, fmt.Errorf("custom tokenizer %s: loading tokenizers from library_path is not supported", config.Name)
+		}
+		return NewMockTokenizer(config.Name), nil
+	case "":
+		return nil, fmt.Errorf("tokenizer %s is not built in and has no type", config.Name)
+	default:
+		return nil, fmt.Errorf("tokenizer %s has unsupported type: %s", config.Name, config.Type)
+	}
+}
+
+// NewConfiguredTokenizer creates the adapter for a configured tokenizer and
+// initializes it with the configured parameters. Built-in names start from their
+// default model; other names get an adapter chosen by type.
+func NewConfiguredTokenizer(config TokenizerConfig) (Tokenizer, error) {
+	if config.Type == "" {
+		if tokenizerType := GetTokenizerType(config.Name); tokenizerType != "unknown" {
+			config.Type = tokenizerType
+		}
+	}
+	if config.Parameters == nil {
+		config.Parameters = map[string]string{}
+	}
+
+	tokenizer := newBuiltinTokenizer(config.Name)
+	if tokenizer == nil {
+		var err error
+		if tokenizer, err = newTokenizerForType(config); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tokenizer.Initialize(config); err != nil {
+		return nil, fmt.Errorf("failed to initialize tokenizer %s: %w", config.Name, err)
+	}
+
+	return tokenizer, nil
+}
+
+// RegisterConfiguredTokenizers creates and initializes each configured tokenizer and
+// registers it with registry, replacing any tokenizer already registered under the
+// same name. A tokenizer that fails to initialize leaves the registry unchanged.
+func RegisterConfiguredTokenizers(registry *TokenizerRegistry, configs []TokenizerConfig) error {
+	var errors []string
+	for _, config := range configs {
+		tokenizer, err := NewConfiguredTokenizer(config)
+		if err != nil {
+			errors = append(errors, err.Error())
+			continue
+		}
+
+		previous, err := registry.Replace(config.Name, tokenizer)
+		if err != nil {
+			tokenizer.Close()
+			errors = append(errors, fmt.Sprintf("%s: %v", config.Name, err))
+			continue
+		}
+		if previous != nil && previous != tokenizer {
+			previous.Close()
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("failed to register some configured tokenizers: %s", strings.Join(errors, "; "))
+	}
+
+	return nil
+}
+
 // GetAvailableTokenizers returns a list of all available tokenizer names
 func GetAvailableTokenizers() []string {
 	return []string{
@@ -54,6 +207,10 @@ func GetAvailableTokenizers() []string {
 		"mt5-base",
 		"albert-base",
 		"openai-api",
+		"bpe-local",
+		"char",
+		"byte",
+		"whitespace",
 	}
 }
 
@@ -81,8 +238,12 @@ func GetTokenizerDescription(name string) string {
 		"distilbert-base": "DistilBERT tokenizer using HuggingFace (WordPiece)",
 		"t5-base":        "T5 tokenizer using SentencePiece (Unigram)",
 		"mt5-base":       "mT5 tokenizer using SentencePiece (Unigram)",
-		"albert-base":    "ALBERT tokenizer using SentencePiece (WordPiece)",
-		"openai-api":     "OpenAI API tokenizer (requires API key)",
+		"albert-base":    "ALBERT tokenizer using SentencePiece (Unigram)",
+		"openai-api":     "OpenAI model encodings via tiktoken or a token counting endpoint",
+		"bpe-local":      "Pure-Go byte-level BPE from local vocab.json and merges.txt",
+		"char":           "Baseline with one token per character (rune)",
+		"byte":           "Baseline with one token per UTF-8 byte",
+		"whitespace":     "Baseline splitting on whitespace with punctuation separated",
 	}
 
 	if desc, ok := descriptions[name]; ok {
@@ -117,17 +278,28 @@ func GetTokenizerRequirements(name string) map[string]string {
 			"python": "Python 3.7+ with transformers package",
 		},
 		"t5-base": {
-			"python": "Python 3.7+ with sentencepiece package",
+			"python":     "Python 3.7+ with sentencepiece package",
+			"model_path": "spiece.model from the t5-base repository, or the repository in the HuggingFace cache",
 		},
 		"mt5-base": {
-			"python": "Python 3.7+ with sentencepiece package",
+			"python":     "Python 3.7+ with sentencepiece package",
+			"model_path": "spiece.model from the google/mt5-base repository, or the repository in the HuggingFace cache",
 		},
 		"albert-base": {
-			"python": "Python 3.7+ with sentencepiece package",
+			"python":     "Python 3.7+ with sentencepiece package",
+			"model_path": "spiece.model from the albert-base-v2 repository, or the repository in the HuggingFace cache",
 		},
 		"openai-api": {
-			"api_key": "OpenAI API key required",
+			"python":   "Python 3.7+ with tiktoken package, unless endpoint is set",
+			"endpoint": "Optional URL of a compatible token counting endpoint",
+		},
+		"bpe-local": {
+			"vocab_path":  "Path to a GPT-2 style vocab.json",
+			"merges_path": "Path to the matching merges.txt",
 		},
+		"char":       {},
+		"byte":       {},
+		"whitespace": {},
 	}
 
 	if req, ok := requirements[name]; ok {
@@ -149,8 +321,12 @@ func GetTokenizerType(name string) string {
 		"distilbert-base": "wordpiece",
 		"t5-base":        "unigram",
 		"mt5-base":       "unigram",
-		"albert-base":    "wordpiece",
+		"albert-base":    "unigram",
 		"openai-api":     "bpe",
+		"bpe-local":      "bpe",
+		"char":           "custom",
+		"byte":           "custom",
+		"whitespace":     "custom",
 	}
 
 	if tokenizerType, ok := types[name]; ok {
@@ -174,6 +350,10 @@ func GetTokenizerBackend(name string) string {
 		"mt5-base":       "sentencepiece",
 		"albert-base":    "sentencepiece",
 		"openai-api":     "api",
+		"bpe-local":      "go",
+		"char":           "go",
+		"byte":           "go",
+		"whitespace":     "go",
 	}
 
 	if backend, ok := backends[name]; ok {
@@ -0,0 +1,209 @@
+package tokenizers
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestNewTokenizerForType(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     TokenizerConfig
+		want       Tokenizer
+		wantErrMsg string
+	}{
+		{"tiktoken model", TokenizerConfig{Name: "x", Type: "bpe", Parameters: map[string]string{"model": "gpt-4"}}, &GPT2Tokenizer{}, ""},
+		{"huggingface model", TokenizerConfig{Name: "x", Type: "bpe", Parameters: map[string]string{"model": "facebook/bart-base"}}, &HuggingFaceTokenizer{}, ""},
+		{"local vocab", TokenizerConfig{Name: "x", Type: "bpe", Parameters: map[string]string{"vocab_path": "vocab.json"}}, &LocalBPETokenizer{}, ""},
+		{"api key", TokenizerConfig{Name: "x", Type: "bpe", Parameters: map[string]string{"api_key": "sk-test"}}, &OpenAITokenizer{}, ""},
+		{"wordpiece", TokenizerConfig{Name: "x", Type: "wordpiece"}, &HuggingFaceTokenizer{}, ""},
+		{"spiece", TokenizerConfig{Name: "x", Type: "spiece"}, &SentencePieceTokenizer{}, ""},
+		{"custom", TokenizerConfig{Name: "x", Type: "custom"}, &MockTokenizer{}, ""},
+		{"custom library", TokenizerConfig{Name: "x", Type: "custom", LibraryPath: "/lib/tok.so"}, nil, "library_path"},
+		{"no type", TokenizerConfig{Name: "x"}, nil, "has no type"},
+		{"unknown type", TokenizerConfig{Name: "x", Type: "morfessor"}, nil, "unsupported type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newTokenizerForType(tt.config)
+			if tt.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrMsg) {
+					t.Errorf("expected an error mentioning %q, got %v", tt.wantErrMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newTokenizerForType returned error: %v", err)
+			}
+			if reflect.TypeOf(got) != reflect.TypeOf(tt.want) {
+				t.Errorf("got %T, want %T", got, tt.want)
+			}
+			if got.Name() != tt.config.Name {
+				t.Errorf("Name() = %q, want %q", got.Name(), tt.config.Name)
+			}
+		})
+	}
+}
+
+func TestNewConfiguredTokenizer(t *testing.T) {
+	tokenizer, err := NewConfiguredTokenizer(TokenizerConfig{
+		Name:       "mock",
+		Parameters: map[string]string{"vocab_size": "77"},
+	})
+	if err != nil {
+		t.Fatalf("NewConfiguredTokenizer returned error: %v", err)
+	}
+	if tokenizer.Type() != "custom" {
+		t.Errorf("Type() = %q, want the built-in type custom", tokenizer.Type())
+	}
+	if size, _ := tokenizer.GetVocabSize(); size != 77 {
+		t.Errorf("vocab size = %d, want the configured 77", size)
+	}
+
+	_, err = NewConfiguredTokenizer(TokenizerConfig{Name: "my-bpe", Type: "bpe", Parameters: map[string]string{"vocab_path": "missing.json"}})
+	if err == nil || !strings.Contains(err.Error(), "my-bpe") {
+		t.Errorf("expected an initialization error naming my-bpe, got %v", err)
+	}
+}
+
+func TestRegisterConfiguredTokenizers(t *testing.T) {
+	registry := NewTokenizerRegistry()
+	if err := registry.Register("mock", NewMockTokenizer("mock")); err != nil {
+		t.Fatalf("Register returned error: %v", err)
+	}
+	previous, _ := registry.Get("mock")
+
+	err := RegisterConfiguredTokenizers(registry, []TokenizerConfig{
+		{Name: "mock", Parameters: map[string]string{"vocab_size": "5"}},
+		{
+			Name: "local",
+			Type: "bpe",
+			Parameters: map[string]string{
+				"vocab_path":  filepath.Join("testdata", "bpe", "vocab.json"),
+				"merges_path": filepath.Join("testdata", "bpe", "merges.txt"),
+			},
+		},
+		{Name: "broken", Type: "custom", LibraryPath: "/lib/tok.so"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("expected an error for the broken tokenizer, got %v", err)
+	}
+
+	mock, err := registry.Get("mock")
+	if err != nil || mock == previous {
+		t.Errorf("mock was not replaced by the configured tokenizer")
+	}
+	if size, _ := mock.GetVocabSize(); size != 5 {
+		t.Errorf("mock vocab size = %d, want 5", size)
+	}
+	if _, err := registry.Get("local"); err != nil {
+		t.Errorf("local tokenizer was not registered: %v", err)
+	}
+	if _, err := registry.Get("broken"); err == nil {
+		t.Error("broken tokenizer should not be registered")
+	}
+}
+
+func TestRegistryConcurrentAccess(t *testing.T) {
+	registry := NewTokenizerRegistry()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				name := fmt.Sprintf("tok-%d", (i+j)%8)
+				registry.Register(name, NewMockTokenizer(name))
+				registry.Get(name)
+				registry.List()
+				if j%10 == 0 {
+					registry.Unregister(name)
+				}
+				if j%7 == 0 {
+					registry.Replace(name, NewMockTokenizer(name))
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, name := range registry.List() {
+		if _, err := registry.Get(name); err != nil {
+			t.Errorf("listed tokenizer %s cannot be retrieved: %v", name, err)
+		}
+	}
+}
+
+func TestRegistryGetOrCreate(t *testing.T) {
+	registry := NewTokenizerRegistry()
+
+	var calls int32
+	create := func() (Tokenizer, error) {
+		atomic.AddInt32(&calls, 1)
+		return NewMockTokenizer("shared"), nil
+	}
+
+	results := make([]Tokenizer, 64)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tokenizer, err := registry.GetOrCreate("shared", create)
+			if err != nil {
+				t.Errorf("GetOrCreate returned error: %v", err)
+			}
+			results[i] = tokenizer
+		}(i)
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("create called %d times, want 1", calls)
+	}
+	for i, tokenizer := range results {
+		if tokenizer != results[0] {
+			t.Fatalf("caller %d got a different tokenizer", i)
+		}
+	}
+
+	_, err := registry.GetOrCreate("failing", func() (Tokenizer, error) {
+		return nil, fmt.Errorf("backend unavailable")
+	})
+	if err == nil || !strings.Contains(err.Error(), "backend unavailable") {
+		t.Errorf("expected the create error, got %v", err)
+	}
+	if _, err := registry.Get("failing"); err == nil {
+		t.Error("a failed create should not register a tokenizer")
+	}
+}
+
+func TestRegistryReplace(t *testing.T) {
+	registry := NewTokenizerRegistry()
+	first := NewMockTokenizer("tok")
+
+	previous, err := registry.Replace("tok", first)
+	if err != nil || previous != nil {
+		t.Fatalf("Replace = %v, %v, want nil, nil", previous, err)
+	}
+
+	second := NewMockTokenizer("tok")
+	previous, err = registry.Replace("tok", second)
+	if err != nil || previous != Tokenizer(first) {
+		t.Errorf("Replace returned %v, %v, want the first tokenizer", previous, err)
+	}
+	if got, _ := registry.Get("tok"); got != Tokenizer(second) {
+		t.Error("Get did not return the replacement")
+	}
+
+	if _, err := registry.Replace("", second); err == nil {
+		t.Error("expected an error for an empty name")
+	}
+}
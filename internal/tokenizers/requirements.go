@@ -0,0 +1,115 @@
+package tokenizers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Problem is a requirement a tokenizer's configuration does not meet. A warning may
+// still work, depending on the environment it runs in.
+type Problem struct {
+	Parameter string `json:"parameter,omitempty"` // parameter at fault; empty for the tokenizer as a whole
+	Message   string `json:"message"`
+	Warning   bool   `json:"warning,omitempty"`
+}
+
+// CheckConfig checks a tokenizer's configuration against the requirements of the
+// adapter it would use, returning every problem found rather than the first. Without
+// deep only the configuration itself is checked; with deep the tokenizer is also
+// initialized, which probes its Python packages, model and vocabulary files, and the
+// format of an API key is checked.
+func CheckConfig(config TokenizerConfig, deep bool) []Problem {
+	var problems []Problem
+	fail := func(parameter, format string, args ...interface{}) {
+		problems = append(problems, Problem{Parameter: parameter, Message: fmt.Sprintf(format, args...)})
+	}
+	warn := func(parameter, format string, args ...interface{}) {
+		problems = append(problems, Problem{Parameter: parameter, Message: fmt.Sprintf(format, args...), Warning: true})
+	}
+
+	tokenizer := newBuiltinTokenizer(config.Name)
+	if tokenizer == nil {
+		var err error
+		if tokenizer, err = newTokenizerForType(config); err != nil {
+			fail("", "%v", err)
+			return problems
+		}
+	}
+
+	parameters := config.Parameters
+	switch t := tokenizer.(type) {
+	case *SentencePieceTokenizer:
+		if parameters["model_path"] == "" {
+			if t.modelPath == "" {
+				fail("model_path", "required: %s", "the path of a SentencePiece .model file, or a directory or HuggingFace repository containing one")
+			} else {
+				warn("model_path", "not set, so the %s repository must already be in the HuggingFace cache", t.modelPath)
+			}
+		}
+	case *LocalBPETokenizer:
+		for _, parameter := range []string{"vocab_path", "merges_path"} {
+			if parameters[parameter] == "" {
+				fail(parameter, "required: %s", GetTokenizerRequirements("bpe-local")[parameter])
+			}
+		}
+	case *OpenAITokenizer:
+		if _, ok := parameters["api_base"]; ok {
+			fail("api_base", "no longer supported; remove it to use local tiktoken, or set endpoint to a compatible token counting endpoint")
+		}
+		if parameters["endpoint"] != "" && parameters["api_key"] == "" {
+			warn("api_key", "not set, so requests to %s are sent without an API key", parameters["endpoint"])
+		}
+		if value, ok := parameters["max_retries"]; ok {
+			if retries, err := strconv.Atoi(value); err != nil || retries < 0 {
+				fail("max_retries", "must be a non-negative integer: %s", value)
+			}
+		}
+		for _, parameter := range []string{"retry_backoff", "timeout"} {
+			if value, ok := parameters[parameter]; ok {
+				if d, err := time.ParseDuration(value); err != nil || d < 0 || (parameter == "timeout" && d == 0) {
+					fail(parameter, "invalid duration: %s", value)
+				}
+			}
+		}
+		if key := parameters["api_key"]; deep && key != "" && !validAPIKey(key) {
+			warn("api_key", "does not look like an API key (sk- followed by letters, digits, - or _)")
+		}
+	}
+
+	// Initializing probes the backend; skip it when the configuration is already wrong
+	if deep && onlyWarnings(problems) {
+		configured, err := NewConfiguredTokenizer(config)
+		if err != nil {
+			fail("", "%v", err)
+		} else {
+			configured.Close()
+		}
+	}
+	return problems
+}
+
+// onlyWarnings reports whether every problem is a warning
+func onlyWarnings(problems []Problem) bool {
+	for _, problem := range problems {
+		if !problem.Warning {
+			return false
+		}
+	}
+	return true
+}
+
+// validAPIKey reports whether key has the shape of an OpenAI API key
+func validAPIKey(key string) bool {
+	rest, ok := strings.CutPrefix(key, "sk-")
+	if !ok || len(rest) < 20 {
+		return false
+	}
+	for _, r := range rest {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
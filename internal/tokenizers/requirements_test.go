@@ -0,0 +1,59 @@
+package tokenizers
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCheckConfig(t *testing.T) {
+	vocab := filepath.Join("testdata", "bpe", "vocab.json")
+	merges := filepath.Join("testdata", "bpe", "merges.txt")
+	key := "sk-" + "abcdefghijklmnopqrstuvwxyz0123"
+
+	tests := []struct {
+		name   string
+		config TokenizerConfig
+		deep   bool
+		want   []Problem // messages are not compared
+	}{
+		{"builtin without requirements", TokenizerConfig{Name: "char"}, false, nil},
+		{"no type", TokenizerConfig{Name: "x"}, false, []Problem{{}}},
+		{"bpe-local missing files", TokenizerConfig{Name: "bpe-local"}, false,
+			[]Problem{{Parameter: "vocab_path"}, {Parameter: "merges_path"}}},
+		{"bpe vocab without merges", TokenizerConfig{Name: "x", Type: "bpe", Parameters: map[string]string{"vocab_path": vocab}}, false,
+			[]Problem{{Parameter: "merges_path"}}},
+		{"spiece without model", TokenizerConfig{Name: "x", Type: "spiece"}, false, []Problem{{Parameter: "model_path"}}},
+		{"builtin spiece from cache", TokenizerConfig{Name: "t5-base"}, false, []Problem{{Parameter: "model_path", Warning: true}}},
+		{"openai settings", TokenizerConfig{Name: "openai-api", Parameters: map[string]string{
+			"api_base": "https://api.openai.com", "max_retries": "-1", "timeout": "0s", "retry_backoff": "soon"}}, false,
+			[]Problem{{Parameter: "api_base"}, {Parameter: "max_retries"}, {Parameter: "retry_backoff"}, {Parameter: "timeout"}}},
+		{"endpoint without key", TokenizerConfig{Name: "openai-api", Parameters: map[string]string{"endpoint": "http://localhost:1"}}, false,
+			[]Problem{{Parameter: "api_key", Warning: true}}},
+		{"key format only checked deep", TokenizerConfig{Name: "x", Type: "bpe", Parameters: map[string]string{"api_key": "not a key"}}, false, nil},
+		{"deep key format", TokenizerConfig{Name: "x", Type: "bpe", Parameters: map[string]string{"api_key": "not a key", "endpoint": "http://localhost:1"}}, true,
+			[]Problem{{Parameter: "api_key", Warning: true}}},
+		{"deep valid key", TokenizerConfig{Name: "x", Type: "bpe", Parameters: map[string]string{"api_key": key, "endpoint": "http://localhost:1"}}, true, nil},
+		{"deep missing vocabulary", TokenizerConfig{Name: "x", Type: "bpe", Parameters: map[string]string{"vocab_path": "missing.json", "merges_path": merges}}, true,
+			[]Problem{{}}},
+		{"deep vocabulary", TokenizerConfig{Name: "x", Type: "bpe", Parameters: map[string]string{"vocab_path": vocab, "merges_path": merges}}, true, nil},
+		{"deep skipped after a failure", TokenizerConfig{Name: "bpe-local", Parameters: map[string]string{"vocab_path": "missing.json"}}, true,
+			[]Problem{{Parameter: "merges_path"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := CheckConfig(tt.config, tt.deep)
+			var got []Problem
+			for _, problem := range problems {
+				if problem.Message == "" {
+					t.Errorf("problem %+v has no message", problem)
+				}
+				got = append(got, Problem{Parameter: problem.Parameter, Warning: problem.Warning})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckConfig() = %+v, want %+v", problems, tt.want)
+			}
+		})
+	}
+}
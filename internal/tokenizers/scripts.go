@@ -0,0 +1,21 @@
+package tokenizers
+
+import _ "embed"
+
+// The Python adapter scripts are constant; models and paths are passed to them via
+// the TED_MODEL and TED_MODEL_PATH environment variables, never in the source
+
+//go:embed scripts/gpt2_batch.py
+var gpt2BatchScript string
+
+//go:embed scripts/huggingface_worker.py
+var huggingFaceWorkerScript string
+
+//go:embed scripts/sentencepiece_batch.py
+var sentencePieceBatchScript string
+
+// scriptEnv returns the environment for a Python adapter script. Both variables are
+// always set, so values inherited from the parent process never leak into the script.
+func scriptEnv(venvPath, model, modelPath string) []string {
+	return append(venvEnv(venvPath), "TED_MODEL="+model, "TED_MODEL_PATH="+modelPath)
+}
@@ -0,0 +1,66 @@
+# Tokenizes a batch of texts with tiktoken.
+#
+# Configuration comes from the environment so that no value is ever interpolated into
+# this source: TED_MODEL names the tiktoken model. Reads {"texts": [...]} from stdin
+# and writes one JSON result line per text, or reads {"vocab": {"max_entries": n}} and
+# writes the vocabulary as {"vocab": {token: id}}.
+import json
+import os
+import sys
+
+try:
+    import tiktoken
+
+    # Initialize tokenizer
+    encoding = tiktoken.encoding_for_model(os.environ["TED_MODEL"])
+except Exception as e:
+    print(json.dumps({"error": str(e)}), file=sys.stderr)
+    sys.exit(1)
+
+
+def token_text(token_bytes):
+    # Tokens that are not valid UTF-8 on their own are written as <0xNN> bytes, the
+    # way SentencePiece writes its byte fallback pieces
+    try:
+        return token_bytes.decode("utf-8")
+    except UnicodeDecodeError:
+        return "".join("<0x%02X>" % b for b in token_bytes)
+
+
+request = json.loads(sys.stdin.buffer.read())
+
+if "vocab" in request:
+    if encoding.n_vocab > request["vocab"]["max_entries"]:
+        print(json.dumps({"error": "vocabulary has %d entries, more than the limit of %d"
+                          % (encoding.n_vocab, request["vocab"]["max_entries"])}))
+        sys.exit(0)
+
+    vocab = {}
+    for token_id in range(encoding.n_vocab):
+        try:
+            vocab[token_text(encoding.decode_single_token_bytes(token_id))] = token_id
+        except KeyError:
+            # Encodings leave gaps in their IDs before the special tokens
+            continue
+    print(json.dumps({"vocab": vocab}))
+    sys.exit(0)
+
+texts = request["texts"]
+
+for text in texts:
+    try:
+        # Tokenize text
+        tokens = encoding.encode(text)
+
+        # Get token texts and byte lengths; offsets are assigned on the Go side
+        token_texts = []
+        for token_id in tokens:
+            token_texts.append({
+                "id": token_id,
+                "text": encoding.decode([token_id]),
+                "byte_length": len(encoding.decode_single_token_bytes(token_id))
+            })
+
+        print(json.dumps({"tokens": token_texts, "vocab_size": encoding.n_vocab}), flush=True)
+    except Exception as e:
+        print(json.dumps({"error": str(e)}), flush=True)
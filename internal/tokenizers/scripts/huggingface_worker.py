@@ -0,0 +1,62 @@
+# Long-lived HuggingFace tokenizer worker.
+#
+# Configuration comes from the environment so that no value is ever interpolated into
+# this source: TED_MODEL_PATH (a local directory) takes precedence over TED_MODEL (a
+# hub model name). After loading, writes a handshake line with the vocabulary size,
+# then answers each {"texts": [...]} request line with one JSON result line per text,
+# and each {"vocab": {"max_entries": n}} line with the vocabulary as {"vocab": {token: id}}.
+import json
+import os
+import sys
+
+def send(message):
+    sys.stdout.write(json.dumps(message) + "\n")
+    sys.stdout.flush()
+
+try:
+    from transformers import AutoTokenizer
+
+    source = os.environ.get("TED_MODEL_PATH") or os.environ.get("TED_MODEL", "")
+    tokenizer = AutoTokenizer.from_pretrained(source)
+except Exception as e:
+    send({"error": str(e)})
+    sys.exit(1)
+
+send({"ready": True, "vocab_size": tokenizer.vocab_size})
+
+for line in sys.stdin.buffer:
+    try:
+        request = json.loads(line)
+        if "vocab" in request:
+            # Includes added tokens, which vocab_size does not count
+            vocab = tokenizer.get_vocab()
+            if len(vocab) > request["vocab"]["max_entries"]:
+                send({"error": "vocabulary has %d entries, more than the limit of %d"
+                      % (len(vocab), request["vocab"]["max_entries"])})
+            else:
+                send({"vocab": vocab})
+            continue
+        texts = request["texts"]
+    except Exception as e:
+        send({"error": str(e)})
+        continue
+
+    for text in texts:
+        try:
+            # Tokenize text
+            encoding = tokenizer(text, return_offsets_mapping=True, add_special_tokens=False)
+            tokens = encoding.tokens()
+            input_ids = encoding.input_ids
+
+            token_objects = []
+            for i, (token, (start, end)) in enumerate(zip(tokens, encoding.offset_mapping)):
+                token_objects.append({
+                    "id": input_ids[i] if i < len(input_ids) else 0,
+                    "text": token,
+                    "start_pos": start,
+                    "end_pos": end
+                })
+
+            send({"tokens": token_objects, "vocab_size": tokenizer.vocab_size})
+        except Exception as e:
+            send({"error": str(e)})
@@ -0,0 +1,45 @@
+# Tokenizes a batch of texts with a SentencePiece model.
+#
+# Configuration comes from the environment so that no value is ever interpolated into
+# this source: TED_MODEL_PATH is the .model file. Reads {"texts": [...]} from stdin
+# and writes one JSON result line per text, or reads {"vocab": {"max_entries": n}} and
+# writes the vocabulary as {"vocab": {piece: id}}.
+import json
+import os
+import sys
+
+try:
+    import sentencepiece as spm
+
+    # Initialize tokenizer
+    sp = spm.SentencePieceProcessor()
+    sp.load(os.environ["TED_MODEL_PATH"])
+except Exception as e:
+    print(json.dumps({"error": str(e)}), file=sys.stderr)
+    sys.exit(1)
+
+request = json.loads(sys.stdin.buffer.read())
+
+if "vocab" in request:
+    if sp.get_piece_size() > request["vocab"]["max_entries"]:
+        print(json.dumps({"error": "vocabulary has %d entries, more than the limit of %d"
+                          % (sp.get_piece_size(), request["vocab"]["max_entries"])}))
+        sys.exit(0)
+
+    print(json.dumps({"vocab": {sp.id_to_piece(i): i for i in range(sp.get_piece_size())}}))
+    sys.exit(0)
+
+texts = request["texts"]
+
+for text in texts:
+    try:
+        # Tokenize text
+        pieces = sp.encode_as_pieces(text)
+        ids = sp.encode_as_ids(text)
+
+        # Positions are left to the adapter, which matches the pieces against the text
+        token_objects = [{"id": token_id, "text": piece} for piece, token_id in zip(pieces, ids)]
+
+        print(json.dumps({"tokens": token_objects, "vocab_size": sp.get_piece_size()}), flush=True)
+    except Exception as e:
+        print(json.dumps({"error": str(e)}), flush=True)
@@ -0,0 +1,216 @@
+package tokenizers
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// awkwardNames are path components that would break a script built by string
+// interpolation
+var awkwardNames = []string{
+	`model "quoted" dir`,
+	`it's here`,
+	`with spaces`,
+	`ünïcödé モデル`,
+	`"); import os; os._exit(3); ("`,
+}
+
+// useScriptStubs points PYTHONPATH at testdata/pystubs so the real adapter scripts run
+// against stand-ins for tiktoken, sentencepiece and transformers
+func useScriptStubs(t *testing.T) string {
+	t.Helper()
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not available")
+	}
+	stubs, err := filepath.Abs(filepath.Join("testdata", "pystubs"))
+	if err != nil {
+		t.Fatalf("failed to resolve stub directory: %v", err)
+	}
+	t.Setenv("PYTHONPATH", stubs)
+	return python
+}
+
+func tokenTexts(result *TokenizationResult) []string {
+	texts := make([]string, len(result.Tokens))
+	for i, token := range result.Tokens {
+		texts[i] = token.Text
+	}
+	return texts
+}
+
+func TestSentencePieceScriptWithAwkwardPaths(t *testing.T) {
+	python := useScriptStubs(t)
+
+	for _, name := range awkwardNames {
+		t.Run(name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), name)
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatalf("failed to create model directory: %v", err)
+			}
+			modelPath := filepath.Join(dir, name+".model")
+			if err := os.WriteFile(modelPath, []byte("model"), 0644); err != nil {
+				t.Fatalf("failed to write model: %v", err)
+			}
+
+			tokenizer := NewSentencePieceTokenizer("spm")
+			err := tokenizer.Initialize(TokenizerConfig{
+				Name:       "spm",
+				Type:       "unigram",
+				Parameters: map[string]string{"model_path": modelPath, "python_path": python},
+			})
+			if err != nil {
+				t.Fatalf("Initialize returned error: %v", err)
+			}
+
+			result, err := tokenizer.Tokenize(context.Background(), "hello world")
+			if err != nil {
+				t.Fatalf("Tokenize returned error: %v", err)
+			}
+			if got, want := tokenTexts(result), []string{"▁hello", "▁world"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("tokens = %q, want %q", got, want)
+			}
+
+			vocabSize, err := tokenizer.GetVocabSize()
+			if err != nil || vocabSize != 100 {
+				t.Errorf("GetVocabSize = %d, %v, want 100", vocabSize, err)
+			}
+		})
+	}
+}
+
+func TestHuggingFaceScriptWithAwkwardPaths(t *testing.T) {
+	python := useScriptStubs(t)
+
+	for _, name := range awkwardNames {
+		t.Run(name, func(t *testing.T) {
+			modelPath := filepath.Join(t.TempDir(), name)
+			if err := os.MkdirAll(modelPath, 0755); err != nil {
+				t.Fatalf("failed to create model directory: %v", err)
+			}
+
+			tokenizer := NewHuggingFaceTokenizer("hf")
+			err := tokenizer.Initialize(TokenizerConfig{
+				Name:       "hf",
+				Type:       "bpe",
+				Parameters: map[string]string{"model_path": modelPath, "python_path": python},
+			})
+			if err != nil {
+				t.Fatalf("Initialize returned error: %v", err)
+			}
+			defer tokenizer.Close()
+
+			result, err := tokenizer.Tokenize(context.Background(), "héllo wörld")
+			if err != nil {
+				t.Fatalf("Tokenize returned error: %v", err)
+			}
+			if got, want := tokenTexts(result), []string{"héllo", "wörld"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("tokens = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+// TestScriptOffsetsAreBytes checks that the Python adapters report byte offsets for
+// multi-byte text, although the scripts work in code points or report no positions
+func TestScriptOffsetsAreBytes(t *testing.T) {
+	python := useScriptStubs(t)
+	dir := t.TempDir()
+	modelFile := writeModel(t, filepath.Join(dir, "spm.model"))
+
+	huggingFace := NewHuggingFaceTokenizer("hf")
+	if err := huggingFace.Initialize(TokenizerConfig{
+		Name:       "hf",
+		Type:       "bpe",
+		Parameters: map[string]string{"model_path": dir, "python_path": python},
+	}); err != nil {
+		t.Fatalf("Initialize returned error: %v", err)
+	}
+	defer huggingFace.Close()
+
+	sentencePiece := NewSentencePieceTokenizer("spm")
+	if err := sentencePiece.Initialize(TokenizerConfig{
+		Name:       "spm",
+		Type:       "unigram",
+		Parameters: map[string]string{"model_path": modelFile, "python_path": python},
+	}); err != nil {
+		t.Fatalf("Initialize returned error: %v", err)
+	}
+
+	tests := []struct {
+		tokenizer Tokenizer
+		want      []string
+	}{
+		{huggingFace, []string{"naïve", "日本"}},
+		{sentencePiece, []string{"naïve", " 日本"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tokenizer.Name(), func(t *testing.T) {
+			result, err := tt.tokenizer.Tokenize(context.Background(), "naïve 日本")
+			if err != nil {
+				t.Fatalf("Tokenize returned error: %v", err)
+			}
+			if got := spanTexts(result); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("offsets give %q, want %q", got, tt.want)
+			}
+			if warning, ok := result.Metadata["offset_warning"]; ok {
+				t.Errorf("unexpected offset warning: %v", warning)
+			}
+		})
+	}
+}
+
+func TestGPT2Script(t *testing.T) {
+	python := useScriptStubs(t)
+
+	tokenizer := NewGPT2Tokenizer("gpt2")
+	err := tokenizer.Initialize(TokenizerConfig{
+		Name:       "gpt2",
+		Type:       "bpe",
+		Parameters: map[string]string{"python_path": python},
+	})
+	if err != nil {
+		t.Fatalf("Initialize returned error: %v", err)
+	}
+
+	result, err := tokenizer.Tokenize(context.Background(), "hé")
+	if err != nil {
+		t.Fatalf("Tokenize returned error: %v", err)
+	}
+	if len(result.Tokens) != 3 {
+		t.Fatalf("got %d tokens, want one per byte", len(result.Tokens))
+	}
+	if warning, ok := result.Metadata["offset_warning"]; ok {
+		t.Errorf("unexpected offset warning: %v", warning)
+	}
+
+	vocabSize, err := tokenizer.GetVocabSize()
+	if err != nil || vocabSize != 256 {
+		t.Errorf("GetVocabSize = %d, %v, want 256", vocabSize, err)
+	}
+}
+
+func TestScriptEnvOverridesInheritedValues(t *testing.T) {
+	t.Setenv("TED_MODEL_PATH", "/inherited")
+
+	env := scriptEnv("", "gpt2", "")
+	cmd := exec.Command("true")
+	cmd.Env = env
+
+	// exec keeps the last value for duplicate keys
+	last := ""
+	for _, entry := range cmd.Environ() {
+		if strings.HasPrefix(entry, "TED_MODEL_PATH=") {
+			last = entry
+		}
+	}
+	if last != "TED_MODEL_PATH=" {
+		t.Errorf("TED_MODEL_PATH = %q, want it cleared", last)
+	}
+}
@@ -2,20 +2,22 @@ package tokenizers
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
 // SentencePieceTokenizer implements the Tokenizer interface for SentencePiece models
 type SentencePieceTokenizer struct {
 	*BaseTokenizer
-	modelPath  string
-	pythonPath string
-	modelType  string
+	modelPath     string // as configured: a file, a directory or a HuggingFace repository ID
+	modelFile     string // the resolved .model file, set by Initialize
+	pythonPath    string
+	venvPath      string
+	modelType     string
+	maxBatchBytes int
 }
 
 // NewSentencePieceTokenizer creates a new SentencePiece tokenizer
@@ -24,6 +26,7 @@ func NewSentencePieceTokenizer(name string) *SentencePieceTokenizer {
 		BaseTokenizer: NewBaseTokenizer(name),
 		pythonPath:    "python3",
 		modelType:     "unigram",
+		maxBatchBytes: DefaultMaxBatchBytes,
 	}
 }
 
@@ -38,16 +41,20 @@ func (s *SentencePieceTokenizer) Initialize(config TokenizerConfig) error {
 		s.modelPath = modelPath
 	}
 
-	// Set Python path from config
-	if pythonPath, ok := config.Parameters["python_path"]; ok {
-		s.pythonPath = pythonPath
-	}
+	// Set Python interpreter and virtualenv from config
+	s.pythonPath, s.venvPath = pythonParameters(config.Parameters, s.pythonPath, s.venvPath)
 
 	// Set model type from config
 	if modelType, ok := config.Parameters["model_type"]; ok {
 		s.modelType = modelType
 	}
 
+	maxBatchBytes, err := parseMaxBatchBytes(config.Parameters, s.maxBatchBytes)
+	if err != nil {
+		return err
+	}
+	s.maxBatchBytes = maxBatchBytes
+
 	// Validate model type
 	validTypes := map[string]bool{
 		"unigram": true,
@@ -60,114 +67,66 @@ func (s *SentencePieceTokenizer) Initialize(config TokenizerConfig) error {
 		return fmt.Errorf("invalid sentencepiece model type: %s", s.modelType)
 	}
 
-	return nil
+	modelFile, err := resolveSentencePieceModel(s.modelPath)
+	if err != nil {
+		return err
+	}
+	s.modelFile = modelFile
+
+	return probePython(s.pythonPath, s.venvPath, "sentencepiece")
 }
 
 // Tokenize tokenizes a single document using SentencePiece
 func (s *SentencePieceTokenizer) Tokenize(ctx context.Context, text string) (*TokenizationResult, error) {
-	// Create Python script for tokenization
-	script := fmt.Sprintf(`
-import sentencepiece as spm
-import json
-import sys
-
-try:
-    # Read text from stdin
-    text = sys.stdin.read()
-    
-    # Initialize tokenizer
-    sp = spm.SentencePieceProcessor()
-    sp.load("%s")
-    
-    # Tokenize text
-    pieces = sp.encode_as_pieces(text)
-    ids = sp.encode_as_ids(text)
-    
-    # Get token positions (approximate)
-    token_objects = []
-    current_pos = 0
-    
-    for i, (piece, token_id) in enumerate(zip(pieces, ids)):
-        # Estimate position based on piece length
-        start_pos = current_pos
-        end_pos = start_pos + len(piece)
-        current_pos = end_pos
-        
-        token_objects.append({
-            "id": token_id,
-            "text": piece,
-            "start_pos": start_pos,
-            "end_pos": end_pos
-        })
-    
-    # Create result
-    result = {
-        "document": text,
-        "tokens": token_objects,
-        "tokenizer": "%s",
-        "metadata": {
-            "model_path": "%s",
-            "model_type": "%s",
-            "vocab_size": sp.get_piece_size()
-        }
-    }
-    
-    print(json.dumps(result))
-    
-except Exception as e:
-    print(json.dumps({"error": str(e)}), file=sys.stderr)
-    sys.exit(1)
-`, s.modelPath, s.Name(), s.modelPath, s.modelType)
-
-	// Execute Python script with virtual environment
-	cmd := exec.CommandContext(ctx, s.pythonPath, "-c", script)
-	cmd.Stdin = strings.NewReader(text)
-
-	// Set virtual environment variables
-	cmd.Env = append(os.Environ(),
-		"VIRTUAL_ENV="+filepath.Join(".", "venv"),
-		"PATH="+filepath.Join(".", "venv", "bin")+":"+os.Getenv("PATH"),
-	)
-
-	output, err := cmd.Output()
+	results, err := s.TokenizeBatch(ctx, []string{text})
 	if err != nil {
-		// Try to get error output
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return nil, fmt.Errorf("sentencepiece error: %s", string(exitErr.Stderr))
-		}
-		return nil, fmt.Errorf("failed to execute sentencepiece: %w", err)
+		return nil, err
 	}
+	return results[0], nil
+}
 
-	// Parse JSON output
-	var result struct {
-		Document string `json:"document"`
-		Tokens   []struct {
-			ID       int    `json:"id"`
-			Text     string `json:"text"`
-			StartPos int    `json:"start_pos"`
-			EndPos   int    `json:"end_pos"`
-		} `json:"tokens"`
-		Tokenizer string                 `json:"tokenizer"`
-		Metadata  map[string]interface{} `json:"metadata"`
-		Error     string                 `json:"error,omitempty"`
+// TokenizeBatch tokenizes multiple documents, running one Python process per batch
+// of at most maxBatchBytes of text
+func (s *SentencePieceTokenizer) TokenizeBatch(ctx context.Context, texts []string) ([]*TokenizationResult, error) {
+	modelFile, err := s.model()
+	if err != nil {
+		return nil, err
 	}
 
-	if err := json.Unmarshal(output, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse sentencepiece output: %w", err)
+	results := make([]*TokenizationResult, len(texts))
+	env := scriptEnv(s.venvPath, "", modelFile)
+
+	for _, batch := range splitBatches(texts, s.maxBatchBytes) {
+		batchTexts := texts[batch.start:batch.end]
+		err := runPythonBatch(ctx, s.pythonPath, sentencePieceBatchScript, env, batchTexts, func(i int, result *pythonTokenization) error {
+			results[batch.start+i] = s.convertResult(batchTexts[i], result)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("sentencepiece error for documents %d-%d: %w", batch.start, batch.end-1, err)
+		}
 	}
 
-	if result.Error != "" {
-		return nil, fmt.Errorf("sentencepiece error: %s", result.Error)
+	return results, nil
+}
+
+// convertResult converts a script result line to our token format. The script reports
+// no positions, so byte offsets are found by matching the pieces against the document
+// and checked against it.
+func (s *SentencePieceTokenizer) convertResult(text string, result *pythonTokenization) *TokenizationResult {
+	pieces := make([]string, len(result.Tokens))
+	for i, t := range result.Tokens {
+		pieces[i] = t.Text
 	}
+	offsets := offsetsFromByteLengths(text, sentencePieceLengths(text, pieces))
 
-	// Convert to our token format
 	tokens := make([]Token, len(result.Tokens))
 	for i, t := range result.Tokens {
 		tokens[i] = Token{
 			Text:     t.Text,
 			ID:       t.ID,
-			StartPos: t.StartPos,
-			EndPos:   t.EndPos,
+			StartPos: offsets[i][0],
+			EndPos:   offsets[i][1],
 			Metadata: map[string]string{
 				"tokenizer":  "sentencepiece",
 				"model_path": s.modelPath,
@@ -176,71 +135,104 @@ except Exception as e:
 		}
 	}
 
+	metadata := map[string]interface{}{
+		"model_path": s.modelPath,
+		"model_type": s.modelType,
+		"vocab_size": result.VocabSize,
+	}
+	if warning := validateTokenOffsets(text, tokens); warning != "" {
+		metadata["offset_warning"] = warning
+	}
+
 	return &TokenizationResult{
-		Document:  result.Document,
+		Document:  text,
 		Tokens:    tokens,
-		Tokenizer: result.Tokenizer,
-		Metadata:  result.Metadata,
-	}, nil
-}
-
-// TokenizeBatch tokenizes multiple documents
-func (s *SentencePieceTokenizer) TokenizeBatch(ctx context.Context, texts []string) ([]*TokenizationResult, error) {
-	results := make([]*TokenizationResult, len(texts))
-
-	for i, text := range texts {
-		result, err := s.Tokenize(ctx, text)
-		if err != nil {
-			return nil, fmt.Errorf("error tokenizing document %d: %w", i, err)
-		}
-		results[i] = result
+		Tokenizer: s.Name(),
+		Metadata:  metadata,
 	}
-
-	return results, nil
 }
 
 // GetVocabSize returns the vocabulary size
 func (s *SentencePieceTokenizer) GetVocabSize() (int, error) {
-	// Create Python script to get vocab size
-	script := fmt.Sprintf(`
-import sentencepiece as spm
-import json
-
-try:
-    sp = spm.SentencePieceProcessor()
-    sp.load("%s")
-    print(json.dumps({"vocab_size": sp.get_piece_size()}))
-except Exception as e:
-    print(json.dumps({"error": str(e)}))
-`, s.modelPath)
-
-	cmd := exec.Command(s.pythonPath, "-c", script)
-
-	// Set virtual environment variables
-	cmd.Env = append(os.Environ(),
-		"VIRTUAL_ENV="+filepath.Join(".", "venv"),
-		"PATH="+filepath.Join(".", "venv", "bin")+":"+os.Getenv("PATH"),
-	)
-
-	output, err := cmd.Output()
+	modelFile, err := s.model()
+	if err != nil {
+		return 0, err
+	}
+
+	vocabSize := 0
+	err = runPythonBatch(context.Background(), s.pythonPath, sentencePieceBatchScript, scriptEnv(s.venvPath, "", modelFile), []string{""}, func(_ int, result *pythonTokenization) error {
+		vocabSize = result.VocabSize
+		return nil
+	})
 	if err != nil {
 		return 0, fmt.Errorf("failed to get vocab size: %w", err)
 	}
+	return vocabSize, nil
+}
 
-	var result struct {
-		VocabSize int    `json:"vocab_size"`
-		Error     string `json:"error,omitempty"`
+// model returns the .model file to load. A tokenizer that was registered without
+// being initialized resolves its model path on each call.
+func (s *SentencePieceTokenizer) model() (string, error) {
+	if s.modelFile != "" {
+		return s.modelFile, nil
 	}
+	return resolveSentencePieceModel(s.modelPath)
+}
 
-	if err := json.Unmarshal(output, &result); err != nil {
-		return 0, fmt.Errorf("failed to parse vocab size output: %w", err)
+// sentencePieceModelFiles are the names HuggingFace repositories use for their
+// SentencePiece model file
+var sentencePieceModelFiles = []string{"spiece.model", "tokenizer.model", "sentencepiece.bpe.model"}
+
+// resolveSentencePieceModel finds the .model file for modelPath, which may be the
+// file itself, a directory containing one, or the ID of a HuggingFace repository
+// already downloaded to the local HuggingFace cache. Nothing is downloaded.
+func resolveSentencePieceModel(modelPath string) (string, error) {
+	if modelPath == "" {
+		return "", fmt.Errorf("model_path parameter is required for sentencepiece tokenizers")
 	}
 
-	if result.Error != "" {
-		return 0, fmt.Errorf("error getting vocab size: %s", result.Error)
+	info, err := os.Stat(modelPath)
+	if err == nil && !info.IsDir() {
+		return modelPath, nil
+	}
+	if err == nil {
+		for _, name := range sentencePieceModelFiles {
+			candidate := filepath.Join(modelPath, name)
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate, nil
+			}
+		}
+		return "", fmt.Errorf("no sentencepiece model (%s) found in directory %s",
+			strings.Join(sentencePieceModelFiles, ", "), modelPath)
 	}
 
-	return result.VocabSize, nil
+	if cache := huggingFaceCacheDir(); cache != "" {
+		repo := filepath.Join(cache, "models--"+strings.ReplaceAll(modelPath, "/", "--"), "snapshots")
+		for _, name := range sentencePieceModelFiles {
+			matches, _ := filepath.Glob(filepath.Join(repo, "*", name))
+			if len(matches) > 0 {
+				sort.Strings(matches)
+				return matches[len(matches)-1], nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("sentencepiece model %s not found: set model_path to a local .model file "+
+		"or a directory containing one, or download the %s repository into the HuggingFace cache", modelPath, modelPath)
+}
+
+// huggingFaceCacheDir returns the HuggingFace hub cache directory
+func huggingFaceCacheDir() string {
+	if dir := os.Getenv("HF_HUB_CACHE"); dir != "" {
+		return dir
+	}
+	if home := os.Getenv("HF_HOME"); home != "" {
+		return filepath.Join(home, "hub")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".cache", "huggingface", "hub")
+	}
+	return ""
 }
 
 // Close cleans up resources
@@ -251,24 +243,31 @@ func (s *SentencePieceTokenizer) Close() error {
 
 // RegisterT5Tokenizer registers the T5 tokenizer
 func RegisterT5Tokenizer() error {
-	t5Tokenizer := NewSentencePieceTokenizer("t5-base")
-	t5Tokenizer.modelPath = "t5-base"
-	t5Tokenizer.modelType = "unigram"
-	return RegisterGlobal("t5-base", t5Tokenizer)
+	return RegisterGlobal("t5-base", newBuiltinTokenizer("t5-base"))
 }
 
 // RegisterMT5Tokenizer registers the mT5 tokenizer
 func RegisterMT5Tokenizer() error {
-	mt5Tokenizer := NewSentencePieceTokenizer("mt5-base")
-	mt5Tokenizer.modelPath = "mt5-base"
-	mt5Tokenizer.modelType = "unigram"
-	return RegisterGlobal("mt5-base", mt5Tokenizer)
+	return RegisterGlobal("mt5-base", newBuiltinTokenizer("mt5-base"))
 }
 
 // RegisterALBERTTokenizer registers the ALBERT tokenizer
 func RegisterALBERTTokenizer() error {
-	albertTokenizer := NewSentencePieceTokenizer("albert-base-v2")
-	albertTokenizer.modelPath = "albert-base-v2"
-	albertTokenizer.modelType = "wordpiece"
-	return RegisterGlobal("albert-base", albertTokenizer)
+	return RegisterGlobal("albert-base", newBuiltinTokenizer("albert-base"))
+}
+
+// RegisterSentencePieceModel initializes a tokenizer for a local SentencePiece model,
+// such as a Llama or Mistral tokenizer.model, and registers it with the global
+// registry under name. modelType may be empty for the default, unigram.
+func RegisterSentencePieceModel(name, path, modelType string) error {
+	parameters := map[string]string{"model_path": path}
+	if modelType != "" {
+		parameters["model_type"] = modelType
+	}
+
+	tokenizer := NewSentencePieceTokenizer(name)
+	if err := tokenizer.Initialize(TokenizerConfig{Name: name, Type: "spiece", Parameters: parameters}); err != nil {
+		return fmt.Errorf("failed to initialize sentencepiece model %s: %w", name, err)
+	}
+	return RegisterGlobal(name, tokenizer)
 }
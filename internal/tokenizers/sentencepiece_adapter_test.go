@@ -0,0 +1,109 @@
+package tokenizers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeModel creates an empty stand-in model file at path
+func writeModel(t *testing.T, path string) string {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create model directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("model"), 0644); err != nil {
+		t.Fatalf("failed to write model: %v", err)
+	}
+	return path
+}
+
+func TestResolveSentencePieceModel(t *testing.T) {
+	dir := t.TempDir()
+	cache := filepath.Join(dir, "hf-cache")
+	t.Setenv("HF_HUB_CACHE", cache)
+
+	file := writeModel(t, filepath.Join(dir, "llama2", "tokenizer.model"))
+	inDir := writeModel(t, filepath.Join(dir, "t5", "spiece.model"))
+	cached := writeModel(t, filepath.Join(cache, "models--google--mt5-base", "snapshots", "abc123", "spiece.model"))
+	if err := os.MkdirAll(filepath.Join(dir, "empty"), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		modelPath string
+		want      string
+		wantErr   string
+	}{
+		{"file", file, file, ""},
+		{"directory", filepath.Join(dir, "t5"), inDir, ""},
+		{"cached repository", "google/mt5-base", cached, ""},
+		{"empty directory", filepath.Join(dir, "empty"), "", "no sentencepiece model"},
+		{"missing repository", "t5-base", "", "HuggingFace cache"},
+		{"not set", "", "", "model_path parameter is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveSentencePieceModel(tt.modelPath)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected an error mentioning %q, got %q, %v", tt.wantErr, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("resolveSentencePieceModel(%q) = %q, %v, want %q", tt.modelPath, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuiltinSentencePieceModelNotDownloaded(t *testing.T) {
+	t.Setenv("HF_HUB_CACHE", t.TempDir())
+
+	for _, name := range []string{"t5-base", "mt5-base", "albert-base"} {
+		t.Run(name, func(t *testing.T) {
+			tokenizer := newBuiltinTokenizer(name)
+			_, err := tokenizer.Tokenize(context.Background(), "hello")
+			if err == nil || !strings.Contains(err.Error(), "model_path") {
+				t.Errorf("expected a model_path error before running python, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRegisterSentencePieceModel(t *testing.T) {
+	// The default python3 imports the sentencepiece stub
+	useScriptStubs(t)
+	modelPath := writeModel(t, filepath.Join(t.TempDir(), "tokenizer.model"))
+
+	name := "llama2-test"
+	if err := RegisterSentencePieceModel(name, modelPath, "bpe"); err != nil {
+		t.Fatalf("RegisterSentencePieceModel returned error: %v", err)
+	}
+	defer GlobalRegistry.Unregister(name)
+
+	tokenizer, err := GetGlobal(name)
+	if err != nil {
+		t.Fatalf("tokenizer was not registered: %v", err)
+	}
+	result, err := tokenizer.Tokenize(context.Background(), "hello world")
+	if err != nil {
+		t.Fatalf("Tokenize returned error: %v", err)
+	}
+	if got, want := tokenTexts(result), []string{"▁hello", "▁world"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tokens = %q, want %q", got, want)
+	}
+	if result.Metadata["model_type"] != "bpe" {
+		t.Errorf("model_type = %v, want bpe", result.Metadata["model_type"])
+	}
+
+	if err := RegisterSentencePieceModel("missing-model", filepath.Join(t.TempDir(), "none.model"), ""); err == nil {
+		t.Error("expected an error for a missing model file")
+	}
+}
@@ -0,0 +1,20 @@
+# Stand-in for sentencepiece in adapter script tests: loads any existing file and
+# splits text on whitespace.
+
+
+class SentencePieceProcessor:
+    def load(self, path):
+        with open(path):
+            pass
+
+    def encode_as_pieces(self, text):
+        return ["▁" + word for word in text.split()]
+
+    def encode_as_ids(self, text):
+        return list(range(len(text.split())))
+
+    def get_piece_size(self):
+        return 100
+
+    def id_to_piece(self, piece_id):
+        return "▁piece%d" % piece_id
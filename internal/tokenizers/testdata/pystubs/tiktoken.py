@@ -0,0 +1,20 @@
+# Stand-in for tiktoken in adapter script tests: one token per UTF-8 byte.
+
+
+class _Encoding:
+    n_vocab = 256
+
+    def encode(self, text):
+        return list(text.encode("utf-8"))
+
+    def decode(self, ids):
+        return bytes(ids).decode("utf-8", errors="replace")
+
+    def decode_single_token_bytes(self, token_id):
+        return bytes([token_id])
+
+
+def encoding_for_model(name):
+    if name not in ("gpt2", "gpt-3.5-turbo", "gpt-4", "gpt-4o"):
+        raise KeyError(name)
+    return _Encoding()
@@ -0,0 +1,270 @@
+package tokenizers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupported is returned, wrapped, by tokenizers that cannot list their vocabulary
+var ErrUnsupported = errors.New("vocabulary not available")
+
+// DefaultMaxVocabEntries bounds the vocabularies GetVocab loads; override with the
+// max_vocab_entries parameter
+const DefaultMaxVocabEntries = 1 << 20
+
+// VocabProvider is implemented by tokenizers that can list their vocabulary
+type VocabProvider interface {
+	// GetVocab returns the vocabulary as token text to ID. Tokens are written as the
+	// tokenizer writes them, such as "Ġthe", "▁the" or "##ing".
+	GetVocab(ctx context.Context) (map[string]int, error)
+}
+
+// GetVocab reports the vocabulary as unavailable; adapters that can list theirs
+// override it
+func (b *BaseTokenizer) GetVocab(ctx context.Context) (map[string]int, error) {
+	return nil, fmt.Errorf("tokenizer %s: %w", b.name, ErrUnsupported)
+}
+
+// Vocabulary returns the vocabulary of tokenizer, or an error wrapping ErrUnsupported
+// if it cannot list it
+func Vocabulary(ctx context.Context, tokenizer Tokenizer) (map[string]int, error) {
+	if provider, ok := tokenizer.(VocabProvider); ok {
+		return provider.GetVocab(ctx)
+	}
+	return nil, fmt.Errorf("tokenizer %s: %w", tokenizer.Name(), ErrUnsupported)
+}
+
+// maxVocabEntries reads the max_vocab_entries parameter
+func maxVocabEntries(parameters map[string]string) (int, error) {
+	value, ok := parameters["max_vocab_entries"]
+	if !ok {
+		return DefaultMaxVocabEntries, nil
+	}
+
+	entries, err := strconv.Atoi(value)
+	if err != nil || entries <= 0 {
+		return 0, fmt.Errorf("invalid max_vocab_entries parameter: %s", value)
+	}
+	return entries, nil
+}
+
+// vocabCacheDir returns the directory vocabularies are kept in: the vocab_cache_dir
+// parameter, or the user cache directory. Empty means not to keep them.
+func vocabCacheDir(parameters map[string]string) string {
+	if dir, ok := parameters["vocab_cache_dir"]; ok {
+		return dir
+	}
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "tokentropydrift", "vocab")
+	}
+	return ""
+}
+
+// loadVocab returns the vocabulary of the tokenizer b configures, from the on-disk
+// cache when it has been loaded before and otherwise from load, which is given the
+// entry limit. Vocabularies are kept under the tokenizer's name and configuration
+// fingerprint, plus version, which identifies what the configuration does not, such
+// as the contents of a model file. Failing to write the cache is not an error.
+func loadVocab(ctx context.Context, b *BaseTokenizer, version string, load func(ctx context.Context, maxEntries int) (map[string]int, error)) (map[string]int, error) {
+	maxEntries, err := maxVocabEntries(b.config.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	var path string
+	if dir := vocabCacheDir(b.config.Parameters); dir != "" {
+		name := strings.Map(func(r rune) rune {
+			if r == '/' || r == '\\' || r == os.PathSeparator {
+				return '_'
+			}
+			return r
+		}, b.Name())
+		key := b.ConfigFingerprint()
+		if version != "" {
+			sum := sha256.Sum256([]byte(key + "\x00" + version))
+			key = hex.EncodeToString(sum[:8])
+		}
+		path = filepath.Join(dir, name+"-"+key+".json")
+
+		if data, err := os.ReadFile(path); err == nil {
+			var vocab map[string]int
+			if json.Unmarshal(data, &vocab) == nil && len(vocab) <= maxEntries {
+				return vocab, nil
+			}
+		}
+	}
+
+	vocab, err := load(ctx, maxEntries)
+	if err != nil {
+		return nil, err
+	}
+	if len(vocab) > maxEntries {
+		return nil, fmt.Errorf("vocabulary of %s has %d entries, more than max_vocab_entries (%d)", b.Name(), len(vocab), maxEntries)
+	}
+
+	if path != "" {
+		writeVocabCache(path, vocab)
+	}
+	return vocab, nil
+}
+
+// writeVocabCache writes vocab to path through a temporary file, so concurrent
+// readers never see a partial one
+func writeVocabCache(path string, vocab map[string]int) {
+	data, err := json.Marshal(vocab)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), ".vocab-*")
+	if err != nil {
+		return
+	}
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil || os.Rename(temp.Name(), path) != nil {
+		os.Remove(temp.Name())
+	}
+}
+
+// vocabRequest asks an adapter script for its vocabulary instead of tokenizing
+type vocabRequest struct {
+	Vocab struct {
+		MaxEntries int `json:"max_entries"`
+	} `json:"vocab"`
+}
+
+// vocabResponse is the line an adapter script answers a vocabRequest with
+type vocabResponse struct {
+	Vocab map[string]int `json:"vocab"`
+	Error string         `json:"error,omitempty"`
+}
+
+// newVocabRequest asks for a vocabulary of at most maxEntries entries
+func newVocabRequest(maxEntries int) vocabRequest {
+	var req vocabRequest
+	req.Vocab.MaxEntries = maxEntries
+	return req
+}
+
+// runPythonVocab runs a batch script with a vocabRequest and returns the vocabulary
+// it writes
+func runPythonVocab(ctx context.Context, pythonPath, script string, env []string, maxEntries int) (map[string]int, error) {
+	payload, err := json.Marshal(newVocabRequest(maxEntries))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode vocabulary request: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, pythonPath, "-c", script)
+	cmd.Env = env
+	cmd.Stdin = bytes.NewReader(payload)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("python process failed: %w%s", err, formatStderr(stderr.String()))
+	}
+
+	var response vocabResponse
+	if err := json.Unmarshal(output, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse vocabulary: %w", err)
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("failed to get vocabulary: %s", response.Error)
+	}
+	return response.Vocab, nil
+}
+
+// GetVocab returns the tiktoken encoding's tokens. Tokens that are not valid UTF-8
+// on their own are written as <0xNN> bytes.
+func (g *GPT2Tokenizer) GetVocab(ctx context.Context) (map[string]int, error) {
+	return loadVocab(ctx, g.BaseTokenizer, g.modelName, func(ctx context.Context, maxEntries int) (map[string]int, error) {
+		return runPythonVocab(ctx, g.pythonPath, gpt2BatchScript, scriptEnv(g.venvPath, g.modelName, ""), maxEntries)
+	})
+}
+
+// GetVocab returns the model's pieces. Vocabularies are kept on disk by the model
+// file's size and modification time as well, so a replaced model is loaded again.
+func (s *SentencePieceTokenizer) GetVocab(ctx context.Context) (map[string]int, error) {
+	modelFile, err := s.model()
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(modelFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model: %w", err)
+	}
+	version := fmt.Sprintf("%s:%d:%d", modelFile, info.Size(), info.ModTime().UnixNano())
+
+	return loadVocab(ctx, s.BaseTokenizer, version, func(ctx context.Context, maxEntries int) (map[string]int, error) {
+		return runPythonVocab(ctx, s.pythonPath, sentencePieceBatchScript, scriptEnv(s.venvPath, "", modelFile), maxEntries)
+	})
+}
+
+// GetVocab returns the tokenizer's vocabulary, including added tokens, from the worker
+func (h *HuggingFaceTokenizer) GetVocab(ctx context.Context) (map[string]int, error) {
+	return loadVocab(ctx, h.BaseTokenizer, "", func(ctx context.Context, maxEntries int) (map[string]int, error) {
+		var response vocabResponse
+		if err := h.worker().Call(ctx, newVocabRequest(maxEntries), &response); err != nil {
+			return nil, fmt.Errorf("failed to get vocabulary: %w", err)
+		}
+		return response.Vocab, nil
+	})
+}
+
+// GetVocab returns a copy of the loaded vocabulary, in the byte-level form of
+// vocab.json. It is already in memory, so it is not kept on disk.
+func (b *LocalBPETokenizer) GetVocab(ctx context.Context) (map[string]int, error) {
+	if b.vocab == nil {
+		return nil, fmt.Errorf("tokenizer %s is not initialized", b.Name())
+	}
+	maxEntries, err := maxVocabEntries(b.config.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	if len(b.vocab) > maxEntries {
+		return nil, fmt.Errorf("vocabulary of %s has %d entries, more than max_vocab_entries (%d)", b.Name(), len(b.vocab), maxEntries)
+	}
+
+	vocab := make(map[string]int, len(b.vocab))
+	for token, id := range b.vocab {
+		vocab[token] = id
+	}
+	return vocab, nil
+}
+
+// GetVocab returns the vocabulary of local tiktoken; a token counting endpoint does
+// not list one
+func (o *OpenAITokenizer) GetVocab(ctx context.Context) (map[string]int, error) {
+	if o.endpoint != "" {
+		return o.BaseTokenizer.GetVocab(ctx)
+	}
+	local, err := o.localTokenizer()
+	if err != nil {
+		return nil, err
+	}
+	return local.GetVocab(ctx)
+}
+
+// GetVocab returns the vocabulary of the underlying tokenizer
+func (c *CachedTokenizer) GetVocab(ctx context.Context) (map[string]int, error) {
+	return Vocabulary(ctx, c.tokenizer)
+}
@@ -0,0 +1,147 @@
+package tokenizers
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newScriptTokenizer initializes an adapter backed by a stub Python script, keeping
+// its vocabularies in cacheDir
+func newScriptTokenizer(t *testing.T, kind, cacheDir string, parameters map[string]string) Tokenizer {
+	t.Helper()
+	python := useScriptStubs(t)
+	config := TokenizerConfig{Name: kind, Type: "bpe", Parameters: map[string]string{
+		"python_path":     python,
+		"vocab_cache_dir": cacheDir,
+	}}
+	for key, value := range parameters {
+		config.Parameters[key] = value
+	}
+
+	var tokenizer Tokenizer
+	switch kind {
+	case "gpt2":
+		tokenizer = NewGPT2Tokenizer(kind)
+	case "spm":
+		modelPath := filepath.Join(t.TempDir(), "spm.model")
+		if err := os.WriteFile(modelPath, []byte("model"), 0644); err != nil {
+			t.Fatalf("failed to write model: %v", err)
+		}
+		config.Parameters["model_path"] = modelPath
+		tokenizer = NewSentencePieceTokenizer(kind)
+	case "hf":
+		config.Parameters["model_path"] = t.TempDir()
+		tokenizer = NewHuggingFaceTokenizer(kind)
+	}
+	if err := tokenizer.Initialize(config); err != nil {
+		t.Fatalf("Initialize returned error: %v", err)
+	}
+	t.Cleanup(func() { tokenizer.Close() })
+	return tokenizer
+}
+
+func TestScriptVocabularies(t *testing.T) {
+	tests := []struct {
+		kind string
+		size int
+		want map[string]int
+	}{
+		// Bytes that are not UTF-8 on their own are written like SentencePiece's
+		{"gpt2", 256, map[string]int{"a": 'a', " ": ' ', "<0x80>": 0x80}},
+		{"spm", 100, map[string]int{"▁piece3": 3}},
+		{"hf", 101, map[string]int{"token7": 7, "[ADDED]": 100}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			tokenizer := newScriptTokenizer(t, tt.kind, t.TempDir(), nil)
+			vocab, err := Vocabulary(context.Background(), tokenizer)
+			if err != nil {
+				t.Fatalf("Vocabulary returned error: %v", err)
+			}
+			if len(vocab) != tt.size {
+				t.Errorf("got %d entries, want %d", len(vocab), tt.size)
+			}
+			for token, id := range tt.want {
+				if got, ok := vocab[token]; !ok || got != id {
+					t.Errorf("vocab[%q] = %d, %v, want %d", token, got, ok, id)
+				}
+			}
+
+			limited := newScriptTokenizer(t, tt.kind, t.TempDir(), map[string]string{"max_vocab_entries": "10"})
+			if _, err := Vocabulary(context.Background(), limited); err == nil || !strings.Contains(err.Error(), "more than the limit of 10") {
+				t.Errorf("expected the entry limit to be enforced, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVocabularyKeptOnDisk(t *testing.T) {
+	dir := t.TempDir()
+	tokenizer := newScriptTokenizer(t, "gpt2", dir, nil)
+	if _, err := Vocabulary(context.Background(), tokenizer); err != nil {
+		t.Fatalf("Vocabulary returned error: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "gpt2-*.json"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected one cached vocabulary, found %v", files)
+	}
+	if err := os.WriteFile(files[0], []byte(`{"cached": 1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	vocab, err := Vocabulary(context.Background(), tokenizer)
+	if err != nil {
+		t.Fatalf("Vocabulary returned error: %v", err)
+	}
+	if len(vocab) != 1 || vocab["cached"] != 1 {
+		t.Errorf("vocabulary was not read from disk: %d entries", len(vocab))
+	}
+
+	// A differently configured tokenizer is not served another's vocabulary
+	other := newScriptTokenizer(t, "gpt2", dir, map[string]string{"model": "gpt-4"})
+	if vocab, err := Vocabulary(context.Background(), other); err != nil || len(vocab) != 256 {
+		t.Errorf("got %d entries, %v, want the loaded vocabulary", len(vocab), err)
+	}
+}
+
+func TestLocalBPEVocabulary(t *testing.T) {
+	tokenizer := newFixtureBPE(t)
+	vocab, err := Vocabulary(context.Background(), tokenizer)
+	if err != nil {
+		t.Fatalf("Vocabulary returned error: %v", err)
+	}
+	if len(vocab) != len(tokenizer.vocab) || vocab["he"] != 257 {
+		t.Errorf("got %d entries with he = %d, want %d with he = 257", len(vocab), vocab["he"], len(tokenizer.vocab))
+	}
+
+	// The tokenizer keeps its own map
+	vocab["he"] = 0
+	if tokenizer.vocab["he"] != 257 {
+		t.Error("changing the returned vocabulary changed the tokenizer's")
+	}
+}
+
+func TestVocabularyUnsupported(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokenizer Tokenizer
+	}{
+		{"mock", NewMockTokenizer("mock")},
+		{"cached mock", NewCachedTokenizerWithCache(NewMockTokenizer("mock"), nil)},
+		{"command", NewCommandTokenizer("command")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Vocabulary(context.Background(), tt.tokenizer); !errors.Is(err, ErrUnsupported) {
+				t.Errorf("Vocabulary error = %v, want ErrUnsupported", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,6 @@
+// Package version holds the version of the TokEntropyDrift build.
+package version
+
+// Version is the version of this build. Release builds set it with
+// -ldflags "-X github.com/RevBooyah/TokEntropyDrift/internal/version.Version=v1.2.3".
+var Version = "dev"
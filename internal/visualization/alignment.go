@@ -0,0 +1,266 @@
+package visualization
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// AlignmentData holds how the tokens of two tokenizations map onto each other over a
+// span of the text
+type AlignmentData struct {
+	ComparisonID  string                         `json:"comparison_id"` // names the output file
+	Tokenization1 *tokenizers.TokenizationResult `json:"tokenization1"`
+	Tokenization2 *tokenizers.TokenizationResult `json:"tokenization2"`
+	Alignment     *metrics.TokenAlignment        `json:"alignment"`
+}
+
+// NewAlignmentData aligns two tokenizations over the byte span [start, end) of the
+// text, where an end of zero or less means the end of the text. Keeping the span short
+// keeps the diagram readable.
+func NewAlignmentData(calc *metrics.DriftCalculator, result1, result2 *tokenizers.TokenizationResult, start, end int) (AlignmentData, error) {
+	alignment, err := calc.AlignTokens(result1, result2, start, end)
+	if err != nil {
+		return AlignmentData{}, fmt.Errorf("error aligning tokens: %w", err)
+	}
+	return AlignmentData{
+		ComparisonID:  fmt.Sprintf("%s_vs_%s", result1.Tokenizer, result2.Tokenizer),
+		Tokenization1: result1,
+		Tokenization2: result2,
+		Alignment:     alignment,
+	}, nil
+}
+
+// GenerateAlignmentDiagram generates a Sankey diagram linking each token of the first
+// tokenization to the tokens of the second that overlap it, with tokens in text order
+// on either side and links colored by whether tokens map one-to-one, are split, merged
+// or cross boundaries. The result's Data holds the raw alignment pairs.
+func (v *VisualizationEngine) GenerateAlignmentDiagram(data AlignmentData) (*VisualizationResult, error) {
+	if data.Alignment == nil || data.Tokenization1 == nil || data.Tokenization2 == nil {
+		return nil, fmt.Errorf("alignment diagram needs both tokenizations and their alignment")
+	}
+	alignment := data.Alignment
+	kinds := alignmentPairKinds(alignment)
+
+	// Nodes of the first tokenization come first, then those of the second
+	node1 := make(map[int]int, len(alignment.Tokens1))
+	node2 := make(map[int]int, len(alignment.Tokens2))
+	var labels, nodeColors, nodeHover []string
+	var nodeX, nodeY []float64
+	addNodes := func(result *tokenizers.TokenizationResult, indexes []int, nodes map[int]int, x float64) {
+		for k, i := range indexes {
+			token := result.Tokens[i]
+			nodes[i] = len(labels)
+			labels = append(labels, visibleWhitespace.Replace(strings.ToValidUTF8(token.Text, "�")))
+			nodeColors = append(nodeColors, v.palette().tokenSegmentColor(k))
+			nodeHover = append(nodeHover, fmt.Sprintf("%s token %d (bytes %d-%d)", result.Tokenizer, i, token.StartPos, token.EndPos))
+			nodeX = append(nodeX, x)
+			nodeY = append(nodeY, (float64(k)+0.5)/float64(len(indexes)))
+		}
+	}
+	addNodes(data.Tokenization1, alignment.Tokens1, node1, 0.001)
+	addNodes(data.Tokenization2, alignment.Tokens2, node2, 0.999)
+
+	sources := make([]int, len(alignment.Pairs))
+	targets := make([]int, len(alignment.Pairs))
+	values := make([]int, len(alignment.Pairs))
+	linkColors := make([]string, len(alignment.Pairs))
+	linkHover := make([]string, len(alignment.Pairs))
+	for p, pair := range alignment.Pairs {
+		sources[p], targets[p] = node1[pair.Index1], node2[pair.Index2]
+		values[p] = max(pair.End-pair.Start, 1)
+		linkColors[p] = translucent(v.palette().alignmentKindColor(kinds[p]), 0.5)
+		linkHover[p] = fmt.Sprintf("%s, bytes %d-%d", strings.ReplaceAll(kinds[p], "_", " "), pair.Start, pair.End)
+	}
+
+	plotData := []map[string]interface{}{{
+		"type":        "sankey",
+		"arrangement": "snap",
+		"node": map[string]interface{}{
+			"label":         labels,
+			"color":         nodeColors,
+			"x":             nodeX,
+			"y":             nodeY,
+			"pad":           4,
+			"thickness":     18,
+			"customdata":    nodeHover,
+			"hovertemplate": "<b>%{label}</b><br>%{customdata}<extra></extra>",
+		},
+		"link": map[string]interface{}{
+			"source":        sources,
+			"target":        targets,
+			"value":         values,
+			"color":         linkColors,
+			"customdata":    linkHover,
+			"hovertemplate": "%{source.label} → %{target.label}<br>%{customdata}<extra></extra>",
+		},
+	}}
+
+	title := fmt.Sprintf("Token Alignment: %s vs %s (bytes %d-%d)", alignment.Tokenizer1, alignment.Tokenizer2, alignment.Start, alignment.End)
+	notes := alignmentNotes(alignment)
+
+	layout := map[string]interface{}{
+		"title": map[string]interface{}{
+			"text": title,
+			"x":    0.5,
+		},
+		"height":   v.getHeight(),
+		"width":    v.getWidth(),
+		"template": v.getTemplate(),
+	}
+	if len(notes) > 0 {
+		layout["margin"] = map[string]interface{}{"b": 40 + 20*len(notes)}
+		layout["annotations"] = []map[string]interface{}{{
+			"text":      strings.Join(notes, "<br>"),
+			"xref":      "paper",
+			"yref":      "paper",
+			"x":         0,
+			"y":         -0.08,
+			"xanchor":   "left",
+			"yanchor":   "top",
+			"align":     "left",
+			"showarrow": false,
+			"font":      map[string]interface{}{"color": v.palette().Accent},
+		}}
+	}
+
+	// Generate HTML
+	html := v.generatePlotlyHTML(plotData, layout, "token_alignment")
+
+	// Save to file
+	filename := fmt.Sprintf("token_alignment_%s.%s", data.ComparisonID, v.fileType())
+	filepath := filepath.Join(v.config.OutputDir, filename)
+
+	if err := v.writeVisualization(filepath, html, v.alignmentChart(title, data, kinds, notes), v.getWidth(), v.getHeight()); err != nil {
+		return nil, err
+	}
+
+	groupCounts := make(map[string]int)
+	for _, group := range alignment.Groups {
+		groupCounts[group.Kind]++
+	}
+	metadata := map[string]interface{}{
+		"comparison_id": data.ComparisonID,
+		"span_start":    alignment.Start,
+		"span_end":      alignment.End,
+		"groups":        groupCounts,
+	}
+	if len(alignment.Notes) > 0 {
+		metadata["notes"] = alignment.Notes
+	}
+
+	return v.exportData(&VisualizationResult{
+		Type:     "token_alignment",
+		Filepath: filepath,
+		Data:     alignment.Pairs,
+		Metadata: v.generationMetadata(metadata, []string{alignment.Tokenizer1, alignment.Tokenizer2}, 1),
+	}, data, nil)
+}
+
+// alignmentPairKinds returns the kind of the group each pair belongs to
+func alignmentPairKinds(alignment *metrics.TokenAlignment) []string {
+	groupKinds := make(map[int]string)
+	for _, group := range alignment.Groups {
+		for _, i := range group.Tokens1 {
+			groupKinds[i] = group.Kind
+		}
+	}
+	kinds := make([]string, len(alignment.Pairs))
+	for p, pair := range alignment.Pairs {
+		kinds[p] = groupKinds[pair.Index1]
+	}
+	return kinds
+}
+
+// alignmentNotes returns the lines shown under the diagram: tokens skipped for lack of
+// offsets and tokens without a counterpart
+func alignmentNotes(alignment *metrics.TokenAlignment) []string {
+	notes := append([]string(nil), alignment.Notes...)
+	unaligned := 0
+	for _, group := range alignment.Groups {
+		if group.Kind == metrics.AlignmentUnaligned {
+			unaligned += len(group.Tokens1) + len(group.Tokens2)
+		}
+	}
+	if unaligned > 0 {
+		notes = append(notes, fmt.Sprintf("%d tokens have no counterpart", unaligned))
+	}
+	return notes
+}
+
+// translucent turns a #rrggbb color into a CSS rgba color with the given opacity
+func translucent(hex string, alpha float64) string {
+	c := hexColor(hex)
+	return fmt.Sprintf("rgba(%d,%d,%d,%g)", c.R, c.G, c.B, alpha)
+}
+
+// alignmentChart draws the tokens of each tokenization as a row of equal-width bars in
+// text order, with connectors between overlapping tokens
+func (v *VisualizationEngine) alignmentChart(title string, data AlignmentData, kinds []string, notes []string) staticChart {
+	theme := v.staticTheme()
+	alignment := data.Alignment
+
+	unaligned := make(map[[2]int]bool)
+	for _, group := range alignment.Groups {
+		if group.Kind != metrics.AlignmentUnaligned {
+			continue
+		}
+		for _, i := range group.Tokens1 {
+			unaligned[[2]int{1, i}] = true
+		}
+		for _, j := range group.Tokens2 {
+			unaligned[[2]int{2, j}] = true
+		}
+	}
+
+	return func(c staticCanvas, width, height float64) {
+		const left, right, top, barHeight = 110.0, 30.0, 70.0, 30.0
+		c.text(width/2, 30, title, "middle", 16, theme.foreground)
+
+		plotWidth := width - left - right
+		bottomRow := height - 80 - barHeight - 16*float64(len(notes))
+		rows := []struct {
+			side    int
+			name    string
+			result  *tokenizers.TokenizationResult
+			indexes []int
+			y       float64
+		}{
+			{1, alignment.Tokenizer1, data.Tokenization1, alignment.Tokens1, top},
+			{2, alignment.Tokenizer2, data.Tokenization2, alignment.Tokens2, bottomRow},
+		}
+
+		centers := [3]map[int]float64{nil, make(map[int]float64), make(map[int]float64)}
+		for _, row := range rows {
+			c.text(left-8, row.y+barHeight/2+4, truncateLabel(row.name, 14), "end", 12, theme.foreground)
+			if len(row.indexes) == 0 {
+				continue
+			}
+			segment := plotWidth / float64(len(row.indexes))
+			for k, i := range row.indexes {
+				x := left + float64(k)*segment
+				fill := hexColor(theme.palette.tokenSegmentColor(k))
+				if unaligned[[2]int{row.side, i}] {
+					fill = hexColor(theme.palette.Muted)
+				}
+				c.rect(x+1, row.y, max(segment-2, 1), barHeight, fill)
+				if fit := int(segment / 7); fit >= 2 {
+					label := visibleWhitespace.Replace(strings.ToValidUTF8(row.result.Tokens[i].Text, "�"))
+					c.text(x+segment/2, row.y+barHeight/2+4, truncateLabel(label, fit), "middle", 11, theme.foreground)
+				}
+				centers[row.side][i] = x + segment/2
+			}
+		}
+
+		for p, pair := range alignment.Pairs {
+			c.line(centers[1][pair.Index1], top+barHeight, centers[2][pair.Index2], bottomRow, hexColor(theme.palette.alignmentKindColor(kinds[p])))
+		}
+
+		for n, note := range notes {
+			c.text(left, bottomRow+barHeight+28+16*float64(n), note, "start", 12, hexColor(theme.palette.Accent))
+		}
+	}
+}
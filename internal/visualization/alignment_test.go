@@ -0,0 +1,82 @@
+package visualization
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// spanTokenization tokenizes document at the given byte boundaries
+func spanTokenization(tokenizer, document string, boundaries ...int) *tokenizers.TokenizationResult {
+	result := &tokenizers.TokenizationResult{Document: document, Tokenizer: tokenizer}
+	for i := 1; i < len(boundaries); i++ {
+		start, end := boundaries[i-1], boundaries[i]
+		result.Tokens = append(result.Tokens, tokenizers.Token{Text: document[start:end], ID: i, StartPos: start, EndPos: end})
+	}
+	return result
+}
+
+func TestGenerateAlignmentDiagram(t *testing.T) {
+	document := "unbelievable cat"
+	words := spanTokenization("words", document, 0, 12, 16)
+	pieces := spanTokenization("pieces", document, 0, 2, 9, 12, 16)
+
+	engine := NewVisualizationEngine(VisualizationConfig{OutputDir: t.TempDir()})
+	data, err := NewAlignmentData(metrics.NewDriftCalculator(0.5), words, pieces, 0, 12)
+	if err != nil {
+		t.Fatalf("NewAlignmentData returned error: %v", err)
+	}
+	result, err := engine.GenerateAlignmentDiagram(data)
+	if err != nil {
+		t.Fatalf("GenerateAlignmentDiagram returned error: %v", err)
+	}
+
+	// The span leaves out " cat", and the raw pairs are returned for programmatic use
+	pairs, ok := result.Data.([]metrics.AlignmentPair)
+	if !ok {
+		t.Fatalf("Data is %T, want the alignment pairs", result.Data)
+	}
+	var pieceTexts []string
+	for _, pair := range pairs {
+		pieceTexts = append(pieceTexts, pair.Text2)
+	}
+	if want := []string{"un", "believa", "ble"}; !reflect.DeepEqual(pieceTexts, want) {
+		t.Errorf("paired pieces = %v, want %v", pieceTexts, want)
+	}
+	if groups := result.Metadata["groups"].(map[string]int); groups[metrics.AlignmentOneToMany] != 1 || len(groups) != 1 {
+		t.Errorf("groups = %v, want a single one_to_many group", groups)
+	}
+	if result.Filepath == "" || !strings.HasSuffix(result.Filepath, "token_alignment_words_vs_pieces.html") {
+		t.Errorf("Filepath = %s", result.Filepath)
+	}
+}
+
+func TestGenerateAlignmentDiagramNotesSkippedTokens(t *testing.T) {
+	words := spanTokenization("words", "hi there", 0, 2, 8)
+	plain := &tokenizers.TokenizationResult{Tokenizer: "plain", Tokens: []tokenizers.Token{{Text: "hi"}, {Text: " there"}}}
+
+	engine := NewVisualizationEngine(VisualizationConfig{OutputDir: t.TempDir()})
+	data, err := NewAlignmentData(metrics.NewDriftCalculator(0.5), words, plain, 0, 0)
+	if err != nil {
+		t.Fatalf("NewAlignmentData returned error: %v", err)
+	}
+	result, err := engine.GenerateAlignmentDiagram(data)
+	if err != nil {
+		t.Fatalf("GenerateAlignmentDiagram returned error: %v", err)
+	}
+
+	if notes, _ := result.Metadata["notes"].([]string); len(notes) != 1 || !strings.Contains(notes[0], "plain") {
+		t.Errorf("notes = %v, want one about the tokens of plain", notes)
+	}
+	content, err := os.ReadFile(result.Filepath)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", result.Filepath, err)
+	}
+	if !strings.Contains(string(content), "skipped all 2 tokens of plain") || !strings.Contains(string(content), "2 tokens have no counterpart") {
+		t.Error("diagram does not show the skipped and unaligned tokens")
+	}
+}
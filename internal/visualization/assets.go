@@ -0,0 +1,87 @@
+package visualization
+
+import (
+	"embed"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PlotlyVersion is the Plotly.js release that HTML visualizations load, pinned so that
+// reports render the same way over time
+const PlotlyVersion = "2.35.2"
+
+// plotlyFile is the name of the Plotly.js bundle, in assets and next to offline outputs
+const plotlyFile = "plotly-" + PlotlyVersion + ".min.js"
+
+//go:embed assets
+var assets embed.FS
+
+// plotlyBundle returns the embedded Plotly.js bundle
+func plotlyBundle() ([]byte, error) {
+	bundle, err := assets.ReadFile("assets/" + plotlyFile)
+	if err != nil {
+		return nil, fmt.Errorf("plotly.js %s is not bundled in this build (run make plotly-assets and rebuild, or disable offline_assets and self_contained)", PlotlyVersion)
+	}
+	return bundle, nil
+}
+
+// plotlyScriptTag returns the script tag that loads Plotly.js in a visualization: the
+// copy next to the outputs with offline assets, or the pinned release from the CDN
+func (v *VisualizationEngine) plotlyScriptTag() string {
+	if v.config.OfflineAssets {
+		return fmt.Sprintf(`<script src="%s"></script>`, plotlyFile)
+	}
+	return fmt.Sprintf(`<script src="https://cdn.plot.ly/%s"></script>`, plotlyFile)
+}
+
+// writePlotlyAsset copies the embedded Plotly.js bundle into the output directory,
+// unless it is already there
+func (v *VisualizationEngine) writePlotlyAsset() error {
+	path := filepath.Join(v.config.OutputDir, plotlyFile)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	bundle, err := plotlyBundle()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, bundle, 0644); err != nil {
+		return fmt.Errorf("error writing %s: %w", plotlyFile, err)
+	}
+	return nil
+}
+
+// inlineScript returns a script element holding source, which must not end the
+// element early
+func inlineScript(source []byte) string {
+	return "<script>" + strings.ReplaceAll(string(source), "</script", `<\/script`) + "</script>"
+}
+
+// inlineVisualization returns a report element holding a visualization's file itself.
+// HTML visualizations go in a srcdoc frame that borrows Plotly.js from the report;
+// static images become data URIs.
+func (v *VisualizationEngine) inlineVisualization(viz *VisualizationResult) (string, error) {
+	content, err := os.ReadFile(viz.Filepath)
+	if err != nil {
+		return "", fmt.Errorf("error reading visualization file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(viz.Filepath)) {
+	case ".html":
+		page := strings.Replace(string(content), v.plotlyScriptTag(), "<script>var Plotly = parent.Plotly;</script>", 1)
+		return fmt.Sprintf(`<iframe srcdoc="%s" width="100%%" height="600px" frameborder="0"></iframe>`, html.EscapeString(page)), nil
+	case ".svg":
+		return fmt.Sprintf(`<img src="data:image/svg+xml;base64,%s" alt="%s" style="max-width: 100%%;">`,
+			base64.StdEncoding.EncodeToString(content), html.EscapeString(viz.Type)), nil
+	case ".png":
+		return fmt.Sprintf(`<img src="data:image/png;base64,%s" alt="%s" style="max-width: 100%%;">`,
+			base64.StdEncoding.EncodeToString(content), html.EscapeString(viz.Type)), nil
+	default:
+		return "", fmt.Errorf("cannot inline visualization file %s", viz.Filepath)
+	}
+}
@@ -0,0 +1,88 @@
+package visualization
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+)
+
+var assetHeatmap = HeatmapData{
+	XLabels: []string{"doc 1", "doc 2"},
+	YLabels: []string{"gpt2"},
+	Values:  [][]float64{{1, 2}},
+}
+
+func TestPlotlyVersionPinned(t *testing.T) {
+	engine := NewVisualizationEngine(VisualizationConfig{FileType: "html", OutputDir: t.TempDir()})
+	result, err := engine.GenerateHeatmap(assetHeatmap, "token_count")
+	if err != nil {
+		t.Fatalf("GenerateHeatmap returned error: %v", err)
+	}
+
+	page, err := os.ReadFile(result.Filepath)
+	if err != nil {
+		t.Fatalf("failed to read visualization: %v", err)
+	}
+	if strings.Contains(string(page), "plotly-latest") || !strings.Contains(string(page), "cdn.plot.ly/"+plotlyFile) {
+		t.Errorf("visualization should load the pinned Plotly.js %s from the CDN", PlotlyVersion)
+	}
+}
+
+func TestOfflineAssets(t *testing.T) {
+	dir := t.TempDir()
+	engine := NewVisualizationEngine(VisualizationConfig{FileType: "html", OutputDir: dir, OfflineAssets: true})
+	result, err := engine.GenerateHeatmap(assetHeatmap, "token_count")
+
+	if _, bundleErr := plotlyBundle(); bundleErr != nil {
+		// Without the bundle, offline output must fail rather than fall back to the CDN
+		if err == nil || !strings.Contains(err.Error(), "make plotly-assets") {
+			t.Errorf("GenerateHeatmap error = %v, want the missing bundle", err)
+		}
+		return
+	}
+
+	if err != nil {
+		t.Fatalf("GenerateHeatmap returned error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, plotlyFile)); err != nil {
+		t.Errorf("Plotly.js was not copied next to the outputs: %v", err)
+	}
+	page, _ := os.ReadFile(result.Filepath)
+	if strings.Contains(string(page), "cdn.plot.ly") || !strings.Contains(string(page), `src="`+plotlyFile+`"`) {
+		t.Error("offline visualization should load Plotly.js from the copy next to it")
+	}
+}
+
+func TestSelfContainedReport(t *testing.T) {
+	results := []*metrics.AnalysisResult{
+		{Document: "one", TokenizerName: "gpt2", TokenCount: 3, Metrics: map[string]metrics.MetricResult{}},
+		{Document: "two", TokenizerName: "gpt2", TokenCount: 5, Metrics: map[string]metrics.MetricResult{}},
+	}
+
+	for fileType, dataURI := range map[string]string{
+		"svg": "data:image/svg+xml;base64,",
+		"png": "data:image/png;base64,",
+	} {
+		t.Run(fileType, func(t *testing.T) {
+			engine := NewVisualizationEngine(VisualizationConfig{FileType: fileType, OutputDir: t.TempDir(), SelfContained: true})
+			report, err := engine.GenerateComprehensiveReport(results)
+			if err != nil {
+				t.Fatalf("GenerateComprehensiveReport returned error: %v", err)
+			}
+
+			page, err := os.ReadFile(report.Filepath)
+			if err != nil {
+				t.Fatalf("failed to read report: %v", err)
+			}
+			if !strings.Contains(string(page), dataURI) {
+				t.Errorf("report should inline its visualizations as %s URIs", dataURI)
+			}
+			if strings.Contains(string(page), "<iframe src=") {
+				t.Error("self-contained report should not frame other files")
+			}
+		})
+	}
+}
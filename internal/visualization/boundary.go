@@ -0,0 +1,289 @@
+package visualization
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// tokenSpan is one token placed on the document by byte offset
+type tokenSpan struct {
+	token    tokenizers.Token
+	start    int
+	end      int
+	text     string // the document text the token covers, or its own text when estimated
+	disputed bool   // a boundary of the token is not shared by every tokenizer
+}
+
+// boundaryRow is one tokenizer's tokens placed on the document
+type boundaryRow struct {
+	name      string
+	spans     []tokenSpan
+	estimated bool // offsets were missing, so positions follow cumulative token lengths
+}
+
+// visibleWhitespace shows whitespace in token segments
+var visibleWhitespace = strings.NewReplacer(" ", "·", "\n", "↵", "\t", "→", "\r", "")
+
+// tokenBoundaryRows places each tokenization's tokens on the document, one row per
+// tokenizer. Real offsets are used when a tokenization has them; otherwise positions
+// are estimated from cumulative token lengths and the row is marked as estimated.
+func tokenBoundaryRows(data TokenBoundaryData) []boundaryRow {
+	rows := make([]boundaryRow, 0, len(data.Tokenizations))
+	for i, tokenization := range data.Tokenizations {
+		if tokenization == nil {
+			continue
+		}
+		document := data.Document
+		if document == "" {
+			document = tokenization.Document
+		}
+
+		row := boundaryRow{name: tokenization.Tokenizer}
+		if i < len(data.TokenizerNames) {
+			row.name = data.TokenizerNames[i]
+		}
+		row.estimated = !hasTokenOffsets(tokenization.Tokens, document)
+
+		pos := 0
+		for _, token := range tokenization.Tokens {
+			span := tokenSpan{token: token, start: token.StartPos, end: token.EndPos, text: token.Text}
+			if row.estimated {
+				span.start, span.end = pos, pos+len(token.Text)
+				pos = span.end
+			} else if document != "" {
+				span.text = document[span.start:span.end]
+			}
+			row.spans = append(row.spans, span)
+		}
+		rows = append(rows, row)
+	}
+
+	markDisputedBoundaries(rows)
+	return rows
+}
+
+// hasTokenOffsets reports whether tokens carry usable byte offsets into document.
+// Adapters without position information leave every StartPos at zero.
+func hasTokenOffsets(tokens []tokenizers.Token, document string) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+
+	positioned := len(tokens) == 1 && tokens[0].EndPos > 0
+	for _, token := range tokens {
+		if token.StartPos < 0 || token.EndPos < token.StartPos {
+			return false
+		}
+		if document != "" && token.EndPos > len(document) {
+			return false
+		}
+		if token.StartPos != 0 {
+			positioned = true
+		}
+	}
+	return positioned
+}
+
+// markDisputedBoundaries marks the tokens that start or end at a position where some
+// other tokenizer has no boundary
+func markDisputedBoundaries(rows []boundaryRow) {
+	if len(rows) < 2 {
+		return
+	}
+
+	counts := make(map[int]int)
+	for _, row := range rows {
+		boundaries := make(map[int]bool)
+		for _, span := range row.spans {
+			boundaries[span.start] = true
+			boundaries[span.end] = true
+		}
+		for boundary := range boundaries {
+			counts[boundary]++
+		}
+	}
+
+	for _, row := range rows {
+		for i := range row.spans {
+			span := &row.spans[i]
+			span.disputed = counts[span.start] < len(rows) || counts[span.end] < len(rows)
+		}
+	}
+}
+
+// estimatedOffsetsWarning returns the warning shown when some rows have estimated
+// positions, or "" when every row has real offsets
+func estimatedOffsetsWarning(rows []boundaryRow) string {
+	names := estimatedRowNames(rows)
+	if len(names) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("⚠ Offsets missing for %s: positions estimated from token lengths", strings.Join(names, ", "))
+}
+
+// estimatedRowNames returns the tokenizers whose positions were estimated
+func estimatedRowNames(rows []boundaryRow) []string {
+	var names []string
+	for _, row := range rows {
+		if row.estimated {
+			names = append(names, row.name)
+		}
+	}
+	return names
+}
+
+// createTokenBoundaryPlotData creates one horizontal bar trace per tokenizer, with a
+// segment for each token labelled with the text it covers
+func (v *VisualizationEngine) createTokenBoundaryPlotData(rows []boundaryRow) []map[string]interface{} {
+	plotData := make([]map[string]interface{}, 0, len(rows))
+	palette := v.palette()
+
+	for _, row := range rows {
+		names := make([]string, len(row.spans))
+		bases := make([]int, len(row.spans))
+		widths := make([]float64, len(row.spans))
+		text := make([]string, len(row.spans))
+		colors := make([]string, len(row.spans))
+		outlines := make([]string, len(row.spans))
+		outlineWidths := make([]int, len(row.spans))
+		customData := make([][]interface{}, len(row.spans))
+
+		for i, span := range row.spans {
+			names[i] = row.name
+			bases[i] = span.start
+			// Keep empty tokens visible as a sliver
+			widths[i] = max(float64(span.end-span.start), 0.2)
+			text[i] = visibleWhitespace.Replace(strings.ToValidUTF8(span.text, "�"))
+			// Colors alternate between neighbouring tokens; disputed tokens are outlined
+			colors[i] = palette.tokenSegmentColor(i)
+			outlines[i], outlineWidths[i] = "rgba(0,0,0,0.2)", 1
+			if span.disputed {
+				outlines[i], outlineWidths[i] = palette.Accent, 2
+			}
+			customData[i] = []interface{}{
+				strings.ToValidUTF8(span.token.Text, "�"), span.token.ID, span.end - span.start, span.start, span.end,
+			}
+		}
+
+		plotData = append(plotData, map[string]interface{}{
+			"type":             "bar",
+			"orientation":      "h",
+			"name":             row.name,
+			"y":                names,
+			"x":                widths,
+			"base":             bases,
+			"text":             text,
+			"textposition":     "inside",
+			"insidetextanchor": "middle",
+			"textangle":        0,
+			"customdata":       customData,
+			"marker": map[string]interface{}{
+				"color": colors,
+				"line": map[string]interface{}{
+					"color": outlines,
+					"width": outlineWidths,
+				},
+			},
+			"hovertemplate": "<b>%{customdata[0]}</b><br>ID: %{customdata[1]}<br>Bytes: %{customdata[2]} (%{customdata[3]}-%{customdata[4]})<extra>%{fullData.name}</extra>",
+			"showlegend":    false,
+		})
+	}
+
+	return plotData
+}
+
+// tokenBoundaryLayout creates the layout of the token boundary plot, with rows in
+// tokenizer order and the estimated offsets warning above the plot
+func (v *VisualizationEngine) tokenBoundaryLayout(rows []boundaryRow) map[string]interface{} {
+	layout := map[string]interface{}{
+		"title": map[string]interface{}{
+			"text": "Token Boundary Analysis",
+			"x":    0.5,
+		},
+		"xaxis": map[string]interface{}{
+			"title":    "Byte Offset",
+			"showgrid": true,
+		},
+		"yaxis": map[string]interface{}{
+			"title":     "Tokenizer",
+			"autorange": "reversed",
+		},
+		"barmode":     "overlay",
+		"bargap":      0.3,
+		"uniformtext": map[string]interface{}{"mode": "hide", "minsize": 8},
+		"height":      v.getHeight(),
+		"width":       v.getWidth(),
+		"template":    v.getTemplate(),
+	}
+
+	if warning := estimatedOffsetsWarning(rows); warning != "" {
+		layout["annotations"] = []map[string]interface{}{{
+			"text":      warning,
+			"xref":      "paper",
+			"yref":      "paper",
+			"x":         0,
+			"y":         1.08,
+			"xanchor":   "left",
+			"showarrow": false,
+			"font":      map[string]interface{}{"color": v.palette().Accent},
+		}}
+	}
+	return layout
+}
+
+// tokenBoundaryChart draws the token segments of each tokenizer as a static chart
+func (v *VisualizationEngine) tokenBoundaryChart(rows []boundaryRow) staticChart {
+	theme := v.staticTheme()
+	warning := estimatedOffsetsWarning(rows)
+
+	return func(c staticCanvas, width, height float64) {
+		const left, right, top, bottom = 110.0, 30.0, 70.0, 50.0
+		c.text(width/2, 30, "Token Boundary Analysis", "middle", 18, theme.foreground)
+		if warning != "" {
+			c.text(left, 54, warning, "start", 12, hexColor(theme.palette.Accent))
+		}
+		if len(rows) == 0 {
+			return
+		}
+
+		extent := 1
+		for _, row := range rows {
+			for _, span := range row.spans {
+				extent = max(extent, span.end)
+			}
+		}
+
+		plotWidth, plotHeight := width-left-right, height-top-bottom
+		rowHeight := plotHeight / float64(len(rows))
+		px := func(offset int) float64 { return left + float64(offset)/float64(extent)*plotWidth }
+
+		for r, row := range rows {
+			y, h := top+float64(r)*rowHeight+rowHeight*0.2, rowHeight*0.6
+			c.text(left-8, y+h/2+4, truncateLabel(row.name, 14), "end", 12, theme.foreground)
+
+			for i, span := range row.spans {
+				x, w := px(span.start), max(px(span.end)-px(span.start), 1)
+				c.rect(x, y, w, h, hexColor(theme.palette.tokenSegmentColor(i)))
+				if span.disputed {
+					outline := hexColor(theme.palette.Accent)
+					c.line(x, y, x+w, y, outline)
+					c.line(x+w, y, x+w, y+h, outline)
+					c.line(x+w, y+h, x, y+h, outline)
+					c.line(x, y+h, x, y, outline)
+				}
+				// About 7 pixels per character at this font size
+				if fit := int(w / 7); fit >= 2 {
+					label := visibleWhitespace.Replace(strings.ToValidUTF8(span.text, "�"))
+					c.text(x+w/2, y+h/2+4, truncateLabel(label, fit), "middle", 11, theme.foreground)
+				}
+			}
+		}
+
+		c.line(left, top+plotHeight, left+plotWidth, top+plotHeight, theme.grid)
+		c.text(left, top+plotHeight+16, "0", "middle", 11, theme.foreground)
+		c.text(left+plotWidth, top+plotHeight+16, fmt.Sprint(extent), "middle", 11, theme.foreground)
+		c.text(left+plotWidth/2, top+plotHeight+36, "Byte Offset", "middle", 12, theme.foreground)
+	}
+}
@@ -0,0 +1,111 @@
+package visualization
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+func TestTokenBoundaryRows(t *testing.T) {
+	document := "Hello world"
+	data := TokenBoundaryData{
+		Document:       document,
+		TokenizerNames: []string{"words", "pieces", "plain"},
+		Tokenizations: []*tokenizers.TokenizationResult{
+			{Tokens: []tokenizers.Token{
+				{Text: "Hello", ID: 1, StartPos: 0, EndPos: 5},
+				{Text: "Ġworld", ID: 2, StartPos: 5, EndPos: 11},
+			}},
+			{Tokens: []tokenizers.Token{
+				{Text: "Hel", ID: 3, StartPos: 0, EndPos: 3},
+				{Text: "lo", ID: 4, StartPos: 3, EndPos: 5},
+				{Text: " world", ID: 5, StartPos: 5, EndPos: 11},
+			}},
+			// No offsets, so positions come from token lengths
+			{Tokens: []tokenizers.Token{{Text: "Hello"}, {Text: " world"}}},
+		},
+	}
+
+	rows := tokenBoundaryRows(data)
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3", len(rows))
+	}
+
+	tests := []struct {
+		row       int
+		estimated bool
+		texts     []string
+		ends      []int
+		disputed  []bool
+	}{
+		// The GPT-2 style token shows the document text it covers, not "Ġworld"
+		{0, false, []string{"Hello", " world"}, []int{5, 11}, []bool{false, false}},
+		{1, false, []string{"Hel", "lo", " world"}, []int{3, 5, 11}, []bool{true, true, false}},
+		{2, true, []string{"Hello", " world"}, []int{5, 11}, []bool{false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(rows[tt.row].name, func(t *testing.T) {
+			row := rows[tt.row]
+			if row.estimated != tt.estimated {
+				t.Errorf("estimated = %v, want %v", row.estimated, tt.estimated)
+			}
+			var texts []string
+			var ends []int
+			var disputed []bool
+			for _, span := range row.spans {
+				texts = append(texts, span.text)
+				ends = append(ends, span.end)
+				disputed = append(disputed, span.disputed)
+			}
+			if !reflect.DeepEqual(texts, tt.texts) {
+				t.Errorf("texts = %q, want %q", texts, tt.texts)
+			}
+			if !reflect.DeepEqual(ends, tt.ends) {
+				t.Errorf("ends = %v, want %v", ends, tt.ends)
+			}
+			if !reflect.DeepEqual(disputed, tt.disputed) {
+				t.Errorf("disputed = %v, want %v", disputed, tt.disputed)
+			}
+		})
+	}
+}
+
+func TestTokenBoundaryMapWarnsAboutEstimatedOffsets(t *testing.T) {
+	tests := []struct {
+		name   string
+		tokens []tokenizers.Token
+		warns  bool
+	}{
+		{"offsets", []tokenizers.Token{{Text: "Hi", StartPos: 0, EndPos: 2}, {Text: "!", StartPos: 2, EndPos: 3}}, false},
+		{"no offsets", []tokenizers.Token{{Text: "Hi"}, {Text: "!"}}, true},
+		{"out of range", []tokenizers.Token{{Text: "Hi", StartPos: 0, EndPos: 2}, {Text: "!", StartPos: 2, EndPos: 9}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewVisualizationEngine(VisualizationConfig{OutputDir: t.TempDir()})
+			result, err := engine.GenerateTokenBoundaryMap(TokenBoundaryData{
+				DocumentID:     "doc",
+				Document:       "Hi!",
+				TokenizerNames: []string{"mock"},
+				Tokenizations:  []*tokenizers.TokenizationResult{{Tokens: tt.tokens}},
+			})
+			if err != nil {
+				t.Fatalf("GenerateTokenBoundaryMap returned error: %v", err)
+			}
+
+			content, err := os.ReadFile(result.Filepath)
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", result.Filepath, err)
+			}
+			_, estimated := result.Metadata["estimated_offsets"]
+			warned := strings.Contains(string(content), "Offsets missing for mock")
+			if estimated != tt.warns || warned != tt.warns {
+				t.Errorf("estimated_offsets recorded = %v, warning shown = %v, want %v", estimated, warned, tt.warns)
+			}
+		})
+	}
+}
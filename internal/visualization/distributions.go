@@ -0,0 +1,431 @@
+package visualization
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+)
+
+// HistogramData holds the values of one or more series whose distributions are shown
+// side by side, e.g. the token lengths of each tokenizer
+type HistogramData struct {
+	ID     string      `json:"id"` // names the output file
+	Title  string      `json:"title"`
+	XLabel string      `json:"x_label"`
+	Names  []string    `json:"names"`
+	Values [][]float64 `json:"values"` // one series per name
+	Bins   int         `json:"bins"`   // number of bins; 0 picks one from the data
+}
+
+// BoxPlotData holds the values of one or more groups summarized as box plots, e.g. the
+// per-document token counts of each tokenizer
+type BoxPlotData struct {
+	ID     string      `json:"id"` // names the output file
+	Title  string      `json:"title"`
+	YLabel string      `json:"y_label"`
+	Names  []string    `json:"names"`
+	Values [][]float64 `json:"values"` // one group per name
+}
+
+// NewTokenLengthHistogramData builds a histogram of token lengths in characters, one
+// series per tokenizer, from the analysis results that carry tokenizations
+func NewTokenLengthHistogramData(analysisResults []*metrics.AnalysisResult) HistogramData {
+	data := HistogramData{
+		ID:     "token_length",
+		Title:  "Token Length Distribution",
+		XLabel: "Token Length (characters)",
+	}
+
+	series := make(map[string]int)
+	for _, result := range analysisResults {
+		if result.Tokenization == nil || len(result.Tokenization.Tokens) == 0 {
+			continue
+		}
+		i, exists := series[result.TokenizerName]
+		if !exists {
+			i = len(data.Names)
+			series[result.TokenizerName] = i
+			data.Names = append(data.Names, result.TokenizerName)
+			data.Values = append(data.Values, nil)
+		}
+		for _, token := range result.Tokenization.Tokens {
+			data.Values[i] = append(data.Values[i], float64(utf8.RuneCountInString(token.Text)))
+		}
+	}
+	return data
+}
+
+// NewRollingEntropyHistogramData builds a histogram of rolling entropy values, pooling
+// the windows of every document into one series per tokenizer
+func NewRollingEntropyHistogramData(rolling []RollingEntropyData) HistogramData {
+	data := HistogramData{
+		ID:     "rolling_entropy",
+		Title:  "Rolling Entropy Distribution",
+		XLabel: "Entropy",
+	}
+
+	series := make(map[string]int)
+	for _, entry := range rolling {
+		i, exists := series[entry.TokenizerName]
+		if !exists {
+			i = len(data.Names)
+			series[entry.TokenizerName] = i
+			data.Names = append(data.Names, entry.TokenizerName)
+			data.Values = append(data.Values, nil)
+		}
+		data.Values[i] = append(data.Values[i], entry.EntropyValues...)
+	}
+	return data
+}
+
+// NewTokenCountBoxPlotData builds box plots of per-document token counts grouped by
+// tokenizer
+func NewTokenCountBoxPlotData(analysisResults []*metrics.AnalysisResult) BoxPlotData {
+	data := BoxPlotData{
+		ID:     "token_count",
+		Title:  "Token Counts per Document",
+		YLabel: "Token Count",
+	}
+
+	groups := make(map[string]int)
+	for _, result := range analysisResults {
+		i, exists := groups[result.TokenizerName]
+		if !exists {
+			i = len(data.Names)
+			groups[result.TokenizerName] = i
+			data.Names = append(data.Names, result.TokenizerName)
+			data.Values = append(data.Values, nil)
+		}
+		data.Values[i] = append(data.Values[i], float64(result.TokenCount))
+	}
+	return data
+}
+
+// GenerateHistogram generates a histogram of each series in data, with bars of the
+// same bins grouped side by side
+func (v *VisualizationEngine) GenerateHistogram(data HistogramData) (*VisualizationResult, error) {
+	if len(data.Names) == 0 || len(data.Names) != len(data.Values) {
+		return nil, fmt.Errorf("histogram needs one series of values per name")
+	}
+	bins := histogramBins(data.Values, data.Bins)
+
+	// Create Plotly.js histogram
+	plotData := make([]map[string]interface{}, len(data.Names))
+	for i, name := range data.Names {
+		plotData[i] = map[string]interface{}{
+			"type": "histogram",
+			"name": name,
+			"x":    data.Values[i],
+			"xbins": map[string]interface{}{
+				"start": bins.start,
+				"end":   bins.start + bins.size*float64(bins.count),
+				"size":  bins.size,
+			},
+			"autobinx": false,
+			"marker":   map[string]interface{}{"color": v.palette().seriesColor(i)},
+		}
+	}
+
+	layout := map[string]interface{}{
+		"title": map[string]interface{}{
+			"text": data.Title,
+			"x":    0.5,
+		},
+		"xaxis": map[string]interface{}{
+			"title":    data.XLabel,
+			"showgrid": true,
+		},
+		"yaxis": map[string]interface{}{
+			"title":    "Count",
+			"showgrid": true,
+		},
+		"barmode":  "group",
+		"height":   v.getHeight(),
+		"width":    v.getWidth(),
+		"template": v.getTemplate(),
+	}
+
+	// Generate HTML
+	html := v.generatePlotlyHTML(plotData, layout, "histogram")
+
+	// Save to file
+	filename := fmt.Sprintf("histogram_%s.%s", data.ID, v.fileType())
+	filepath := filepath.Join(v.config.OutputDir, filename)
+
+	if err := v.writeVisualization(filepath, html, v.histogramChart(data, bins), v.getWidth(), v.getHeight()); err != nil {
+		return nil, err
+	}
+
+	return v.exportData(&VisualizationResult{
+		Type:     "histogram",
+		Filepath: filepath,
+		Data:     plotData,
+		Metadata: v.generationMetadata(map[string]interface{}{
+			"id":       data.ID,
+			"bins":     bins.count,
+			"bin_size": bins.size,
+		}, data.Names, 0),
+	}, data, nil)
+}
+
+// GenerateBoxPlot generates a box plot of each group in data, with whiskers reaching
+// the furthest values within 1.5 interquartile ranges and the values beyond shown as
+// outliers
+func (v *VisualizationEngine) GenerateBoxPlot(data BoxPlotData) (*VisualizationResult, error) {
+	if len(data.Names) == 0 || len(data.Names) != len(data.Values) {
+		return nil, fmt.Errorf("box plot needs one group of values per name")
+	}
+
+	// Create Plotly.js box plot
+	plotData := make([]map[string]interface{}, len(data.Names))
+	for i, name := range data.Names {
+		plotData[i] = map[string]interface{}{
+			"type":           "box",
+			"name":           name,
+			"y":              data.Values[i],
+			"boxpoints":      "outliers",
+			"quartilemethod": "linear",
+			"marker":         map[string]interface{}{"color": v.palette().seriesColor(i)},
+		}
+	}
+
+	layout := map[string]interface{}{
+		"title": map[string]interface{}{
+			"text": data.Title,
+			"x":    0.5,
+		},
+		"yaxis": map[string]interface{}{
+			"title":    data.YLabel,
+			"showgrid": true,
+		},
+		"showlegend": false,
+		"height":     v.getHeight(),
+		"width":      v.getWidth(),
+		"template":   v.getTemplate(),
+	}
+
+	// Generate HTML
+	html := v.generatePlotlyHTML(plotData, layout, "box_plot")
+
+	// Save to file
+	filename := fmt.Sprintf("box_plot_%s.%s", data.ID, v.fileType())
+	filepath := filepath.Join(v.config.OutputDir, filename)
+
+	if err := v.writeVisualization(filepath, html, v.boxPlotChart(data), v.getWidth(), v.getHeight()); err != nil {
+		return nil, err
+	}
+
+	return v.exportData(&VisualizationResult{
+		Type:     "box_plot",
+		Filepath: filepath,
+		Data:     plotData,
+		Metadata: v.generationMetadata(map[string]interface{}{
+			"id": data.ID,
+		}, data.Names, 0),
+	}, data, nil)
+}
+
+// binning describes equal-width histogram bins
+type binning struct {
+	start float64
+	size  float64
+	count int
+}
+
+// histogramBins chooses bins covering every value. Without a requested count, integer
+// values spanning at most 50 get one bin per value and other values follow Sturges'
+// rule.
+func histogramBins(values [][]float64, requested int) binning {
+	low, high, n := math.Inf(1), math.Inf(-1), 0
+	integers := true
+	for _, series := range values {
+		for _, value := range series {
+			low, high = math.Min(low, value), math.Max(high, value)
+			integers = integers && value == math.Trunc(value)
+			n++
+		}
+	}
+	if n == 0 {
+		return binning{start: 0, size: 1, count: 1}
+	}
+
+	if requested <= 0 && integers && high-low < 50 {
+		return binning{start: low - 0.5, size: 1, count: int(high-low) + 1}
+	}
+
+	count := requested
+	if count <= 0 {
+		count = min(int(math.Ceil(math.Log2(float64(n))))+1, 50)
+	}
+	if high == low {
+		return binning{start: low - 0.5, size: 1, count: 1}
+	}
+	// Widen the last bin slightly so the maximum falls inside it
+	size := (high - low) / float64(count) * (1 + 1e-9)
+	return binning{start: low, size: size, count: count}
+}
+
+// counts returns how many of values fall in each bin
+func (b binning) counts(values []float64) []int {
+	counts := make([]int, b.count)
+	for _, value := range values {
+		bin := int((value - b.start) / b.size)
+		if bin >= 0 && bin < b.count {
+			counts[bin]++
+		}
+	}
+	return counts
+}
+
+// boxSummary is the five-number summary of a group drawn as a box plot
+type boxSummary struct {
+	q1, median, q3          float64
+	lowWhisker, highWhisker float64
+	outliers                []float64
+}
+
+// summarizeBox computes a box plot summary with linearly interpolated quartiles
+func summarizeBox(values []float64) (boxSummary, bool) {
+	if len(values) == 0 {
+		return boxSummary{}, false
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	summary := boxSummary{
+		q1:     quantile(sorted, 0.25),
+		median: quantile(sorted, 0.5),
+		q3:     quantile(sorted, 0.75),
+	}
+	iqr := summary.q3 - summary.q1
+	lowFence, highFence := summary.q1-1.5*iqr, summary.q3+1.5*iqr
+
+	summary.lowWhisker, summary.highWhisker = summary.q1, summary.q3
+	for _, value := range sorted {
+		if value < lowFence || value > highFence {
+			summary.outliers = append(summary.outliers, value)
+			continue
+		}
+		summary.lowWhisker = math.Min(summary.lowWhisker, value)
+		summary.highWhisker = math.Max(summary.highWhisker, value)
+	}
+	return summary, true
+}
+
+// quantile returns the q-quantile of sorted values, interpolating linearly between
+// neighbouring values
+func quantile(sorted []float64, q float64) float64 {
+	position := q * float64(len(sorted)-1)
+	lower := int(math.Floor(position))
+	upper := min(lower+1, len(sorted)-1)
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(position-float64(lower))
+}
+
+// histogramChart draws the histogram bars of each series grouped within each bin
+func (v *VisualizationEngine) histogramChart(data HistogramData, bins binning) staticChart {
+	theme := v.staticTheme()
+	counts := make([][]int, len(data.Values))
+	maxCount := 1
+	for i, series := range data.Values {
+		counts[i] = bins.counts(series)
+		for _, count := range counts[i] {
+			maxCount = max(maxCount, count)
+		}
+	}
+
+	return func(c staticCanvas, width, height float64) {
+		const left, right, top, bottom = 80.0, 40.0, 70.0, 60.0
+		c.text(width/2, 30, data.Title, "middle", 18, theme.foreground)
+		drawLegend(c, theme, data.Names, left, 52)
+
+		plotWidth, plotHeight := width-left-right, height-top-bottom
+		binWidth := plotWidth / float64(bins.count)
+		barWidth := binWidth * 0.8 / float64(len(counts))
+		for i, series := range counts {
+			for bin, count := range series {
+				barHeight := float64(count) / float64(maxCount) * plotHeight
+				x := left + float64(bin)*binWidth + binWidth*0.1 + float64(i)*barWidth
+				c.rect(x, top+plotHeight-barHeight, barWidth, barHeight, hexColor(theme.palette.seriesColor(i)))
+			}
+		}
+
+		c.line(left, top+plotHeight, left+plotWidth, top+plotHeight, theme.grid)
+		c.line(left, top, left, top+plotHeight, theme.grid)
+		c.text(left-8, top+10, fmt.Sprint(maxCount), "end", 11, theme.foreground)
+		c.text(left-8, top+plotHeight, "0", "end", 11, theme.foreground)
+		c.text(left, top+plotHeight+16, formatTick(bins.start), "middle", 11, theme.foreground)
+		c.text(left+plotWidth, top+plotHeight+16, formatTick(bins.start+bins.size*float64(bins.count)), "middle", 11, theme.foreground)
+		c.text(left+plotWidth/2, top+plotHeight+40, data.XLabel, "middle", 12, theme.foreground)
+	}
+}
+
+// boxPlotChart draws a box with whiskers and outliers for each group
+func (v *VisualizationEngine) boxPlotChart(data BoxPlotData) staticChart {
+	theme := v.staticTheme()
+	summaries := make([]boxSummary, len(data.Values))
+	present := make([]bool, len(data.Values))
+	low, high := math.Inf(1), math.Inf(-1)
+	for i, values := range data.Values {
+		summaries[i], present[i] = summarizeBox(values)
+		for _, value := range values {
+			low, high = math.Min(low, value), math.Max(high, value)
+		}
+	}
+	if math.IsInf(low, 1) {
+		low, high = 0, 1
+	}
+	if low == high {
+		low, high = low-0.5, high+0.5
+	}
+	pad := (high - low) * 0.05
+	low, high = low-pad, high+pad
+
+	return func(c staticCanvas, width, height float64) {
+		const left, right, top, bottom = 80.0, 40.0, 60.0, 60.0
+		c.text(width/2, 30, data.Title, "middle", 18, theme.foreground)
+
+		plotWidth, plotHeight := width-left-right, height-top-bottom
+		py := func(value float64) float64 { return top + plotHeight - (value-low)/(high-low)*plotHeight }
+		groupWidth := plotWidth / float64(len(data.Names))
+
+		for i, name := range data.Names {
+			center := left + (float64(i)+0.5)*groupWidth
+			c.text(center, top+plotHeight+16, truncateLabel(name, 14), "middle", 11, theme.foreground)
+			if !present[i] {
+				continue
+			}
+
+			s, color := summaries[i], hexColor(theme.palette.seriesColor(i))
+			boxWidth := groupWidth * 0.5
+			c.line(center, py(s.lowWhisker), center, py(s.q1), color)
+			c.line(center, py(s.q3), center, py(s.highWhisker), color)
+			c.line(center-boxWidth/4, py(s.lowWhisker), center+boxWidth/4, py(s.lowWhisker), color)
+			c.line(center-boxWidth/4, py(s.highWhisker), center+boxWidth/4, py(s.highWhisker), color)
+			c.rect(center-boxWidth/2, py(s.q3), boxWidth, max(py(s.q1)-py(s.q3), 1), color)
+			c.line(center-boxWidth/2, py(s.median), center+boxWidth/2, py(s.median), theme.background)
+			for _, outlier := range s.outliers {
+				c.circle(center, py(outlier), 3, color)
+			}
+		}
+
+		c.line(left, top+plotHeight, left+plotWidth, top+plotHeight, theme.grid)
+		c.line(left, top, left, top+plotHeight, theme.grid)
+		c.text(left-8, top+10, formatTick(high-pad), "end", 11, theme.foreground)
+		c.text(left-8, top+plotHeight, formatTick(low+pad), "end", 11, theme.foreground)
+		c.text(left+plotWidth/2, top+plotHeight+40, data.YLabel, "middle", 12, theme.foreground)
+	}
+}
+
+// drawLegend draws a row of colored swatches and series names starting at x, y
+func drawLegend(c staticCanvas, theme staticTheme, names []string, x, y float64) {
+	for i, name := range names {
+		label := truncateLabel(name, 16)
+		c.rect(x, y-9, 10, 10, hexColor(theme.palette.seriesColor(i)))
+		c.text(x+14, y, label, "start", 11, theme.foreground)
+		x += 30 + float64(utf8.RuneCountInString(label))*7
+	}
+}
@@ -0,0 +1,120 @@
+package visualization
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+func TestHistogramBins(t *testing.T) {
+	tests := []struct {
+		name      string
+		values    [][]float64
+		requested int
+		want      binning
+		counts    []int
+	}{
+		{"integers get one bin per value", [][]float64{{1, 2, 2, 4}}, 0, binning{start: 0.5, size: 1, count: 4}, []int{1, 2, 0, 1}},
+		{"requested bins", [][]float64{{0, 1, 2, 3, 4}}, 2, binning{start: 0, size: 2 * (1 + 1e-9), count: 2}, []int{3, 2}},
+		{"single value", [][]float64{{2.5, 2.5}}, 0, binning{start: 2, size: 1, count: 1}, []int{2}},
+		{"no values", nil, 0, binning{start: 0, size: 1, count: 1}, []int{0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := histogramBins(tt.values, tt.requested)
+			if got != tt.want {
+				t.Errorf("histogramBins = %+v, want %+v", got, tt.want)
+			}
+			var values []float64
+			if len(tt.values) > 0 {
+				values = tt.values[0]
+			}
+			if counts := got.counts(values); !reflect.DeepEqual(counts, tt.counts) {
+				t.Errorf("counts = %v, want %v", counts, tt.counts)
+			}
+		})
+	}
+}
+
+func TestSummarizeBox(t *testing.T) {
+	summary, ok := summarizeBox([]float64{40, 1, 2, 3, 4, 5})
+	if !ok {
+		t.Fatal("summarizeBox reported no values")
+	}
+
+	want := boxSummary{q1: 2.25, median: 3.5, q3: 4.75, lowWhisker: 1, highWhisker: 5, outliers: []float64{40}}
+	if math.Abs(summary.q1-want.q1) > 1e-9 || math.Abs(summary.median-want.median) > 1e-9 || math.Abs(summary.q3-want.q3) > 1e-9 {
+		t.Errorf("quartiles = %v, %v, %v, want %v, %v, %v", summary.q1, summary.median, summary.q3, want.q1, want.median, want.q3)
+	}
+	if summary.lowWhisker != want.lowWhisker || summary.highWhisker != want.highWhisker {
+		t.Errorf("whiskers = %v-%v, want %v-%v", summary.lowWhisker, summary.highWhisker, want.lowWhisker, want.highWhisker)
+	}
+	if !reflect.DeepEqual(summary.outliers, want.outliers) {
+		t.Errorf("outliers = %v, want %v", summary.outliers, want.outliers)
+	}
+
+	if _, ok := summarizeBox(nil); ok {
+		t.Error("summarizeBox summarized no values")
+	}
+}
+
+func TestDistributionData(t *testing.T) {
+	results := []*metrics.AnalysisResult{
+		{TokenizerName: "gpt2", TokenCount: 2, Tokenization: &tokenizers.TokenizationResult{Tokens: []tokenizers.Token{{Text: "Hé"}, {Text: "llo"}}}},
+		{TokenizerName: "bert", TokenCount: 4},
+		{TokenizerName: "gpt2", TokenCount: 1, Tokenization: &tokenizers.TokenizationResult{Tokens: []tokenizers.Token{{Text: "a"}}}},
+	}
+
+	lengths := NewTokenLengthHistogramData(results)
+	if !reflect.DeepEqual(lengths.Names, []string{"gpt2"}) || !reflect.DeepEqual(lengths.Values, [][]float64{{2, 3, 1}}) {
+		t.Errorf("token lengths = %v %v, want gpt2 with [2 3 1] in characters", lengths.Names, lengths.Values)
+	}
+
+	counts := NewTokenCountBoxPlotData(results)
+	if !reflect.DeepEqual(counts.Names, []string{"gpt2", "bert"}) || !reflect.DeepEqual(counts.Values, [][]float64{{2, 1}, {4}}) {
+		t.Errorf("token counts = %v %v", counts.Names, counts.Values)
+	}
+
+	rolling := NewRollingEntropyHistogramData([]RollingEntropyData{
+		{TokenizerName: "gpt2", EntropyValues: []float64{1, 2}},
+		{TokenizerName: "gpt2", EntropyValues: []float64{3}},
+	})
+	if !reflect.DeepEqual(rolling.Values, [][]float64{{1, 2, 3}}) {
+		t.Errorf("rolling entropy values = %v, want windows pooled per tokenizer", rolling.Values)
+	}
+}
+
+func TestComprehensiveReportTokenLengthHistogram(t *testing.T) {
+	tests := []struct {
+		name         string
+		tokenization *tokenizers.TokenizationResult
+		want         bool
+	}{
+		{"with tokenizations", &tokenizers.TokenizationResult{Tokens: []tokenizers.Token{{Text: "Hi"}}}, true},
+		{"without tokenizations", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewVisualizationEngine(VisualizationConfig{OutputDir: t.TempDir()})
+			report, err := engine.GenerateComprehensiveReport([]*metrics.AnalysisResult{
+				{Document: "Hi", TokenizerName: "gpt2", TokenCount: 1, Metrics: map[string]metrics.MetricResult{}, Tokenization: tt.tokenization},
+			})
+			if err != nil {
+				t.Fatalf("GenerateComprehensiveReport returned error: %v", err)
+			}
+
+			found := false
+			for _, viz := range report.Data.([]*VisualizationResult) {
+				found = found || viz.Type == "histogram"
+			}
+			if found != tt.want {
+				t.Errorf("report includes a histogram = %v, want %v", found, tt.want)
+			}
+		})
+	}
+}
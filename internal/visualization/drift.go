@@ -18,12 +18,12 @@ func (v *VisualizationEngine) createTokenCountDriftPlot(data DriftData) map[stri
 		"y":    data.DriftMetrics["token_count_delta"],
 		"name": "Token Count Delta",
 		"line": map[string]interface{}{
-			"color": "#1f77b4",
+			"color": v.palette().seriesColor(0),
 			"width": 2,
 		},
 		"marker": map[string]interface{}{
 			"size":  6,
-			"color": "#1f77b4",
+			"color": v.palette().seriesColor(0),
 		},
 	}
 
@@ -49,12 +49,12 @@ func (v *VisualizationEngine) createEntropyDriftPlot(data DriftData) map[string]
 		"y":    data.DriftMetrics["entropy_delta"],
 		"name": "Entropy Delta",
 		"line": map[string]interface{}{
-			"color": "#ff7f0e",
+			"color": v.palette().seriesColor(1),
 			"width": 2,
 		},
 		"marker": map[string]interface{}{
 			"size":  6,
-			"color": "#ff7f0e",
+			"color": v.palette().seriesColor(1),
 		},
 	}
 
@@ -79,7 +79,7 @@ func (v *VisualizationEngine) createAlignmentPlot(data DriftData) map[string]int
 		"y":    data.DriftMetrics["alignment_score"],
 		"name": "Alignment Score",
 		"marker": map[string]interface{}{
-			"color": "#2ca02c",
+			"color": v.palette().seriesColor(2),
 		},
 	}
 
@@ -91,55 +91,6 @@ func (v *VisualizationEngine) createAlignmentPlot(data DriftData) map[string]int
 	return plotData
 }
 
-// createTokenBoundaryPlotData creates data for token boundary visualization
-func (v *VisualizationEngine) createTokenBoundaryPlotData(data TokenBoundaryData) []map[string]interface{} {
-	var plotData []map[string]interface{}
-
-	for i, tokenization := range data.Tokenizations {
-		// Create horizontal bar for each tokenizer
-		xPositions := make([]float64, 0)
-		yPositions := make([]float64, 0)
-		colors := make([]string, 0)
-		text := make([]string, 0)
-
-		currentPos := 0.0
-		for _, token := range tokenization.Tokens {
-			// Add start position
-			xPositions = append(xPositions, currentPos)
-			yPositions = append(yPositions, float64(i))
-			colors = append(colors, "#1f77b4")
-			text = append(text, fmt.Sprintf("Start: %s", token.Text))
-
-			// Add end position
-			currentPos += float64(len(token.Text))
-			xPositions = append(xPositions, currentPos)
-			yPositions = append(yPositions, float64(i))
-			colors = append(colors, "#ff7f0e")
-			text = append(text, fmt.Sprintf("End: %s", token.Text))
-		}
-
-		// Create scatter plot for this tokenizer
-		plot := map[string]interface{}{
-			"type": "scatter",
-			"mode": "markers",
-			"x":    xPositions,
-			"y":    yPositions,
-			"text": text,
-			"name": data.TokenizerNames[i],
-			"marker": map[string]interface{}{
-				"size":   8,
-				"color":  colors,
-				"symbol": "circle",
-			},
-			"hovertemplate": "<b>%{text}</b><br>Position: %{x}<br>Tokenizer: %{fullData.name}<extra></extra>",
-		}
-
-		plotData = append(plotData, plot)
-	}
-
-	return plotData
-}
-
 // createRollingEntropyPlotData creates data for rolling entropy visualization
 func (v *VisualizationEngine) createRollingEntropyPlotData(data RollingEntropyData) []map[string]interface{} {
 	// Create x-axis positions
@@ -157,12 +108,12 @@ func (v *VisualizationEngine) createRollingEntropyPlotData(data RollingEntropyDa
 			"y":    data.EntropyValues,
 			"name": fmt.Sprintf("%s (window=%d)", data.TokenizerName, data.WindowSize),
 			"line": map[string]interface{}{
-				"color": "#1f77b4",
+				"color": v.palette().seriesColor(0),
 				"width": 2,
 			},
 			"marker": map[string]interface{}{
 				"size":  4,
-				"color": "#1f77b4",
+				"color": v.palette().seriesColor(0),
 			},
 			"hovertemplate": "<b>%{fullData.name}</b><br>Window: %{x}<br>Entropy: %{y:.4f}<extra></extra>",
 		},
@@ -170,3 +121,51 @@ func (v *VisualizationEngine) createRollingEntropyPlotData(data RollingEntropyDa
 
 	return plotData
 }
+
+// driftPanels creates the static chart panels of the drift metrics that are present
+func driftPanels(data DriftData, palette Palette) []staticPanel {
+	var panels []staticPanel
+	for _, metric := range []struct {
+		key, title string
+		series     int
+		bars       bool
+	}{
+		{"token_count_delta", "Token Count Delta", 0, false},
+		{"entropy_delta", "Entropy Delta", 1, false},
+		{"alignment_score", "Alignment Score", 2, true},
+	} {
+		values := data.DriftMetrics[metric.key]
+		if len(data.Documents) == 0 || len(values) == 0 {
+			continue
+		}
+		panels = append(panels, staticPanel{
+			title: metric.title,
+			series: []staticSeries{{
+				name:    metric.title,
+				x:       indexSeries(len(values)),
+				y:       values,
+				color:   hexColor(palette.seriesColor(metric.series)),
+				lines:   !metric.bars,
+				markers: !metric.bars,
+				bars:    metric.bars,
+			}},
+			xLabels: data.Documents,
+		})
+	}
+	return panels
+}
+
+// rollingEntropyPanel creates the static chart panel of a rolling entropy series
+func rollingEntropyPanel(data RollingEntropyData, palette Palette) staticPanel {
+	return staticPanel{
+		title: fmt.Sprintf("%s (window=%d)", data.TokenizerName, data.WindowSize),
+		series: []staticSeries{{
+			name:    data.TokenizerName,
+			x:       indexSeries(len(data.EntropyValues)),
+			y:       data.EntropyValues,
+			color:   hexColor(palette.seriesColor(0)),
+			lines:   true,
+			markers: true,
+		}},
+	}
+}
@@ -4,14 +4,22 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"time"
 
+	"github.com/RevBooyah/TokEntropyDrift/internal/logger"
 	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
 	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+	"github.com/RevBooyah/TokEntropyDrift/internal/version"
 )
 
 // VisualizationEngine handles generation of various visualizations
 type VisualizationEngine struct {
-	config VisualizationConfig
+	config   VisualizationConfig
+	colors   Palette
+	now      func() time.Time
+	location *time.Location
+	logger   *logger.Logger
 }
 
 // VisualizationConfig holds configuration for visualization generation
@@ -21,15 +29,59 @@ type VisualizationConfig struct {
 	FileType    string `json:"file_type"`  // svg, png, html
 	Interactive bool   `json:"interactive"`
 	OutputDir   string `json:"output_dir"`
+	Timezone    string `json:"timezone"` // IANA name for generation times; empty uses local time
+
+	// OfflineAssets loads Plotly.js from a copy written next to the outputs instead of
+	// the CDN; SelfContained makes the comprehensive report a single file
+	OfflineAssets bool `json:"offline_assets"`
+	SelfContained bool `json:"self_contained"`
+
+	// DisableDataExport stops generators from writing each visualization's data next
+	// to it as <name>.data.json, and as <name>.csv for heatmaps and scatter plots
+	DisableDataExport bool `json:"disable_data_export"`
+
+	// HeatmapNormalize and HeatmapLogScale are the transforms given to the heatmaps
+	// the engine prepares itself
+	HeatmapNormalize string `json:"heatmap_normalize"` // none, row, column, zscore
+	HeatmapLogScale  bool   `json:"heatmap_log_scale"`
+
+	// Palette overrides colors of the theme's built-in palette; its empty fields keep
+	// the theme's colors
+	Palette *Palette `json:"palette,omitempty"`
 }
 
-// NewVisualizationEngine creates a new visualization engine
+// NewVisualizationEngine creates a new visualization engine. An unknown timezone falls
+// back to local time.
 func NewVisualizationEngine(config VisualizationConfig) *VisualizationEngine {
+	location, err := time.LoadLocation(config.Timezone)
+	if err != nil {
+		location = time.Local
+	}
+
 	return &VisualizationEngine{
-		config: config,
+		config:   config,
+		colors:   newPalette(config.Theme, config.Palette),
+		now:      time.Now,
+		location: location,
+		logger:   logger.Discard(),
 	}
 }
 
+// SetClock replaces the clock that generation times are read from, so that output can
+// be reproduced in tests
+func (v *VisualizationEngine) SetClock(now func() time.Time) {
+	v.now = now
+}
+
+// WithLogger returns a copy of the engine that logs the visualizations it generates
+// to l, at the visualization module's level, so a request's visualizations are logged
+// with its fields
+func (v *VisualizationEngine) WithLogger(l *logger.Logger) *VisualizationEngine {
+	engine := *v
+	engine.logger = l.ForModule(logger.ModuleVisualization)
+	return &engine
+}
+
 // GenerateHeatmap generates a heatmap visualization
 func (v *VisualizationEngine) GenerateHeatmap(data HeatmapData, vizType string) (*VisualizationResult, error) {
 	switch vizType {
@@ -42,53 +94,48 @@ func (v *VisualizationEngine) GenerateHeatmap(data HeatmapData, vizType string)
 	case "reuse":
 		return v.generateReuseHeatmap(data)
 	default:
+		if metrics.IsPluginMetric(vizType) {
+			return v.generateMetricHeatmap(data, vizType)
+		}
 		return nil, fmt.Errorf("unsupported heatmap type: %s", vizType)
 	}
 }
 
-// GenerateTokenBoundaryMap generates a token boundary visualization
+// GenerateTokenBoundaryMap generates a token boundary visualization showing the text
+// each token covers, one row per tokenizer aligned on byte offsets. Tokens whose
+// boundaries are not shared by every tokenizer are outlined. Tokenizations without
+// offsets are positioned by cumulative token length, with a warning on the plot.
 func (v *VisualizationEngine) GenerateTokenBoundaryMap(data TokenBoundaryData) (*VisualizationResult, error) {
-	// Create Plotly.js visualization
-	plotData := v.createTokenBoundaryPlotData(data)
+	rows := tokenBoundaryRows(data)
 
-	layout := map[string]interface{}{
-		"title": map[string]interface{}{
-			"text": "Token Boundary Analysis",
-			"x":    0.5,
-		},
-		"xaxis": map[string]interface{}{
-			"title":    "Character Position",
-			"showgrid": true,
-		},
-		"yaxis": map[string]interface{}{
-			"title":    "Tokenizer",
-			"showgrid": true,
-		},
-		"height":   v.getHeight(),
-		"width":    v.getWidth(),
-		"template": v.getTemplate(),
-	}
+	// Create Plotly.js visualization
+	plotData := v.createTokenBoundaryPlotData(rows)
+	layout := v.tokenBoundaryLayout(rows)
 
 	// Generate HTML
 	html := v.generatePlotlyHTML(plotData, layout, "token_boundary")
 
 	// Save to file
-	filename := fmt.Sprintf("token_boundary_%s.%s", data.DocumentID, v.config.FileType)
+	filename := fmt.Sprintf("token_boundary_%s.%s", data.DocumentID, v.fileType())
 	filepath := filepath.Join(v.config.OutputDir, filename)
 
-	if err := os.WriteFile(filepath, []byte(html), 0644); err != nil {
-		return nil, fmt.Errorf("error writing visualization file: %w", err)
+	if err := v.writeVisualization(filepath, html, v.tokenBoundaryChart(rows), v.getWidth(), v.getHeight()); err != nil {
+		return nil, err
 	}
 
-	return &VisualizationResult{
+	metadata := map[string]interface{}{
+		"document_id": data.DocumentID,
+	}
+	if estimated := estimatedRowNames(rows); len(estimated) > 0 {
+		metadata["estimated_offsets"] = estimated
+	}
+
+	return v.exportData(&VisualizationResult{
 		Type:     "token_boundary",
 		Filepath: filepath,
 		Data:     plotData,
-		Metadata: map[string]interface{}{
-			"document_id": data.DocumentID,
-			"tokenizers":  data.TokenizerNames,
-		},
-	}, nil
+		Metadata: v.generationMetadata(metadata, data.TokenizerNames, 1),
+	}, data, nil)
 }
 
 // GenerateDriftVisualization generates drift comparison visualizations
@@ -115,23 +162,35 @@ func (v *VisualizationEngine) GenerateDriftVisualization(data DriftData) (*Visua
 	html := v.generateMultiPlotHTML(plots, "drift_analysis")
 
 	// Save to file
-	filename := fmt.Sprintf("drift_analysis_%s.%s", data.ComparisonID, v.config.FileType)
+	filename := fmt.Sprintf("drift_analysis_%s.%s", data.ComparisonID, v.fileType())
 	filepath := filepath.Join(v.config.OutputDir, filename)
 
-	if err := os.WriteFile(filepath, []byte(html), 0644); err != nil {
-		return nil, fmt.Errorf("error writing visualization file: %w", err)
+	panels := driftPanels(data, v.palette())
+	chart := v.lineChart(fmt.Sprintf("Drift: %s vs %s", data.Tokenizer1, data.Tokenizer2), panels)
+	if err := v.writeVisualization(filepath, html, chart, v.getWidth(), v.getHeight()*max(len(panels), 1)); err != nil {
+		return nil, err
 	}
 
-	return &VisualizationResult{
+	return v.exportData(&VisualizationResult{
 		Type:     "drift_analysis",
 		Filepath: filepath,
 		Data:     plots,
-		Metadata: map[string]interface{}{
+		Metadata: v.generationMetadata(map[string]interface{}{
 			"comparison_id": data.ComparisonID,
 			"tokenizer1":    data.Tokenizer1,
 			"tokenizer2":    data.Tokenizer2,
-		},
-	}, nil
+		}, []string{data.Tokenizer1, data.Tokenizer2}, len(data.Documents)),
+	}, data, nil)
+}
+
+// GenerateComparisonHeatmap generates a tokenizer-by-tokenizer heatmap of a drift metric
+func (v *VisualizationEngine) GenerateComparisonHeatmap(comparison *metrics.ComparisonResult, metric string) (*VisualizationResult, error) {
+	data := v.prepareComparisonHeatmapData(comparison, metric)
+	if data == nil {
+		return nil, fmt.Errorf("comparison requires at least 2 tokenizers")
+	}
+
+	return v.generateComparisonHeatmap(*data, metric)
 }
 
 // GenerateRollingEntropyPlot generates rolling entropy visualization
@@ -161,72 +220,158 @@ func (v *VisualizationEngine) GenerateRollingEntropyPlot(data RollingEntropyData
 	html := v.generatePlotlyHTML(plotData, layout, "rolling_entropy")
 
 	// Save to file
-	filename := fmt.Sprintf("rolling_entropy_%s.%s", data.DocumentID, v.config.FileType)
+	filename := fmt.Sprintf("rolling_entropy_%s.%s", data.DocumentID, v.fileType())
 	filepath := filepath.Join(v.config.OutputDir, filename)
 
-	if err := os.WriteFile(filepath, []byte(html), 0644); err != nil {
-		return nil, fmt.Errorf("error writing visualization file: %w", err)
+	chart := v.lineChart("Rolling Entropy Analysis", []staticPanel{rollingEntropyPanel(data, v.palette())})
+	if err := v.writeVisualization(filepath, html, chart, v.getWidth(), v.getHeight()); err != nil {
+		return nil, err
 	}
 
-	return &VisualizationResult{
+	return v.exportData(&VisualizationResult{
 		Type:     "rolling_entropy",
 		Filepath: filepath,
 		Data:     plotData,
-		Metadata: map[string]interface{}{
+		Metadata: v.generationMetadata(map[string]interface{}{
 			"document_id": data.DocumentID,
 			"window_size": data.WindowSize,
-		},
-	}, nil
+		}, []string{data.TokenizerName}, 1),
+	}, data, nil)
 }
 
-// GenerateComprehensiveReport generates a comprehensive visualization report
-func (v *VisualizationEngine) GenerateComprehensiveReport(analysisResults []*metrics.AnalysisResult) (*VisualizationResult, error) {
+// GenerateComprehensiveReport generates a comprehensive visualization report: the
+// generated visualizations, plus sortable tables summarizing the results per tokenizer
+// and per document and, when tokenizers share documents, their mean pairwise drift.
+// Given vocabulary comparisons, from Engine.CompareVocabularies, it adds a table of
+// vocabulary overlap. The tokenizer, drift and vocabulary summaries are also recorded
+// in the metadata as tokenizer_summary, drift_summary and vocabulary_summary.
+func (v *VisualizationEngine) GenerateComprehensiveReport(analysisResults []*metrics.AnalysisResult, vocabularies ...*metrics.VocabularyComparison) (*VisualizationResult, error) {
+	if err := v.checkFileType(); err != nil {
+		return nil, err
+	}
+
 	// Generate multiple visualizations
 	visualizations := make([]*VisualizationResult, 0)
 
 	// Token count heatmap
-	if heatmapData := v.prepareHeatmapData(analysisResults, "token_count"); heatmapData != nil {
+	if heatmapData := v.PrepareHeatmapData(analysisResults, "token_count"); heatmapData != nil {
 		if heatmap, err := v.GenerateHeatmap(*heatmapData, "token_count"); err == nil {
 			visualizations = append(visualizations, heatmap)
 		}
 	}
 
 	// Entropy heatmap
-	if entropyData := v.prepareHeatmapData(analysisResults, "entropy"); entropyData != nil {
+	if entropyData := v.PrepareHeatmapData(analysisResults, "entropy"); entropyData != nil {
 		if entropyHeatmap, err := v.GenerateHeatmap(*entropyData, "entropy"); err == nil {
 			visualizations = append(visualizations, entropyHeatmap)
 		}
 	}
 
 	// Compression heatmap
-	if compressionData := v.prepareHeatmapData(analysisResults, "compression"); compressionData != nil {
+	if compressionData := v.PrepareHeatmapData(analysisResults, "compression"); compressionData != nil {
 		if compressionHeatmap, err := v.GenerateHeatmap(*compressionData, "compression"); err == nil {
 			visualizations = append(visualizations, compressionHeatmap)
 		}
 	}
 
+	// A heatmap for each plugin metric
+	for _, metric := range pluginMetricNames(analysisResults) {
+		if metricData := v.PrepareHeatmapData(analysisResults, metric); metricData != nil {
+			if metricHeatmap, err := v.GenerateHeatmap(*metricData, metric); err == nil {
+				visualizations = append(visualizations, metricHeatmap)
+			}
+		}
+	}
+
+	// Token length histogram, when the results carry tokenizations
+	if lengthData := NewTokenLengthHistogramData(analysisResults); len(lengthData.Names) > 0 {
+		if histogram, err := v.GenerateHistogram(lengthData); err == nil {
+			visualizations = append(visualizations, histogram)
+		}
+	}
+
+	// Metric tables, with the mean drift between tokenizers on shared documents
+	summary := NewReportSummary(metrics.NewDriftCalculator(0.5), analysisResults)
+	for _, vocabulary := range vocabularies {
+		if vocabulary != nil {
+			summary.Vocabulary = append(summary.Vocabulary, vocabulary)
+		}
+	}
+
+	tokenizerNames, documentCount := reportCoverage(analysisResults)
+	metadata := v.generationMetadata(map[string]interface{}{
+		"visualization_count": len(visualizations),
+		"analysis_results":    len(analysisResults),
+		"tokenizer_summary":   summary.Tokenizers,
+		"drift_summary":       summary.Drift,
+		"vocabulary_summary":  summary.Vocabulary,
+	}, tokenizerNames, documentCount)
+
 	// Generate report HTML
-	html := v.generateReportHTML(visualizations)
+	html, err := v.generateReportHTML(visualizations, summary, metadata)
+	if err != nil {
+		return nil, err
+	}
 
 	// Save to file
-	filename := fmt.Sprintf("comprehensive_report.%s", v.config.FileType)
+	// The report links its visualizations in frames, so it is always HTML
+	filename := "comprehensive_report.html"
 	filepath := filepath.Join(v.config.OutputDir, filename)
 
 	if err := os.WriteFile(filepath, []byte(html), 0644); err != nil {
 		return nil, fmt.Errorf("error writing report file: %w", err)
 	}
+	v.logger.LogVisualizationGenerated("comprehensive_report", filepath)
 
 	return &VisualizationResult{
 		Type:     "comprehensive_report",
 		Filepath: filepath,
 		Data:     visualizations,
-		Metadata: map[string]interface{}{
-			"visualization_count": len(visualizations),
-			"analysis_results":    len(analysisResults),
-		},
+		Metadata: metadata,
+		source:   summary,
 	}, nil
 }
 
+// generationMetadata adds to metadata when and by which version of the tool a
+// visualization was generated, and the tokenizers and number of documents it covers.
+// A document count of 0 means it is not known and is left out.
+func (v *VisualizationEngine) generationMetadata(metadata map[string]interface{}, tokenizerNames []string, documentCount int) map[string]interface{} {
+	metadata["generated_at"] = v.now().In(v.location)
+	metadata["tool_version"] = version.Version
+	metadata["tokenizers"] = tokenizerNames
+	if documentCount > 0 {
+		metadata["document_count"] = documentCount
+	}
+	return metadata
+}
+
+// formatTime formats a generation time for display in the configured timezone
+func (v *VisualizationEngine) formatTime(t time.Time) string {
+	return t.In(v.location).Format("2006-01-02 15:04:05 MST")
+}
+
+// reportCoverage returns the sorted tokenizers and the number of distinct documents in
+// a set of analysis results
+func reportCoverage(analysisResults []*metrics.AnalysisResult) ([]string, int) {
+	tokenizerSet := make(map[string]bool)
+	documents := make(map[string]bool)
+	for _, result := range analysisResults {
+		tokenizerSet[result.TokenizerName] = true
+		docKey := result.DocumentID
+		if docKey == "" {
+			docKey = result.Document
+		}
+		documents[docKey] = true
+	}
+
+	tokenizerNames := make([]string, 0, len(tokenizerSet))
+	for name := range tokenizerSet {
+		tokenizerNames = append(tokenizerNames, name)
+	}
+	sort.Strings(tokenizerNames)
+	return tokenizerNames, len(documents)
+}
+
 // Helper methods for configuration
 func (v *VisualizationEngine) getHeight() int {
 	switch v.config.ImageSize {
@@ -263,6 +408,8 @@ type VisualizationResult struct {
 	Filepath string                 `json:"filepath"`
 	Data     interface{}            `json:"data"`
 	Metadata map[string]interface{} `json:"metadata"`
+
+	source interface{} // the data the visualization was drawn from, for terminal rendering
 }
 
 // Data structures for different visualization types
@@ -272,6 +419,11 @@ type HeatmapData struct {
 	Values     [][]float64 `json:"values"`
 	ColorScale string      `json:"color_scale"`
 	Title      string      `json:"title"`
+
+	// Normalize (none, row, column or zscore) and LogScale transform the values
+	// before plotting; hover text still shows the raw values
+	Normalize string `json:"normalize,omitempty"`
+	LogScale  bool   `json:"log_scale,omitempty"`
 }
 
 type TokenBoundaryData struct {
@@ -289,6 +441,17 @@ type DriftData struct {
 	DriftMetrics map[string][]float64 `json:"drift_metrics"`
 }
 
+// NewDriftData builds drift visualization data from a corpus drift analysis
+func NewDriftData(result *metrics.CorpusDriftResult) DriftData {
+	return DriftData{
+		ComparisonID: fmt.Sprintf("%s_vs_%s", result.TokenizerA, result.TokenizerB),
+		Tokenizer1:   result.TokenizerA,
+		Tokenizer2:   result.TokenizerB,
+		Documents:    result.Documents,
+		DriftMetrics: result.Series,
+	}
+}
+
 type RollingEntropyData struct {
 	DocumentID    string    `json:"document_id"`
 	TokenizerName string    `json:"tokenizer_name"`
@@ -0,0 +1,114 @@
+package visualization
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// exportedData is the content of a visualization's data file
+type exportedData struct {
+	Type     string                 `json:"type"`
+	File     string                 `json:"file"`
+	Data     interface{}            `json:"data"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+// exportData writes the data a visualization was generated from next to its file, as
+// <name>.data.json and, when table is not nil, <name>.csv, and records their paths in
+// the result's metadata under data_file and csv_file. Nothing is written when data
+// export is disabled. The result keeps the data either way, for RenderTerminal. Every
+// generated visualization passes through here, so this is also where it is logged.
+func (v *VisualizationEngine) exportData(result *VisualizationResult, data interface{}, table [][]string) (*VisualizationResult, error) {
+	result.source = data
+	v.logger.LogVisualizationGenerated(result.Type, result.Filepath)
+	if v.config.DisableDataExport {
+		return result, nil
+	}
+
+	base := strings.TrimSuffix(result.Filepath, filepath.Ext(result.Filepath))
+	dataFile := base + ".data.json"
+	result.Metadata["data_file"] = dataFile
+	csvFile := base + ".csv"
+	if table != nil {
+		result.Metadata["csv_file"] = csvFile
+	}
+
+	content, err := json.MarshalIndent(exportedData{
+		Type:     result.Type,
+		File:     filepath.Base(result.Filepath),
+		Data:     data,
+		Metadata: result.Metadata,
+	}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error encoding visualization data: %w", err)
+	}
+	if err := os.WriteFile(dataFile, content, 0644); err != nil {
+		return nil, fmt.Errorf("error writing visualization data: %w", err)
+	}
+
+	if table != nil {
+		if err := writeCSV(csvFile, table); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// writeCSV writes rows to a CSV file
+func writeCSV(path string, rows [][]string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error writing visualization data: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("error writing visualization data: %w", err)
+	}
+	return file.Close()
+}
+
+// heatmapTable lays out heatmap values as a matrix with the x labels as the header row
+// and the y labels as the first column
+func heatmapTable(data HeatmapData) [][]string {
+	columns := len(data.XLabels)
+	for _, row := range data.Values {
+		columns = max(columns, len(row))
+	}
+
+	header := make([]string, columns+1)
+	copy(header[1:], data.XLabels)
+	table := [][]string{header}
+
+	for i, row := range data.Values {
+		record := make([]string, columns+1)
+		if i < len(data.YLabels) {
+			record[0] = data.YLabels[i]
+		}
+		for j, value := range row {
+			record[j+1] = formatValue(value)
+		}
+		table = append(table, record)
+	}
+	return table
+}
+
+// scatterTable lists scatter points with one row per document and tokenizer
+func scatterTable(data ScatterData) [][]string {
+	table := [][]string{{"document", "tokenizer", data.XMetric, data.YMetric}}
+	for _, point := range data.Points {
+		table = append(table, []string{point.Doc, point.Tokenizer, formatValue(point.X), formatValue(point.Y)})
+	}
+	return table
+}
+
+// formatValue formats a value for CSV without losing precision
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
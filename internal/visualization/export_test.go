@@ -0,0 +1,112 @@
+package visualization
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHeatmapDataExport(t *testing.T) {
+	dir := t.TempDir()
+	engine := NewVisualizationEngine(VisualizationConfig{OutputDir: dir})
+	heatmap := HeatmapData{
+		XLabels: []string{"doc, 1", "doc 2"},
+		YLabels: []string{"gpt2", "bert"},
+		Values:  [][]float64{{1, 2.5}, {3, 0.125}},
+	}
+
+	result, err := engine.GenerateHeatmap(heatmap, "token_count")
+	if err != nil {
+		t.Fatalf("GenerateHeatmap returned error: %v", err)
+	}
+
+	dataFile := filepath.Join(dir, "token_count_heatmap.data.json")
+	csvFile := filepath.Join(dir, "token_count_heatmap.csv")
+	if result.Metadata["data_file"] != dataFile || result.Metadata["csv_file"] != csvFile {
+		t.Errorf("metadata records %v and %v, want %s and %s", result.Metadata["data_file"], result.Metadata["csv_file"], dataFile, csvFile)
+	}
+
+	content, err := os.ReadFile(dataFile)
+	if err != nil {
+		t.Fatalf("failed to read data file: %v", err)
+	}
+	var exported struct {
+		Type     string                 `json:"type"`
+		File     string                 `json:"file"`
+		Data     HeatmapData            `json:"data"`
+		Metadata map[string]interface{} `json:"metadata"`
+	}
+	if err := json.Unmarshal(content, &exported); err != nil {
+		t.Fatalf("data file is not valid JSON: %v", err)
+	}
+	if exported.Type != "token_count_heatmap" || exported.File != "token_count_heatmap.html" {
+		t.Errorf("data file describes %s in %s", exported.Type, exported.File)
+	}
+	if !reflect.DeepEqual(exported.Data.Values, heatmap.Values) || !reflect.DeepEqual(exported.Data.XLabels, heatmap.XLabels) {
+		t.Errorf("exported data = %+v, want %+v", exported.Data, heatmap)
+	}
+	if _, ok := exported.Metadata["generated_at"]; !ok {
+		t.Error("data file lacks the generation metadata")
+	}
+
+	file, err := os.Open(csvFile)
+	if err != nil {
+		t.Fatalf("failed to open CSV file: %v", err)
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("CSV file is invalid: %v", err)
+	}
+	want := [][]string{
+		{"", "doc, 1", "doc 2"},
+		{"gpt2", "1", "2.5"},
+		{"bert", "3", "0.125"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("CSV = %q, want %q", records, want)
+	}
+}
+
+func TestDataExportFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		generate func(engine *VisualizationEngine) (*VisualizationResult, error)
+		csv      bool
+	}{
+		{"rolling entropy", func(engine *VisualizationEngine) (*VisualizationResult, error) {
+			return engine.GenerateRollingEntropyPlot(RollingEntropyData{DocumentID: "doc", TokenizerName: "gpt2", WindowSize: 2, EntropyValues: []float64{1, 2}})
+		}, false},
+		{"scatter plot", func(engine *VisualizationEngine) (*VisualizationResult, error) {
+			return engine.GenerateScatterPlot(ScatterData{XMetric: "entropy", YMetric: "token_count", Points: []ScatterPoint{{Doc: "a", Tokenizer: "gpt2", X: 1, Y: 2}}})
+		}, true},
+	}
+
+	for _, tt := range tests {
+		for _, disabled := range []bool{false, true} {
+			t.Run(tt.name, func(t *testing.T) {
+				engine := NewVisualizationEngine(VisualizationConfig{OutputDir: t.TempDir(), DisableDataExport: disabled})
+				result, err := tt.generate(engine)
+				if err != nil {
+					t.Fatalf("generator returned error: %v", err)
+				}
+
+				_, hasData := result.Metadata["data_file"]
+				_, hasCSV := result.Metadata["csv_file"]
+				if hasData != !disabled || hasCSV != (tt.csv && !disabled) {
+					t.Errorf("with export disabled = %v, data_file recorded = %v and csv_file recorded = %v", disabled, hasData, hasCSV)
+				}
+				for _, key := range []string{"data_file", "csv_file"} {
+					if path, ok := result.Metadata[key].(string); ok {
+						if _, err := os.Stat(path); err != nil {
+							t.Errorf("%s %s was not written: %v", key, path, err)
+						}
+					}
+				}
+			})
+		}
+	}
+}
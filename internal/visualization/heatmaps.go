@@ -2,6 +2,9 @@ package visualization
 
 import (
 	"fmt"
+	"math"
+	"sort"
+	"strings"
 
 	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
 )
@@ -9,15 +12,10 @@ import (
 // generateTokenCountHeatmap generates a heatmap showing token counts
 func (v *VisualizationEngine) generateTokenCountHeatmap(data HeatmapData) (*VisualizationResult, error) {
 	// Create Plotly.js heatmap
-	plotData := map[string]interface{}{
-		"type":       "heatmap",
-		"x":          data.XLabels,
-		"y":          data.YLabels,
-		"z":          data.Values,
-		"colorscale": "Viridis",
-		"colorbar": map[string]interface{}{
-			"title": "Token Count",
-		},
+	data.ColorScale = v.heatmapColorScale(data, "token_count")
+	plotData, plotted, err := heatmapTrace(data, "Token Count", data.ColorScale)
+	if err != nil {
+		return nil, err
 	}
 
 	layout := map[string]interface{}{
@@ -41,38 +39,36 @@ func (v *VisualizationEngine) generateTokenCountHeatmap(data HeatmapData) (*Visu
 	html := v.generatePlotlyHTML([]map[string]interface{}{plotData}, layout, "token_count_heatmap")
 
 	// Save to file
-	filename := fmt.Sprintf("token_count_heatmap.%s", v.config.FileType)
+	filename := fmt.Sprintf("token_count_heatmap.%s", v.fileType())
 	filepath := fmt.Sprintf("%s/%s", v.config.OutputDir, filename)
 
-	if err := v.saveHTML(filepath, html); err != nil {
+	chart := v.heatmapChart(plotted, "Token Count Heatmap", data.ColorScale)
+	if err := v.writeVisualization(filepath, html, chart, v.getWidth(), v.getHeight()); err != nil {
 		return nil, err
 	}
 
-	return &VisualizationResult{
+	return v.exportData(&VisualizationResult{
 		Type:     "token_count_heatmap",
 		Filepath: filepath,
 		Data:     plotData,
-		Metadata: map[string]interface{}{
+		Metadata: v.generationMetadata(map[string]interface{}{
 			"x_labels_count": len(data.XLabels),
 			"y_labels_count": len(data.YLabels),
 			"min_value":      v.getMinValue(data.Values),
 			"max_value":      v.getMaxValue(data.Values),
-		},
-	}, nil
+			"normalize":      plotted.Normalize,
+			"log_scale":      plotted.LogScale,
+		}, data.YLabels, len(data.XLabels)),
+	}, data, heatmapTable(data))
 }
 
 // generateEntropyHeatmap generates a heatmap showing entropy values
 func (v *VisualizationEngine) generateEntropyHeatmap(data HeatmapData) (*VisualizationResult, error) {
 	// Create Plotly.js heatmap
-	plotData := map[string]interface{}{
-		"type":       "heatmap",
-		"x":          data.XLabels,
-		"y":          data.YLabels,
-		"z":          data.Values,
-		"colorscale": "Plasma",
-		"colorbar": map[string]interface{}{
-			"title": "Entropy",
-		},
+	data.ColorScale = v.heatmapColorScale(data, "entropy")
+	plotData, plotted, err := heatmapTrace(data, "Entropy", data.ColorScale)
+	if err != nil {
+		return nil, err
 	}
 
 	layout := map[string]interface{}{
@@ -96,38 +92,36 @@ func (v *VisualizationEngine) generateEntropyHeatmap(data HeatmapData) (*Visuali
 	html := v.generatePlotlyHTML([]map[string]interface{}{plotData}, layout, "entropy_heatmap")
 
 	// Save to file
-	filename := fmt.Sprintf("entropy_heatmap.%s", v.config.FileType)
+	filename := fmt.Sprintf("entropy_heatmap.%s", v.fileType())
 	filepath := fmt.Sprintf("%s/%s", v.config.OutputDir, filename)
 
-	if err := v.saveHTML(filepath, html); err != nil {
+	chart := v.heatmapChart(plotted, "Entropy Heatmap", data.ColorScale)
+	if err := v.writeVisualization(filepath, html, chart, v.getWidth(), v.getHeight()); err != nil {
 		return nil, err
 	}
 
-	return &VisualizationResult{
+	return v.exportData(&VisualizationResult{
 		Type:     "entropy_heatmap",
 		Filepath: filepath,
 		Data:     plotData,
-		Metadata: map[string]interface{}{
+		Metadata: v.generationMetadata(map[string]interface{}{
 			"x_labels_count": len(data.XLabels),
 			"y_labels_count": len(data.YLabels),
 			"min_value":      v.getMinValue(data.Values),
 			"max_value":      v.getMaxValue(data.Values),
-		},
-	}, nil
+			"normalize":      plotted.Normalize,
+			"log_scale":      plotted.LogScale,
+		}, data.YLabels, len(data.XLabels)),
+	}, data, heatmapTable(data))
 }
 
 // generateCompressionHeatmap generates a heatmap showing compression ratios
 func (v *VisualizationEngine) generateCompressionHeatmap(data HeatmapData) (*VisualizationResult, error) {
 	// Create Plotly.js heatmap
-	plotData := map[string]interface{}{
-		"type":       "heatmap",
-		"x":          data.XLabels,
-		"y":          data.YLabels,
-		"z":          data.Values,
-		"colorscale": "RdYlBu_r", // Red for high compression, blue for low
-		"colorbar": map[string]interface{}{
-			"title": "Compression Ratio",
-		},
+	data.ColorScale = v.heatmapColorScale(data, "compression")
+	plotData, plotted, err := heatmapTrace(data, "Compression Ratio", data.ColorScale)
+	if err != nil {
+		return nil, err
 	}
 
 	layout := map[string]interface{}{
@@ -151,38 +145,36 @@ func (v *VisualizationEngine) generateCompressionHeatmap(data HeatmapData) (*Vis
 	html := v.generatePlotlyHTML([]map[string]interface{}{plotData}, layout, "compression_heatmap")
 
 	// Save to file
-	filename := fmt.Sprintf("compression_heatmap.%s", v.config.FileType)
+	filename := fmt.Sprintf("compression_heatmap.%s", v.fileType())
 	filepath := fmt.Sprintf("%s/%s", v.config.OutputDir, filename)
 
-	if err := v.saveHTML(filepath, html); err != nil {
+	chart := v.heatmapChart(plotted, "Compression Ratio Heatmap", data.ColorScale)
+	if err := v.writeVisualization(filepath, html, chart, v.getWidth(), v.getHeight()); err != nil {
 		return nil, err
 	}
 
-	return &VisualizationResult{
+	return v.exportData(&VisualizationResult{
 		Type:     "compression_heatmap",
 		Filepath: filepath,
 		Data:     plotData,
-		Metadata: map[string]interface{}{
+		Metadata: v.generationMetadata(map[string]interface{}{
 			"x_labels_count": len(data.XLabels),
 			"y_labels_count": len(data.YLabels),
 			"min_value":      v.getMinValue(data.Values),
 			"max_value":      v.getMaxValue(data.Values),
-		},
-	}, nil
+			"normalize":      plotted.Normalize,
+			"log_scale":      plotted.LogScale,
+		}, data.YLabels, len(data.XLabels)),
+	}, data, heatmapTable(data))
 }
 
 // generateReuseHeatmap generates a heatmap showing token reuse rates
 func (v *VisualizationEngine) generateReuseHeatmap(data HeatmapData) (*VisualizationResult, error) {
 	// Create Plotly.js heatmap
-	plotData := map[string]interface{}{
-		"type":       "heatmap",
-		"x":          data.XLabels,
-		"y":          data.YLabels,
-		"z":          data.Values,
-		"colorscale": "Greens", // Green for high reuse
-		"colorbar": map[string]interface{}{
-			"title": "Reuse Rate",
-		},
+	data.ColorScale = v.heatmapColorScale(data, "reuse")
+	plotData, plotted, err := heatmapTrace(data, "Reuse Rate", data.ColorScale)
+	if err != nil {
+		return nil, err
 	}
 
 	layout := map[string]interface{}{
@@ -206,73 +198,369 @@ func (v *VisualizationEngine) generateReuseHeatmap(data HeatmapData) (*Visualiza
 	html := v.generatePlotlyHTML([]map[string]interface{}{plotData}, layout, "reuse_heatmap")
 
 	// Save to file
-	filename := fmt.Sprintf("reuse_heatmap.%s", v.config.FileType)
+	filename := fmt.Sprintf("reuse_heatmap.%s", v.fileType())
 	filepath := fmt.Sprintf("%s/%s", v.config.OutputDir, filename)
 
-	if err := v.saveHTML(filepath, html); err != nil {
+	chart := v.heatmapChart(plotted, "Token Reuse Heatmap", data.ColorScale)
+	if err := v.writeVisualization(filepath, html, chart, v.getWidth(), v.getHeight()); err != nil {
 		return nil, err
 	}
 
-	return &VisualizationResult{
+	return v.exportData(&VisualizationResult{
 		Type:     "reuse_heatmap",
 		Filepath: filepath,
 		Data:     plotData,
-		Metadata: map[string]interface{}{
+		Metadata: v.generationMetadata(map[string]interface{}{
 			"x_labels_count": len(data.XLabels),
 			"y_labels_count": len(data.YLabels),
 			"min_value":      v.getMinValue(data.Values),
 			"max_value":      v.getMaxValue(data.Values),
+			"normalize":      plotted.Normalize,
+			"log_scale":      plotted.LogScale,
+		}, data.YLabels, len(data.XLabels)),
+	}, data, heatmapTable(data))
+}
+
+// generateMetricHeatmap generates a heatmap of any metric by name, such as a plugin metric
+func (v *VisualizationEngine) generateMetricHeatmap(data HeatmapData, metric string) (*VisualizationResult, error) {
+	// Create Plotly.js heatmap
+	data.ColorScale = v.heatmapColorScale(data, "metric")
+	plotData, plotted, err := heatmapTrace(data, metric, data.ColorScale)
+	if err != nil {
+		return nil, err
+	}
+
+	layout := map[string]interface{}{
+		"title": map[string]interface{}{
+			"text": fmt.Sprintf("%s Heatmap", metric),
+			"x":    0.5,
+		},
+		"xaxis": map[string]interface{}{
+			"title":     "Document",
+			"tickangle": -45,
+		},
+		"yaxis": map[string]interface{}{
+			"title": "Tokenizer",
 		},
-	}, nil
+		"height":   v.getHeight(),
+		"width":    v.getWidth(),
+		"template": v.getTemplate(),
+	}
+
+	// Generate HTML
+	html := v.generatePlotlyHTML([]map[string]interface{}{plotData}, layout, metric+"_heatmap")
+
+	// Save to file
+	filename := fmt.Sprintf("%s_heatmap.%s", metric, v.fileType())
+	filepath := fmt.Sprintf("%s/%s", v.config.OutputDir, filename)
+
+	chart := v.heatmapChart(plotted, fmt.Sprintf("%s Heatmap", metric), data.ColorScale)
+	if err := v.writeVisualization(filepath, html, chart, v.getWidth(), v.getHeight()); err != nil {
+		return nil, err
+	}
+
+	return v.exportData(&VisualizationResult{
+		Type:     metric + "_heatmap",
+		Filepath: filepath,
+		Data:     plotData,
+		Metadata: v.generationMetadata(map[string]interface{}{
+			"metric":         metric,
+			"x_labels_count": len(data.XLabels),
+			"y_labels_count": len(data.YLabels),
+			"min_value":      v.getMinValue(data.Values),
+			"max_value":      v.getMaxValue(data.Values),
+			"normalize":      plotted.Normalize,
+			"log_scale":      plotted.LogScale,
+		}, data.YLabels, len(data.XLabels)),
+	}, data, heatmapTable(data))
 }
 
-// prepareHeatmapData prepares data for heatmap generation from analysis results
-func (v *VisualizationEngine) prepareHeatmapData(analysisResults []*metrics.AnalysisResult, metricType string) *HeatmapData {
-	if len(analysisResults) == 0 {
-		return nil
+// Heatmap normalizations
+const (
+	NormalizeNone   = "none"
+	NormalizeRow    = "row"
+	NormalizeColumn = "column"
+	NormalizeZScore = "zscore"
+)
+
+// heatmapColorScale returns the heatmap's own color scale, or the palette's scale for
+// the heatmap type when it has none
+func (v *VisualizationEngine) heatmapColorScale(data HeatmapData, kind string) string {
+	if data.ColorScale != "" {
+		return data.ColorScale
 	}
+	return v.palette().colorScale(kind)
+}
 
-	// Group results by tokenizer and document
-	tokenizerMap := make(map[string]map[string]float64)
-	documents := make(map[string]bool)
+// heatmapTrace builds the Plotly trace of a heatmap and returns it with the data as
+// plotted, after its log scale and normalization. When the values are transformed, the
+// colorbar title says how and hover text shows the raw values.
+func heatmapTrace(data HeatmapData, colorbarTitle, colorScale string) (map[string]interface{}, HeatmapData, error) {
+	plotted, err := transformHeatmap(data)
+	if err != nil {
+		return nil, HeatmapData{}, err
+	}
 
-	for _, result := range analysisResults {
-		if _, exists := tokenizerMap[result.TokenizerName]; !exists {
-			tokenizerMap[result.TokenizerName] = make(map[string]float64)
+	trace := map[string]interface{}{
+		"type":       "heatmap",
+		"x":          data.XLabels,
+		"y":          data.YLabels,
+		"z":          plotted.Values,
+		"colorscale": colorScale,
+		"colorbar": map[string]interface{}{
+			"title": heatmapScaleTitle(colorbarTitle, plotted),
+		},
+	}
+	if plotted.LogScale || plotted.Normalize != NormalizeNone {
+		trace["customdata"] = data.Values
+		trace["hovertemplate"] = fmt.Sprintf("%%{y}<br>%%{x}<br>%s: %%{customdata}<br>plotted: %%{z:.3g}<extra></extra>", colorbarTitle)
+	}
+	return trace, plotted, nil
+}
+
+// transformHeatmap returns a copy of the data with its values transformed: first the
+// log scale, log10(1+|x|) keeping the sign, then the normalization, which scales each
+// row or column to [0, 1] or turns every value into its z-score over all values
+func transformHeatmap(data HeatmapData) (HeatmapData, error) {
+	values := make([][]float64, len(data.Values))
+	columns := 0
+	for i, row := range data.Values {
+		values[i] = append([]float64(nil), row...)
+		if data.LogScale {
+			for j, value := range values[i] {
+				values[i][j] = math.Copysign(math.Log10(1+math.Abs(value)), value)
+			}
 		}
+		columns = max(columns, len(row))
+	}
 
-		// Extract metric value
-		var value float64
-		switch metricType {
-		case "token_count":
-			value = float64(result.TokenCount)
-		case "entropy":
-			if metric, exists := result.Metrics["entropy_global_entropy"]; exists {
-				value = metric.Value
+	switch data.Normalize {
+	case "", NormalizeNone:
+		data.Normalize = NormalizeNone
+	case NormalizeRow:
+		for i := range values {
+			cells := make([]*float64, len(values[i]))
+			for j := range values[i] {
+				cells[j] = &values[i][j]
 			}
-		case "compression":
-			if metric, exists := result.Metrics["compression_compression_ratio"]; exists {
-				value = metric.Value
+			rescaleCells(cells)
+		}
+	case NormalizeColumn:
+		for j := 0; j < columns; j++ {
+			var cells []*float64
+			for i := range values {
+				if j < len(values[i]) {
+					cells = append(cells, &values[i][j])
+				}
 			}
-		case "reuse":
-			if metric, exists := result.Metrics["reuse_reuse_ratio"]; exists {
-				value = metric.Value
+			rescaleCells(cells)
+		}
+	case NormalizeZScore:
+		var cells []*float64
+		for i := range values {
+			for j := range values[i] {
+				cells = append(cells, &values[i][j])
 			}
 		}
+		standardizeCells(cells)
+	default:
+		return HeatmapData{}, fmt.Errorf("unsupported heatmap normalization: %s (use none, row, column or zscore)", data.Normalize)
+	}
+
+	data.Values = values
+	return data, nil
+}
+
+// rescaleCells scales values linearly to [0, 1]; values that are all equal become 0
+func rescaleCells(cells []*float64) {
+	if len(cells) == 0 {
+		return
+	}
+	low, high := *cells[0], *cells[0]
+	for _, cell := range cells {
+		low, high = min(low, *cell), max(high, *cell)
+	}
+	for _, cell := range cells {
+		if high > low {
+			*cell = (*cell - low) / (high - low)
+		} else {
+			*cell = 0
+		}
+	}
+}
+
+// standardizeCells replaces values by their distance from the mean in standard
+// deviations; values that are all equal become 0
+func standardizeCells(cells []*float64) {
+	if len(cells) == 0 {
+		return
+	}
+	mean := 0.0
+	for _, cell := range cells {
+		mean += *cell
+	}
+	mean /= float64(len(cells))
 
-		tokenizerMap[result.TokenizerName][result.Document] = value
-		documents[result.Document] = true
+	variance := 0.0
+	for _, cell := range cells {
+		variance += (*cell - mean) * (*cell - mean)
 	}
+	stddev := math.Sqrt(variance / float64(len(cells)))
 
-	// Create ordered lists
-	tokenizers := make([]string, 0, len(tokenizerMap))
-	for tokenizer := range tokenizerMap {
-		tokenizers = append(tokenizers, tokenizer)
+	for _, cell := range cells {
+		if stddev > 0 {
+			*cell = (*cell - mean) / stddev
+		} else {
+			*cell = 0
+		}
 	}
+}
 
-	docList := make([]string, 0, len(documents))
-	for doc := range documents {
-		docList = append(docList, doc)
+// heatmapScaleTitle describes the transform applied to a heatmap's values, e.g.
+// "log10(1 + Token Count), row-normalized"
+func heatmapScaleTitle(title string, data HeatmapData) string {
+	if data.LogScale {
+		title = fmt.Sprintf("log10(1 + %s)", title)
+	}
+	switch data.Normalize {
+	case NormalizeRow, NormalizeColumn:
+		title += fmt.Sprintf(", %s-normalized", data.Normalize)
+	case NormalizeZScore:
+		title += ", z-score"
+	}
+	return title
+}
+
+// pluginMetricNames returns the plugin metrics present in any of the results, sorted
+func pluginMetricNames(analysisResults []*metrics.AnalysisResult) []string {
+	seen := make(map[string]bool)
+	names := make([]string, 0)
+	for _, result := range analysisResults {
+		for name := range result.Metrics {
+			if metrics.IsPluginMetric(name) && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// generateComparisonHeatmap generates a heatmap of pairwise drift between tokenizers
+func (v *VisualizationEngine) generateComparisonHeatmap(data HeatmapData, metric string) (*VisualizationResult, error) {
+	// Create Plotly.js heatmap
+	data.ColorScale = v.heatmapColorScale(data, "comparison")
+	plotData, plotted, err := heatmapTrace(data, metric, data.ColorScale)
+	if err != nil {
+		return nil, err
+	}
+
+	layout := map[string]interface{}{
+		"title": map[string]interface{}{
+			"text": data.Title,
+			"x":    0.5,
+		},
+		"xaxis": map[string]interface{}{
+			"title":     "Tokenizer",
+			"tickangle": -45,
+		},
+		"yaxis": map[string]interface{}{
+			"title": "Tokenizer",
+		},
+		"height":   v.getHeight(),
+		"width":    v.getWidth(),
+		"template": v.getTemplate(),
+	}
+
+	// Generate HTML
+	html := v.generatePlotlyHTML([]map[string]interface{}{plotData}, layout, "comparison_heatmap")
+
+	// Save to file
+	filename := fmt.Sprintf("comparison_heatmap_%s.%s", fileNamePart(metric), v.fileType())
+	filepath := fmt.Sprintf("%s/%s", v.config.OutputDir, filename)
+
+	chart := v.heatmapChart(plotted, data.Title, data.ColorScale)
+	if err := v.writeVisualization(filepath, html, chart, v.getWidth(), v.getHeight()); err != nil {
+		return nil, err
+	}
+
+	return v.exportData(&VisualizationResult{
+		Type:     "comparison_heatmap",
+		Filepath: filepath,
+		Data:     plotData,
+		Metadata: v.generationMetadata(map[string]interface{}{
+			"metric":          metric,
+			"tokenizer_count": len(data.XLabels),
+			"min_value":       v.getMinValue(data.Values),
+			"max_value":       v.getMaxValue(data.Values),
+			"normalize":       plotted.Normalize,
+			"log_scale":       plotted.LogScale,
+		}, data.XLabels, 0),
+	}, data, heatmapTable(data))
+}
+
+// fileNamePart makes name safe to use within a file name, replacing every character
+// other than ASCII letters, digits, '-' and '_' with '_'
+func fileNamePart(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// prepareComparisonHeatmapData builds a tokenizer-by-tokenizer matrix of a drift metric
+func (v *VisualizationEngine) prepareComparisonHeatmapData(comparison *metrics.ComparisonResult, metric string) *HeatmapData {
+	if comparison == nil || len(comparison.Tokenizers) < 2 {
+		return nil
+	}
+
+	return &HeatmapData{
+		XLabels:   comparison.Tokenizers,
+		YLabels:   comparison.Tokenizers,
+		Values:    comparison.PairwiseMatrix(metric),
+		Title:     fmt.Sprintf("%s Comparison", metric),
+		Normalize: v.config.HeatmapNormalize,
+		LogScale:  v.config.HeatmapLogScale,
+	}
+}
+
+// PrepareHeatmapData prepares data for heatmap generation from analysis results
+func (v *VisualizationEngine) PrepareHeatmapData(analysisResults []*metrics.AnalysisResult, metricType string) *HeatmapData {
+	if len(analysisResults) == 0 {
+		return nil
+	}
+
+	// Group results by tokenizer and document, keeping first-seen order so that
+	// per-line results appear along the X axis in document order
+	tokenizerMap := make(map[string]map[string]float64)
+	tokenizers := make([]string, 0)
+	documents := make(map[string]bool)
+	docList := make([]string, 0)
+
+	for _, result := range analysisResults {
+		if _, exists := tokenizerMap[result.TokenizerName]; !exists {
+			tokenizerMap[result.TokenizerName] = make(map[string]float64)
+			tokenizers = append(tokenizers, result.TokenizerName)
+		}
+
+		// A metric missing from a result counts as zero
+		value, _ := metricValue(result, metricType)
+
+		// Prefer the document label assigned by per-line analysis over the raw text
+		docKey := result.DocumentID
+		if docKey == "" {
+			docKey = result.Document
+		}
+
+		tokenizerMap[result.TokenizerName][docKey] = value
+		if !documents[docKey] {
+			documents[docKey] = true
+			docList = append(docList, docKey)
+		}
 	}
 
 	// Create values matrix
@@ -289,13 +577,34 @@ func (v *VisualizationEngine) prepareHeatmapData(analysisResults []*metrics.Anal
 	}
 
 	return &HeatmapData{
-		XLabels: docList,
-		YLabels: tokenizers,
-		Values:  values,
-		Title:   fmt.Sprintf("%s Heatmap", metricType),
+		XLabels:   docList,
+		YLabels:   tokenizers,
+		Values:    values,
+		Title:     fmt.Sprintf("%s Heatmap", metricType),
+		Normalize: v.config.HeatmapNormalize,
+		LogScale:  v.config.HeatmapLogScale,
 	}
 }
 
+// metricValue returns the value of a metric in an analysis result. Besides metric
+// names such as plugin metrics, it accepts token_count and the entropy, compression
+// and reuse shorthands for their headline metrics.
+func metricValue(result *metrics.AnalysisResult, name string) (float64, bool) {
+	switch name {
+	case "token_count":
+		return float64(result.TokenCount), true
+	case "entropy":
+		name = "entropy_global_entropy"
+	case "compression":
+		name = "compression_compression_ratio"
+	case "reuse":
+		name = "reuse_reuse_ratio"
+	}
+
+	metric, exists := result.Metrics[name]
+	return metric.Value, exists
+}
+
 // Helper functions
 func (v *VisualizationEngine) getMinValue(values [][]float64) float64 {
 	if len(values) == 0 || len(values[0]) == 0 {
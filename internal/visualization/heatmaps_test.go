@@ -0,0 +1,164 @@
+package visualization
+
+import (
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+)
+
+func TestTransformHeatmap(t *testing.T) {
+	values := [][]float64{{1, 3}, {2, 10}}
+
+	tests := []struct {
+		name      string
+		normalize string
+		logScale  bool
+		want      [][]float64
+	}{
+		{"none", "", false, [][]float64{{1, 3}, {2, 10}}},
+		{"row", NormalizeRow, false, [][]float64{{0, 1}, {0, 1}}},
+		{"column", NormalizeColumn, false, [][]float64{{0, 0}, {1, 1}}},
+		{"zscore", NormalizeZScore, false, [][]float64{{-0.8485, -0.2828}, {-0.5657, 1.6971}}},
+		{"log", NormalizeNone, true, [][]float64{{0.3010, 0.6021}, {0.4771, 1.0414}}},
+		{"log then row", NormalizeRow, true, [][]float64{{0, 1}, {0, 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := HeatmapData{Values: values, Normalize: tt.normalize, LogScale: tt.logScale}
+			plotted, err := transformHeatmap(data)
+			if err != nil {
+				t.Fatalf("transformHeatmap returned error: %v", err)
+			}
+			for i, row := range tt.want {
+				for j, want := range row {
+					if got := plotted.Values[i][j]; math.Abs(got-want) > 1e-4 {
+						t.Errorf("value [%d][%d] = %v, want %v", i, j, got, want)
+					}
+				}
+			}
+		})
+	}
+
+	if !reflect.DeepEqual(values, [][]float64{{1, 3}, {2, 10}}) {
+		t.Errorf("transformHeatmap modified the raw values: %v", values)
+	}
+}
+
+func TestTransformHeatmapEdgeCases(t *testing.T) {
+	plotted, err := transformHeatmap(HeatmapData{Values: [][]float64{{5, 5}, {-99}}, Normalize: NormalizeRow, LogScale: true})
+	if err != nil {
+		t.Fatalf("transformHeatmap returned error: %v", err)
+	}
+	if want := [][]float64{{0, 0}, {0}}; !reflect.DeepEqual(plotted.Values, want) {
+		t.Errorf("constant and single-value rows = %v, want %v", plotted.Values, want)
+	}
+
+	plotted, err = transformHeatmap(HeatmapData{Values: [][]float64{{-99, 99}}, LogScale: true})
+	if err != nil {
+		t.Fatalf("transformHeatmap returned error: %v", err)
+	}
+	if want := [][]float64{{-2, 2}}; !reflect.DeepEqual(plotted.Values, want) {
+		t.Errorf("log scale of negative values = %v, want %v", plotted.Values, want)
+	}
+
+	if _, err := transformHeatmap(HeatmapData{Normalize: "max"}); err == nil {
+		t.Error("expected an error for an unknown normalization")
+	}
+}
+
+func TestHeatmapScaleTitle(t *testing.T) {
+	tests := []struct {
+		normalize string
+		logScale  bool
+		want      string
+	}{
+		{NormalizeNone, false, "Token Count"},
+		{NormalizeNone, true, "log10(1 + Token Count)"},
+		{NormalizeColumn, false, "Token Count, column-normalized"},
+		{NormalizeZScore, true, "log10(1 + Token Count), z-score"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			got := heatmapScaleTitle("Token Count", HeatmapData{Normalize: tt.normalize, LogScale: tt.logScale})
+			if got != tt.want {
+				t.Errorf("heatmapScaleTitle = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateHeatmapTransformKeepsRawValuesInHover(t *testing.T) {
+	engine := NewVisualizationEngine(VisualizationConfig{OutputDir: t.TempDir(), DisableDataExport: true})
+	raw := [][]float64{{10, 100}, {20, 50}}
+	data := HeatmapData{
+		XLabels:   []string{"doc1", "doc2"},
+		YLabels:   []string{"gpt2", "bert"},
+		Values:    raw,
+		Normalize: NormalizeRow,
+		LogScale:  true,
+	}
+
+	result, err := engine.GenerateHeatmap(data, "token_count")
+	if err != nil {
+		t.Fatalf("GenerateHeatmap returned error: %v", err)
+	}
+
+	trace := result.Data.(map[string]interface{})
+	if !reflect.DeepEqual(trace["customdata"], raw) {
+		t.Errorf("customdata = %v, want the raw values %v", trace["customdata"], raw)
+	}
+	if z := trace["z"].([][]float64); z[0][0] != 0 || z[0][1] != 1 {
+		t.Errorf("plotted values = %v, want each row scaled to 0-1", z)
+	}
+	title := trace["colorbar"].(map[string]interface{})["title"]
+	if title != "log10(1 + Token Count), row-normalized" {
+		t.Errorf("colorbar title = %v", title)
+	}
+	if result.Metadata["normalize"] != NormalizeRow || result.Metadata["log_scale"] != true {
+		t.Errorf("metadata records normalize %v and log_scale %v", result.Metadata["normalize"], result.Metadata["log_scale"])
+	}
+	if result.Metadata["max_value"] != 100.0 {
+		t.Errorf("max_value = %v, want the raw maximum", result.Metadata["max_value"])
+	}
+
+	data.Normalize = "median"
+	if _, err := engine.GenerateHeatmap(data, "token_count"); err == nil {
+		t.Error("expected an error for an unknown normalization")
+	}
+}
+
+func TestComparisonHeatmapFileName(t *testing.T) {
+	dir := t.TempDir()
+	engine := NewVisualizationEngine(VisualizationConfig{OutputDir: dir, DisableDataExport: true})
+	comparison := &metrics.ComparisonResult{
+		Tokenizers: []string{"a", "b"},
+		Pairs:      []metrics.TokenizerPair{{A: "a", B: "b", Drift: map[string]float64{"../../x y": 0.5}}},
+	}
+
+	result, err := engine.GenerateComparisonHeatmap(comparison, "../../x y")
+	if err != nil {
+		t.Fatalf("GenerateComparisonHeatmap returned error: %v", err)
+	}
+	if want := filepath.Join(dir, "comparison_heatmap_______x_y.html"); filepath.Clean(result.Filepath) != want {
+		t.Errorf("file = %s, want %s", result.Filepath, want)
+	}
+}
+
+func TestPreparedHeatmapsUseConfiguredTransform(t *testing.T) {
+	engine := NewVisualizationEngine(VisualizationConfig{HeatmapNormalize: NormalizeZScore, HeatmapLogScale: true})
+	data := engine.PrepareHeatmapData([]*metrics.AnalysisResult{
+		{DocumentID: "doc1", TokenizerName: "gpt2", TokenCount: 12},
+		{DocumentID: "doc1", TokenizerName: "bert", TokenCount: 15},
+	}, "token_count")
+	if data == nil {
+		t.Fatal("PrepareHeatmapData returned nil")
+	}
+	if data.Normalize != NormalizeZScore || !data.LogScale {
+		t.Errorf("prepared heatmap has normalize %q and log scale %v", data.Normalize, data.LogScale)
+	}
+}
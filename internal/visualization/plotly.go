@@ -3,21 +3,24 @@ package visualization
 import (
 	"encoding/json"
 	"fmt"
-	"os"
+	"html"
+	"strings"
+	"time"
 )
 
 // generatePlotlyHTML generates HTML with Plotly.js visualization
 func (v *VisualizationEngine) generatePlotlyHTML(data []map[string]interface{}, layout map[string]interface{}, id string) string {
 	// Convert data to JSON
 	dataJSON, _ := json.Marshal(data)
-	layoutJSON, _ := json.Marshal(layout)
+	layoutJSON, _ := json.Marshal(v.palette().themeLayout(layout))
 
 	// Generate HTML template
+	palette := v.palette()
 	html := fmt.Sprintf(`<!DOCTYPE html>
 <html>
 <head>
     <title>TokEntropyDrift Visualization</title>
-    <script src="https://cdn.plot.ly/plotly-latest.min.js"></script>
+    %s
     <style>
         body {
             font-family: Arial, sans-serif;
@@ -29,7 +32,7 @@ func (v *VisualizationEngine) generatePlotlyHTML(data []map[string]interface{},
             margin: 0 auto;
         }
         .plot-container {
-            background-color: white;
+            background-color: %s;
             border-radius: 8px;
             box-shadow: 0 2px 4px rgba(0,0,0,0.1);
             padding: 20px;
@@ -37,7 +40,7 @@ func (v *VisualizationEngine) generatePlotlyHTML(data []map[string]interface{},
         }
         .title {
             text-align: center;
-            color: #333;
+            color: %s;
             margin-bottom: 20px;
         }
     </style>
@@ -68,7 +71,7 @@ func (v *VisualizationEngine) generatePlotlyHTML(data []map[string]interface{},
         });
     </script>
 </body>
-</html>`, v.getBackgroundColor(), id, string(dataJSON), string(layoutJSON), id, id, v.getHeight(), v.getWidth())
+</html>`, v.plotlyScriptTag(), palette.Background, palette.Surface, palette.Foreground, id, string(dataJSON), string(layoutJSON), id, id, v.getHeight(), v.getWidth())
 
 	return html
 }
@@ -97,14 +100,15 @@ func (v *VisualizationEngine) generateMultiPlotHTML(plots []map[string]interface
 		"template": v.getTemplate(),
 	}
 
-	layoutJSON, _ := json.Marshal(layout)
+	layoutJSON, _ := json.Marshal(v.palette().themeLayout(layout))
 
 	// Generate HTML template
+	palette := v.palette()
 	html := fmt.Sprintf(`<!DOCTYPE html>
 <html>
 <head>
     <title>TokEntropyDrift Multi-Plot Visualization</title>
-    <script src="https://cdn.plot.ly/plotly-latest.min.js"></script>
+    %s
     <style>
         body {
             font-family: Arial, sans-serif;
@@ -116,7 +120,7 @@ func (v *VisualizationEngine) generateMultiPlotHTML(plots []map[string]interface
             margin: 0 auto;
         }
         .plot-container {
-            background-color: white;
+            background-color: %s;
             border-radius: 8px;
             box-shadow: 0 2px 4px rgba(0,0,0,0.1);
             padding: 20px;
@@ -124,7 +128,7 @@ func (v *VisualizationEngine) generateMultiPlotHTML(plots []map[string]interface
         }
         .title {
             text-align: center;
-            color: #333;
+            color: %s;
             margin-bottom: 20px;
         }
     </style>
@@ -155,37 +159,72 @@ func (v *VisualizationEngine) generateMultiPlotHTML(plots []map[string]interface
         });
     </script>
 </body>
-</html>`, v.getBackgroundColor(), id, string(plotsJSON), string(layoutJSON), id, id, v.getHeight()*rows, v.getWidth())
+</html>`, v.plotlyScriptTag(), palette.Background, palette.Surface, palette.Foreground, id, string(plotsJSON), string(layoutJSON), id, id, v.getHeight()*rows, v.getWidth())
 
 	return html
 }
 
-// generateReportHTML generates a comprehensive report HTML
-func (v *VisualizationEngine) generateReportHTML(visualizations []*VisualizationResult) string {
+// generateReportHTML generates a comprehensive report HTML, summarizing the report's
+// generation metadata and when each visualization was created, followed by the metric
+// tables. A self-contained report inlines Plotly.js and every visualization instead of
+// referring to other files.
+func (v *VisualizationEngine) generateReportHTML(visualizations []*VisualizationResult, summary ReportSummary, metadata map[string]interface{}) (string, error) {
 	// Create navigation and iframe structure
 	navItems := ""
 	iframeContent := ""
+	createdItems := ""
+	headScripts := ""
 
 	for i, viz := range visualizations {
 		navItems += fmt.Sprintf(`
             <li><a href="#viz%d" onclick="showVisualization(%d)">%s</a></li>`, i, i, viz.Type)
 
+		created := "unknown"
+		if generatedAt, ok := viz.Metadata["generated_at"].(time.Time); ok {
+			created = v.formatTime(generatedAt)
+		}
+		createdItems += fmt.Sprintf(`
+                <li><strong>%s:</strong> %s</li>`, html.EscapeString(viz.Type), created)
+
+		frame := fmt.Sprintf(`<iframe src="%s" width="100%%" height="600px" frameborder="0"></iframe>`, viz.Filepath)
+		if v.config.SelfContained {
+			inlined, err := v.inlineVisualization(viz)
+			if err != nil {
+				return "", err
+			}
+			frame = inlined
+		}
+
 		iframeContent += fmt.Sprintf(`
             <div id="viz%d" class="viz-frame" style="display: %s;">
-                <iframe src="%s" width="100%%" height="600px" frameborder="0"></iframe>
+                %s
             </div>`, i, func() string {
 			if i == 0 {
 				return "block"
 			} else {
 				return "none"
 			}
-		}(), viz.Filepath)
+		}(), frame)
 	}
 
-	html := fmt.Sprintf(`<!DOCTYPE html>
+	// Inlined HTML visualizations share one copy of Plotly.js from the report
+	if v.config.SelfContained && v.fileType() == "html" && len(visualizations) > 0 {
+		bundle, err := plotlyBundle()
+		if err != nil {
+			return "", err
+		}
+		headScripts = "\n    " + inlineScript(bundle)
+	}
+
+	generated := v.formatTime(metadata["generated_at"].(time.Time))
+	tokenizerNames, _ := metadata["tokenizers"].([]string)
+	documentCount, _ := metadata["document_count"].(int)
+
+	palette := v.palette()
+	report := fmt.Sprintf(`<!DOCTYPE html>
 <html>
 <head>
-    <title>TokEntropyDrift Comprehensive Report</title>
+    <title>TokEntropyDrift Comprehensive Report</title>%s
     <style>
         body {
             font-family: Arial, sans-serif;
@@ -194,13 +233,13 @@ func (v *VisualizationEngine) generateReportHTML(visualizations []*Visualization
             background-color: %s;
         }
         .header {
-            background-color: #2c3e50;
-            color: white;
+            background-color: %s;
+            color: %s;
             padding: 20px;
             text-align: center;
         }
         .nav {
-            background-color: #34495e;
+            background-color: %s;
             padding: 10px;
         }
         .nav ul {
@@ -215,14 +254,14 @@ func (v *VisualizationEngine) generateReportHTML(visualizations []*Visualization
             margin: 0 10px;
         }
         .nav a {
-            color: white;
+            color: %s;
             text-decoration: none;
             padding: 8px 16px;
             border-radius: 4px;
             transition: background-color 0.3s;
         }
         .nav a:hover {
-            background-color: #5a6c7d;
+            background-color: %s;
         }
         .content {
             padding: 20px;
@@ -230,19 +269,38 @@ func (v *VisualizationEngine) generateReportHTML(visualizations []*Visualization
             margin: 0 auto;
         }
         .viz-frame {
-            background-color: white;
+            background-color: %s;
             border-radius: 8px;
             box-shadow: 0 2px 4px rgba(0,0,0,0.1);
             margin: 20px 0;
             overflow: hidden;
         }
         .summary {
-            background-color: white;
+            background-color: %s;
+            color: %s;
             border-radius: 8px;
             box-shadow: 0 2px 4px rgba(0,0,0,0.1);
             padding: 20px;
             margin: 20px 0;
         }
+        .report-table {
+            border-collapse: collapse;
+            margin: 10px 0 20px;
+            font-size: 14px;
+        }
+        .report-table th, .report-table td {
+            border: 1px solid %s;
+            padding: 6px 10px;
+            text-align: left;
+        }
+        .report-table th {
+            background-color: %s;
+            cursor: pointer;
+            user-select: none;
+        }
+        .report-table td.number {
+            text-align: right;
+        }
     </style>
 </head>
 <body>
@@ -264,8 +322,14 @@ func (v *VisualizationEngine) generateReportHTML(visualizations []*Visualization
             <ul>
                 <li><strong>Total Visualizations:</strong> %d</li>
                 <li><strong>Generated:</strong> %s</li>
+                <li><strong>Tool Version:</strong> %s</li>
+                <li><strong>Tokenizers:</strong> %s</li>
+                <li><strong>Documents:</strong> %d</li>
                 <li><strong>Theme:</strong> %s</li>
             </ul>
+            <h3>Visualizations Created</h3>
+            <ul>%s
+            </ul>%s
         </div>
         
         %s
@@ -287,7 +351,36 @@ func (v *VisualizationEngine) generateReportHTML(visualizations []*Visualization
             for (var i = 0; i < navLinks.length; i++) {
                 navLinks[i].style.backgroundColor = '';
             }
-            event.target.style.backgroundColor = '#5a6c7d';
+            event.target.style.backgroundColor = '%s';
+        }
+        
+        function sortTable(id, column) {
+            // Clicking the same column again reverses the order
+            var table = document.getElementById(id);
+            var body = table.tBodies[0];
+            var ascending = table.getAttribute('data-sort-column') != column || table.getAttribute('data-sort-order') != 'asc';
+            table.setAttribute('data-sort-column', column);
+            table.setAttribute('data-sort-order', ascending ? 'asc' : 'desc');
+
+            var rows = Array.prototype.slice.call(body.rows);
+            rows.sort(function(a, b) {
+                var x = a.cells[column], y = b.cells[column];
+                var hasX = x.hasAttribute('data-value'), hasY = y.hasAttribute('data-value');
+                var result;
+                if (hasX && hasY) {
+                    var p = parseFloat(x.getAttribute('data-value')), q = parseFloat(y.getAttribute('data-value'));
+                    result = p === q ? 0 : (p < q ? -1 : 1);
+                } else if (hasX || hasY) {
+                    // Missing values sort last in either order
+                    return hasX ? -1 : 1;
+                } else {
+                    result = x.textContent.localeCompare(y.textContent);
+                }
+                return ascending ? result : -result;
+            });
+            for (var i = 0; i < rows.length; i++) {
+                body.appendChild(rows[i]);
+            }
         }
         
         function showSummary() {
@@ -305,28 +398,15 @@ func (v *VisualizationEngine) generateReportHTML(visualizations []*Visualization
             for (var i = 0; i < navLinks.length; i++) {
                 navLinks[i].style.backgroundColor = '';
             }
-            event.target.style.backgroundColor = '#5a6c7d';
+            event.target.style.backgroundColor = '%s';
         }
     </script>
 </body>
-</html>`, v.getBackgroundColor(), v.getCurrentTimestamp(), navItems, len(visualizations), len(visualizations), v.getCurrentTimestamp(), v.config.Theme, iframeContent)
-
-	return html
-}
-
-// Helper methods for HTML generation
-func (v *VisualizationEngine) getBackgroundColor() string {
-	if v.config.Theme == "dark" {
-		return "#1a1a1a"
-	}
-	return "#f5f5f5"
-}
-
-func (v *VisualizationEngine) getCurrentTimestamp() string {
-	// This would be better implemented with actual time formatting
-	return "2024-01-01 12:00:00"
-}
+</html>`, headScripts, palette.Background, palette.Header, palette.HeaderText, palette.Nav, palette.HeaderText, palette.NavActive,
+		palette.Surface, palette.Surface, palette.Foreground, palette.Grid, palette.TableHeader,
+		generated, navItems, len(visualizations), len(visualizations), generated,
+		html.EscapeString(fmt.Sprint(metadata["tool_version"])), html.EscapeString(strings.Join(tokenizerNames, ", ")), documentCount,
+		v.config.Theme, createdItems, reportTablesHTML(summary), iframeContent, palette.NavActive, palette.NavActive)
 
-func (v *VisualizationEngine) saveHTML(filepath string, html string) error {
-	return os.WriteFile(filepath, []byte(html), 0644)
+	return report, nil
 }
@@ -0,0 +1,341 @@
+package visualization
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strings"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+)
+
+// TokenizerSummary holds a tokenizer's mean metrics over the documents it analyzed.
+// Means cover only the documents the metric was computed for.
+type TokenizerSummary struct {
+	Tokenizer            string  `json:"tokenizer"`
+	Documents            int     `json:"documents"`
+	MeanTokenCount       float64 `json:"mean_token_count"`
+	MeanEntropy          float64 `json:"mean_entropy"`
+	MeanCompressionRatio float64 `json:"mean_compression_ratio"`
+}
+
+// DocumentSummary holds the metrics of one document under one tokenizer
+type DocumentSummary struct {
+	Document         string   `json:"document"`
+	Tokenizer        string   `json:"tokenizer"`
+	TokenCount       int      `json:"token_count"`
+	Entropy          *float64 `json:"entropy,omitempty"`
+	CompressionRatio *float64 `json:"compression_ratio,omitempty"`
+}
+
+// DriftSummary holds the mean drift between two tokenizers over the documents both
+// tokenized
+type DriftSummary struct {
+	A         string             `json:"a"`
+	B         string             `json:"b"`
+	Documents int                `json:"documents"`
+	Drift     map[string]float64 `json:"drift"`
+}
+
+// ReportSummary holds the tables of the comprehensive report
+type ReportSummary struct {
+	Tokenizers []TokenizerSummary `json:"tokenizers"`
+	Documents  []DocumentSummary  `json:"documents"`
+	Drift      []DriftSummary     `json:"drift,omitempty"`
+
+	// Vocabulary compares the full vocabularies of pairs of tokenizers
+	Vocabulary []*metrics.VocabularyComparison `json:"vocabulary,omitempty"`
+}
+
+// reportDriftMetrics are the drift metrics shown in the report's drift table
+var reportDriftMetrics = []struct{ name, label string }{
+	{"jaccard_distance", "Jaccard Distance"},
+	{"normalized_edit_distance", "Normalized Edit Distance"},
+	{"token_count_drift", "Token Count Drift"},
+	{"vocab_overlap", "Vocab Overlap"},
+	{"boundary_f1", "Boundary F1"},
+	{"js_divergence", "JS Divergence"},
+}
+
+// NewReportSummary summarizes analysis results per tokenizer and per document, in
+// first-seen order. When two or more tokenizers tokenized the same documents, the
+// calculator computes their drift on each shared document and the summary holds the
+// mean per pair; documents whose results carry no tokenization are left out of it.
+func NewReportSummary(calc *metrics.DriftCalculator, analysisResults []*metrics.AnalysisResult) ReportSummary {
+	type running struct {
+		documents    int
+		tokens       float64
+		entropy      meanAccumulator
+		compression  meanAccumulator
+		tokenized    []string // documents with a tokenization, in order
+		tokenization map[string]*metrics.AnalysisResult
+	}
+
+	var summary ReportSummary
+	var order []string
+	byTokenizer := make(map[string]*running)
+	for _, result := range analysisResults {
+		docKey := result.DocumentID
+		if docKey == "" {
+			docKey = result.Document
+		}
+
+		r, exists := byTokenizer[result.TokenizerName]
+		if !exists {
+			r = &running{tokenization: make(map[string]*metrics.AnalysisResult)}
+			byTokenizer[result.TokenizerName] = r
+			order = append(order, result.TokenizerName)
+		}
+		r.documents++
+		r.tokens += float64(result.TokenCount)
+
+		row := DocumentSummary{Document: docKey, Tokenizer: result.TokenizerName, TokenCount: result.TokenCount}
+		if value, ok := metricValue(result, "entropy"); ok {
+			r.entropy.add(value)
+			row.Entropy = &value
+		}
+		if value, ok := metricValue(result, "compression"); ok {
+			r.compression.add(value)
+			row.CompressionRatio = &value
+		}
+		summary.Documents = append(summary.Documents, row)
+
+		if result.Tokenization != nil {
+			if _, seen := r.tokenization[docKey]; !seen {
+				r.tokenized = append(r.tokenized, docKey)
+			}
+			r.tokenization[docKey] = result
+		}
+	}
+
+	for _, name := range order {
+		r := byTokenizer[name]
+		summary.Tokenizers = append(summary.Tokenizers, TokenizerSummary{
+			Tokenizer:            name,
+			Documents:            r.documents,
+			MeanTokenCount:       r.tokens / float64(r.documents),
+			MeanEntropy:          r.entropy.mean(),
+			MeanCompressionRatio: r.compression.mean(),
+		})
+	}
+
+	if calc == nil {
+		return summary
+	}
+	for i := 0; i < len(order); i++ {
+		for j := i + 1; j < len(order); j++ {
+			a, b := byTokenizer[order[i]], byTokenizer[order[j]]
+			sums := make(map[string]*meanAccumulator)
+			documents := 0
+			for _, doc := range a.tokenized {
+				resultA := a.tokenization[doc]
+				resultB, shared := b.tokenization[doc]
+				if !shared {
+					continue
+				}
+				drift, err := calc.CalculateCrossTokenizerDrift(resultA.Tokenization, resultB.Tokenization)
+				if err != nil {
+					continue
+				}
+				documents++
+				for metric, value := range drift {
+					if sums[metric] == nil {
+						sums[metric] = &meanAccumulator{}
+					}
+					sums[metric].add(value)
+				}
+			}
+			if documents == 0 {
+				continue
+			}
+
+			pair := DriftSummary{A: order[i], B: order[j], Documents: documents, Drift: make(map[string]float64, len(sums))}
+			for metric, sum := range sums {
+				pair.Drift[metric] = sum.mean()
+			}
+			summary.Drift = append(summary.Drift, pair)
+		}
+	}
+	return summary
+}
+
+// meanAccumulator tracks the mean of the values added to it
+type meanAccumulator struct {
+	sum   float64
+	count int
+}
+
+func (m *meanAccumulator) add(value float64) {
+	m.sum += value
+	m.count++
+}
+
+// mean returns the mean of the values added so far, or 0 when there are none
+func (m *meanAccumulator) mean() float64 {
+	if m.count == 0 {
+		return 0
+	}
+	return m.sum / float64(m.count)
+}
+
+// reportTable is one table of the report summary
+type reportTable struct {
+	id     string
+	title  string
+	header []string
+	rows   [][]reportCell
+}
+
+// reportTables lays out the summary as tables: tokenizers, drift between tokenizers
+// and vocabulary overlap when there is any, and documents
+func reportTables(summary ReportSummary) []reportTable {
+	tokenizerTable := reportTable{
+		id:     "tokenizer-summary",
+		title:  "Tokenizer Summary",
+		header: []string{"Tokenizer", "Documents", "Mean Token Count", "Mean Entropy", "Mean Compression Ratio"},
+	}
+	for _, s := range summary.Tokenizers {
+		tokenizerTable.rows = append(tokenizerTable.rows, []reportCell{
+			textCell(s.Tokenizer),
+			numberCell(float64(s.Documents)),
+			numberCell(s.MeanTokenCount),
+			numberCell(s.MeanEntropy),
+			numberCell(s.MeanCompressionRatio),
+		})
+	}
+	tables := []reportTable{tokenizerTable}
+
+	if len(summary.Drift) > 0 {
+		driftTable := reportTable{
+			id:     "drift-summary",
+			title:  "Cross-Tokenizer Drift",
+			header: []string{"Tokenizer A", "Tokenizer B", "Documents"},
+		}
+		for _, metric := range reportDriftMetrics {
+			driftTable.header = append(driftTable.header, metric.label)
+		}
+		for _, pair := range summary.Drift {
+			row := []reportCell{textCell(pair.A), textCell(pair.B), numberCell(float64(pair.Documents))}
+			for _, metric := range reportDriftMetrics {
+				if value, ok := pair.Drift[metric.name]; ok {
+					row = append(row, numberCell(value))
+				} else {
+					row = append(row, reportCell{})
+				}
+			}
+			driftTable.rows = append(driftTable.rows, row)
+		}
+		tables = append(tables, driftTable)
+	}
+
+	if len(summary.Vocabulary) > 0 {
+		vocabularyTable := reportTable{
+			id:    "vocabulary-summary",
+			title: "Vocabulary Overlap",
+			header: []string{"Tokenizer A", "Tokenizer B", "Size A", "Size B", "Shared", "Overlap Coefficient",
+				"Jaccard", "Unique to A", "Unique to B", "Mean Length A", "Mean Length B", "Length Distance"},
+		}
+		for _, v := range summary.Vocabulary {
+			vocabularyTable.rows = append(vocabularyTable.rows, []reportCell{
+				textCell(v.A),
+				textCell(v.B),
+				numberCell(float64(v.SizeA)),
+				numberCell(float64(v.SizeB)),
+				numberCell(float64(v.Shared)),
+				numberCell(v.OverlapCoefficient),
+				numberCell(v.Jaccard),
+				numberCell(float64(v.UniqueToA)),
+				numberCell(float64(v.UniqueToB)),
+				numberCell(v.MeanLengthA),
+				numberCell(v.MeanLengthB),
+				numberCell(v.LengthDistance),
+			})
+		}
+		tables = append(tables, vocabularyTable)
+	}
+
+	documentTable := reportTable{
+		id:     "document-summary",
+		title:  "Documents",
+		header: []string{"Document", "Tokenizer", "Token Count", "Entropy", "Compression Ratio"},
+	}
+	for _, d := range summary.Documents {
+		documentTable.rows = append(documentTable.rows, []reportCell{
+			textCell(truncateLabel(d.Document, 60)),
+			textCell(d.Tokenizer),
+			numberCell(float64(d.TokenCount)),
+			optionalCell(d.Entropy),
+			optionalCell(d.CompressionRatio),
+		})
+	}
+	return append(tables, documentTable)
+}
+
+// reportTablesHTML renders the summary as sortable tables
+func reportTablesHTML(summary ReportSummary) string {
+	var b strings.Builder
+	for _, table := range reportTables(summary) {
+		fmt.Fprintf(&b, `
+            <h3>%s</h3>`, html.EscapeString(table.title))
+		writeReportTable(&b, table.id, table.header, table.rows)
+	}
+	return b.String()
+}
+
+// reportCell is a table cell; numeric cells sort by value rather than by text
+type reportCell struct {
+	text    string
+	value   float64
+	numeric bool
+}
+
+func textCell(text string) reportCell {
+	return reportCell{text: text}
+}
+
+// numberCell is a cell showing whole numbers as they are and others to four decimals
+func numberCell(value float64) reportCell {
+	text := fmt.Sprintf("%.4f", value)
+	if value == math.Trunc(value) {
+		text = fmt.Sprintf("%.0f", value)
+	}
+	return reportCell{text: text, value: value, numeric: true}
+}
+
+// optionalCell is a number cell, or an empty cell for a missing metric
+func optionalCell(value *float64) reportCell {
+	if value == nil {
+		return reportCell{}
+	}
+	return numberCell(*value)
+}
+
+// writeReportTable writes a table whose columns sort when their header is clicked
+func writeReportTable(b *strings.Builder, id string, header []string, rows [][]reportCell) {
+	fmt.Fprintf(b, `
+            <table id="%s" class="report-table">
+                <thead><tr>`, id)
+	for column, label := range header {
+		fmt.Fprintf(b, `<th onclick="sortTable('%s', %d)">%s</th>`, id, column, html.EscapeString(label))
+	}
+	b.WriteString(`</tr></thead>
+                <tbody>`)
+	for _, row := range rows {
+		b.WriteString(`
+                    <tr>`)
+		for _, cell := range row {
+			switch {
+			case cell.numeric:
+				fmt.Fprintf(b, `<td class="number" data-value="%s">%s</td>`, formatValue(cell.value), html.EscapeString(cell.text))
+			case cell.text == "":
+				b.WriteString(`<td class="number">–</td>`)
+			default:
+				fmt.Fprintf(b, `<td>%s</td>`, html.EscapeString(cell.text))
+			}
+		}
+		b.WriteString(`</tr>`)
+	}
+	b.WriteString(`
+                </tbody>
+            </table>`)
+}
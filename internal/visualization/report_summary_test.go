@@ -0,0 +1,221 @@
+package visualization
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata" // the test's timezone must not depend on the system database
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+	"github.com/RevBooyah/TokEntropyDrift/internal/version"
+)
+
+// summaryResults has gpt2 and bert analyze two documents, with tokenizations for doc1
+// only and no compression metric for bert
+func summaryResults() []*metrics.AnalysisResult {
+	document := "hello world"
+	entropy := func(value float64) map[string]metrics.MetricResult {
+		return map[string]metrics.MetricResult{"entropy_global_entropy": {Value: value}}
+	}
+	return []*metrics.AnalysisResult{
+		{DocumentID: "doc1", Document: document, TokenizerName: "gpt2", TokenCount: 2, Tokenization: spanTokenization("gpt2", document, 0, 5, 11),
+			Metrics: map[string]metrics.MetricResult{"entropy_global_entropy": {Value: 1}, "compression_compression_ratio": {Value: 5.5}}},
+		{DocumentID: "doc1", Document: document, TokenizerName: "bert", TokenCount: 2, Tokenization: spanTokenization("bert", document, 0, 5, 11),
+			Metrics: entropy(1)},
+		{DocumentID: "doc2", Document: "other", TokenizerName: "gpt2", TokenCount: 4,
+			Metrics: map[string]metrics.MetricResult{"entropy_global_entropy": {Value: 2}, "compression_compression_ratio": {Value: 1.5}}},
+		{DocumentID: "doc2", Document: "other", TokenizerName: "bert", TokenCount: 6, Metrics: entropy(3)},
+	}
+}
+
+func TestNewReportSummary(t *testing.T) {
+	summary := NewReportSummary(metrics.NewDriftCalculator(0.5), summaryResults())
+
+	want := []TokenizerSummary{
+		{Tokenizer: "gpt2", Documents: 2, MeanTokenCount: 3, MeanEntropy: 1.5, MeanCompressionRatio: 3.5},
+		{Tokenizer: "bert", Documents: 2, MeanTokenCount: 4, MeanEntropy: 2, MeanCompressionRatio: 0},
+	}
+	if len(summary.Tokenizers) != len(want) {
+		t.Fatalf("got %d tokenizer summaries, want %d", len(summary.Tokenizers), len(want))
+	}
+	for i, s := range summary.Tokenizers {
+		if s != want[i] {
+			t.Errorf("tokenizer summary %d = %+v, want %+v", i, s, want[i])
+		}
+	}
+
+	if len(summary.Documents) != 4 {
+		t.Fatalf("got %d document rows, want 4", len(summary.Documents))
+	}
+	if row := summary.Documents[3]; row.Document != "doc2" || row.Tokenizer != "bert" || row.TokenCount != 6 || row.CompressionRatio != nil {
+		t.Errorf("document row = %+v, want doc2 under bert without a compression ratio", row)
+	}
+
+	// Only doc1 has tokenizations, and both tokenizers split it the same way
+	if len(summary.Drift) != 1 {
+		t.Fatalf("got %d drift summaries, want 1", len(summary.Drift))
+	}
+	drift := summary.Drift[0]
+	if drift.A != "gpt2" || drift.B != "bert" || drift.Documents != 1 {
+		t.Errorf("drift summary covers %s and %s over %d documents", drift.A, drift.B, drift.Documents)
+	}
+	if drift.Drift["jaccard_distance"] != 0 || drift.Drift["boundary_f1"] != 1 {
+		t.Errorf("identical tokenizations drift = %v", drift.Drift)
+	}
+}
+
+func TestNewReportSummaryWithoutSharedDocuments(t *testing.T) {
+	results := summaryResults()
+	summary := NewReportSummary(metrics.NewDriftCalculator(0.5), results[:1])
+	if len(summary.Tokenizers) != 1 || len(summary.Drift) != 0 {
+		t.Errorf("single tokenizer summary = %+v", summary)
+	}
+
+	// Without a calculator the drift is not computed
+	if summary := NewReportSummary(nil, results); len(summary.Drift) != 0 {
+		t.Errorf("drift computed without a calculator: %+v", summary.Drift)
+	}
+}
+
+func TestComprehensiveReportTables(t *testing.T) {
+	engine := NewVisualizationEngine(VisualizationConfig{OutputDir: t.TempDir(), DisableDataExport: true})
+	report, err := engine.GenerateComprehensiveReport(summaryResults())
+	if err != nil {
+		t.Fatalf("GenerateComprehensiveReport returned error: %v", err)
+	}
+
+	tokenizerSummary, ok := report.Metadata["tokenizer_summary"].([]TokenizerSummary)
+	if !ok || len(tokenizerSummary) != 2 || tokenizerSummary[1].MeanTokenCount != 4 {
+		t.Errorf("metadata tokenizer_summary = %v", report.Metadata["tokenizer_summary"])
+	}
+	driftSummary, ok := report.Metadata["drift_summary"].([]DriftSummary)
+	if !ok || len(driftSummary) != 1 {
+		t.Errorf("metadata drift_summary = %v", report.Metadata["drift_summary"])
+	}
+
+	content, err := os.ReadFile(report.Filepath)
+	if err != nil {
+		t.Fatalf("failed to read report: %v", err)
+	}
+	page := string(content)
+	for _, want := range []string{
+		`<table id="tokenizer-summary"`,
+		`<table id="drift-summary"`,
+		`<table id="document-summary"`,
+		`onclick="sortTable('document-summary', 2)"`,
+		`<td class="number" data-value="5.5">5.5000</td>`,
+		`<td class="number">–</td>`,
+		"function sortTable(id, column)",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("report lacks %q", want)
+		}
+	}
+}
+
+func TestComprehensiveReportVocabularyTable(t *testing.T) {
+	engine := NewVisualizationEngine(VisualizationConfig{OutputDir: t.TempDir(), DisableDataExport: true})
+	vocabulary := &metrics.VocabularyComparison{A: "gpt2", B: "bert", SizeA: 50257, SizeB: 30522, Shared: 20000, OverlapCoefficient: 0.6553}
+
+	for _, tt := range []struct {
+		name         string
+		vocabularies []*metrics.VocabularyComparison
+		want         bool
+	}{
+		{"with vocabularies", []*metrics.VocabularyComparison{vocabulary, nil}, true},
+		{"without", nil, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := engine.GenerateComprehensiveReport(summaryResults(), tt.vocabularies...)
+			if err != nil {
+				t.Fatalf("GenerateComprehensiveReport returned error: %v", err)
+			}
+			if summary, _ := report.Metadata["vocabulary_summary"].([]*metrics.VocabularyComparison); (len(summary) == 1) != tt.want {
+				t.Errorf("metadata vocabulary_summary = %v", report.Metadata["vocabulary_summary"])
+			}
+
+			content, err := os.ReadFile(report.Filepath)
+			if err != nil {
+				t.Fatalf("failed to read report: %v", err)
+			}
+			page := string(content)
+			for _, want := range []string{`<table id="vocabulary-summary"`, `<td class="number" data-value="50257">50257</td>`} {
+				if strings.Contains(page, want) != tt.want {
+					t.Errorf("report containing %q = %v, want %v", want, !tt.want, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestComprehensiveReportGenerationMetadata(t *testing.T) {
+	previousVersion := version.Version
+	version.Version = "v1.2.3"
+	defer func() { version.Version = previousVersion }()
+
+	engine := NewVisualizationEngine(VisualizationConfig{OutputDir: t.TempDir(), DisableDataExport: true, Timezone: "Asia/Tokyo"})
+	// Each reading is a minute after the last, starting at 12:00 UTC, 21:00 in Tokyo
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	readings := 0
+	engine.SetClock(func() time.Time {
+		readings++
+		return start.Add(time.Duration(readings-1) * time.Minute)
+	})
+
+	report, err := engine.GenerateComprehensiveReport(summaryResults())
+	if err != nil {
+		t.Fatalf("GenerateComprehensiveReport returned error: %v", err)
+	}
+	visualizations, ok := report.Data.([]*VisualizationResult)
+	if !ok || len(visualizations) == 0 {
+		t.Fatalf("report data = %v, want the generated visualizations", report.Data)
+	}
+	if readings != len(visualizations)+1 {
+		t.Fatalf("clock read %d times, want once per visualization and once for the report", readings)
+	}
+
+	content, err := os.ReadFile(report.Filepath)
+	if err != nil {
+		t.Fatalf("failed to read report: %v", err)
+	}
+	page := string(content)
+
+	// The report is generated last, after every visualization
+	reportTime := fmt.Sprintf("2024-03-01 21:%02d:00 JST", len(visualizations))
+	for _, want := range []string{
+		"<p>Generated on " + reportTime + "</p>",
+		"<li><strong>Generated:</strong> " + reportTime + "</li>",
+		"<li><strong>Tool Version:</strong> v1.2.3</li>",
+		"<li><strong>Tokenizers:</strong> bert, gpt2</li>",
+		"<li><strong>Documents:</strong> 2</li>",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("report lacks %q", want)
+		}
+	}
+	if got, _ := report.Metadata["generated_at"].(time.Time); !got.Equal(start.Add(time.Duration(len(visualizations))*time.Minute)) || got.Location().String() != "Asia/Tokyo" {
+		t.Errorf("report generated_at = %v, want the last clock reading in Asia/Tokyo", report.Metadata["generated_at"])
+	}
+	if report.Metadata["tool_version"] != "v1.2.3" || report.Metadata["document_count"] != 2 ||
+		!reflect.DeepEqual(report.Metadata["tokenizers"], []string{"bert", "gpt2"}) {
+		t.Errorf("report metadata = %v, want version v1.2.3, tokenizers bert and gpt2 and 2 documents", report.Metadata)
+	}
+
+	for i, viz := range visualizations {
+		created := fmt.Sprintf("2024-03-01 21:%02d:00 JST", i)
+		if want := fmt.Sprintf("<li><strong>%s:</strong> %s</li>", viz.Type, created); !strings.Contains(page, want) {
+			t.Errorf("report lacks %q", want)
+		}
+		if viz.Metadata["tool_version"] != "v1.2.3" {
+			t.Errorf("%s tool_version = %v, want v1.2.3", viz.Type, viz.Metadata["tool_version"])
+		}
+		// Heatmaps list their tokenizers in row order and cover both documents
+		if strings.HasSuffix(viz.Type, "_heatmap") && (viz.Metadata["document_count"] != 2 ||
+			!reflect.DeepEqual(viz.Metadata["tokenizers"], []string{"gpt2", "bert"})) {
+			t.Errorf("%s metadata = %v, want tokenizers gpt2 and bert and 2 documents", viz.Type, viz.Metadata)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package visualization
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// EntropySeries is one tokenizer's rolling entropy over a document
+type EntropySeries struct {
+	Name         string    `json:"name"`
+	WindowSize   int       `json:"window_size"`
+	WindowStarts []int     `json:"window_starts"` // token index where each window starts
+	Values       []float64 `json:"values"`
+}
+
+// MultiRollingEntropyData holds the rolling entropy of several tokenizations of one
+// document. Series may have different lengths, since tokenizers split the document
+// into different numbers of tokens.
+type MultiRollingEntropyData struct {
+	DocumentID string          `json:"document_id"`
+	Series     []EntropySeries `json:"series"`
+	Smoothing  int             `json:"smoothing,omitempty"` // moving average over this many windows; 0 or 1 plots the raw values
+}
+
+// NewMultiRollingEntropyData computes the rolling entropy of each tokenization of a
+// document with the calculator's window size and stride, one series per tokenizer
+func NewMultiRollingEntropyData(calc *metrics.EntropyCalculator, documentID string, results []*tokenizers.TokenizationResult) (MultiRollingEntropyData, error) {
+	data := MultiRollingEntropyData{DocumentID: documentID}
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		values, err := calc.CalculateRollingEntropy(result.Tokens)
+		if err != nil {
+			return MultiRollingEntropyData{}, fmt.Errorf("error calculating rolling entropy for %s: %w", result.Tokenizer, err)
+		}
+
+		windowSize, stride := calc.RollingWindow(len(result.Tokens))
+		starts := make([]int, len(values))
+		for i := range starts {
+			starts[i] = i * stride
+		}
+		data.Series = append(data.Series, EntropySeries{
+			Name:         result.Tokenizer,
+			WindowSize:   windowSize,
+			WindowStarts: starts,
+			Values:       values,
+		})
+	}
+	return data, nil
+}
+
+// GenerateMultiRollingEntropyPlot overlays the rolling entropy of several tokenizers on
+// one plot, with the window start token index as the shared x axis; series without
+// window starts are plotted against their window number. With smoothing, each series
+// is drawn as its moving average over the raw values, which stay visible as faint lines.
+func (v *VisualizationEngine) GenerateMultiRollingEntropyPlot(data MultiRollingEntropyData) (*VisualizationResult, error) {
+	if len(data.Series) == 0 {
+		return nil, fmt.Errorf("rolling entropy overlay needs at least one series")
+	}
+	series, err := withWindowStarts(data.Series)
+	if err != nil {
+		return nil, err
+	}
+	data.Series = series
+
+	title := "Rolling Entropy Comparison"
+	if data.Smoothing > 1 {
+		title += fmt.Sprintf(" (moving average over %d windows)", data.Smoothing)
+	}
+
+	var plotData []map[string]interface{}
+	names := make([]string, len(data.Series))
+	for i, series := range data.Series {
+		color := v.palette().seriesColor(i)
+		name := fmt.Sprintf("%s (window=%d)", series.Name, series.WindowSize)
+		names[i] = series.Name
+
+		if data.Smoothing > 1 {
+			plotData = append(plotData, map[string]interface{}{
+				"type":          "scatter",
+				"mode":          "lines",
+				"x":             series.WindowStarts,
+				"y":             series.Values,
+				"name":          name + " raw",
+				"legendgroup":   series.Name,
+				"showlegend":    false,
+				"opacity":       0.3,
+				"line":          map[string]interface{}{"color": color, "width": 1},
+				"hovertemplate": "<b>%{fullData.name}</b><br>Window start: %{x}<br>Entropy: %{y:.4f}<extra></extra>",
+			})
+		}
+
+		plotData = append(plotData, map[string]interface{}{
+			"type":          "scatter",
+			"mode":          "lines",
+			"x":             series.WindowStarts,
+			"y":             movingAverage(series.Values, data.Smoothing),
+			"name":          name,
+			"legendgroup":   series.Name,
+			"line":          map[string]interface{}{"color": color, "width": 2},
+			"hovertemplate": "<b>%{fullData.name}</b><br>Window start: %{x}<br>Entropy: %{y:.4f}<extra></extra>",
+		})
+	}
+
+	layout := map[string]interface{}{
+		"title": map[string]interface{}{
+			"text": title,
+			"x":    0.5,
+		},
+		"xaxis": map[string]interface{}{
+			"title":    "Window Start (token index)",
+			"showgrid": true,
+		},
+		"yaxis": map[string]interface{}{
+			"title":    "Entropy",
+			"showgrid": true,
+		},
+		"height":   v.getHeight(),
+		"width":    v.getWidth(),
+		"template": v.getTemplate(),
+	}
+
+	// Generate HTML
+	html := v.generatePlotlyHTML(plotData, layout, "rolling_entropy_overlay")
+
+	// Save to file
+	filename := fmt.Sprintf("rolling_entropy_overlay_%s.%s", data.DocumentID, v.fileType())
+	filepath := filepath.Join(v.config.OutputDir, filename)
+
+	if err := v.writeVisualization(filepath, html, v.rollingEntropyOverlayChart(title, data), v.getWidth(), v.getHeight()); err != nil {
+		return nil, err
+	}
+
+	return v.exportData(&VisualizationResult{
+		Type:     "rolling_entropy_overlay",
+		Filepath: filepath,
+		Data:     plotData,
+		Metadata: v.generationMetadata(map[string]interface{}{
+			"document_id":  data.DocumentID,
+			"series_count": len(data.Series),
+			"smoothing":    data.Smoothing,
+		}, names, 1),
+	}, data, nil)
+}
+
+// withWindowStarts returns a copy of the series where those without window starts are
+// placed at their window number, leaving the caller's series untouched
+func withWindowStarts(series []EntropySeries) ([]EntropySeries, error) {
+	series = append([]EntropySeries(nil), series...)
+	for i, s := range series {
+		switch {
+		case len(s.WindowStarts) == 0:
+			series[i].WindowStarts = make([]int, len(s.Values))
+			for j := range s.Values {
+				series[i].WindowStarts[j] = j
+			}
+		case len(s.WindowStarts) != len(s.Values):
+			return nil, fmt.Errorf("rolling entropy series %s has %d window starts for %d values", s.Name, len(s.WindowStarts), len(s.Values))
+		}
+	}
+	return series, nil
+}
+
+// movingAverage smooths values with a centered moving average over window values,
+// shrinking the window at the ends of the series. A window of 1 or less returns the
+// values unchanged.
+func movingAverage(values []float64, window int) []float64 {
+	if window <= 1 {
+		return values
+	}
+
+	// Prefix sums make each average a single subtraction
+	prefix := make([]float64, len(values)+1)
+	for i, value := range values {
+		prefix[i+1] = prefix[i] + value
+	}
+
+	smoothed := make([]float64, len(values))
+	for i := range values {
+		start := max(i-(window-1)/2, 0)
+		end := min(i+window/2+1, len(values))
+		smoothed[i] = (prefix[end] - prefix[start]) / float64(end-start)
+	}
+	return smoothed
+}
+
+// rollingEntropyOverlayChart draws every series on one panel with a legend
+func (v *VisualizationEngine) rollingEntropyOverlayChart(title string, data MultiRollingEntropyData) staticChart {
+	theme := v.staticTheme()
+
+	names := make([]string, len(data.Series))
+	panel := staticPanel{}
+	for i, series := range data.Series {
+		names[i] = series.Name
+		x := make([]float64, len(series.WindowStarts))
+		for j, start := range series.WindowStarts {
+			x[j] = float64(start)
+		}
+		panel.series = append(panel.series, staticSeries{
+			name:  series.Name,
+			x:     x,
+			y:     movingAverage(series.Values, data.Smoothing),
+			color: hexColor(theme.palette.seriesColor(i)),
+			lines: true,
+		})
+	}
+
+	chart := v.lineChart(title, []staticPanel{panel})
+	return func(c staticCanvas, width, height float64) {
+		chart(c, width, height)
+		drawLegend(c, theme, names, 80, 52)
+	}
+}
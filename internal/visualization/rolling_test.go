@@ -0,0 +1,115 @@
+package visualization
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+func TestNewMultiRollingEntropyData(t *testing.T) {
+	textTokens := func(tokenizer string, texts ...string) *tokenizers.TokenizationResult {
+		result := &tokenizers.TokenizationResult{Tokenizer: tokenizer}
+		for _, text := range texts {
+			result.Tokens = append(result.Tokens, tokenizers.Token{Text: text})
+		}
+		return result
+	}
+
+	calc := metrics.NewEntropyCalculator(2, false)
+	calc.SetStride(2)
+	data, err := NewMultiRollingEntropyData(calc, "doc", []*tokenizers.TokenizationResult{
+		textTokens("words", "a", "b", "a", "a", "c", "d"),
+		textTokens("chars", "a", "a", "a"),
+	})
+	if err != nil {
+		t.Fatalf("NewMultiRollingEntropyData returned error: %v", err)
+	}
+
+	if len(data.Series) != 2 {
+		t.Fatalf("got %d series, want 2", len(data.Series))
+	}
+	words, chars := data.Series[0], data.Series[1]
+	if words.Name != "words" || words.WindowSize != 2 {
+		t.Errorf("first series is %s with window %d", words.Name, words.WindowSize)
+	}
+	if want := []int{0, 2, 4}; !reflect.DeepEqual(words.WindowStarts, want) {
+		t.Errorf("window starts = %v, want %v", words.WindowStarts, want)
+	}
+	if want := []float64{1, 0, 1}; !reflect.DeepEqual(words.Values, want) {
+		t.Errorf("values = %v, want %v", words.Values, want)
+	}
+	if len(chars.Values) != 1 || len(chars.WindowStarts) != 1 {
+		t.Errorf("shorter series has %d values at %v", len(chars.Values), chars.WindowStarts)
+	}
+}
+
+func TestMovingAverage(t *testing.T) {
+	values := []float64{1, 2, 3, 4, 10}
+
+	tests := []struct {
+		window int
+		want   []float64
+	}{
+		{0, values},
+		{1, values},
+		{2, []float64{1.5, 2.5, 3.5, 7, 10}},
+		{3, []float64{1.5, 2, 3, 17.0 / 3, 7}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("window %d", tt.window), func(t *testing.T) {
+			if got := movingAverage(values, tt.window); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("movingAverage(%d) = %v, want %v", tt.window, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateMultiRollingEntropyPlot(t *testing.T) {
+	engine := NewVisualizationEngine(VisualizationConfig{OutputDir: t.TempDir(), DisableDataExport: true})
+	series := []EntropySeries{
+		{Name: "gpt2", WindowSize: 3, Values: []float64{1, 2, 3}},
+		{Name: "bert", WindowSize: 3, WindowStarts: []int{0, 4}, Values: []float64{2, 2}},
+	}
+
+	result, err := engine.GenerateMultiRollingEntropyPlot(MultiRollingEntropyData{DocumentID: "doc", Series: series, Smoothing: 3})
+	if err != nil {
+		t.Fatalf("GenerateMultiRollingEntropyPlot returned error: %v", err)
+	}
+	if !strings.HasSuffix(result.Filepath, "rolling_entropy_overlay_doc.html") {
+		t.Errorf("filepath = %s", result.Filepath)
+	}
+	if series[0].WindowStarts != nil {
+		t.Error("GenerateMultiRollingEntropyPlot modified the caller's series")
+	}
+
+	// Each series has a faint raw trace followed by its smoothed trace
+	traces := result.Data.([]map[string]interface{})
+	if len(traces) != 4 {
+		t.Fatalf("got %d traces, want 4", len(traces))
+	}
+	if traces[0]["showlegend"] != false || !reflect.DeepEqual(traces[0]["y"], []float64{1, 2, 3}) {
+		t.Errorf("raw trace = %v", traces[0])
+	}
+	if want := []float64{1.5, 2, 2.5}; !reflect.DeepEqual(traces[1]["y"], want) {
+		t.Errorf("smoothed values = %v, want %v", traces[1]["y"], want)
+	}
+	if want := []int{0, 1, 2}; !reflect.DeepEqual(traces[1]["x"], want) {
+		t.Errorf("series without window starts is plotted at %v, want %v", traces[1]["x"], want)
+	}
+	if want := []int{0, 4}; !reflect.DeepEqual(traces[3]["x"], want) {
+		t.Errorf("window starts = %v, want %v", traces[3]["x"], want)
+	}
+
+	if _, err := engine.GenerateMultiRollingEntropyPlot(MultiRollingEntropyData{DocumentID: "doc"}); err == nil {
+		t.Error("expected an error without series")
+	}
+	mismatched := []EntropySeries{{Name: "gpt2", WindowStarts: []int{0}, Values: []float64{1, 2}}}
+	if _, err := engine.GenerateMultiRollingEntropyPlot(MultiRollingEntropyData{DocumentID: "doc", Series: mismatched}); err == nil {
+		t.Error("expected an error for window starts not matching the values")
+	}
+}
@@ -0,0 +1,233 @@
+package visualization
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+)
+
+// ScatterPoint is one document's values of two metrics under one tokenizer
+type ScatterPoint struct {
+	Doc       string  `json:"doc"`
+	Tokenizer string  `json:"tokenizer"`
+	X         float64 `json:"x"`
+	Y         float64 `json:"y"`
+}
+
+// ScatterData holds the points of a scatter plot comparing two metrics across documents
+type ScatterData struct {
+	XMetric string         `json:"x_metric"`
+	YMetric string         `json:"y_metric"`
+	Points  []ScatterPoint `json:"points"`
+}
+
+// NewScatterData builds scatter plot data from analysis results, one point per result
+// that has both metrics. Metric names are looked up as in heatmaps, so token_count and
+// the entropy, compression and reuse shorthands work too. It is an error for either
+// metric to be absent from every result, or for no result to have both.
+func NewScatterData(analysisResults []*metrics.AnalysisResult, xMetric, yMetric string) (ScatterData, error) {
+	data := ScatterData{XMetric: xMetric, YMetric: yMetric}
+
+	foundX, foundY := false, false
+	for _, result := range analysisResults {
+		x, hasX := metricValue(result, xMetric)
+		y, hasY := metricValue(result, yMetric)
+		foundX, foundY = foundX || hasX, foundY || hasY
+		if !hasX || !hasY {
+			continue
+		}
+
+		doc := result.DocumentID
+		if doc == "" {
+			doc = result.Document
+		}
+		data.Points = append(data.Points, ScatterPoint{Doc: doc, Tokenizer: result.TokenizerName, X: x, Y: y})
+	}
+
+	if !foundX {
+		return ScatterData{}, fmt.Errorf("metric %s not found in the analysis results", xMetric)
+	}
+	if !foundY {
+		return ScatterData{}, fmt.Errorf("metric %s not found in the analysis results", yMetric)
+	}
+	if len(data.Points) == 0 {
+		return ScatterData{}, fmt.Errorf("no analysis results have both %s and %s", xMetric, yMetric)
+	}
+	return data, nil
+}
+
+// scatterGroup is the points of one tokenizer
+type scatterGroup struct {
+	tokenizer string
+	points    []ScatterPoint
+}
+
+// groupByTokenizer splits points by tokenizer in first-seen order
+func groupByTokenizer(points []ScatterPoint) []scatterGroup {
+	var groups []scatterGroup
+	index := make(map[string]int)
+	for _, point := range points {
+		i, exists := index[point.Tokenizer]
+		if !exists {
+			i = len(groups)
+			index[point.Tokenizer] = i
+			groups = append(groups, scatterGroup{tokenizer: point.Tokenizer})
+		}
+		groups[i].points = append(groups[i].points, point)
+	}
+	return groups
+}
+
+// trendLine fits a least-squares line to points, returning its ends at the smallest
+// and largest x. It reports false when there are fewer than two distinct x values.
+func trendLine(points []ScatterPoint) (x, y [2]float64, ok bool) {
+	n := float64(len(points))
+	var sumX, sumY, sumXY, sumXX float64
+	minX, maxX := math.Inf(1), math.Inf(-1)
+	for _, point := range points {
+		sumX += point.X
+		sumY += point.Y
+		sumXY += point.X * point.Y
+		sumXX += point.X * point.X
+		minX, maxX = math.Min(minX, point.X), math.Max(maxX, point.X)
+	}
+	denominator := n*sumXX - sumX*sumX
+	if len(points) < 2 || minX == maxX || denominator == 0 {
+		return x, y, false
+	}
+
+	slope := (n*sumXY - sumX*sumY) / denominator
+	intercept := (sumY - slope*sumX) / n
+	return [2]float64{minX, maxX}, [2]float64{intercept + slope*minX, intercept + slope*maxX}, true
+}
+
+// GenerateScatterPlot generates a scatter plot of two metrics across documents, colored
+// by tokenizer, with each point labelled by its document and a least-squares trend line
+// per tokenizer
+func (v *VisualizationEngine) GenerateScatterPlot(data ScatterData) (*VisualizationResult, error) {
+	if data.XMetric == "" || data.YMetric == "" {
+		return nil, fmt.Errorf("scatter plot needs an x and a y metric")
+	}
+	if len(data.Points) == 0 {
+		return nil, fmt.Errorf("no documents have both %s and %s", data.XMetric, data.YMetric)
+	}
+	groups := groupByTokenizer(data.Points)
+	title := fmt.Sprintf("%s vs %s", data.YMetric, data.XMetric)
+
+	// Create Plotly.js scatter plot
+	plotData := make([]map[string]interface{}, 0, 2*len(groups))
+	tokenizerNames := make([]string, len(groups))
+	for i, group := range groups {
+		tokenizerNames[i] = group.tokenizer
+		x := make([]float64, len(group.points))
+		y := make([]float64, len(group.points))
+		docs := make([]string, len(group.points))
+		for j, point := range group.points {
+			x[j], y[j], docs[j] = point.X, point.Y, point.Doc
+		}
+
+		plotData = append(plotData, map[string]interface{}{
+			"type":          "scatter",
+			"mode":          "markers",
+			"name":          group.tokenizer,
+			"legendgroup":   group.tokenizer,
+			"x":             x,
+			"y":             y,
+			"text":          docs,
+			"marker":        map[string]interface{}{"size": 9, "color": v.palette().seriesColor(i)},
+			"hovertemplate": "<b>%{text}</b><br>" + data.XMetric + ": %{x}<br>" + data.YMetric + ": %{y}<extra>%{fullData.name}</extra>",
+		})
+		if trendX, trendY, ok := trendLine(group.points); ok {
+			plotData = append(plotData, map[string]interface{}{
+				"type":        "scatter",
+				"mode":        "lines",
+				"name":        group.tokenizer + " trend",
+				"legendgroup": group.tokenizer,
+				"showlegend":  false,
+				"x":           trendX,
+				"y":           trendY,
+				"line":        map[string]interface{}{"color": v.palette().seriesColor(i), "dash": "dash"},
+				"hoverinfo":   "skip",
+			})
+		}
+	}
+
+	layout := map[string]interface{}{
+		"title": map[string]interface{}{
+			"text": title,
+			"x":    0.5,
+		},
+		"xaxis": map[string]interface{}{
+			"title":    data.XMetric,
+			"showgrid": true,
+		},
+		"yaxis": map[string]interface{}{
+			"title":    data.YMetric,
+			"showgrid": true,
+		},
+		"hovermode": "closest",
+		"height":    v.getHeight(),
+		"width":     v.getWidth(),
+		"template":  v.getTemplate(),
+	}
+
+	// Generate HTML
+	html := v.generatePlotlyHTML(plotData, layout, "scatter_plot")
+
+	// Save to file
+	filename := fmt.Sprintf("scatter_%s_vs_%s.%s", data.YMetric, data.XMetric, v.fileType())
+	filepath := filepath.Join(v.config.OutputDir, filename)
+
+	if err := v.writeVisualization(filepath, html, v.scatterChart(title, groups), v.getWidth(), v.getHeight()); err != nil {
+		return nil, err
+	}
+
+	documents := make(map[string]bool)
+	for _, point := range data.Points {
+		documents[point.Doc] = true
+	}
+
+	return v.exportData(&VisualizationResult{
+		Type:     "scatter_plot",
+		Filepath: filepath,
+		Data:     plotData,
+		Metadata: v.generationMetadata(map[string]interface{}{
+			"x_metric": data.XMetric,
+			"y_metric": data.YMetric,
+		}, tokenizerNames, len(documents)),
+	}, data, scatterTable(data))
+}
+
+// scatterChart draws the points and trend line of each tokenizer as a static chart
+func (v *VisualizationEngine) scatterChart(title string, groups []scatterGroup) staticChart {
+	theme := v.staticTheme()
+
+	names := make([]string, len(groups))
+	panel := staticPanel{}
+	for i, group := range groups {
+		names[i] = group.tokenizer
+		points := staticSeries{name: group.tokenizer, color: hexColor(theme.palette.seriesColor(i)), markers: true}
+		for _, point := range group.points {
+			points.x = append(points.x, point.X)
+			points.y = append(points.y, point.Y)
+		}
+		panel.series = append(panel.series, points)
+
+		if trendX, trendY, ok := trendLine(group.points); ok {
+			panel.series = append(panel.series, staticSeries{
+				x:     trendX[:],
+				y:     trendY[:],
+				color: hexColor(theme.palette.seriesColor(i)),
+				lines: true,
+			})
+		}
+	}
+
+	chart := v.lineChart(title, []staticPanel{panel})
+	return func(c staticCanvas, width, height float64) {
+		chart(c, width, height)
+		drawLegend(c, theme, names, 80, 52)
+	}
+}
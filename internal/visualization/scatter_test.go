@@ -0,0 +1,120 @@
+package visualization
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+)
+
+func TestNewScatterData(t *testing.T) {
+	results := []*metrics.AnalysisResult{
+		{DocumentID: "doc.txt:1", TokenizerName: "gpt2", TokenCount: 4, Metrics: map[string]metrics.MetricResult{
+			"entropy_global_entropy":        {Value: 2.5},
+			"compression_compression_ratio": {Value: 1.2},
+		}},
+		// Missing the compression ratio, so it is left out
+		{DocumentID: "doc.txt:2", TokenizerName: "gpt2", TokenCount: 6, Metrics: map[string]metrics.MetricResult{
+			"entropy_global_entropy": {Value: 3},
+		}},
+		{Document: "raw text", TokenizerName: "bert", TokenCount: 5, Metrics: map[string]metrics.MetricResult{
+			"entropy_global_entropy":        {Value: 2},
+			"compression_compression_ratio": {Value: 0.8},
+		}},
+	}
+
+	tests := []struct {
+		name    string
+		x, y    string
+		want    []ScatterPoint
+		wantErr string
+	}{
+		{
+			name: "shorthands",
+			x:    "compression",
+			y:    "entropy",
+			want: []ScatterPoint{
+				{Doc: "doc.txt:1", Tokenizer: "gpt2", X: 1.2, Y: 2.5},
+				{Doc: "raw text", Tokenizer: "bert", X: 0.8, Y: 2},
+			},
+		},
+		{
+			name: "token count and metric name",
+			x:    "token_count",
+			y:    "entropy_global_entropy",
+			want: []ScatterPoint{
+				{Doc: "doc.txt:1", Tokenizer: "gpt2", X: 4, Y: 2.5},
+				{Doc: "doc.txt:2", Tokenizer: "gpt2", X: 6, Y: 3},
+				{Doc: "raw text", Tokenizer: "bert", X: 5, Y: 2},
+			},
+		},
+		{name: "missing x metric", x: "plugin_missing", y: "entropy", wantErr: "plugin_missing"},
+		{name: "missing y metric", x: "entropy", y: "reuse", wantErr: "reuse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := NewScatterData(results, tt.x, tt.y)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("NewScatterData error = %v, want one naming %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewScatterData returned error: %v", err)
+			}
+			if !reflect.DeepEqual(data.Points, tt.want) {
+				t.Errorf("points = %+v, want %+v", data.Points, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrendLine(t *testing.T) {
+	x, y, ok := trendLine([]ScatterPoint{{X: 1, Y: 3}, {X: 2, Y: 5}, {X: 3, Y: 7}})
+	if !ok {
+		t.Fatal("trendLine found no line")
+	}
+	if x != [2]float64{1, 3} || math.Abs(y[0]-3) > 1e-9 || math.Abs(y[1]-7) > 1e-9 {
+		t.Errorf("trend line = %v %v, want from (1, 3) to (3, 7)", x, y)
+	}
+
+	if _, _, ok := trendLine([]ScatterPoint{{X: 1, Y: 1}, {X: 1, Y: 2}}); ok {
+		t.Error("trendLine fitted a line through points with the same x")
+	}
+}
+
+func TestGenerateScatterPlot(t *testing.T) {
+	engine := NewVisualizationEngine(VisualizationConfig{OutputDir: t.TempDir()})
+	result, err := engine.GenerateScatterPlot(ScatterData{XMetric: "entropy", YMetric: "compression", Points: []ScatterPoint{
+		{Doc: "a", Tokenizer: "gpt2", X: 1, Y: 2},
+		{Doc: "b", Tokenizer: "gpt2", X: 2, Y: 3},
+		{Doc: "a", Tokenizer: "bert", X: 1, Y: 1},
+	}})
+	if err != nil {
+		t.Fatalf("GenerateScatterPlot returned error: %v", err)
+	}
+
+	// Markers for each tokenizer, and a trend line only where one can be fitted
+	plots := result.Data.([]map[string]interface{})
+	var names []string
+	for _, plot := range plots {
+		names = append(names, plot["name"].(string))
+	}
+	if want := []string{"gpt2", "gpt2 trend", "bert"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("traces = %v, want %v", names, want)
+	}
+	if docs := plots[0]["text"]; !reflect.DeepEqual(docs, []string{"a", "b"}) {
+		t.Errorf("hover labels = %v, want the document labels", docs)
+	}
+	if count := result.Metadata["document_count"]; count != 2 {
+		t.Errorf("document_count = %v, want 2", count)
+	}
+
+	if _, err := engine.GenerateScatterPlot(ScatterData{XMetric: "entropy", YMetric: "compression"}); err == nil {
+		t.Error("GenerateScatterPlot accepted data without points")
+	}
+}
@@ -0,0 +1,424 @@
+package visualization
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"strings"
+)
+
+// staticCanvas is what static charts are drawn on. PNG output has no font to draw
+// with, so the PNG canvas leaves text out; SVG output keeps it.
+type staticCanvas interface {
+	rect(x, y, w, h float64, fill color.RGBA)
+	line(x1, y1, x2, y2 float64, stroke color.RGBA)
+	circle(cx, cy, r float64, fill color.RGBA)
+	text(x, y float64, s string, anchor string, size float64, fill color.RGBA)
+}
+
+// staticChart draws a chart on a canvas of the given size
+type staticChart func(c staticCanvas, width, height float64)
+
+// staticTheme holds the colors of static charts
+type staticTheme struct {
+	background color.RGBA
+	foreground color.RGBA
+	grid       color.RGBA
+	palette    Palette
+}
+
+// fileType returns the configured file type, html when unset
+func (v *VisualizationEngine) fileType() string {
+	if v.config.FileType == "" {
+		return "html"
+	}
+	return v.config.FileType
+}
+
+// staticTheme returns the static chart colors of the engine's palette
+func (v *VisualizationEngine) staticTheme() staticTheme {
+	palette := v.palette()
+	return staticTheme{
+		background: hexColor(palette.Surface),
+		foreground: hexColor(palette.Foreground),
+		grid:       hexColor(palette.Grid),
+		palette:    palette,
+	}
+}
+
+// checkFileType returns an error for a file type visualizations cannot be written in
+func (v *VisualizationEngine) checkFileType() error {
+	switch v.fileType() {
+	case "html", "svg", "png":
+		return nil
+	default:
+		return fmt.Errorf("unsupported visualization file type %q (use html, svg or png)", v.config.FileType)
+	}
+}
+
+// writeVisualization writes a visualization in the configured file type: the Plotly
+// page for html, or the static chart rendered natively for svg and png
+func (v *VisualizationEngine) writeVisualization(path string, page string, chart staticChart, width, height int) error {
+	if err := v.checkFileType(); err != nil {
+		return err
+	}
+
+	var content []byte
+	switch v.fileType() {
+	case "svg":
+		content = v.renderSVG(chart, width, height)
+	case "png":
+		rendered, err := v.renderPNG(chart, width, height)
+		if err != nil {
+			return err
+		}
+		content = rendered
+	default:
+		if v.config.OfflineAssets {
+			if err := v.writePlotlyAsset(); err != nil {
+				return err
+			}
+		}
+		content = []byte(page)
+	}
+
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("error writing visualization file: %w", err)
+	}
+	return nil
+}
+
+// renderSVG draws a chart as an SVG document
+func (v *VisualizationEngine) renderSVG(chart staticChart, width, height int) []byte {
+	canvas := &svgCanvas{}
+	fmt.Fprintf(&canvas.b, `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="Arial, sans-serif">
+`, width, height, width, height)
+	canvas.rect(0, 0, float64(width), float64(height), v.staticTheme().background)
+	chart(canvas, float64(width), float64(height))
+	canvas.b.WriteString("</svg>\n")
+	return canvas.b.Bytes()
+}
+
+// renderPNG draws a chart as a PNG image, without its text
+func (v *VisualizationEngine) renderPNG(chart staticChart, width, height int) ([]byte, error) {
+	canvas := &pngCanvas{img: image.NewRGBA(image.Rect(0, 0, width, height))}
+	canvas.rect(0, 0, float64(width), float64(height), v.staticTheme().background)
+	chart(canvas, float64(width), float64(height))
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, canvas.img); err != nil {
+		return nil, fmt.Errorf("error encoding PNG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// svgCanvas writes SVG elements
+type svgCanvas struct {
+	b bytes.Buffer
+}
+
+func (s *svgCanvas) rect(x, y, w, h float64, fill color.RGBA) {
+	fmt.Fprintf(&s.b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"/>`+"\n", x, y, w, h, svgColor(fill))
+}
+
+func (s *svgCanvas) line(x1, y1, x2, y2 float64, stroke color.RGBA) {
+	fmt.Fprintf(&s.b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s" stroke-width="2"/>`+"\n", x1, y1, x2, y2, svgColor(stroke))
+}
+
+func (s *svgCanvas) circle(cx, cy, r float64, fill color.RGBA) {
+	fmt.Fprintf(&s.b, `<circle cx="%.1f" cy="%.1f" r="%.1f" fill="%s"/>`+"\n", cx, cy, r, svgColor(fill))
+}
+
+func (s *svgCanvas) text(x, y float64, text string, anchor string, size float64, fill color.RGBA) {
+	fmt.Fprintf(&s.b, `<text x="%.1f" y="%.1f" text-anchor="%s" font-size="%.0f" fill="%s">%s</text>`+"\n",
+		x, y, anchor, size, svgColor(fill), html.EscapeString(text))
+}
+
+// pngCanvas rasterizes shapes onto an image
+type pngCanvas struct {
+	img *image.RGBA
+}
+
+func (p *pngCanvas) rect(x, y, w, h float64, fill color.RGBA) {
+	bounds := image.Rect(int(math.Round(x)), int(math.Round(y)), int(math.Round(x+w)), int(math.Round(y+h))).Intersect(p.img.Bounds())
+	for py := bounds.Min.Y; py < bounds.Max.Y; py++ {
+		for px := bounds.Min.X; px < bounds.Max.X; px++ {
+			p.img.SetRGBA(px, py, fill)
+		}
+	}
+}
+
+func (p *pngCanvas) line(x1, y1, x2, y2 float64, stroke color.RGBA) {
+	steps := int(math.Max(math.Abs(x2-x1), math.Abs(y2-y1))) + 1
+	for i := 0; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		p.rect(x1+(x2-x1)*t-1, y1+(y2-y1)*t-1, 2, 2, stroke)
+	}
+}
+
+func (p *pngCanvas) circle(cx, cy, r float64, fill color.RGBA) {
+	for py := int(cy - r); py <= int(cy+r); py++ {
+		for px := int(cx - r); px <= int(cx+r); px++ {
+			dx, dy := float64(px)+0.5-cx, float64(py)+0.5-cy
+			if dx*dx+dy*dy <= r*r && image.Pt(px, py).In(p.img.Bounds()) {
+				p.img.SetRGBA(px, py, fill)
+			}
+		}
+	}
+}
+
+func (p *pngCanvas) text(x, y float64, s string, anchor string, size float64, fill color.RGBA) {}
+
+// heatmapChart draws a grid of values with the first row at the bottom, as Plotly
+// does, with a color bar spanning the minimum and maximum value
+func (v *VisualizationEngine) heatmapChart(data HeatmapData, title, colorScale string) staticChart {
+	theme := v.staticTheme()
+	stops := colorScaleStops(colorScale)
+	minValue, maxValue := v.getMinValue(data.Values), v.getMaxValue(data.Values)
+
+	return func(c staticCanvas, width, height float64) {
+		const left, right, top, bottom = 140.0, 90.0, 50.0, 90.0
+		c.text(width/2, 30, title, "middle", 18, theme.foreground)
+
+		rows := len(data.Values)
+		if rows == 0 {
+			return
+		}
+		cols := 0
+		for _, row := range data.Values {
+			cols = max(cols, len(row))
+		}
+		if cols == 0 {
+			return
+		}
+
+		plotWidth, plotHeight := width-left-right, height-top-bottom
+		cellWidth, cellHeight := plotWidth/float64(cols), plotHeight/float64(rows)
+		for i, row := range data.Values {
+			y := top + plotHeight - float64(i+1)*cellHeight
+			for j, value := range row {
+				c.rect(left+float64(j)*cellWidth, y, cellWidth, cellHeight, interpolateColor(stops, normalize(value, minValue, maxValue)))
+			}
+			if i < len(data.YLabels) {
+				c.text(left-8, y+cellHeight/2+4, truncateLabel(data.YLabels[i], 20), "end", 12, theme.foreground)
+			}
+		}
+
+		// Label at most about 20 columns so the labels stay readable
+		every := max(1, (cols+19)/20)
+		for j := 0; j < cols && j < len(data.XLabels); j += every {
+			c.text(left+(float64(j)+0.5)*cellWidth, top+plotHeight+18, truncateLabel(data.XLabels[j], 12), "middle", 11, theme.foreground)
+		}
+
+		// Color bar
+		const steps = 50
+		barX, barWidth := width-right+20, 20.0
+		for k := 0; k < steps; k++ {
+			t := (float64(k) + 0.5) / steps
+			c.rect(barX, top+plotHeight*(1-float64(k+1)/steps), barWidth, plotHeight/steps+0.5, interpolateColor(stops, t))
+		}
+		c.text(barX+barWidth/2, top-6, formatTick(maxValue), "middle", 11, theme.foreground)
+		c.text(barX+barWidth/2, top+plotHeight+16, formatTick(minValue), "middle", 11, theme.foreground)
+	}
+}
+
+// staticSeries is one series of a static line chart
+type staticSeries struct {
+	name    string
+	x       []float64
+	y       []float64
+	color   color.RGBA
+	lines   bool
+	markers bool
+	bars    bool
+}
+
+// staticPanel is one plot of a static line chart, with optional category labels for
+// its x and y values
+type staticPanel struct {
+	title   string
+	series  []staticSeries
+	xLabels []string
+	yLabels []string
+}
+
+// lineChart draws panels stacked vertically, each with its own axes
+func (v *VisualizationEngine) lineChart(title string, panels []staticPanel) staticChart {
+	theme := v.staticTheme()
+
+	return func(c staticCanvas, width, height float64) {
+		const left, right, top, bottom, gap = 80.0, 40.0, 70.0, 40.0, 50.0
+		c.text(width/2, 30, title, "middle", 18, theme.foreground)
+		if len(panels) == 0 {
+			return
+		}
+
+		panelHeight := (height - top - bottom - gap*float64(len(panels)-1)) / float64(len(panels))
+		for p, panel := range panels {
+			x0, y0 := left, top+float64(p)*(panelHeight+gap)
+			plotWidth := width - left - right
+			drawPanel(c, theme, panel, x0, y0, plotWidth, panelHeight)
+		}
+	}
+}
+
+// drawPanel draws one panel's axes and series in the given box
+func drawPanel(c staticCanvas, theme staticTheme, panel staticPanel, x0, y0, w, h float64) {
+	minX, maxX, minY, maxY := math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)
+	for _, series := range panel.series {
+		for i := range series.y {
+			minX, maxX = math.Min(minX, series.x[i]), math.Max(maxX, series.x[i])
+			minY, maxY = math.Min(minY, series.y[i]), math.Max(maxY, series.y[i])
+		}
+		if series.bars {
+			minY, maxY = math.Min(minY, 0), math.Max(maxY, 0)
+		}
+	}
+	if math.IsInf(minX, 1) {
+		minX, maxX, minY, maxY = 0, 1, 0, 1
+	}
+	if minX == maxX {
+		minX, maxX = minX-0.5, maxX+0.5
+	}
+	if minY == maxY {
+		minY, maxY = minY-0.5, maxY+0.5
+	}
+	// Leave room around the data so markers at the edges stay inside the panel
+	padX, padY := (maxX-minX)*0.05, (maxY-minY)*0.05
+	minX, maxX, minY, maxY = minX-padX, maxX+padX, minY-padY, maxY+padY
+
+	px := func(x float64) float64 { return x0 + (x-minX)/(maxX-minX)*w }
+	py := func(y float64) float64 { return y0 + h - (y-minY)/(maxY-minY)*h }
+
+	if panel.title != "" {
+		c.text(x0+w/2, y0-8, panel.title, "middle", 13, theme.foreground)
+	}
+	c.line(x0, y0+h, x0+w, y0+h, theme.grid)
+	c.line(x0, y0, x0, y0+h, theme.grid)
+
+	if len(panel.yLabels) > 0 {
+		for i, label := range panel.yLabels {
+			c.text(x0-8, py(float64(i))+4, truncateLabel(label, 12), "end", 11, theme.foreground)
+		}
+	} else {
+		c.text(x0-8, y0+10, formatTick(maxY-padY), "end", 11, theme.foreground)
+		c.text(x0-8, y0+h, formatTick(minY+padY), "end", 11, theme.foreground)
+	}
+	if len(panel.xLabels) > 0 {
+		every := max(1, (len(panel.xLabels)+9)/10)
+		for i := 0; i < len(panel.xLabels); i += every {
+			c.text(px(float64(i)), y0+h+16, truncateLabel(panel.xLabels[i], 12), "middle", 11, theme.foreground)
+		}
+	} else {
+		c.text(x0, y0+h+16, formatTick(minX+padX), "middle", 11, theme.foreground)
+		c.text(x0+w, y0+h+16, formatTick(maxX-padX), "middle", 11, theme.foreground)
+	}
+
+	for _, series := range panel.series {
+		for i := range series.y {
+			x, y := px(series.x[i]), py(series.y[i])
+			if series.bars {
+				barWidth := math.Max(2, w/float64(len(series.y))*0.6)
+				base := py(0)
+				c.rect(x-barWidth/2, math.Min(y, base), barWidth, math.Abs(base-y), series.color)
+				continue
+			}
+			if series.lines && i > 0 {
+				c.line(px(series.x[i-1]), py(series.y[i-1]), x, y, series.color)
+			}
+			if series.markers {
+				c.circle(x, y, 3, series.color)
+			}
+		}
+	}
+}
+
+// indexSeries returns the positions 0..n-1 as x values
+func indexSeries(n int) []float64 {
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = float64(i)
+	}
+	return x
+}
+
+// colorScaleStops returns the colors of a Plotly color scale, from low to high.
+// Unknown scales use Viridis.
+func colorScaleStops(name string) []color.RGBA {
+	var hexes []string
+	switch name {
+	case "Plasma":
+		hexes = []string{"#0d0887", "#46039f", "#7201a8", "#9c179e", "#bd3786", "#d8576b", "#ed7953", "#fb9f3a", "#fdca26", "#f0f921"}
+	case "RdYlBu_r":
+		hexes = []string{"#313695", "#4575b4", "#74add1", "#abd9e9", "#e0f3f8", "#ffffbf", "#fee090", "#fdae61", "#f46d43", "#d73027", "#a50026"}
+	case "Greens":
+		hexes = []string{"#f7fcf5", "#e5f5e0", "#c7e9c0", "#a1d99b", "#74c476", "#41ab5d", "#238b45", "#006d2c", "#00441b"}
+	case "Cividis":
+		hexes = []string{"#00204c", "#00336f", "#39486b", "#575c6d", "#707173", "#8a8779", "#a69d75", "#c4b56c", "#e4cf5b", "#ffe945"}
+	case "Portland":
+		hexes = []string{"#0c3383", "#0a88ba", "#f2d338", "#f28f38", "#d91e1e"}
+	case "YlGnBu":
+		hexes = []string{"#081d58", "#253494", "#225ea8", "#1d91c0", "#41b6c4", "#7fcdbb", "#c7e9b4", "#edf8d9", "#ffffd9"}
+	default:
+		hexes = []string{"#440154", "#482878", "#3e4989", "#31688e", "#26828e", "#1f9e89", "#35b779", "#6ece58", "#b5de2b", "#fde725"}
+	}
+
+	stops := make([]color.RGBA, len(hexes))
+	for i, hex := range hexes {
+		stops[i] = hexColor(hex)
+	}
+	return stops
+}
+
+// interpolateColor returns the color at t, between 0 and 1, along evenly spaced stops
+func interpolateColor(stops []color.RGBA, t float64) color.RGBA {
+	t = math.Max(0, math.Min(1, t))
+	position := t * float64(len(stops)-1)
+	i := int(position)
+	if i >= len(stops)-1 {
+		return stops[len(stops)-1]
+	}
+	f := position - float64(i)
+	mix := func(a, b uint8) uint8 { return uint8(math.Round(float64(a) + (float64(b)-float64(a))*f)) }
+	return color.RGBA{R: mix(stops[i].R, stops[i+1].R), G: mix(stops[i].G, stops[i+1].G), B: mix(stops[i].B, stops[i+1].B), A: 255}
+}
+
+// normalize maps value into [0, 1] between low and high
+func normalize(value, low, high float64) float64 {
+	if high <= low {
+		return 0.5
+	}
+	return (value - low) / (high - low)
+}
+
+// hexColor parses a #rrggbb color
+func hexColor(hex string) color.RGBA {
+	var r, g, b uint8
+	fmt.Sscanf(strings.TrimPrefix(hex, "#"), "%02x%02x%02x", &r, &g, &b)
+	return color.RGBA{R: r, G: g, B: b, A: 255}
+}
+
+// svgColor formats a color for SVG
+func svgColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// formatTick formats an axis value compactly
+func formatTick(value float64) string {
+	return fmt.Sprintf("%.4g", value)
+}
+
+// truncateLabel shortens a label to at most n characters
+func truncateLabel(label string, n int) string {
+	runes := []rune(label)
+	if len(runes) <= n {
+		return label
+	}
+	return string(runes[:n-1]) + "…"
+}
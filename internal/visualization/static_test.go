@@ -0,0 +1,158 @@
+package visualization
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+	"github.com/RevBooyah/TokEntropyDrift/internal/tokenizers"
+)
+
+// checkFormat fails unless the file at path has the extension and content of fileType
+func checkFormat(t *testing.T, path, fileType string) {
+	t.Helper()
+	if ext := filepath.Ext(path); ext != "."+fileType {
+		t.Errorf("%s has extension %s, want .%s", path, ext, fileType)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+
+	switch fileType {
+	case "html":
+		if !bytes.HasPrefix(content, []byte("<!DOCTYPE html>")) {
+			t.Errorf("%s does not start with an HTML doctype", path)
+		}
+	case "svg":
+		if !bytes.HasPrefix(content, []byte("<?xml")) || !bytes.Contains(content, []byte("<svg ")) {
+			t.Errorf("%s is not an SVG document", path)
+		}
+		decoder := xml.NewDecoder(bytes.NewReader(content))
+		for {
+			if _, err := decoder.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Errorf("%s is not well-formed XML: %v", path, err)
+				break
+			}
+		}
+	case "png":
+		if !bytes.HasPrefix(content, []byte("\x89PNG\r\n\x1a\n")) {
+			t.Errorf("%s does not start with the PNG signature", path)
+		}
+		if _, err := png.Decode(bytes.NewReader(content)); err != nil {
+			t.Errorf("%s is not a valid PNG: %v", path, err)
+		}
+	}
+}
+
+// testGenerators generates one visualization of every kind with the engine
+func testGenerators(engine *VisualizationEngine) map[string]func() (*VisualizationResult, error) {
+	heatmap := HeatmapData{
+		XLabels: []string{"doc <1>", "doc 2", "doc 3"},
+		YLabels: []string{"gpt2", "bert"},
+		Values:  [][]float64{{1, 2, 3}, {4, 5, 6}},
+	}
+	tokenization := &tokenizers.TokenizationResult{Tokens: []tokenizers.Token{{Text: "Hello"}, {Text: " world"}}}
+
+	return map[string]func() (*VisualizationResult, error){
+		"heatmap": func() (*VisualizationResult, error) { return engine.GenerateHeatmap(heatmap, "entropy") },
+		"plugin heatmap": func() (*VisualizationResult, error) {
+			return engine.GenerateHeatmap(heatmap, metrics.PluginMetricPrefix+"demo_score")
+		},
+		"rolling entropy": func() (*VisualizationResult, error) {
+			return engine.GenerateRollingEntropyPlot(RollingEntropyData{DocumentID: "doc", TokenizerName: "gpt2", WindowSize: 2, EntropyValues: []float64{1, 1.5, 0.5}})
+		},
+		"rolling entropy overlay": func() (*VisualizationResult, error) {
+			return engine.GenerateMultiRollingEntropyPlot(MultiRollingEntropyData{DocumentID: "doc", Smoothing: 2, Series: []EntropySeries{
+				{Name: "gpt2", WindowSize: 2, WindowStarts: []int{0, 1, 2}, Values: []float64{1, 1.5, 0.5}},
+				{Name: "bert", WindowSize: 2, WindowStarts: []int{0, 1}, Values: []float64{0.8, 1.2}},
+			}})
+		},
+		"drift": func() (*VisualizationResult, error) {
+			return engine.GenerateDriftVisualization(DriftData{
+				ComparisonID: "gpt2_vs_bert",
+				Documents:    []string{"a", "b"},
+				DriftMetrics: map[string][]float64{
+					"token_count_delta": {1, -2},
+					"alignment_score":   {0.5, 0.9},
+				},
+			})
+		},
+		"histogram": func() (*VisualizationResult, error) {
+			return engine.GenerateHistogram(HistogramData{ID: "lengths", Names: []string{"gpt2", "bert"}, Values: [][]float64{{1, 2, 2, 5}, {3, 3}}})
+		},
+		"box plot": func() (*VisualizationResult, error) {
+			return engine.GenerateBoxPlot(BoxPlotData{ID: "counts", Names: []string{"gpt2", "bert"}, Values: [][]float64{{1, 2, 3, 40}, {5}}})
+		},
+		"scatter plot": func() (*VisualizationResult, error) {
+			return engine.GenerateScatterPlot(ScatterData{XMetric: "entropy", YMetric: "compression", Points: []ScatterPoint{
+				{Doc: "a", Tokenizer: "gpt2", X: 1, Y: 2}, {Doc: "b", Tokenizer: "gpt2", X: 2, Y: 3}, {Doc: "a", Tokenizer: "bert", X: 1.5, Y: 1},
+			}})
+		},
+		"token alignment": func() (*VisualizationResult, error) {
+			data, err := NewAlignmentData(metrics.NewDriftCalculator(0.5), spanTokenization("gpt2", "Hello", 0, 5), spanTokenization("bert", "Hello", 0, 3, 5), 0, 0)
+			if err != nil {
+				return nil, err
+			}
+			return engine.GenerateAlignmentDiagram(data)
+		},
+		"token boundary": func() (*VisualizationResult, error) {
+			return engine.GenerateTokenBoundaryMap(TokenBoundaryData{
+				DocumentID:     "doc",
+				TokenizerNames: []string{"gpt2"},
+				Tokenizations:  []*tokenizers.TokenizationResult{tokenization},
+			})
+		},
+	}
+}
+
+func TestVisualizationFileTypes(t *testing.T) {
+	for _, fileType := range []string{"html", "svg", "png"} {
+		t.Run(fileType, func(t *testing.T) {
+			engine := NewVisualizationEngine(VisualizationConfig{FileType: fileType, OutputDir: t.TempDir()})
+
+			generators := testGenerators(engine)
+			for name, generate := range generators {
+				result, err := generate()
+				if err != nil {
+					t.Fatalf("%s returned error: %v", name, err)
+				}
+				checkFormat(t, result.Filepath, fileType)
+			}
+
+			// The report frames the visualizations, so it is HTML whatever the file type
+			report, err := engine.GenerateComprehensiveReport([]*metrics.AnalysisResult{
+				{Document: "text", TokenizerName: "gpt2", TokenCount: 3, Metrics: map[string]metrics.MetricResult{}},
+			})
+			if err != nil {
+				t.Fatalf("GenerateComprehensiveReport returned error: %v", err)
+			}
+			checkFormat(t, report.Filepath, "html")
+		})
+	}
+}
+
+func TestVisualizationUnsupportedFileType(t *testing.T) {
+	dir := t.TempDir()
+	engine := NewVisualizationEngine(VisualizationConfig{FileType: "pdf", OutputDir: dir})
+
+	_, err := engine.GenerateHeatmap(HeatmapData{Values: [][]float64{{1}}}, "token_count")
+	if err == nil || !strings.Contains(err.Error(), `"pdf"`) {
+		t.Errorf("GenerateHeatmap error = %v, want the unsupported file type", err)
+	}
+	if _, err := engine.GenerateComprehensiveReport(nil); err == nil {
+		t.Error("GenerateComprehensiveReport accepted an unsupported file type")
+	}
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("wrote %d files for an unsupported file type", len(entries))
+	}
+}
@@ -0,0 +1,363 @@
+package visualization
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Characters of heatmap cells and sparklines, from low to high
+var (
+	terminalShades = []rune("░▒▓█")
+	sparkBlocks    = []rune("▁▂▃▄▅▆▇█")
+)
+
+const ansiReset = "\x1b[0m"
+
+// TerminalRenderer renders visualizations as text for terminals where HTML and images
+// cannot be opened: heatmaps as blocks of shades, rolling entropy as sparklines and
+// report summaries as aligned tables
+type TerminalRenderer struct {
+	color bool
+	width int
+}
+
+// NewTerminalRenderer creates a renderer for output written to w. It uses ANSI colors
+// only when w is a terminal and NO_COLOR is not set, and fits its output to $COLUMNS,
+// or 80 columns when that is not set.
+func NewTerminalRenderer(w io.Writer) *TerminalRenderer {
+	r := &TerminalRenderer{
+		color: isTerminal(w) && os.Getenv("NO_COLOR") == "",
+		width: 80,
+	}
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		r.width = columns
+	}
+	return r
+}
+
+// SetColor turns ANSI colors on or off
+func (r *TerminalRenderer) SetColor(color bool) {
+	r.color = color
+}
+
+// SetWidth sets the number of columns output is fitted to
+func (r *TerminalRenderer) SetWidth(width int) {
+	r.width = width
+}
+
+// isTerminal reports whether w writes to a character device such as a terminal
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Render renders a visualization from the data it was generated from. Heatmaps,
+// rolling entropy plots and the comprehensive report can be rendered; the report shows
+// its summary tables followed by its heatmaps and rolling entropy plots.
+func (r *TerminalRenderer) Render(result *VisualizationResult) (string, error) {
+	switch data := result.source.(type) {
+	case HeatmapData:
+		if data.Title == "" {
+			data.Title = strings.ReplaceAll(result.Type, "_", " ")
+		}
+		return r.Heatmap(data)
+	case RollingEntropyData:
+		return r.RollingEntropy(data)
+	case MultiRollingEntropyData:
+		return r.MultiRollingEntropy(data)
+	case ReportSummary:
+		sections := []string{r.Summary(data)}
+		visualizations, _ := result.Data.([]*VisualizationResult)
+		for _, viz := range visualizations {
+			if rendered, err := r.Render(viz); err == nil {
+				sections = append(sections, rendered)
+			}
+		}
+		return strings.Join(sections, "\n"), nil
+	default:
+		return "", fmt.Errorf("%s visualizations have no terminal rendering", result.Type)
+	}
+}
+
+// Heatmap renders a heatmap as one row of shaded cells per y label, after its log scale
+// and normalization. Columns are numbered, with a key to their labels underneath.
+func (r *TerminalRenderer) Heatmap(data HeatmapData) (string, error) {
+	plotted, err := transformHeatmap(data)
+	if err != nil {
+		return "", err
+	}
+
+	columns := 0
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, row := range plotted.Values {
+		columns = max(columns, len(row))
+		for _, value := range row {
+			low, high = math.Min(low, value), math.Max(high, value)
+		}
+	}
+	stops := colorScaleStops(data.ColorScale)
+
+	title := data.Title
+	if title == "" {
+		title = "Heatmap"
+	}
+	var b strings.Builder
+	b.WriteString(r.bold(title) + "\n")
+	if columns == 0 {
+		b.WriteString("(no values)\n")
+		return b.String(), nil
+	}
+
+	labelWidth := 0
+	for _, label := range data.YLabels {
+		labelWidth = max(labelWidth, utf8.RuneCountInString(label))
+	}
+	labelWidth = min(labelWidth, 20)
+
+	// Three characters per cell fit a column number above each; narrow terminals get
+	// one character per cell with every tenth column numbered
+	cellWidth := 3
+	if labelWidth+1+columns*cellWidth > r.width {
+		cellWidth = 1
+	}
+	header := []rune(strings.Repeat(" ", columns*cellWidth+2))
+	for j := 0; j < columns; j++ {
+		if cellWidth == 1 && j%10 != 0 {
+			continue
+		}
+		number := strconv.Itoa(j + 1)
+		position := j * cellWidth
+		if cellWidth > 1 {
+			position += cellWidth - len(number)
+		}
+		copy(header[position:], []rune(number))
+	}
+	b.WriteString(strings.Repeat(" ", labelWidth+1) + strings.TrimRight(string(header), " ") + "\n")
+
+	cell := func(t float64) string {
+		if r.color {
+			return r.paint(strings.Repeat("█", cellWidth), interpolateColor(stops, t))
+		}
+		shade := terminalShades[int(math.Round(t*float64(len(terminalShades)-1)))]
+		return strings.Repeat(string(shade), cellWidth)
+	}
+
+	for i, row := range plotted.Values {
+		label := ""
+		if i < len(data.YLabels) {
+			label = truncateLabel(data.YLabels[i], labelWidth)
+		}
+		b.WriteString(padRight(label, labelWidth) + " ")
+		for _, value := range row {
+			b.WriteString(cell(normalize(value, low, high)))
+		}
+		b.WriteString("\n")
+	}
+
+	scale := fmt.Sprintf("scale: %s %s … %s %s", cell(0), formatTick(low), cell(1), formatTick(high))
+	if plotted.LogScale || plotted.Normalize != NormalizeNone {
+		scale += " (" + heatmapScaleTitle("value", plotted) + ")"
+	}
+	b.WriteString(scale + "\n")
+
+	if len(data.XLabels) > 0 {
+		keys := make([]string, 0, len(data.XLabels))
+		for j, label := range data.XLabels {
+			keys = append(keys, fmt.Sprintf("%d %s", j+1, truncateLabel(label, 30)))
+		}
+		b.WriteString(wrapWords("columns: ", keys, r.width))
+	}
+	return b.String(), nil
+}
+
+// RollingEntropy renders a single rolling entropy series as a sparkline
+func (r *TerminalRenderer) RollingEntropy(data RollingEntropyData) (string, error) {
+	return r.MultiRollingEntropy(MultiRollingEntropyData{
+		DocumentID: data.DocumentID,
+		Series:     []EntropySeries{{Name: data.TokenizerName, WindowSize: data.WindowSize, Values: data.EntropyValues}},
+	})
+}
+
+// MultiRollingEntropy renders each rolling entropy series as a sparkline on a shared
+// scale, with windows placed by their start token so the series line up. Windows
+// falling into the same character are averaged.
+func (r *TerminalRenderer) MultiRollingEntropy(data MultiRollingEntropyData) (string, error) {
+	series, err := withWindowStarts(data.Series)
+	if err != nil {
+		return "", err
+	}
+
+	title := fmt.Sprintf("Rolling Entropy: %s", data.DocumentID)
+	if data.Smoothing > 1 {
+		title += fmt.Sprintf(" (moving average over %d windows)", data.Smoothing)
+	}
+	var b strings.Builder
+	b.WriteString(r.bold(title) + "\n")
+
+	span, labelWidth := 1, 0
+	low, high := math.Inf(1), math.Inf(-1)
+	smoothed := make([][]float64, len(series))
+	for i, s := range series {
+		smoothed[i] = movingAverage(s.Values, data.Smoothing)
+		for _, value := range smoothed[i] {
+			low, high = math.Min(low, value), math.Max(high, value)
+		}
+		for _, start := range s.WindowStarts {
+			span = max(span, start+1)
+		}
+		labelWidth = max(labelWidth, utf8.RuneCountInString(s.Name))
+	}
+	labelWidth = min(labelWidth, 16)
+	if math.IsInf(low, 1) {
+		b.WriteString("(no values)\n")
+		return b.String(), nil
+	}
+
+	// Leave room for the statistics after each sparkline
+	buckets := min(max(r.width-labelWidth-36, 10), span)
+	for i, s := range series {
+		sums := make([]float64, buckets)
+		counts := make([]int, buckets)
+		for k, start := range s.WindowStarts {
+			if start < 0 {
+				continue
+			}
+			bucket := start * buckets / span
+			sums[bucket] += smoothed[i][k]
+			counts[bucket]++
+		}
+
+		var line strings.Builder
+		for bucket := range sums {
+			if counts[bucket] == 0 {
+				line.WriteByte(' ')
+				continue
+			}
+			t := normalize(sums[bucket]/float64(counts[bucket]), low, high)
+			line.WriteRune(sparkBlocks[int(math.Round(t*float64(len(sparkBlocks)-1)))])
+		}
+
+		stats := "no windows"
+		if len(s.Values) > 0 {
+			minValue, maxValue, sum := math.Inf(1), math.Inf(-1), 0.0
+			for _, value := range s.Values {
+				minValue, maxValue, sum = math.Min(minValue, value), math.Max(maxValue, value), sum+value
+			}
+			stats = fmt.Sprintf("min %s  max %s  mean %s", formatTick(minValue), formatTick(maxValue), formatTick(sum/float64(len(s.Values))))
+		}
+		fmt.Fprintf(&b, "%s %s  %s\n", padRight(truncateLabel(s.Name, labelWidth), labelWidth),
+			r.paint(line.String(), hexColor(lightPalette.seriesColor(i))), stats)
+	}
+	fmt.Fprintf(&b, "x: window start token 0 … %d, y: entropy %s … %s\n", span-1, formatTick(low), formatTick(high))
+	return b.String(), nil
+}
+
+// Summary renders the report summary as aligned tables
+func (r *TerminalRenderer) Summary(summary ReportSummary) string {
+	var sections []string
+	for _, table := range reportTables(summary) {
+		sections = append(sections, r.table(table))
+	}
+	return strings.Join(sections, "\n")
+}
+
+// table renders a report table with its columns aligned, numbers to the right
+func (r *TerminalRenderer) table(table reportTable) string {
+	text := func(cell reportCell) string {
+		if cell.text == "" && !cell.numeric {
+			return "–"
+		}
+		return cell.text
+	}
+
+	widths := make([]int, len(table.header))
+	for column, label := range table.header {
+		widths[column] = utf8.RuneCountInString(label)
+	}
+	for _, row := range table.rows {
+		for column, cell := range row {
+			if column < len(widths) {
+				widths[column] = max(widths[column], utf8.RuneCountInString(text(cell)))
+			}
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(r.bold(table.title) + "\n")
+	header := make([]string, len(table.header))
+	rule := make([]string, len(table.header))
+	for column, label := range table.header {
+		header[column] = padRight(label, widths[column])
+		rule[column] = strings.Repeat("─", widths[column])
+	}
+	b.WriteString(strings.TrimRight(strings.Join(header, "  "), " ") + "\n")
+	b.WriteString(strings.Join(rule, "  ") + "\n")
+
+	for _, row := range table.rows {
+		cells := make([]string, 0, len(row))
+		for column, cell := range row {
+			if column >= len(widths) {
+				break
+			}
+			if cell.numeric || cell.text == "" {
+				cells = append(cells, padLeft(text(cell), widths[column]))
+			} else {
+				cells = append(cells, padRight(text(cell), widths[column]))
+			}
+		}
+		b.WriteString(strings.TrimRight(strings.Join(cells, "  "), " ") + "\n")
+	}
+	return b.String()
+}
+
+// paint colors text when colors are on
+func (r *TerminalRenderer) paint(text string, c color.RGBA) string {
+	if !r.color {
+		return text
+	}
+	return fmt.Sprintf("\x1b[38;2;%d;%d;%dm%s%s", c.R, c.G, c.B, text, ansiReset)
+}
+
+// bold emphasizes text when colors are on
+func (r *TerminalRenderer) bold(text string) string {
+	if !r.color {
+		return text
+	}
+	return "\x1b[1m" + text + ansiReset
+}
+
+// wrapWords joins words after a prefix, wrapping lines at width
+func wrapWords(prefix string, words []string, width int) string {
+	var b strings.Builder
+	line := prefix
+	for i, word := range words {
+		if i > 0 {
+			word = ", " + word
+		}
+		if utf8.RuneCountInString(line)+utf8.RuneCountInString(word) > width && line != prefix {
+			b.WriteString(line + ",\n")
+			line = strings.Repeat(" ", utf8.RuneCountInString(prefix)) + strings.TrimPrefix(word, ", ")
+			continue
+		}
+		line += word
+	}
+	return b.String() + line + "\n"
+}
+
+func padRight(text string, width int) string {
+	return text + strings.Repeat(" ", max(width-utf8.RuneCountInString(text), 0))
+}
+
+func padLeft(text string, width int) string {
+	return strings.Repeat(" ", max(width-utf8.RuneCountInString(text), 0)) + text
+}
@@ -0,0 +1,177 @@
+package visualization
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestTerminalHeatmap(t *testing.T) {
+	r := &TerminalRenderer{width: 80}
+	data := HeatmapData{
+		Title:   "Token Count Heatmap",
+		XLabels: []string{"doc1", "doc2", "doc3"},
+		YLabels: []string{"gpt2", "bert"},
+		Values:  [][]float64{{1, 4, 10}, {10, 7}},
+	}
+
+	out, err := r.Heatmap(data)
+	if err != nil {
+		t.Fatalf("Heatmap returned error: %v", err)
+	}
+	want := strings.Join([]string{
+		"Token Count Heatmap",
+		"       1  2  3",
+		"gpt2 ░░░▒▒▒███",
+		"bert ███▓▓▓",
+		"scale: ░░░ 1 … ███ 10",
+		"columns: 1 doc1, 2 doc2, 3 doc3",
+		"",
+	}, "\n")
+	if out != want {
+		t.Errorf("Heatmap =\n%s\nwant\n%s", out, want)
+	}
+
+	// Transforms apply before shading and are named in the scale
+	data.Normalize = NormalizeRow
+	out, err = r.Heatmap(data)
+	if err != nil {
+		t.Fatalf("Heatmap returned error: %v", err)
+	}
+	if !strings.Contains(out, "bert ███░░░") || !strings.Contains(out, "(value, row-normalized)") {
+		t.Errorf("row-normalized heatmap =\n%s", out)
+	}
+
+	data.Normalize = "median"
+	if _, err := r.Heatmap(data); err == nil {
+		t.Error("expected an error for an unknown normalization")
+	}
+}
+
+func TestTerminalHeatmapNarrow(t *testing.T) {
+	r := &TerminalRenderer{width: 20}
+	values := make([]float64, 25)
+	out, err := r.Heatmap(HeatmapData{YLabels: []string{"gpt2"}, Values: [][]float64{values}})
+	if err != nil {
+		t.Fatalf("Heatmap returned error: %v", err)
+	}
+
+	// One character per cell, with every tenth column numbered
+	lines := strings.Split(out, "\n")
+	if lines[1] != "     1         11        21" {
+		t.Errorf("header = %q", lines[1])
+	}
+	if lines[2] != "gpt2 "+strings.Repeat("▓", 25) {
+		t.Errorf("row = %q", lines[2])
+	}
+}
+
+func TestTerminalRollingEntropy(t *testing.T) {
+	r := &TerminalRenderer{width: 80}
+	out, err := r.MultiRollingEntropy(MultiRollingEntropyData{DocumentID: "doc", Series: []EntropySeries{
+		{Name: "gpt2", Values: []float64{0, 1, 2, 3}},
+		{Name: "bert", WindowStarts: []int{0, 3}, Values: []float64{3, 0}},
+	}})
+	if err != nil {
+		t.Fatalf("MultiRollingEntropy returned error: %v", err)
+	}
+
+	// Both series share the x axis of window starts and the y scale
+	want := strings.Join([]string{
+		"Rolling Entropy: doc",
+		"gpt2 ▁▃▆█  min 0  max 3  mean 1.5",
+		"bert █  ▁  min 0  max 3  mean 1.5",
+		"x: window start token 0 … 3, y: entropy 0 … 3",
+		"",
+	}, "\n")
+	if out != want {
+		t.Errorf("MultiRollingEntropy =\n%s\nwant\n%s", out, want)
+	}
+
+	// Long series are averaged into as many characters as fit
+	values := make([]float64, 1000)
+	for i := range values {
+		values[i] = float64(i % 2)
+	}
+	out, err = r.RollingEntropy(RollingEntropyData{DocumentID: "doc", TokenizerName: "gpt2", EntropyValues: values})
+	if err != nil {
+		t.Fatalf("RollingEntropy returned error: %v", err)
+	}
+	line := strings.Split(out, "\n")[1]
+	if spark := strings.Fields(line)[1]; len([]rune(spark)) != 80-4-36 {
+		t.Errorf("sparkline has %d characters, want %d", len([]rune(spark)), 80-4-36)
+	}
+}
+
+func TestTerminalColor(t *testing.T) {
+	data := HeatmapData{YLabels: []string{"gpt2"}, Values: [][]float64{{1, 2}}}
+
+	r := &TerminalRenderer{width: 80, color: true}
+	out, _ := r.Heatmap(data)
+	if !strings.Contains(out, "\x1b[38;2;") || !strings.Contains(out, ansiReset) {
+		t.Errorf("colored heatmap has no ANSI colors:\n%q", out)
+	}
+
+	r.SetColor(false)
+	out, _ = r.Heatmap(data)
+	if strings.Contains(out, "\x1b[") {
+		t.Errorf("uncolored heatmap has ANSI escapes:\n%q", out)
+	}
+
+	// Colors need a terminal and no NO_COLOR
+	if NewTerminalRenderer(&bytes.Buffer{}).color {
+		t.Error("renderer for a buffer uses colors")
+	}
+	t.Setenv("NO_COLOR", "1")
+	if NewTerminalRenderer(os.Stdout).color {
+		t.Error("renderer uses colors despite NO_COLOR")
+	}
+	t.Setenv("COLUMNS", "120")
+	if width := NewTerminalRenderer(os.Stdout).width; width != 120 {
+		t.Errorf("width = %d, want $COLUMNS", width)
+	}
+}
+
+func TestTerminalRender(t *testing.T) {
+	engine := NewVisualizationEngine(VisualizationConfig{OutputDir: t.TempDir(), DisableDataExport: true})
+	r := &TerminalRenderer{width: 100}
+
+	report, err := engine.GenerateComprehensiveReport(summaryResults())
+	if err != nil {
+		t.Fatalf("GenerateComprehensiveReport returned error: %v", err)
+	}
+	out, err := r.Render(report)
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+	for _, want := range []string{
+		"Tokenizer Summary",
+		"Tokenizer  Documents  Mean Token Count  Mean Entropy  Mean Compression Ratio",
+		"bert               2                 4             2                       0",
+		"Cross-Tokenizer Drift",
+		"doc2      bert                 6        3                  –",
+		"token_count Heatmap",
+		"columns: 1 doc1, 2 doc2",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("rendered report lacks %q:\n%s", want, out)
+		}
+	}
+
+	rolling, err := engine.GenerateRollingEntropyPlot(RollingEntropyData{DocumentID: "doc", TokenizerName: "gpt2", EntropyValues: []float64{1, 2}})
+	if err != nil {
+		t.Fatalf("GenerateRollingEntropyPlot returned error: %v", err)
+	}
+	if out, err := r.Render(rolling); err != nil || !strings.Contains(out, "gpt2 ▁█") {
+		t.Errorf("rendered rolling entropy = %q, %v", out, err)
+	}
+
+	histogram, err := engine.GenerateHistogram(HistogramData{ID: "lengths", Names: []string{"gpt2"}, Values: [][]float64{{1}}})
+	if err != nil {
+		t.Fatalf("GenerateHistogram returned error: %v", err)
+	}
+	if _, err := r.Render(histogram); err == nil {
+		t.Error("expected an error rendering a histogram")
+	}
+}
@@ -0,0 +1,245 @@
+package visualization
+
+import (
+	"strings"
+
+	"github.com/RevBooyah/TokEntropyDrift/internal/metrics"
+)
+
+// Palette holds the colors visualizations and the report are drawn with. Colors are
+// #rrggbb strings; color scales are Plotly color scale names.
+type Palette struct {
+	Background  string `json:"background"`   // page background
+	Surface     string `json:"surface"`      // plot, panel and static chart background
+	Foreground  string `json:"foreground"`   // text and axis lines
+	Grid        string `json:"grid"`         // grid lines and table borders
+	Header      string `json:"header"`       // report header background
+	HeaderText  string `json:"header_text"`  // report header and navigation text
+	Nav         string `json:"nav"`          // report navigation background
+	NavActive   string `json:"nav_active"`   // hovered and selected navigation links
+	TableHeader string `json:"table_header"` // report table header background
+
+	Series        []string `json:"series"`         // trace colors, cycled through
+	Accent        string   `json:"accent"`         // disputed tokens and warnings
+	Muted         string   `json:"muted"`          // unaligned tokens
+	TokenSegments []string `json:"token_segments"` // alternating fills of neighbouring tokens
+
+	// ColorScales maps a heatmap type (token_count, entropy, compression, reuse, metric
+	// or comparison) to its default color scale
+	ColorScales map[string]string `json:"color_scales"`
+}
+
+// Built-in palettes of the light and dark themes
+var (
+	lightPalette = Palette{
+		Background:  "#f5f5f5",
+		Surface:     "#ffffff",
+		Foreground:  "#333333",
+		Grid:        "#dddddd",
+		Header:      "#2c3e50",
+		HeaderText:  "#ffffff",
+		Nav:         "#34495e",
+		NavActive:   "#5a6c7d",
+		TableHeader: "#ecf0f1",
+		// Plotly's default trace colors
+		Series: []string{
+			"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd",
+			"#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf",
+		},
+		Accent:        "#d62728",
+		Muted:         "#cccccc",
+		TokenSegments: []string{"#9ecae1", "#fdd0a2"},
+		ColorScales: map[string]string{
+			"token_count": "Viridis",
+			"entropy":     "Plasma",
+			"compression": "RdYlBu_r", // red for high compression, blue for low
+			"reuse":       "Greens",   // green for high reuse
+			"metric":      "Viridis",
+			"comparison":  "Viridis",
+		},
+	}
+
+	darkPalette = Palette{
+		Background:  "#1a1a1a",
+		Surface:     "#262626",
+		Foreground:  "#e0e0e0",
+		Grid:        "#444444",
+		Header:      "#111820",
+		HeaderText:  "#e0e0e0",
+		Nav:         "#1f2933",
+		NavActive:   "#3e4c59",
+		TableHeader: "#303030",
+		// Plotly's dark template trace colors, bright enough to read on dark backgrounds
+		Series: []string{
+			"#636efa", "#ef553b", "#00cc96", "#ab63fa", "#ffa15a",
+			"#19d3f3", "#ff6692", "#b6e880", "#ff97ff", "#fecb52",
+		},
+		Accent:        "#ff5c5c",
+		Muted:         "#555555",
+		TokenSegments: []string{"#2c5f7c", "#7a5230"},
+		// Scales that start dark wash out on a dark background, and those passing
+		// through white glare, so the dark theme uses scales that avoid both ends
+		ColorScales: map[string]string{
+			"token_count": "Viridis",
+			"entropy":     "Cividis",
+			"compression": "Portland",
+			"reuse":       "YlGnBu",
+			"metric":      "Viridis",
+			"comparison":  "Viridis",
+		},
+	}
+)
+
+// LightPalette returns a copy of the light theme's palette
+func LightPalette() Palette {
+	return lightPalette.merge(Palette{})
+}
+
+// DarkPalette returns a copy of the dark theme's palette
+func DarkPalette() Palette {
+	return darkPalette.merge(Palette{})
+}
+
+// newPalette returns the palette of a theme with the given overrides applied
+func newPalette(theme string, overrides *Palette) Palette {
+	base := lightPalette
+	if theme == "dark" {
+		base = darkPalette
+	}
+	if overrides == nil {
+		return base.merge(Palette{})
+	}
+	return base.merge(*overrides)
+}
+
+// merge returns a copy of the palette with the non-empty colors of overrides in place
+// of its own. Color scales are overridden per heatmap type.
+func (p Palette) merge(overrides Palette) Palette {
+	merged := p
+	for _, field := range []struct {
+		dst *string
+		src string
+	}{
+		{&merged.Background, overrides.Background},
+		{&merged.Surface, overrides.Surface},
+		{&merged.Foreground, overrides.Foreground},
+		{&merged.Grid, overrides.Grid},
+		{&merged.Header, overrides.Header},
+		{&merged.HeaderText, overrides.HeaderText},
+		{&merged.Nav, overrides.Nav},
+		{&merged.NavActive, overrides.NavActive},
+		{&merged.TableHeader, overrides.TableHeader},
+		{&merged.Accent, overrides.Accent},
+		{&merged.Muted, overrides.Muted},
+	} {
+		if field.src != "" {
+			*field.dst = field.src
+		}
+	}
+
+	merged.Series = append([]string(nil), p.Series...)
+	if len(overrides.Series) > 0 {
+		merged.Series = append([]string(nil), overrides.Series...)
+	}
+	merged.TokenSegments = append([]string(nil), p.TokenSegments...)
+	if len(overrides.TokenSegments) > 0 {
+		merged.TokenSegments = append([]string(nil), overrides.TokenSegments...)
+	}
+
+	merged.ColorScales = make(map[string]string, len(p.ColorScales))
+	for kind, scale := range p.ColorScales {
+		merged.ColorScales[kind] = scale
+	}
+	for kind, scale := range overrides.ColorScales {
+		if scale != "" {
+			merged.ColorScales[kind] = scale
+		}
+	}
+	return merged
+}
+
+// seriesColor returns the color of the i-th series
+func (p Palette) seriesColor(i int) string {
+	return p.Series[i%len(p.Series)]
+}
+
+// tokenSegmentColor returns the fill of the k-th token in a row of tokens
+func (p Palette) tokenSegmentColor(k int) string {
+	return p.TokenSegments[k%len(p.TokenSegments)]
+}
+
+// colorScale returns the color scale of a heatmap type, Viridis when it has none
+func (p Palette) colorScale(kind string) string {
+	if scale := p.ColorScales[kind]; scale != "" {
+		return scale
+	}
+	return "Viridis"
+}
+
+// alignmentKindColor returns the color of links and connectors of an alignment kind
+func (p Palette) alignmentKindColor(kind string) string {
+	switch kind {
+	case metrics.AlignmentOneToOne:
+		return p.seriesColor(2)
+	case metrics.AlignmentOneToMany:
+		return p.seriesColor(0)
+	case metrics.AlignmentManyToOne:
+		return p.seriesColor(1)
+	case metrics.AlignmentManyToMany:
+		return p.Accent
+	default:
+		return p.seriesColor(7)
+	}
+}
+
+// themeLayout fills in the palette's colors on a Plotly layout: backgrounds, text,
+// trace colors and the grid of every axis, leaving colors the layout sets alone.
+// Plotly.js has no named templates, so without these a dark theme only darkens the page.
+func (p Palette) themeLayout(layout map[string]interface{}) map[string]interface{} {
+	themed := make(map[string]interface{}, len(layout)+4)
+	for key, value := range layout {
+		themed[key] = value
+	}
+	setDefault := func(m map[string]interface{}, key string, value interface{}) {
+		if _, ok := m[key]; !ok {
+			m[key] = value
+		}
+	}
+	setDefault(themed, "paper_bgcolor", p.Surface)
+	setDefault(themed, "plot_bgcolor", p.Surface)
+	setDefault(themed, "colorway", p.Series)
+
+	font := map[string]interface{}{}
+	if existing, ok := themed["font"].(map[string]interface{}); ok {
+		for key, value := range existing {
+			font[key] = value
+		}
+	}
+	setDefault(font, "color", p.Foreground)
+	themed["font"] = font
+
+	axes := []string{"xaxis", "yaxis"}
+	for key := range layout {
+		if key != "xaxis" && key != "yaxis" && (strings.HasPrefix(key, "xaxis") || strings.HasPrefix(key, "yaxis")) {
+			axes = append(axes, key)
+		}
+	}
+	for _, key := range axes {
+		axis := map[string]interface{}{}
+		if existing, ok := themed[key].(map[string]interface{}); ok {
+			for k, value := range existing {
+				axis[k] = value
+			}
+		}
+		setDefault(axis, "gridcolor", p.Grid)
+		setDefault(axis, "zerolinecolor", p.Grid)
+		setDefault(axis, "linecolor", p.Grid)
+		themed[key] = axis
+	}
+	return themed
+}
+
+// palette returns the colors the engine draws with
+func (v *VisualizationEngine) palette() Palette {
+	return v.colors
+}
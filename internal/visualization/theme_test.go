@@ -0,0 +1,148 @@
+package visualization
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// lightLiterals returns the colors and color scales of the light palette that the dark
+// palette does not share, along with the CSS colors pages used before palettes
+func lightLiterals() []string {
+	light, dark := LightPalette(), DarkPalette()
+	literals := []string{"white", "#333;", "#ddd;"}
+	add := func(lightValue, darkValue string) {
+		if lightValue != darkValue {
+			literals = append(literals, lightValue)
+		}
+	}
+	for _, pair := range [][2]string{
+		{light.Background, dark.Background},
+		{light.Surface, dark.Surface},
+		{light.Foreground, dark.Foreground},
+		{light.Grid, dark.Grid},
+		{light.Header, dark.Header},
+		{light.HeaderText, dark.HeaderText},
+		{light.Nav, dark.Nav},
+		{light.NavActive, dark.NavActive},
+		{light.TableHeader, dark.TableHeader},
+		{light.Accent, dark.Accent},
+		{light.Muted, dark.Muted},
+	} {
+		add(pair[0], pair[1])
+	}
+	literals = append(literals, light.Series...)
+	literals = append(literals, light.TokenSegments...)
+	for kind, scale := range light.ColorScales {
+		add(`"`+scale+`"`, `"`+dark.ColorScales[kind]+`"`)
+	}
+	return literals
+}
+
+func TestDarkThemeOutputHasNoLightPaletteColors(t *testing.T) {
+	literals := lightLiterals()
+	check := func(t *testing.T, name, path string) {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		page := strings.ToLower(string(content))
+		for _, literal := range literals {
+			if strings.Contains(page, strings.ToLower(literal)) {
+				t.Errorf("dark %s contains the light palette's %s", name, literal)
+			}
+		}
+	}
+
+	for _, fileType := range []string{"html", "svg"} {
+		t.Run(fileType, func(t *testing.T) {
+			engine := NewVisualizationEngine(VisualizationConfig{Theme: "dark", FileType: fileType, OutputDir: t.TempDir(), DisableDataExport: true})
+
+			generators := testGenerators(engine)
+			heatmap := HeatmapData{XLabels: []string{"doc1", "doc2"}, YLabels: []string{"gpt2", "bert"}, Values: [][]float64{{1, 2}, {3, 4}}}
+			for _, kind := range []string{"token_count", "compression", "reuse"} {
+				kind := kind
+				generators[kind+" heatmap"] = func() (*VisualizationResult, error) { return engine.GenerateHeatmap(heatmap, kind) }
+			}
+			for name, generate := range generators {
+				result, err := generate()
+				if err != nil {
+					t.Fatalf("%s returned error: %v", name, err)
+				}
+				check(t, name, result.Filepath)
+			}
+
+			report, err := engine.GenerateComprehensiveReport(summaryResults())
+			if err != nil {
+				t.Fatalf("GenerateComprehensiveReport returned error: %v", err)
+			}
+			check(t, "report", report.Filepath)
+		})
+	}
+}
+
+func TestDarkThemeStylesPlotlyLayout(t *testing.T) {
+	engine := NewVisualizationEngine(VisualizationConfig{Theme: "dark", OutputDir: t.TempDir(), DisableDataExport: true})
+	result, err := engine.GenerateHeatmap(HeatmapData{Values: [][]float64{{1, 2}}}, "entropy")
+	if err != nil {
+		t.Fatalf("GenerateHeatmap returned error: %v", err)
+	}
+	content, err := os.ReadFile(result.Filepath)
+	if err != nil {
+		t.Fatalf("failed to read heatmap: %v", err)
+	}
+
+	dark := DarkPalette()
+	for _, want := range []string{
+		`"paper_bgcolor":"` + dark.Surface + `"`,
+		`"font":{"color":"` + dark.Foreground + `"}`,
+		`"gridcolor":"` + dark.Grid + `"`,
+		`"colorscale":"` + dark.ColorScales["entropy"] + `"`,
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("dark heatmap lacks %s", want)
+		}
+	}
+}
+
+func TestCustomPalette(t *testing.T) {
+	engine := NewVisualizationEngine(VisualizationConfig{
+		Theme:     "dark",
+		OutputDir: t.TempDir(),
+		Palette: &Palette{
+			Background:  "#000000",
+			Series:      []string{"#112233"},
+			ColorScales: map[string]string{"entropy": "Hot"},
+		},
+	})
+
+	palette := engine.palette()
+	dark := DarkPalette()
+	if palette.Background != "#000000" || palette.Surface != dark.Surface {
+		t.Errorf("palette background %s and surface %s, want the override and the dark surface", palette.Background, palette.Surface)
+	}
+	if palette.seriesColor(3) != "#112233" {
+		t.Errorf("series color = %s, want the only custom series color", palette.seriesColor(3))
+	}
+	if palette.colorScale("entropy") != "Hot" || palette.colorScale("reuse") != dark.ColorScales["reuse"] {
+		t.Errorf("color scales = %v", palette.ColorScales)
+	}
+	if DarkPalette().Background != dark.Background {
+		t.Error("overrides changed the built-in dark palette")
+	}
+
+	result, err := engine.GenerateHistogram(HistogramData{ID: "lengths", Names: []string{"gpt2"}, Values: [][]float64{{1, 2}}})
+	if err != nil {
+		t.Fatalf("GenerateHistogram returned error: %v", err)
+	}
+	trace := result.Data.([]map[string]interface{})[0]
+	if color := trace["marker"].(map[string]interface{})["color"]; color != "#112233" {
+		t.Errorf("histogram color = %v, want the custom series color", color)
+	}
+
+	// A heatmap's own color scale still wins over the palette's
+	data := HeatmapData{Values: [][]float64{{1}}, ColorScale: "Greys"}
+	if scale := engine.heatmapColorScale(data, "entropy"); scale != "Greys" {
+		t.Errorf("heatmap color scale = %s, want its own", scale)
+	}
+}
@@ -184,7 +184,13 @@ class Dashboard {
                 this.currentAnalysis = analysis;
                 this.renderAnalysisResults(analysis);
                 this.addToAnalysisHistory(analysis);
-                this.showAlert('Analysis completed successfully!', 'success');
+                const failures = Object.entries(analysis.errors || {});
+                if (failures.length > 0) {
+                    const details = failures.map(([id, message]) => `${id}: ${message}`).join('; ');
+                    this.showAlert('Some tokenizers failed: ' + details, 'warning');
+                } else {
+                    this.showAlert('Analysis completed successfully!', 'success');
+                }
             } else {
                 const error = await response.text();
                 this.showAlert('Analysis failed: ' + error, 'danger');